@@ -6,7 +6,10 @@ require gopkg.in/yaml.v3 v3.0.1
 
 require (
 	github.com/jchv/go-webview2 v0.0.0-20260205173254-56598839c808
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	github.com/yuin/gopher-lua v1.1.2
 	golang.org/x/sys v0.40.0
+	golang.org/x/text v0.40.0
 )
 
 require github.com/jchv/go-winloader v0.0.0-20250406163304-c1995be93bd1 // indirect
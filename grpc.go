@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/grpcapi"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// grpcBackend выполняет методы gRPC через контроллер.
+type grpcBackend struct {
+	controller *app.Controller
+}
+
+func (b grpcBackend) GetQueueState() (bool, int, string) { return b.controller.GetQueueState() }
+func (b grpcBackend) ToggleQueue()                       { b.controller.ToggleQueue() }
+func (b grpcBackend) PasteNext()                         { b.controller.PasteNext() }
+
+func (b grpcBackend) ListHistory(includeContent bool) []grpcapi.Item {
+	return toGRPCItems(b.controller.GetHistory(), includeContent)
+}
+
+func (b grpcBackend) ListQueue(includeContent bool) []grpcapi.Item {
+	return toGRPCItems(b.controller.GetQueue(), includeContent)
+}
+
+func (b grpcBackend) PushText(text string) (string, error) { return b.controller.PushText(text) }
+
+func (b grpcBackend) PushImagePNG(data []byte) (string, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("image_png не является PNG: %w", err)
+	}
+	item, err := windows.NewImageContent(img)
+	if err != nil {
+		return "", err
+	}
+	if item, err = b.controller.PushItem(item); err != nil {
+		return "", err
+	}
+	return item.ID, nil
+}
+
+func toGRPCItem(content windows.ClipboardContent, includeContent bool) grpcapi.Item {
+	item := grpcapi.Item{
+		ID:              content.ID,
+		TimestampUnixMs: content.Timestamp.UnixMilli(),
+		Type:            content.Type.String(),
+		Preview:         content.Preview,
+		SizeBytes:       int64(content.SizeBytes),
+		Files:           content.Files,
+	}
+	if includeContent {
+		item.Text = content.Text
+		item.ImagePNG = content.ImagePNG
+	}
+	return item
+}
+
+func toGRPCItems(items []windows.ClipboardContent, includeContent bool) []grpcapi.Item {
+	out := make([]grpcapi.Item, 0, len(items))
+	for _, item := range items {
+		out = append(out, toGRPCItem(item, includeContent))
+	}
+	return out
+}
+
+// grpcService запускает сервер gRPC и перезапускает его при изменении раздела grpc.
+type grpcService struct {
+	backend grpcBackend
+
+	mu      sync.Mutex
+	server  *grpcapi.Server
+	applied config.Config // Применённый раздел GRPC; остальные поля не используются
+}
+
+func newGRPCService(controller *app.Controller) *grpcService {
+	return &grpcService{backend: grpcBackend{controller: controller}}
+}
+
+// apply запускает, останавливает или перезапускает сервер по настройкам cfg.
+func (g *grpcService) apply(cfg *config.Config) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.server != nil && reflect.DeepEqual(g.applied.GRPC, cfg.GRPC) {
+		return
+	}
+	g.stopLocked()
+	g.applied.GRPC = cfg.GRPC
+	if !cfg.GRPC.Enabled {
+		return
+	}
+
+	ln, err := net.Listen("tcp", cfg.GRPC.Listen)
+	if err != nil {
+		logger.Error("gRPC API не запущен: %v", err)
+		return
+	}
+	server := grpcapi.NewServer(g.backend, cfg.GRPC.Token)
+	g.server = server
+	logger.Info("gRPC API слушает %s", ln.Addr())
+	crash.Go("grpc.Serve", func() {
+		if err := server.Serve(ln); err != nil {
+			logger.Error("gRPC API остановлен с ошибкой: %v", err)
+		}
+	})
+}
+
+// stop останавливает сервер при выходе из приложения.
+func (g *grpcService) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopLocked()
+}
+
+func (g *grpcService) stopLocked() {
+	if g.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := g.server.Shutdown(ctx); err != nil {
+		logger.Warn("gRPC API: %v", err)
+	}
+	g.server = nil
+}
+
+// publish передаёт событие подписчикам WatchState. Содержимое элемента в поток не попадает.
+func (g *grpcService) publish(event string, content *windows.ClipboardContent) {
+	g.mu.Lock()
+	server := g.server
+	g.mu.Unlock()
+	if server == nil {
+		return
+	}
+	var item *grpcapi.Item
+	if content != nil {
+		converted := toGRPCItem(*content, false)
+		item = &converted
+	}
+	server.Publish(event, item)
+}
@@ -0,0 +1,62 @@
+// Package agent перезапускает основной процесс ClipQueue после аварийного завершения.
+// Агент запускается из задачи планировщика при входе пользователя и живёт,
+// пока дочерний процесс не завершится штатно.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Policy задаёт задержки между перезапусками.
+type Policy struct {
+	MinDelay    time.Duration // Задержка после первого сбоя
+	MaxDelay    time.Duration // Верхняя граница экспоненциальной задержки
+	StableAfter time.Duration // Процесс, проработавший дольше, сбрасывает задержку
+	MaxRestarts int           // 0 — без ограничения
+}
+
+// DefaultPolicy используется агентом по умолчанию.
+var DefaultPolicy = Policy{
+	MinDelay:    time.Second,
+	MaxDelay:    time.Minute,
+	StableAfter: 5 * time.Minute,
+}
+
+// RunFunc запускает дочерний процесс, дожидается его завершения и возвращает код выхода.
+type RunFunc func() (exitCode int, err error)
+
+// Supervise запускает run, пока он завершается с ненулевым кодом или ошибкой.
+// Код 0 означает штатный выход (например, пункт «Выход» в трее) и завершает агента.
+func Supervise(ctx context.Context, run RunFunc, p Policy, logf func(format string, v ...any)) error {
+	delay := p.MinDelay
+	restarts := 0
+	for {
+		started := time.Now()
+		code, err := run()
+		if err == nil && code == 0 {
+			logf("Процесс завершился штатно, агент останавливается")
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if time.Since(started) >= p.StableAfter {
+			delay = p.MinDelay
+		}
+		restarts++
+		if p.MaxRestarts > 0 && restarts > p.MaxRestarts {
+			return fmt.Errorf("превышено число перезапусков (%d)", p.MaxRestarts)
+		}
+		logf("Процесс завершился аварийно (код=%d, ошибка=%v), перезапуск через %v", code, err, delay)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, p.MaxDelay)
+	}
+}
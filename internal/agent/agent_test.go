@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{MinDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond, StableAfter: time.Hour}
+
+func nopLog(string, ...any) {}
+
+func TestSuperviseRestartsUntilCleanExit(t *testing.T) {
+	codes := []int{2, 1, 0}
+	calls := 0
+	run := func() (int, error) {
+		code := codes[calls]
+		calls++
+		return code, nil
+	}
+
+	if err := Supervise(context.Background(), run, testPolicy, nopLog); err != nil {
+		t.Fatalf("Supervise: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("ожидалось 3 запуска, было %d", calls)
+	}
+}
+
+func TestSuperviseStopsAfterMaxRestarts(t *testing.T) {
+	p := testPolicy
+	p.MaxRestarts = 2
+	calls := 0
+	run := func() (int, error) {
+		calls++
+		return 0, errors.New("не удалось запустить")
+	}
+
+	if err := Supervise(context.Background(), run, p, nopLog); err == nil {
+		t.Fatal("ожидалась ошибка после исчерпания перезапусков")
+	}
+	if calls != 3 {
+		t.Fatalf("ожидалось 3 запуска (1 + 2 перезапуска), было %d", calls)
+	}
+}
+
+func TestSuperviseHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := func() (int, error) {
+		cancel()
+		return 1, nil
+	}
+
+	if err := Supervise(ctx, run, testPolicy, nopLog); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ожидалась context.Canceled, получено %v", err)
+	}
+}
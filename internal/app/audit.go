@@ -0,0 +1,183 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const (
+	auditFile = "audit.jsonl"
+	// auditPruneInterval — как часто при записи удалять записи старше audit.retention_days.
+	auditPruneInterval = 24 * time.Hour
+)
+
+// AuditQuery отбирает записи журнала вставок. Нулевые поля не ограничивают выборку.
+type AuditQuery struct {
+	Since  time.Time
+	Until  time.Time // Записи раньше этого момента
+	ItemID string
+	App    string // Процесс окна-получателя без учёта регистра
+	Limit  int
+}
+
+// auditLog — журнал всех вставок из очереди и макросов, по строке JSON на запись.
+// В отличие от pasteTargetStore он не ограничен числом записей и переживает
+// перезапуск; старые записи удаляются по сроку хранения.
+type auditLog struct {
+	mu        sync.Mutex
+	path      string
+	enabled   bool
+	retention time.Duration
+	pruned    time.Time
+}
+
+func newAuditLog(cfg *config.Config) *auditLog {
+	a := &auditLog{path: filepath.Join(config.ResolvePath(cfg.App.DataDir), auditFile)}
+	a.configure(cfg)
+	return a
+}
+
+// configure применяет раздел audit и сразу удаляет записи старше нового срока.
+func (a *auditLog) configure(cfg *config.Config) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled = cfg.Audit.Enabled
+	a.retention = time.Duration(cfg.Audit.RetentionDays) * 24 * time.Hour
+	a.pruneLocked(time.Now())
+}
+
+func (a *auditLog) append(rec PasteRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.enabled {
+		return
+	}
+	if rec.Timestamp.Sub(a.pruned) > auditPruneInterval {
+		a.pruneLocked(rec.Timestamp)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		logger.Warn("Не удалось записать вставку в журнал: %v", err)
+		return
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Warn("Не удалось открыть журнал вставок: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logger.Warn("Не удалось записать вставку в журнал: %v", err)
+	}
+}
+
+// readLocked читает журнал от старых записей к новым. Повреждённые строки
+// пропускаются, чтобы одна оборванная запись не скрывала остальные.
+func (a *auditLog) readLocked() ([]PasteRecord, error) {
+	data, err := os.ReadFile(a.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []PasteRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var rec PasteRecord
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			logger.Debug("Пропущена повреждённая строка журнала вставок: %v", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// pruneLocked удаляет записи старше срока хранения; нулевой срок — хранить всегда.
+func (a *auditLog) pruneLocked(now time.Time) {
+	a.pruned = now
+	if a.retention <= 0 {
+		return
+	}
+	records, err := a.readLocked()
+	if err != nil {
+		logger.Warn("Не удалось прочитать журнал вставок: %v", err)
+		return
+	}
+	cutoff := now.Add(-a.retention)
+	kept := slices.DeleteFunc(slices.Clone(records), func(rec PasteRecord) bool { return rec.Timestamp.Before(cutoff) })
+	if len(kept) == len(records) {
+		return
+	}
+	var buf bytes.Buffer
+	for _, rec := range kept {
+		line, _ := json.Marshal(rec)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		logger.Warn("Не удалось сократить журнал вставок: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		logger.Warn("Не удалось сократить журнал вставок: %v", err)
+		return
+	}
+	logger.Info("Из журнала вставок удалено записей старше %d дн.: %d", int(a.retention.Hours()/24), len(records)-len(kept))
+}
+
+// query возвращает подходящие записи, начиная с самой свежей.
+func (a *auditLog) query(q AuditQuery) ([]PasteRecord, error) {
+	a.mu.Lock()
+	records, err := a.readLocked()
+	a.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PasteRecord, 0)
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if rec.Timestamp.Before(q.Since) || (!q.Until.IsZero() && !rec.Timestamp.Before(q.Until)) {
+			continue
+		}
+		if q.ItemID != "" && rec.ItemID != q.ItemID {
+			continue
+		}
+		if q.App != "" && !strings.EqualFold(rec.Process, strings.TrimSpace(q.App)) {
+			continue
+		}
+		result = append(result, rec)
+		if q.Limit > 0 && len(result) == q.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// SetAudit применяет раздел audit конфигурации.
+func (c *Controller) SetAudit(cfg *config.Config) {
+	c.audit.configure(cfg)
+}
+
+// GetAudit возвращает записи журнала вставок, начиная с самой свежей.
+func (c *Controller) GetAudit(q AuditQuery) ([]PasteRecord, error) {
+	return c.audit.query(q)
+}
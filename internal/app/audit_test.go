@@ -0,0 +1,49 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+)
+
+func TestAuditLogQueryAndRetention(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Audit.Enabled = true
+	cfg.Audit.RetentionDays = 0
+	log := &auditLog{path: filepath.Join(t.TempDir(), auditFile)}
+	log.configure(cfg)
+
+	now := time.Now()
+	log.append(PasteRecord{ItemID: "old", Process: "notepad.exe", Title: "a.txt", Success: true, Timestamp: now.Add(-10 * 24 * time.Hour)})
+	log.append(PasteRecord{ItemID: "1", Process: "Code.exe", Title: "main.go", Success: true, Timestamp: now.Add(-time.Hour)})
+	log.append(PasteRecord{Macro: "Подпись", Process: "code.exe", Title: "README.md", Success: true, Timestamp: now})
+
+	all, err := log.query(AuditQuery{})
+	if err != nil || len(all) != 3 || all[0].Macro != "Подпись" {
+		t.Fatalf("ожидались три записи, новые первыми: %+v, %v", all, err)
+	}
+	if got, _ := log.query(AuditQuery{App: "CODE.EXE", Limit: 1}); len(got) != 1 || got[0].Title != "README.md" {
+		t.Fatalf("фильтр по приложению и limit: %+v", got)
+	}
+	if got, _ := log.query(AuditQuery{ItemID: "1"}); len(got) != 1 || got[0].Title != "main.go" {
+		t.Fatalf("фильтр по элементу: %+v", got)
+	}
+	if got, _ := log.query(AuditQuery{Until: now.Add(-30 * time.Minute)}); len(got) != 2 {
+		t.Fatalf("фильтр until: %+v", got)
+	}
+
+	cfg.Audit.RetentionDays = 7
+	log.configure(cfg)
+	if got, _ := log.query(AuditQuery{}); len(got) != 2 {
+		t.Fatalf("записи старше срока хранения должны удаляться: %+v", got)
+	}
+
+	cfg.Audit.Enabled = false
+	log.configure(cfg)
+	log.append(PasteRecord{ItemID: "2", Timestamp: now})
+	if got, _ := log.query(AuditQuery{}); len(got) != 2 {
+		t.Fatalf("выключенный журнал не должен пополняться: %+v", got)
+	}
+}
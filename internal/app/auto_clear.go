@@ -0,0 +1,80 @@
+package app
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/sensitive"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// autoClearOptions — очистка буфера после записи самим приложением (clipboard.auto_clear_seconds).
+type autoClearOptions struct {
+	after time.Duration // 0 — не очищать
+	all   bool          // Очищать после любой записи, а не только после секретов
+}
+
+func autoClearOptionsFromConfig(cfg *config.Config) autoClearOptions {
+	return autoClearOptions{
+		after: time.Duration(cfg.Clipboard.AutoClearSeconds) * time.Second,
+		all:   cfg.Clipboard.AutoClearAll,
+	}
+}
+
+// SetAutoClear применяет clipboard.auto_clear_seconds и clipboard.auto_clear_all.
+// Уже запланированные очистки выполняются по прежним настройкам.
+func (c *Controller) SetAutoClear(cfg *config.Config) {
+	opts := autoClearOptionsFromConfig(cfg)
+	c.mu.Lock()
+	c.autoClear = opts
+	c.mu.Unlock()
+}
+
+// scheduleAutoClear вызывается сразу после того, как приложение записало content
+// в буфер. Если запись подлежит очистке, через заданное время буфер очищается —
+// но только когда в нём всё ещё лежит эта запись (номер последовательности не изменился).
+func (c *Controller) scheduleAutoClear(content windows.ClipboardContent) {
+	c.mu.Lock()
+	opts := c.autoClear
+	c.mu.Unlock()
+	if opts.after <= 0 {
+		return
+	}
+	if !opts.all && !looksSensitive(content) {
+		return
+	}
+	seq := windows.GetClipboardSequenceNumber()
+	if seq == 0 {
+		return
+	}
+	time.AfterFunc(opts.after, func() { c.autoClearClipboard(seq) })
+}
+
+// looksSensitive проверяет содержимое детектором секретов независимо от
+// clipboard.detect_sensitive: очистка буфера не связана со скрытием предпросмотра.
+func looksSensitive(content windows.ClipboardContent) bool {
+	if content.Sensitive != "" {
+		return true
+	}
+	return content.Type == windows.Text && sensitive.Detect(content.Text) != ""
+}
+
+func (c *Controller) autoClearClipboard(seq uint32) {
+	if windows.GetClipboardSequenceNumber() != seq {
+		logger.Debug("Автоочистка буфера пропущена: буфер уже изменился")
+		return
+	}
+	if err := windows.Write(windows.ClipboardContent{Type: windows.Empty}); err != nil {
+		logger.Warn("Не удалось очистить буфер обмена: %v", err)
+		return
+	}
+	// Очистка — собственное событие: наблюдатель не должен принимать её за копирование.
+	c.mu.Lock()
+	c.addSelfEventLocked(windows.GetClipboardSequenceNumber())
+	c.currentClipboardID = ""
+	uiCB := c.onUIRefresh
+	c.mu.Unlock()
+	logger.Info("Буфер обмена очищен по clipboard.auto_clear_seconds")
+	uiCB()
+}
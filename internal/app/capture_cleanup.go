@@ -0,0 +1,62 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/textclean"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// captureCleanup — встроенная очистка скопированного текста до внешних преобразований:
+// clipboard.normalize и clipboard.clean_urls.
+type captureCleanup struct {
+	form           textclean.Form // clipboard.normalize.form
+	stripInvisible bool           // clipboard.normalize.strip_invisible
+	trim           bool           // clipboard.normalize.trim
+	urlParams      []string       // clipboard.clean_urls.params; nil — ссылки не чистятся
+}
+
+func captureCleanupFromConfig(cfg *config.Config) captureCleanup {
+	cleanup := captureCleanup{
+		form:           textclean.Form(cfg.Clipboard.Normalize.Form),
+		stripInvisible: cfg.Clipboard.Normalize.StripInvisible,
+		trim:           cfg.Clipboard.Normalize.Trim,
+	}
+	if cfg.Clipboard.CleanURLs.Enabled {
+		cleanup.urlParams = append([]string{}, cfg.Clipboard.CleanURLs.Params...)
+	}
+	return cleanup
+}
+
+// apply возвращает очищенный текст. Текст из одних пробелов обрезкой не
+// опустошается: пустой элемент нельзя ни вставить, ни отличить в истории.
+func (o captureCleanup) apply(text string) string {
+	if o.stripInvisible {
+		text = textclean.StripInvisible(text)
+	}
+	text = textclean.Normalize(text, o.form)
+	text = textclean.CleanURLs(text, o.urlParams)
+	if trimmed := strings.TrimSpace(text); o.trim && trimmed != "" {
+		text = trimmed
+	}
+	return text
+}
+
+// cleanCapture применяет к новому тексту встроенную очистку из раздела clipboard.
+func (c *Controller) cleanCapture(content windows.ClipboardContent) windows.ClipboardContent {
+	if content.Type != windows.Text {
+		return content
+	}
+	c.mu.Lock()
+	cleanup := c.cleanup
+	c.mu.Unlock()
+
+	text := cleanup.apply(content.Text)
+	if text == content.Text {
+		return content
+	}
+	logger.Debug("Текст элемента %s очищен по clipboard.normalize и clipboard.clean_urls", content.ID)
+	return withText(content, text)
+}
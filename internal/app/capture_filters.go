@@ -0,0 +1,118 @@
+package app
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/sensitive"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func ignorePatternsFromConfig(cfg *config.Config) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(cfg.Clipboard.IgnorePatterns))
+	for _, p := range cfg.Clipboard.IgnorePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logger.Warn("Правило игнорирования %q пропущено: %v", p, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// SetCaptureFilters применяет clipboard.ignore_patterns, clipboard.detect_sensitive,
+// clipboard.normalize, clipboard.clean_urls и clipboard.fetch_titles конфигурации.
+func (c *Controller) SetCaptureFilters(cfg *config.Config) {
+	patterns := ignorePatternsFromConfig(cfg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ignorePatterns = patterns
+	c.detectSensitive = cfg.Clipboard.DetectSensitive
+	c.cleanup = captureCleanupFromConfig(cfg)
+	c.titles = titleFetchOptionsFromConfig(cfg)
+}
+
+// ignoredPattern возвращает правило, под которое попадает скопированный текст,
+// или пустую строку, если текст можно сохранять.
+func (c *Controller) ignoredPattern(content windows.ClipboardContent) string {
+	if content.Type != windows.Text {
+		return ""
+	}
+	c.mu.Lock()
+	patterns := c.ignorePatterns
+	c.mu.Unlock()
+	for _, re := range patterns {
+		if re.MatchString(content.Text) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// markSensitive помечает текст с вероятным секретом и маскирует его предпросмотр.
+// При заданном history.sensitive_ttl элемент удаляется из истории и очереди по
+// истечении срока.
+func (c *Controller) markSensitive(content windows.ClipboardContent) windows.ClipboardContent {
+	if content.Type != windows.Text {
+		return content
+	}
+	c.mu.Lock()
+	detect := c.detectSensitive
+	ttl := c.historyLimits.sensitiveTTL
+	c.mu.Unlock()
+	if !detect {
+		return content
+	}
+	kind := sensitive.Detect(content.Text)
+	if kind == "" {
+		return content
+	}
+	content.Sensitive = string(kind)
+	content.Preview = sensitive.MaskPreview(kind, content.Text)
+	logger.Info("Скопированный текст похож на секрет (%s): предпросмотр скрыт", kind)
+	if ttl > 0 {
+		// Таймер точнее фоновой очистки; она подберёт элементы, восстановленные из state.json.
+		time.AfterFunc(ttl+time.Second, c.SweepHistory)
+	}
+	return content
+}
+
+// expireSensitiveLocked удаляет из истории и очереди секреты старше
+// history.sensitive_ttl. Предполагает, что мьютекс уже захвачен. Возвращает
+// число удалённых элементов истории и очереди.
+func (c *Controller) expireSensitiveLocked(now time.Time) (history, queue int) {
+	ttl := c.historyLimits.sensitiveTTL
+	if ttl <= 0 {
+		return 0, 0
+	}
+	expired := func(item windows.ClipboardContent) bool {
+		return item.Sensitive != "" && now.Sub(item.Timestamp) > ttl
+	}
+	keptHistory := c.history[:0:0]
+	for _, item := range c.history {
+		if !expired(item) {
+			keptHistory = append(keptHistory, item)
+			continue
+		}
+		if item.ID == c.currentClipboardID {
+			c.currentClipboardID = ""
+		}
+	}
+	keptQueue := c.queue[:0:0]
+	for _, item := range c.queue {
+		if !expired(item) {
+			keptQueue = append(keptQueue, item)
+		}
+	}
+	history, queue = len(c.history)-len(keptHistory), len(c.queue)-len(keptQueue)
+	if history > 0 {
+		c.history = keptHistory
+	}
+	if queue > 0 {
+		c.queue = keptQueue
+	}
+	return history, queue
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// PickColor берёт цвет пикселя под курсором и записывает его в буфер обмена
+// в формате format (hex, rgb или hsl). Наблюдатель добавит его в историю как
+// обычное копирование.
+func (c *Controller) PickColor(format string) error {
+	f, err := imaging.ParseColorFormat(format)
+	if err != nil {
+		return err
+	}
+	pixel, at, err := windows.PixelAtCursor()
+	if err != nil {
+		return err
+	}
+	text := imaging.FormatColor(pixel, f)
+	content := windows.NewTextContent(text)
+	if err := windows.Write(content); err != nil {
+		return fmt.Errorf("не удалось записать цвет в буфер обмена: %w", err)
+	}
+	c.scheduleAutoClear(content)
+	logger.Info("Пипетка: %s в точке (%d, %d)", text, at.X, at.Y)
+	c.notify("Цвет скопирован", text, false)
+	return nil
+}
@@ -2,11 +2,15 @@ package app
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/serty2005/clipqueue/internal/config"
 	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/ocr"
+	"github.com/serty2005/clipqueue/internal/snippets"
 	"github.com/serty2005/clipqueue/platform/windows"
 )
 
@@ -15,33 +19,93 @@ type Controller struct {
 	mu                 sync.Mutex
 	queueEnabled       bool
 	queue              []windows.ClipboardContent
-	history            []windows.ClipboardContent // Stores last 50 clipboard items
+	history            []windows.ClipboardContent // Clipboard history bounded by historyLimits
+	historyLimits      historyLimits              // Лимиты размера и времени жизни истории
 	currentClipboardID string
-	selfEventsRing     []uint32 // Ring buffer for self-event suppression
-	ringIndex          int      // Current index for ring buffer
-	ringSize           int      // Size of ring buffer
+	selfEventsRing     []selfEvent // Ring buffer for self-event suppression
+	ringIndex          int         // Current index for ring buffer
+	ringSize           int         // Size of ring buffer
 	cfg                *config.Config
-	orderStrategy      string                                     // "LIFO" or "FIFO"
+	orderStrategy      string                                     // Один из QueueOrders
+	randomNextID       string                                     // Заранее выбранный следующий элемент в режиме RANDOM
 	onStateChange      func(enabled bool, count int, mode string) // Callback for state changes
 	onUIRefresh        func()                                     // Callback for UI refresh notifications
 	onMacroInvoke      func(name string, done bool)               // Callback for macro execution UI notifications
+	onNotify           func(title, text string, failure bool)     // Callback for user-facing tray notifications
+	onElevatedTarget   func(target windows.WindowInfo)            // Вставка отменена: окно запущено с повышенными правами
+	onCapture          func(content windows.ClipboardContent)     // Новый локальный элемент для синхронизации; nil — не нужен
+	captureImages      bool                                       // Передавать ли в onCapture изображения
+	onEvent            func(ev Event)                             // События для вебхуков
+	targets            *pasteTargetStore                          // История вставок и выученные настройки приложений-получателей
+	audit              *auditLog                                  // Журнал всех вставок из очереди и макросов
+	snippets           *snippets.Store                            // Библиотека сниппетов, вставляемых без хоткея
+	plugins            Plugins                                    // Пользовательские скрипты; nil — выключены
+	transforms         []transformRule                            // Внешние команды из раздела transforms
+	ocr                ocr.Options                                // Движок и языки распознавания текста
+	textCounts         textCountsCache                            // Статистика текстов для списка истории
+	ignorePatterns     []*regexp.Regexp                           // Текст, который не сохраняется в историю и очередь
+	detectSensitive    bool                                       // Помечать и маскировать вероятные секреты
+	cleanup            captureCleanup                             // Нормализация скопированного текста
+	titles             titleFetchOptions                          // Заголовки страниц для ссылок
+	historyImages      historyImageOptions                        // Формат хранения изображений в истории
+	compactingImages   bool                                       // Идёт фоновое уменьшение изображений истории
+	dedup              dedupOptions                               // Поиск дубликатов по SHA-256 содержимого
+	queueIdleTimeout   time.Duration                              // queue.auto_disable_minutes; 0 — не выключать
+	autoClear          autoClearOptions                           // clipboard.auto_clear_seconds
+	typing             typingSettings                             // Темп набора текста макросами (раздел input)
+	remote             remoteTiming                               // Задержки для удалённых сеансов (раздел remote_session)
+	pasteMethods       []config.PasteMethodRule                   // clipboard.paste_methods
+	lineEndings        lineEndingOptions                          // clipboard.line_endings
+	lastQueueActivity  time.Time                                  // Последняя вставка или включение режима записи
+	queueSnapshot      *queueSnapshot                             // Буфер до включения режима записи
+}
+
+// selfEventTTL ограничивает время, в течение которого записанный нами seq считается собственным.
+// После истечения окна совпадение номера (переполнение или переиспользование) не подавляет реальное копирование.
+const selfEventTTL = 5 * time.Second
+
+// selfEvent хранит номер последовательности буфера, записанный самим приложением, и момент записи.
+type selfEvent struct {
+	seq uint32
+	at  time.Time
 }
 
 // NewController creates a new instance of Controller
 func NewController(cfg *config.Config) *Controller {
 	const ringBufferSize = 8
 	order := cfg.Queue.DefaultOrder
-	if order != "LIFO" && order != "FIFO" {
-		order = "LIFO" // Default to LIFO if invalid
+	if !validOrder(order) {
+		order = OrderLIFO // Default to LIFO if invalid
 	}
 	return &Controller{
-		selfEventsRing: make([]uint32, ringBufferSize),
-		ringSize:       ringBufferSize,
-		cfg:            cfg,
-		orderStrategy:  order,
-		onStateChange:  func(enabled bool, count int, mode string) {}, // Default empty callback
-		onUIRefresh:    func() {},
-		onMacroInvoke:  func(name string, done bool) {},
+		selfEventsRing:   make([]selfEvent, ringBufferSize),
+		ringSize:         ringBufferSize,
+		cfg:              cfg,
+		orderStrategy:    order,
+		historyLimits:    historyLimitsFromConfig(cfg),
+		historyImages:    historyImageOptionsFromConfig(cfg),
+		dedup:            dedupOptionsFromConfig(cfg),
+		queueIdleTimeout: queueIdleTimeoutFromConfig(cfg),
+		autoClear:        autoClearOptionsFromConfig(cfg),
+		typing:           typingSettingsFromConfig(cfg),
+		remote:           remoteTimingFromConfig(cfg),
+		pasteMethods:     append([]config.PasteMethodRule{}, cfg.Clipboard.PasteMethods...),
+		lineEndings:      lineEndingOptionsFromConfig(cfg),
+		transforms:       transformRulesFromConfig(cfg),
+		ocr:              ocrOptionsFromConfig(cfg),
+		ignorePatterns:   ignorePatternsFromConfig(cfg),
+		detectSensitive:  cfg.Clipboard.DetectSensitive,
+		cleanup:          captureCleanupFromConfig(cfg),
+		titles:           titleFetchOptionsFromConfig(cfg),
+		targets:          newPasteTargetStore(cfg.App.DataDir),
+		audit:            newAuditLog(cfg),
+		snippets:         openSnippets(cfg.App.DataDir),
+		onStateChange:    func(enabled bool, count int, mode string) {}, // Default empty callback
+		onUIRefresh:      func() {},
+		onMacroInvoke:    func(name string, done bool) {},
+		onNotify:         func(title, text string, failure bool) {},
+		onElevatedTarget: func(target windows.WindowInfo) {},
+		onEvent:          func(ev Event) {},
 	}
 }
 
@@ -70,6 +134,23 @@ func (c *Controller) SetMacroInvokeCallback(fn func(name string, done bool)) {
 	c.onMacroInvoke = fn
 }
 
+// SetNotifyCallback sets the callback used to show user-facing notifications
+func (c *Controller) SetNotifyCallback(fn func(title, text string, failure bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fn == nil {
+		fn = func(title, text string, failure bool) {}
+	}
+	c.onNotify = fn
+}
+
+func (c *Controller) notify(title, text string, failure bool) {
+	c.mu.Lock()
+	fn := c.onNotify
+	c.mu.Unlock()
+	fn(title, text, failure)
+}
+
 // ClearQueue clears the clipboard queue
 func (c *Controller) ClearQueue() {
 	c.mu.Lock()
@@ -92,14 +173,11 @@ func (c *Controller) ClearQueue() {
 	uiCB()
 }
 
-// ToggleOrder toggles the queue order between LIFO and FIFO
+// ToggleOrder переключает порядок очереди по кругу: LIFO, FIFO, ROUND_ROBIN, RANDOM.
 func (c *Controller) ToggleOrder() {
 	c.mu.Lock()
-	if c.orderStrategy == "LIFO" {
-		c.orderStrategy = "FIFO"
-	} else {
-		c.orderStrategy = "LIFO"
-	}
+	c.orderStrategy = nextOrder(c.orderStrategy)
+	c.randomNextID = ""
 	cb := c.onStateChange
 	uiCB := c.onUIRefresh
 	enabled := c.queueEnabled
@@ -114,12 +192,24 @@ func (c *Controller) ToggleOrder() {
 
 // ToggleQueue toggles the queue mode on or off
 func (c *Controller) ToggleQueue() {
-	logger.Info("Entering ToggleQueue, current state: %v", c.queueEnabled)
+	c.mu.Lock()
+	enabling := !c.queueEnabled
+	c.mu.Unlock()
+	logger.Info("Entering ToggleQueue, current state: %v", !enabling)
+
+	// Снимок делается до включения: буфер открывается надолго, держать мьютекс нельзя.
+	var snapshot *queueSnapshot
+	if enabling {
+		snapshot = c.takeQueueSnapshot()
+	}
 
 	c.mu.Lock()
+	c.clearSelfEventsLocked()
 
 	if !c.queueEnabled {
 		c.queueEnabled = true
+		c.queueSnapshot = snapshot
+		c.markQueueActivityLocked(time.Now())
 		cb := c.onStateChange
 		uiCB := c.onUIRefresh
 		count := len(c.queue)
@@ -128,9 +218,12 @@ func (c *Controller) ToggleQueue() {
 		logger.Info("Queue mode enabled")
 		cb(true, count, mode)
 		uiCB()
+		c.notify("Очередь включена", fmt.Sprintf("Порядок: %s, элементов: %d", mode, count), false)
 	} else {
 		// Disable queue mode but keep queued items so the user can resume later.
 		c.queueEnabled = false
+		snapshot = c.queueSnapshot
+		c.queueSnapshot = nil
 		cb := c.onStateChange
 		uiCB := c.onUIRefresh
 		count := len(c.queue)
@@ -138,8 +231,10 @@ func (c *Controller) ToggleQueue() {
 		c.mu.Unlock()
 
 		logger.Info("Queue mode disabled")
+		c.restoreQueueSnapshot(snapshot)
 		cb(false, count, mode)
 		uiCB()
+		c.notify("Очередь выключена", fmt.Sprintf("Элементов в очереди: %d", count), false)
 	}
 }
 
@@ -173,6 +268,47 @@ func (c *Controller) OnClipboardUpdate() {
 	if content.Type == windows.Image {
 		content.SourceSeq = seq
 	}
+	source := windows.GetClipboardSourceInfo()
+	content.SourceApp, content.SourceTitle = source.ProcessName, source.Title
+
+	// Правила проверяются до преобразований и плагинов: секрет не должен уходить во внешние команды.
+	if pattern := c.ignoredPattern(content); pattern != "" {
+		logger.Debug("OnClipboardUpdate: текст не сохранён по правилу игнорирования %q", pattern)
+		c.mu.Lock()
+		c.currentClipboardID = ""
+		uiCB := c.onUIRefresh
+		c.mu.Unlock()
+		uiCB()
+		return
+	}
+
+	if transformed := c.applyAutoTransforms(c.cleanCapture(content)); transformed.Text != content.Text {
+		content = transformed
+		// Результат очистки и правил заменяет и сам буфер, если пользователь ещё не скопировал другое.
+		if windows.GetClipboardSequenceNumber() == seq {
+			if err := windows.Write(content); err != nil {
+				logger.Warn("OnClipboardUpdate: не удалось записать результат преобразования в буфер: %v", err)
+			} else {
+				c.addSelfEvent(windows.GetClipboardSequenceNumber())
+				c.scheduleAutoClear(content)
+			}
+		}
+	}
+
+	if p := c.getPlugins(); p != nil && content.Type != windows.Empty {
+		var keep bool
+		if content, keep = p.Capture(content); !keep {
+			c.mu.Lock()
+			c.currentClipboardID = ""
+			uiCB := c.onUIRefresh
+			c.mu.Unlock()
+			uiCB()
+			return
+		}
+	}
+
+	content = c.markSensitive(content)
+	content.Hash = contentHash(content)
 
 	c.mu.Lock()
 
@@ -200,19 +336,33 @@ func (c *Controller) OnClipboardUpdate() {
 		}
 	}
 
+	// Повтор уже сохранённого содержимого не создаёт новый элемент: ID выводится из хеша.
+	content = c.assignContentIDLocked(content)
+	content, duplicate := c.dedupHistoryLocked(content)
+	if duplicate {
+		c.currentClipboardID = content.ID
+		logger.Debug("OnClipboardUpdate: содержимое уже есть в истории (id=%s, перенесено=%v)", content.ID, c.dedup.bump)
+	}
+
 	// Add to history if enabled
-	if c.cfg.Features.EnableClipboard {
-		if len(c.history) >= 50 {
-			c.history = c.history[1:]
-		}
+	if c.cfg.Features.EnableClipboard && !duplicate {
 		c.history = append(c.history, content)
 		c.currentClipboardID = content.ID
+		c.trimHistoryLocked(time.Now())
+		c.compactImagesSoonLocked()
 		logger.Debug("OnClipboardUpdate: добавлено в историю (тип=%s, размер=%d байт, предпросмотр=%q, длина истории=%d)",
 			content.Type.String(), content.SizeBytes, content.Preview, len(c.history))
 	}
 
 	// Add to queue only while queue mode is enabled.
 	if c.cfg.Features.EnableQueue && c.queueEnabled {
+		if c.dedupQueueLocked(content) {
+			uiCB := c.onUIRefresh
+			c.mu.Unlock()
+			logger.Debug("OnClipboardUpdate: элемент %s уже в очереди", content.ID)
+			uiCB()
+			return
+		}
 		c.queue = append(c.queue, content)
 		cb := c.onStateChange
 		uiCB := c.onUIRefresh
@@ -225,6 +375,15 @@ func (c *Controller) OnClipboardUpdate() {
 			content.Type.String(), content.SizeBytes, content.Preview, count)
 		cb(enabled, count, mode)
 		uiCB()
+		c.notify(fmt.Sprintf("Добавлено в очередь (%d)", count), content.Preview, false)
+		if !duplicate {
+			c.emit(Event{Kind: EventCapture, Item: content})
+		}
+		c.emit(Event{Kind: EventEnqueue, Item: content})
+		if !duplicate {
+			c.notifyCapture(content)
+			c.fetchTitleSoon(content)
+		}
 		return
 	}
 
@@ -232,6 +391,29 @@ func (c *Controller) OnClipboardUpdate() {
 	c.mu.Unlock()
 	logger.Debug("OnClipboardUpdate: не добавлено в очередь (режим очереди выключен или фича отключена)")
 	uiCB()
+	if !duplicate {
+		c.emit(Event{Kind: EventCapture, Item: content})
+		c.notifyCapture(content)
+		c.fetchTitleSoon(content)
+	}
+}
+
+// PasteNextTo делает окно hwnd активным и вставляет в него следующий элемент
+// очереди. Так очередь можно разбирать из UI или API в окно, которое сейчас не в фокусе.
+func (c *Controller) PasteNextTo(hwnd uintptr) error {
+	c.mu.Lock()
+	ready := c.queueEnabled && len(c.queue) > 0
+	c.mu.Unlock()
+	if !ready {
+		return ErrNothingToPaste
+	}
+	if err := windows.ActivateWindow(hwnd); err != nil {
+		return err
+	}
+	// Окну нужно время, чтобы вернуть фокус ввода своему элементу управления.
+	time.Sleep(c.pasteTiming(windows.GetWindowInfo(hwnd)).activate)
+	c.PasteNext()
+	return nil
 }
 
 // PasteNext retrieves and pastes the next item from the clipboard queue
@@ -251,20 +433,19 @@ func (c *Controller) PasteNext() {
 		return
 	}
 
-	logger.Info("PasteNext called, queue length: %d, order: %s", len(c.queue), c.orderStrategy)
+	// Ввод в окно процесса с более высоким уровнем целостности молча теряется,
+	// поэтому элемент остаётся в очереди, а пользователь получает предупреждение.
+	target := windows.GetForegroundWindowInfo()
+	if inputBlocked(target) {
+		c.mu.Unlock()
+		c.warnElevatedTarget(target)
+		return
+	}
 
-	var item windows.ClipboardContent
+	logger.Info("PasteNext called, queue length: %d, order: %s", len(c.queue), c.orderStrategy)
 
-	// Get next item from queue based on order strategy
-	if c.orderStrategy == "LIFO" {
-		// LIFO: get last item
-		item = c.queue[len(c.queue)-1]
-		c.queue = c.queue[:len(c.queue)-1]
-	} else {
-		// FIFO: get first item
-		item = c.queue[0]
-		c.queue = c.queue[1:]
-	}
+	item, index := c.takeNextLocked()
+	c.markQueueActivityLocked(time.Now())
 
 	logger.Info("Dequeued clipboard content (type=%s, size=%d bytes, preview=%q, queue length=%d, order=%s)",
 		item.Type.String(), item.SizeBytes, item.Preview, len(c.queue), c.orderStrategy)
@@ -277,11 +458,20 @@ func (c *Controller) PasteNext() {
 	cb(enabled, count, mode)
 	uiCB()
 
+	if p := c.getPlugins(); p != nil {
+		var ok bool
+		if item, ok = p.BeforePaste(item, target); !ok {
+			c.requeue(item, index)
+			return
+		}
+	}
+
 	// Save current clipboard state
 	logger.Debug("Saving current clipboard state before pasting")
 	before, err := windows.Read()
 	if err != nil {
 		logger.Error("Failed to save current clipboard state: %v", err)
+		c.notify("Вставка не выполнена", fmt.Sprintf("Не удалось сохранить текущий буфер: %v", err), true)
 		return
 	}
 
@@ -289,32 +479,39 @@ func (c *Controller) PasteNext() {
 	item, err = c.resolveImagePayload(item)
 	if err != nil {
 		logger.Error("Не удалось подготовить элемент очереди к вставке: %v", err)
+		c.notify("Вставка не выполнена", err.Error(), true)
 		return
 	}
 
 	logger.Debug("Writing item to clipboard for pasting")
-	err = windows.Write(item)
+	err = windows.Write(c.forPasteTarget(item, target))
 	if err != nil {
 		logger.Error("Failed to write item to clipboard: %v", err)
+		c.notify("Вставка не выполнена", fmt.Sprintf("Не удалось записать элемент в буфер: %v", err), true)
 		return
 	}
-	c.addSelfEvent(windows.GetClipboardSequenceNumber())
+	pastedSeq := windows.GetClipboardSequenceNumber()
+	c.addSelfEvent(pastedSeq)
 
-	// Give Windows time to update clipboard handles before sending Ctrl+V
-	time.Sleep(10 * time.Millisecond)
+	// Give Windows time to update clipboard handles before sending Ctrl+V;
+	// клиенту удалённого рабочего стола нужно больше, чтобы передать буфер в сеанс.
+	timing := c.pasteTiming(target)
+	time.Sleep(timing.settle)
 
-	logger.Debug("Sending Ctrl+V keystroke")
-	err = windows.SendCtrlV()
+	method := c.pasteMethod(target)
+	err = sendPaste(method)
+	c.recordPaste(target, PasteRecord{ItemID: item.ID, Method: method, Success: err == nil})
 	if err != nil {
-		logger.Error("Failed to send Ctrl+V keystroke: %v", err)
+		logger.Error("Failed to paste (method=%s): %v", method, err)
+		c.notify("Вставка не выполнена", fmt.Sprintf("Не удалось вставить (%s): %v", method, err), true)
 		// Try to restore clipboard anyway
 		_ = windows.Write(before)
 		c.addSelfEvent(windows.GetClipboardSequenceNumber())
 		return
 	}
+	c.emit(Event{Kind: EventPaste, Item: item, Target: target.ProcessName})
 
-	// Wait before restoring clipboard
-	time.Sleep(time.Duration(c.cfg.Clipboard.RestoreDelayMs) * time.Millisecond)
+	c.waitPasteRead(pastedSeq, target, timing.restore)
 
 	logger.Debug("Restoring previous clipboard state")
 	err = windows.Write(before)
@@ -366,13 +563,13 @@ func (c *Controller) GetQueueState() (enabled bool, count int, order string) {
 	return c.queueEnabled, len(c.queue), c.orderStrategy
 }
 
-// SetOrderStrategy sets the queue order strategy (LIFO or FIFO)
+// SetOrderStrategy задаёт порядок очереди — одно из значений QueueOrders.
 func (c *Controller) SetOrderStrategy(order string) error {
 	c.mu.Lock()
 
-	if order != "LIFO" && order != "FIFO" {
+	if !validOrder(order) {
 		c.mu.Unlock()
-		return fmt.Errorf("unsupported order strategy: %s. Allowed values: LIFO, FIFO", order)
+		return fmt.Errorf("unsupported order strategy: %s. Allowed values: %s", order, strings.Join(QueueOrders, ", "))
 	}
 
 	if c.orderStrategy == order {
@@ -382,6 +579,7 @@ func (c *Controller) SetOrderStrategy(order string) error {
 	}
 
 	c.orderStrategy = order
+	c.randomNextID = ""
 	cb := c.onStateChange
 	uiCB := c.onUIRefresh
 	enabled := c.queueEnabled
@@ -418,10 +616,68 @@ func (c *Controller) RemoveItem(index int) error {
 	return nil
 }
 
+// PromoteItem делает элемент очереди следующим для вставки независимо от порядка:
+// при LIFO он переносится в конец очереди, при FIFO и ROUND_ROBIN — в начало,
+// при RANDOM запоминается как заранее выбранный.
+func (c *Controller) PromoteItem(id string) error {
+	return c.moveQueueItem(id, true)
+}
+
+// DemoteItem переносит элемент очереди на место, которое вставится последним.
+func (c *Controller) DemoteItem(id string) error {
+	return c.moveQueueItem(id, false)
+}
+
+func (c *Controller) moveQueueItem(id string, next bool) error {
+	c.mu.Lock()
+	index := -1
+	for i, item := range c.queue {
+		if item.ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: id %s", ErrNotQueued, id)
+	}
+
+	item := c.queue[index]
+	rest := append(c.queue[:index:index], c.queue[index+1:]...)
+	// Конец очереди вставляется первым при LIFO и последним при остальных порядках.
+	if next == (c.orderStrategy == OrderLIFO) {
+		c.queue = append(rest, item)
+	} else {
+		c.queue = append([]windows.ClipboardContent{item}, rest...)
+	}
+	switch {
+	case c.orderStrategy != OrderRandom:
+	case next:
+		c.randomNextID = id
+	case c.randomNextID == id:
+		c.randomNextID = ""
+	}
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("Элемент %s перемещён в очереди (следующим=%v, порядок=%s)", id, next, mode)
+	cb(enabled, count, mode)
+	uiCB()
+	return nil
+}
+
 // addSelfEventLocked adds a sequence number to the self-event suppression ring buffer
 // Предполагает, что мьютекс уже захвачен
 func (c *Controller) addSelfEventLocked(seq uint32) {
-	c.selfEventsRing[c.ringIndex] = seq
+	if seq == 0 {
+		// GetClipboardSequenceNumber возвращает 0 при отсутствии доступа к станции окон.
+		return
+	}
+	c.selfEventsRing[c.ringIndex] = selfEvent{seq: seq, at: time.Now()}
 	c.ringIndex = (c.ringIndex + 1) % c.ringSize
 	logger.Debug("Added self-event sequence number: %d", seq)
 }
@@ -435,14 +691,32 @@ func (c *Controller) addSelfEvent(seq uint32) {
 
 // isSelfEvent checks if a sequence number is in the self-event suppression ring buffer
 func (c *Controller) isSelfEvent(seq uint32) bool {
-	for _, s := range c.selfEventsRing {
-		if s == seq {
+	return c.isSelfEventAt(seq, time.Now())
+}
+
+// isSelfEventAt проверяет seq с учётом окна selfEventTTL относительно момента now.
+func (c *Controller) isSelfEventAt(seq uint32, now time.Time) bool {
+	if seq == 0 {
+		return false
+	}
+	for _, ev := range c.selfEventsRing {
+		if ev.seq != seq || ev.at.IsZero() {
+			continue
+		}
+		if age := now.Sub(ev.at); age >= 0 && age <= selfEventTTL {
 			return true
 		}
 	}
 	return false
 }
 
+// clearSelfEventsLocked сбрасывает кольцо подавления self-событий.
+// Предполагает, что мьютекс уже захвачен
+func (c *Controller) clearSelfEventsLocked() {
+	clear(c.selfEventsRing)
+	c.ringIndex = 0
+}
+
 func (c *Controller) clipboardContentMatches(current, previous windows.ClipboardContent) bool {
 	switch current.Type {
 	case windows.Text:
@@ -478,16 +752,30 @@ func (c *Controller) resolveImagePayload(item windows.ClipboardContent) (windows
 	if windows.GetClipboardSequenceNumber() != item.SourceSeq {
 		return item, fmt.Errorf("буфер изменился во время чтения изображения")
 	}
+	if resolved.Type == windows.Image && resolved.Oversized {
+		// Изображение сверх лимита остаётся заглушкой, повторно его не дочитываем.
+		c.applyResolvedImagePayload(item.ID, resolved)
+		item.SourceSeq = 0
+		item.Oversized = true
+		item.SizeBytes = resolved.SizeBytes
+		item.Preview = resolved.Preview
+		return item, fmt.Errorf("%w: %s", windows.ErrOversized, resolved.Preview)
+	}
 	if resolved.Type != windows.Image || len(resolved.ImagePNG) == 0 {
 		return item, fmt.Errorf("буфер не вернул данные изображения")
 	}
 
 	resolved.SourceSeq = item.SourceSeq
+	resolved.Hash = contentHash(resolved)
 	c.applyResolvedImagePayload(item.ID, resolved)
 
 	item.ImagePNG = append([]byte(nil), resolved.ImagePNG...)
 	item.SizeBytes = resolved.SizeBytes
 	item.Preview = resolved.Preview
+	item.Thumbnail = resolved.Thumbnail
+	item.ThumbnailType = resolved.ThumbnailType
+	item.EMF = resolved.EMF
+	item.Hash = resolved.Hash
 	return item, nil
 }
 
@@ -504,6 +792,11 @@ func (c *Controller) applyResolvedImagePayload(id string, resolved windows.Clipb
 		c.history[i].SizeBytes = resolved.SizeBytes
 		c.history[i].Preview = resolved.Preview
 		c.history[i].SourceSeq = resolved.SourceSeq
+		c.history[i].Thumbnail = resolved.Thumbnail
+		c.history[i].ThumbnailType = resolved.ThumbnailType
+		c.history[i].Oversized = resolved.Oversized
+		c.history[i].EMF = resolved.EMF
+		c.history[i].Hash = resolved.Hash
 		updated = true
 	}
 
@@ -515,9 +808,19 @@ func (c *Controller) applyResolvedImagePayload(id string, resolved windows.Clipb
 		c.queue[i].SizeBytes = resolved.SizeBytes
 		c.queue[i].Preview = resolved.Preview
 		c.queue[i].SourceSeq = resolved.SourceSeq
+		c.queue[i].Thumbnail = resolved.Thumbnail
+		c.queue[i].ThumbnailType = resolved.ThumbnailType
+		c.queue[i].Oversized = resolved.Oversized
+		c.queue[i].EMF = resolved.EMF
+		c.queue[i].Hash = resolved.Hash
 		updated = true
 	}
 
+	// Размер изображения известен только после захвата, поэтому лимит истории перепроверяется.
+	if updated {
+		c.trimHistoryLocked(time.Now())
+		c.compactImagesSoonLocked()
+	}
 	c.mu.Unlock()
 
 	if updated {
@@ -534,10 +837,28 @@ func (c *Controller) ExecuteMacro(macro config.Macro) error {
 	macroCB(macro.Name, false)
 	defer macroCB(macro.Name, true)
 
+	target := windows.GetForegroundWindowInfo()
+	rec := PasteRecord{Macro: macro.Name, Mode: macro.Mode}
+	switch macro.Mode {
+	case "paste":
+		rec.Method = c.pasteMethod(target)
+	case "transform":
+		rec.Transform = macro.Action
+	}
+	err := c.executeMacro(macro)
+	rec.Success = err == nil
+	c.recordPaste(target, rec)
+	if err != nil {
+		c.notify("Макрос не выполнен", fmt.Sprintf("%s: %v", macro.Name, err), true)
+	}
+	return err
+}
+
+func (c *Controller) executeMacro(macro config.Macro) error {
 	switch macro.Mode {
 	case "type":
 		// Режим "type" - ввод текста символ за символом
-		err := windows.TypeString(macro.Text)
+		err := windows.TypeString(macro.Text, c.typingOptions(macro))
 		if err != nil {
 			logger.Error("Failed to type text: %v", err)
 			return err
@@ -546,51 +867,14 @@ func (c *Controller) ExecuteMacro(macro config.Macro) error {
 
 	case "paste":
 		// Режим "paste" - вставка через буфер обмена с сохранением и восстановлением текущего состояния
-		// Сохраняем текущий буфер обмена
-		oldContent, err := windows.Read()
-		if err != nil {
-			logger.Error("Failed to read current clipboard: %v", err)
-			return err
-		}
-
-		// Записываем текст макроса в буфер обмена
-		content := windows.ClipboardContent{
-			Type: windows.Text,
-			Text: macro.Text,
-		}
-		if err := windows.Write(content); err != nil {
-			logger.Error("Failed to write macro text to clipboard: %v", err)
-			return err
-		}
-		c.addSelfEvent(windows.GetClipboardSequenceNumber())
-
-		// Дайте время для обновления буфера обмена
-		time.Sleep(100 * time.Millisecond)
-
-		// Отправляем Ctrl+V для вставки
-		if err := windows.SendCtrlV(); err != nil {
-			logger.Error("Failed to send Ctrl+V: %v", err)
-			// Попытка восстановить буфер даже при ошибке
-			_ = windows.Write(oldContent)
-			c.addSelfEvent(windows.GetClipboardSequenceNumber())
-			return err
-		}
-
-		// Дожидаемся завершения вставки
-		time.Sleep(time.Duration(c.cfg.Clipboard.RestoreDelayMs) * time.Millisecond)
-
-		// Восстанавливаем исходный буфер обмена
-		if err := windows.Write(oldContent); err != nil {
-			logger.Error("Failed to restore clipboard: %v", err)
+		if err := c.pasteText(macro.Text); err != nil {
 			return err
 		}
-		c.addSelfEvent(windows.GetClipboardSequenceNumber())
-
 		logger.Debug("Macro executed in paste mode")
 
 	case "type_hw":
 		// Режим "type_hw" - ввод текста с использованием аппаратного ввода
-		err := windows.TypeStringHardware(macro.Text)
+		err := windows.TypeStringHardware(macro.Text, c.typingOptions(macro))
 		if err != nil {
 			logger.Error("Failed to type hardware text: %v", err)
 			return err
@@ -621,8 +905,54 @@ func (c *Controller) ExecuteMacro(macro config.Macro) error {
 		}
 		logger.Debug("Macro executed in sequence mode")
 
+	case "transform":
+		if err := c.TransformClipboard(macro.Action); err != nil {
+			logger.Error("Failed to transform clipboard: %v", err)
+			return err
+		}
+		logger.Debug("Macro executed in transform mode")
+
+	case "ocr":
+		if err := c.OCRClipboard(); err != nil {
+			logger.Error("Failed to recognize clipboard image: %v", err)
+			return err
+		}
+		logger.Debug("Macro executed in ocr mode")
+
+	case "format":
+		if err := c.FormatClipboard(); err != nil {
+			logger.Error("Failed to format clipboard text: %v", err)
+			return err
+		}
+		logger.Debug("Macro executed in format mode")
+
+	case "screenshot":
+		if err := c.Screenshot(macro.Action); err != nil {
+			logger.Error("Failed to capture screenshot: %v", err)
+			return err
+		}
+		logger.Debug("Macro executed in screenshot mode")
+
+	case "color":
+		if err := c.PickColor(macro.Action); err != nil {
+			logger.Error("Failed to pick screen color: %v", err)
+			return err
+		}
+		logger.Debug("Macro executed in color mode")
+
+	case "script":
+		p := c.getPlugins()
+		if p == nil {
+			return errNoPlugins
+		}
+		if err := p.RunAction(macro.Action, macro.Text); err != nil {
+			logger.Error("Failed to run script action: %v", err)
+			return err
+		}
+		logger.Debug("Macro executed in script mode")
+
 	default:
-		return fmt.Errorf("unsupported macro mode: %s. Supported modes: type, paste, type_hw, sequence", macro.Mode)
+		return fmt.Errorf("unsupported macro mode: %s. Supported modes: type, paste, type_hw, sequence, script, transform, ocr, format, screenshot, color", macro.Mode)
 	}
 
 	return nil
@@ -661,6 +991,7 @@ func (c *Controller) CopyItem(id string) error {
 	uiCB := c.onUIRefresh
 	c.mu.Unlock()
 
+	c.scheduleAutoClear(item)
 	logger.Info("Элемент из истории скопирован в буфер обмена (id=%s, type=%s)", id, item.Type.String())
 	go uiCB()
 	return nil
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func newTestController() *Controller {
+	return NewController(&config.Config{})
+}
+
+func TestSelfEventSuppressedWithinWindow(t *testing.T) {
+	c := newTestController()
+	c.addSelfEvent(100)
+
+	if !c.isSelfEvent(100) {
+		t.Fatal("ожидалось подавление только что записанного seq")
+	}
+	if c.isSelfEvent(101) {
+		t.Fatal("чужой seq не должен подавляться")
+	}
+}
+
+func TestSelfEventExpiresAfterTTL(t *testing.T) {
+	c := newTestController()
+	c.addSelfEvent(100)
+
+	now := time.Now()
+	if !c.isSelfEventAt(100, now.Add(selfEventTTL-time.Millisecond)) {
+		t.Fatal("seq должен подавляться до истечения окна")
+	}
+	if c.isSelfEventAt(100, now.Add(selfEventTTL+time.Second)) {
+		t.Fatal("seq после истечения окна не должен подавлять реальное копирование")
+	}
+}
+
+func TestSelfEventZeroSequenceNeverSuppressed(t *testing.T) {
+	c := newTestController()
+
+	if c.isSelfEvent(0) {
+		t.Fatal("пустое кольцо не должно подавлять seq=0")
+	}
+
+	c.addSelfEvent(0)
+	if c.isSelfEvent(0) {
+		t.Fatal("seq=0 не должен попадать в кольцо подавления")
+	}
+}
+
+func TestSelfEventRingOverwritesOldest(t *testing.T) {
+	c := newTestController()
+	for seq := uint32(1); seq <= uint32(c.ringSize)+1; seq++ {
+		c.addSelfEvent(seq)
+	}
+
+	if c.isSelfEvent(1) {
+		t.Fatal("самый старый seq должен быть вытеснен из кольца")
+	}
+	if !c.isSelfEvent(uint32(c.ringSize) + 1) {
+		t.Fatal("последний seq должен подавляться")
+	}
+}
+
+func TestSelfEventsClearedOnQueueToggle(t *testing.T) {
+	c := newTestController()
+	c.addSelfEvent(100)
+
+	c.ToggleQueue()
+
+	if c.isSelfEvent(100) {
+		t.Fatal("переключение очереди должно сбрасывать кольцо подавления")
+	}
+}
+
+func TestIgnoredPattern(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Clipboard.IgnorePatterns = []string{`^\d{6}$`, `^sk-[A-Za-z0-9]{32}`, `(`}
+	c := NewController(cfg)
+
+	for text, want := range map[string]string{
+		"123456":                              `^\d{6}$`,
+		"1234567":                             "",
+		"sk-0123456789abcdef0123456789ABCDEF": `^sk-[A-Za-z0-9]{32}`,
+		"мой ключ sk-0123456789abcdef0123456789": "",
+	} {
+		if got := c.ignoredPattern(windows.NewTextContent(text)); got != want {
+			t.Errorf("ignoredPattern(%q) = %q, ожидалось %q", text, got, want)
+		}
+	}
+	files := windows.ClipboardContent{Type: windows.Files, Files: []string{"123456"}}
+	if got := c.ignoredPattern(files); got != "" {
+		t.Errorf("правила применяются только к тексту, получено %q", got)
+	}
+}
+
+func TestPromoteAndDemoteQueueItem(t *testing.T) {
+	c := newTestController()
+	c.queue = []windows.ClipboardContent{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	// LIFO: следующий — последний элемент очереди.
+	if err := c.PromoteItem("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueIDs(c); got != "bca" {
+		t.Fatalf("после PromoteItem при LIFO: %s", got)
+	}
+	c.orderStrategy = "FIFO"
+	if err := c.PromoteItem("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DemoteItem("b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueIDs(c); got != "cab" {
+		t.Fatalf("после перестановок при FIFO: %s", got)
+	}
+	if err := c.PromoteItem("нет"); !errors.Is(err, ErrNotQueued) {
+		t.Fatalf("ожидалась ErrNotQueued, получено %v", err)
+	}
+}
+
+func queueIDs(c *Controller) string {
+	var ids string
+	for _, item := range c.queue {
+		ids += item.ID
+	}
+	return ids
+}
+
+func TestRoundRobinKeepsItems(t *testing.T) {
+	c := newTestController()
+	c.orderStrategy = OrderRoundRobin
+	c.queue = []windows.ClipboardContent{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	var pasted string
+	for range 4 {
+		item, _ := c.takeNextLocked()
+		pasted += item.ID
+	}
+	if pasted != "abca" || queueIDs(c) != "bca" {
+		t.Fatalf("вставлено %s, очередь %s", pasted, queueIDs(c))
+	}
+}
+
+func TestRandomNextIsStable(t *testing.T) {
+	c := newTestController()
+	c.orderStrategy = OrderRandom
+	c.queue = []windows.ClipboardContent{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	next := c.NextQueueItemID()
+	if c.NextQueueItemID() != next {
+		t.Fatal("выбор следующего элемента должен сохраняться до вставки")
+	}
+	if item, _ := c.takeNextLocked(); item.ID != next || len(c.queue) != 2 {
+		t.Fatalf("вставлен %s вместо %s", item.ID, next)
+	}
+}
+
+func TestDisableIdleQueue(t *testing.T) {
+	c := newTestController()
+	c.queueIdleTimeout = 10 * time.Minute
+	c.ToggleQueue()
+	c.queue = []windows.ClipboardContent{{ID: "a"}}
+	start := c.lastQueueActivity
+
+	c.disableIdleQueue(start.Add(9 * time.Minute))
+	if !c.queueEnabled {
+		t.Fatal("очередь выключена раньше срока")
+	}
+	c.disableIdleQueue(start.Add(11 * time.Minute))
+	if c.queueEnabled || len(c.queue) != 1 {
+		t.Fatalf("ожидалось выключение с сохранением элементов: включена=%v, элементов=%d", c.queueEnabled, len(c.queue))
+	}
+}
+
+func TestLooksSensitiveIgnoresDetectSetting(t *testing.T) {
+	if !looksSensitive(windows.NewTextContent("4111 1111 1111 1111")) {
+		t.Fatal("номер карты должен очищаться из буфера")
+	}
+	if looksSensitive(windows.NewTextContent("обычный текст")) {
+		t.Fatal("обычный текст не должен считаться секретом")
+	}
+}
+
+func TestTypingOptionsFor(t *testing.T) {
+	settings := typingSettings{chunkSize: 50, chunkDelay: 20 * time.Millisecond}
+
+	if got := typingOptionsFor(settings, config.Macro{}); got.ChunkSize != 50 || got.ChunkDelay != 20*time.Millisecond {
+		t.Fatalf("без переопределений = %+v", got)
+	}
+	got := typingOptionsFor(settings, config.Macro{TypeChunkSize: 10, TypeChunkDelayMs: 100})
+	if got.ChunkSize != 10 || got.ChunkDelay != 100*time.Millisecond {
+		t.Fatalf("переопределение макроса = %+v", got)
+	}
+	// Медленный режим набирает по символу, но не ускоряет заданную паузу.
+	got = typingOptionsFor(settings, config.Macro{TypeChunkDelayMs: 100, SlowTyping: true})
+	if got.ChunkSize != 2 || got.ChunkDelay != 100*time.Millisecond {
+		t.Fatalf("медленный режим = %+v", got)
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// contentIDLength — сколько шестнадцатеричных знаков SHA-256 идёт в ID элемента (64 бита).
+const contentIDLength = 16
+
+// dedupOptions управляет повторным копированием уже сохранённого содержимого.
+type dedupOptions struct {
+	bump bool // Переносить найденный элемент в конец истории и очереди с новым временем
+}
+
+func dedupOptionsFromConfig(cfg *config.Config) dedupOptions {
+	return dedupOptions{bump: cfg.History.DedupBump}
+}
+
+// contentHash возвращает SHA-256 полезной нагрузки элемента в hex. Пустая строка —
+// хеш посчитать нельзя: изображение ещё не дочитано из буфера или не сохранено по размеру.
+func contentHash(content windows.ClipboardContent) string {
+	h := sha256.New()
+	switch content.Type {
+	case windows.Text:
+		writeHashPart(h, "text", []byte(content.Text))
+	case windows.Files:
+		writeHashPart(h, "files", nil)
+		for _, path := range content.Files {
+			writeHashPart(h, "", []byte(path))
+		}
+	case windows.Image:
+		if len(content.ImagePNG) == 0 {
+			return ""
+		}
+		writeHashPart(h, "image", content.ImagePNG)
+		writeHashPart(h, "emf", content.EMF)
+	default:
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeHashPart пишет часть с префиксом длины, чтобы разные наборы частей не давали одинаковый поток.
+func writeHashPart(h hash.Hash, tag string, data []byte) {
+	h.Write([]byte(tag))
+	h.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(data))))
+	h.Write(data)
+}
+
+// assignContentIDLocked выводит ID элемента из хеша содержимого, чтобы одно и то же
+// содержимое было одним элементом, а ссылки API переживали перезапуск. ID присваивается
+// при создании и потом не меняется, даже если текст элемента отредактировали. Прежний ID
+// по времени захвата остаётся у не дочитанного изображения и в редком случае, когда
+// выведенный ID уже занят отредактированным элементом.
+func (c *Controller) assignContentIDLocked(content windows.ClipboardContent) windows.ClipboardContent {
+	if content.Hash == "" {
+		return content
+	}
+	id := content.Hash[:contentIDLength]
+	if existing, ok := c.findItemLocked(id); ok && existing.Hash != content.Hash {
+		return content
+	}
+	content.ID = id
+	return content
+}
+
+// dedupHistoryLocked ищет в истории элемент с тем же хешем, что у content.
+// При history.dedup_bump найденный элемент переносится в конец истории со временем
+// content. Возвращает найденный элемент (уже с новым временем) и признак находки.
+func (c *Controller) dedupHistoryLocked(content windows.ClipboardContent) (windows.ClipboardContent, bool) {
+	if content.Hash == "" {
+		return content, false
+	}
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if c.history[i].Hash != content.Hash {
+			continue
+		}
+		existing := c.history[i]
+		if c.dedup.bump {
+			existing.Timestamp = content.Timestamp
+			if content.SourceApp != "" {
+				existing.SourceApp, existing.SourceTitle = content.SourceApp, content.SourceTitle
+			}
+			c.history = append(c.history[:i], c.history[i+1:]...)
+			c.history = append(c.history, existing)
+		}
+		return existing, true
+	}
+	return content, false
+}
+
+// dedupQueueLocked проверяет, есть ли в очереди элемент с тем же хешем или ID.
+// При history.dedup_bump он переносится в конец очереди — туда, куда встал бы новый.
+func (c *Controller) dedupQueueLocked(content windows.ClipboardContent) bool {
+	for i := len(c.queue) - 1; i >= 0; i-- {
+		if c.queue[i].ID != content.ID && (content.Hash == "" || c.queue[i].Hash != content.Hash) {
+			continue
+		}
+		if c.dedup.bump {
+			existing := c.queue[i]
+			existing.Timestamp = content.Timestamp
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			c.queue = append(c.queue, existing)
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// SetElevatedTargetCallback задаёт обработчик вставки, отменённой из-за окна с повышенными
+// правами; через него main предлагает или выполняет перезапуск от имени администратора.
+func (c *Controller) SetElevatedTargetCallback(fn func(target windows.WindowInfo)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fn == nil {
+		fn = func(target windows.WindowInfo) {}
+	}
+	c.onElevatedTarget = fn
+}
+
+// inputBlocked проверяет, отбросит ли Windows (UIPI) нажатия, отправленные в окно target.
+// SendInput в таком случае не сообщает об ошибке, поэтому проверка выполняется заранее.
+func inputBlocked(target windows.WindowInfo) bool {
+	blocked, err := windows.IsInputBlocked(target)
+	if err != nil {
+		logger.Debug("Не удалось проверить уровень целостности окна %q: %v", target.ProcessName, err)
+		return false
+	}
+	return blocked
+}
+
+// warnElevatedTarget сообщает, что вставка в окно с повышенными правами невозможна.
+func (c *Controller) warnElevatedTarget(target windows.WindowInfo) {
+	logger.Warn("Вставка отменена: окно %q (процесс %s, PID %d) запущено с повышенными правами, ввод будет заблокирован UIPI",
+		target.Title, target.ProcessName, target.ProcessID)
+	c.recordPaste(target, PasteRecord{Method: c.pasteMethod(target), Success: false})
+	c.notify("Вставка не выполнена",
+		fmt.Sprintf("%s запущен от имени администратора. Запустите ClipQueue от имени администратора, чтобы вставлять в это окно.", target.ProcessName),
+		true)
+
+	c.mu.Lock()
+	fn := c.onElevatedTarget
+	c.mu.Unlock()
+	fn(target)
+}
@@ -0,0 +1,35 @@
+package app
+
+import "github.com/serty2005/clipqueue/platform/windows"
+
+// События контроллера для внешних подписчиков (вебхуков).
+const (
+	EventCapture = "capture" // Новый элемент в буфере обмена
+	EventEnqueue = "enqueue" // Элемент добавлен в очередь
+	EventPaste   = "paste"   // Элемент вставлен из очереди
+)
+
+// Event описывает событие с элементом. Target — процесс окна, в которое выполнена вставка.
+type Event struct {
+	Kind   string
+	Item   windows.ClipboardContent
+	Target string
+}
+
+// SetEventCallback задаёт обработчик событий захвата, добавления в очередь и вставки.
+// Обработчик вызывается синхронно и не должен блокироваться.
+func (c *Controller) SetEventCallback(fn func(ev Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fn == nil {
+		fn = func(ev Event) {}
+	}
+	c.onEvent = fn
+}
+
+func (c *Controller) emit(ev Event) {
+	c.mu.Lock()
+	fn := c.onEvent
+	c.mu.Unlock()
+	fn(ev)
+}
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/serty2005/clipqueue/internal/fileinfo"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// ErrNotFiles возвращается для операций над списком файлов, если элемент другого типа.
+var ErrNotFiles = errors.New("элемент не содержит списка файлов")
+
+// GetItemFileTree обходит пути элемента Files и возвращает дерево для предпросмотра
+// с настоящими размерами. Обход идёт по диску, поэтому сведения актуальны на момент вызова.
+func (c *Controller) GetItemFileTree(id string) ([]fileinfo.Entry, fileinfo.Totals, error) {
+	item, err := c.GetItem(id)
+	if err != nil {
+		return nil, fileinfo.Totals{}, err
+	}
+	if item.Type != windows.Files {
+		return nil, fileinfo.Totals{}, fmt.Errorf("%w: id %s", ErrNotFiles, id)
+	}
+	entries, totals := fileinfo.Tree(item.Files, fileinfo.Options{})
+	return entries, totals, nil
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// historySweepInterval задаёт период фоновой очистки устаревших элементов истории.
+const historySweepInterval = time.Minute
+
+// historyLimits ограничивает историю буфера. Нулевое значение поля означает отсутствие лимита.
+type historyLimits struct {
+	maxItems      int
+	maxTotalBytes int64
+	ttl           time.Duration
+	sensitiveTTL  time.Duration // Время жизни элементов-секретов в истории и очереди
+}
+
+func historyLimitsFromConfig(cfg *config.Config) historyLimits {
+	ttl, err := config.ParseHistoryTTL(cfg.History.TTL)
+	if err != nil {
+		logger.Warn("Некорректный history.ttl, TTL отключён: %v", err)
+	}
+	sensitiveTTL, err := config.ParseHistoryTTL(cfg.History.SensitiveTTL)
+	if err != nil {
+		logger.Warn("Некорректный history.sensitive_ttl, срок жизни секретов отключён: %v", err)
+	}
+	return historyLimits{
+		maxItems:      cfg.History.MaxItems,
+		maxTotalBytes: cfg.History.MaxTotalBytes,
+		ttl:           ttl,
+		sensitiveTTL:  sensitiveTTL,
+	}
+}
+
+// SetHistoryLimits применяет новые лимиты, формат изображений и поиск дубликатов истории
+// и сразу вытесняет лишние элементы.
+func (c *Controller) SetHistoryLimits(cfg *config.Config) {
+	limits := historyLimitsFromConfig(cfg)
+	images := historyImageOptionsFromConfig(cfg)
+
+	c.mu.Lock()
+	c.historyLimits = limits
+	c.historyImages = images
+	c.dedup = dedupOptionsFromConfig(cfg)
+	c.mu.Unlock()
+	c.SweepHistory()
+}
+
+// SweepHistory удаляет просроченные и не помещающиеся в лимиты элементы истории,
+// а также секреты старше history.sensitive_ttl из истории и очереди.
+// Заодно запускает уменьшение изображений, пропущенных при добавлении.
+func (c *Controller) SweepHistory() {
+	now := time.Now()
+	c.mu.Lock()
+	expired, expiredQueued := c.expireSensitiveLocked(now)
+	removed := c.trimHistoryLocked(now)
+	c.compactImagesSoonLocked()
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	if expired > 0 || expiredQueued > 0 {
+		logger.Info("SweepHistory: удалены секреты с истёкшим сроком: %d из истории, %d из очереди", expired, expiredQueued)
+	}
+	if removed > 0 {
+		logger.Debug("SweepHistory: удалено %d элементов истории", removed)
+	}
+	if expiredQueued > 0 {
+		cb(enabled, count, mode)
+	}
+	if removed > 0 || expired > 0 || expiredQueued > 0 {
+		uiCB()
+	}
+}
+
+// RunHistorySweeper периодически вызывает SweepHistory до закрытия stop и заодно
+// выключает простаивающий режим записи очереди (queue.auto_disable_minutes).
+func (c *Controller) RunHistorySweeper(stop <-chan struct{}) {
+	ticker := time.NewTicker(historySweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.SweepHistory()
+			c.disableIdleQueue(time.Now())
+		}
+	}
+}
+
+// ImportHistory добавляет в историю элементы из других программ. Элементы
+// встают по времени копирования; последним остаётся прежний самый свежий элемент,
+// потому что он отражает текущий буфер. Как и при копировании, текст проверяется
+// на секреты, а хеш и ID выводятся из содержимого; элементы с уже известным
+// содержимым или ID пропускаются, поэтому повторный импорт не создаёт дублей.
+// Найденный повтор не переносится в конец истории: импорт — не новое копирование.
+// Очередь и обработчики событий не затрагиваются. Возвращает число элементов,
+// оставшихся в истории после лимитов.
+func (c *Controller) ImportHistory(items []windows.ClipboardContent) (int, error) {
+	prepared := make([]windows.ClipboardContent, 0, len(items))
+	for _, item := range items {
+		if item.Type == windows.Empty {
+			continue
+		}
+		item = c.markSensitive(item)
+		item.Hash = contentHash(item)
+		prepared = append(prepared, item)
+	}
+
+	c.mu.Lock()
+	if !c.cfg.Features.EnableClipboard {
+		c.mu.Unlock()
+		return 0, ErrHistoryDisabled
+	}
+
+	known := make(map[string]bool, len(c.history))
+	knownHashes := make(map[string]bool, len(c.history))
+	for _, item := range c.history {
+		known[item.ID] = true
+		knownHashes[item.Hash] = true
+	}
+	merged := append([]windows.ClipboardContent(nil), c.history...)
+	var newest []windows.ClipboardContent
+	if n := len(merged); n > 0 {
+		newest, merged = merged[n-1:], merged[:n-1]
+	}
+	added := make(map[string]bool, len(prepared))
+	for _, item := range prepared {
+		item = c.assignContentIDLocked(item)
+		if known[item.ID] || added[item.ID] || (item.Hash != "" && knownHashes[item.Hash]) {
+			continue
+		}
+		added[item.ID] = true
+		knownHashes[item.Hash] = true
+		merged = append(merged, item)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp.Before(merged[j].Timestamp) })
+	c.history = append(merged, newest...)
+	c.trimHistoryLocked(time.Now())
+
+	imported := 0
+	for _, item := range c.history {
+		if added[item.ID] {
+			imported++
+		}
+	}
+	uiCB := c.onUIRefresh
+	c.mu.Unlock()
+
+	logger.Info("ImportHistory: импортировано %d из %d элементов", imported, len(items))
+	uiCB()
+	return imported, nil
+}
+
+// trimHistoryLocked вытесняет самые старые элементы: сначала просроченные по TTL,
+// затем сверх лимита количества и суммарного размера. Самый свежий элемент
+// отражает текущее содержимое буфера, поэтому по размеру он не вытесняется.
+// Предполагает, что мьютекс уже захвачен. Возвращает число удалённых элементов.
+func (c *Controller) trimHistoryLocked(now time.Time) int {
+	limits := c.historyLimits
+	drop := 0
+
+	if limits.ttl > 0 {
+		for drop < len(c.history) && now.Sub(c.history[drop].Timestamp) > limits.ttl {
+			drop++
+		}
+	}
+	if limits.maxItems > 0 && len(c.history)-drop > limits.maxItems {
+		drop = len(c.history) - limits.maxItems
+	}
+	if limits.maxTotalBytes > 0 {
+		total := historyBytes(c.history[drop:])
+		for drop < len(c.history)-1 && total > limits.maxTotalBytes {
+			total -= int64(c.history[drop].SizeBytes)
+			drop++
+		}
+	}
+
+	if drop == 0 {
+		return 0
+	}
+	for _, item := range c.history[:drop] {
+		if item.ID == c.currentClipboardID {
+			c.currentClipboardID = ""
+		}
+	}
+	c.history = append([]windows.ClipboardContent(nil), c.history[drop:]...)
+	return drop
+}
+
+func historyBytes(items []windows.ClipboardContent) int64 {
+	var total int64
+	for _, item := range items {
+		total += int64(item.SizeBytes)
+	}
+	return total
+}
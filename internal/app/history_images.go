@@ -0,0 +1,122 @@
+package app
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// historyImageOptions задаёт, как история хранит изображения. Формат original
+// оставляет их без изменений.
+type historyImageOptions struct {
+	format  imaging.CompactFormat
+	maxSize int
+	quality int
+}
+
+func historyImageOptionsFromConfig(cfg *config.Config) historyImageOptions {
+	format, err := imaging.ParseCompactFormat(cfg.History.ImageFormat)
+	if err != nil {
+		logger.Warn("Некорректный history.image_format, изображения хранятся без изменений: %v", err)
+		format = imaging.CompactOriginal
+	}
+	return historyImageOptions{
+		format:  format,
+		maxSize: cfg.History.ImageMaxDimension,
+		quality: cfg.History.ImageQuality,
+	}
+}
+
+// compactCandidate — изображение истории, ожидающее уменьшения.
+type compactCandidate struct {
+	id   string
+	data []byte
+}
+
+// compactImagesSoonLocked запускает фоновое уменьшение изображений истории,
+// если оно включено и ещё не идёт. Предполагает, что мьютекс уже захвачен.
+func (c *Controller) compactImagesSoonLocked() {
+	if c.historyImages.format == imaging.CompactOriginal || c.compactingImages {
+		return
+	}
+	c.compactingImages = true
+	// После паники compactingImages остаётся выставленным: то же изображение
+	// уронило бы повторный проход, поэтому уменьшение ждёт перезапуска.
+	crash.Go("history.compact", c.compactHistoryImages)
+}
+
+// compactHistoryImages перекодирует ещё не уменьшенные изображения истории.
+// Кодирование идёт без мьютекса, поэтому результат применяется, только если
+// элемент за это время не изменился. Копии тех же элементов в очереди не
+// трогаются: до вставки очередь хранит оригинал.
+func (c *Controller) compactHistoryImages() {
+	for {
+		c.mu.Lock()
+		opts := c.historyImages
+		var candidates []compactCandidate
+		if opts.format != imaging.CompactOriginal {
+			for _, item := range c.history {
+				if item.Type == windows.Image && len(item.ImagePNG) > 0 && !item.Compacted {
+					candidates = append(candidates, compactCandidate{id: item.ID, data: item.ImagePNG})
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			c.compactingImages = false
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		start := time.Now()
+		var saved int
+		for _, candidate := range candidates {
+			data, contentType, smaller, err := imaging.CompactImage(candidate.data, opts.format, opts.maxSize, opts.quality)
+			if err != nil {
+				logger.Warn("Не удалось уменьшить изображение истории (id=%s): %v", candidate.id, err)
+			}
+			if err != nil || !smaller {
+				data, contentType = candidate.data, ""
+			}
+			c.mu.Lock()
+			saved += c.applyCompactedLocked(candidate, data, contentType)
+			c.mu.Unlock()
+		}
+		logger.Debug("Изображения истории уменьшены: %d шт., освобождено %d байт за %v", len(candidates), saved, time.Since(start))
+
+		c.mu.Lock()
+		uiCB := c.onUIRefresh
+		c.mu.Unlock()
+		uiCB()
+	}
+}
+
+// applyCompactedLocked подменяет данные изображения в истории, если элемент
+// всё ещё хранит те же байты. Пустой contentType оставляет исходный формат.
+// Возвращает число освобождённых байт. Предполагает, что мьютекс уже захвачен.
+func (c *Controller) applyCompactedLocked(candidate compactCandidate, data []byte, contentType string) int {
+	for i := range c.history {
+		item := &c.history[i]
+		if item.ID != candidate.id || item.Compacted || !sameBytes(item.ImagePNG, candidate.data) {
+			continue
+		}
+		saved := len(item.ImagePNG) - len(data)
+		item.ImagePNG = data
+		item.SizeBytes = len(data)
+		item.Compacted = true
+		if contentType != "" {
+			item.ImageType = contentType
+		}
+		return saved
+	}
+	return 0
+}
+
+// sameBytes проверяет, что срезы ссылаются на одни и те же данные, без сравнения содержимого.
+func sameBytes(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
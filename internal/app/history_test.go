@@ -0,0 +1,257 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func historyItem(id string, size int, at time.Time) windows.ClipboardContent {
+	return windows.ClipboardContent{ID: id, Type: windows.Text, SizeBytes: size, Timestamp: at}
+}
+
+func historyIDs(c *Controller) []string {
+	ids := make([]string, 0, len(c.history))
+	for _, item := range c.history {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestTrimHistoryByMaxItems(t *testing.T) {
+	c := newTestController()
+	c.historyLimits = historyLimits{maxItems: 2}
+	now := time.Now()
+	c.history = []windows.ClipboardContent{
+		historyItem("a", 1, now), historyItem("b", 1, now), historyItem("c", 1, now),
+	}
+
+	if removed := c.trimHistoryLocked(now); removed != 1 {
+		t.Fatalf("ожидалось удаление 1 элемента, удалено %d", removed)
+	}
+	if got := historyIDs(c); len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Fatalf("неожиданная история после вытеснения: %v", got)
+	}
+}
+
+func TestTrimHistoryByTotalBytesKeepsNewest(t *testing.T) {
+	c := newTestController()
+	c.historyLimits = historyLimits{maxTotalBytes: 100}
+	now := time.Now()
+	c.history = []windows.ClipboardContent{
+		historyItem("a", 60, now), historyItem("b", 30, now), historyItem("c", 500, now),
+	}
+
+	c.trimHistoryLocked(now)
+
+	if got := historyIDs(c); len(got) != 1 || got[0] != "c" {
+		t.Fatalf("самый свежий элемент должен остаться даже сверх лимита: %v", got)
+	}
+}
+
+func TestTrimHistoryByTTL(t *testing.T) {
+	c := newTestController()
+	c.historyLimits = historyLimits{ttl: time.Hour}
+	now := time.Now()
+	c.history = []windows.ClipboardContent{
+		historyItem("old", 1, now.Add(-2*time.Hour)),
+		historyItem("fresh", 1, now.Add(-time.Minute)),
+	}
+	c.currentClipboardID = "old"
+
+	c.trimHistoryLocked(now)
+
+	if got := historyIDs(c); len(got) != 1 || got[0] != "fresh" {
+		t.Fatalf("просроченный элемент должен быть удалён: %v", got)
+	}
+	if c.currentClipboardID != "" {
+		t.Fatalf("ID текущего элемента должен сбрасываться при его вытеснении")
+	}
+}
+
+func TestImportHistoryMergesByTimeAndSkipsKnown(t *testing.T) {
+	c := newTestController()
+	c.cfg.Features.EnableClipboard = true
+	c.historyLimits = historyLimits{maxItems: 4}
+	now := time.Now()
+	c.history = []windows.ClipboardContent{
+		capturedItem("local-old", now.Add(-time.Hour)),
+		capturedItem("current", now.Add(-2*time.Hour)),
+	}
+
+	imported, err := c.ImportHistory([]windows.ClipboardContent{
+		importedItem("import-ditto-1", "oldest", now.Add(-3*time.Hour)),
+		importedItem("import-ditto-2", "local-old", now),
+		importedItem("import-ditto-3", "middle", now.Add(-90*time.Minute)),
+		importedItem("import-ditto-4", "newer", now.Add(-time.Minute)),
+		importedItem("import-ditto-5", "newer", now.Add(-time.Minute)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Лимит в 4 элемента вытесняет самый старый импортированный.
+	if imported != 2 {
+		t.Fatalf("импортировано %d, ожидалось 2", imported)
+	}
+	want := []string{"middle", "local-old", "newer", "current"}
+	if got := historyTexts(c); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("история %v, ожидалось %v", got, want)
+	}
+	if got := c.history[0]; got.Hash == "" || got.ID != got.Hash[:contentIDLength] {
+		t.Fatalf("ID импортированного элемента не выведен из содержимого: %+v", got)
+	}
+
+	// Повторный импорт того же файла ничего не добавляет.
+	again, err := c.ImportHistory([]windows.ClipboardContent{importedItem("import-ditto-3", "middle", now.Add(-90*time.Minute))})
+	if err != nil || again != 0 || len(c.history) != 4 {
+		t.Fatalf("повторный импорт: %d, %v, история %v", again, err, historyTexts(c))
+	}
+}
+
+func TestImportHistoryMarksSensitive(t *testing.T) {
+	c := newTestController()
+	c.cfg.Features.EnableClipboard = true
+	c.detectSensitive = true
+
+	if _, err := c.ImportHistory([]windows.ClipboardContent{importedItem("import-ditto-7", "4111 1111 1111 1111", time.Now())}); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.history) != 1 || c.history[0].Sensitive != "card" || strings.Contains(c.history[0].Preview, "4111") {
+		t.Fatalf("импортированный секрет не помечен: %+v", c.history)
+	}
+}
+
+// capturedItem — текстовый элемент истории с хешем и ID из содержимого, как после копирования.
+func capturedItem(text string, at time.Time) windows.ClipboardContent {
+	item := windows.NewTextContent(text)
+	item.Timestamp = at
+	item.Hash = contentHash(item)
+	item.ID = item.Hash[:contentIDLength]
+	return item
+}
+
+// importedItem — элемент из другой программы с её собственным ID.
+func importedItem(id, text string, at time.Time) windows.ClipboardContent {
+	item := windows.NewTextContent(text)
+	item.ID = id
+	item.Timestamp = at
+	return item
+}
+
+func historyTexts(c *Controller) []string {
+	texts := make([]string, 0, len(c.history))
+	for _, item := range c.history {
+		texts = append(texts, item.Text)
+	}
+	return texts
+}
+
+func TestImportHistoryRequiresHistory(t *testing.T) {
+	c := newTestController()
+	if _, err := c.ImportHistory([]windows.ClipboardContent{historyItem("a", 1, time.Now())}); !errors.Is(err, ErrHistoryDisabled) {
+		t.Fatalf("ожидалась ErrHistoryDisabled, получено %v", err)
+	}
+}
+
+func TestExpireSensitiveFromHistoryAndQueue(t *testing.T) {
+	c := newTestController()
+	c.historyLimits = historyLimits{sensitiveTTL: time.Minute}
+	now := time.Now()
+	secret := historyItem("card", 16, now.Add(-2*time.Minute))
+	secret.Sensitive = "card"
+	fresh := historyItem("jwt", 100, now)
+	fresh.Sensitive = "jwt"
+	plain := historyItem("plain", 5, now.Add(-time.Hour))
+	c.history = []windows.ClipboardContent{plain, secret, fresh}
+	c.queue = []windows.ClipboardContent{secret, fresh}
+	c.currentClipboardID = "card"
+
+	if history, queue := c.expireSensitiveLocked(now); history != 1 || queue != 1 {
+		t.Fatalf("удалено %d из истории и %d из очереди, ожидалось по 1", history, queue)
+	}
+	if got := historyIDs(c); len(got) != 2 || got[0] != "plain" || got[1] != "jwt" {
+		t.Fatalf("неожиданная история: %v", got)
+	}
+	if len(c.queue) != 1 || c.queue[0].ID != "jwt" {
+		t.Fatalf("неожиданная очередь: %+v", c.queue)
+	}
+	if c.currentClipboardID != "" {
+		t.Fatal("удалённый элемент не должен оставаться текущим")
+	}
+}
+
+func TestMarkSensitiveMasksPreview(t *testing.T) {
+	c := newTestController()
+	c.detectSensitive = true
+	content := c.markSensitive(windows.NewTextContent("4111 1111 1111 1111"))
+	if content.Sensitive != "card" || strings.Contains(content.Preview, "4111") {
+		t.Fatalf("элемент не помечен как секрет: %+v", content)
+	}
+	c.detectSensitive = false
+	if content := c.markSensitive(windows.NewTextContent("4111 1111 1111 1111")); content.Sensitive != "" {
+		t.Fatal("при выключенном распознавании элемент не должен помечаться")
+	}
+}
+
+func TestFindItemPrefersQueuedOriginal(t *testing.T) {
+	c := newTestController()
+	original := windows.ClipboardContent{ID: "img", Type: windows.Image, ImagePNG: []byte("original")}
+	compacted := original
+	c.mu.Lock()
+	c.history = []windows.ClipboardContent{compacted}
+	c.queue = []windows.ClipboardContent{original}
+	c.applyCompactedLocked(compactCandidate{id: "img", data: original.ImagePNG}, []byte("jpeg"), "image/jpeg")
+
+	item, _ := c.findItemLocked("img")
+	if string(item.ImagePNG) != "original" {
+		t.Fatalf("ожидался оригинал из очереди, получено %q", item.ImagePNG)
+	}
+	c.queue = nil
+	item, _ = c.findItemLocked("img")
+	c.mu.Unlock()
+	if string(item.ImagePNG) != "jpeg" || item.ImageType != "image/jpeg" || !item.Compacted {
+		t.Fatalf("ожидалась уменьшенная копия истории, получено %+v", item)
+	}
+}
+
+func TestAppendItemDedupBumpsExisting(t *testing.T) {
+	c := newTestController()
+	c.cfg.Features.EnableClipboard = true
+	c.cfg.Features.EnableQueue = true
+	c.queueEnabled = true
+	c.dedup = dedupOptions{bump: true}
+	old := time.Now().Add(-time.Hour)
+	first, _ := c.appendItemLocked(windows.ClipboardContent{ID: "1", Type: windows.Text, Text: "одинаковый", Timestamp: old})
+	c.appendItemLocked(windows.ClipboardContent{ID: "2", Type: windows.Text, Text: "другой", Timestamp: old})
+	if first.ID != first.Hash[:contentIDLength] {
+		t.Fatalf("ID должен выводиться из хеша содержимого: %s", first.ID)
+	}
+
+	now := time.Now()
+	got, queued := c.appendItemLocked(windows.ClipboardContent{ID: "3", Type: windows.Text, Text: "одинаковый", Timestamp: now})
+	if got.ID != first.ID || queued {
+		t.Fatalf("ожидался прежний элемент без новой записи в очереди, получено %s, в очередь=%v", got.ID, queued)
+	}
+	if ids := historyIDs(c); len(ids) != 2 || ids[1] != first.ID || !c.history[1].Timestamp.Equal(now) {
+		t.Fatalf("повтор должен переместиться в конец истории с новым временем: %v", ids)
+	}
+	if len(c.queue) != 2 || c.queue[1].ID != first.ID {
+		t.Fatalf("повтор должен переместиться в конец очереди: %+v", c.queue)
+	}
+}
+
+func TestContentIDKeptByEditedItem(t *testing.T) {
+	c := newTestController()
+	c.cfg.Features.EnableClipboard = true
+	first, _ := c.appendItemLocked(windows.ClipboardContent{ID: "1", Type: windows.Text, Text: "до правки"})
+	c.history[0].Text = "после правки"
+	c.history[0].Hash = contentHash(c.history[0])
+
+	again, _ := c.appendItemLocked(windows.ClipboardContent{ID: "2", Type: windows.Text, Text: "до правки"})
+	if again.ID == first.ID || len(c.history) != 2 {
+		t.Fatalf("ID отредактированного элемента не должен переходить к новому: %s", again.ID)
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// EditItemText создаёт правку текстового элемента с новым текстом. Правка — новый
+// элемент (ID выводится из содержимого): в истории она встаёт сразу после исходного,
+// который остаётся, чтобы к нему можно было вернуться, а в очереди заменяет исходный
+// на его месте, так что PasteNext вставит уже исправленный текст. Текущий буфер
+// обмена не меняется. Неизменённый текст возвращает сам элемент.
+func (c *Controller) EditItemText(id, text string) (windows.ClipboardContent, error) {
+	if text == "" {
+		return windows.ClipboardContent{}, fmt.Errorf("пустой текст нельзя сохранить")
+	}
+
+	c.mu.Lock()
+	original, found := c.findItemLocked(id)
+	c.mu.Unlock()
+	if !found {
+		return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrItemNotFound, id)
+	}
+	if original.Type != windows.Text {
+		return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrNotText, id)
+	}
+	if original.Text == text {
+		return original, nil
+	}
+
+	revision := windows.NewTextContent(text)
+	revision.SourceApp, revision.SourceTitle = original.SourceApp, original.SourceTitle
+	revision.Revision = original.Revision + 1
+	revision.RevisionOf = original.RevisionOf
+	if revision.RevisionOf == "" {
+		revision.RevisionOf = original.ID
+	}
+	revision = c.markSensitive(revision)
+	revision.Hash = contentHash(revision)
+
+	c.mu.Lock()
+	revision = c.assignContentIDLocked(revision)
+	_, exists := c.findItemLocked(revision.ID)
+	if c.cfg.Features.EnableClipboard && !exists {
+		inserted := false
+		for i, item := range c.history {
+			if item.ID == id {
+				c.history = append(c.history[:i+1], append([]windows.ClipboardContent{revision}, c.history[i+1:]...)...)
+				inserted = true
+				break
+			}
+		}
+		if !inserted {
+			c.history = append(c.history, revision)
+		}
+		c.trimHistoryLocked(time.Now())
+	}
+	replaced := 0
+	for i, item := range c.queue {
+		if item.ID == id {
+			c.queue[i] = revision
+			replaced++
+		}
+	}
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("EditItemText: элемент %s исправлен, правка %d (id=%s, заменено в очереди: %d)", id, revision.Revision, revision.ID, replaced)
+	if replaced > 0 {
+		cb(enabled, count, mode)
+	}
+	uiCB()
+	return revision, nil
+}
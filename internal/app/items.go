@@ -0,0 +1,147 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+var (
+	// ErrItemNotFound возвращается, если элемента нет ни в истории, ни в очереди.
+	ErrItemNotFound = errors.New("элемент не найден")
+	// ErrNoThumbnail возвращается для элементов без миниатюры (не изображения или не захваченные).
+	ErrNoThumbnail = errors.New("у элемента нет миниатюры")
+	// ErrQueueDisabled возвращается, если функция очереди выключена в конфигурации.
+	ErrQueueDisabled = errors.New("очередь выключена в конфигурации")
+	// ErrHistoryDisabled возвращается, если история буфера выключена в конфигурации.
+	ErrHistoryDisabled = errors.New("история буфера выключена в конфигурации")
+	// ErrNotQueued возвращается при перестановке элемента, которого нет в очереди.
+	ErrNotQueued = errors.New("элемента нет в очереди")
+	// ErrNothingToPaste возвращается, если режим записи выключен или очередь пуста.
+	ErrNothingToPaste = errors.New("режим записи выключен или очередь пуста")
+)
+
+// findItemLocked ищет элемент по ID сначала в истории, затем в очереди.
+// Если история хранит уменьшенную копию, а очередь — оригинал, возвращается оригинал.
+// Предполагает, что мьютекс уже захвачен.
+func (c *Controller) findItemLocked(id string) (windows.ClipboardContent, bool) {
+	for _, item := range c.history {
+		if item.ID == id {
+			if item.Compacted {
+				for _, queued := range c.queue {
+					if queued.ID == id && !queued.Compacted {
+						return queued, true
+					}
+				}
+			}
+			return item, true
+		}
+	}
+	for _, item := range c.queue {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return windows.ClipboardContent{}, false
+}
+
+// GetItem возвращает копию элемента истории или очереди с полным содержимым.
+// Не захваченное изображение не дочитывается из буфера: у него пустой ImagePNG.
+func (c *Controller) GetItem(id string) (windows.ClipboardContent, error) {
+	c.mu.Lock()
+	item, found := c.findItemLocked(id)
+	c.mu.Unlock()
+
+	if !found {
+		return item, fmt.Errorf("%w: id %s", ErrItemNotFound, id)
+	}
+	item.Files = append([]string(nil), item.Files...)
+	return item, nil
+}
+
+// GetItemThumbnail возвращает миниатюру изображения и её MIME-тип.
+func (c *Controller) GetItemThumbnail(id string) ([]byte, string, error) {
+	c.mu.Lock()
+	item, found := c.findItemLocked(id)
+	c.mu.Unlock()
+
+	if !found {
+		return nil, "", fmt.Errorf("%w: id %s", ErrItemNotFound, id)
+	}
+	if len(item.Thumbnail) == 0 {
+		return nil, "", fmt.Errorf("%w: id %s", ErrNoThumbnail, id)
+	}
+	return item.Thumbnail, item.ThumbnailType, nil
+}
+
+// PushItem добавляет внешний элемент в конец очереди и в историю, минуя буфер обмена.
+// Буфер не перезаписывается, поэтому отметка собственного события не нужна и
+// текущий буфер пользователя не меняется. Элемент попадает в очередь даже
+// при выключенном режиме записи — его явно передали для вставки, поэтому и
+// повтор уже стоящего в очереди содержимого добавляется ещё раз.
+// Возвращает сохранённый элемент: ID выводится из содержимого, а для повтора
+// это уже существующий элемент истории. Текст проверяется на секреты так же,
+// как скопированный.
+func (c *Controller) PushItem(content windows.ClipboardContent) (windows.ClipboardContent, error) {
+	if !c.cfg.Features.EnableQueue {
+		return content, ErrQueueDisabled
+	}
+	if content.Type == windows.Empty {
+		return content, fmt.Errorf("пустой элемент нельзя добавить в очередь")
+	}
+	content = c.markSensitive(content)
+	content.Hash = contentHash(content)
+
+	c.mu.Lock()
+	content = c.assignContentIDLocked(content)
+	content, duplicate := c.dedupHistoryLocked(content)
+	if c.cfg.Features.EnableClipboard && !duplicate {
+		c.history = append(c.history, content)
+		c.trimHistoryLocked(time.Now())
+		c.compactImagesSoonLocked()
+	}
+	c.queue = append(c.queue, content)
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("PushItem: внешний элемент добавлен в очередь (тип=%s, размер=%d байт, предпросмотр=%q, длина очереди=%d)",
+		content.Type.String(), content.SizeBytes, content.Preview, count)
+	cb(enabled, count, mode)
+	uiCB()
+	c.emit(Event{Kind: EventEnqueue, Item: content})
+	return content, nil
+}
+
+// PushText добавляет текст в очередь через PushItem и возвращает ID нового элемента.
+func (c *Controller) PushText(text string) (string, error) {
+	if text == "" {
+		return "", fmt.Errorf("пустой текст нельзя добавить в очередь")
+	}
+	item, err := c.PushItem(windows.NewTextContent(text))
+	if err != nil {
+		return "", err
+	}
+	return item.ID, nil
+}
+
+// CopyText записывает текст в буфер обмена как обычное копирование: наблюдатель
+// добавит его в историю и, при включённом режиме записи, в очередь.
+func (c *Controller) CopyText(text string) error {
+	if text == "" {
+		return fmt.Errorf("пустой текст нельзя скопировать")
+	}
+	content := windows.NewTextContent(text)
+	if err := windows.Write(content); err != nil {
+		return err
+	}
+	c.scheduleAutoClear(content)
+	logger.Info("Текст записан в буфер обмена по внешнему запросу (длина=%d)", len(text))
+	return nil
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestPushItemAppendsToQueueAndHistory(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+
+	item, err := c.PushItem(windows.NewTextContent("из скрипта"))
+	if err != nil {
+		t.Fatalf("PushItem: %v", err)
+	}
+
+	if queue := c.GetQueue(); len(queue) != 1 || queue[0].ID != item.ID {
+		t.Fatalf("элемент должен попасть в очередь: %+v", queue)
+	}
+	if got, err := c.GetItem(item.ID); err != nil || got.Text != "из скрипта" {
+		t.Fatalf("элемент должен находиться по ID: %+v, %v", got, err)
+	}
+	if c.GetCurrentClipboardID() != "" {
+		t.Fatal("добавление в очередь не должно менять текущий элемент буфера")
+	}
+	if again, _ := c.PushItem(windows.NewTextContent("из скрипта")); again.ID != item.ID || len(c.GetHistory()) != 1 {
+		t.Fatalf("повтор должен сохранить ID и не дублировать историю: %s", again.ID)
+	}
+}
+
+func TestPushItemMarksSensitive(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+	c.detectSensitive = true
+
+	item, err := c.PushItem(windows.NewTextContent("4111 1111 1111 1111"))
+	if err != nil {
+		t.Fatalf("PushItem: %v", err)
+	}
+	if item.Sensitive != "card" || strings.Contains(item.Preview, "4111") {
+		t.Fatalf("добавленный извне секрет не помечен: %+v", item)
+	}
+	if queue := c.GetQueue(); len(queue) != 1 || queue[0].Sensitive != "card" {
+		t.Fatalf("в очереди секрет без пометки: %+v", queue)
+	}
+}
+
+func TestPushItemRequiresQueueFeature(t *testing.T) {
+	c := newTestController()
+	if _, err := c.PushItem(windows.NewTextContent("x")); !errors.Is(err, ErrQueueDisabled) {
+		t.Fatalf("ожидалась ErrQueueDisabled, получено %v", err)
+	}
+}
+
+func TestItemTextStats(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+
+	text, err := c.PushItem(windows.NewTextContent("две строки\r\nтекста"))
+	if err != nil {
+		t.Fatalf("PushItem: %v", err)
+	}
+	files, err := c.PushItem(windows.ClipboardContent{Type: windows.Files, Files: []string{`C:\a.txt`}})
+	if err != nil {
+		t.Fatalf("PushItem: %v", err)
+	}
+
+	counts := c.GetItemTextCounts()
+	if len(counts) != 1 || counts[text.ID].Words != 3 || counts[text.ID].Lines != 2 {
+		t.Fatalf("краткая статистика: %+v", counts)
+	}
+	if st, err := c.GetItemTextStats(text.ID); err != nil || st.Chars != 18 || st.CharsNoSpaces != 15 {
+		t.Fatalf("подробная статистика: %+v, %v", st, err)
+	}
+	if _, err := c.GetItemTextStats(files.ID); !errors.Is(err, ErrNotText) {
+		t.Fatalf("ожидалась ErrNotText, получено %v", err)
+	}
+}
+
+func TestEditItemTextCreatesRevision(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+
+	first, _ := c.PushItem(windows.NewTextContent("опечтака"))
+	second, _ := c.PushItem(windows.NewTextContent("второй"))
+
+	revision, err := c.EditItemText(first.ID, "опечатка")
+	if err != nil {
+		t.Fatalf("EditItemText: %v", err)
+	}
+	if revision.ID == first.ID || revision.Revision != 1 || revision.RevisionOf != first.ID {
+		t.Fatalf("правка должна быть новым элементом с номером 1: %+v", revision)
+	}
+	if queue := c.GetQueue(); len(queue) != 2 || queue[0].ID != revision.ID || queue[1].ID != second.ID {
+		t.Fatalf("правка должна заменить исходный элемент на его месте в очереди: %+v", queue)
+	}
+	if got := historyIDs(c); len(got) != 3 || got[0] != first.ID || got[1] != revision.ID {
+		t.Fatalf("правка должна встать в истории сразу после исходного: %v", got)
+	}
+
+	again, err := c.EditItemText(revision.ID, "опечатка!")
+	if err != nil || again.Revision != 2 || again.RevisionOf != first.ID {
+		t.Fatalf("следующая правка должна ссылаться на исходный элемент: %+v, %v", again, err)
+	}
+	if _, err := c.EditItemText("нет", "x"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("ожидалась ErrItemNotFound, получено %v", err)
+	}
+}
+
+func TestSetTitleSkipsEditedText(t *testing.T) {
+	c := newTestController()
+	link := windows.NewTextContent("https://github.com/serty2005/clipQueue")
+	c.history = []windows.ClipboardContent{link}
+	c.queue = []windows.ClipboardContent{link}
+
+	c.setTitle(link.ID, link.Text, "GitHub - clipQueue")
+	if c.history[0].Title != "GitHub - clipQueue" || c.queue[0].Title != "GitHub - clipQueue" {
+		t.Fatalf("заголовок не задан: %+v, %+v", c.history[0], c.queue[0])
+	}
+	c.setTitle(link.ID, "https://example.com", "Другая страница")
+	if c.history[0].Title != "GitHub - clipQueue" {
+		t.Fatal("заголовок для изменённого текста не должен применяться")
+	}
+}
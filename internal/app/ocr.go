@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/ocr"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// ErrNotImage возвращается, если распознавание запрошено для элемента без изображения.
+var ErrNotImage = errors.New("элемент не является изображением")
+
+func ocrOptionsFromConfig(cfg *config.Config) ocr.Options {
+	return ocr.Options{
+		Engine:        ocr.Engine(cfg.OCR.Engine),
+		Language:      cfg.OCR.Language,
+		TesseractPath: cfg.OCR.TesseractPath,
+		TesseractLang: cfg.OCR.TesseractLang,
+		Timeout:       time.Duration(cfg.OCR.TimeoutMs) * time.Millisecond,
+	}
+}
+
+// SetOCR применяет раздел ocr конфигурации.
+func (c *Controller) SetOCR(cfg *config.Config) {
+	opts := ocrOptionsFromConfig(cfg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ocr = opts
+}
+
+func (c *Controller) recognize(png []byte) (string, error) {
+	c.mu.Lock()
+	opts := c.ocr
+	c.mu.Unlock()
+
+	start := time.Now()
+	text, engine, err := ocr.Recognize(context.Background(), png, opts)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("OCR (%s): распознано %d символов за %s", engine, len([]rune(text)), time.Since(start).Round(time.Millisecond))
+	return text, nil
+}
+
+// OCRItem распознаёт текст изображения id и добавляет его новым текстовым
+// элементом: в историю и, при включённом режиме записи, в очередь. Буфер обмена
+// не меняется. Не захваченное изображение дочитывается из буфера, если он ещё
+// содержит его.
+func (c *Controller) OCRItem(id string) (windows.ClipboardContent, error) {
+	item, err := c.GetItem(id)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	if item.Type != windows.Image {
+		return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrNotImage, id)
+	}
+	if item, err = c.resolveImagePayload(item); err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	text, err := c.recognize(item.ImagePNG)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+
+	content := windows.NewTextContent(text)
+	c.mu.Lock()
+	content, queued := c.appendItemLocked(content)
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	if queued {
+		cb(enabled, count, mode)
+		c.emit(Event{Kind: EventEnqueue, Item: content})
+	}
+	uiCB()
+	return content, nil
+}
+
+// OCRClipboard распознаёт изображение из буфера обмена и записывает текст в буфер.
+// Наблюдатель добавит его в историю как обычное копирование.
+func (c *Controller) OCRClipboard() error {
+	content, err := windows.Read()
+	if err != nil {
+		return err
+	}
+	if content.Type != windows.Image || len(content.ImagePNG) == 0 {
+		return errors.New("в буфере обмена нет изображения")
+	}
+	text, err := c.recognize(content.ImagePNG)
+	if err != nil {
+		return err
+	}
+	result := windows.NewTextContent(text)
+	if err := windows.Write(result); err != nil {
+		return err
+	}
+	c.scheduleAutoClear(result)
+	c.notify("Текст распознан", result.Preview, false)
+	return nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/pagetitle"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// titleClient загружает страницы для clipboard.fetch_titles; время ожидания
+// задаётся контекстом каждого запроса.
+var titleClient = &http.Client{}
+
+// titleFetchOptions — clipboard.fetch_titles.
+type titleFetchOptions struct {
+	enabled bool
+	timeout time.Duration
+}
+
+func titleFetchOptionsFromConfig(cfg *config.Config) titleFetchOptions {
+	timeout := time.Duration(cfg.Clipboard.FetchTitles.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return titleFetchOptions{enabled: cfg.Clipboard.FetchTitles.Enabled, timeout: timeout}
+}
+
+// fetchTitleSoon в фоне загружает заголовок страницы, если новый элемент — одна
+// ссылка, и делает его отображаемым именем элемента. Секреты не отправляются.
+func (c *Controller) fetchTitleSoon(content windows.ClipboardContent) {
+	c.mu.Lock()
+	opts := c.titles
+	c.mu.Unlock()
+	if !opts.enabled || content.Type != windows.Text || content.Sensitive != "" || content.Title != "" {
+		return
+	}
+	link := pagetitle.SingleURL(content.Text)
+	if link == "" {
+		return
+	}
+	crash.Go("pagetitle.fetch", func() {
+		title, err := pagetitle.Fetch(context.Background(), titleClient, link, opts.timeout)
+		if err != nil {
+			logger.Debug("Заголовок страницы %s не получен: %v", link, err)
+			return
+		}
+		c.setTitle(content.ID, content.Text, title)
+	})
+}
+
+// setTitle задаёт отображаемое имя копиям элемента id в истории и очереди,
+// если их текст не изменился, пока загружалась страница.
+func (c *Controller) setTitle(id, text, title string) {
+	c.mu.Lock()
+	updated := 0
+	for _, items := range [][]windows.ClipboardContent{c.history, c.queue} {
+		for i := range items {
+			if items[i].ID == id && items[i].Text == text {
+				items[i].Title = title
+				updated++
+			}
+		}
+	}
+	uiCB := c.onUIRefresh
+	c.mu.Unlock()
+	if updated == 0 {
+		return
+	}
+	logger.Debug("Элементу %s задан заголовок страницы %q", id, title)
+	uiCB()
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/textclean"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// SetPasteMethods применяет clipboard.paste_methods и clipboard.line_endings:
+// способ вставки и переводы строк по приложению.
+func (c *Controller) SetPasteMethods(cfg *config.Config) {
+	rules := append([]config.PasteMethodRule{}, cfg.Clipboard.PasteMethods...)
+	endings := lineEndingOptionsFromConfig(cfg)
+	c.mu.Lock()
+	c.pasteMethods = rules
+	c.lineEndings = endings
+	c.mu.Unlock()
+}
+
+// lineEndingOptions — clipboard.line_endings.
+type lineEndingOptions struct {
+	fallback textclean.LineEnding
+	rules    []config.LineEndingRule
+}
+
+func lineEndingOptionsFromConfig(cfg *config.Config) lineEndingOptions {
+	return lineEndingOptions{
+		fallback: textclean.LineEnding(cfg.Clipboard.LineEndings.Default),
+		rules:    append([]config.LineEndingRule{}, cfg.Clipboard.LineEndings.Rules...),
+	}
+}
+
+// lineEndingFor возвращает перевод строки для процесса: режим первого правила
+// с тем же именем исполняемого файла (без учёта регистра), иначе общий.
+func lineEndingFor(opts lineEndingOptions, process string) textclean.LineEnding {
+	key := targetKey(process)
+	for _, rule := range opts.rules {
+		if key != "" && targetKey(rule.Process) == key {
+			return textclean.LineEnding(rule.Mode)
+		}
+	}
+	return opts.fallback
+}
+
+// forPasteTarget готовит текстовый элемент к вставке в target: пропускает текст
+// через преобразование из правила clipboard.paste_methods и приводит переводы
+// строк по clipboard.line_endings. Элемент в истории и очереди не меняется.
+func (c *Controller) forPasteTarget(item windows.ClipboardContent, target windows.WindowInfo) windows.ClipboardContent {
+	if item.Type != windows.Text {
+		return item
+	}
+	c.mu.Lock()
+	opts := c.lineEndings
+	rules := c.pasteMethods
+	transforms := c.transforms
+	c.mu.Unlock()
+	if rule, ok := pasteRuleFor(rules, target.ProcessName); ok && rule.Transform != "" {
+		item.Text = transformForPaste(transforms, rule.Transform, item.Text, target.ProcessName)
+	}
+	ending := lineEndingFor(opts, target.ProcessName)
+	if text := textclean.ConvertLineEndings(item.Text, ending); text != item.Text {
+		logger.Debug("Переводы строк приведены к %s для %s", ending, target.ProcessName)
+		item.Text = text
+	}
+	return item
+}
+
+// transformForPaste пропускает text через преобразование name. Если преобразования
+// нет или оно завершилось ошибкой, текст вставляется как есть.
+func transformForPaste(rules []transformRule, name, text, process string) string {
+	for _, rule := range rules {
+		if rule.Name != name {
+			continue
+		}
+		out, err := rule.run(text)
+		if err != nil {
+			logger.Warn("Преобразование %s перед вставкой в %s не применено: %v", name, process, err)
+			return text
+		}
+		logger.Debug("Преобразование %s применено перед вставкой в %s", name, process)
+		return out
+	}
+	logger.Warn("Преобразование %s для %s не найдено в transforms", name, process)
+	return text
+}
+
+// pasteRuleFor возвращает первое правило clipboard.paste_methods с тем же именем
+// исполняемого файла (без учёта регистра).
+func pasteRuleFor(rules []config.PasteMethodRule, process string) (config.PasteMethodRule, bool) {
+	key := targetKey(process)
+	for _, rule := range rules {
+		if key != "" && targetKey(rule.Process) == key {
+			return rule, true
+		}
+	}
+	return config.PasteMethodRule{}, false
+}
+
+// pasteMethodFor возвращает способ вставки для процесса по правилам, иначе Ctrl+V.
+func pasteMethodFor(rules []config.PasteMethodRule, process string) string {
+	if rule, ok := pasteRuleFor(rules, process); ok {
+		return rule.Method
+	}
+	return pasteMethodCtrlV
+}
+
+// pasteMethod выбирает способ вставки в target: по правилу clipboard.paste_methods,
+// а без правила — выученный по прошлым вставкам в это приложение.
+func (c *Controller) pasteMethod(target windows.WindowInfo) string {
+	c.mu.Lock()
+	rules := c.pasteMethods
+	c.mu.Unlock()
+	if _, ok := pasteRuleFor(rules, target.ProcessName); ok {
+		return pasteMethodFor(rules, target.ProcessName)
+	}
+	if learned, ok := c.targets.learned(target.ProcessName, c.cfg.Clipboard.RestoreDelayMs); ok {
+		return learned.Method
+	}
+	return pasteMethodCtrlV
+}
+
+// targetRestoreDelay возвращает ожидание чтения буфера для target из правила
+// clipboard.paste_methods, а без правила — выученное; 0 — общая задержка.
+func (c *Controller) targetRestoreDelay(target windows.WindowInfo) time.Duration {
+	c.mu.Lock()
+	rules := c.pasteMethods
+	c.mu.Unlock()
+	if rule, ok := pasteRuleFor(rules, target.ProcessName); ok {
+		return time.Duration(rule.RestoreDelayMs) * time.Millisecond
+	}
+	if learned, ok := c.targets.learned(target.ProcessName, c.cfg.Clipboard.RestoreDelayMs); ok {
+		return time.Duration(learned.RestoreDelayMs) * time.Millisecond
+	}
+	return 0
+}
+
+// sendPaste вставляет содержимое буфера в активное окно выбранным способом.
+func sendPaste(method string) error {
+	if method == config.PasteMethodMessage {
+		logger.Debug("Отправка WM_PASTE элементу с фокусом")
+		return windows.SendPasteMessage()
+	}
+	logger.Debug("Sending Ctrl+V keystroke")
+	return windows.SendCtrlV()
+}
+
+// waitPasteRead ждёт, пока окно-получатель прочитает записанный для вставки буфер,
+// чтобы восстановить прежнее содержимое не раньше и не позже нужного. Если чтение
+// не замечено, ожидание длится timeout: clipboard.restore_delay_ms или
+// remote_session.restore_delay_ms для удалённого сеанса.
+func (c *Controller) waitPasteRead(seq uint32, target windows.WindowInfo, timeout time.Duration) {
+	start := time.Now()
+	if windows.WaitClipboardRead(seq, target.ProcessID, timeout) {
+		logger.Debug("Получатель %s прочитал буфер через %v после вставки", target.ProcessName, time.Since(start))
+		return
+	}
+	logger.Debug("Чтение буфера получателем %s не замечено за %v", target.ProcessName, time.Since(start))
+}
+
+// pasteText вставляет текст в активное окно через буфер обмена и затем
+// восстанавливает прежнее содержимое буфера. Очередь и история не меняются.
+func (c *Controller) pasteText(text string) error {
+	// Сохраняем текущий буфер обмена
+	oldContent, err := windows.Read()
+	if err != nil {
+		logger.Error("Failed to read current clipboard: %v", err)
+		return err
+	}
+
+	target := windows.GetForegroundWindowInfo()
+	content := c.forPasteTarget(windows.ClipboardContent{Type: windows.Text, Text: text}, target)
+	if err := windows.Write(content); err != nil {
+		logger.Error("Failed to write text to clipboard: %v", err)
+		return err
+	}
+	pastedSeq := windows.GetClipboardSequenceNumber()
+	c.addSelfEvent(pastedSeq)
+
+	// Дайте время для обновления буфера обмена
+	timing := c.pasteTiming(target)
+	time.Sleep(max(100*time.Millisecond, timing.settle))
+
+	// Отправляем Ctrl+V или WM_PASTE, если для приложения задано правило
+	if err := sendPaste(c.pasteMethod(target)); err != nil {
+		logger.Error("Failed to paste text: %v", err)
+		// Попытка восстановить буфер даже при ошибке
+		_ = windows.Write(oldContent)
+		c.addSelfEvent(windows.GetClipboardSequenceNumber())
+		return err
+	}
+
+	// Дожидаемся, пока получатель прочитает буфер
+	c.waitPasteRead(pastedSeq, target, timing.restore)
+
+	// Восстанавливаем исходный буфер обмена
+	if err := windows.Write(oldContent); err != nil {
+		logger.Error("Failed to restore clipboard: %v", err)
+		return err
+	}
+	c.addSelfEvent(windows.GetClipboardSequenceNumber())
+	return nil
+}
@@ -0,0 +1,407 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+const (
+	maxPasteRecords     = 200
+	pasteTargetsFile    = "paste_targets.json"
+	pasteMethodCtrlV    = config.PasteMethodCtrlV
+	maxLearnedDelayMult = 4
+	// minLearnedPastes — сколько удачных вставок нужно, чтобы выученные способ
+	// и задержка применялись к приложению без правила в clipboard.paste_methods.
+	minLearnedPastes = 5
+	// minLearnedTransformUses — сколько раз преобразование должно примениться
+	// в приложении, чтобы его предложить.
+	minLearnedTransformUses = 3
+	// pasteTargetsSaveDelay — через сколько после вставки профили пишутся на диск:
+	// серия вставок сохраняется одной записью.
+	pasteTargetsSaveDelay = 5 * time.Second
+)
+
+// ErrTargetNotFound возвращается, если о приложении-получателе ещё ничего не известно.
+var ErrTargetNotFound = errors.New("приложение-получатель не найдено")
+
+// PasteRecord описывает одну вставку элемента очереди или макроса в окно-получатель.
+// Method заполняется только для вставки через буфер (paste или wm_paste), Transform —
+// для макроса-преобразования; остальные макросы попадают только в журнал.
+type PasteRecord struct {
+	ItemID    string    `json:"itemId,omitempty"`
+	Macro     string    `json:"macro,omitempty"`
+	Mode      string    `json:"mode,omitempty"` // Режим макроса
+	Process   string    `json:"process"`
+	Title     string    `json:"title"`
+	Method    string    `json:"method,omitempty"`
+	Transform string    `json:"transform,omitempty"`
+	Success   bool      `json:"success"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TargetProfile накапливает статистику вставок в одно приложение.
+type TargetProfile struct {
+	Process    string         `json:"process"`
+	Pastes     int            `json:"pastes"`
+	Failures   int            `json:"failures"`
+	Methods    map[string]int `json:"methods"`
+	Transforms map[string]int `json:"transforms,omitempty"` // Преобразования, запущенные в этом приложении
+	LastUsed   time.Time      `json:"lastUsed"`
+}
+
+// TargetSuggestion содержит выученные настройки вставки для приложения.
+type TargetSuggestion struct {
+	Process        string    `json:"process"`
+	Pastes         int       `json:"pastes"`
+	Failures       int       `json:"failures"`
+	Method         string    `json:"method"`
+	RestoreDelayMs int       `json:"restoreDelayMs"`
+	Transform      string    `json:"transform,omitempty"`
+	Applied        bool      `json:"applied"` // Правило clipboard.paste_methods уже совпадает с рекомендацией
+	LastUsed       time.Time `json:"lastUsed"`
+}
+
+// Rule возвращает правило clipboard.paste_methods с выученными настройками.
+// Задержка записывается, только если она больше общей baseRestoreDelayMs.
+func (s TargetSuggestion) Rule(baseRestoreDelayMs int) config.PasteMethodRule {
+	rule := config.PasteMethodRule{Process: s.Process, Method: s.Method, Transform: s.Transform}
+	if s.RestoreDelayMs > baseRestoreDelayMs {
+		rule.RestoreDelayMs = s.RestoreDelayMs
+	}
+	return rule
+}
+
+// isPasteMethod сообщает, что method — способ вставки через буфер, а не другой режим макроса.
+func isPasteMethod(method string) bool {
+	return method == config.PasteMethodCtrlV || method == config.PasteMethodMessage
+}
+
+// pasteTargetStore хранит недавние вставки и профили приложений-получателей.
+// Профили сохраняются в App.DataDir, чтобы обучение переживало перезапуск.
+type pasteTargetStore struct {
+	mu        sync.Mutex
+	path      string
+	records   []PasteRecord
+	profiles  map[string]*TargetProfile
+	saveTimer *time.Timer // Отложенная запись профилей; nil — изменений нет
+
+	writeMu sync.Mutex // Не даёт таймеру и flush писать файл одновременно
+}
+
+func newPasteTargetStore(dataDir string) *pasteTargetStore {
+	store := &pasteTargetStore{
+		path:     filepath.Join(config.ResolvePath(dataDir), pasteTargetsFile),
+		profiles: make(map[string]*TargetProfile),
+	}
+	store.load()
+	return store
+}
+
+func targetKey(process string) string {
+	return strings.ToLower(strings.TrimSpace(process))
+}
+
+func (s *pasteTargetStore) load() {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("Не удалось прочитать профили приложений-получателей: %v", err)
+		}
+		return
+	}
+	var profiles []*TargetProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		logger.Warn("Повреждён файл профилей приложений-получателей %s: %v", s.path, err)
+		return
+	}
+	for _, p := range profiles {
+		if p == nil || targetKey(p.Process) == "" {
+			continue
+		}
+		if p.Methods == nil {
+			p.Methods = make(map[string]int)
+		}
+		// Ранние версии записывали в способы и режимы макросов (type, script и другие).
+		for m := range p.Methods {
+			if !isPasteMethod(m) {
+				delete(p.Methods, m)
+			}
+		}
+		if p.Transforms == nil {
+			p.Transforms = make(map[string]int)
+		}
+		s.profiles[targetKey(p.Process)] = p
+	}
+}
+
+// scheduleSaveLocked откладывает запись профилей на pasteTargetsSaveDelay, чтобы
+// не переписывать файл после каждой вставки. Предполагает, что мьютекс уже захвачен.
+func (s *pasteTargetStore) scheduleSaveLocked() {
+	if s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(pasteTargetsSaveDelay, s.flush)
+	}
+}
+
+// flush сразу записывает отложенные изменения профилей. Файл пишется через
+// временный и переименовывается, чтобы обрыв записи не испортил выученное.
+func (s *pasteTargetStore) flush() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	if s.saveTimer == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.saveTimer.Stop()
+	s.saveTimer = nil
+	profiles := make([]*TargetProfile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return targetKey(profiles[i].Process) < targetKey(profiles[j].Process) })
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Warn("Не удалось сериализовать профили приложений-получателей: %v", err)
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger.Warn("Не удалось сохранить профили приложений-получателей: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		logger.Warn("Не удалось сохранить профили приложений-получателей: %v", err)
+	}
+}
+
+func (s *pasteTargetStore) record(rec PasteRecord) {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.records) >= maxPasteRecords {
+		s.records = s.records[1:]
+	}
+	s.records = append(s.records, rec)
+
+	key := targetKey(rec.Process)
+	learnMethod := isPasteMethod(rec.Method)
+	learnTransform := rec.Transform != "" && rec.Success
+	if key == "" || !learnMethod && !learnTransform {
+		return
+	}
+	p, ok := s.profiles[key]
+	if !ok {
+		p = &TargetProfile{Process: rec.Process, Methods: make(map[string]int), Transforms: make(map[string]int)}
+		s.profiles[key] = p
+	}
+	p.LastUsed = rec.Timestamp
+	if learnMethod {
+		p.Pastes++
+		if rec.Success {
+			p.Methods[rec.Method]++
+		} else {
+			p.Failures++
+		}
+	}
+	if learnTransform {
+		p.Transforms[rec.Transform]++
+	}
+	s.scheduleSaveLocked()
+}
+
+// recent возвращает последние limit записей, начиная с самой свежей.
+func (s *pasteTargetStore) recent(limit int) []PasteRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 || limit > len(s.records) {
+		limit = len(s.records)
+	}
+	result := make([]PasteRecord, 0, limit)
+	for i := len(s.records) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, s.records[i])
+	}
+	return result
+}
+
+// itemTargets возвращает для каждого элемента список приложений, куда он вставлялся.
+func (s *pasteTargetStore) itemTargets() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string][]string)
+	for _, rec := range s.records {
+		if rec.ItemID == "" || rec.Process == "" || !rec.Success {
+			continue
+		}
+		known := false
+		for _, p := range result[rec.ItemID] {
+			if strings.EqualFold(p, rec.Process) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			result[rec.ItemID] = append(result[rec.ItemID], rec.Process)
+		}
+	}
+	return result
+}
+
+// suggestions выводит рекомендации для всех известных приложений, самые частые — первыми.
+func (s *pasteTargetStore) suggestions(baseRestoreDelayMs int) []TargetSuggestion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]TargetSuggestion, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		result = append(result, suggestFromProfile(p, baseRestoreDelayMs))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Pastes != result[j].Pastes {
+			return result[i].Pastes > result[j].Pastes
+		}
+		return targetKey(result[i].Process) < targetKey(result[j].Process)
+	})
+	return result
+}
+
+// learned возвращает выученные настройки приложения, если в него было достаточно
+// удачных вставок, чтобы применять их без правила.
+func (s *pasteTargetStore) learned(process string, baseRestoreDelayMs int) (TargetSuggestion, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.profiles[targetKey(process)]
+	if !ok || p.Pastes-p.Failures < minLearnedPastes {
+		return TargetSuggestion{}, false
+	}
+	return suggestFromProfile(p, baseRestoreDelayMs), true
+}
+
+// suggestFromProfile выбирает самый успешный способ вставки, увеличивает задержку
+// восстановления буфера пропорционально доле неудачных вставок и предлагает
+// преобразование, которое чаще других запускалось в этом приложении.
+func suggestFromProfile(p *TargetProfile, baseRestoreDelayMs int) TargetSuggestion {
+	suggestion := TargetSuggestion{
+		Process:        p.Process,
+		Pastes:         p.Pastes,
+		Failures:       p.Failures,
+		Method:         pasteMethodCtrlV,
+		RestoreDelayMs: baseRestoreDelayMs,
+		LastUsed:       p.LastUsed,
+	}
+
+	best := 0
+	methods := make([]string, 0, len(p.Methods))
+	for m := range p.Methods {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	for _, m := range methods {
+		if p.Methods[m] > best {
+			best = p.Methods[m]
+			suggestion.Method = m
+		}
+	}
+
+	if p.Pastes > 0 && p.Failures > 0 {
+		delay := baseRestoreDelayMs + 2*baseRestoreDelayMs*p.Failures/p.Pastes
+		suggestion.RestoreDelayMs = min(delay, baseRestoreDelayMs*maxLearnedDelayMult)
+	}
+
+	uses := minLearnedTransformUses - 1
+	transforms := make([]string, 0, len(p.Transforms))
+	for name := range p.Transforms {
+		transforms = append(transforms, name)
+	}
+	sort.Strings(transforms)
+	for _, name := range transforms {
+		if p.Transforms[name] > uses {
+			uses = p.Transforms[name]
+			suggestion.Transform = name
+		}
+	}
+	return suggestion
+}
+
+// recordPaste дополняет запись сведениями об окне-получателе и сохраняет её.
+// В журнал аудита попадает каждая вставка, в профили — только с известным окном.
+func (c *Controller) recordPaste(target windows.WindowInfo, rec PasteRecord) {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	if target.HWND != 0 {
+		rec.Process = target.ProcessName
+		rec.Title = target.Title
+	}
+	c.audit.append(rec)
+	if target.HWND == 0 {
+		return
+	}
+	c.targets.record(rec)
+	logger.Debug("Вставка записана: процесс=%q, способ=%s, успех=%v", rec.Process, rec.Method, rec.Success)
+}
+
+// GetTargetSuggestions возвращает выученные настройки вставки для известных приложений
+// и отмечает те, для которых правило clipboard.paste_methods уже совпадает с ними.
+func (c *Controller) GetTargetSuggestions() []TargetSuggestion {
+	base := c.cfg.Clipboard.RestoreDelayMs
+	suggestions := c.targets.suggestions(base)
+	c.mu.Lock()
+	rules := c.pasteMethods
+	c.mu.Unlock()
+	for i, s := range suggestions {
+		rule, ok := pasteRuleFor(rules, s.Process)
+		suggestions[i].Applied = ok && rule == s.Rule(base)
+	}
+	return suggestions
+}
+
+// TargetRule возвращает правило clipboard.paste_methods с выученными настройками
+// приложения process.
+func (c *Controller) TargetRule(process string) (config.PasteMethodRule, error) {
+	base := c.cfg.Clipboard.RestoreDelayMs
+	for _, s := range c.targets.suggestions(base) {
+		if targetKey(s.Process) == targetKey(process) {
+			return s.Rule(base), nil
+		}
+	}
+	return config.PasteMethodRule{}, fmt.Errorf("%w: %s", ErrTargetNotFound, process)
+}
+
+// GetPasteRecords возвращает последние вставки, начиная с самой свежей.
+func (c *Controller) GetPasteRecords(limit int) []PasteRecord {
+	return c.targets.recent(limit)
+}
+
+// GetItemPasteTargets возвращает приложения, в которые вставлялся каждый элемент истории.
+func (c *Controller) GetItemPasteTargets() map[string][]string {
+	return c.targets.itemTargets()
+}
+
+// FlushPasteTargets сразу записывает профили приложений-получателей, не дожидаясь
+// отложенной записи; вызывается при выходе и завершении сеанса Windows.
+func (c *Controller) FlushPasteTargets() {
+	c.targets.flush()
+}
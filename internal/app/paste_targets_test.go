@@ -0,0 +1,166 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/textclean"
+)
+
+func TestSuggestFromProfilePicksMostSuccessfulMethod(t *testing.T) {
+	p := &TargetProfile{
+		Process: "notepad.exe",
+		Pastes:  5,
+		Methods: map[string]int{"paste": 1, "wm_paste": 3},
+	}
+
+	s := suggestFromProfile(p, 100)
+	if s.Method != "wm_paste" {
+		t.Fatalf("ожидался способ wm_paste, получен %q", s.Method)
+	}
+	if s.RestoreDelayMs != 100 {
+		t.Fatalf("без ошибок задержка не должна меняться, получено %d", s.RestoreDelayMs)
+	}
+}
+
+func TestSuggestFromProfileRaisesDelayOnFailures(t *testing.T) {
+	p := &TargetProfile{Process: "mstsc.exe", Pastes: 4, Failures: 2, Methods: map[string]int{"paste": 2}}
+	if got := suggestFromProfile(p, 100).RestoreDelayMs; got != 200 {
+		t.Fatalf("ожидалась задержка 200 мс, получено %d", got)
+	}
+
+	p = &TargetProfile{Process: "mstsc.exe", Pastes: 10, Failures: 10, Methods: map[string]int{}}
+	if got := suggestFromProfile(p, 100).RestoreDelayMs; got != 300 {
+		t.Fatalf("ожидалась задержка 300 мс, получено %d", got)
+	}
+}
+
+func TestPasteTargetStorePersistsProfiles(t *testing.T) {
+	dir := t.TempDir()
+	store := newPasteTargetStore(dir)
+	store.record(PasteRecord{ItemID: "1", Process: "Code.exe", Method: "paste", Success: true})
+	store.record(PasteRecord{ItemID: "1", Process: "code.exe", Method: "paste", Success: true})
+	store.record(PasteRecord{ItemID: "2", Process: "code.exe", Method: "paste", Success: false})
+
+	if targets := store.itemTargets(); len(targets["1"]) != 1 || len(targets["2"]) != 0 {
+		t.Fatalf("неожиданные приложения элементов: %v", targets)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, pasteTargetsFile)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("профили должны записываться отложенно, а не после каждой вставки: %v", err)
+	}
+	store.flush()
+	if _, err := os.Stat(filepath.Join(dir, pasteTargetsFile+".tmp")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("временный файл должен переименовываться: %v", err)
+	}
+
+	reloaded := newPasteTargetStore(dir)
+	suggestions := reloaded.suggestions(100)
+	if len(suggestions) != 1 {
+		t.Fatalf("ожидался один профиль, получено %d", len(suggestions))
+	}
+	if suggestions[0].Pastes != 3 || suggestions[0].Failures != 1 {
+		t.Fatalf("неожиданная статистика профиля: %+v", suggestions[0])
+	}
+	if len(reloaded.recent(0)) != 0 {
+		t.Fatal("журнал вставок не должен сохраняться между запусками")
+	}
+}
+
+func TestPasteTargetStoreLearnsOnlyPasteMethodsAndTransforms(t *testing.T) {
+	store := newPasteTargetStore(t.TempDir())
+	store.record(PasteRecord{Macro: "typed", Mode: "type_hw", Process: "mstsc.exe", Success: true})
+	store.record(PasteRecord{Macro: "lua", Mode: "script", Process: "mstsc.exe", Success: false})
+	if got := store.suggestions(100); len(got) != 0 {
+		t.Fatalf("макросы без вставки через буфер не должны создавать профиль: %+v", got)
+	}
+	if len(store.recent(0)) != 2 {
+		t.Fatal("макросы без вставки должны оставаться в журнале")
+	}
+
+	for range minLearnedTransformUses {
+		store.record(PasteRecord{Macro: "jq", Mode: "transform", Process: "Code.exe", Transform: "jq", Success: true})
+	}
+	store.record(PasteRecord{Macro: "upper", Mode: "transform", Process: "code.exe", Transform: "upper", Success: true})
+	store.record(PasteRecord{Macro: "snippet", Mode: "paste", Process: "code.exe", Method: "wm_paste", Success: true})
+
+	got := store.suggestions(100)
+	if len(got) != 1 || got[0].Transform != "jq" || got[0].Method != "wm_paste" || got[0].Pastes != 1 {
+		t.Fatalf("неожиданная рекомендация: %+v", got)
+	}
+	if rule := got[0].Rule(100); rule.Process != "Code.exe" || rule.Method != "wm_paste" || rule.Transform != "jq" || rule.RestoreDelayMs != 0 {
+		t.Fatalf("неожиданное правило: %+v", rule)
+	}
+}
+
+func TestPasteTargetStoreLearnedNeedsEnoughPastes(t *testing.T) {
+	store := newPasteTargetStore(t.TempDir())
+	for range minLearnedPastes - 1 {
+		store.record(PasteRecord{Process: "cmd.exe", Method: "wm_paste", Success: true})
+	}
+	if _, ok := store.learned("cmd.exe", 100); ok {
+		t.Fatal("мало вставок — выученные настройки не должны применяться")
+	}
+	store.record(PasteRecord{Process: "cmd.exe", Method: "wm_paste", Success: true})
+	if s, ok := store.learned("CMD.EXE", 100); !ok || s.Method != "wm_paste" {
+		t.Fatalf("ожидался выученный wm_paste, получено %+v, %v", s, ok)
+	}
+}
+
+func TestPasteTargetStoreDropsLegacyModes(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"process":"mstsc.exe","pastes":4,"methods":{"type_hw":3,"paste":1}}]`
+	if err := os.WriteFile(filepath.Join(dir, pasteTargetsFile), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := newPasteTargetStore(dir).suggestions(100)
+	if len(got) != 1 || got[0].Method != "paste" {
+		t.Fatalf("режимы макросов не должны считаться способами вставки: %+v", got)
+	}
+}
+
+func TestTargetSuggestionRuleKeepsLongerDelay(t *testing.T) {
+	s := TargetSuggestion{Process: "mstsc.exe", Method: "paste", RestoreDelayMs: 200}
+	if rule := s.Rule(100); rule.RestoreDelayMs != 200 {
+		t.Fatalf("увеличенная задержка должна попасть в правило: %+v", rule)
+	}
+	if rule := s.Rule(200); rule.RestoreDelayMs != 0 {
+		t.Fatalf("задержка, равная общей, не должна записываться: %+v", rule)
+	}
+}
+
+func TestPasteMethodFor(t *testing.T) {
+	rules := []config.PasteMethodRule{
+		{Process: "cmd.exe", Method: config.PasteMethodMessage},
+		{Process: "CMD.EXE", Method: config.PasteMethodCtrlV},
+	}
+	if got := pasteMethodFor(rules, "Cmd.exe"); got != config.PasteMethodMessage {
+		t.Fatalf("ожидалось первое совпавшее правило wm_paste, получено %q", got)
+	}
+	if got := pasteMethodFor(rules, "notepad.exe"); got != config.PasteMethodCtrlV {
+		t.Fatalf("без правила ожидался Ctrl+V, получено %q", got)
+	}
+	if got := pasteMethodFor(rules, ""); got != config.PasteMethodCtrlV {
+		t.Fatalf("для неизвестного окна ожидался Ctrl+V, получено %q", got)
+	}
+}
+
+func TestLineEndingFor(t *testing.T) {
+	opts := lineEndingOptions{
+		fallback: textclean.LF,
+		rules:    []config.LineEndingRule{{Process: "notepad.exe", Mode: "crlf"}, {Process: "Code.exe", Mode: "keep"}},
+	}
+	if got := lineEndingFor(opts, "NOTEPAD.EXE"); got != textclean.CRLF {
+		t.Fatalf("ожидалось правило crlf, получено %q", got)
+	}
+	if got := lineEndingFor(opts, "code.exe"); got != textclean.KeepLineEndings {
+		t.Fatalf("ожидалось правило keep, получено %q", got)
+	}
+	if got := lineEndingFor(opts, "WindowsTerminal.exe"); got != textclean.LF {
+		t.Fatalf("без правила ожидался общий режим lf, получено %q", got)
+	}
+}
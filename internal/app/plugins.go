@@ -0,0 +1,54 @@
+package app
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// Plugins — пользовательские скрипты, которые могут изменить или отбросить новый
+// элемент, изменить или отменить вставку и выполнить действие макроса режима script.
+type Plugins interface {
+	Capture(content windows.ClipboardContent) (windows.ClipboardContent, bool)
+	BeforePaste(content windows.ClipboardContent, target windows.WindowInfo) (windows.ClipboardContent, bool)
+	RunAction(name, text string) error
+}
+
+// errNoPlugins возвращается макросом режима script, когда плагины выключены.
+var errNoPlugins = errors.New("плагины выключены: включите plugins.enabled в конфигурации")
+
+// SetPlugins задаёт загруженные плагины; nil отключает их.
+func (c *Controller) SetPlugins(p Plugins) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.plugins = p
+}
+
+func (c *Controller) getPlugins() Plugins {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.plugins
+}
+
+// requeue возвращает элемент, вставку которого отменил плагин, на то место
+// очереди, откуда он был взят. В режиме ROUND_ROBIN элемент уже стоит в конце
+// очереди, поэтому он переносится обратно в начало.
+func (c *Controller) requeue(item windows.ClipboardContent, index int) {
+	c.mu.Lock()
+	if c.orderStrategy == OrderRoundRobin {
+		if last := len(c.queue) - 1; last >= 0 && c.queue[last].ID == item.ID {
+			c.queue = c.queue[:last]
+		}
+	}
+	index = min(index, len(c.queue))
+	c.queue = slices.Insert(c.queue, index, item)
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+	cb(enabled, count, mode)
+	uiCB()
+}
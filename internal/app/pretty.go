@@ -0,0 +1,70 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/prettyprint"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// FormatItem переформатирует JSON или XML из текстового элемента id с отступами
+// и добавляет результат новым элементом: в историю и, при включённом режиме
+// записи, в очередь. Исходный элемент и буфер обмена не меняются.
+func (c *Controller) FormatItem(id string) (windows.ClipboardContent, error) {
+	item, err := c.GetItem(id)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	if item.Type != windows.Text {
+		return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrNotText, id)
+	}
+	text, format, err := prettyprint.Pretty(item.Text)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	logger.Info("Элемент %s отформатирован как %s", id, format)
+
+	content := windows.NewTextContent(text)
+	content.SourceApp, content.SourceTitle = item.SourceApp, item.SourceTitle
+	c.mu.Lock()
+	content, queued := c.appendItemLocked(content)
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	if queued {
+		cb(enabled, count, mode)
+		c.emit(Event{Kind: EventEnqueue, Item: content})
+	}
+	uiCB()
+	return content, nil
+}
+
+// FormatClipboard переформатирует JSON или XML из буфера обмена и записывает
+// результат в буфер. Наблюдатель добавит его в историю как обычное копирование.
+func (c *Controller) FormatClipboard() error {
+	content, err := windows.Read()
+	if err != nil {
+		return err
+	}
+	if content.Type != windows.Text {
+		return errors.New("в буфере обмена нет текста")
+	}
+	text, format, err := prettyprint.Pretty(content.Text)
+	if err != nil {
+		return err
+	}
+	result := windows.NewTextContent(text)
+	if err := windows.Write(result); err != nil {
+		return err
+	}
+	c.scheduleAutoClear(result)
+	c.notify(strings.ToUpper(string(format))+" отформатирован", result.Preview, false)
+	return nil
+}
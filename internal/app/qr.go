@@ -0,0 +1,58 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// ErrNotText возвращается, если действие требует текста, а элемент — изображение.
+var ErrNotText = errors.New("элемент не содержит текста")
+
+// QRItem кодирует текст элемента id в QR-код. С toQueue изображение добавляется
+// в очередь и историю, иначе записывается в буфер обмена, откуда наблюдатель
+// добавит его в историю как обычное копирование. Список файлов кодируется
+// путями по одному на строку.
+func (c *Controller) QRItem(id string, toQueue bool) (windows.ClipboardContent, error) {
+	item, err := c.GetItem(id)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	var text string
+	switch item.Type {
+	case windows.Text:
+		text = item.Text
+	case windows.Files:
+		text = strings.Join(item.Files, "\r\n")
+	default:
+		return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrNotText, id)
+	}
+
+	img, err := imaging.QRCode(text, 0)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	content, err := windows.NewImageContent(img)
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	content.Preview = "QR: " + item.Preview
+
+	if toQueue {
+		if content, err = c.PushItem(content); err != nil {
+			return windows.ClipboardContent{}, err
+		}
+		logger.Info("QR-код из элемента %s (длина текста %d) добавлен в очередь", id, len(text))
+		return content, nil
+	}
+	if err := windows.Write(content); err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	c.scheduleAutoClear(content)
+	logger.Info("QR-код из элемента %s (длина текста %d) записан в буфер обмена", id, len(text))
+	return content, nil
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+func queueIdleTimeoutFromConfig(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.Queue.AutoDisableMinutes) * time.Minute
+}
+
+// SetQueueAutoDisable применяет queue.auto_disable_minutes. Отсчёт идёт от
+// последней вставки или включения режима записи, а не от смены настройки.
+func (c *Controller) SetQueueAutoDisable(cfg *config.Config) {
+	timeout := queueIdleTimeoutFromConfig(cfg)
+	c.mu.Lock()
+	c.queueIdleTimeout = timeout
+	c.mu.Unlock()
+}
+
+// markQueueActivityLocked откладывает автоматическое выключение режима записи.
+func (c *Controller) markQueueActivityLocked(now time.Time) {
+	c.lastQueueActivity = now
+}
+
+// disableIdleQueue выключает режим записи, если с последней вставки прошло больше
+// queue.auto_disable_minutes. Как и при ручном выключении, набранные элементы
+// остаются в очереди.
+func (c *Controller) disableIdleQueue(now time.Time) {
+	c.mu.Lock()
+	timeout := c.queueIdleTimeout
+	if !c.queueEnabled || timeout <= 0 || now.Sub(c.lastQueueActivity) < timeout {
+		c.mu.Unlock()
+		return
+	}
+	c.queueEnabled = false
+	c.clearSelfEventsLocked()
+	snapshot := c.queueSnapshot
+	c.queueSnapshot = nil
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("Режим записи очереди выключен: нет вставок %s", timeout)
+	c.restoreQueueSnapshot(snapshot)
+	cb(false, count, mode)
+	uiCB()
+	c.notify("Очередь выключена автоматически",
+		fmt.Sprintf("Нет вставок %d мин. Элементов в очереди: %d", int(timeout/time.Minute), count), false)
+}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+var (
+	// ErrMergeTooFew возвращается, если для объединения передано меньше двух элементов.
+	ErrMergeTooFew = errors.New("для объединения нужно хотя бы два разных элемента очереди")
+	// ErrSplit возвращается, если текст нельзя разделить: неизвестный способ,
+	// некорректное выражение или меньше двух частей.
+	ErrSplit = errors.New("текст не разделить")
+)
+
+// Способы разделения текста на элементы очереди.
+const (
+	SplitLines = "lines" // По строкам: столбец из Excel
+	SplitTab   = "tab"   // По табуляции: строка из Excel
+	SplitCells = "cells" // По строкам и табуляции: диапазон из Excel по строкам
+	SplitRegex = "regex" // По регулярному выражению
+)
+
+// splitMaxParts ограничивает число элементов, на которые делится один текст.
+const splitMaxParts = 1000
+
+// cellSeparators разделяет ячейки диапазона, скопированного из таблицы.
+var cellSeparators = regexp.MustCompile(`\r?\n|\t`)
+
+// MergeQueueItems склеивает текстовые элементы очереди в указанном порядке через
+// separator и ставит результат в очередь на место самого раннего из них; исходные
+// элементы удаляются из очереди, но остаются в истории. Новый элемент добавляется
+// и в историю. Повторы ID в списке не учитываются.
+func (c *Controller) MergeQueueItems(ids []string, separator string) (windows.ClipboardContent, error) {
+	ids = uniqueIDs(ids)
+	if len(ids) < 2 {
+		return windows.ClipboardContent{}, ErrMergeTooFew
+	}
+
+	c.mu.Lock()
+	parts := make([]string, 0, len(ids))
+	first := len(c.queue)
+	var source string
+	for n, id := range ids {
+		index := slices.IndexFunc(c.queue, func(item windows.ClipboardContent) bool { return item.ID == id })
+		if index < 0 {
+			c.mu.Unlock()
+			return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrNotQueued, id)
+		}
+		item := c.queue[index]
+		if item.Type != windows.Text {
+			c.mu.Unlock()
+			return windows.ClipboardContent{}, fmt.Errorf("%w: id %s", ErrNotText, id)
+		}
+		parts = append(parts, item.Text)
+		first = min(first, index)
+		switch {
+		case n == 0:
+			source = item.SourceApp
+		case !strings.EqualFold(source, item.SourceApp):
+			source = ""
+		}
+	}
+	c.mu.Unlock()
+
+	merged := windows.NewTextContent(strings.Join(parts, separator))
+	merged.SourceApp = source
+	merged = c.markSensitive(merged)
+	merged.Hash = contentHash(merged)
+
+	c.mu.Lock()
+	merged = c.assignContentIDLocked(merged)
+	if _, exists := c.findItemLocked(merged.ID); c.cfg.Features.EnableClipboard && !exists {
+		c.history = append(c.history, merged)
+		c.trimHistoryLocked(time.Now())
+	}
+	rest := make([]windows.ClipboardContent, 0, len(c.queue)-len(ids)+1)
+	for i, item := range c.queue {
+		if i == first {
+			rest = append(rest, merged)
+		}
+		if !slices.Contains(ids, item.ID) {
+			rest = append(rest, item)
+		}
+	}
+	c.queue = rest
+	if slices.Contains(ids, c.randomNextID) {
+		c.randomNextID = merged.ID
+	}
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("MergeQueueItems: объединено элементов: %d (id=%s, размер=%d байт, длина очереди=%d)", len(ids), merged.ID, merged.SizeBytes, count)
+	cb(enabled, count, mode)
+	uiCB()
+	c.emit(Event{Kind: EventEnqueue, Item: merged})
+	return merged, nil
+}
+
+// uniqueIDs убирает пустые и повторяющиеся ID, сохраняя порядок.
+func uniqueIDs(ids []string) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// splitText делит текст способом by. Пустые части отбрасываются, а при keepEmpty
+// остаются все, кроме последней после завершающего разделителя: Excel заканчивает
+// скопированный диапазон переводом строки.
+func splitText(text, by, pattern string, keepEmpty bool) ([]string, error) {
+	var parts []string
+	switch by {
+	case SplitLines, "":
+		parts = strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	case SplitTab:
+		parts = strings.Split(text, "\t")
+	case SplitCells:
+		parts = cellSeparators.Split(text, -1)
+	case SplitRegex:
+		if pattern == "" {
+			return nil, fmt.Errorf("%w: не задано регулярное выражение", ErrSplit)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSplit, err)
+		}
+		parts = re.Split(text, -1)
+	default:
+		return nil, fmt.Errorf("%w: неизвестный способ %q", ErrSplit, by)
+	}
+
+	if keepEmpty {
+		if len(parts) > 0 && parts[len(parts)-1] == "" {
+			parts = parts[:len(parts)-1]
+		}
+	} else {
+		parts = slices.DeleteFunc(parts, func(p string) bool { return p == "" })
+	}
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%w: получилось частей: %d", ErrSplit, len(parts))
+	}
+	if len(parts) > splitMaxParts {
+		return nil, fmt.Errorf("%w: частей больше %d", ErrSplit, splitMaxParts)
+	}
+	return parts, nil
+}
+
+// SplitItem делит текстовый элемент истории или очереди на части и ставит их в
+// очередь так, чтобы PasteNext вставлял их по порядку: при LIFO части добавляются
+// в обратном порядке. Если исходный элемент стоит в очереди, части занимают его
+// место, иначе добавляются в её конец. Части попадают и в историю. Возвращает
+// новые элементы в порядке текста.
+func (c *Controller) SplitItem(id, by, pattern string, keepEmpty bool) ([]windows.ClipboardContent, error) {
+	if !c.cfg.Features.EnableQueue {
+		return nil, ErrQueueDisabled
+	}
+	c.mu.Lock()
+	original, found := c.findItemLocked(id)
+	c.mu.Unlock()
+	if !found {
+		return nil, fmt.Errorf("%w: id %s", ErrItemNotFound, id)
+	}
+	if original.Type != windows.Text {
+		return nil, fmt.Errorf("%w: id %s", ErrNotText, id)
+	}
+	texts, err := splitText(original.Text, by, pattern, keepEmpty)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]windows.ClipboardContent, 0, len(texts))
+	for _, text := range texts {
+		part := windows.NewTextContent(text)
+		part.SourceApp, part.SourceTitle = original.SourceApp, original.SourceTitle
+		part = c.markSensitive(part)
+		part.Hash = contentHash(part)
+		parts = append(parts, part)
+	}
+
+	c.mu.Lock()
+	for i := range parts {
+		parts[i] = c.assignContentIDLocked(parts[i])
+		var duplicate bool
+		parts[i], duplicate = c.dedupHistoryLocked(parts[i])
+		if c.cfg.Features.EnableClipboard && !duplicate {
+			c.history = append(c.history, parts[i])
+		}
+	}
+	c.trimHistoryLocked(time.Now())
+
+	queued := slices.Clone(parts)
+	if c.orderStrategy == OrderLIFO {
+		slices.Reverse(queued)
+	}
+	index := slices.IndexFunc(c.queue, func(item windows.ClipboardContent) bool { return item.ID == id })
+	if index >= 0 {
+		c.queue = slices.Replace(c.queue, index, index+1, queued...)
+	} else {
+		c.queue = append(c.queue, queued...)
+	}
+	if c.randomNextID == id {
+		c.randomNextID = ""
+	}
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("SplitItem: элемент %s разделён (%s) на %d частей, длина очереди=%d", id, by, len(parts), count)
+	cb(enabled, count, mode)
+	uiCB()
+	for _, part := range parts {
+		c.emit(Event{Kind: EventEnqueue, Item: part})
+	}
+	return parts, nil
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestMergeQueueItems(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+
+	a, _ := c.PushItem(windows.NewTextContent("a"))
+	b, _ := c.PushItem(windows.NewTextContent("b"))
+	d, _ := c.PushItem(windows.NewTextContent("d"))
+
+	merged, err := c.MergeQueueItems([]string{d.ID, a.ID, d.ID}, ", ")
+	if err != nil {
+		t.Fatalf("MergeQueueItems: %v", err)
+	}
+	if merged.Text != "d, a" {
+		t.Fatalf("текст склеивается в порядке ID, получено %q", merged.Text)
+	}
+	if queue := c.GetQueue(); len(queue) != 2 || queue[0].ID != merged.ID || queue[1].ID != b.ID {
+		t.Fatalf("результат должен встать на место самого раннего элемента: %+v", queue)
+	}
+	if len(c.GetHistory()) != 4 {
+		t.Fatalf("исходные элементы остаются в истории, результат добавляется: %d", len(c.GetHistory()))
+	}
+
+	if _, err := c.MergeQueueItems([]string{b.ID}, ""); !errors.Is(err, ErrMergeTooFew) {
+		t.Fatalf("ожидалась ErrMergeTooFew, получено %v", err)
+	}
+	if _, err := c.MergeQueueItems([]string{b.ID, a.ID}, ""); !errors.Is(err, ErrNotQueued) {
+		t.Fatalf("ожидалась ErrNotQueued, получено %v", err)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	parts, err := splitText("a\r\nb\r\n\r\nc\r\n", SplitLines, "", false)
+	if err != nil || len(parts) != 3 || parts[2] != "c" {
+		t.Fatalf("столбец Excel: %q, %v", parts, err)
+	}
+	parts, err = splitText("a\r\n\r\nc\r\n", SplitLines, "", true)
+	if err != nil || len(parts) != 3 || parts[1] != "" {
+		t.Fatalf("пустая ячейка должна остаться, завершающий перевод строки — нет: %q, %v", parts, err)
+	}
+	parts, err = splitText("a\tb\r\nc\td\r\n", SplitCells, "", false)
+	if err != nil || len(parts) != 4 || parts[3] != "d" {
+		t.Fatalf("диапазон Excel: %q, %v", parts, err)
+	}
+	parts, err = splitText("1; 2;3", SplitRegex, `;\s*`, false)
+	if err != nil || len(parts) != 3 || parts[1] != "2" {
+		t.Fatalf("регулярное выражение: %q, %v", parts, err)
+	}
+	if _, err := splitText("один", SplitLines, "", false); !errors.Is(err, ErrSplit) {
+		t.Fatalf("одна часть — ожидалась ErrSplit, получено %v", err)
+	}
+	if _, err := splitText("a(b", SplitRegex, "(", false); !errors.Is(err, ErrSplit) {
+		t.Fatalf("некорректное выражение — ожидалась ErrSplit, получено %v", err)
+	}
+}
+
+func TestSplitItemKeepsPasteOrder(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+
+	column, _ := c.PushItem(windows.NewTextContent("1\n2\n3"))
+	parts, err := c.SplitItem(column.ID, SplitLines, "", false)
+	if err != nil || len(parts) != 3 {
+		t.Fatalf("SplitItem: %d, %v", len(parts), err)
+	}
+	// По умолчанию LIFO: первым вставится последний элемент очереди, то есть «1».
+	queue := c.GetQueue()
+	if len(queue) != 3 || queue[2].Text != "1" || queue[0].Text != "3" {
+		t.Fatalf("части должны заменить исходный элемент в обратном порядке: %+v", queue)
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// Порядки вставки из очереди. ROUND_ROBIN вставляет элементы по кругу от первого:
+// вставленный элемент не удаляется, а уходит в конец очереди. RANDOM берёт
+// случайный элемент; выбор делается заранее, чтобы UI мог показать следующий.
+const (
+	OrderLIFO       = "LIFO"
+	OrderFIFO       = "FIFO"
+	OrderRoundRobin = "ROUND_ROBIN"
+	OrderRandom     = "RANDOM"
+)
+
+// QueueOrders — допустимые порядки в том же порядке, в каком их перебирает ToggleOrder.
+var QueueOrders = []string{OrderLIFO, OrderFIFO, OrderRoundRobin, OrderRandom}
+
+// validOrder сообщает, поддерживается ли порядок.
+func validOrder(order string) bool {
+	return slices.Contains(QueueOrders, order)
+}
+
+// nextOrder возвращает порядок, следующий за order при переключении.
+func nextOrder(order string) string {
+	i := slices.Index(QueueOrders, order)
+	return QueueOrders[(i+1)%len(QueueOrders)]
+}
+
+// nextIndexLocked возвращает индекс элемента, который вставится следующим, или -1.
+func (c *Controller) nextIndexLocked() int {
+	if len(c.queue) == 0 {
+		return -1
+	}
+	switch c.orderStrategy {
+	case OrderLIFO:
+		return len(c.queue) - 1
+	case OrderRandom:
+		for i, item := range c.queue {
+			if item.ID == c.randomNextID {
+				return i
+			}
+		}
+		i := rand.IntN(len(c.queue))
+		c.randomNextID = c.queue[i].ID
+		return i
+	default:
+		return 0
+	}
+}
+
+// NextQueueItemID возвращает ID элемента, который вставится следующим; пусто — очередь пуста.
+func (c *Controller) NextQueueItemID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.nextIndexLocked(); i >= 0 {
+		return c.queue[i].ID
+	}
+	return ""
+}
+
+// takeNextLocked извлекает следующий элемент и возвращает его прежний индекс.
+// В режиме ROUND_ROBIN элемент остаётся в очереди и переносится в её конец.
+func (c *Controller) takeNextLocked() (windows.ClipboardContent, int) {
+	i := c.nextIndexLocked()
+	item := c.queue[i]
+	c.queue = slices.Delete(c.queue, i, i+1)
+	c.randomNextID = ""
+	if c.orderStrategy == OrderRoundRobin {
+		c.queue = append(c.queue, item)
+	}
+	return item, i
+}
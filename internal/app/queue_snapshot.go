@@ -0,0 +1,45 @@
+package app
+
+import (
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// queueSnapshot — буфер обмена на момент включения режима записи. Пока режим
+// включён, буфер занят копируемыми в очередь элементами; при выключении
+// пользователь получает прежнее содержимое во всех форматах (HTML, RTF, форматы
+// приложений), а не только тот, который понимает Read.
+type queueSnapshot struct {
+	clipboard *windows.Snapshot
+	currentID string // Элемент истории, лежавший в буфере
+}
+
+// takeQueueSnapshot снимает буфер перед включением режима записи. nil — снимок
+// не получился, и при выключении буфер останется как есть.
+func (c *Controller) takeQueueSnapshot() *queueSnapshot {
+	snapshot, err := windows.TakeSnapshot()
+	if err != nil {
+		logger.Warn("Снимок буфера обмена перед включением очереди не сделан: %v", err)
+		return nil
+	}
+	c.mu.Lock()
+	currentID := c.currentClipboardID
+	c.mu.Unlock()
+	return &queueSnapshot{clipboard: snapshot, currentID: currentID}
+}
+
+// restoreQueueSnapshot возвращает в буфер снимок, сделанный при включении режима записи.
+func (c *Controller) restoreQueueSnapshot(snapshot *queueSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	if err := windows.RestoreSnapshot(snapshot.clipboard); err != nil {
+		logger.Warn("Не удалось восстановить буфер обмена после выключения очереди: %v", err)
+		return
+	}
+	c.mu.Lock()
+	c.addSelfEventLocked(windows.GetClipboardSequenceNumber())
+	c.currentClipboardID = snapshot.currentID
+	c.mu.Unlock()
+	logger.Info("Буфер обмена восстановлен из снимка (форматов: %d)", len(snapshot.clipboard.Formats))
+}
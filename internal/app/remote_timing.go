@@ -0,0 +1,91 @@
+package app
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// pasteSettleDelay — пауза между записью в буфер и Ctrl+V для локального окна.
+const pasteSettleDelay = 10 * time.Millisecond
+
+// remoteTiming — раздел remote_session конфигурации.
+type remoteTiming struct {
+	adaptive     bool
+	clients      []string
+	pasteDelay   time.Duration
+	restoreDelay time.Duration
+	slowTyping   bool
+}
+
+func remoteTimingFromConfig(cfg *config.Config) remoteTiming {
+	return remoteTiming{
+		adaptive:     cfg.RemoteSession.Adaptive,
+		clients:      append([]string{}, cfg.RemoteSession.Clients...),
+		pasteDelay:   time.Duration(cfg.RemoteSession.PasteDelayMs) * time.Millisecond,
+		restoreDelay: time.Duration(cfg.RemoteSession.RestoreDelayMs) * time.Millisecond,
+		slowTyping:   cfg.RemoteSession.SlowTyping,
+	}
+}
+
+// SetRemoteTiming применяет раздел remote_session.
+func (c *Controller) SetRemoteTiming(cfg *config.Config) {
+	timing := remoteTimingFromConfig(cfg)
+	c.mu.Lock()
+	c.remote = timing
+	c.mu.Unlock()
+}
+
+// isRemoteClient сообщает, что процесс — клиент удалённого рабочего стола из списка.
+func isRemoteClient(clients []string, process string) bool {
+	key := targetKey(process)
+	for _, client := range clients {
+		if key != "" && targetKey(client) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// pasteTiming — задержки одной вставки.
+type pasteTiming struct {
+	settle   time.Duration // Пауза между записью в буфер и нажатием Ctrl+V
+	activate time.Duration // Пауза после активации окна, выбранного в UI или API
+	restore  time.Duration // Сколько ждать чтения буфера получателем
+	slow     bool          // Набирать текст медленно
+	remote   bool
+}
+
+// pasteTimingFor выбирает задержки для получателя: обычные из раздела clipboard
+// либо увеличенные из remote_session, если получатель — клиент RDP или Citrix
+// или ClipQueue сам работает в удалённом сеансе.
+func pasteTimingFor(cfg *config.Config, remote remoteTiming, target windows.WindowInfo, remoteSession bool) pasteTiming {
+	t := pasteTiming{
+		settle:   pasteSettleDelay,
+		activate: time.Duration(cfg.Clipboard.PasteDelayMs) * time.Millisecond,
+		restore:  time.Duration(cfg.Clipboard.RestoreDelayMs) * time.Millisecond,
+	}
+	if !remote.adaptive || (!remoteSession && !isRemoteClient(remote.clients, target.ProcessName)) {
+		return t
+	}
+	t.remote = true
+	t.settle = max(t.settle, remote.pasteDelay)
+	t.activate = max(t.activate, remote.pasteDelay)
+	t.restore = max(t.restore, remote.restoreDelay)
+	t.slow = remote.slowTyping
+	return t
+}
+
+func (c *Controller) pasteTiming(target windows.WindowInfo) pasteTiming {
+	c.mu.Lock()
+	remote := c.remote
+	c.mu.Unlock()
+	t := pasteTimingFor(c.cfg, remote, target, windows.IsRemoteSession())
+	t.restore = max(t.restore, c.targetRestoreDelay(target))
+	if t.remote {
+		logger.Debug("Удалённый сеанс (%s): пауза перед вставкой %v, ожидание чтения буфера %v", target.ProcessName, t.settle, t.restore)
+	}
+	return t
+}
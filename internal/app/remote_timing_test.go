@@ -0,0 +1,46 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestPasteTimingFor(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Clipboard.PasteDelayMs = 50
+	cfg.Clipboard.RestoreDelayMs = 250
+	cfg.RemoteSession.Adaptive = true
+	cfg.RemoteSession.Clients = []string{"mstsc.exe"}
+	cfg.RemoteSession.PasteDelayMs = 150
+	cfg.RemoteSession.RestoreDelayMs = 1500
+	cfg.RemoteSession.SlowTyping = true
+	remote := remoteTimingFromConfig(cfg)
+
+	local := pasteTimingFor(cfg, remote, windows.WindowInfo{ProcessName: "notepad.exe"}, false)
+	if local.remote || local.slow || local.settle != pasteSettleDelay || local.restore != 250*time.Millisecond {
+		t.Fatalf("для локального окна ожидались обычные задержки, получено %+v", local)
+	}
+
+	rdp := pasteTimingFor(cfg, remote, windows.WindowInfo{ProcessName: "MSTSC.EXE"}, false)
+	if !rdp.remote || !rdp.slow || rdp.settle != 150*time.Millisecond || rdp.activate != 150*time.Millisecond || rdp.restore != 1500*time.Millisecond {
+		t.Fatalf("для клиента RDP ожидались задержки remote_session, получено %+v", rdp)
+	}
+
+	if inside := pasteTimingFor(cfg, remote, windows.WindowInfo{ProcessName: "notepad.exe"}, true); !inside.remote {
+		t.Fatal("внутри удалённого сеанса задержки должны увеличиваться для любого окна")
+	}
+
+	// Обычная задержка больше удалённой — остаётся обычная.
+	cfg.Clipboard.RestoreDelayMs = 3000
+	if got := pasteTimingFor(cfg, remote, windows.WindowInfo{ProcessName: "mstsc.exe"}, false); got.restore != 3*time.Second {
+		t.Fatalf("ожидалось ожидание 3s, получено %v", got.restore)
+	}
+
+	cfg.RemoteSession.Adaptive = false
+	if got := pasteTimingFor(cfg, remoteTimingFromConfig(cfg), windows.WindowInfo{ProcessName: "mstsc.exe"}, true); got.remote {
+		t.Fatal("при выключенном remote_session.adaptive задержки не меняются")
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// Screenshot снимает экран (full, window или region) и добавляет изображение
+// в историю и, при включённом режиме записи, в очередь. Буфер обмена не меняется.
+// Отмена выделения области не считается ошибкой.
+func (c *Controller) Screenshot(mode string) error {
+	img, err := windows.Screenshot(windows.ScreenshotMode(mode))
+	if errors.Is(err, windows.ErrScreenshotCancelled) {
+		logger.Debug("Снимок экрана отменён")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	content, err := windows.NewImageContent(img)
+	if err != nil {
+		return err
+	}
+	content.Preview = "Снимок экрана: " + content.Preview
+
+	c.mu.Lock()
+	content, queued := c.appendItemLocked(content)
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	orderMode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("Снимок экрана (%s, %dx%d) добавлен: в очередь=%v", mode, img.Bounds().Dx(), img.Bounds().Dy(), queued)
+	if queued {
+		cb(enabled, count, orderMode)
+		c.emit(Event{Kind: EventEnqueue, Item: content})
+	}
+	uiCB()
+	c.notify("Снимок экрана", content.Preview, false)
+	return nil
+}
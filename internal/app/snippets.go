@@ -0,0 +1,90 @@
+package app
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/snippets"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// openSnippets открывает библиотеку сниппетов в App.DataDir. Повреждённый файл
+// не мешает запуску: библиотека начинается пустой, а ошибка попадает в лог.
+func openSnippets(dataDir string) *snippets.Store {
+	store, err := snippets.Open(filepath.Join(config.ResolvePath(dataDir), snippets.FileName))
+	if err != nil {
+		logger.Warn("%v", err)
+	}
+	return store
+}
+
+// ListSnippets возвращает сниппеты из папки folder (вместе с вложенными) и с тегом
+// tag; пустые folder и tag не ограничивают список.
+func (c *Controller) ListSnippets(folder, tag string) []snippets.Snippet {
+	var list []snippets.Snippet
+	for _, snippet := range c.snippets.List() {
+		if snippet.InFolder(folder) && (tag == "" || snippet.HasTag(tag)) {
+			list = append(list, snippet)
+		}
+	}
+	return list
+}
+
+// SnippetFolders возвращает папки библиотеки вместе с родительскими.
+func (c *Controller) SnippetFolders() []string {
+	return c.snippets.Folders()
+}
+
+// SearchSnippets ищет сниппеты нечётким совпадением по заголовкам, папкам, тегам и текстам.
+func (c *Controller) SearchSnippets(query string, limit int) []snippets.SearchResult {
+	return c.snippets.Search(query, limit)
+}
+
+// GetSnippet возвращает сниппет по ID.
+func (c *Controller) GetSnippet(id string) (snippets.Snippet, error) {
+	return c.snippets.Get(id)
+}
+
+// CreateSnippet добавляет сниппет в библиотеку.
+func (c *Controller) CreateSnippet(s snippets.Snippet) (snippets.Snippet, error) {
+	created, err := c.snippets.Create(s)
+	if err == nil {
+		logger.Info("Добавлен сниппет %q", created.Title)
+	}
+	return created, err
+}
+
+// UpdateSnippet заменяет категорию, заголовок и текст сниппета.
+func (c *Controller) UpdateSnippet(id string, s snippets.Snippet) (snippets.Snippet, error) {
+	return c.snippets.Update(id, s)
+}
+
+// DeleteSnippet удаляет сниппет из библиотеки.
+func (c *Controller) DeleteSnippet(id string) error {
+	return c.snippets.Delete(id)
+}
+
+// PasteSnippet вставляет текст сниппета в активное окно, а если hwnd не 0 —
+// сначала делает активным окно hwnd. Поля {{field:Имя}} заменяются значениями из
+// fields. Буфер обмена после вставки восстанавливается, очередь и история не меняются.
+func (c *Controller) PasteSnippet(id string, hwnd uintptr, fields map[string]string) error {
+	snippet, err := c.snippets.Get(id)
+	if err != nil {
+		return err
+	}
+	text, err := snippets.Fill(snippet.Body, fields)
+	if err != nil {
+		return err
+	}
+	if hwnd != 0 {
+		if err := windows.ActivateWindow(hwnd); err != nil {
+			return err
+		}
+		// Окну нужно время, чтобы вернуть фокус ввода своему элементу управления.
+		time.Sleep(c.pasteTiming(windows.GetWindowInfo(hwnd)).activate)
+	}
+	logger.Info("Вставка сниппета %q", snippet.Title)
+	return c.pasteText(text)
+}
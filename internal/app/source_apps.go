@@ -0,0 +1,63 @@
+package app
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// SourceAppStats — сколько элементов истории скопировано из одного приложения.
+type SourceAppStats struct {
+	App        string    `json:"app"`
+	Items      int       `json:"items"`
+	Bytes      int64     `json:"bytes"`
+	LastCopied time.Time `json:"lastCopied"`
+}
+
+// MatchesSourceApp сообщает, скопирован ли элемент из приложения app (имя
+// исполняемого файла без учёта регистра). Пустой app подходит любому элементу.
+func MatchesSourceApp(item windows.ClipboardContent, app string) bool {
+	return app == "" || strings.EqualFold(item.SourceApp, strings.TrimSpace(app))
+}
+
+// sourceAppStats группирует элементы не старше since по приложению-источнику;
+// элементы без источника не учитываются. Первыми идут приложения с большим числом элементов.
+func sourceAppStats(items []windows.ClipboardContent, since time.Time) []SourceAppStats {
+	byApp := make(map[string]*SourceAppStats)
+	for _, item := range items {
+		if item.SourceApp == "" || item.Timestamp.Before(since) {
+			continue
+		}
+		key := targetKey(item.SourceApp)
+		stats, ok := byApp[key]
+		if !ok {
+			stats = &SourceAppStats{App: item.SourceApp}
+			byApp[key] = stats
+		}
+		stats.Items++
+		stats.Bytes += int64(item.SizeBytes)
+		if item.Timestamp.After(stats.LastCopied) {
+			stats.LastCopied = item.Timestamp
+		}
+	}
+
+	result := make([]SourceAppStats, 0, len(byApp))
+	for _, stats := range byApp {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Items != result[j].Items {
+			return result[i].Items > result[j].Items
+		}
+		return targetKey(result[i].App) < targetKey(result[j].App)
+	})
+	return result
+}
+
+// GetSourceAppStats возвращает статистику истории по приложениям-источникам
+// для элементов, скопированных не раньше since.
+func (c *Controller) GetSourceAppStats(since time.Time) []SourceAppStats {
+	return sourceAppStats(c.GetHistory(), since)
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestSourceAppStats(t *testing.T) {
+	now := time.Now()
+	items := []windows.ClipboardContent{
+		{ID: "1", SourceApp: "chrome.exe", SizeBytes: 10, Timestamp: now.Add(-time.Hour)},
+		{ID: "2", SourceApp: "Chrome.exe", SizeBytes: 5, Timestamp: now},
+		{ID: "3", SourceApp: "code.exe", SizeBytes: 7, Timestamp: now},
+		{ID: "4", SizeBytes: 1, Timestamp: now},
+		{ID: "5", SourceApp: "code.exe", SizeBytes: 3, Timestamp: now.Add(-48 * time.Hour)},
+	}
+
+	stats := sourceAppStats(items, now.Add(-24*time.Hour))
+	if len(stats) != 2 {
+		t.Fatalf("ожидались два приложения, получено %+v", stats)
+	}
+	if stats[0].App != "chrome.exe" || stats[0].Items != 2 || stats[0].Bytes != 15 || !stats[0].LastCopied.Equal(now) {
+		t.Fatalf("неожиданная статистика chrome.exe: %+v", stats[0])
+	}
+	if stats[1].App != "code.exe" || stats[1].Items != 1 {
+		t.Fatalf("элемент старше since не должен учитываться: %+v", stats[1])
+	}
+	if !MatchesSourceApp(items[1], "CHROME.EXE") || MatchesSourceApp(items[3], "chrome.exe") {
+		t.Fatal("фильтр по приложению должен сравнивать имена без учёта регистра")
+	}
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+const (
+	stateFile    = "state.json"
+	stateVersion = 1
+)
+
+// savedItem — элемент очереди или истории в снимке состояния.
+type savedItem struct {
+	ID            string              `json:"id"`
+	Timestamp     time.Time           `json:"timestamp"`
+	Type          windows.ContentType `json:"type"`
+	Text          string              `json:"text,omitempty"`
+	Files         []string            `json:"files,omitempty"`
+	ImagePNG      []byte              `json:"imagePng,omitempty"`
+	SizeBytes     int                 `json:"sizeBytes"`
+	Preview       string              `json:"preview"`
+	Thumbnail     []byte              `json:"thumbnail,omitempty"`
+	ThumbnailType string              `json:"thumbnailType,omitempty"`
+	Sensitive     string              `json:"sensitive,omitempty"`
+	Oversized     bool                `json:"oversized,omitempty"`
+	ImageType     string              `json:"imageType,omitempty"`
+	Compacted     bool                `json:"compacted,omitempty"`
+	EMF           []byte              `json:"emf,omitempty"`
+	FilesBytes    int64               `json:"filesBytes,omitempty"`
+	FileDirs      int                 `json:"fileDirs,omitempty"`
+	Hash          string              `json:"hash,omitempty"`
+	SourceApp     string              `json:"sourceApp,omitempty"`
+	SourceTitle   string              `json:"sourceTitle,omitempty"`
+	Title         string              `json:"title,omitempty"`
+	Revision      int                 `json:"revision,omitempty"`
+	RevisionOf    string              `json:"revisionOf,omitempty"`
+}
+
+// savedState — снимок очереди и истории, переживающий перезапуск и завершение сеанса Windows.
+type savedState struct {
+	Version int         `json:"version"`
+	SavedAt time.Time   `json:"savedAt"`
+	Queue   []savedItem `json:"queue"`
+	History []savedItem `json:"history"`
+}
+
+func toSavedItems(items []windows.ClipboardContent) []savedItem {
+	out := make([]savedItem, 0, len(items))
+	for _, item := range items {
+		// Изображение, которое ещё не дочитано из буфера, после перезапуска не восстановить.
+		if item.NeedsImageCapture() {
+			continue
+		}
+		// Секреты не пишутся на диск открытым текстом: иначе маскировка и
+		// history.sensitive_ttl теряют смысл после перезапуска.
+		if item.Sensitive != "" {
+			continue
+		}
+		out = append(out, savedItem{
+			ID:            item.ID,
+			Timestamp:     item.Timestamp,
+			Type:          item.Type,
+			Text:          item.Text,
+			Files:         item.Files,
+			ImagePNG:      item.ImagePNG,
+			SizeBytes:     item.SizeBytes,
+			Preview:       item.Preview,
+			Thumbnail:     item.Thumbnail,
+			ThumbnailType: item.ThumbnailType,
+			Sensitive:     item.Sensitive,
+			Oversized:     item.Oversized,
+			ImageType:     item.ImageType,
+			Compacted:     item.Compacted,
+			EMF:           item.EMF,
+			FilesBytes:    item.FilesBytes,
+			FileDirs:      item.FileDirs,
+			Hash:          item.Hash,
+			SourceApp:     item.SourceApp,
+			SourceTitle:   item.SourceTitle,
+			Title:         item.Title,
+			Revision:      item.Revision,
+			RevisionOf:    item.RevisionOf,
+		})
+	}
+	return out
+}
+
+func fromSavedItems(items []savedItem) []windows.ClipboardContent {
+	out := make([]windows.ClipboardContent, 0, len(items))
+	for _, item := range items {
+		content := windows.ClipboardContent{
+			ID:            item.ID,
+			Timestamp:     item.Timestamp,
+			Type:          item.Type,
+			Text:          item.Text,
+			Files:         item.Files,
+			ImagePNG:      item.ImagePNG,
+			SizeBytes:     item.SizeBytes,
+			Preview:       item.Preview,
+			Thumbnail:     item.Thumbnail,
+			ThumbnailType: item.ThumbnailType,
+			Sensitive:     item.Sensitive,
+			Oversized:     item.Oversized,
+			ImageType:     item.ImageType,
+			Compacted:     item.Compacted,
+			EMF:           item.EMF,
+			FilesBytes:    item.FilesBytes,
+			FileDirs:      item.FileDirs,
+			Hash:          item.Hash,
+			SourceApp:     item.SourceApp,
+			SourceTitle:   item.SourceTitle,
+			Title:         item.Title,
+			Revision:      item.Revision,
+			RevisionOf:    item.RevisionOf,
+		}
+		// Состояние прежних версий хранится без хеша.
+		if content.Hash == "" && !content.Compacted {
+			content.Hash = contentHash(content)
+		}
+		out = append(out, content)
+	}
+	return out
+}
+
+func (c *Controller) statePath() string {
+	return filepath.Join(config.ResolvePath(c.cfg.App.DataDir), stateFile)
+}
+
+// SaveState записывает снимок очереди и истории в <data_dir>\state.json.
+// Файл пишется через временный и переименовывается, чтобы обрыв записи
+// при завершении сеанса не оставил повреждённый снимок.
+func (c *Controller) SaveState() error {
+	c.mu.Lock()
+	state := savedState{
+		Version: stateVersion,
+		SavedAt: time.Now(),
+		Queue:   toSavedItems(c.queue),
+		History: toSavedItems(c.history),
+	}
+	path := c.statePath()
+	c.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("сериализация состояния: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("запись состояния: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("запись состояния: %w", err)
+	}
+	logger.Info("Состояние сохранено: очередь=%d, история=%d", len(state.Queue), len(state.History))
+	return nil
+}
+
+// LoadState восстанавливает очередь и историю из снимка. Отсутствие файла не ошибка.
+// Лимиты истории применяются сразу, поэтому просроченные по TTL элементы не возвращаются.
+func (c *Controller) LoadState() error {
+	path := c.statePath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("чтение состояния: %w", err)
+	}
+	var state savedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("повреждён файл состояния %s: %w", path, err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("неподдерживаемая версия файла состояния: %d", state.Version)
+	}
+
+	c.mu.Lock()
+	c.queue = fromSavedItems(state.Queue)
+	c.history = fromSavedItems(state.History)
+	c.trimHistoryLocked(time.Now())
+	cb := c.onStateChange
+	enabled, count, mode := c.queueEnabled, len(c.queue), c.orderStrategy
+	historyCount := len(c.history)
+	c.mu.Unlock()
+
+	logger.Info("Состояние восстановлено: очередь=%d, история=%d", count, historyCount)
+	cb(enabled, count, mode)
+	return nil
+}
+
+// RemoveState удаляет снимок, чтобы при выключенном сохранении содержимое
+// буфера не оставалось на диске.
+func (c *Controller) RemoveState() error {
+	if err := os.Remove(c.statePath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("удаление состояния: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// Stats — сводка по истории, очереди и вставкам для карточки в интерфейсе.
+type Stats struct {
+	Items         int            `json:"items"`
+	ByType        map[string]int `json:"byType"` // Text, Image, Files
+	Bytes         int64          `json:"bytes"`
+	AverageBytes  int64          `json:"averageBytes"`
+	AddedToday    int            `json:"addedToday"`
+	Queued        int            `json:"queued"`
+	Pastes        int            `json:"pastes"`        // Успешные вставки за всё время
+	PasteFailures int            `json:"pasteFailures"` // Неудачные вставки за всё время
+}
+
+// historyStats считает элементы истории; «сегодня» — с начала суток now.
+func historyStats(items []windows.ClipboardContent, now time.Time) Stats {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	stats := Stats{Items: len(items), ByType: make(map[string]int)}
+	for _, item := range items {
+		stats.ByType[item.Type.String()]++
+		stats.Bytes += int64(item.SizeBytes)
+		if !item.Timestamp.Before(today) {
+			stats.AddedToday++
+		}
+	}
+	if stats.Items > 0 {
+		stats.AverageBytes = stats.Bytes / int64(stats.Items)
+	}
+	return stats
+}
+
+// totals суммирует вставки по профилям приложений-получателей. Профили хранятся
+// на диске, поэтому счётчики переживают перезапуск, в отличие от журнала recent.
+func (s *pasteTargetStore) totals() (pastes, failures int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.profiles {
+		pastes += p.Pastes - p.Failures
+		failures += p.Failures
+	}
+	return pastes, failures
+}
+
+// GetStats возвращает сводку по истории, очереди и вставкам.
+func (c *Controller) GetStats() Stats {
+	stats := historyStats(c.GetHistory(), time.Now())
+	_, stats.Queued, _ = c.GetQueueState()
+	stats.Pastes, stats.PasteFailures = c.targets.totals()
+	return stats
+}
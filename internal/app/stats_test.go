@@ -0,0 +1,31 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestHistoryStats(t *testing.T) {
+	now := time.Date(2026, 5, 10, 9, 0, 0, 0, time.Local)
+	items := []windows.ClipboardContent{
+		{Type: windows.Text, SizeBytes: 10, Timestamp: now},
+		{Type: windows.Text, SizeBytes: 20, Timestamp: now.Add(-8 * time.Hour)},
+		{Type: windows.Image, SizeBytes: 30, Timestamp: now.Add(-10 * time.Hour)},
+	}
+
+	stats := historyStats(items, now)
+	if stats.Items != 3 || stats.Bytes != 60 || stats.AverageBytes != 20 {
+		t.Fatalf("неожиданные итоги: %+v", stats)
+	}
+	if stats.ByType["Text"] != 2 || stats.ByType["Image"] != 1 {
+		t.Fatalf("неожиданная разбивка по типам: %v", stats.ByType)
+	}
+	if stats.AddedToday != 2 {
+		t.Fatalf("сегодня добавлено 2 элемента, получено %d", stats.AddedToday)
+	}
+	if empty := historyStats(nil, now); empty.AverageBytes != 0 || empty.ByType == nil {
+		t.Fatalf("пустая история: %+v", empty)
+	}
+}
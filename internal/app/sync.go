@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// SetCaptureCallback задаёт обработчик элементов, скопированных на этом компьютере;
+// через него элементы уходят на другие узлы синхронизации. nil отключает вызов.
+// При images=false изображения обработчику не передаются и не дочитываются из буфера заранее.
+func (c *Controller) SetCaptureCallback(fn func(content windows.ClipboardContent), images bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onCapture = fn
+	c.captureImages = images
+}
+
+// notifyCapture передаёт новый элемент обработчику захвата. Отложенное
+// изображение дочитывается сразу: позже буфер может уже измениться.
+// Секреты на другие компьютеры не уходят.
+func (c *Controller) notifyCapture(content windows.ClipboardContent) {
+	c.mu.Lock()
+	fn := c.onCapture
+	images := c.captureImages
+	c.mu.Unlock()
+	if fn == nil || content.Oversized || (content.Type == windows.Image && !images) {
+		return
+	}
+	if content.Sensitive != "" {
+		logger.Debug("Элемент %s похож на секрет (%s) и не синхронизируется", content.ID, content.Sensitive)
+		return
+	}
+	if content.NeedsImageCapture() {
+		resolved, err := c.resolveImagePayload(content)
+		if err != nil {
+			logger.Warn("Не удалось дочитать изображение %s для синхронизации: %v", content.ID, err)
+			return
+		}
+		content = resolved
+	}
+	fn(content)
+}
+
+// AddRemoteItem добавляет элемент, полученный с другого компьютера, так же как
+// локальное копирование: в историю и, при включённом режиме записи, в очередь.
+// Секреты помечаются по настройкам этого компьютера.
+// Буфер обмена этого компьютера не меняется.
+func (c *Controller) AddRemoteItem(content windows.ClipboardContent) {
+	if content.Type == windows.Empty {
+		return
+	}
+	content = c.markSensitive(content)
+
+	c.mu.Lock()
+	for _, item := range c.history {
+		if item.ID == content.ID {
+			c.mu.Unlock()
+			logger.Debug("AddRemoteItem: элемент %s уже есть в истории", content.ID)
+			return
+		}
+	}
+	content, queued := c.appendItemLocked(content)
+	cb := c.onStateChange
+	uiCB := c.onUIRefresh
+	enabled := c.queueEnabled
+	count := len(c.queue)
+	mode := c.orderStrategy
+	c.mu.Unlock()
+
+	logger.Info("AddRemoteItem: получен элемент с другого компьютера (тип=%s, размер=%d байт, в очередь=%v)",
+		content.Type.String(), content.SizeBytes, queued)
+	if queued {
+		cb(enabled, count, mode)
+		c.notify(fmt.Sprintf("Добавлено в очередь с другого компьютера (%d)", count), content.Preview, false)
+		c.emit(Event{Kind: EventEnqueue, Item: content})
+	}
+	uiCB()
+}
+
+// appendItemLocked добавляет элемент, появившийся не из локального буфера, так же
+// как копирование: в историю и, при включённом режиме записи, в очередь.
+// Возвращает сохранённый элемент (для повтора — уже существующий)
+// и true, если элемент попал в очередь.
+func (c *Controller) appendItemLocked(content windows.ClipboardContent) (windows.ClipboardContent, bool) {
+	if content.Hash == "" {
+		content.Hash = contentHash(content)
+	}
+	content = c.assignContentIDLocked(content)
+	content, duplicate := c.dedupHistoryLocked(content)
+	if c.cfg.Features.EnableClipboard && !duplicate {
+		c.history = append(c.history, content)
+		c.trimHistoryLocked(time.Now())
+		c.compactImagesSoonLocked()
+	}
+	queued := c.cfg.Features.EnableQueue && c.queueEnabled && !c.dedupQueueLocked(content)
+	if queued {
+		c.queue = append(c.queue, content)
+	}
+	return content, queued
+}
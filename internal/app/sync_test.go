@@ -0,0 +1,43 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestNotifyCaptureSkipsSensitive(t *testing.T) {
+	c := newTestController()
+	var sent []string
+	c.SetCaptureCallback(func(content windows.ClipboardContent) { sent = append(sent, content.Text) }, false)
+
+	secret := windows.NewTextContent("4111 1111 1111 1111")
+	secret.Sensitive = "card"
+	c.notifyCapture(secret)
+	c.notifyCapture(windows.NewTextContent("обычный текст"))
+
+	if len(sent) != 1 || sent[0] != "обычный текст" {
+		t.Fatalf("на другие узлы ушло %q, ожидался только обычный текст", sent)
+	}
+}
+
+func TestAddRemoteItemMarksSensitive(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Features.EnableQueue = true
+	cfg.Features.EnableClipboard = true
+	c := NewController(cfg)
+	c.detectSensitive = true
+	c.queueEnabled = true
+
+	c.AddRemoteItem(windows.NewTextContent("4111 1111 1111 1111"))
+
+	history := c.GetHistory()
+	if len(history) != 1 || history[0].Sensitive != "card" || strings.Contains(history[0].Preview, "4111") {
+		t.Fatalf("секрет с другого компьютера не помечен: %+v", history)
+	}
+	if queue := c.GetQueue(); len(queue) != 1 || queue[0].Sensitive != "card" {
+		t.Fatalf("в очереди секрет без пометки: %+v", queue)
+	}
+}
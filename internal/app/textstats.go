@@ -0,0 +1,61 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/serty2005/clipqueue/internal/textstats"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// textCountsCache запоминает краткую статистику текстовых элементов, чтобы не
+// пересчитывать её при каждом обновлении списка истории.
+type textCountsCache struct {
+	mu      sync.Mutex
+	entries map[string]textCountsEntry
+}
+
+// textCountsEntry хранит текст, по которому посчитана статистика: если текст
+// элемента с тем же ID изменится, запись пересчитывается. Сравнение строк с
+// общим буфером не читает их содержимое.
+type textCountsEntry struct {
+	text   string
+	counts textstats.Counts
+}
+
+// GetItemTextCounts возвращает символы, слова и строки текстовых элементов
+// истории по ID. Записи удалённых элементов выбрасываются из кэша.
+func (c *Controller) GetItemTextCounts() map[string]textstats.Counts {
+	history := c.GetHistory()
+	cache := &c.textCounts
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	entries := make(map[string]textCountsEntry, len(history))
+	out := make(map[string]textstats.Counts, len(history))
+	for _, item := range history {
+		if item.Type != windows.Text {
+			continue
+		}
+		entry, ok := cache.entries[item.ID]
+		if !ok || entry.text != item.Text {
+			entry = textCountsEntry{text: item.Text, counts: textstats.Count(item.Text)}
+		}
+		entries[item.ID] = entry
+		out[item.ID] = entry.counts
+	}
+	cache.entries = entries
+	return out
+}
+
+// GetItemTextStats считает подробную статистику текста элемента id.
+func (c *Controller) GetItemTextStats(id string) (textstats.Stats, error) {
+	item, err := c.GetItem(id)
+	if err != nil {
+		return textstats.Stats{}, err
+	}
+	if item.Type != windows.Text {
+		return textstats.Stats{}, fmt.Errorf("%w: id %s", ErrNotText, id)
+	}
+	return textstats.Compute(item.Text), nil
+}
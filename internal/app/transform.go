@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/transform"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// ErrTransformNotFound возвращается, если в разделе transforms нет преобразования с таким именем.
+var ErrTransformNotFound = errors.New("преобразование не найдено")
+
+// transformRule — преобразование из конфигурации с разобранным выражением Match.
+type transformRule struct {
+	config.Transform
+	match *regexp.Regexp
+}
+
+func (r transformRule) run(text string) (string, error) {
+	return transform.Run(context.Background(), r.Command, text, time.Duration(r.TimeoutMs)*time.Millisecond)
+}
+
+func transformRulesFromConfig(cfg *config.Config) []transformRule {
+	rules := make([]transformRule, 0, len(cfg.Transforms))
+	for _, t := range cfg.Transforms {
+		re, err := regexp.Compile(t.Match)
+		if err != nil {
+			logger.Warn("Преобразование %s пропущено: некорректный match: %v", t.Name, err)
+			continue
+		}
+		rules = append(rules, transformRule{Transform: t, match: re})
+	}
+	return rules
+}
+
+// SetTransforms применяет раздел transforms конфигурации.
+func (c *Controller) SetTransforms(cfg *config.Config) {
+	rules := transformRulesFromConfig(cfg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transforms = rules
+}
+
+// applyAutoTransforms пропускает новый текст по очереди через все правила с auto,
+// чей match совпадает с текстом. Ошибка правила пишется в журнал, текст остаётся прежним.
+func (c *Controller) applyAutoTransforms(content windows.ClipboardContent) windows.ClipboardContent {
+	if content.Type != windows.Text {
+		return content
+	}
+	c.mu.Lock()
+	rules := c.transforms
+	c.mu.Unlock()
+
+	text := content.Text
+	for _, rule := range rules {
+		if !rule.Auto || !rule.match.MatchString(text) {
+			continue
+		}
+		out, err := rule.run(text)
+		if err != nil {
+			logger.Warn("Преобразование %s не применено: %v", rule.Name, err)
+			continue
+		}
+		logger.Debug("Преобразование %s применено к элементу %s", rule.Name, content.ID)
+		text = out
+	}
+	if text == content.Text {
+		return content
+	}
+	return withText(content, text)
+}
+
+// TransformClipboard пропускает текст буфера обмена через преобразование name и
+// записывает результат обратно в буфер. Текущий элемент истории и его копия в
+// очереди заменяются результатом с тем же ID.
+func (c *Controller) TransformClipboard(name string) error {
+	c.mu.Lock()
+	var rule transformRule
+	found := false
+	for _, r := range c.transforms {
+		if r.Name == name {
+			rule, found = r, true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if !found {
+		return fmt.Errorf("%w: %s", ErrTransformNotFound, name)
+	}
+
+	content, err := windows.Read()
+	if err != nil {
+		return err
+	}
+	if content.Type != windows.Text {
+		return errors.New("в буфере обмена нет текста")
+	}
+	out, err := rule.run(content.Text)
+	if err != nil {
+		return err
+	}
+	result := c.markSensitive(windows.NewTextContent(out))
+	if err := windows.Write(result); err != nil {
+		return err
+	}
+	c.scheduleAutoClear(result)
+
+	c.mu.Lock()
+	c.addSelfEventLocked(windows.GetClipboardSequenceNumber())
+	replaced := c.replaceTextLocked(c.currentClipboardID, content.Text, result)
+	uiCB := c.onUIRefresh
+	c.mu.Unlock()
+
+	logger.Info("Буфер обмена преобразован командой %s (длина %d -> %d, элементов заменено: %d)", rule.Name, len(content.Text), len(out), replaced)
+	uiCB()
+	return nil
+}
+
+// replaceTextLocked заменяет текст элемента id в истории и очереди текстом result,
+// если элемент всё ещё содержит old. Пометка секрета и предпросмотр берутся из
+// result: его заранее проверяет markSensitive. Возвращает число заменённых копий.
+func (c *Controller) replaceTextLocked(id, old string, result windows.ClipboardContent) int {
+	if id == "" {
+		return 0
+	}
+	replaced := 0
+	for _, items := range [][]windows.ClipboardContent{c.history, c.queue} {
+		for i := range items {
+			if items[i].ID == id && items[i].Type == windows.Text && items[i].Text == old {
+				changed := withText(items[i], result.Text)
+				changed.Sensitive, changed.Preview = result.Sensitive, result.Preview
+				items[i] = changed
+				replaced++
+			}
+		}
+	}
+	return replaced
+}
+
+// withText возвращает текстовый элемент с новым текстом, сохраняя ID, время копирования,
+// приложение-источник, название и место в цепочке правок. Хеш пересчитывается, ID — нет:
+// ссылки на элемент остаются действительными. Пометку секрета для нового текста
+// вызывающий определяет сам.
+func withText(content windows.ClipboardContent, text string) windows.ClipboardContent {
+	changed := windows.NewTextContent(text)
+	changed.ID = content.ID
+	changed.Timestamp = content.Timestamp
+	changed.SourceApp, changed.SourceTitle = content.SourceApp, content.SourceTitle
+	changed.Title = content.Title
+	changed.Revision, changed.RevisionOf = content.Revision, content.RevisionOf
+	changed.Hash = contentHash(changed)
+	return changed
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestAutoTransformsSkipNonMatching(t *testing.T) {
+	c := newTestController()
+	cfg := &config.Config{Transforms: []config.Transform{
+		{Name: "json", Command: "команда, которой нет", Auto: true, Match: `^\s*[{\[]`},
+		{Name: "manual", Command: "команда, которой нет"},
+	}}
+	c.SetTransforms(cfg)
+
+	item := windows.NewTextContent("обычный текст")
+	if got := c.applyAutoTransforms(item); got.Text != item.Text || got.ID != item.ID {
+		t.Fatalf("текст без совпадения изменён: %+v", got)
+	}
+}
+
+func TestReplaceTextKeepsIDAndSkipsChangedItems(t *testing.T) {
+	c := newTestController()
+	item := windows.NewTextContent(`{"a":1}`)
+	other := windows.NewTextContent("другой")
+	c.history = []windows.ClipboardContent{other, item}
+	c.queue = []windows.ClipboardContent{item}
+
+	if n := c.replaceTextLocked(item.ID, `{"a":1}`, windows.NewTextContent("{\n  \"a\": 1\n}")); n != 2 {
+		t.Fatalf("заменено %d копий, ожидалось 2", n)
+	}
+	if got := c.history[1]; got.ID != item.ID || got.Timestamp != item.Timestamp || got.Text != "{\n  \"a\": 1\n}" {
+		t.Fatalf("элемент истории после замены: %+v", got)
+	}
+	if n := c.replaceTextLocked(item.ID, `{"a":1}`, windows.NewTextContent("x")); n != 0 {
+		t.Fatal("элемент, текст которого уже изменился, не должен заменяться")
+	}
+}
+
+func TestReplaceTextKeepsMetadataAndMarksSecret(t *testing.T) {
+	c := newTestController()
+	c.detectSensitive = true
+	item := windows.NewTextContent("номер карты")
+	item.Title = "Оплата"
+	item.Revision, item.RevisionOf = 2, "orig"
+	c.history = []windows.ClipboardContent{item}
+
+	result := c.markSensitive(windows.NewTextContent("4111 1111 1111 1111"))
+	if n := c.replaceTextLocked(item.ID, "номер карты", result); n != 1 {
+		t.Fatalf("заменено %d копий, ожидалась 1", n)
+	}
+	got := c.history[0]
+	if got.Title != "Оплата" || got.Revision != 2 || got.RevisionOf != "orig" {
+		t.Fatalf("название и цепочка правок потеряны: %+v", got)
+	}
+	if got.Sensitive != "card" || strings.Contains(got.Preview, "4111") {
+		t.Fatalf("результат преобразования не помечен как секрет: %+v", got)
+	}
+}
+
+func TestCaptureCleanup(t *testing.T) {
+	c := newTestController()
+	cfg := &config.Config{}
+	cfg.Clipboard.Normalize.StripInvisible = true
+	cfg.Clipboard.Normalize.Trim = true
+	c.SetCaptureFilters(cfg)
+
+	item := windows.NewTextContent("  git\u200b status\r\n\n")
+	item.SourceApp = "WindowsTerminal.exe"
+	if got := c.cleanCapture(item); got.Text != "git status" || got.ID != item.ID || got.SourceApp != item.SourceApp {
+		t.Fatalf("очищенный элемент: %+v", got)
+	}
+	blank := windows.NewTextContent(" \n ")
+	if got := c.cleanCapture(blank); got.Text != blank.Text {
+		t.Fatalf("текст из одних пробелов не должен становиться пустым: %q", got.Text)
+	}
+}
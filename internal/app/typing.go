@@ -0,0 +1,63 @@
+package app
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// slowTypingOptions — медленный режим: по одному символу (нажатие и отпускание)
+// за вызов SendInput с паузой не меньше 30 мс.
+var slowTypingOptions = windows.TypingOptions{ChunkSize: 2, ChunkDelay: 30 * time.Millisecond}
+
+// typingSettings — раздел input конфигурации.
+type typingSettings struct {
+	chunkSize  int
+	chunkDelay time.Duration
+	slow       bool
+}
+
+func typingSettingsFromConfig(cfg *config.Config) typingSettings {
+	return typingSettings{
+		chunkSize:  cfg.Input.TypeChunkSize,
+		chunkDelay: time.Duration(cfg.Input.TypeChunkDelayMs) * time.Millisecond,
+		slow:       cfg.Input.SlowMode,
+	}
+}
+
+// SetTypingOptions применяет раздел input: темп набора текста макросами type и type_hw.
+func (c *Controller) SetTypingOptions(cfg *config.Config) {
+	settings := typingSettingsFromConfig(cfg)
+	c.mu.Lock()
+	c.typing = settings
+	c.mu.Unlock()
+}
+
+// typingOptionsFor возвращает темп набора для макроса: его поля переопределяют
+// раздел input, медленный режим макроса или глобальный замедляет итоговый темп.
+func typingOptionsFor(settings typingSettings, macro config.Macro) windows.TypingOptions {
+	opts := windows.TypingOptions{ChunkSize: settings.chunkSize, ChunkDelay: settings.chunkDelay}
+	if macro.TypeChunkSize > 0 {
+		opts.ChunkSize = macro.TypeChunkSize
+	}
+	if macro.TypeChunkDelayMs > 0 {
+		opts.ChunkDelay = time.Duration(macro.TypeChunkDelayMs) * time.Millisecond
+	}
+	if settings.slow || macro.SlowTyping {
+		opts.ChunkSize = slowTypingOptions.ChunkSize
+		opts.ChunkDelay = max(opts.ChunkDelay, slowTypingOptions.ChunkDelay)
+	}
+	return opts
+}
+
+func (c *Controller) typingOptions(macro config.Macro) windows.TypingOptions {
+	c.mu.Lock()
+	settings := c.typing
+	c.mu.Unlock()
+	// Клиенты RDP и Citrix теряют символы при быстром наборе.
+	if c.pasteTiming(windows.GetForegroundWindowInfo()).slow {
+		settings.slow = true
+	}
+	return typingOptionsFor(settings, macro)
+}
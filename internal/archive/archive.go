@@ -0,0 +1,331 @@
+// Package archive записывает историю ClipQueue в переносимые файлы (JSON, CSV, ZIP)
+// и читает их обратно, чтобы историю можно было сохранить или перенести на другой компьютер.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/filebundle"
+)
+
+// Version — версия формата; чтение файлов более новой версии отклоняется.
+const Version = 1
+
+// Типы элементов совпадают с ContentType.String() в API.
+const (
+	TypeText  = "Text"
+	TypeImage = "Image"
+	TypeFiles = "Files"
+)
+
+// Format — формат файла экспорта.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatZIP  Format = "zip"
+)
+
+// Файлы внутри ZIP.
+const (
+	zipIndex     = "history.json"
+	zipImagesDir = "images/"
+	zipFilesDir  = "files/"
+)
+
+// csvHeader — заголовок CSV; по нему CSV узнаётся при импорте.
+var csvHeader = []string{"id", "timestamp", "type", "text", "files"}
+
+// Item — элемент истории в файле экспорта.
+type Item struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	Text      string    `json:"text,omitempty"`
+	Files     []string  `json:"files,omitempty"`
+	ImagePNG  []byte    `json:"imagePng,omitempty"`
+	ImageType string    `json:"imageType,omitempty"` // MIME-тип ImagePNG, если это не PNG
+	Image     string    `json:"image,omitempty"`     // Путь к изображению внутри ZIP вместо ImagePNG
+	// Embedded — содержимое файлов элемента Files (files.embed); в ZIP данные
+	// лежат отдельными файлами, а в индексе остаётся только путь к ним.
+	Embedded []filebundle.File `json:"embedded,omitempty"`
+}
+
+// Limits ограничивает распаковку ZIP: сжатый файл в несколько мегабайт может
+// разворачиваться в гигабайты. EntryBytes — предел одного изображения или
+// встроенного файла, TotalBytes — всего распакованного, включая индекс; 0 — без предела.
+type Limits struct {
+	EntryBytes int64
+	TotalBytes int64
+}
+
+// ErrTooLarge возвращается, когда распакованные данные ZIP превышают Limits.
+var ErrTooLarge = errors.New("распакованные данные архива превышают допустимый размер")
+
+type document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Items      []Item    `json:"items"`
+}
+
+// ParseFormat проверяет значение параметра format; пустое значение означает JSON.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCSV, FormatZIP:
+		return f, nil
+	}
+	return "", fmt.Errorf("неизвестный формат экспорта %q: ожидается json, csv или zip", s)
+}
+
+// Ext возвращает расширение файла формата.
+func (f Format) Ext() string { return "." + string(f) }
+
+// ContentType возвращает MIME-тип файла формата.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatZIP:
+		return "application/zip"
+	}
+	return "application/json"
+}
+
+// Write записывает элементы в w. CSV хранит только текст и списки файлов:
+// изображения в нём пропускаются. В ZIP изображения лежат отдельными PNG.
+// Возвращает число записанных элементов.
+func Write(w io.Writer, format Format, items []Item) (int, error) {
+	switch format {
+	case FormatJSON:
+		return len(items), json.NewEncoder(w).Encode(document{Version: Version, ExportedAt: time.Now(), Items: items})
+	case FormatCSV:
+		return writeCSV(w, items)
+	case FormatZIP:
+		return writeZIP(w, items)
+	}
+	return 0, fmt.Errorf("неизвестный формат экспорта %q", format)
+}
+
+func writeCSV(w io.Writer, items []Item) (int, error) {
+	// BOM нужен Excel, чтобы открыть файл в UTF-8.
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write(csvHeader); err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, item := range items {
+		if item.Type == TypeImage {
+			continue
+		}
+		record := []string{item.ID, item.Timestamp.Format(time.RFC3339Nano), item.Type, item.Text, strings.Join(item.Files, "\n")}
+		if err := cw.Write(record); err != nil {
+			return written, err
+		}
+		written++
+	}
+	cw.Flush()
+	return written, cw.Error()
+}
+
+func writeZIP(w io.Writer, items []Item) (int, error) {
+	zw := zip.NewWriter(w)
+	index := make([]Item, 0, len(items))
+	for _, item := range items {
+		if len(item.ImagePNG) > 0 {
+			ext := ".png"
+			if item.ImageType == "image/jpeg" {
+				ext = ".jpg"
+			}
+			name := zipImagesDir + safeName(item.ID) + ext
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: item.Timestamp})
+			if err != nil {
+				return 0, err
+			}
+			if _, err := f.Write(item.ImagePNG); err != nil {
+				return 0, err
+			}
+			item.ImagePNG, item.Image = nil, name
+		}
+		if len(item.Embedded) > 0 {
+			embedded := make([]filebundle.File, len(item.Embedded))
+			for i, file := range item.Embedded {
+				name := fmt.Sprintf("%s%s/%d/%s", zipFilesDir, safeName(item.ID), i, file.Name)
+				f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: item.Timestamp})
+				if err != nil {
+					return 0, err
+				}
+				if _, err := f.Write(file.Data); err != nil {
+					return 0, err
+				}
+				embedded[i] = filebundle.File{Name: file.Name, Path: name}
+			}
+			item.Embedded = embedded
+		}
+		index = append(index, item)
+	}
+	f, err := zw.Create(zipIndex)
+	if err != nil {
+		return 0, err
+	}
+	if err := json.NewEncoder(f).Encode(document{Version: Version, ExportedAt: time.Now(), Items: index}); err != nil {
+		return 0, err
+	}
+	return len(index), zw.Close()
+}
+
+// safeName оставляет в ID только символы, безопасные для имени файла.
+func safeName(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// Detect сообщает, похоже ли содержимое на файл экспорта ClipQueue, и его формат.
+func Detect(data []byte) (Format, bool) {
+	trimmed := bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("PK\x03\x04")):
+		return FormatZIP, true
+	case bytes.HasPrefix(trimmed, []byte(strings.Join(csvHeader, ","))):
+		return FormatCSV, true
+	case bytes.HasPrefix(bytes.TrimLeft(trimmed, " \t\r\n"), []byte("{")):
+		return FormatJSON, true
+	}
+	return "", false
+}
+
+// Read разбирает файл экспорта любого формата. В элементах из ZIP изображения
+// уже подставлены в ImagePNG; распаковка ограничена limits.
+func Read(data []byte, limits Limits) ([]Item, error) {
+	format, ok := Detect(data)
+	if !ok {
+		return nil, errors.New("не файл экспорта истории ClipQueue")
+	}
+	switch format {
+	case FormatZIP:
+		return readZIP(data, limits)
+	case FormatCSV:
+		return readCSV(data)
+	}
+	return readJSON(data)
+}
+
+func readJSON(data []byte) ([]Item, error) {
+	var doc document
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &doc); err != nil {
+		return nil, fmt.Errorf("некорректный JSON экспорта: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > Version {
+		return nil, fmt.Errorf("неподдерживаемая версия экспорта %d", doc.Version)
+	}
+	return doc.Items, nil
+}
+
+func readCSV(data []byte) ([]Item, error) {
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	cr.FieldsPerRecord = len(csvHeader)
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("некорректный CSV экспорта: %w", err)
+	}
+	items := make([]Item, 0, len(records))
+	for _, record := range records[1:] {
+		ts, err := time.Parse(time.RFC3339Nano, record[1])
+		if err != nil {
+			return nil, fmt.Errorf("строка %s: %w", record[0], err)
+		}
+		item := Item{ID: record[0], Timestamp: ts, Type: record[2], Text: record[3]}
+		if record[4] != "" {
+			item.Files = strings.Split(record[4], "\n")
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func readZIP(data []byte, limits Limits) ([]Item, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("некорректный ZIP экспорта: %w", err)
+	}
+	zf := &zipFiles{zr: zr, limits: limits}
+	index, err := zf.read(zipIndex, limits.TotalBytes)
+	if err != nil {
+		return nil, err
+	}
+	items, err := readJSON(index)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		for j, file := range items[i].Embedded {
+			if file.Path == "" {
+				continue
+			}
+			if items[i].Embedded[j].Data, err = zf.read(file.Path, limits.EntryBytes); err != nil {
+				return nil, err
+			}
+			items[i].Embedded[j].Path = ""
+		}
+		if items[i].Image == "" {
+			continue
+		}
+		if items[i].ImagePNG, err = zf.read(items[i].Image, limits.EntryBytes); err != nil {
+			return nil, err
+		}
+		items[i].Image = ""
+	}
+	return items, nil
+}
+
+// zipFiles читает файлы из ZIP и ведёт счёт распакованного для Limits.TotalBytes.
+type zipFiles struct {
+	zr     *zip.Reader
+	limits Limits
+	total  int64
+}
+
+// read распаковывает файл name не больше limit байт (0 — без предела) и не больше
+// остатка общего предела. Заголовку ZIP с размером не доверяет: читает через LimitReader.
+func (z *zipFiles) read(name string, limit int64) ([]byte, error) {
+	f, err := z.zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("в архиве нет %s: %w", name, err)
+	}
+	defer f.Close()
+	if z.limits.TotalBytes > 0 {
+		if rest := z.limits.TotalBytes - z.total; limit <= 0 || rest < limit {
+			limit = rest
+		}
+	}
+	if limit <= 0 && z.limits.TotalBytes <= 0 {
+		return io.ReadAll(f)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s: %w", name, ErrTooLarge)
+	}
+	z.total += int64(len(data))
+	return data, nil
+}
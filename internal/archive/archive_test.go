@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/filebundle"
+)
+
+func sampleItems() []Item {
+	at := time.Date(2026, 3, 1, 12, 30, 0, 123, time.UTC)
+	return []Item{
+		{ID: "1", Timestamp: at, Type: TypeText, Text: "строка, с \"кавычками\"\nи переводом"},
+		{ID: "2", Timestamp: at.Add(time.Second), Type: TypeFiles, Files: []string{`C:\a.txt`, `C:\b c.txt`}},
+		{ID: "3/../x", Timestamp: at.Add(2 * time.Second), Type: TypeImage, ImagePNG: []byte("\x89PNG fake")},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatCSV, FormatZIP} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			written, err := Write(&buf, format, sampleItems())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := Detect(buf.Bytes()); !ok || got != format {
+				t.Fatalf("Detect = %q, %v", got, ok)
+			}
+			items, err := Read(buf.Bytes(), Limits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := sampleItems()
+			if format == FormatCSV {
+				want = want[:2] // В CSV изображений нет
+			}
+			if written != len(want) || len(items) != len(want) {
+				t.Fatalf("записано %d, прочитано %d, ожидалось %d", written, len(items), len(want))
+			}
+			for i := range want {
+				if !items[i].Timestamp.Equal(want[i].Timestamp) {
+					t.Fatalf("элемент %d: время %v", i, items[i].Timestamp)
+				}
+				items[i].Timestamp = want[i].Timestamp
+				if !reflect.DeepEqual(items[i], want[i]) {
+					t.Fatalf("элемент %d: %+v, ожидалось %+v", i, items[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedFilesRoundTrip(t *testing.T) {
+	embedded := []filebundle.File{{Name: "отчёт.txt", Data: []byte("содержимое")}, {Name: "пустой.bin"}}
+	for _, format := range []Format{FormatJSON, FormatZIP} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			item := Item{ID: "f", Type: TypeFiles, Files: []string{`C:\отчёт.txt`, `C:\пустой.bin`}, Embedded: embedded}
+			if _, err := Write(&buf, format, []Item{item}); err != nil {
+				t.Fatal(err)
+			}
+			items, err := Read(buf.Bytes(), Limits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := items[0].Embedded
+			if len(got) != 2 || got[0].Name != "отчёт.txt" || string(got[0].Data) != "содержимое" || len(got[1].Data) != 0 || got[0].Path != "" {
+				t.Fatalf("встроенные файлы: %+v", got)
+			}
+		})
+	}
+}
+
+func TestReadRejectsNewerVersion(t *testing.T) {
+	_, err := Read([]byte(`{"version": 99, "items": []}`), Limits{})
+	if err == nil || !strings.Contains(err.Error(), "99") {
+		t.Fatalf("ожидалась ошибка версии, получено %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(""); err != nil || f != FormatJSON {
+		t.Fatalf("пустой формат: %q, %v", f, err)
+	}
+	if f, err := ParseFormat("ZIP"); err != nil || f != FormatZIP {
+		t.Fatalf("ZIP: %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatal("xml не поддерживается")
+	}
+}
+
+func TestReadZIPLimits(t *testing.T) {
+	// Сжатые нули: архив в несколько килобайт разворачивается в 2 МиБ.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	index, _ := zw.Create(zipIndex)
+	index.Write([]byte(`{"version":1,"items":[{"id":"a","type":"Image","image":"images/a.png"},{"id":"b","type":"Image","image":"images/b.png"}]}`))
+	for _, name := range []string{"images/a.png", "images/b.png"} {
+		f, _ := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		f.Write(bytes.Repeat([]byte{0}, 1<<20))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Read(buf.Bytes(), Limits{EntryBytes: 1 << 20, TotalBytes: 4 << 20}); err != nil {
+		t.Fatalf("архив в пределах лимитов: %v", err)
+	}
+	if _, err := Read(buf.Bytes(), Limits{EntryBytes: 1<<20 - 1}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("изображение сверх EntryBytes: ожидалась ErrTooLarge, получено %v", err)
+	}
+	if _, err := Read(buf.Bytes(), Limits{TotalBytes: 3 << 19}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("сумма сверх TotalBytes: ожидалась ErrTooLarge, получено %v", err)
+	}
+}
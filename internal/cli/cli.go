@@ -0,0 +1,316 @@
+// Package cli реализует консольный клиент ClipQueue: подкоманды обращаются
+// к HTTP API уже запущенного экземпляра.
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/instance"
+)
+
+// AddrEnv переопределяет адрес API запущенного экземпляра.
+const AddrEnv = "CLIPQUEUE_ADDR"
+
+const usage = `Использование: clipqueue <команда> [аргументы]
+
+Команды:
+  copy <текст>         записать текст в буфер обмена
+  push <текст>         добавить текст в очередь, не трогая буфер
+  push -image <файл>   добавить изображение (PNG, JPEG, GIF) в очередь
+  list [-n N]          показать последние элементы истории
+  paste-next           вставить следующий элемент очереди
+  toggle               включить или выключить запись в очередь
+  state                показать состояние очереди
+
+Адрес API берётся из %s или файла server.addr в каталоге данных.
+`
+
+// Options задаёт окружение клиента.
+type Options struct {
+	DataDir string
+	Addr    string
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Client  *http.Client
+}
+
+var commands = map[string]func(c *client, args []string) error{
+	"copy":       runCopy,
+	"push":       runPush,
+	"list":       runList,
+	"paste-next": runPasteNext,
+	"toggle":     runToggle,
+	"state":      runState,
+	"help":       runHelp,
+}
+
+// IsCommand сообщает, является ли аргумент подкомандой CLI.
+func IsCommand(arg string) bool {
+	_, ok := commands[arg]
+	return ok || arg == "-h" || arg == "--help"
+}
+
+// Run выполняет подкоманду и возвращает код выхода: 0 — успех, 1 — ошибка, 2 — неверный вызов.
+func Run(args []string, opts Options) int {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintf(opts.Stdout, usage, AddrEnv)
+		return 0
+	}
+	run, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(opts.Stderr, "неизвестная команда %q\n\n", args[0])
+		fmt.Fprintf(opts.Stderr, usage, AddrEnv)
+		return 2
+	}
+
+	c := &client{opts: opts}
+	if err := run(c, args[1:]); err != nil {
+		fmt.Fprintf(opts.Stderr, "clipqueue %s: %v\n", args[0], err)
+		var uerr usageError
+		if errors.As(err, &uerr) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+type client struct {
+	opts Options
+	base string
+}
+
+// baseURL определяет адрес API: явный Addr, затем переменная окружения, затем server.addr.
+func (c *client) baseURL() (string, error) {
+	if c.base != "" {
+		return c.base, nil
+	}
+	addr := c.opts.Addr
+	if addr == "" {
+		addr = os.Getenv(AddrEnv)
+	}
+	if addr == "" {
+		var err error
+		addr, err = instance.ReadAddr(c.opts.DataDir)
+		if err != nil {
+			return "", err
+		}
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	c.base = strings.TrimRight(addr, "/")
+	return c.base, nil
+}
+
+// do выполняет запрос и декодирует JSON-ответ в out (если out != nil).
+func (c *client) do(method, path, contentType string, body io.Reader, out any) error {
+	base, err := c.baseURL()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, base+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	// Без этого заголовка сервер не принимает multipart-загрузки: так их не
+	// может отправить сторонняя страница в браузере.
+	req.Header.Set("X-ClipQueue-Request", "1")
+	resp, err := c.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("нет связи с ClipQueue: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return errors.New(apiErr.Error)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *client) postJSON(path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, path, "application/json", bytes.NewReader(data), out)
+}
+
+type queueState struct {
+	Enabled bool   `json:"enabled"`
+	Count   int    `json:"count"`
+	Order   string `json:"order"`
+}
+
+func (c *client) printState(st queueState) {
+	status := "выключена"
+	if st.Enabled {
+		status = "включена"
+	}
+	fmt.Fprintf(c.opts.Stdout, "Очередь %s, элементов: %d, порядок: %s\n", status, st.Count, st.Order)
+}
+
+func runCopy(c *client, args []string) error {
+	if len(args) == 0 {
+		return usageError("нужен текст: clipqueue copy <текст>")
+	}
+	return c.postJSON("/api/copy", map[string]string{"text": strings.Join(args, " ")}, nil)
+}
+
+func runPush(c *client, args []string) error {
+	fs := flag.NewFlagSet("push", flag.ContinueOnError)
+	fs.SetOutput(c.opts.Stderr)
+	imagePath := fs.String("image", "", "файл изображения")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+
+	var resp struct {
+		ID    string     `json:"id"`
+		Queue queueState `json:"queue"`
+	}
+	var err error
+	switch {
+	case *imagePath != "":
+		err = c.pushImage(*imagePath, &resp)
+	case fs.NArg() > 0:
+		err = c.postJSON("/api/queue", map[string]string{"type": "text", "text": strings.Join(fs.Args(), " ")}, &resp)
+	default:
+		return usageError("нужен текст или -image <файл>")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.opts.Stdout, "Добавлено в очередь: %s\n", resp.ID)
+	c.printState(resp.Queue)
+	return nil
+}
+
+func (c *client) pushImage(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("image", filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, "/api/queue", mw.FormDataContentType(), &body, out)
+}
+
+func runList(c *client, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(c.opts.Stderr)
+	limit := fs.Int("n", 20, "сколько элементов показать")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+
+	var items []struct {
+		ID                 string `json:"id"`
+		Type               string `json:"type"`
+		Preview            string `json:"preview"`
+		IsQueued           bool   `json:"isQueued"`
+		QueueIndex         int    `json:"queueIndex"`
+		IsCurrentClipboard bool   `json:"isCurrentClipboard"`
+	}
+	if err := c.do(http.MethodGet, "/api/history", "", nil, &items); err != nil {
+		return err
+	}
+	for i, it := range items {
+		if *limit > 0 && i >= *limit {
+			break
+		}
+		mark := " "
+		if it.IsCurrentClipboard {
+			mark = "*"
+		}
+		queued := "   "
+		if it.IsQueued {
+			queued = fmt.Sprintf("Q%-2d", it.QueueIndex+1)
+		}
+		fmt.Fprintf(c.opts.Stdout, "%s %s %-6s %s  %s\n", mark, queued, it.Type, it.ID, it.Preview)
+	}
+	return nil
+}
+
+func runPasteNext(c *client, args []string) error {
+	var st queueState
+	if err := c.do(http.MethodPost, "/api/queue/paste-next", "", nil, &st); err != nil {
+		return err
+	}
+	c.printState(st)
+	return nil
+}
+
+func runToggle(c *client, args []string) error {
+	var st queueState
+	if err := c.do(http.MethodPost, "/api/queue/toggle", "", nil, &st); err != nil {
+		return err
+	}
+	c.printState(st)
+	return nil
+}
+
+func runState(c *client, args []string) error {
+	var st queueState
+	if err := c.do(http.MethodGet, "/api/queue/state", "", nil, &st); err != nil {
+		return err
+	}
+	c.printState(st)
+	return nil
+}
+
+func runHelp(c *client, args []string) error {
+	fmt.Fprintf(c.opts.Stdout, usage, AddrEnv)
+	return nil
+}
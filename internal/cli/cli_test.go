@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/instance"
+)
+
+func runCLI(t *testing.T, addr string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, Options{DataDir: t.TempDir(), Addr: addr, Stdout: &stdout, Stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestPushSendsTextToQueue(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/queue" {
+			t.Errorf("неожиданный запрос %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, `{"id":"42","queue":{"enabled":true,"count":3,"order":"FIFO"}}`)
+	}))
+	defer srv.Close()
+
+	code, out, errOut := runCLI(t, srv.URL, "push", "hello", "world")
+
+	if code != 0 {
+		t.Fatalf("код выхода %d, stderr: %s", code, errOut)
+	}
+	if got["type"] != "text" || got["text"] != "hello world" {
+		t.Fatalf("неожиданное тело запроса: %v", got)
+	}
+	if !strings.Contains(out, "42") || !strings.Contains(out, "элементов: 3") {
+		t.Fatalf("неожиданный вывод: %q", out)
+	}
+}
+
+func TestPushImageSendsRequestHeader(t *testing.T) {
+	var header, field string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-ClipQueue-Request")
+		if file, fh, err := r.FormFile("image"); err == nil {
+			file.Close()
+			field = fh.Filename
+		}
+		io.WriteString(w, `{"id":"7","queue":{"enabled":true,"count":1,"order":"FIFO"}}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(path, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, errOut := runCLI(t, srv.URL, "push", "-image", path)
+
+	if code != 0 {
+		t.Fatalf("код выхода %d, stderr: %s", code, errOut)
+	}
+	if header == "" || field != "shot.png" {
+		t.Fatalf("заголовок %q, файл %q", header, field)
+	}
+}
+
+func TestAPIErrorIsReported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, `{"error":"очередь выключена в конфигурации"}`)
+	}))
+	defer srv.Close()
+
+	code, _, errOut := runCLI(t, srv.URL, "push", "x")
+
+	if code != 1 || !strings.Contains(errOut, "очередь выключена") {
+		t.Fatalf("код %d, stderr %q", code, errOut)
+	}
+}
+
+func TestListPrintsHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":"1","type":"Text","preview":"first","isQueued":true,"queueIndex":0,"isCurrentClipboard":true},{"id":"2","type":"Image","preview":"10x10 PNG","queueIndex":-1}]`)
+	}))
+	defer srv.Close()
+
+	code, out, _ := runCLI(t, srv.URL, "list", "-n", "1")
+
+	if code != 0 {
+		t.Fatalf("код выхода %d", code)
+	}
+	if !strings.Contains(out, "* Q1") || !strings.Contains(out, "first") || strings.Contains(out, "10x10") {
+		t.Fatalf("неожиданный вывод: %q", out)
+	}
+}
+
+func TestAddrFromDataDir(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"enabled":false,"count":0,"order":"LIFO"}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if err := instance.WriteAddr(dir, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(AddrEnv, "")
+	var stdout bytes.Buffer
+	if code := Run([]string{"state"}, Options{DataDir: dir, Stdout: &stdout, Stderr: io.Discard}); code != 0 {
+		t.Fatalf("код выхода %d", code)
+	}
+	if !strings.Contains(stdout.String(), "выключена") {
+		t.Fatalf("неожиданный вывод: %q", stdout.String())
+	}
+}
+
+func TestUnknownCommandAndUsageErrors(t *testing.T) {
+	if code, _, _ := runCLI(t, "http://127.0.0.1:1", "bogus"); code != 2 {
+		t.Fatalf("для неизвестной команды ожидался код 2, получен %d", code)
+	}
+	if code, _, _ := runCLI(t, "http://127.0.0.1:1", "copy"); code != 2 {
+		t.Fatalf("для copy без текста ожидался код 2, получен %d", code)
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"hash/fnv"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/serty2005/clipqueue/internal/filebundle"
+	"github.com/serty2005/clipqueue/internal/i18n"
 	"gopkg.in/yaml.v3"
 )
 
@@ -82,6 +84,12 @@ func parseHotkey(hotkeyString string) (uint32, uint32, error) {
 	}
 	return modifiers, vk, nil
 }
+
+// HotkeySignature возвращает сигнатуру макроса для сочетания вида "Ctrl+Shift+A".
+func HotkeySignature(hotkey string) (string, error) {
+	return generateSignatureFromHotkey(hotkey)
+}
+
 func generateSignatureFromHotkey(hotkeyString string) (string, error) {
 	modifiers, vk, err := parseHotkey(hotkeyString)
 	if err != nil {
@@ -108,12 +116,53 @@ type Macro struct {
 	Name                    string `yaml:"name" json:"name"`
 	Hotkey                  string `yaml:"hotkey" json:"hotkey"`
 	Signature               string `yaml:"signature" json:"signature"`
-	Enabled                 bool   `yaml:"enabled,omitempty" json:"enabled"`
+	Enabled                 bool   `yaml:"enabled" json:"enabled"` // Без omitempty: отсутствие поля в YAML означает true
 	Text                    string `yaml:"text" json:"text"`
 	Sequence                string `yaml:"sequence,omitempty" json:"sequence,omitempty"`
 	SequenceNormalizeDelays bool   `yaml:"sequence_normalize_delays,omitempty" json:"sequenceNormalizeDelays,omitempty"`
 	SequenceDelayMs         int    `yaml:"sequence_delay_ms,omitempty" json:"sequenceDelayMs,omitempty"`
-	Mode                    string `yaml:"mode" json:"mode"` // "type" (default), "paste", "type_hw", or "sequence"
+	Mode                    string `yaml:"mode" json:"mode"` // "type" (default), "paste", "type_hw", "sequence", "script", "transform", "ocr", "format", "screenshot" or "color"
+	Action                  string `yaml:"action,omitempty" json:"action,omitempty"`
+	// TypeChunkSize, TypeChunkDelayMs и SlowTyping переопределяют раздел input для
+	// этого макроса; 0 и false — как в input.
+	TypeChunkSize    int  `yaml:"type_chunk_size,omitempty" json:"typeChunkSize,omitempty"`
+	TypeChunkDelayMs int  `yaml:"type_chunk_delay_ms,omitempty" json:"typeChunkDelayMs,omitempty"`
+	SlowTyping       bool `yaml:"slow_typing,omitempty" json:"slowTyping,omitempty"`
+}
+
+// Transform — внешняя команда, через которую пропускается текст буфера: текст
+// подаётся на stdin, stdout заменяет элемент. Запускается макросом режима
+// transform (имя преобразования задаётся в Macro.Action) или автоматически для
+// каждого нового текста при Auto.
+type Transform struct {
+	Name      string `yaml:"name" json:"name"`
+	Command   string `yaml:"command" json:"command"`
+	Auto      bool   `yaml:"auto" json:"auto"`
+	Match     string `yaml:"match,omitempty" json:"match,omitempty"`          // Регулярное выражение: Auto срабатывает только на совпадающий текст
+	TimeoutMs int    `yaml:"timeout_ms,omitempty" json:"timeoutMs,omitempty"` // 0 — 5 секунд
+}
+
+// Способы вставки элемента в окно-получатель.
+const (
+	PasteMethodCtrlV   = "paste"    // Нажатие Ctrl+V
+	PasteMethodMessage = "wm_paste" // Сообщение WM_PASTE элементу с фокусом
+)
+
+// PasteMethodRule выбирает способ вставки для приложения: некоторые окна
+// (консоли, старые элементы управления) не реагируют на Ctrl+V от SendInput.
+// Правило может также увеличить ожидание чтения буфера и пропустить текст
+// через преобразование перед вставкой.
+type PasteMethodRule struct {
+	Process        string `yaml:"process" json:"process"`                                     // Имя исполняемого файла, например cmd.exe
+	Method         string `yaml:"method" json:"method"`                                       // paste или wm_paste
+	RestoreDelayMs int    `yaml:"restore_delay_ms,omitempty" json:"restoreDelayMs,omitempty"` // Больше clipboard.restore_delay_ms; 0 — как у остальных
+	Transform      string `yaml:"transform,omitempty" json:"transform,omitempty"`             // Имя из transforms
+}
+
+// LineEndingRule задаёт перевод строки текста, вставляемого в приложение.
+type LineEndingRule struct {
+	Process string `yaml:"process" json:"process"` // Имя исполняемого файла, например wsl.exe
+	Mode    string `yaml:"mode" json:"mode"`       // lf, crlf или keep
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling for backward compatibility
@@ -136,6 +185,10 @@ func (m *Macro) UnmarshalYAML(value *yaml.Node) error {
 			SequenceNormalizeDelays bool   `yaml:"sequence_normalize_delays"`
 			SequenceDelayMs         int    `yaml:"sequence_delay_ms"`
 			Mode                    string `yaml:"mode"`
+			Action                  string `yaml:"action"`
+			TypeChunkSize           int    `yaml:"type_chunk_size"`
+			TypeChunkDelayMs        int    `yaml:"type_chunk_delay_ms"`
+			SlowTyping              bool   `yaml:"slow_typing"`
 		}
 		var aux macroDecoded
 		if err := value.Decode(&aux); err != nil {
@@ -149,6 +202,10 @@ func (m *Macro) UnmarshalYAML(value *yaml.Node) error {
 		m.SequenceNormalizeDelays = aux.SequenceNormalizeDelays
 		m.SequenceDelayMs = aux.SequenceDelayMs
 		m.Mode = aux.Mode
+		m.Action = aux.Action
+		m.TypeChunkSize = aux.TypeChunkSize
+		m.TypeChunkDelayMs = aux.TypeChunkDelayMs
+		m.SlowTyping = aux.SlowTyping
 		if aux.Enabled == nil {
 			m.Enabled = true
 		} else {
@@ -163,28 +220,6 @@ func (m *Macro) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-type oldConfig struct {
-	App struct {
-		DataDir string `yaml:"data_dir" json:"dataDir"`
-		Silent  bool   `yaml:"silent" json:"silent"`
-		Logs    bool   `yaml:"logs" json:"logs"`
-	} `yaml:"app"`
-	Hotkeys struct {
-		ToggleQueue      string `yaml:"toggle_queue" json:"toggleQueue"`
-		PasteNext        string `yaml:"paste_next" json:"pasteNext"`
-		ToggleQueueOrder string `yaml:"toggle_queue_order" json:"toggleQueueOrder"`
-	} `yaml:"hotkeys"`
-	Clipboard struct {
-		WatchDebounceMs int `yaml:"watch_debounce_ms" json:"watchDebounceMs"`
-		PasteDelayMs    int `yaml:"paste_delay_ms" json:"pasteDelayMs"`
-		RestoreDelayMs  int `yaml:"restore_delay_ms" json:"restoreDelayMs"`
-	} `yaml:"clipboard"`
-	Queue struct {
-		DefaultOrder string `yaml:"default_order" json:"defaultOrder"`
-	} `yaml:"queue"`
-	Macros map[string]Macro `yaml:"macros"`
-}
-
 type UIConfig struct {
 	Visible   bool `yaml:"visible" json:"visible"`
 	HasBounds bool `yaml:"has_bounds" json:"hasBounds"`
@@ -195,10 +230,26 @@ type UIConfig struct {
 }
 
 type Config struct {
-	App struct {
-		DataDir string `yaml:"data_dir" json:"dataDir"`
-		Silent  bool   `yaml:"silent" json:"silent"`
-		Logs    bool   `yaml:"logs" json:"logs"`
+	// Version — версия формата файла; Load переводит старые файлы на CurrentVersion.
+	Version int `yaml:"version" json:"version"`
+	App     struct {
+		DataDir   string `yaml:"data_dir" json:"dataDir"`
+		Silent    bool   `yaml:"silent" json:"silent"`
+		Logs      bool   `yaml:"logs" json:"logs"`
+		Autostart bool   `yaml:"autostart" json:"autostart"`
+		// PauseHooksOnLock снимает хуки клавиатуры и мыши, пока сеанс заблокирован.
+		PauseHooksOnLock bool `yaml:"pause_hooks_on_lock" json:"pauseHooksOnLock"`
+		// PersistState сохраняет очередь и историю в state.json при выходе и завершении сеанса.
+		// Выключено по умолчанию: без него история живёт только в памяти.
+		PersistState bool `yaml:"persist_state" json:"persistState"`
+		// Language — язык меню трея и ошибок API: auto, en или ru.
+		Language string `yaml:"language" json:"language"`
+		// AutoElevate перезапускает ClipQueue от имени администратора (через запрос UAC),
+		// когда вставка отменена из-за окна, запущенного с повышенными правами.
+		AutoElevate bool `yaml:"auto_elevate" json:"autoElevate"`
+		// PauseMinutes — через сколько минут пауза из трея или по хоткею снимается
+		// сама; 0 — только вручную.
+		PauseMinutes int `yaml:"pause_minutes" json:"pauseMinutes"`
 	} `yaml:"app" json:"app"`
 	Hotkeys struct {
 		ToggleQueue             string `yaml:"toggle_queue" json:"toggleQueue"`
@@ -209,23 +260,231 @@ type Config struct {
 		PasteNextDisplay        string `yaml:"paste_next_display" json:"pasteNextDisplay"`
 		ToggleQueueOrderDisplay string `yaml:"toggle_queue_order_display" json:"toggleQueueOrderDisplay"`
 		ToggleUIDisplay         string `yaml:"toggle_ui_display" json:"toggleUIDisplay"`
+		// Pause — сочетание вида "Ctrl+Alt+P" для паузы ClipQueue. Регистрируется через
+		// RegisterHotKey, а не хуком, чтобы снимать паузу, пока хуки сняты.
+		Pause string `yaml:"pause" json:"pause"`
 	} `yaml:"hotkeys" json:"hotkeys"`
+	// HotkeyProfiles — наборы системных хоткеев, между которыми можно переключаться
+	// без перезапуска хоткеем cycle, из трея или через API.
+	HotkeyProfiles struct {
+		Active   string          `yaml:"active" json:"active"` // Имя активного набора; пустое — основной набор hotkeys
+		Cycle    string          `yaml:"cycle" json:"cycle"`   // Хоткей перехода к следующему набору; действует во всех наборах
+		Profiles []HotkeyProfile `yaml:"profiles" json:"profiles"`
+	} `yaml:"hotkey_profiles" json:"hotkeyProfiles"`
 	Clipboard struct {
 		WatchDebounceMs int `yaml:"watch_debounce_ms" json:"watchDebounceMs"`
 		PasteDelayMs    int `yaml:"paste_delay_ms" json:"pasteDelayMs"`
 		RestoreDelayMs  int `yaml:"restore_delay_ms" json:"restoreDelayMs"`
+		// IgnorePatterns — регулярные выражения: совпавший с любым из них скопированный
+		// текст не попадает ни в историю, ни в очередь (одноразовые коды, ключи API).
+		IgnorePatterns []string `yaml:"ignore_patterns" json:"ignorePatterns"`
+		// DetectSensitive помечает текст с номерами карт, JWT и закрытыми ключами
+		// как секрет и маскирует его предпросмотр.
+		DetectSensitive bool `yaml:"detect_sensitive" json:"detectSensitive"`
+		// Normalize приводит скопированный текст к форме Unicode Form ("nfc" или "nfkc";
+		// пусто — не менять) и при StripInvisible удаляет символы нулевой ширины и
+		// управления направлением, незаметные при вставке в терминал и код. Trim
+		// срезает пробелы и переводы строк в начале и конце текста.
+		Normalize struct {
+			Form           string `yaml:"form" json:"form"`
+			StripInvisible bool   `yaml:"strip_invisible" json:"stripInvisible"`
+			Trim           bool   `yaml:"trim" json:"trim"`
+		} `yaml:"normalize" json:"normalize"`
+		// CleanURLs удаляет из ссылок в скопированном тексте параметры запроса,
+		// совпавшие с шаблонами Params (utm_*, fbclid), до попадания в историю и очередь.
+		CleanURLs struct {
+			Enabled bool     `yaml:"enabled" json:"enabled"`
+			Params  []string `yaml:"params" json:"params"`
+		} `yaml:"clean_urls" json:"cleanUrls"`
+		// FetchTitles — для текста из одной ссылки загружать в фоне <title> страницы
+		// и показывать его в истории вместо ссылки; TimeoutMs ограничивает запрос.
+		FetchTitles struct {
+			Enabled   bool `yaml:"enabled" json:"enabled"`
+			TimeoutMs int  `yaml:"timeout_ms" json:"timeoutMs"`
+		} `yaml:"fetch_titles" json:"fetchTitles"`
+		// MaxItemBytes и MaxImagePixels ограничивают элемент, который читается в память:
+		// размер данных в буфере (для изображения — DIB) и число пикселей изображения.
+		// Элемент сверх лимита сохраняется заглушкой без содержимого; 0 — без ограничения.
+		MaxItemBytes   int64 `yaml:"max_item_bytes" json:"maxItemBytes"`
+		MaxImagePixels int64 `yaml:"max_image_pixels" json:"maxImagePixels"`
+		// AutoClearSeconds — через сколько секунд очищать буфер после того, как в него
+		// записало само приложение; 0 — не очищать. По умолчанию очищаются только секреты,
+		// AutoClearAll распространяет очистку на любое записанное содержимое.
+		AutoClearSeconds int  `yaml:"auto_clear_seconds" json:"autoClearSeconds"`
+		AutoClearAll     bool `yaml:"auto_clear_all" json:"autoClearAll"`
+		// PasteMethods — способ вставки по приложению-получателю; для остальных — Ctrl+V.
+		PasteMethods []PasteMethodRule `yaml:"paste_methods" json:"pasteMethods"`
+		// LineEndings приводит переводы строк вставляемого текста к Default или к
+		// режиму первого правила Rules для приложения-получателя; пусто — как есть.
+		LineEndings struct {
+			Default string           `yaml:"default" json:"default"`
+			Rules   []LineEndingRule `yaml:"rules" json:"rules"`
+		} `yaml:"line_endings" json:"lineEndings"`
+		// OpenRetry — повторные попытки открыть буфер, занятый другим приложением:
+		// пауза растёт вдвое от InitialDelayMs до MaxDelayMs и случайно отклоняется
+		// на долю Jitter (0.2 — ±20 %).
+		OpenRetry struct {
+			Attempts       int     `yaml:"attempts" json:"attempts"`
+			InitialDelayMs int     `yaml:"initial_delay_ms" json:"initialDelayMs"`
+			MaxDelayMs     int     `yaml:"max_delay_ms" json:"maxDelayMs"`
+			Jitter         float64 `yaml:"jitter" json:"jitter"`
+		} `yaml:"open_retry" json:"openRetry"`
 	} `yaml:"clipboard" json:"clipboard"`
 	Queue struct {
 		DefaultOrder string `yaml:"default_order" json:"defaultOrder"`
+		// AutoDisableMinutes — через сколько минут без вставок выключать режим записи; 0 — никогда.
+		AutoDisableMinutes int `yaml:"auto_disable_minutes" json:"autoDisableMinutes"`
 	} `yaml:"queue" json:"queue"`
+	// Audit — журнал вставок из очереди и макросов в audit.jsonl внутри app.data_dir.
+	Audit struct {
+		Enabled       bool `yaml:"enabled" json:"enabled"`
+		RetentionDays int  `yaml:"retention_days" json:"retentionDays"` // Записи старше удаляются; 0 — хранить всегда
+	} `yaml:"audit" json:"audit"`
+	// Fullscreen — приостановка хоткеев и захвата буфера обмена, пока активно
+	// полноэкранное окно (игра, презентация).
+	Fullscreen struct {
+		Suspend bool     `yaml:"suspend" json:"suspend"`
+		Allow   []string `yaml:"allow" json:"allow"` // Процессы, при которых ClipQueue работает и в полноэкранном режиме
+		Deny    []string `yaml:"deny" json:"deny"`   // Если список не пуст, приостанавливают только эти процессы
+	} `yaml:"fullscreen" json:"fullscreen"`
+	// RemoteSession — задержки вставки и темп набора, когда получатель — окно клиента
+	// удалённого рабочего стола (RDP, Citrix) или ClipQueue сам запущен в удалённом сеансе.
+	// Значения действуют, только если они больше обычных.
+	RemoteSession struct {
+		Adaptive       bool     `yaml:"adaptive" json:"adaptive"`
+		Clients        []string `yaml:"clients" json:"clients"`                 // Процессы клиентов удалённого рабочего стола
+		PasteDelayMs   int      `yaml:"paste_delay_ms" json:"pasteDelayMs"`     // Пауза между записью в буфер и нажатием Ctrl+V
+		RestoreDelayMs int      `yaml:"restore_delay_ms" json:"restoreDelayMs"` // Сколько ждать чтения буфера перед восстановлением
+		SlowTyping     bool     `yaml:"slow_typing" json:"slowTyping"`          // Набирать макросы type и type_hw как input.slow_mode
+	} `yaml:"remote_session" json:"remoteSession"`
+	// Input — темп набора текста макросами type и type_hw: события ввода отправляются
+	// порциями по TypeChunkSize (0 — по умолчанию, 50) с паузой TypeChunkDelayMs.
+	// SlowMode набирает по одному символу с паузой не меньше 30 мс — для сеансов RDP
+	// и Citrix, которые теряют символы.
+	Input struct {
+		TypeChunkSize    int  `yaml:"type_chunk_size" json:"typeChunkSize"`
+		TypeChunkDelayMs int  `yaml:"type_chunk_delay_ms" json:"typeChunkDelayMs"`
+		SlowMode         bool `yaml:"slow_mode" json:"slowMode"`
+	} `yaml:"input" json:"input"`
+	History struct {
+		MaxItems      int    `yaml:"max_items" json:"maxItems"`
+		MaxTotalBytes int64  `yaml:"max_total_bytes" json:"maxTotalBytes"`
+		TTL           string `yaml:"ttl" json:"ttl"`
+		// SensitiveTTL — время жизни элементов-секретов в истории и очереди; пустое — как у остальных.
+		SensitiveTTL string `yaml:"sensitive_ttl" json:"sensitiveTTL"`
+		// ImageFormat — как история хранит изображения: original, jpeg или png (только уменьшение).
+		// Элемент очереди сохраняет оригинал до вставки.
+		ImageFormat       string `yaml:"image_format" json:"imageFormat"`
+		ImageMaxDimension int    `yaml:"image_max_dimension" json:"imageMaxDimension"`
+		ImageQuality      int    `yaml:"image_quality" json:"imageQuality"`
+		// DedupBump — переносить повторно скопированный элемент в конец истории и очереди
+		// с новым временем; иначе он остаётся на прежнем месте.
+		DedupBump bool `yaml:"dedup_bump" json:"dedupBump"`
+	} `yaml:"history" json:"history"`
 	Features struct {
 		EnableQueue     bool `yaml:"enable_queue" json:"enableQueue"`
 		EnableClipboard bool `yaml:"enable_clipboard" json:"enableClipboard"`
 		EnableMacros    bool `yaml:"enable_macros" json:"enableMacros"`
 		EnableLab       bool `yaml:"enable_lab" json:"enableLab"`
 	} `yaml:"features" json:"features"`
-	UI     UIConfig `yaml:"ui" json:"ui"`
-	Macros []Macro  `yaml:"macros" json:"macros"`
+	Logging struct {
+		Format     string            `yaml:"format" json:"format"` // "text" (по умолчанию) или "json"
+		Level      string            `yaml:"level" json:"level"`   // debug, info, warn, error
+		Modules    map[string]string `yaml:"modules,omitempty" json:"modules,omitempty"`
+		MaxSizeMB  int               `yaml:"max_size_mb" json:"maxSizeMB"`
+		MaxFiles   int               `yaml:"max_files" json:"maxFiles"`
+		MaxAgeDays int               `yaml:"max_age_days" json:"maxAgeDays"`
+		Compress   bool              `yaml:"compress" json:"compress"`
+	} `yaml:"logging" json:"logging"`
+	Debug struct {
+		EnablePprof bool `yaml:"enable_pprof" json:"enablePprof"`
+	} `yaml:"debug" json:"debug"`
+	Notifications struct {
+		Enabled bool `yaml:"enabled" json:"enabled"`
+	} `yaml:"notifications" json:"notifications"`
+	IPC struct {
+		NamedPipe bool `yaml:"named_pipe" json:"namedPipe"`
+	} `yaml:"ipc" json:"ipc"`
+	Updates struct {
+		Check         bool   `yaml:"check" json:"check"`
+		AutoDownload  bool   `yaml:"auto_download" json:"autoDownload"`
+		IntervalHours int    `yaml:"interval_hours" json:"intervalHours"`
+		Repo          string `yaml:"repo" json:"repo"`
+	} `yaml:"updates" json:"updates"`
+	// Sync — синхронизация элементов с другими экземплярами ClipQueue в локальной сети.
+	Sync struct {
+		Enabled bool     `yaml:"enabled" json:"enabled"`
+		Listen  string   `yaml:"listen" json:"listen"` // Адрес входящих подключений; пустой — только исходящие
+		Peers   []string `yaml:"peers" json:"peers"`   // Адреса host:port других узлов
+		Key     string   `yaml:"key" json:"key"`       // Общий пароль; из него выводится ключ шифрования
+		Images  bool     `yaml:"images" json:"images"` // Пересылать изображения, а не только текст
+	} `yaml:"sync" json:"sync"`
+	// Files — встраивание содержимого файлов в элементы Files при синхронизации и экспорте,
+	// чтобы на другом компьютере список файлов можно было вставить.
+	Files struct {
+		Embed         bool  `yaml:"embed" json:"embed"`
+		MaxEmbedBytes int64 `yaml:"max_embed_bytes" json:"maxEmbedBytes"` // Предел суммарного размера; 0 — без предела
+	} `yaml:"files" json:"files"`
+	// Webhooks — POST-запросы с JSON на внешние адреса при захвате, добавлении в очередь и вставке.
+	Webhooks struct {
+		URLs        []string `yaml:"urls" json:"urls"`
+		Events      []string `yaml:"events" json:"events"`            // capture, enqueue, paste; пустой список — все
+		IncludeText bool     `yaml:"include_text" json:"includeText"` // Полный текст вместо одного предпросмотра
+		Secret      string   `yaml:"secret" json:"secret"`            // Ключ подписи X-ClipQueue-Signature
+	} `yaml:"webhooks" json:"webhooks"`
+	// MQTT — публикация событий в брокер MQTT и приём текста для очереди из темы push.
+	MQTT struct {
+		Enabled     bool   `yaml:"enabled" json:"enabled"`
+		Broker      string `yaml:"broker" json:"broker"` // tcp://host:1883 или tls://host:8883
+		ClientID    string `yaml:"client_id" json:"clientId"`
+		Username    string `yaml:"username" json:"username"`
+		Password    string `yaml:"password" json:"password"`
+		TopicPrefix string `yaml:"topic_prefix" json:"topicPrefix"` // События публикуются в <topic_prefix>/<событие>
+		PushTopic   string `yaml:"push_topic" json:"pushTopic"`     // Текст из этой темы добавляется в очередь; пустая — без подписки
+		IncludeText bool   `yaml:"include_text" json:"includeText"`
+		Retain      bool   `yaml:"retain" json:"retain"`
+	} `yaml:"mqtt" json:"mqtt"`
+	// GRPC — сервис clipqueue.v1.ClipQueue для интеграции с другими программами.
+	GRPC struct {
+		Enabled bool   `yaml:"enabled" json:"enabled"`
+		Listen  string `yaml:"listen" json:"listen"`
+		Token   string `yaml:"token" json:"token"` // Непустой — требуется authorization: Bearer <token>
+	} `yaml:"grpc" json:"grpc"`
+	// Remote — доступ к API и веб-интерфейсу с других устройств локальной сети.
+	Remote struct {
+		Enabled bool   `yaml:"enabled" json:"enabled"`
+		Listen  string `yaml:"listen" json:"listen"` // Адрес в локальной сети, например 192.168.1.10:8765
+		Token   string `yaml:"token" json:"token"`   // Обязателен: authorization: Bearer <token> или вход по ссылке ?token=
+		// TLSCert и TLSKey — сертификат и ключ в PEM; заданы — слушатель работает по HTTPS,
+		// иначе токен передаётся по сети открытым текстом.
+		TLSCert string `yaml:"tls_cert" json:"tlsCert"`
+		TLSKey  string `yaml:"tls_key" json:"tlsKey"`
+		// AllowedOrigins — страницы других сайтов, которым разрешены запросы из браузера (CORS),
+		// например http://nas.local:8080; * — любые. Собственный интерфейс разрешён всегда.
+		AllowedOrigins []string `yaml:"allowed_origins" json:"allowedOrigins"`
+	} `yaml:"remote" json:"remote"`
+	// Plugins — скрипты Lua из каталога plugins внутри app.data_dir.
+	Plugins struct {
+		Enabled   bool `yaml:"enabled" json:"enabled"`
+		TimeoutMs int  `yaml:"timeout_ms" json:"timeoutMs"` // Предел одного вызова скрипта
+	} `yaml:"plugins" json:"plugins"`
+	// Lab — выполнение пайплайнов раздела Lab.
+	Lab struct {
+		AllowExec bool   `yaml:"allow_exec" json:"allowExec"` // Команды выполняются с правами пользователя, поэтому выключено по умолчанию
+		Shell     string `yaml:"shell" json:"shell"`          // cmd или powershell
+		TimeoutMs int    `yaml:"timeout_ms" json:"timeoutMs"`
+	} `yaml:"lab" json:"lab"`
+	// OCR — распознавание текста на изображениях из истории.
+	OCR struct {
+		Engine        string `yaml:"engine" json:"engine"`     // auto, windows или tesseract
+		Language      string `yaml:"language" json:"language"` // Язык Windows OCR (ru-RU); пустой — языки профиля
+		TesseractPath string `yaml:"tesseract_path" json:"tesseractPath"`
+		TesseractLang string `yaml:"tesseract_lang" json:"tesseractLang"` // Языки tesseract, например rus+eng
+		TimeoutMs     int    `yaml:"timeout_ms" json:"timeoutMs"`
+	} `yaml:"ocr" json:"ocr"`
+	Transforms []Transform `yaml:"transforms" json:"transforms"`
+	UI         UIConfig    `yaml:"ui" json:"ui"`
+	Macros     []Macro     `yaml:"macros" json:"macros"`
 }
 
 // SafeConfig wraps Config with RWMutex for thread-safe access
@@ -241,33 +500,27 @@ func NewSafeConfig(cfg *Config) *SafeConfig {
 	}
 }
 
-func executableDir() string {
-	exePath, err := os.Executable()
-	if err != nil || exePath == "" {
-		return "."
-	}
-	return filepath.Dir(exePath)
-}
-
-func ConfigPath() string {
-	return filepath.Join(executableDir(), "config.yml")
-}
-
-func ResolvePath(path string) string {
-	if path == "" {
-		return executableDir()
-	}
-	if filepath.IsAbs(path) {
-		return path
-	}
-	return filepath.Clean(filepath.Join(executableDir(), path))
-}
-
 func cloneConfig(src *Config) *Config {
 	copyCfg := defaultConfig()
 	*copyCfg = *src
 	copyCfg.Macros = make([]Macro, len(src.Macros))
 	copy(copyCfg.Macros, src.Macros)
+	copyCfg.Transforms = make([]Transform, len(src.Transforms))
+	copy(copyCfg.Transforms, src.Transforms)
+	copyCfg.Clipboard.IgnorePatterns = append([]string{}, src.Clipboard.IgnorePatterns...)
+	copyCfg.Clipboard.PasteMethods = append([]PasteMethodRule{}, src.Clipboard.PasteMethods...)
+	copyCfg.Clipboard.LineEndings.Rules = append([]LineEndingRule{}, src.Clipboard.LineEndings.Rules...)
+	copyCfg.Clipboard.CleanURLs.Params = append([]string{}, src.Clipboard.CleanURLs.Params...)
+	copyCfg.HotkeyProfiles.Profiles = append([]HotkeyProfile{}, src.HotkeyProfiles.Profiles...)
+	copyCfg.Fullscreen.Allow = append([]string{}, src.Fullscreen.Allow...)
+	copyCfg.Fullscreen.Deny = append([]string{}, src.Fullscreen.Deny...)
+	copyCfg.RemoteSession.Clients = append([]string{}, src.RemoteSession.Clients...)
+	if src.Logging.Modules != nil {
+		copyCfg.Logging.Modules = make(map[string]string, len(src.Logging.Modules))
+		for k, v := range src.Logging.Modules {
+			copyCfg.Logging.Modules[k] = v
+		}
+	}
 	return copyCfg
 }
 
@@ -316,10 +569,12 @@ func (sc *SafeConfig) Mutate(fn func(cfg *Config)) error {
 }
 
 func defaultConfig() *Config {
-	cfg := &Config{}
+	cfg := &Config{Version: CurrentVersion}
 	cfg.App.DataDir = "."
 	cfg.App.Silent = false
 	cfg.App.Logs = false
+	cfg.App.PauseHooksOnLock = true
+	cfg.App.Language = i18n.Auto
 	cfg.Hotkeys.ToggleQueueDisplay = "Ctrl+Alt+C"
 	cfg.Hotkeys.PasteNextDisplay = "Ctrl+Alt+V"
 	cfg.Hotkeys.ToggleQueue = "sig:AQADCgBDAC4AAAAAAAAB"
@@ -328,14 +583,75 @@ func defaultConfig() *Config {
 	cfg.Hotkeys.ToggleUI = ""
 	cfg.Hotkeys.ToggleQueueOrderDisplay = ""
 	cfg.Hotkeys.ToggleUIDisplay = ""
+	cfg.HotkeyProfiles.Profiles = []HotkeyProfile{}
 	cfg.Clipboard.WatchDebounceMs = 30
 	cfg.Clipboard.PasteDelayMs = 50
 	cfg.Clipboard.RestoreDelayMs = 250
+	cfg.Clipboard.IgnorePatterns = []string{}
+	cfg.Clipboard.PasteMethods = []PasteMethodRule{}
+	cfg.Clipboard.LineEndings.Rules = []LineEndingRule{}
+	cfg.Clipboard.CleanURLs.Params = []string{
+		"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid",
+		"mc_cid", "mc_eid", "igshid", "_openstat", "_hsenc", "_hsmi",
+	}
+	cfg.Clipboard.DetectSensitive = true
+	cfg.Clipboard.MaxItemBytes = 100 << 20
+	cfg.Clipboard.MaxImagePixels = 50_000_000
+	cfg.Clipboard.FetchTitles.TimeoutMs = 5000
+	cfg.Clipboard.OpenRetry.Attempts = 5
+	cfg.Clipboard.OpenRetry.InitialDelayMs = 50
+	cfg.Clipboard.OpenRetry.MaxDelayMs = 800
+	cfg.Clipboard.OpenRetry.Jitter = 0.2
 	cfg.Queue.DefaultOrder = "LIFO"
+	cfg.Input.TypeChunkSize = 50
+	cfg.Input.TypeChunkDelayMs = 20
+	cfg.History.MaxItems = 50
+	cfg.History.MaxTotalBytes = 0
+	cfg.History.TTL = ""
+	cfg.History.ImageFormat = "original"
+	cfg.History.ImageMaxDimension = 1920
+	cfg.History.ImageQuality = 80
+	cfg.History.DedupBump = true
+	cfg.Files.MaxEmbedBytes = filebundle.DefaultMaxBytes
 	cfg.Features.EnableQueue = true
 	cfg.Features.EnableClipboard = true
 	cfg.Features.EnableMacros = true
 	cfg.Features.EnableLab = false
+	cfg.Logging.Format = "text"
+	cfg.Logging.Level = "debug"
+	cfg.Logging.MaxSizeMB = 10
+	cfg.Logging.MaxFiles = 5
+	cfg.Logging.MaxAgeDays = 30
+	cfg.Logging.Compress = true
+	cfg.Notifications.Enabled = true
+	cfg.IPC.NamedPipe = true
+	cfg.Audit.Enabled = true
+	cfg.Audit.RetentionDays = 30
+	cfg.Fullscreen.Allow = []string{}
+	cfg.Fullscreen.Deny = []string{}
+	cfg.RemoteSession.Adaptive = true
+	cfg.RemoteSession.Clients = []string{"mstsc.exe", "msrdc.exe", "vmconnect.exe", "wfica32.exe", "cdviewer.exe", "vmware-view.exe"}
+	cfg.RemoteSession.PasteDelayMs = 150
+	cfg.RemoteSession.RestoreDelayMs = 1500
+	cfg.RemoteSession.SlowTyping = true
+	cfg.Updates.IntervalHours = 24
+	cfg.Updates.Repo = "serty2005/clipQueue"
+	cfg.Sync.Listen = ":47321"
+	cfg.Sync.Peers = []string{}
+	cfg.Sync.Images = true
+	cfg.Webhooks.URLs = []string{}
+	cfg.Webhooks.Events = []string{}
+	cfg.MQTT.ClientID = "clipqueue"
+	cfg.MQTT.TopicPrefix = "clipqueue"
+	cfg.MQTT.PushTopic = "clipqueue/push"
+	cfg.GRPC.Listen = "127.0.0.1:47322"
+	cfg.Plugins.TimeoutMs = 1000
+	cfg.Lab.Shell = "cmd"
+	cfg.Lab.TimeoutMs = 10000
+	cfg.OCR.Engine = "auto"
+	cfg.OCR.TesseractLang = "rus+eng"
+	cfg.OCR.TimeoutMs = 30000
+	cfg.Transforms = []Transform{}
 	cfg.UI.Visible = false
 	cfg.UI.HasBounds = false
 	cfg.UI.Width = 500
@@ -376,37 +692,49 @@ func EnsureSignatures(cfg *Config) error {
 	return nil
 }
 
-func validateConfig(cfg *Config) error {
-	validModes := map[string]bool{
-		"type":     true,
-		"paste":    true,
-		"type_hw":  true,
-		"sequence": true,
+// minSyncKeyLength совпадает с peersync.MinKeyLength: config не импортирует peersync,
+// потому что тот сам зависит от config через logger.
+const minSyncKeyLength = 16
+
+// webhookEvents повторяет webhook.Events по той же причине, что и minSyncKeyLength.
+var webhookEvents = map[string]bool{"capture": true, "enqueue": true, "paste": true}
+
+// mqttSchemes — схемы адреса брокера, которые понимает mqtt.ParseBroker.
+var mqttSchemes = map[string]bool{"tcp": true, "mqtt": true, "tls": true, "ssl": true, "mqtts": true}
+
+// screenshotActions — что снимает макрос режима screenshot; пустое значение — весь экран.
+var screenshotActions = map[string]bool{"": true, "full": true, "window": true, "region": true}
+
+// validMacroModes — режимы, которые понимает Controller.ExecuteMacro.
+var validMacroModes = map[string]bool{
+	"type":       true,
+	"paste":      true,
+	"type_hw":    true,
+	"sequence":   true,
+	"script":     true,
+	"transform":  true,
+	"ocr":        true,
+	"format":     true,
+	"screenshot": true,
+	"color":      true,
+}
+
+var validLogLevels = map[string]bool{"debug": true, "info": true, "warn": true, "warning": true, "error": true}
+
+// ParseHistoryTTL разбирает history.ttl ("72h", "30m"). Пустая строка и "0" отключают TTL.
+func ParseHistoryTTL(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return 0, nil
 	}
-	for i, macro := range cfg.Macros {
-		if macro.Hotkey == "" {
-			return fmt.Errorf("macro %d has empty hotkey", i)
-		}
-		if macro.Signature == "" {
-			return fmt.Errorf("macro %d has empty signature", i)
-		}
-		sig := macro.Signature
-		if strings.HasPrefix(sig, "sig:") {
-			sig = strings.TrimPrefix(sig, "sig:")
-		}
-		if _, err := base64.StdEncoding.DecodeString(sig); err != nil {
-			return fmt.Errorf("macro %d has invalid signature: %v", i, err)
-		}
-		if macro.Sequence != "" {
-			if _, err := base64.StdEncoding.DecodeString(macro.Sequence); err != nil {
-				return fmt.Errorf("macro %d has invalid sequence: %v", i, err)
-			}
-		}
-		if !validModes[macro.Mode] {
-			return fmt.Errorf("macro %d has invalid mode: %s", i, macro.Mode)
-		}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("history: некорректный ttl %q: %v", value, err)
 	}
-	return nil
+	if ttl < 0 {
+		return 0, fmt.Errorf("history: ttl не может быть отрицательным")
+	}
+	return ttl, nil
 }
 
 func Load() (*Config, error) {
@@ -429,49 +757,14 @@ func Load() (*Config, error) {
 	}
 
 	// Read existing config file
-	data, err := os.ReadFile(configPath)
+	original, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Try to parse as old config with map[string]Macro
-	oldCfg := &oldConfig{}
-	if err := yaml.Unmarshal(data, oldCfg); err == nil && len(oldCfg.Macros) > 0 {
-		// Migration: convert map to slice
-		cfg := defaultConfig()
-		cfg.App = oldCfg.App
-		cfg.Hotkeys.ToggleQueue = oldCfg.Hotkeys.ToggleQueue
-		cfg.Hotkeys.PasteNext = oldCfg.Hotkeys.PasteNext
-		cfg.Hotkeys.ToggleQueueOrder = oldCfg.Hotkeys.ToggleQueueOrder
-		cfg.Clipboard = oldCfg.Clipboard
-		cfg.Queue = oldCfg.Queue
-		cfg.Macros = make([]Macro, 0, len(oldCfg.Macros))
-		for sig, macro := range oldCfg.Macros {
-			generatedSig, err := generateSignatureFromHotkey(sig)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate signature for hotkey %s: %v", sig, err)
-			}
-			cfg.Macros = append(cfg.Macros, Macro{
-				Name:      sig,
-				Hotkey:    sig,
-				Signature: generatedSig,
-				Enabled:   true,
-				Text:      macro.Text,
-				Mode:      macro.Mode,
-			})
-		}
-		if err := validateConfig(cfg); err != nil {
-			return nil, err
-		}
-		// Save migrated config
-		if err := saveConfig(cfg); err != nil {
-			return nil, err
-		}
-		// Ensure data dir exists
-		if err := os.MkdirAll(ResolvePath(cfg.App.DataDir), 0755); err != nil {
-			return nil, err
-		}
-		return cfg, nil
+	data, from, err := migrate(original)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse as new config
@@ -483,6 +776,14 @@ func Load() (*Config, error) {
 	if err := validateConfig(cfg); err != nil {
 		return nil, err
 	}
+	if from < CurrentVersion {
+		if err := backupConfig(configPath, original, from); err != nil {
+			return nil, err
+		}
+		if err := saveConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
 
 	// Ensure data dir exists
 	if err := os.MkdirAll(ResolvePath(cfg.App.DataDir), 0755); err != nil {
@@ -492,7 +793,9 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// saveConfig записывает конфиг в config.yml всегда в текущей версии формата.
 func saveConfig(cfg *Config) error {
+	cfg.Version = CurrentVersion
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return err
@@ -500,5 +803,9 @@ func saveConfig(cfg *Config) error {
 
 	normalized := yamlQuotedYKeyPattern.ReplaceAllString(string(data), "${1}y:")
 
+	// В режиме AppData каталога ещё может не быть при первом запуске.
+	if err := os.MkdirAll(BaseDir(), 0755); err != nil {
+		return err
+	}
 	return os.WriteFile(ConfigPath(), []byte(normalized), 0644)
 }
@@ -0,0 +1,24 @@
+package config
+
+import "strings"
+
+// FullscreenSuspends сообщает, приостанавливать ли ClipQueue, пока процесс process
+// показывает полноэкранное окно. fullscreen.allow важнее fullscreen.deny; пустой
+// deny значит «все процессы, кроме allow».
+func (cfg *Config) FullscreenSuspends(process string) bool {
+	if !cfg.Fullscreen.Suspend || containsApp(cfg.Fullscreen.Allow, process) {
+		return false
+	}
+	return len(cfg.Fullscreen.Deny) == 0 || containsApp(cfg.Fullscreen.Deny, process)
+}
+
+// containsApp ищет имя исполняемого файла без учёта регистра; ".exe" можно не писать.
+func containsApp(list []string, process string) bool {
+	process = strings.TrimSuffix(strings.ToLower(process), ".exe")
+	for _, app := range list {
+		if strings.TrimSuffix(strings.ToLower(strings.TrimSpace(app)), ".exe") == process {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestFullscreenSuspends(t *testing.T) {
+	cfg := defaultConfig()
+	if cfg.FullscreenSuspends("game.exe") {
+		t.Fatal("по умолчанию приостановка выключена")
+	}
+
+	cfg.Fullscreen.Suspend = true
+	cfg.Fullscreen.Allow = []string{"POWERPNT"}
+	if !cfg.FullscreenSuspends("game.exe") {
+		t.Error("при пустом deny приостанавливает любой процесс")
+	}
+	if cfg.FullscreenSuspends("powerpnt.exe") {
+		t.Error("процесс из allow не должен приостанавливать")
+	}
+
+	cfg.Fullscreen.Deny = []string{"game.exe", "powerpnt.exe"}
+	if cfg.FullscreenSuspends("vlc.exe") {
+		t.Error("при непустом deny приостанавливают только его процессы")
+	}
+	if !cfg.FullscreenSuspends("Game.exe") {
+		t.Error("имя процесса сравнивается без учёта регистра")
+	}
+	if cfg.FullscreenSuspends("powerpnt.exe") {
+		t.Error("allow важнее deny")
+	}
+}
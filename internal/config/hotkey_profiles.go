@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HotkeyProfile — альтернативный набор системных хоткеев, например для игры или
+// удалённого сеанса, где привычные сочетания заняты. Хоткей задаётся текстом
+// (Ctrl+Alt+V) или сигнатурой sig:…; пустое поле — в этом наборе хоткей не регистрируется.
+type HotkeyProfile struct {
+	Name             string `yaml:"name" json:"name"`
+	ToggleQueue      string `yaml:"toggle_queue" json:"toggleQueue"`
+	PasteNext        string `yaml:"paste_next" json:"pasteNext"`
+	ToggleQueueOrder string `yaml:"toggle_queue_order" json:"toggleQueueOrder"`
+	ToggleUI         string `yaml:"toggle_ui" json:"toggleUI"`
+	// DisableMacros снимает хоткеи макросов, пока набор активен.
+	DisableMacros bool `yaml:"disable_macros" json:"disableMacros"`
+}
+
+// ActiveHotkeyProfile возвращает активный набор хоткеев; nil — действует основной
+// набор из раздела hotkeys.
+func (cfg *Config) ActiveHotkeyProfile() *HotkeyProfile {
+	return cfg.findHotkeyProfile(cfg.HotkeyProfiles.Active)
+}
+
+func (cfg *Config) findHotkeyProfile(name string) *HotkeyProfile {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	for i := range cfg.HotkeyProfiles.Profiles {
+		if strings.EqualFold(cfg.HotkeyProfiles.Profiles[i].Name, name) {
+			return &cfg.HotkeyProfiles.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// HotkeyProfileNames возвращает имена наборов в порядке переключения; первым
+// идёт пустое имя основного набора.
+func (cfg *Config) HotkeyProfileNames() []string {
+	names := []string{""}
+	for _, p := range cfg.HotkeyProfiles.Profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// NextHotkeyProfile возвращает имя набора, следующего за активным; после
+// последнего снова идёт основной.
+func (cfg *Config) NextHotkeyProfile() string {
+	active := cfg.ActiveHotkeyProfile()
+	names := cfg.HotkeyProfileNames()
+	for i, name := range names {
+		if active != nil && name == active.Name {
+			return names[(i+1)%len(names)]
+		}
+	}
+	if len(names) > 1 {
+		return names[1]
+	}
+	return ""
+}
+
+// SetHotkeyProfile делает активным набор name; пустое имя — основной набор.
+// Имя сохраняется в написании из конфига.
+func (cfg *Config) SetHotkeyProfile(name string) error {
+	if strings.TrimSpace(name) == "" {
+		cfg.HotkeyProfiles.Active = ""
+		return nil
+	}
+	p := cfg.findHotkeyProfile(name)
+	if p == nil {
+		return fmt.Errorf("набор хоткеев %q не найден", name)
+	}
+	cfg.HotkeyProfiles.Active = p.Name
+	return nil
+}
+
+// checkHotkeyProfiles проверяет раздел hotkey_profiles.
+func checkHotkeyProfiles(l *issueList, cfg *Config) {
+	checkHotkey := func(field, value string) {
+		if value == "" || strings.HasPrefix(value, "sig:") {
+			return
+		}
+		if _, _, err := parseHotkey(value); err != nil {
+			l.errorf(field, "%v", err)
+		}
+	}
+	checkHotkey("hotkey_profiles.cycle", cfg.HotkeyProfiles.Cycle)
+
+	names := make(map[string]int)
+	for i, p := range cfg.HotkeyProfiles.Profiles {
+		field := fmt.Sprintf("hotkey_profiles.profiles[%d]", i)
+		key := strings.ToLower(strings.TrimSpace(p.Name))
+		if key == "" {
+			l.errorf(field+".name", "нужно указать имя набора")
+		} else if j, ok := names[key]; ok {
+			l.errorf(field+".name", "имя %q уже занято набором %d", p.Name, j)
+		} else {
+			names[key] = i
+		}
+		checkHotkey(field+".toggle_queue", p.ToggleQueue)
+		checkHotkey(field+".paste_next", p.PasteNext)
+		checkHotkey(field+".toggle_queue_order", p.ToggleQueueOrder)
+		checkHotkey(field+".toggle_ui", p.ToggleUI)
+	}
+	if active := cfg.HotkeyProfiles.Active; active != "" && cfg.ActiveHotkeyProfile() == nil {
+		l.warnf("hotkey_profiles.active", "набор %q не найден, действует основной набор hotkeys", active)
+	}
+	if len(cfg.HotkeyProfiles.Profiles) > 0 && cfg.HotkeyProfiles.Cycle == "" {
+		l.warnf("hotkey_profiles.cycle", "без хоткея cycle набор переключается только из трея и API")
+	}
+}
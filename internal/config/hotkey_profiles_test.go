@@ -0,0 +1,57 @@
+package config
+
+import "testing"
+
+func TestHotkeyProfileSwitching(t *testing.T) {
+	cfg := defaultConfig()
+	if cfg.NextHotkeyProfile() != "" || cfg.ActiveHotkeyProfile() != nil {
+		t.Fatal("без наборов действует только основной")
+	}
+
+	cfg.HotkeyProfiles.Cycle = "Ctrl+Alt+F12"
+	cfg.HotkeyProfiles.Profiles = []HotkeyProfile{
+		{Name: "Game", PasteNext: "Ctrl+Alt+Shift+V", DisableMacros: true},
+		{Name: "RDP", PasteNext: "sig:AQADCgBWAC8AAAAAAAAB"},
+	}
+	var order []string
+	for range 3 {
+		next := cfg.NextHotkeyProfile()
+		if err := cfg.SetHotkeyProfile(next); err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, cfg.HotkeyProfiles.Active)
+	}
+	if order[0] != "Game" || order[1] != "RDP" || order[2] != "" {
+		t.Fatalf("наборы должны переключаться по кругу через основной: %q", order)
+	}
+
+	if err := cfg.SetHotkeyProfile("game"); err != nil || cfg.HotkeyProfiles.Active != "Game" {
+		t.Fatalf("имя набора сравнивается без учёта регистра: %v, %q", err, cfg.HotkeyProfiles.Active)
+	}
+	if p := cfg.ActiveHotkeyProfile(); p == nil || !p.DisableMacros {
+		t.Fatalf("активный набор: %+v", p)
+	}
+	if err := cfg.SetHotkeyProfile("nope"); err == nil || cfg.HotkeyProfiles.Active != "Game" {
+		t.Fatal("неизвестный набор не должен становиться активным")
+	}
+	if issues := Validate(cfg); len(issues) != 0 {
+		t.Fatalf("корректные наборы: %v", issues)
+	}
+
+	cfg.HotkeyProfiles.Profiles = append(cfg.HotkeyProfiles.Profiles, HotkeyProfile{Name: "rdp", ToggleUI: "Ctrl+Nope"})
+	cfg.HotkeyProfiles.Active = "missing"
+	got := make(map[string]string)
+	for _, issue := range Validate(cfg) {
+		got[issue.Field] = issue.Severity
+	}
+	want := map[string]string{
+		"hotkey_profiles.profiles[2].name":      SeverityError,
+		"hotkey_profiles.profiles[2].toggle_ui": SeverityError,
+		"hotkey_profiles.active":                SeverityWarning,
+	}
+	for field, severity := range want {
+		if got[field] != severity {
+			t.Errorf("%s: ожидался уровень %q, получено %v", field, severity, got)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Способы разрешить совпадение имён при импорте набора макросов.
+const (
+	MacroConflictSkip      = "skip"      // Макрос с тем же именем остаётся, импортируемый пропускается
+	MacroConflictOverwrite = "overwrite" // Импортируемый заменяет макрос с тем же именем
+	MacroConflictRename    = "rename"    // Импортируемый добавляется под именем «Имя (2)»
+)
+
+// MacroPack — файл обмена макросами: тот же раздел macros, что и в config.yml,
+// поэтому его можно вставить в конфиг и руками.
+type MacroPack struct {
+	Macros []Macro `yaml:"macros" json:"macros"`
+}
+
+// MacroImportSkip — макрос из набора, который не был импортирован, и причина.
+type MacroImportSkip struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// MacroChange — изменение одного макроса: Prev равен nil для добавленного.
+type MacroChange struct {
+	Prev, Next *Macro
+}
+
+// MacroImportResult — итог импорта набора макросов.
+type MacroImportResult struct {
+	Added   []string          `json:"added"`
+	Updated []string          `json:"updated"`
+	Skipped []MacroImportSkip `json:"skipped"`
+	Changes []MacroChange     `json:"-"` // Для перерегистрации хоткеев изменённых макросов
+}
+
+// SetDefaults заполняет поля, которые можно не указывать: без сигнатуры
+// используется хоткей, без режима — type.
+func (m *Macro) SetDefaults() {
+	if m.Signature == "" {
+		m.Signature = m.Hotkey
+	}
+	if m.Mode == "" {
+		m.Mode = "type"
+	}
+}
+
+// ParseMacroConflict проверяет способ разрешения конфликтов; пустой — skip.
+func ParseMacroConflict(conflict string) (string, error) {
+	switch conflict = strings.ToLower(strings.TrimSpace(conflict)); conflict {
+	case "":
+		return MacroConflictSkip, nil
+	case MacroConflictSkip, MacroConflictOverwrite, MacroConflictRename:
+		return conflict, nil
+	}
+	return "", fmt.Errorf("неизвестный способ разрешения конфликтов %q: допустимы %s, %s и %s",
+		conflict, MacroConflictSkip, MacroConflictOverwrite, MacroConflictRename)
+}
+
+// ParseMacroPack читает набор макросов из YAML или JSON: объект с полем macros
+// или просто список. Как и в config.yml, макрос без enabled считается включённым.
+func ParseMacroPack(data []byte) ([]Macro, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))
+	if len(data) == 0 {
+		return nil, errors.New("пустой набор макросов")
+	}
+	var macros []Macro
+	var err error
+	if data[0] == '{' || data[0] == '[' {
+		macros, err = parseMacroPackJSON(data)
+	} else {
+		var pack MacroPack
+		if err = yaml.Unmarshal(data, &pack); err != nil {
+			err = yaml.Unmarshal(data, &macros)
+		}
+		if len(pack.Macros) > 0 {
+			macros = pack.Macros
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать набор макросов: %w", err)
+	}
+	if len(macros) == 0 {
+		return nil, errors.New("в наборе нет макросов")
+	}
+	return macros, nil
+}
+
+func parseMacroPackJSON(data []byte) ([]Macro, error) {
+	var raw []json.RawMessage
+	if data[0] == '{' {
+		var pack struct {
+			Macros []json.RawMessage `json:"macros"`
+		}
+		if err := json.Unmarshal(data, &pack); err != nil {
+			return nil, err
+		}
+		raw = pack.Macros
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	macros := make([]Macro, 0, len(raw))
+	for _, item := range raw {
+		macro := Macro{Enabled: true}
+		if err := json.Unmarshal(item, &macro); err != nil {
+			return nil, err
+		}
+		macros = append(macros, macro)
+	}
+	return macros, nil
+}
+
+// MarshalMacroPack сохраняет макросы набором в YAML.
+func MarshalMacroPack(macros []Macro) ([]byte, error) {
+	return yaml.Marshal(MacroPack{Macros: macros})
+}
+
+// ImportMacros добавляет макросы набора, сопоставляя их с существующими по имени
+// (без учёта регистра); совпадения разрешаются способом conflict. Макрос, который
+// не прошёл проверку или чья сигнатура занята другим макросом, пропускается.
+func (sc *SafeConfig) ImportMacros(macros []Macro, conflict string) (MacroImportResult, error) {
+	var res MacroImportResult
+	err := sc.mutateMacros(func(cfg *Config) error {
+		res = importMacros(cfg, macros, conflict)
+		return nil
+	})
+	return res, err
+}
+
+func importMacros(cfg *Config, incoming []Macro, conflict string) MacroImportResult {
+	res := MacroImportResult{Added: []string{}, Updated: []string{}, Skipped: []MacroImportSkip{}}
+	skip := func(name, reason string) {
+		res.Skipped = append(res.Skipped, MacroImportSkip{Name: name, Reason: reason})
+	}
+	for _, macro := range incoming {
+		macro.SetDefaults()
+		macro.Name = strings.TrimSpace(macro.Name)
+		if macro.Name == "" {
+			skip(macro.Hotkey, "у макроса нет имени")
+			continue
+		}
+		if err := checkMacro(cfg, macro); err != nil {
+			skip(macro.Name, err.Error())
+			continue
+		}
+
+		i := macroNameIndex(cfg.Macros, macro.Name)
+		if i >= 0 && conflict == MacroConflictSkip {
+			skip(macro.Name, "макрос с таким именем уже есть")
+			continue
+		}
+		if i >= 0 && conflict == MacroConflictRename {
+			macro.Name = uniqueMacroName(cfg.Macros, macro.Name)
+			i = -1
+		}
+		if j := macroIndex(cfg.Macros, macro.Signature); j >= 0 && j != i {
+			skip(macro.Name, fmt.Sprintf("сочетание %s уже занято макросом %q", macro.Hotkey, cfg.Macros[j].Name))
+			continue
+		}
+
+		next := macro
+		if i >= 0 {
+			prev := cfg.Macros[i]
+			cfg.Macros[i] = macro
+			res.Updated = append(res.Updated, macro.Name)
+			res.Changes = append(res.Changes, MacroChange{Prev: &prev, Next: &next})
+			continue
+		}
+		cfg.Macros = append(cfg.Macros, macro)
+		res.Added = append(res.Added, macro.Name)
+		res.Changes = append(res.Changes, MacroChange{Next: &next})
+	}
+	return res
+}
+
+func macroNameIndex(macros []Macro, name string) int {
+	for i, macro := range macros {
+		if strings.EqualFold(strings.TrimSpace(macro.Name), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func uniqueMacroName(macros []Macro, name string) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if macroNameIndex(macros, candidate) < 0 {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMacroNotFound — макроса с такой сигнатурой нет.
+	ErrMacroNotFound = errors.New("макрос не найден")
+	// ErrMacroExists — сигнатура уже занята другим макросом.
+	ErrMacroExists = errors.New("макрос с такой сигнатурой уже есть")
+	// ErrInvalidMacro оборачивает ошибку проверки макроса.
+	ErrInvalidMacro = errors.New("некорректный макрос")
+)
+
+// Macro ищет макрос по сигнатуре.
+func (cfg *Config) Macro(signature string) (Macro, bool) {
+	if i := macroIndex(cfg.Macros, signature); i >= 0 {
+		return cfg.Macros[i], true
+	}
+	return Macro{}, false
+}
+
+// AddMacro проверяет макрос и добавляет его в конец списка, сохраняя конфиг.
+// Остальные настройки не меняются, поэтому вызов не конкурирует с сохранением
+// других разделов.
+func (sc *SafeConfig) AddMacro(macro Macro) error {
+	return sc.mutateMacros(func(cfg *Config) error {
+		macros, err := addMacro(cfg, macro)
+		cfg.Macros = macros
+		return err
+	})
+}
+
+// UpdateMacro заменяет макрос с сигнатурой signature и возвращает прежнюю версию.
+// Сигнатуру можно сменить, если новая не занята другим макросом.
+func (sc *SafeConfig) UpdateMacro(signature string, macro Macro) (Macro, error) {
+	var prev Macro
+	err := sc.mutateMacros(func(cfg *Config) error {
+		macros, old, err := updateMacro(cfg, signature, macro)
+		cfg.Macros, prev = macros, old
+		return err
+	})
+	return prev, err
+}
+
+// DeleteMacro удаляет макрос с сигнатурой signature и возвращает его.
+func (sc *SafeConfig) DeleteMacro(signature string) (Macro, error) {
+	var prev Macro
+	err := sc.mutateMacros(func(cfg *Config) error {
+		i := macroIndex(cfg.Macros, signature)
+		if i < 0 {
+			return fmt.Errorf("%w: %s", ErrMacroNotFound, signature)
+		}
+		prev = cfg.Macros[i]
+		cfg.Macros = append(cfg.Macros[:i:i], cfg.Macros[i+1:]...)
+		return nil
+	})
+	return prev, err
+}
+
+// mutateMacros как Mutate, но не сохраняет конфиг, если fn вернула ошибку.
+func (sc *SafeConfig) mutateMacros(fn func(cfg *Config) error) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	nextCfg := cloneConfig(sc.cfg)
+	if err := fn(nextCfg); err != nil {
+		return err
+	}
+	if err := saveConfig(nextCfg); err != nil {
+		return err
+	}
+	*sc.cfg = *nextCfg
+	return nil
+}
+
+func addMacro(cfg *Config, macro Macro) ([]Macro, error) {
+	if err := checkMacro(cfg, macro); err != nil {
+		return cfg.Macros, err
+	}
+	if macroIndex(cfg.Macros, macro.Signature) >= 0 {
+		return cfg.Macros, fmt.Errorf("%w: %s", ErrMacroExists, macro.Signature)
+	}
+	return append(cfg.Macros, macro), nil
+}
+
+func updateMacro(cfg *Config, signature string, macro Macro) ([]Macro, Macro, error) {
+	i := macroIndex(cfg.Macros, signature)
+	if i < 0 {
+		return cfg.Macros, Macro{}, fmt.Errorf("%w: %s", ErrMacroNotFound, signature)
+	}
+	if err := checkMacro(cfg, macro); err != nil {
+		return cfg.Macros, Macro{}, err
+	}
+	if j := macroIndex(cfg.Macros, macro.Signature); j >= 0 && j != i {
+		return cfg.Macros, Macro{}, fmt.Errorf("%w: %s", ErrMacroExists, macro.Signature)
+	}
+	prev := cfg.Macros[i]
+	macros := append([]Macro{}, cfg.Macros...)
+	macros[i] = macro
+	return macros, prev, nil
+}
+
+// checkMacro проверяет макрос теми же правилами, что и загрузка конфига.
+func checkMacro(cfg *Config, macro Macro) error {
+	transforms := make(map[string]bool, len(cfg.Transforms))
+	for _, t := range cfg.Transforms {
+		transforms[t.Name] = true
+	}
+	if err := validateMacro(macro, transforms); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMacro, err)
+	}
+	return nil
+}
+
+func macroIndex(macros []Macro, signature string) int {
+	for i, macro := range macros {
+		if macro.Signature == signature {
+			return i
+		}
+	}
+	return -1
+}
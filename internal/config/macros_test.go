@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMacroCRUD(t *testing.T) {
+	cfg := defaultConfig()
+	a := Macro{Name: "a", Hotkey: "Ctrl+Alt+1", Signature: "sig:AQADCgAxAAAAAAAAAAAB", Enabled: true, Text: "1", Mode: "type"}
+	b := Macro{Name: "b", Hotkey: "Ctrl+Alt+2", Signature: "sig:AQADCgAyAAAAAAAAAAAB", Enabled: true, Text: "2", Mode: "paste"}
+
+	macros, err := addMacro(cfg, a)
+	if err != nil {
+		t.Fatalf("addMacro: %v", err)
+	}
+	cfg.Macros = macros
+	if cfg.Macros, err = addMacro(cfg, b); err != nil {
+		t.Fatalf("addMacro: %v", err)
+	}
+	if _, err := addMacro(cfg, a); !errors.Is(err, ErrMacroExists) {
+		t.Fatalf("ожидалась ErrMacroExists, получено %v", err)
+	}
+	if _, err := addMacro(cfg, Macro{Name: "c", Hotkey: "X", Signature: "sig:AQADCgAzAAAAAAAAAAAB", Mode: "nope"}); !errors.Is(err, ErrInvalidMacro) {
+		t.Fatalf("ожидалась ErrInvalidMacro, получено %v", err)
+	}
+
+	renamed := a
+	renamed.Text = "один"
+	macros, prev, err := updateMacro(cfg, a.Signature, renamed)
+	if err != nil || prev.Text != "1" || macros[0].Text != "один" || cfg.Macros[0].Text != "1" {
+		t.Fatalf("updateMacro: %v, prev=%+v, macros=%+v", err, prev, macros)
+	}
+	moved := a
+	moved.Signature = b.Signature
+	if _, _, err := updateMacro(cfg, a.Signature, moved); !errors.Is(err, ErrMacroExists) {
+		t.Fatalf("ожидалась ErrMacroExists при смене сигнатуры на занятую, получено %v", err)
+	}
+	if _, _, err := updateMacro(cfg, "sig:none", a); !errors.Is(err, ErrMacroNotFound) {
+		t.Fatalf("ожидалась ErrMacroNotFound, получено %v", err)
+	}
+	if m, ok := cfg.Macro(b.Signature); !ok || m.Name != "b" {
+		t.Fatalf("Macro(%s) = %+v, %v", b.Signature, m, ok)
+	}
+}
+
+func TestParseMacroPack(t *testing.T) {
+	yamlPack := []byte("macros:\n  - name: Подпись\n    hotkey: Ctrl+Alt+1\n    signature: sig:AQADCgAxAAAAAAAAAAAB\n    text: С уважением\n    slow_typing: true\n  - name: Выкл\n    hotkey: Ctrl+Alt+2\n    enabled: false\n")
+	macros, err := ParseMacroPack(yamlPack)
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	if len(macros) != 2 || !macros[0].Enabled || !macros[0].SlowTyping || macros[0].Mode != "type" || macros[1].Enabled {
+		t.Fatalf("неожиданные макросы из YAML: %+v", macros)
+	}
+
+	macros, err = ParseMacroPack([]byte(`[{"name": "a", "hotkey": "Ctrl+Alt+1", "text": "1"}]`))
+	if err != nil || len(macros) != 1 || !macros[0].Enabled {
+		t.Fatalf("JSON-список: %+v, %v", macros, err)
+	}
+	macros, err = ParseMacroPack([]byte(`{"macros": [{"name": "a", "enabled": false}]}`))
+	if err != nil || len(macros) != 1 || macros[0].Enabled {
+		t.Fatalf("JSON-объект: %+v, %v", macros, err)
+	}
+	if _, err := ParseMacroPack([]byte("  ")); err == nil {
+		t.Fatal("пустой набор должен отклоняться")
+	}
+
+	data, err := MarshalMacroPack([]Macro{{Name: "off", Hotkey: "Ctrl+Alt+3", Mode: "type"}})
+	if err != nil {
+		t.Fatalf("MarshalMacroPack: %v", err)
+	}
+	if macros, err := ParseMacroPack(data); err != nil || macros[0].Enabled {
+		t.Fatalf("выключенный макрос должен остаться выключенным: %+v, %v", macros, err)
+	}
+}
+
+func TestImportMacrosConflicts(t *testing.T) {
+	existing := Macro{Name: "Подпись", Hotkey: "Ctrl+Alt+1", Signature: "sig:AQADCgAxAAAAAAAAAAAB", Enabled: true, Text: "old", Mode: "type"}
+	incoming := []Macro{
+		{Name: "подпись", Hotkey: "Ctrl+Alt+1", Signature: "sig:AQADCgAxAAAAAAAAAAAB", Enabled: true, Text: "new"},
+		{Name: "Адрес", Hotkey: "Ctrl+Alt+2", Signature: "sig:AQADCgAyAAAAAAAAAAAB", Enabled: true, Text: "Москва"},
+		{Name: "Плохой", Hotkey: "Ctrl+Alt+3", Signature: "sig:AQADCgAzAAAAAAAAAAAB", Mode: "nope"},
+	}
+
+	tests := []struct {
+		conflict   string
+		added      int
+		updated    int
+		skipped    int
+		wantText   string
+		wantMacros int
+	}{
+		{MacroConflictSkip, 1, 0, 2, "old", 2},
+		{MacroConflictOverwrite, 1, 1, 1, "new", 2},
+		// При переименовании сочетание остаётся занятым исходным макросом.
+		{MacroConflictRename, 1, 0, 2, "old", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.conflict, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Macros = []Macro{existing}
+			res := importMacros(cfg, incoming, tt.conflict)
+			if len(res.Added) != tt.added || len(res.Updated) != tt.updated || len(res.Skipped) != tt.skipped {
+				t.Fatalf("итог импорта: %+v", res)
+			}
+			if cfg.Macros[0].Text != tt.wantText || len(cfg.Macros) != tt.wantMacros {
+				t.Fatalf("макросы после импорта: %+v", cfg.Macros)
+			}
+			if len(res.Changes) != tt.added+tt.updated {
+				t.Fatalf("изменений %d, ожидалось %d", len(res.Changes), tt.added+tt.updated)
+			}
+		})
+	}
+
+	cfg := defaultConfig()
+	cfg.Macros = []Macro{existing}
+	free := incoming[0]
+	free.Signature, free.Hotkey = "sig:AQADCgA0AAAAAAAAAAAB", "Ctrl+Alt+4"
+	res := importMacros(cfg, []Macro{free}, MacroConflictRename)
+	if len(res.Added) != 1 || res.Added[0] != "подпись (2)" {
+		t.Fatalf("ожидалось переименование в «подпись (2)»: %+v", res)
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion — версия формата config.yml, которую пишет эта сборка.
+// Меняя формат, увеличьте её и добавьте шаг в migrations.
+const CurrentVersion = 2
+
+// migration переводит разобранный config.yml на следующую версию формата.
+// Шаги работают с документом, а не с Config: старых полей в структуре может уже не быть.
+type migration func(doc map[string]any) error
+
+// migrations[i] переводит документ версии i+1 в версию i+2.
+var migrations = []migration{
+	migrateMacroMap, // 1 → 2
+}
+
+// documentVersion возвращает версию формата документа. Файлы, записанные до
+// появления поля version, определяются по виду: словарь macros — версия 1,
+// всё остальное — версия 2.
+func documentVersion(doc map[string]any) (int, error) {
+	if raw, ok := doc["version"]; ok && raw != nil {
+		version, ok := raw.(int)
+		if !ok || version < 1 {
+			return 0, fmt.Errorf("version: неверная версия конфига %v", raw)
+		}
+		return version, nil
+	}
+	if _, ok := doc["macros"].(map[string]any); ok {
+		return 1, nil
+	}
+	return 2, nil
+}
+
+// migrate приводит содержимое config.yml к CurrentVersion. from — версия исходного
+// файла; если она уже текущая, data возвращается без изменений.
+func migrate(data []byte) (migrated []byte, from int, err error) {
+	doc := make(map[string]any)
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	from, err = documentVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > CurrentVersion {
+		return nil, from, fmt.Errorf("config.yml версии %d создан более новой версией ClipQueue (поддерживается до %d)", from, CurrentVersion)
+	}
+	if from == CurrentVersion {
+		return data, from, nil
+	}
+	for version := from; version < CurrentVersion; version++ {
+		if err := migrations[version-1](doc); err != nil {
+			return nil, from, fmt.Errorf("миграция конфига с версии %d на %d: %w", version, version+1, err)
+		}
+	}
+	doc["version"] = CurrentVersion
+	migrated, err = yaml.Marshal(doc)
+	return migrated, from, err
+}
+
+// backupConfig сохраняет исходный файл версии from рядом с config.yml перед тем,
+// как миграция его перезапишет. Существующая копия не затирается: в ней уже
+// лежит самый ранний файл этой версии.
+func backupConfig(path string, data []byte, from int) error {
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if fileExists(backup) {
+		return nil
+	}
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		return fmt.Errorf("не удалось сохранить копию конфига перед миграцией: %w", err)
+	}
+	return nil
+}
+
+// migrateMacroMap переводит макросы из словаря «хоткей → макрос» (версия 1)
+// в список с сигнатурами.
+func migrateMacroMap(doc map[string]any) error {
+	old, _ := doc["macros"].(map[string]any)
+	hotkeys := make([]string, 0, len(old))
+	for hotkey := range old {
+		hotkeys = append(hotkeys, hotkey)
+	}
+	sort.Strings(hotkeys)
+	macros := make([]any, 0, len(old))
+	for _, hotkey := range hotkeys {
+		raw := old[hotkey]
+		sig, err := generateSignatureFromHotkey(hotkey)
+		if err != nil {
+			return fmt.Errorf("failed to generate signature for hotkey %s: %v", hotkey, err)
+		}
+		macro := map[string]any{
+			"name":      hotkey,
+			"hotkey":    hotkey,
+			"signature": sig,
+			"enabled":   true,
+		}
+		if fields, ok := raw.(map[string]any); ok {
+			for _, key := range []string{"text", "mode"} {
+				if value, ok := fields[key]; ok {
+					macro[key] = value
+				}
+			}
+		} else if text, ok := raw.(string); ok {
+			macro["text"] = text
+		}
+		macros = append(macros, macro)
+	}
+	doc["macros"] = macros
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateMacroMap(t *testing.T) {
+	data := []byte("hotkeys:\n  toggle_queue: sig:AQADCgBDAC4AAAAAAAAB\nmacros:\n  Ctrl+Alt+1:\n    text: hello\n    mode: paste\n")
+	migrated, from, err := migrate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 1 {
+		t.Fatalf("ожидалась версия 1, получено %d", from)
+	}
+	cfg := defaultConfig()
+	if err := yaml.Unmarshal(migrated, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Fatalf("ожидалась версия %d, получено %d", CurrentVersion, cfg.Version)
+	}
+	if cfg.Hotkeys.ToggleQueue != "sig:AQADCgBDAC4AAAAAAAAB" {
+		t.Fatalf("остальные поля должны сохраниться, получено %q", cfg.Hotkeys.ToggleQueue)
+	}
+	if len(cfg.Macros) != 1 || cfg.Macros[0].Text != "hello" || cfg.Macros[0].Mode != "paste" ||
+		!cfg.Macros[0].Enabled || cfg.Macros[0].Signature == "" {
+		t.Fatalf("макрос не перенесён: %+v", cfg.Macros)
+	}
+}
+
+func TestMigrateVersions(t *testing.T) {
+	current := []byte("version: 2\nmacros: []\n")
+	if migrated, from, err := migrate(current); err != nil || from != CurrentVersion || string(migrated) != string(current) {
+		t.Fatalf("текущая версия должна остаться без изменений: %q, %d, %v", migrated, from, err)
+	}
+	if _, from, err := migrate([]byte("macros: []\n")); err != nil || from != 2 {
+		t.Fatalf("файл без version со списком макросов — версия 2, получено %d, %v", from, err)
+	}
+	if _, _, err := migrate([]byte("version: 99\n")); err == nil {
+		t.Fatal("файл более новой версии должен отклоняться")
+	}
+}
+
+func TestBackupConfigKeepsFirstCopy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := backupConfig(path, []byte("first"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := backupConfig(path, []byte("second"), 1); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path + ".v1.bak")
+	if err != nil || string(data) != "first" {
+		t.Fatalf("ожидалась первая копия, получено %q, %v", data, err)
+	}
+}
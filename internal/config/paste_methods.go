@@ -0,0 +1,15 @@
+package config
+
+import "strings"
+
+// SetPasteMethodRule заменяет правило clipboard.paste_methods для того же процесса
+// (без учёта регистра) или добавляет новое в конец списка.
+func (cfg *Config) SetPasteMethodRule(rule PasteMethodRule) {
+	for i, existing := range cfg.Clipboard.PasteMethods {
+		if strings.EqualFold(strings.TrimSpace(existing.Process), strings.TrimSpace(rule.Process)) {
+			cfg.Clipboard.PasteMethods[i] = rule
+			return
+		}
+	}
+	cfg.Clipboard.PasteMethods = append(cfg.Clipboard.PasteMethods, rule)
+}
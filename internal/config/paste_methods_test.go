@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestSetPasteMethodRule(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Clipboard.PasteMethods = []PasteMethodRule{{Process: "cmd.exe", Method: PasteMethodMessage}}
+
+	cfg.SetPasteMethodRule(PasteMethodRule{Process: "CMD.EXE", Method: PasteMethodCtrlV, RestoreDelayMs: 500})
+	cfg.SetPasteMethodRule(PasteMethodRule{Process: "mstsc.exe", Method: PasteMethodCtrlV})
+
+	rules := cfg.Clipboard.PasteMethods
+	if len(rules) != 2 {
+		t.Fatalf("ожидалось два правила, получено %+v", rules)
+	}
+	if rules[0].Process != "CMD.EXE" || rules[0].Method != PasteMethodCtrlV || rules[0].RestoreDelayMs != 500 {
+		t.Fatalf("правило процесса не заменено: %+v", rules[0])
+	}
+	if rules[1].Process != "mstsc.exe" {
+		t.Fatalf("новое правило не добавлено в конец: %+v", rules[1])
+	}
+}
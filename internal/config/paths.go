@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// PortableArg принудительно включает портативный режим для текущего запуска.
+	PortableArg = "--portable"
+	// PortableMarker — файл рядом с .exe, который постоянно включает портативный режим.
+	PortableMarker = "portable"
+
+	appDirName     = "ClipQueue"
+	configFileName = "config.yml"
+)
+
+var (
+	baseDirOnce    sync.Once
+	baseDirPath    string
+	portableMode   bool
+	portableForced bool
+)
+
+func executableDir() string {
+	exePath, err := os.Executable()
+	if err != nil || exePath == "" {
+		return "."
+	}
+	return filepath.Dir(exePath)
+}
+
+// SetPortable включает портативный режим по аргументу командной строки.
+// Должен вызываться до первого обращения к путям конфигурации.
+func SetPortable(enabled bool) {
+	portableForced = enabled
+}
+
+// resolveBaseDir выбирает каталог конфигурации и данных. Портативный режим
+// (аргумент, файл-маркер или уже существующий config.yml рядом с .exe от прежних
+// версий) хранит всё рядом с .exe, иначе используется %APPDATA%\ClipQueue.
+func resolveBaseDir(exeDir, appData string, forced bool, exists func(string) bool) (string, bool) {
+	if forced || exists(filepath.Join(exeDir, PortableMarker)) || exists(filepath.Join(exeDir, configFileName)) {
+		return exeDir, true
+	}
+	if appData == "" {
+		return exeDir, true
+	}
+	return filepath.Join(appData, appDirName), false
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// BaseDir возвращает каталог, в котором лежат config.yml и данные по умолчанию.
+func BaseDir() string {
+	baseDirOnce.Do(func() {
+		appData, err := os.UserConfigDir()
+		if err != nil {
+			appData = ""
+		}
+		baseDirPath, portableMode = resolveBaseDir(executableDir(), appData, portableForced, fileExists)
+	})
+	return baseDirPath
+}
+
+// IsPortable сообщает, хранит ли приложение конфигурацию рядом с .exe.
+func IsPortable() bool {
+	BaseDir()
+	return portableMode
+}
+
+// LaunchArgs возвращает аргументы, которые нужно передать перезапускаемому
+// экземпляру (автозапуск, агент), чтобы он выбрал тот же каталог конфигурации.
+func LaunchArgs() []string {
+	if portableForced {
+		return []string{PortableArg}
+	}
+	return nil
+}
+
+func ConfigPath() string {
+	return filepath.Join(BaseDir(), configFileName)
+}
+
+// ResolvePath превращает путь из конфига в абсолютный; относительные пути
+// считаются от BaseDir.
+func ResolvePath(path string) string {
+	if path == "" {
+		return BaseDir()
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Clean(filepath.Join(BaseDir(), path))
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveBaseDir(t *testing.T) {
+	exeDir := filepath.Join("opt", "clipqueue")
+	appData := filepath.Join("home", "user", "AppData")
+
+	tests := []struct {
+		name         string
+		forced       bool
+		files        []string
+		appData      string
+		wantDir      string
+		wantPortable bool
+	}{
+		{name: "по умолчанию AppData", appData: appData, wantDir: filepath.Join(appData, appDirName)},
+		{name: "аргумент --portable", forced: true, appData: appData, wantDir: exeDir, wantPortable: true},
+		{name: "файл-маркер", files: []string{PortableMarker}, appData: appData, wantDir: exeDir, wantPortable: true},
+		{name: "config.yml прежней версии", files: []string{configFileName}, appData: appData, wantDir: exeDir, wantPortable: true},
+		{name: "AppData недоступен", wantDir: exeDir, wantPortable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists := func(path string) bool {
+				for _, name := range tt.files {
+					if path == filepath.Join(exeDir, name) {
+						return true
+					}
+				}
+				return false
+			}
+			dir, portable := resolveBaseDir(exeDir, tt.appData, tt.forced, exists)
+			if dir != tt.wantDir || portable != tt.wantPortable {
+				t.Fatalf("resolveBaseDir = (%q, %v), ожидалось (%q, %v)", dir, portable, tt.wantDir, tt.wantPortable)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/ocr"
+	"github.com/serty2005/clipqueue/internal/shell"
+	"github.com/serty2005/clipqueue/internal/textclean"
+	"github.com/serty2005/clipqueue/internal/updater"
+)
+
+// Уровни FieldIssue: ошибка не даёт загрузить или сохранить конфиг,
+// предупреждение сообщает о настройке, которая, скорее всего, не сработает.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// FieldIssue — проблема в одном поле конфига. Field — путь в YAML,
+// например clipboard.paste_methods[2].method или macros[0].hotkey.
+type FieldIssue struct {
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+func (i FieldIssue) String() string {
+	return i.Field + ": " + i.Message
+}
+
+// issueList собирает проблемы по мере проверки.
+type issueList []FieldIssue
+
+func (l *issueList) errorf(field, format string, args ...any) {
+	*l = append(*l, FieldIssue{Field: field, Message: fmt.Sprintf(format, args...), Severity: SeverityError})
+}
+
+func (l *issueList) warnf(field, format string, args ...any) {
+	*l = append(*l, FieldIssue{Field: field, Message: fmt.Sprintf(format, args...), Severity: SeverityWarning})
+}
+
+// firstError возвращает первую ошибку списка; предупреждения пропускаются.
+func (l issueList) firstError() error {
+	for _, issue := range l {
+		if issue.Severity == SeverityError {
+			return errors.New(issue.String())
+		}
+	}
+	return nil
+}
+
+// Validate проверяет конфиг целиком и возвращает все найденные проблемы, а не только
+// первую: так веб-интерфейс может подсветить каждое неверное поле до сохранения.
+// Пустой список — конфиг корректен.
+func Validate(cfg *Config) []FieldIssue {
+	var l issueList
+	transforms := make(map[string]bool, len(cfg.Transforms))
+	for i, t := range cfg.Transforms {
+		field := fmt.Sprintf("transforms[%d]", i)
+		if t.Name == "" || strings.TrimSpace(t.Command) == "" {
+			l.errorf(field, "нужно указать name и command")
+		}
+		if t.Name != "" && transforms[t.Name] {
+			l.errorf(field+".name", "имя %q уже занято", t.Name)
+		}
+		transforms[t.Name] = true
+		if _, err := regexp.Compile(t.Match); err != nil {
+			l.errorf(field+".match", "%v", err)
+		}
+		if t.TimeoutMs < 0 {
+			l.errorf(field+".timeout_ms", "таймаут не может быть отрицательным")
+		}
+	}
+
+	systemHotkeys := map[string]string{
+		cfg.Hotkeys.ToggleQueue:      "hotkeys.toggle_queue",
+		cfg.Hotkeys.PasteNext:        "hotkeys.paste_next",
+		cfg.Hotkeys.ToggleQueueOrder: "hotkeys.toggle_queue_order",
+		cfg.Hotkeys.ToggleUI:         "hotkeys.toggle_ui",
+	}
+	delete(systemHotkeys, "")
+	if pause := cfg.Hotkeys.Pause; pause != "" {
+		if strings.HasPrefix(pause, "sig:") {
+			l.errorf("hotkeys.pause", "хоткей паузы задаётся сочетанием вида Ctrl+Alt+P, а не сигнатурой")
+		} else if _, _, err := parseHotkey(pause); err != nil {
+			l.errorf("hotkeys.pause", "%v", err)
+		}
+	}
+	if cfg.App.PauseMinutes < 0 {
+		l.errorf("app.pause_minutes", "время не может быть отрицательным")
+	}
+	checkHotkeyProfiles(&l, cfg)
+	signatures := make(map[string]int, len(cfg.Macros))
+	for i, macro := range cfg.Macros {
+		field := fmt.Sprintf("macros[%d]", i)
+		checkMacroFields(&l, field+".", macro, transforms)
+		if macro.Signature == "" {
+			continue
+		}
+		if j, ok := signatures[macro.Signature]; ok {
+			l.warnf(field+".hotkey", "хоткей %s уже назначен макросу %d", macro.Hotkey, j)
+		} else {
+			signatures[macro.Signature] = i
+		}
+		if system, ok := systemHotkeys[macro.Signature]; ok {
+			l.warnf(field+".hotkey", "хоткей %s совпадает с %s", macro.Hotkey, system)
+		}
+	}
+
+	if cfg.Clipboard.MaxItemBytes < 0 {
+		l.errorf("clipboard.max_item_bytes", "лимит размера элемента не может быть отрицательным")
+	}
+	if cfg.Clipboard.MaxImagePixels < 0 {
+		l.errorf("clipboard.max_image_pixels", "лимит размера изображения не может быть отрицательным")
+	}
+	for i, pattern := range cfg.Clipboard.IgnorePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			l.errorf(fmt.Sprintf("clipboard.ignore_patterns[%d]", i), "%v", err)
+		}
+	}
+	for i, rule := range cfg.Clipboard.PasteMethods {
+		field := fmt.Sprintf("clipboard.paste_methods[%d]", i)
+		if strings.TrimSpace(rule.Process) == "" {
+			l.errorf(field+".process", "нужно указать process")
+		}
+		if rule.Method != PasteMethodCtrlV && rule.Method != PasteMethodMessage {
+			l.errorf(field+".method", "неизвестный способ %q, допустимы %s и %s",
+				rule.Method, PasteMethodCtrlV, PasteMethodMessage)
+		}
+		if rule.RestoreDelayMs < 0 {
+			l.errorf(field+".restore_delay_ms", "задержка не может быть отрицательной")
+		}
+		if rule.Transform != "" && !transforms[rule.Transform] {
+			l.errorf(field+".transform", "преобразование %q не найдено в transforms", rule.Transform)
+		}
+	}
+	if ending := textclean.LineEnding(cfg.Clipboard.LineEndings.Default); !ending.Valid() {
+		l.errorf("clipboard.line_endings.default", "неизвестный перевод строки %q, допустимы lf, crlf и keep", ending)
+	}
+	for i, rule := range cfg.Clipboard.LineEndings.Rules {
+		field := fmt.Sprintf("clipboard.line_endings.rules[%d]", i)
+		if strings.TrimSpace(rule.Process) == "" {
+			l.errorf(field+".process", "нужно указать process")
+		}
+		if ending := textclean.LineEnding(rule.Mode); ending == "" || !ending.Valid() {
+			l.errorf(field+".mode", "неизвестный перевод строки %q, допустимы lf, crlf и keep", rule.Mode)
+		}
+	}
+	for i, pattern := range cfg.Clipboard.CleanURLs.Params {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			l.errorf(fmt.Sprintf("clipboard.clean_urls.params[%d]", i), "некорректный шаблон параметра %q", pattern)
+		}
+	}
+	if cfg.Clipboard.FetchTitles.TimeoutMs < 0 {
+		l.errorf("clipboard.fetch_titles.timeout_ms", "время ожидания не может быть отрицательным")
+	}
+	if form := textclean.Form(cfg.Clipboard.Normalize.Form); !form.Valid() {
+		l.errorf("clipboard.normalize.form", "неизвестная форма нормализации %q (ожидается nfc или nfkc)", form)
+	}
+	if retry := cfg.Clipboard.OpenRetry; retry.Attempts < 1 {
+		l.errorf("clipboard.open_retry.attempts", "нужна хотя бы одна попытка")
+	} else if retry.InitialDelayMs < 0 || retry.MaxDelayMs < 0 {
+		l.errorf("clipboard.open_retry.initial_delay_ms", "задержка не может быть отрицательной")
+	} else if retry.MaxDelayMs > 0 && retry.MaxDelayMs < retry.InitialDelayMs {
+		l.warnf("clipboard.open_retry.max_delay_ms", "предел %d мс меньше начальной задержки %d мс", retry.MaxDelayMs, retry.InitialDelayMs)
+	}
+	if jitter := cfg.Clipboard.OpenRetry.Jitter; jitter < 0 || jitter > 1 {
+		l.errorf("clipboard.open_retry.jitter", "разброс должен быть от 0 до 1")
+	}
+	if cfg.Clipboard.AutoClearSeconds < 0 {
+		l.errorf("clipboard.auto_clear_seconds", "время не может быть отрицательным")
+	}
+	if cfg.Input.TypeChunkSize < 0 {
+		l.errorf("input.type_chunk_size", "размер порции не может быть отрицательным")
+	}
+	if cfg.Input.TypeChunkDelayMs < 0 {
+		l.errorf("input.type_chunk_delay_ms", "пауза набора не может быть отрицательной")
+	}
+	if cfg.Queue.AutoDisableMinutes < 0 {
+		l.errorf("queue.auto_disable_minutes", "время не может быть отрицательным")
+	}
+
+	if cfg.History.MaxItems < 0 {
+		l.errorf("history.max_items", "лимит истории не может быть отрицательным")
+	}
+	if cfg.History.MaxTotalBytes < 0 {
+		l.errorf("history.max_total_bytes", "лимит истории не может быть отрицательным")
+	}
+	if _, err := ParseHistoryTTL(cfg.History.SensitiveTTL); err != nil {
+		l.errorf("history.sensitive_ttl", "%v", err)
+	}
+	if _, err := ParseHistoryTTL(cfg.History.TTL); err != nil {
+		l.errorf("history.ttl", "%v", err)
+	}
+	if _, err := imaging.ParseCompactFormat(cfg.History.ImageFormat); err != nil {
+		l.errorf("history.image_format", "%v", err)
+	}
+	if cfg.History.ImageMaxDimension < 0 {
+		l.errorf("history.image_max_dimension", "размер не может быть отрицательным")
+	}
+	if cfg.History.ImageQuality < 1 || cfg.History.ImageQuality > 100 {
+		l.errorf("history.image_quality", "качество должно быть от 1 до 100")
+	}
+	if cfg.Files.MaxEmbedBytes < 0 {
+		l.errorf("files.max_embed_bytes", "предел не может быть отрицательным")
+	}
+	if !i18n.Supported(cfg.App.Language) {
+		l.errorf("app.language", "неизвестный язык %q, допустимы auto и %s", cfg.App.Language, strings.Join(i18n.Languages(), ", "))
+	}
+	if cfg.Updates.IntervalHours < 0 {
+		l.errorf("updates.interval_hours", "интервал проверки не может быть отрицательным")
+	}
+	if cfg.Updates.Repo != "" && !updater.ValidRepo(cfg.Updates.Repo) {
+		l.errorf("updates.repo", "неверное имя репозитория %q, ожидается owner/name", cfg.Updates.Repo)
+	}
+
+	if cfg.Sync.Enabled {
+		if len(cfg.Sync.Key) < minSyncKeyLength {
+			l.errorf("sync.key", "ключ синхронизации должен быть не короче %d символов", minSyncKeyLength)
+		}
+		if cfg.Sync.Listen != "" {
+			if _, _, err := net.SplitHostPort(cfg.Sync.Listen); err != nil {
+				l.errorf("sync.listen", "%v", err)
+			}
+		}
+		for i, peer := range cfg.Sync.Peers {
+			if _, _, err := net.SplitHostPort(peer); err != nil {
+				l.errorf(fmt.Sprintf("sync.peers[%d]", i), "адрес %q: %v", peer, err)
+			}
+		}
+		if cfg.Sync.Listen == "" && len(cfg.Sync.Peers) == 0 {
+			l.warnf("sync.peers", "синхронизация включена, но не заданы ни listen, ни peers")
+		}
+	}
+	for i, raw := range cfg.Webhooks.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.errorf(fmt.Sprintf("webhooks.urls[%d]", i), "%q не является адресом http(s)", raw)
+		}
+	}
+	for i, event := range cfg.Webhooks.Events {
+		if !webhookEvents[event] {
+			l.errorf(fmt.Sprintf("webhooks.events[%d]", i), "неизвестное событие %q, допустимы capture, enqueue и paste", event)
+		}
+	}
+	if len(cfg.Webhooks.Events) > 0 && len(cfg.Webhooks.URLs) == 0 {
+		l.warnf("webhooks.urls", "события выбраны, но адреса не заданы: уведомления не отправляются")
+	}
+	if cfg.MQTT.Enabled {
+		u, err := url.Parse(cfg.MQTT.Broker)
+		if err != nil || !mqttSchemes[u.Scheme] || u.Hostname() == "" {
+			l.errorf("mqtt.broker", "%q не является адресом tcp:// или tls://", cfg.MQTT.Broker)
+		}
+		if cfg.MQTT.TopicPrefix == "" || strings.ContainsAny(cfg.MQTT.TopicPrefix, "#+") {
+			l.errorf("mqtt.topic_prefix", "префикс темы не может быть пустым или содержать # и +")
+		}
+	}
+	if cfg.GRPC.Enabled {
+		if host, _, err := net.SplitHostPort(cfg.GRPC.Listen); err != nil {
+			l.errorf("grpc.listen", "%v", err)
+		} else if !isLoopbackHost(host) && len(cfg.GRPC.Token) < minSyncKeyLength {
+			l.errorf("grpc.token", "при адресе не на localhost токен gRPC должен быть не короче %d символов", minSyncKeyLength)
+		}
+	}
+	if cfg.Remote.Enabled {
+		if _, _, err := net.SplitHostPort(cfg.Remote.Listen); err != nil {
+			l.errorf("remote.listen", "%v", err)
+		}
+		if len(cfg.Remote.Token) < minSyncKeyLength {
+			l.errorf("remote.token", "токен удалённого доступа должен быть не короче %d символов", minSyncKeyLength)
+		}
+		if (cfg.Remote.TLSCert == "") != (cfg.Remote.TLSKey == "") {
+			l.errorf("remote.tls_key", "для HTTPS нужны и remote.tls_cert, и remote.tls_key")
+		} else if cfg.Remote.TLSCert == "" {
+			l.warnf("remote.tls_cert", "без сертификата токен и данные передаются по сети незашифрованными")
+		}
+	}
+	for i, origin := range cfg.Remote.AllowedOrigins {
+		field := fmt.Sprintf("remote.allowed_origins[%d]", i)
+		if origin == "*" {
+			l.warnf(field, "запросы из браузера разрешены любому сайту; токен по-прежнему нужен")
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			l.errorf(field, "%q не является origin вида http(s)://хост[:порт]", origin)
+		}
+	}
+
+	if _, err := shell.ParseDialect(cfg.Lab.Shell); err != nil {
+		l.errorf("lab.shell", "%v", err)
+	}
+	if cfg.Lab.TimeoutMs < 0 {
+		l.errorf("lab.timeout_ms", "таймаут не может быть отрицательным")
+	}
+	if _, err := ocr.ParseEngine(cfg.OCR.Engine); err != nil {
+		l.errorf("ocr.engine", "%v", err)
+	}
+	if cfg.OCR.TimeoutMs < 0 {
+		l.errorf("ocr.timeout_ms", "таймаут не может быть отрицательным")
+	}
+	if cfg.Plugins.TimeoutMs < 0 {
+		l.errorf("plugins.timeout_ms", "таймаут не может быть отрицательным")
+	}
+
+	if cfg.Audit.RetentionDays < 0 {
+		l.errorf("audit.retention_days", "срок хранения не может быть отрицательным")
+	}
+	for i, app := range cfg.Fullscreen.Allow {
+		if strings.TrimSpace(app) == "" {
+			l.errorf(fmt.Sprintf("fullscreen.allow[%d]", i), "нужно указать имя процесса")
+		}
+	}
+	for i, app := range cfg.Fullscreen.Deny {
+		if strings.TrimSpace(app) == "" {
+			l.errorf(fmt.Sprintf("fullscreen.deny[%d]", i), "нужно указать имя процесса")
+		} else if containsApp(cfg.Fullscreen.Allow, strings.TrimSpace(app)) {
+			l.warnf(fmt.Sprintf("fullscreen.deny[%d]", i), "процесс %s есть и в fullscreen.allow: allow важнее", app)
+		}
+	}
+
+	for i, app := range cfg.RemoteSession.Clients {
+		if strings.TrimSpace(app) == "" {
+			l.errorf(fmt.Sprintf("remote_session.clients[%d]", i), "нужно указать имя процесса")
+		}
+	}
+	if cfg.RemoteSession.PasteDelayMs < 0 {
+		l.errorf("remote_session.paste_delay_ms", "задержка не может быть отрицательной")
+	}
+	if cfg.RemoteSession.RestoreDelayMs < 0 {
+		l.errorf("remote_session.restore_delay_ms", "задержка не может быть отрицательной")
+	}
+
+	if cfg.Logging.MaxSizeMB < 0 {
+		l.errorf("logging.max_size_mb", "лимит ротации не может быть отрицательным")
+	}
+	if cfg.Logging.MaxFiles < 0 {
+		l.errorf("logging.max_files", "лимит ротации не может быть отрицательным")
+	}
+	if cfg.Logging.MaxAgeDays < 0 {
+		l.errorf("logging.max_age_days", "лимит ротации не может быть отрицательным")
+	}
+	if cfg.Logging.Format != "" && cfg.Logging.Format != "text" && cfg.Logging.Format != "json" {
+		l.errorf("logging.format", "неизвестный формат %q, допустимы text и json", cfg.Logging.Format)
+	}
+	if cfg.Logging.Level != "" && !validLogLevels[strings.ToLower(cfg.Logging.Level)] {
+		l.errorf("logging.level", "неизвестный уровень %q", cfg.Logging.Level)
+	}
+	for module, level := range cfg.Logging.Modules {
+		if !validLogLevels[strings.ToLower(level)] {
+			l.errorf("logging.modules."+module, "неизвестный уровень %q для модуля %s", level, module)
+		}
+	}
+	return l
+}
+
+// validateConfig возвращает первую ошибку Validate; предупреждения загрузке не мешают.
+func validateConfig(cfg *Config) error {
+	return issueList(Validate(cfg)).firstError()
+}
+
+// validateMacro проверяет один макрос; transforms — имена из раздела transforms.
+func validateMacro(macro Macro, transforms map[string]bool) error {
+	var l issueList
+	checkMacroFields(&l, "", macro, transforms)
+	return l.firstError()
+}
+
+// checkMacroFields добавляет в l ошибки макроса; prefix — путь макроса в конфиге.
+func checkMacroFields(l *issueList, prefix string, macro Macro, transforms map[string]bool) {
+	if macro.Hotkey == "" {
+		l.errorf(prefix+"hotkey", "empty hotkey")
+	}
+	if macro.Signature == "" {
+		l.errorf(prefix+"signature", "empty signature")
+	} else if _, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(macro.Signature, "sig:")); err != nil {
+		l.errorf(prefix+"signature", "invalid signature: %v", err)
+	}
+	if macro.Sequence != "" {
+		if _, err := base64.StdEncoding.DecodeString(macro.Sequence); err != nil {
+			l.errorf(prefix+"sequence", "invalid sequence: %v", err)
+		}
+	}
+	switch {
+	case !validMacroModes[macro.Mode]:
+		l.errorf(prefix+"mode", "invalid mode: %s", macro.Mode)
+	case macro.Mode == "script" && macro.Action == "":
+		l.errorf(prefix+"action", "для режима script нужно указать action")
+	case macro.Mode == "screenshot" && !screenshotActions[macro.Action]:
+		l.errorf(prefix+"action", "для режима screenshot action должен быть full, window или region")
+	case macro.Mode == "transform" && !transforms[macro.Action]:
+		l.errorf(prefix+"action", "преобразование %q не найдено в transforms", macro.Action)
+	case macro.Mode == "color":
+		if _, err := imaging.ParseColorFormat(macro.Action); err != nil {
+			l.errorf(prefix+"action", "%v", err)
+		}
+	}
+	if macro.TypeChunkSize < 0 {
+		l.errorf(prefix+"type_chunk_size", "размер порции не может быть отрицательным")
+	}
+	if macro.TypeChunkDelayMs < 0 {
+		l.errorf(prefix+"type_chunk_delay_ms", "пауза набора не может быть отрицательной")
+	}
+}
+
+// isLoopbackHost сообщает, принимает ли адрес с хостом host соединения только
+// с этого компьютера. Пустой хост слушает все интерфейсы.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
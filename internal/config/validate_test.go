@@ -0,0 +1,86 @@
+package config
+
+import "testing"
+
+func TestValidateCollectsFieldIssues(t *testing.T) {
+	cfg := defaultConfig()
+	if issues := Validate(cfg); len(issues) != 0 {
+		t.Fatalf("конфиг по умолчанию должен быть корректен, получено %v", issues)
+	}
+
+	cfg.History.ImageQuality = 0
+	cfg.Clipboard.PasteMethods = []PasteMethodRule{{Process: "cmd.exe", Method: "nope"}, {Process: "wsl.exe", Method: "paste", Transform: "missing"}}
+	cfg.Webhooks.Events = []string{"paste"}
+	cfg.Macros = []Macro{{Name: "m", Hotkey: "X", Signature: "sig:AQADCgAzAAAAAAAAAAAB", Mode: "nope"}}
+	cfg.Hotkeys.Pause = "sig:AQADCgBDAC4AAAAAAAAB"
+	cfg.App.PauseMinutes = -1
+	cfg.Clipboard.OpenRetry.Jitter = 1.5
+	cfg.Clipboard.Normalize.Form = "nfd"
+	cfg.Clipboard.CleanURLs.Params = []string{"utm_*", "[ref"}
+	cfg.Clipboard.LineEndings.Rules = []LineEndingRule{{Process: "wsl.exe", Mode: "cr"}}
+	cfg.Updates.Repo = "evil.example/../x"
+	cfg.GRPC.Enabled = true
+	cfg.GRPC.Listen = ":47322"
+	cfg.Remote.Enabled = true
+	cfg.Remote.Listen = "192.168.1.10:8765"
+	cfg.Remote.Token = "0123456789abcdef"
+	cfg.Remote.TLSCert = "remote.crt"
+
+	want := map[string]string{
+		"history.image_quality":                SeverityError,
+		"clipboard.paste_methods[0].method":    SeverityError,
+		"clipboard.paste_methods[1].transform": SeverityError,
+		"macros[0].mode":                       SeverityError,
+		"webhooks.urls":                        SeverityWarning,
+		"hotkeys.pause":                        SeverityError,
+		"app.pause_minutes":                    SeverityError,
+		"clipboard.open_retry.jitter":          SeverityError,
+		"clipboard.normalize.form":             SeverityError,
+		"clipboard.line_endings.rules[0].mode": SeverityError,
+		"clipboard.clean_urls.params[1]":       SeverityError,
+		"updates.repo":                         SeverityError,
+		"grpc.token":                           SeverityError,
+		"remote.tls_key":                       SeverityError,
+	}
+	got := make(map[string]string)
+	for _, issue := range Validate(cfg) {
+		got[issue.Field] = issue.Severity
+	}
+	for field, severity := range want {
+		if got[field] != severity {
+			t.Errorf("%s: ожидался уровень %q, получено %q (все проблемы: %v)", field, severity, got[field], got)
+		}
+	}
+	if err := validateConfig(cfg); err == nil {
+		t.Fatal("validateConfig должна вернуть первую ошибку")
+	}
+
+	cfg = defaultConfig()
+	cfg.Webhooks.Events = []string{"paste"}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("предупреждения не должны мешать загрузке: %v", err)
+	}
+}
+
+func TestValidateGRPCToken(t *testing.T) {
+	cases := []struct {
+		listen, token string
+		ok            bool
+	}{
+		{"127.0.0.1:47322", "", true},
+		{"localhost:47322", "", true},
+		{"[::1]:47322", "", true},
+		{":47322", "", false},
+		{"0.0.0.0:47322", "short", false},
+		{"192.168.1.10:47322", "0123456789abcdef", true},
+	}
+	for _, c := range cases {
+		cfg := defaultConfig()
+		cfg.GRPC.Enabled = true
+		cfg.GRPC.Listen = c.listen
+		cfg.GRPC.Token = c.token
+		if err := validateConfig(cfg); (err == nil) != c.ok {
+			t.Errorf("listen %q, token %q: err = %v", c.listen, c.token, err)
+		}
+	}
+}
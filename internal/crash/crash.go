@@ -0,0 +1,141 @@
+// Package crash перехватывает паники в обработчиках и фоновых горутинах и
+// сохраняет отчёт о сбое в <data_dir>\crash, не роняя всё приложение.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/version"
+)
+
+const (
+	// DirName — подкаталог data_dir для отчётов о сбоях.
+	DirName = "crash"
+	// LogLines — сколько последних строк лога попадает в отчёт.
+	LogLines = 100
+	// maxReports ограничивает число хранимых отчётов: паника в горячем обработчике
+	// (например, хуке мыши) не должна засыпать диск файлами.
+	maxReports = 20
+)
+
+var (
+	mu      sync.Mutex
+	dir     string
+	summary func() string
+	seq     atomic.Uint32
+)
+
+// Init задаёт каталог отчётов и источник сводки конфигурации.
+func Init(dataDir string, configSummary func() string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dir = filepath.Join(dataDir, DirName)
+	summary = configSummary
+}
+
+// Recover перехватывает панику текущей горутины и пишет отчёт. Вызывается
+// через defer в начале обработчика: defer crash.Recover("hook.keyboard").
+func Recover(where string) {
+	if r := recover(); r != nil {
+		Report(where, r, debug.Stack())
+	}
+}
+
+// ExitCode — код выхода после паники в главной горутине; ненулевой код
+// заставляет агента перезапустить приложение.
+const ExitCode = 2
+
+// RecoverExit пишет отчёт о панике главной горутины и завершает процесс с ExitCode.
+// Продолжать работу после неё нельзя: состояние приложения не определено.
+func RecoverExit(where string) {
+	if r := recover(); r != nil {
+		Report(where, r, debug.Stack())
+		logger.Close()
+		os.Exit(ExitCode)
+	}
+}
+
+// Go запускает fn в горутине с перехватом паники.
+func Go(where string, fn func()) {
+	go func() {
+		defer Recover(where)
+		fn()
+	}()
+}
+
+// Report записывает отчёт о панике и возвращает путь к нему (пустой, если записать не удалось).
+func Report(where string, value any, stack []byte) string {
+	logger.Error("Паника в %s: %v", where, value)
+
+	mu.Lock()
+	reportDir, summaryFn := dir, summary
+	mu.Unlock()
+	if reportDir == "" {
+		return ""
+	}
+
+	path, err := writeReport(reportDir, where, value, stack, summaryFn, time.Now())
+	if err != nil {
+		logger.Error("Не удалось сохранить отчёт о сбое: %v", err)
+		return ""
+	}
+	logger.Error("Отчёт о сбое сохранён: %s", path)
+	return path
+}
+
+func writeReport(reportDir, where string, value any, stack []byte, summaryFn func() string, now time.Time) (string, error) {
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return "", err
+	}
+	if entries, err := os.ReadDir(reportDir); err == nil && len(entries) >= maxReports {
+		return "", fmt.Errorf("в %s уже %d отчётов, новые не пишутся", reportDir, len(entries))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ClipQueue %s — отчёт о сбое\n", version.Version)
+	fmt.Fprintf(&b, "Время: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Место: %s\n", where)
+	fmt.Fprintf(&b, "Паника: %v\n", value)
+	fmt.Fprintf(&b, "Go: %s %s/%s, горутин: %d\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumGoroutine())
+
+	b.WriteString("\n== Стек ==\n")
+	b.Write(stack)
+
+	if summaryFn != nil {
+		b.WriteString("\n== Конфигурация ==\n")
+		b.WriteString(safeSummary(summaryFn))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n== Последние записи лога ==\n")
+	for _, line := range logger.Recent(LogLines) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	name := fmt.Sprintf("crash-%s-%d.txt", now.Format("20060102-150405"), seq.Add(1))
+	path := filepath.Join(reportDir, name)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// safeSummary защищает отчёт от паники в самой сводке (например, при повреждённом конфиге).
+func safeSummary(fn func() string) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("не удалось собрать сводку: %v", r)
+		}
+	}()
+	return fn()
+}
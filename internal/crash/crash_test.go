@@ -0,0 +1,40 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecoverWritesReport(t *testing.T) {
+	dataDir := t.TempDir()
+	Init(dataDir, func() string { return "features.enable_queue=true" })
+	defer Init("", nil)
+
+	func() {
+		defer Recover("test.handler")
+		panic("сломалось")
+	}()
+
+	entries, err := os.ReadDir(filepath.Join(dataDir, DirName))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ожидался один отчёт: %v, %v", entries, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, DirName, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{"Место: test.handler", "Паника: сломалось", "== Стек ==", "features.enable_queue=true", "crash_test.go"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("в отчёте нет %q", want)
+		}
+	}
+}
+
+func TestSummaryPanicDoesNotBreakReport(t *testing.T) {
+	if got := safeSummary(func() string { panic("nil config") }); !strings.Contains(got, "nil config") {
+		t.Fatalf("неожиданная сводка: %q", got)
+	}
+}
@@ -0,0 +1,104 @@
+// Package filebundle упаковывает содержимое файлов из элемента Files, чтобы
+// элемент можно было переслать на другой компьютер или выгрузить в архив,
+// и восстанавливает файлы во временный каталог на принимающей стороне.
+package filebundle
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxBytes — предел суммарного размера встраиваемых файлов по умолчанию.
+const DefaultMaxBytes = 20 << 20
+
+// ErrTooLarge возвращается, если файлы элемента больше предела.
+var ErrTooLarge = errors.New("файлы больше предела встраивания")
+
+// File — встроенный файл. Name — только имя без каталога; Path — путь к данным
+// внутри ZIP-архива экспорта вместо Data.
+type File struct {
+	Name string `json:"name"`
+	Data []byte `json:"data,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// Collect читает файлы по путям. Каталоги и прочие не обычные файлы пропускаются.
+// Если суммарный размер превышает maxBytes (0 — без предела), возвращается ErrTooLarge.
+func Collect(paths []string, maxBytes int64) ([]File, error) {
+	var total int64
+	var files []File
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		total += info.Size()
+		if maxBytes > 0 && total > maxBytes {
+			return nil, fmt.Errorf("%w: больше %d байт", ErrTooLarge, maxBytes)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Base(path), Data: data})
+	}
+	return files, nil
+}
+
+// DefaultRoot — каталог во временной папке, куда Materialize записывает полученные файлы.
+func DefaultRoot() string {
+	return filepath.Join(os.TempDir(), "ClipQueue", "files")
+}
+
+// Materialize записывает файлы в новый подкаталог root и возвращает их пути.
+// Имена очищаются от каталогов, поэтому файл не может выйти за пределы подкаталога;
+// совпадающие имена получают суффикс.
+func Materialize(root string, files []File) ([]string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(root, hex.EncodeToString(suffix))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(files))
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		name := uniqueName(safeName(f.Name), used)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, f.Data, 0o600); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// safeName оставляет от имени только последний элемент пути.
+func safeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || name == "" {
+		return "file"
+	}
+	return name
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	ext := filepath.Ext(name)
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,55 @@
+package filebundle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectAndMaterialize(t *testing.T) {
+	src := t.TempDir()
+	a := filepath.Join(src, "a.txt")
+	b := filepath.Join(src, "sub", "a.txt")
+	os.MkdirAll(filepath.Dir(b), 0o700)
+	os.WriteFile(a, []byte("первый"), 0o600)
+	os.WriteFile(b, []byte("второй"), 0o600)
+
+	files, err := Collect([]string{a, b, filepath.Dir(b)}, 0)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("ожидалось 2 файла без каталога, получено %d", len(files))
+	}
+
+	paths, err := Materialize(t.TempDir(), files)
+	if err != nil {
+		t.Fatalf("Materialize: %v", err)
+	}
+	if filepath.Base(paths[0]) != "a.txt" || filepath.Base(paths[1]) != "a (2).txt" {
+		t.Fatalf("имена файлов: %v", paths)
+	}
+	if data, _ := os.ReadFile(paths[1]); string(data) != "второй" {
+		t.Fatalf("содержимое = %q", data)
+	}
+}
+
+func TestCollectLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin")
+	os.WriteFile(path, make([]byte, 100), 0o600)
+	if _, err := Collect([]string{path}, 50); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("ожидалась ErrTooLarge, получено %v", err)
+	}
+}
+
+func TestMaterializeStripsDirectories(t *testing.T) {
+	root := t.TempDir()
+	paths, err := Materialize(root, []File{{Name: `..\..\evil.txt`, Data: []byte("x")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(filepath.Dir(paths[0])) != root || filepath.Base(paths[0]) != "evil.txt" {
+		t.Fatalf("файл вышел за пределы каталога: %s", paths[0])
+	}
+}
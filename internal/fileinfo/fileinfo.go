@@ -0,0 +1,131 @@
+// Package fileinfo собирает сведения о путях из элемента Files: настоящие размеры,
+// каталоги и дерево вложенных файлов для предпросмотра.
+package fileinfo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Totals — сводка по путям элемента.
+type Totals struct {
+	Bytes   int64 `json:"bytes"`   // Суммарный размер файлов, включая вложенные в каталоги
+	Files   int   `json:"files"`   // Число файлов, включая вложенные
+	Dirs    int   `json:"dirs"`    // Число каталогов, включая вложенные
+	Missing int   `json:"missing"` // Пути, которых уже нет на диске
+	// Partial — обход остановлен на Options.MaxEntries, размеры и счётчики неполные.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Entry — узел дерева. У каталога Size — суммарный размер вложенных файлов.
+type Entry struct {
+	Name     string  `json:"name"`
+	Path     string  `json:"path,omitempty"` // Полный путь; заполнен только у верхнего уровня
+	Dir      bool    `json:"dir,omitempty"`
+	Size     int64   `json:"size"`
+	Missing  bool    `json:"missing,omitempty"`
+	Children []Entry `json:"children,omitempty"`
+	// Hidden — сколько вложенных элементов не попало в Children из-за ограничений глубины и ширины.
+	Hidden int `json:"hidden,omitempty"`
+}
+
+// Options ограничивает обход. Нулевые поля заменяются значениями по умолчанию.
+type Options struct {
+	MaxDepth    int // Глубина дерева в ответе; размеры считаются на любую глубину
+	MaxChildren int // Сколько вложенных элементов показывать у каталога
+	MaxEntries  int // Сколько элементов файловой системы посетить всего
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = 2
+	}
+	if o.MaxChildren <= 0 {
+		o.MaxChildren = 50
+	}
+	if o.MaxEntries <= 0 {
+		o.MaxEntries = 10000
+	}
+	return o
+}
+
+// Stat проверяет только сами пути, без обхода каталогов: размер каталога
+// в Bytes не входит. Подходит для захвата, где обход может быть долгим.
+func Stat(paths []string) Totals {
+	var t Totals
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			t.Missing++
+		case info.IsDir():
+			t.Dirs++
+		default:
+			t.Files++
+			t.Bytes += info.Size()
+		}
+	}
+	return t
+}
+
+// Tree обходит пути рекурсивно и строит дерево для предпросмотра.
+func Tree(paths []string, opts Options) ([]Entry, Totals) {
+	w := walker{opts: opts.withDefaults()}
+	entries := make([]Entry, 0, len(paths))
+	for _, path := range paths {
+		entry := w.visit(path, filepath.Base(path), 0)
+		entry.Path = path
+		entries = append(entries, entry)
+	}
+	return entries, w.totals
+}
+
+type walker struct {
+	opts    Options
+	visited int
+	totals  Totals
+}
+
+func (w *walker) visit(path, name string, depth int) Entry {
+	entry := Entry{Name: name}
+	if w.visited >= w.opts.MaxEntries {
+		w.totals.Partial = true
+		return entry
+	}
+	w.visited++
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		entry.Missing = true
+		w.totals.Missing++
+		return entry
+	}
+	if !info.IsDir() {
+		entry.Size = info.Size()
+		w.totals.Files++
+		w.totals.Bytes += entry.Size
+		return entry
+	}
+
+	entry.Dir = true
+	w.totals.Dirs++
+	children, err := os.ReadDir(path)
+	if err != nil {
+		return entry
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		// Каталоги первыми, как в проводнике.
+		return children[i].IsDir() && !children[j].IsDir()
+	})
+	for _, child := range children {
+		sub := w.visit(filepath.Join(path, child.Name()), child.Name(), depth+1)
+		entry.Size += sub.Size
+		if depth+1 < w.opts.MaxDepth && len(entry.Children) < w.opts.MaxChildren {
+			entry.Children = append(entry.Children, sub)
+		} else {
+			entry.Hidden++
+		}
+	}
+	return entry
+}
@@ -0,0 +1,69 @@
+package fileinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTree(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "проект")
+	writeFile(t, filepath.Join(dir, "a.txt"), 10)
+	writeFile(t, filepath.Join(dir, "src", "main.go"), 20)
+	writeFile(t, filepath.Join(dir, "src", "deep", "x.bin"), 30)
+	file := filepath.Join(root, "отдельный.txt")
+	writeFile(t, file, 5)
+	missing := filepath.Join(root, "нет")
+
+	entries, totals := Tree([]string{dir, file, missing}, Options{})
+
+	if totals.Bytes != 65 || totals.Files != 4 || totals.Dirs != 3 || totals.Missing != 1 || totals.Partial {
+		t.Fatalf("сводка = %+v", totals)
+	}
+	top := entries[0]
+	if !top.Dir || top.Size != 60 || top.Path != dir {
+		t.Fatalf("каталог = %+v", top)
+	}
+	if len(top.Children) != 2 || top.Children[0].Name != "src" || top.Children[0].Size != 50 {
+		t.Fatalf("вложенные = %+v", top.Children)
+	}
+	// При глубине 2 содержимое src не показывается, но учитывается в размере.
+	if src := top.Children[0]; len(src.Children) != 0 || src.Hidden != 2 {
+		t.Fatalf("src = %+v", src)
+	}
+	if !entries[2].Missing {
+		t.Fatalf("ожидался отсутствующий путь: %+v", entries[2])
+	}
+}
+
+func TestTreePartial(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		writeFile(t, filepath.Join(dir, name), 1)
+	}
+	_, totals := Tree([]string{dir}, Options{MaxEntries: 2})
+	if !totals.Partial || totals.Files != 1 {
+		t.Fatalf("сводка = %+v", totals)
+	}
+}
+
+func TestStat(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f")
+	writeFile(t, file, 7)
+	totals := Stat([]string{dir, file, filepath.Join(dir, "нет")})
+	if totals.Bytes != 7 || totals.Files != 1 || totals.Dirs != 1 || totals.Missing != 1 {
+		t.Fatalf("сводка = %+v", totals)
+	}
+}
@@ -0,0 +1,217 @@
+package grpcapi
+
+// Сообщения clipqueue.proto. Номера полей должны совпадать с контрактом.
+
+// QueueState — состояние очереди.
+type QueueState struct {
+	Enabled bool
+	Count   int32
+	Order   string
+}
+
+func (m *QueueState) marshal() []byte {
+	var b []byte
+	b = appendBoolField(b, 1, m.Enabled)
+	b = appendVarintField(b, 2, uint64(int64(m.Count)))
+	b = appendStringField(b, 3, m.Order)
+	return b
+}
+
+func (m *QueueState) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireVarint:
+			m.Enabled = f.value != 0
+		case f.num == 2 && f.typ == wireVarint:
+			m.Count = int32(f.value)
+		case f.num == 3 && f.typ == wireBytes:
+			m.Order = string(f.data)
+		}
+		return nil
+	})
+}
+
+// Item — элемент истории или очереди.
+type Item struct {
+	ID              string
+	TimestampUnixMs int64
+	Type            string
+	Preview         string
+	Text            string
+	SizeBytes       int64
+	ImagePNG        []byte
+	Files           []string
+}
+
+func (m *Item) marshal() []byte {
+	var b []byte
+	b = appendStringField(b, 1, m.ID)
+	b = appendVarintField(b, 2, uint64(m.TimestampUnixMs))
+	b = appendStringField(b, 3, m.Type)
+	b = appendStringField(b, 4, m.Preview)
+	b = appendStringField(b, 5, m.Text)
+	b = appendVarintField(b, 6, uint64(m.SizeBytes))
+	b = appendBytesField(b, 7, m.ImagePNG)
+	for _, f := range m.Files {
+		// Повторяющиеся строки пишутся всегда, даже пустые.
+		b = appendMessageField(b, 8, []byte(f))
+	}
+	return b
+}
+
+func (m *Item) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			m.ID = string(f.data)
+		case f.num == 2 && f.typ == wireVarint:
+			m.TimestampUnixMs = int64(f.value)
+		case f.num == 3 && f.typ == wireBytes:
+			m.Type = string(f.data)
+		case f.num == 4 && f.typ == wireBytes:
+			m.Preview = string(f.data)
+		case f.num == 5 && f.typ == wireBytes:
+			m.Text = string(f.data)
+		case f.num == 6 && f.typ == wireVarint:
+			m.SizeBytes = int64(f.value)
+		case f.num == 7 && f.typ == wireBytes:
+			m.ImagePNG = append([]byte(nil), f.data...)
+		case f.num == 8 && f.typ == wireBytes:
+			m.Files = append(m.Files, string(f.data))
+		}
+		return nil
+	})
+}
+
+// ListItemsRequest — параметры ListHistory и ListQueue.
+type ListItemsRequest struct {
+	Limit          int32
+	IncludeContent bool
+}
+
+func (m *ListItemsRequest) marshal() []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(int64(m.Limit)))
+	b = appendBoolField(b, 2, m.IncludeContent)
+	return b
+}
+
+func (m *ListItemsRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireVarint:
+			m.Limit = int32(f.value)
+		case f.num == 2 && f.typ == wireVarint:
+			m.IncludeContent = f.value != 0
+		}
+		return nil
+	})
+}
+
+// ListItemsResponse — список элементов.
+type ListItemsResponse struct {
+	Items []Item
+}
+
+func (m *ListItemsResponse) marshal() []byte {
+	var b []byte
+	for i := range m.Items {
+		b = appendMessageField(b, 1, m.Items[i].marshal())
+	}
+	return b
+}
+
+func (m *ListItemsResponse) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		if f.num == 1 && f.typ == wireBytes {
+			var item Item
+			if err := item.unmarshal(f.data); err != nil {
+				return err
+			}
+			m.Items = append(m.Items, item)
+		}
+		return nil
+	})
+}
+
+// PushItemRequest — текст или PNG для добавления в очередь.
+type PushItemRequest struct {
+	Text     string
+	ImagePNG []byte
+}
+
+func (m *PushItemRequest) marshal() []byte {
+	var b []byte
+	b = appendStringField(b, 1, m.Text)
+	b = appendBytesField(b, 2, m.ImagePNG)
+	return b
+}
+
+func (m *PushItemRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			m.Text = string(f.data)
+		case f.num == 2 && f.typ == wireBytes:
+			m.ImagePNG = append([]byte(nil), f.data...)
+		}
+		return nil
+	})
+}
+
+// PushItemResponse — ID добавленного элемента и новое состояние очереди.
+type PushItemResponse struct {
+	ID    string
+	State QueueState
+}
+
+func (m *PushItemResponse) marshal() []byte {
+	var b []byte
+	b = appendStringField(b, 1, m.ID)
+	b = appendMessageField(b, 2, m.State.marshal())
+	return b
+}
+
+func (m *PushItemResponse) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			m.ID = string(f.data)
+		case f.num == 2 && f.typ == wireBytes:
+			return m.State.unmarshal(f.data)
+		}
+		return nil
+	})
+}
+
+// StateEvent — событие потока WatchState.
+type StateEvent struct {
+	Event string
+	State QueueState
+	Item  *Item
+}
+
+func (m *StateEvent) marshal() []byte {
+	var b []byte
+	b = appendStringField(b, 1, m.Event)
+	b = appendMessageField(b, 2, m.State.marshal())
+	if m.Item != nil {
+		b = appendMessageField(b, 3, m.Item.marshal())
+	}
+	return b
+}
+
+func (m *StateEvent) unmarshal(b []byte) error {
+	return decodeFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			m.Event = string(f.data)
+		case f.num == 2 && f.typ == wireBytes:
+			return m.State.unmarshal(f.data)
+		case f.num == 3 && f.typ == wireBytes:
+			m.Item = &Item{}
+			return m.Item.unmarshal(f.data)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,312 @@
+// Package grpcapi реализует gRPC-сервис clipqueue.v1.ClipQueue (clipqueue.proto)
+// поверх HTTP/2 из стандартной библиотеки: сообщения кодируются вручную, поэтому
+// приложению не нужны grpc-go и сгенерированный код. Клиенты генерируют заглушки
+// из clipqueue.proto любым стандартным инструментом.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// ServicePath — префикс путей методов сервиса.
+const ServicePath = "/clipqueue.v1.ClipQueue/"
+
+// maxMessageBytes ограничивает входящее сообщение (PNG в PushItem).
+const maxMessageBytes = 64 << 20
+
+// watcherBuffer — сколько событий ждут медленного подписчика WatchState, прежде чем отбрасываться.
+const watcherBuffer = 64
+
+// Коды статуса gRPC.
+const (
+	codeOK                 = 0
+	codeInvalidArgument    = 3
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnauthenticated    = 16
+)
+
+// Backend выполняет методы сервиса. Его реализует адаптер контроллера в main.
+type Backend interface {
+	GetQueueState() (enabled bool, count int, order string)
+	ToggleQueue()
+	PasteNext()
+	ListHistory(includeContent bool) []Item
+	ListQueue(includeContent bool) []Item
+	PushText(text string) (string, error)
+	PushImagePNG(data []byte) (string, error)
+}
+
+// statusError — ошибка метода с кодом gRPC.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func status(code int, format string, args ...any) error {
+	return &statusError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Server обслуживает вызовы gRPC и рассылает события подписчикам WatchState.
+type Server struct {
+	backend Backend
+	token   string
+
+	mu       sync.Mutex
+	watchers map[chan StateEvent]struct{}
+	http     *http.Server
+}
+
+// NewServer создаёт сервер. Непустой token требует заголовок authorization: Bearer <token>.
+func NewServer(backend Backend, token string) *Server {
+	s := &Server{backend: backend, token: token, watchers: make(map[chan StateEvent]struct{})}
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true) // Клиенты gRPC без TLS подключаются сразу по HTTP/2 (prior knowledge)
+	s.http = &http.Server{Handler: s, Protocols: protocols, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Serve принимает подключения до остановки через Shutdown.
+func (s *Server) Serve(ln net.Listener) error {
+	err := s.http.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown закрывает потоки WatchState и останавливает сервер.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for ch := range s.watchers {
+		close(ch)
+		delete(s.watchers, ch)
+	}
+	s.mu.Unlock()
+	return s.http.Shutdown(ctx)
+}
+
+// Publish рассылает событие подписчикам WatchState. Состояние очереди берётся
+// у Backend. Медленный подписчик теряет события, но не задерживает остальных.
+func (s *Server) Publish(event string, item *Item) {
+	ev := StateEvent{Event: event, State: s.state(), Item: item}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watchers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (s *Server) state() QueueState {
+	enabled, count, order := s.backend.GetQueueState()
+	return QueueState{Enabled: enabled, Count: int32(count), Order: order}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "ожидается запрос gRPC", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	if s.token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			finish(w, status(codeUnauthenticated, "неверный токен"))
+			return
+		}
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, ServicePath)
+	if method == r.URL.Path {
+		finish(w, status(codeUnimplemented, "неизвестный сервис %s", r.URL.Path))
+		return
+	}
+	if method == "WatchState" {
+		s.watch(w, r)
+		return
+	}
+
+	body, err := readMessage(r.Body)
+	if err != nil {
+		finish(w, status(codeInvalidArgument, "%v", err))
+		return
+	}
+	resp, err := s.call(method, body)
+	if err == nil {
+		err = writeMessage(w, resp)
+	}
+	finish(w, err)
+}
+
+// call выполняет унарный метод и возвращает закодированный ответ.
+func (s *Server) call(method string, body []byte) ([]byte, error) {
+	logger.Debug("gRPC: вызов %s", method)
+	switch method {
+	case "GetState":
+		st := s.state()
+		return st.marshal(), nil
+	case "ToggleQueue":
+		s.backend.ToggleQueue()
+		st := s.state()
+		return st.marshal(), nil
+	case "PasteNext":
+		s.backend.PasteNext()
+		st := s.state()
+		return st.marshal(), nil
+	case "ListHistory", "ListQueue":
+		var req ListItemsRequest
+		if err := req.unmarshal(body); err != nil {
+			return nil, status(codeInvalidArgument, "%v", err)
+		}
+		var items []Item
+		if method == "ListHistory" {
+			items = s.backend.ListHistory(req.IncludeContent)
+		} else {
+			items = s.backend.ListQueue(req.IncludeContent)
+		}
+		if req.Limit > 0 && int(req.Limit) < len(items) {
+			items = items[len(items)-int(req.Limit):]
+		}
+		resp := ListItemsResponse{Items: items}
+		return resp.marshal(), nil
+	case "PushItem":
+		var req PushItemRequest
+		if err := req.unmarshal(body); err != nil {
+			return nil, status(codeInvalidArgument, "%v", err)
+		}
+		var id string
+		var err error
+		switch {
+		case req.Text != "":
+			id, err = s.backend.PushText(req.Text)
+		case len(req.ImagePNG) > 0:
+			id, err = s.backend.PushImagePNG(req.ImagePNG)
+		default:
+			return nil, status(codeInvalidArgument, "нужен text или image_png")
+		}
+		if err != nil {
+			return nil, status(codeFailedPrecondition, "%v", err)
+		}
+		resp := PushItemResponse{ID: id, State: s.state()}
+		return resp.marshal(), nil
+	}
+	return nil, status(codeUnimplemented, "неизвестный метод %s", method)
+}
+
+// watch отправляет текущее состояние и затем события до отмены вызова клиентом.
+func (s *Server) watch(w http.ResponseWriter, r *http.Request) {
+	if _, err := readMessage(r.Body); err != nil {
+		finish(w, status(codeInvalidArgument, "%v", err))
+		return
+	}
+	ch := make(chan StateEvent, watcherBuffer)
+	s.mu.Lock()
+	s.watchers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.watchers[ch]; ok {
+			delete(s.watchers, ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	send := func(ev StateEvent) error {
+		if err := writeMessage(w, ev.marshal()); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	if err := send(StateEvent{Event: "state", State: s.state()}); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				finish(w, nil)
+				return
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readMessage читает одно сообщение gRPC: флаг сжатия, 4 байта длины, тело.
+func readMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil // Пустой запрос допустим для сообщений без полей
+		}
+		return nil, fmt.Errorf("неполный заголовок сообщения: %w", err)
+	}
+	if header[0] != 0 {
+		return nil, errors.New("сжатые сообщения не поддерживаются")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageBytes {
+		return nil, fmt.Errorf("сообщение %d байт больше допустимых %d", size, maxMessageBytes)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("неполное сообщение: %w", err)
+	}
+	return body, nil
+}
+
+func writeMessage(w io.Writer, body []byte) error {
+	buf := make([]byte, 5+len(body))
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(body)))
+	copy(buf[5:], body)
+	_, err := w.Write(buf)
+	return err
+}
+
+// finish записывает статус вызова в трейлеры ответа.
+func finish(w http.ResponseWriter, err error) {
+	code, msg := codeOK, ""
+	if err != nil {
+		var se *statusError
+		if errors.As(err, &se) {
+			code, msg = se.code, se.msg
+		} else {
+			code, msg = codeInternal, err.Error()
+		}
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		// grpc-message передаётся в percent-encoding.
+		w.Header().Set("Grpc-Message", url.PathEscape(msg))
+	}
+}
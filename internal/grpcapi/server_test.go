@@ -0,0 +1,162 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	enabled bool
+	queue   []Item
+}
+
+func (b *fakeBackend) GetQueueState() (bool, int, string) { return b.enabled, len(b.queue), "LIFO" }
+func (b *fakeBackend) ToggleQueue()                       { b.enabled = !b.enabled }
+func (b *fakeBackend) PasteNext()                         {}
+func (b *fakeBackend) ListHistory(bool) []Item            { return b.queue }
+func (b *fakeBackend) ListQueue(bool) []Item              { return b.queue }
+func (b *fakeBackend) PushImagePNG([]byte) (string, error) {
+	return "", errors.New("изображения не поддерживаются")
+}
+func (b *fakeBackend) PushText(text string) (string, error) {
+	b.queue = append(b.queue, Item{ID: "id-" + text, Type: "Text", Text: text, Files: []string{"", "a"}})
+	return "id-" + text, nil
+}
+
+func startServer(t *testing.T, backend Backend, token string) (*Server, *http.Client, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(backend, token)
+	go srv.Serve(ln)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	return srv, client, "http://" + ln.Addr().String() + ServicePath
+}
+
+// invoke выполняет унарный вызов так же, как клиент gRPC, и возвращает тело ответа и статус.
+func invoke(t *testing.T, client *http.Client, url, method string, req []byte, token string) ([]byte, string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writeMessage(&body, req)
+	httpReq, _ := http.NewRequest(http.MethodPost, url+method, &body)
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("%s: ответ по HTTP/%d, ожидался HTTP/2", method, resp.ProtoMajor)
+	}
+	msg, err := readMessage(resp.Body)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	readMessage(resp.Body) // Дочитываем до трейлеров
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	message := resp.Trailer.Get("Grpc-Message")
+	if message == "" {
+		message = resp.Header.Get("Grpc-Message")
+	}
+	return msg, status, message
+}
+
+func TestUnaryCalls(t *testing.T) {
+	backend := &fakeBackend{}
+	_, client, base := startServer(t, backend, "")
+
+	msg, status, _ := invoke(t, client, base, "ToggleQueue", nil, "")
+	var st QueueState
+	if err := st.unmarshal(msg); err != nil || status != "0" || !st.Enabled || st.Order != "LIFO" {
+		t.Fatalf("ToggleQueue = %+v, статус %s, %v", st, status, err)
+	}
+
+	req := PushItemRequest{Text: "hello"}
+	msg, status, _ = invoke(t, client, base, "PushItem", req.marshal(), "")
+	var pushed PushItemResponse
+	if err := pushed.unmarshal(msg); err != nil || status != "0" || pushed.ID != "id-hello" || pushed.State.Count != 1 {
+		t.Fatalf("PushItem = %+v, статус %s, %v", pushed, status, err)
+	}
+
+	list := ListItemsRequest{Limit: 5, IncludeContent: true}
+	msg, _, _ = invoke(t, client, base, "ListQueue", list.marshal(), "")
+	var items ListItemsResponse
+	if err := items.unmarshal(msg); err != nil || len(items.Items) != 1 || items.Items[0].Text != "hello" || len(items.Items[0].Files) != 2 {
+		t.Fatalf("ListQueue = %+v, %v", items, err)
+	}
+
+	img := PushItemRequest{ImagePNG: []byte{1}}
+	_, status, message := invoke(t, client, base, "PushItem", img.marshal(), "")
+	if decoded, _ := url.PathUnescape(message); status != "9" || decoded != "изображения не поддерживаются" {
+		t.Fatalf("ошибка PushItem: статус %s, сообщение %q", status, decoded)
+	}
+
+	if _, status, _ = invoke(t, client, base, "Missing", nil, ""); status != "12" {
+		t.Fatalf("неизвестный метод: статус %s", status)
+	}
+}
+
+func TestTokenRequired(t *testing.T) {
+	_, client, base := startServer(t, &fakeBackend{}, "secret")
+	if _, status, _ := invoke(t, client, base, "GetState", nil, "wrong"); status != "16" {
+		t.Fatalf("неверный токен: статус %s", status)
+	}
+	if _, status, _ := invoke(t, client, base, "GetState", nil, "secret"); status != "0" {
+		t.Fatalf("верный токен: статус %s", status)
+	}
+}
+
+func TestWatchStateStreamsEvents(t *testing.T) {
+	srv, client, base := startServer(t, &fakeBackend{}, "")
+	var body bytes.Buffer
+	writeMessage(&body, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, base+"WatchState", &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	next := func() StateEvent {
+		msg, err := readMessage(resp.Body)
+		if err != nil {
+			t.Fatalf("поток: %v", err)
+		}
+		var ev StateEvent
+		if err := ev.unmarshal(msg); err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	}
+	if ev := next(); ev.Event != "state" {
+		t.Fatalf("первое событие %q, ожидалось state", ev.Event)
+	}
+	srv.Publish("paste", &Item{ID: "42"})
+	if ev := next(); ev.Event != "paste" || ev.Item == nil || ev.Item.ID != "42" {
+		t.Fatalf("событие %+v", ev)
+	}
+}
@@ -0,0 +1,110 @@
+// Package i18n переводит строки интерфейса трея и ошибок API.
+// Переводы лежат в locales/<язык>.json и встраиваются в бинарник.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultLanguage используется, если язык не поддерживается или в нём нет ключа.
+const DefaultLanguage = "en"
+
+// Auto — значение app.language, при котором язык берётся из настроек Windows.
+const Auto = "auto"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+var (
+	catalogs = loadCatalogs()
+	current  atomic.Value // string
+)
+
+func init() {
+	current.Store(DefaultLanguage)
+}
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: %v", err))
+	}
+	out := make(map[string]map[string]string, len(entries))
+	for _, e := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", e.Name(), err))
+		}
+		out[strings.TrimSuffix(e.Name(), ".json")] = messages
+	}
+	return out
+}
+
+// Languages возвращает коды поддерживаемых языков.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Supported сообщает, есть ли перевод для языка. Пустое значение и Auto допустимы.
+func Supported(lang string) bool {
+	if lang == "" || lang == Auto {
+		return true
+	}
+	_, ok := catalogs[normalize(lang)]
+	return ok
+}
+
+func normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	// ru-RU, en_US → ru, en
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// SetLanguage выбирает язык переводов и возвращает фактически установленный:
+// неподдерживаемый язык заменяется на DefaultLanguage.
+func SetLanguage(lang string) string {
+	lang = normalize(lang)
+	if _, ok := catalogs[lang]; !ok {
+		lang = DefaultLanguage
+	}
+	current.Store(lang)
+	return lang
+}
+
+// Language возвращает текущий язык.
+func Language() string {
+	return current.Load().(string)
+}
+
+// T возвращает перевод ключа на текущий язык. Аргументы подставляются через fmt.Sprintf.
+// Если ключа нет ни в текущем языке, ни в DefaultLanguage, возвращается сам ключ.
+func T(key string, args ...any) string {
+	msg, ok := catalogs[Language()][key]
+	if !ok {
+		if msg, ok = catalogs[DefaultLanguage][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
@@ -0,0 +1,40 @@
+package i18n
+
+import "testing"
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	base := catalogs[DefaultLanguage]
+	for _, lang := range Languages() {
+		for key := range base {
+			if _, ok := catalogs[lang][key]; !ok {
+				t.Errorf("%s: нет ключа %s", lang, key)
+			}
+		}
+		for key := range catalogs[lang] {
+			if _, ok := base[key]; !ok {
+				t.Errorf("%s: лишний ключ %s, его нет в %s", lang, key, DefaultLanguage)
+			}
+		}
+	}
+}
+
+func TestSetLanguageAndTranslate(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if got := SetLanguage("ru-RU"); got != "ru" {
+		t.Fatalf("SetLanguage(ru-RU) = %q", got)
+	}
+	if got := T("tray.exit"); got != "Выход" {
+		t.Fatalf("T(tray.exit) = %q", got)
+	}
+	if got := T("api.invalid_json", "EOF"); got != "некорректный JSON: EOF" {
+		t.Fatalf("подстановка аргументов: %q", got)
+	}
+
+	if got := SetLanguage("de"); got != DefaultLanguage {
+		t.Fatalf("неподдерживаемый язык должен заменяться на %s, получено %q", DefaultLanguage, got)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Fatalf("неизвестный ключ должен возвращаться как есть: %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// ColorFormat — текстовая запись цвета для пипетки.
+type ColorFormat string
+
+const (
+	ColorHex ColorFormat = "hex" // #RRGGBB
+	ColorRGB ColorFormat = "rgb" // rgb(R, G, B)
+	ColorHSL ColorFormat = "hsl" // hsl(H, S%, L%)
+)
+
+// ParseColorFormat проверяет название формата; пустое значение означает hex.
+func ParseColorFormat(s string) (ColorFormat, error) {
+	switch f := ColorFormat(strings.ToLower(s)); f {
+	case "":
+		return ColorHex, nil
+	case ColorHex, ColorRGB, ColorHSL:
+		return f, nil
+	}
+	return "", fmt.Errorf("неизвестный формат цвета %q: допустимы hex, rgb и hsl", s)
+}
+
+// FormatColor записывает цвет в формате CSS. Альфа-канал не учитывается:
+// пиксели экрана всегда непрозрачны.
+func FormatColor(c color.RGBA, format ColorFormat) string {
+	switch format {
+	case ColorRGB:
+		return fmt.Sprintf("rgb(%d, %d, %d)", c.R, c.G, c.B)
+	case ColorHSL:
+		h, s, l := toHSL(c)
+		return fmt.Sprintf("hsl(%d, %d%%, %d%%)", h, s, l)
+	}
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// toHSL переводит цвет в тон (градусы) и насыщенность и светлоту (проценты),
+// округлённые до целых.
+func toHSL(c color.RGBA) (h, s, l int) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC, minC := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	light := (maxC + minC) / 2
+	delta := maxC - minC
+	if delta == 0 {
+		return 0, 0, int(math.Round(light * 100))
+	}
+	sat := delta / (1 - math.Abs(2*light-1))
+	var hue float64
+	switch maxC {
+	case r:
+		hue = math.Mod((g-b)/delta, 6)
+	case g:
+		hue = (b-r)/delta + 2
+	default:
+		hue = (r-g)/delta + 4
+	}
+	hue *= 60
+	if hue < 0 {
+		hue += 360
+	}
+	return int(math.Round(hue)) % 360, int(math.Round(sat * 100)), int(math.Round(light * 100))
+}
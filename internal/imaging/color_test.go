@@ -0,0 +1,38 @@
+package imaging
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestFormatColor(t *testing.T) {
+	cases := []struct {
+		c      color.RGBA
+		format ColorFormat
+		want   string
+	}{
+		{color.RGBA{0x1E, 0x90, 0xFF, 0xFF}, ColorHex, "#1E90FF"},
+		{color.RGBA{0x1E, 0x90, 0xFF, 0xFF}, ColorRGB, "rgb(30, 144, 255)"},
+		{color.RGBA{0x1E, 0x90, 0xFF, 0xFF}, ColorHSL, "hsl(210, 100%, 56%)"},
+		{color.RGBA{0xFF, 0x00, 0x00, 0xFF}, ColorHSL, "hsl(0, 100%, 50%)"},
+		{color.RGBA{0xFF, 0x00, 0x80, 0xFF}, ColorHSL, "hsl(330, 100%, 50%)"},
+		{color.RGBA{0x80, 0x80, 0x80, 0xFF}, ColorHSL, "hsl(0, 0%, 50%)"},
+	}
+	for _, tc := range cases {
+		if got := FormatColor(tc.c, tc.format); got != tc.want {
+			t.Errorf("FormatColor(%v, %s) = %q, ожидалось %q", tc.c, tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestParseColorFormat(t *testing.T) {
+	if f, err := ParseColorFormat(""); err != nil || f != ColorHex {
+		t.Fatalf("ParseColorFormat(\"\") = %q, %v", f, err)
+	}
+	if f, err := ParseColorFormat("HSL"); err != nil || f != ColorHSL {
+		t.Fatalf("ParseColorFormat(HSL) = %q, %v", f, err)
+	}
+	if _, err := ParseColorFormat("cmyk"); err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+}
@@ -0,0 +1,58 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+)
+
+// CompactFormat — формат, в котором история хранит уменьшенные изображения.
+type CompactFormat string
+
+const (
+	CompactOriginal CompactFormat = "original" // Изображения хранятся без изменений
+	CompactJPEG     CompactFormat = "jpeg"     // Уменьшение и JPEG с заданным качеством
+	CompactPNG      CompactFormat = "png"      // Только уменьшение, без потерь
+)
+
+// ParseCompactFormat проверяет название формата; пустое значение означает original.
+func ParseCompactFormat(s string) (CompactFormat, error) {
+	switch f := CompactFormat(strings.ToLower(s)); f {
+	case "":
+		return CompactOriginal, nil
+	case CompactOriginal, CompactJPEG, CompactPNG:
+		return f, nil
+	}
+	return "", fmt.Errorf("неизвестный формат изображений истории %q: допустимы original, jpeg и png", s)
+}
+
+// CompactImage уменьшает закодированное изображение так, чтобы большая сторона
+// не превышала maxSize (0 — без уменьшения), и перекодирует его в format.
+// Изображения с прозрачностью остаются PNG: JPEG не хранит альфа-канал.
+// Возвращает данные, их MIME-тип и признак того, что результат меньше исходника;
+// если уменьшить не удалось, вызывающий оставляет исходные данные.
+func CompactImage(data []byte, format CompactFormat, maxSize, quality int) ([]byte, string, bool, error) {
+	if format == CompactOriginal || format == "" {
+		return data, "", false, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("не удалось декодировать изображение: %w", err)
+	}
+	small := Thumbnail(img, maxSize)
+
+	var out []byte
+	contentType := "image/png"
+	if format == CompactJPEG && small.Opaque() {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, small, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", false, fmt.Errorf("не удалось закодировать JPEG: %w", err)
+		}
+		out, contentType = buf.Bytes(), "image/jpeg"
+	} else if out, err = EncodePNG(small); err != nil {
+		return nil, "", false, err
+	}
+	return out, contentType, len(out) < len(data), nil
+}
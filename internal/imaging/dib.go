@@ -0,0 +1,232 @@
+// Package imaging содержит преобразования изображений, не зависящие от буфера обмена:
+// разбор и сборку DIB (device-independent bitmap) и кодирование в PNG.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+)
+
+// Значения biCompression из BITMAPINFOHEADER.
+const (
+	BI_RGB       = 0
+	BI_BITFIELDS = 3
+)
+
+// BitmapInfoHeaderSize — размер BITMAPINFOHEADER в байтах.
+const BitmapInfoHeaderSize = 40
+
+// ErrUnsupportedDIB возвращается, если DIB повреждён или его формат не поддерживается.
+var ErrUnsupportedDIB = errors.New("unsupported DIB format")
+
+// BitmapInfoHeader повторяет структуру BITMAPINFOHEADER из WinAPI.
+type BitmapInfoHeader struct {
+	Size          uint32
+	Width         int32
+	Height        int32
+	Planes        uint16
+	BitCount      uint16
+	Compression   uint32
+	SizeImage     uint32
+	XPelsPerMeter int32
+	YPelsPerMeter int32
+	ClrUsed       uint32
+	ClrImportant  uint32
+}
+
+// ParseBitmapInfoHeader читает BITMAPINFOHEADER из начала DIB.
+func ParseBitmapInfoHeader(dib []byte) (BitmapInfoHeader, error) {
+	if len(dib) < BitmapInfoHeaderSize {
+		return BitmapInfoHeader{}, fmt.Errorf("%w: данных меньше размера BITMAPINFOHEADER (%d байт)", ErrUnsupportedDIB, len(dib))
+	}
+	return BitmapInfoHeader{
+		Size:          binary.LittleEndian.Uint32(dib[0:4]),
+		Width:         int32(binary.LittleEndian.Uint32(dib[4:8])),
+		Height:        int32(binary.LittleEndian.Uint32(dib[8:12])),
+		Planes:        binary.LittleEndian.Uint16(dib[12:14]),
+		BitCount:      binary.LittleEndian.Uint16(dib[14:16]),
+		Compression:   binary.LittleEndian.Uint32(dib[16:20]),
+		SizeImage:     binary.LittleEndian.Uint32(dib[20:24]),
+		XPelsPerMeter: int32(binary.LittleEndian.Uint32(dib[24:28])),
+		YPelsPerMeter: int32(binary.LittleEndian.Uint32(dib[28:32])),
+		ClrUsed:       binary.LittleEndian.Uint32(dib[32:36]),
+		ClrImportant:  binary.LittleEndian.Uint32(dib[36:40]),
+	}, nil
+}
+
+func (h BitmapInfoHeader) put(dst []byte) {
+	binary.LittleEndian.PutUint32(dst[0:4], h.Size)
+	binary.LittleEndian.PutUint32(dst[4:8], uint32(h.Width))
+	binary.LittleEndian.PutUint32(dst[8:12], uint32(h.Height))
+	binary.LittleEndian.PutUint16(dst[12:14], h.Planes)
+	binary.LittleEndian.PutUint16(dst[14:16], h.BitCount)
+	binary.LittleEndian.PutUint32(dst[16:20], h.Compression)
+	binary.LittleEndian.PutUint32(dst[20:24], h.SizeImage)
+	binary.LittleEndian.PutUint32(dst[24:28], uint32(h.XPelsPerMeter))
+	binary.LittleEndian.PutUint32(dst[28:32], uint32(h.YPelsPerMeter))
+	binary.LittleEndian.PutUint32(dst[32:36], h.ClrUsed)
+	binary.LittleEndian.PutUint32(dst[36:40], h.ClrImportant)
+}
+
+// DecodeDIB разбирает DIB (CF_DIB или CF_DIBV5) в RGBA-изображение.
+// Поддерживаются 24bpp BGR (BI_RGB) и 32bpp BGRA (BI_RGB или BI_BITFIELDS со стандартными масками),
+// строки снизу вверх и сверху вниз.
+func DecodeDIB(dib []byte) (*image.RGBA, error) {
+	hdr, err := ParseBitmapInfoHeader(dib)
+	if err != nil {
+		return nil, err
+	}
+
+	if hdr.Width <= 0 {
+		return nil, fmt.Errorf("%w: некорректная ширина %d", ErrUnsupportedDIB, hdr.Width)
+	}
+	if hdr.Height == 0 {
+		return nil, fmt.Errorf("%w: некорректная высота %d", ErrUnsupportedDIB, hdr.Height)
+	}
+	if int(hdr.Size) > len(dib) {
+		return nil, fmt.Errorf("%w: размер заголовка %d превышает размер буфера %d", ErrUnsupportedDIB, hdr.Size, len(dib))
+	}
+
+	if (hdr.BitCount != 24 && hdr.BitCount != 32) ||
+		(hdr.BitCount == 24 && hdr.Compression != BI_RGB) ||
+		(hdr.BitCount == 32 && hdr.Compression != BI_RGB && hdr.Compression != BI_BITFIELDS) {
+		return nil, fmt.Errorf("%w: поддерживаются только 24bpp BGR (BI_RGB) и 32bpp BGRA (BI_RGB или BI_BITFIELDS), получено %dbpp, compression=%d",
+			ErrUnsupportedDIB, hdr.BitCount, hdr.Compression)
+	}
+
+	pixelOffset := int(hdr.Size)
+	if hdr.ClrUsed > 0 {
+		colorsCount := 1 << hdr.BitCount
+		if hdr.ClrUsed < uint32(colorsCount) {
+			colorsCount = int(hdr.ClrUsed)
+		}
+		pixelOffset += colorsCount * 4 // Каждый RGBQUAD занимает 4 байта
+	}
+	// Для BI_BITFIELDS после заголовка идут три маски (R, G, B) по 4 байта.
+	if hdr.Compression == BI_BITFIELDS {
+		pixelOffset += 12
+	}
+
+	width := int(hdr.Width)
+	height := int(hdr.Height)
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+
+	bpp := int(hdr.BitCount) / 8
+	stride := RowStride(width, int(hdr.BitCount))
+
+	expectedSize := pixelOffset + height*stride
+	if len(dib) < expectedSize {
+		return nil, fmt.Errorf("%w: недостаточно данных пикселей, ожидалось %d, получено %d", ErrUnsupportedDIB, expectedSize, len(dib))
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	pixels := dib[pixelOffset:]
+
+	for y := 0; y < height; y++ {
+		rowStart := (height - 1 - y) * stride
+		if topDown {
+			rowStart = y * stride
+		}
+		src := pixels[rowStart : rowStart+width*bpp]
+		dst := img.Pix[y*img.Stride : y*img.Stride+width*4]
+
+		for x := 0; x < width; x++ {
+			s := src[x*bpp:]
+			d := dst[x*4 : x*4+4]
+			d[0] = s[2]
+			d[1] = s[1]
+			d[2] = s[0]
+			if bpp == 4 {
+				d[3] = s[3]
+			} else {
+				d[3] = 255
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// DIBToPNG разбирает DIB и кодирует его в PNG.
+func DIBToPNG(dib []byte) ([]byte, error) {
+	img, err := DecodeDIB(dib)
+	if err != nil {
+		return nil, err
+	}
+	return EncodePNG(img)
+}
+
+// EncodePNG кодирует изображение в PNG.
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("не удалось закодировать PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeDIB собирает DIB с BITMAPINFOHEADER (32bpp BGRA, BI_RGB, строки снизу вверх),
+// пригодный для записи в буфер обмена как CF_DIB.
+func EncodeDIB(img image.Image) []byte {
+	rgba := ToRGBA(img)
+
+	bounds := rgba.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	stride := RowStride(width, 32)
+
+	hdr := BitmapInfoHeader{
+		Size:          BitmapInfoHeaderSize,
+		Width:         int32(width),
+		Height:        int32(height), // Положительная высота — стандартный DIB снизу вверх
+		Planes:        1,
+		BitCount:      32,
+		Compression:   BI_RGB,
+		SizeImage:     uint32(stride * height),
+		XPelsPerMeter: 2835, // 72 DPI
+		YPelsPerMeter: 2835,
+	}
+
+	buffer := make([]byte, BitmapInfoHeaderSize+int(hdr.SizeImage))
+	hdr.put(buffer)
+
+	pixels := buffer[BitmapInfoHeaderSize:]
+	for y := 0; y < height; y++ {
+		// В DIB снизу вверх первая строка буфера — нижняя строка изображения.
+		dst := pixels[(height-1-y)*stride:]
+		src := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < width; x++ {
+			dst[x*4] = src[x*4+2]
+			dst[x*4+1] = src[x*4+1]
+			dst[x*4+2] = src[x*4]
+			dst[x*4+3] = src[x*4+3]
+		}
+	}
+
+	return buffer
+}
+
+// ToRGBA возвращает изображение как *image.RGBA с началом координат в (0, 0),
+// копируя пиксели только при необходимости.
+func ToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// RowStride возвращает длину строки DIB в байтах с выравниванием до 4 байт.
+func RowStride(width, bitCount int) int {
+	return ((width*bitCount + 31) / 32) * 4
+}
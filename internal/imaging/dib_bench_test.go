@@ -0,0 +1,46 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func benchmarkImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := 0; y < 1080; y++ {
+		for x := 0; x < 1920; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	return img
+}
+
+func BenchmarkEncodeDIB(b *testing.B) {
+	img := benchmarkImage()
+	b.ReportAllocs()
+	for b.Loop() {
+		EncodeDIB(img)
+	}
+}
+
+func BenchmarkDecodeDIB(b *testing.B) {
+	dib := EncodeDIB(benchmarkImage())
+	b.SetBytes(int64(len(dib)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := DecodeDIB(dib); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDIBToPNG(b *testing.B) {
+	dib := EncodeDIB(benchmarkImage())
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := DIBToPNG(dib); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
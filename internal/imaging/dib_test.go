@@ -0,0 +1,194 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "перезаписать golden-файлы в testdata")
+
+// TestDecodeDIBGolden сверяет результат разбора каждого testdata/*.dib с эталонным PNG.
+func TestDecodeDIBGolden(t *testing.T) {
+	samples, err := filepath.Glob(filepath.Join("testdata", "*.dib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) == 0 {
+		t.Fatal("в testdata нет образцов DIB")
+	}
+
+	for _, sample := range samples {
+		name := strings.TrimSuffix(filepath.Base(sample), ".dib")
+		t.Run(name, func(t *testing.T) {
+			dib, err := os.ReadFile(sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pngData, err := DIBToPNG(dib)
+			if err != nil {
+				t.Fatalf("DIBToPNG: %v", err)
+			}
+
+			golden := strings.TrimSuffix(sample, ".dib") + ".golden.png"
+			if *update {
+				if err := os.WriteFile(golden, pngData, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want := readPNG(t, golden)
+			got, err := png.Decode(bytes.NewReader(pngData))
+			if err != nil {
+				t.Fatalf("результат не декодируется как PNG: %v", err)
+			}
+			assertSamePixels(t, got, want)
+		})
+	}
+}
+
+func TestDecodeDIBBottomUpRowOrderAndPadding(t *testing.T) {
+	dib, err := os.ReadFile(filepath.Join("testdata", "bgr24_bottomup_3x2.dib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := DecodeDIB(dib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("верхний левый пиксель должен быть красным, получено %v", got)
+	}
+	if got := img.RGBAAt(0, 1); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Fatalf("нижний левый пиксель должен быть синим, получено %v", got)
+	}
+}
+
+func TestEncodeDIBRoundTrip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 5, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(x * 50), uint8(y * 80), 200, 255})
+		}
+	}
+
+	dib := EncodeDIB(src)
+	hdr, err := ParseBitmapInfoHeader(dib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Width != 5 || hdr.Height != 3 || hdr.BitCount != 32 || hdr.Compression != BI_RGB {
+		t.Fatalf("неожиданный заголовок: %+v", hdr)
+	}
+	if want := BitmapInfoHeaderSize + 5*4*3; len(dib) != want {
+		t.Fatalf("размер DIB %d, ожидалось %d", len(dib), want)
+	}
+
+	decoded, err := DecodeDIB(dib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSamePixels(t, decoded, src)
+}
+
+func TestEncodeDIBHandlesOffsetBounds(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 10, 12, 11))
+	src.SetRGBA(10, 10, color.RGBA{1, 2, 3, 255})
+	src.SetRGBA(11, 10, color.RGBA{4, 5, 6, 255})
+
+	decoded, err := DecodeDIB(EncodeDIB(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.RGBAAt(1, 0); got != (color.RGBA{4, 5, 6, 255}) {
+		t.Fatalf("неожиданный пиксель %v", got)
+	}
+}
+
+func TestDecodeDIBRejectsUnsupported(t *testing.T) {
+	valid := EncodeDIB(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+
+	cases := map[string][]byte{
+		"short":     valid[:20],
+		"truncated": valid[:len(valid)-1],
+		"8bpp": func() []byte {
+			d := append([]byte(nil), valid...)
+			d[14] = 8
+			return d
+		}(),
+		"zero height": func() []byte {
+			d := append([]byte(nil), valid...)
+			copy(d[8:12], []byte{0, 0, 0, 0})
+			return d
+		}(),
+	}
+	for name, dib := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeDIB(dib); !errors.Is(err, ErrUnsupportedDIB) {
+				t.Fatalf("ожидалась ErrUnsupportedDIB, получено %v", err)
+			}
+		})
+	}
+}
+
+func readPNG(t *testing.T, path string) image.Image {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("нет golden-файла (запустите go test -update): %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func assertSamePixels(t *testing.T, got, want image.Image) {
+	t.Helper()
+	if got.Bounds().Size() != want.Bounds().Size() {
+		t.Fatalf("размер %v, ожидался %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	gb, wb := got.Bounds(), want.Bounds()
+	for y := 0; y < gb.Dy(); y++ {
+		for x := 0; x < gb.Dx(); x++ {
+			g := color.RGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y))
+			w := color.RGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y))
+			if g != w {
+				t.Fatalf("пиксель (%d,%d) = %v, ожидался %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestEncodeIconResourceLayout(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	src.SetNRGBA(0, 0, color.NRGBA{200, 100, 50, 128})
+
+	res := EncodeIconResource(src)
+	hdr, err := ParseBitmapInfoHeader(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Width != 3 || hdr.Height != 4 || hdr.BitCount != 32 {
+		t.Fatalf("неожиданный заголовок иконки: %+v", hdr)
+	}
+	// XOR: 3*4 байта на строку, AND: 1bpp, выровнено до 4 байт.
+	if want := BitmapInfoHeaderSize + 2*12 + 2*4; len(res) != want {
+		t.Fatalf("размер ресурса %d, ожидалось %d", len(res), want)
+	}
+
+	// Верхняя строка изображения — последняя строка XOR-маски; цвет не премультиплицирован.
+	px := res[BitmapInfoHeaderSize+12 : BitmapInfoHeaderSize+16]
+	if px[0] != 50 || px[1] != 100 || px[2] != 200 || px[3] != 128 {
+		t.Fatalf("неожиданный пиксель BGRA %v", px)
+	}
+}
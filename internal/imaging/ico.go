@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidICO возвращается, если данные не являются корректным .ico файлом.
+var ErrInvalidICO = errors.New("invalid ICO file")
+
+// IconEntry описывает одно изображение из каталога .ico файла.
+type IconEntry struct {
+	Width    int
+	Height   int
+	BitCount int
+	Data     []byte // Ресурс изображения: DIB (RT_ICON) или PNG
+}
+
+// ParseICO разбирает каталог .ico файла и возвращает все изображения.
+func ParseICO(ico []byte) ([]IconEntry, error) {
+	if len(ico) < 6 {
+		return nil, fmt.Errorf("%w: слишком короткий заголовок", ErrInvalidICO)
+	}
+	if binary.LittleEndian.Uint16(ico[0:2]) != 0 || binary.LittleEndian.Uint16(ico[2:4]) != 1 {
+		return nil, fmt.Errorf("%w: неверная сигнатура", ErrInvalidICO)
+	}
+	count := int(binary.LittleEndian.Uint16(ico[4:6]))
+	if count == 0 || len(ico) < 6+16*count {
+		return nil, fmt.Errorf("%w: повреждён каталог изображений", ErrInvalidICO)
+	}
+
+	entries := make([]IconEntry, 0, count)
+	for i := 0; i < count; i++ {
+		e := ico[6+16*i : 6+16*(i+1)]
+		// Размер 0 в каталоге означает 256 пикселей.
+		width, height := int(e[0]), int(e[1])
+		if width == 0 {
+			width = 256
+		}
+		if height == 0 {
+			height = 256
+		}
+		size := int(binary.LittleEndian.Uint32(e[8:12]))
+		offset := int(binary.LittleEndian.Uint32(e[12:16]))
+		if offset < 0 || size <= 0 || offset+size > len(ico) {
+			return nil, fmt.Errorf("%w: изображение %d выходит за пределы файла", ErrInvalidICO, i)
+		}
+		entries = append(entries, IconEntry{
+			Width:    width,
+			Height:   height,
+			BitCount: int(binary.LittleEndian.Uint16(e[6:8])),
+			Data:     ico[offset : offset+size],
+		})
+	}
+	return entries, nil
+}
+
+// PickIcon выбирает изображение, наиболее подходящее для размера size:
+// наименьшее среди не меньших size, иначе самое крупное.
+func PickIcon(entries []IconEntry, size int) (IconEntry, bool) {
+	if len(entries) == 0 {
+		return IconEntry{}, false
+	}
+	best := -1
+	largest := 0
+	for i, e := range entries {
+		if e.Width > entries[largest].Width || (e.Width == entries[largest].Width && e.BitCount > entries[largest].BitCount) {
+			largest = i
+		}
+		if e.Width < size {
+			continue
+		}
+		if best < 0 || e.Width < entries[best].Width || (e.Width == entries[best].Width && e.BitCount > entries[best].BitCount) {
+			best = i
+		}
+	}
+	if best < 0 {
+		best = largest
+	}
+	return entries[best], true
+}
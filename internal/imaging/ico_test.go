@@ -0,0 +1,55 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func buildICO(sizes ...int) []byte {
+	dirSize := 6 + 16*len(sizes)
+	data := make([]byte, dirSize, dirSize+4*len(sizes))
+	binary.LittleEndian.PutUint16(data[2:4], 1)
+	binary.LittleEndian.PutUint16(data[4:6], uint16(len(sizes)))
+	for i, size := range sizes {
+		e := data[6+16*i:]
+		e[0], e[1] = byte(size), byte(size) // 256 превращается в 0
+		binary.LittleEndian.PutUint16(e[6:8], 32)
+		binary.LittleEndian.PutUint32(e[8:12], 4)
+		binary.LittleEndian.PutUint32(e[12:16], uint32(dirSize+4*i))
+	}
+	for i := range sizes {
+		data = append(data, byte(i), byte(i), byte(i), byte(i))
+	}
+	return data
+}
+
+func TestParseICOAndPick(t *testing.T) {
+	entries, err := ParseICO(buildICO(16, 32, 256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[2].Width != 256 {
+		t.Fatalf("неожиданный каталог: %+v", entries)
+	}
+
+	cases := map[int]int{16: 16, 20: 32, 32: 32, 48: 256, 512: 256}
+	for size, want := range cases {
+		got, ok := PickIcon(entries, size)
+		if !ok || got.Width != want {
+			t.Fatalf("для размера %d выбрано %d, ожидалось %d", size, got.Width, want)
+		}
+	}
+}
+
+func TestParseICORejectsCorruptData(t *testing.T) {
+	ico := buildICO(16)
+	binary.LittleEndian.PutUint32(ico[6+12:6+16], 1000)
+
+	if _, err := ParseICO(ico); !errors.Is(err, ErrInvalidICO) {
+		t.Fatalf("ожидалась ErrInvalidICO, получено %v", err)
+	}
+	if _, err := ParseICO([]byte{1, 2}); !errors.Is(err, ErrInvalidICO) {
+		t.Fatalf("ожидалась ErrInvalidICO, получено %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// EncodeIconResource собирает ресурс иконки (формат RT_ICON), пригодный для CreateIconFromResourceEx:
+// BITMAPINFOHEADER с удвоенной высотой, 32bpp BGRA XOR-маска снизу вверх и нулевая 1bpp AND-маска.
+// Прозрачность задаётся альфа-каналом, поэтому AND-маска не используется.
+func EncodeIconResource(img image.Image) []byte {
+	b := img.Bounds()
+	width := b.Dx()
+	height := b.Dy()
+	xorStride := RowStride(width, 32)
+	andStride := RowStride(width, 1)
+
+	hdr := BitmapInfoHeader{
+		Size:        BitmapInfoHeaderSize,
+		Width:       int32(width),
+		Height:      int32(height * 2), // XOR- и AND-маски идут друг за другом
+		Planes:      1,
+		BitCount:    32,
+		Compression: BI_RGB,
+		SizeImage:   uint32((xorStride + andStride) * height),
+	}
+
+	buffer := make([]byte, BitmapInfoHeaderSize+int(hdr.SizeImage))
+	hdr.put(buffer)
+
+	pixels := buffer[BitmapInfoHeaderSize:]
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*xorStride:]
+		for x := 0; x < width; x++ {
+			// Иконки хранят цвет без предварительного умножения на альфу.
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			row[x*4] = c.B
+			row[x*4+1] = c.G
+			row[x*4+2] = c.R
+			row[x*4+3] = c.A
+		}
+	}
+
+	return buffer
+}
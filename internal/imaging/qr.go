@@ -0,0 +1,36 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QRSize — сторона изображения QR-кода по умолчанию в пикселях.
+const QRSize = 512
+
+// MaxQRBytes — сколько байт текста помещается в QR-код с уровнем коррекции M.
+const MaxQRBytes = 2331
+
+// ErrQRTooLong возвращается, если текст не помещается в QR-код.
+var ErrQRTooLong = errors.New("текст слишком длинный для QR-кода")
+
+// QRCode кодирует текст в QR-код с уровнем коррекции M и рамкой. size — сторона
+// изображения в пикселях; 0 — QRSize.
+func QRCode(text string, size int) (image.Image, error) {
+	if text == "" {
+		return nil, errors.New("пустой текст нельзя закодировать в QR-код")
+	}
+	if len(text) > MaxQRBytes {
+		return nil, ErrQRTooLong
+	}
+	if size <= 0 {
+		size = QRSize
+	}
+	code, err := qrcode.New(text, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return code.Image(size), nil
+}
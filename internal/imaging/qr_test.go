@@ -0,0 +1,41 @@
+package imaging
+
+import (
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestQRCode(t *testing.T) {
+	img, err := QRCode("https://example.com/путь?q=1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != QRSize || b.Dy() != QRSize {
+		t.Fatalf("размер %v, ожидалось %dx%d", b, QRSize, QRSize)
+	}
+	// Угол — белая рамка, а внутри есть чёрные модули.
+	if r, _, _, _ := img.At(0, 0).RGBA(); r != 0xffff {
+		t.Fatalf("угол не белый: %v", img.At(0, 0))
+	}
+	dark := false
+	for x := 0; x < QRSize && !dark; x++ {
+		dark = color.GrayModel.Convert(img.At(x, QRSize/2)).(color.Gray).Y == 0
+	}
+	if !dark {
+		t.Fatal("в QR-коде нет тёмных модулей")
+	}
+}
+
+func TestQRCodeLimits(t *testing.T) {
+	if _, err := QRCode(strings.Repeat("a", MaxQRBytes+1), 0); !errors.Is(err, ErrQRTooLong) {
+		t.Fatalf("ошибка %v, ожидалась ErrQRTooLong", err)
+	}
+	if _, err := QRCode(strings.Repeat("a", MaxQRBytes), 0); err != nil {
+		t.Fatalf("текст предельной длины: %v", err)
+	}
+	if _, err := QRCode("", 0); err == nil {
+		t.Fatal("ожидалась ошибка для пустого текста")
+	}
+}
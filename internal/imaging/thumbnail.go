@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+const (
+	// ThumbnailSize — длина большей стороны миниатюры по умолчанию, пикселей.
+	ThumbnailSize = 160
+
+	thumbnailJPEGQuality = 80
+)
+
+// Thumbnail уменьшает изображение так, чтобы большая сторона не превышала maxSize,
+// сохраняя пропорции. Пиксели усредняются по площади, поэтому мелкие детали
+// скриншотов не превращаются в шум. Изображения меньше maxSize не увеличиваются.
+func Thumbnail(img image.Image, maxSize int) *image.RGBA {
+	src := ToRGBA(img)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if maxSize <= 0 || (sw <= maxSize && sh <= maxSize) || sw == 0 || sh == 0 {
+		return src
+	}
+
+	dw, dh := maxSize, maxSize
+	if sw >= sh {
+		dh = max(1, sh*maxSize/sw)
+	} else {
+		dw = max(1, sw*maxSize/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, (dy+1)*sh/dh
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, (dx+1)*sw/dw
+			var r, g, b, a, n uint32
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[dy*dst.Stride+dx*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// EncodeThumbnail строит миниатюру и кодирует её: непрозрачные изображения — в JPEG,
+// изображения с прозрачностью — в PNG. Возвращает данные и их MIME-тип.
+func EncodeThumbnail(img image.Image, maxSize int) ([]byte, string, error) {
+	thumb := Thumbnail(img, maxSize)
+	if !thumb.Opaque() {
+		data, err := EncodePNG(thumb)
+		return data, "image/png", err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+		return nil, "", fmt.Errorf("не удалось закодировать JPEG: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
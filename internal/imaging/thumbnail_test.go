@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solidRGBA(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestThumbnailKeepsAspectRatio(t *testing.T) {
+	thumb := Thumbnail(solidRGBA(1920, 1080, color.RGBA{10, 20, 30, 255}), 160)
+
+	if got := thumb.Bounds().Size(); got != (image.Point{160, 90}) {
+		t.Fatalf("размер миниатюры = %v, ожидалось 160x90", got)
+	}
+	if got := thumb.RGBAAt(80, 45); got != (color.RGBA{10, 20, 30, 255}) {
+		t.Fatalf("цвет миниатюры = %v", got)
+	}
+}
+
+func TestThumbnailAveragesPixels(t *testing.T) {
+	img := solidRGBA(4, 2, color.RGBA{0, 0, 0, 255})
+	img.SetRGBA(1, 0, color.RGBA{200, 100, 40, 255})
+
+	thumb := Thumbnail(img, 2)
+
+	if got := thumb.RGBAAt(0, 0); got != (color.RGBA{50, 25, 10, 255}) {
+		t.Fatalf("ожидалось усреднение блока 2x2, получено %v", got)
+	}
+}
+
+func TestThumbnailDoesNotUpscale(t *testing.T) {
+	thumb := Thumbnail(solidRGBA(20, 10, color.RGBA{1, 2, 3, 255}), 160)
+	if got := thumb.Bounds().Size(); got != (image.Point{20, 10}) {
+		t.Fatalf("маленькое изображение не должно увеличиваться, размер %v", got)
+	}
+}
+
+func TestEncodeThumbnailFormat(t *testing.T) {
+	data, contentType, err := EncodeThumbnail(solidRGBA(400, 300, color.RGBA{0, 128, 255, 255}), 100)
+	if err != nil {
+		t.Fatalf("EncodeThumbnail: %v", err)
+	}
+	if contentType != "image/jpeg" {
+		t.Fatalf("непрозрачная миниатюра должна быть JPEG, получено %s", contentType)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("некорректный JPEG: %v", err)
+	}
+
+	data, contentType, err = EncodeThumbnail(solidRGBA(400, 300, color.RGBA{0, 0, 0, 0}), 100)
+	if err != nil {
+		t.Fatalf("EncodeThumbnail: %v", err)
+	}
+	if contentType != "image/png" {
+		t.Fatalf("прозрачная миниатюра должна быть PNG, получено %s", contentType)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("некорректный PNG: %v", err)
+	}
+}
+
+func TestCompactImage(t *testing.T) {
+	src, err := EncodePNG(solidRGBA(800, 600, color.RGBA{0, 128, 255, 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, contentType, smaller, err := CompactImage(src, CompactJPEG, 400, 70)
+	if err != nil {
+		t.Fatalf("CompactImage: %v", err)
+	}
+	if contentType != "image/jpeg" || !smaller {
+		t.Fatalf("ожидался уменьшенный JPEG, получено %s, smaller=%v", contentType, smaller)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("некорректный JPEG: %v", err)
+	}
+	if cfg.Width != 400 || cfg.Height != 300 {
+		t.Fatalf("размер = %dx%d, ожидалось 400x300", cfg.Width, cfg.Height)
+	}
+
+	transparent, _ := EncodePNG(solidRGBA(800, 600, color.RGBA{0, 0, 0, 0}))
+	if _, contentType, _, err := CompactImage(transparent, CompactJPEG, 400, 70); err != nil || contentType != "image/png" {
+		t.Fatalf("прозрачное изображение должно остаться PNG, получено %s, %v", contentType, err)
+	}
+
+	if data, _, smaller, _ := CompactImage(src, CompactOriginal, 400, 70); smaller || !bytes.Equal(data, src) {
+		t.Fatal("original не должен менять данные")
+	}
+}
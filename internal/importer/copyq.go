@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Бинарный экспорт CopyQ (.cpq) — сериализация Qt без описания формата; его не читаем.
+var errCopyQBinary = errors.New("бинарный экспорт CopyQ (.cpq) не поддерживается: выгрузите историю в JSON командой copyq eval (см. README)")
+
+// copyqItem — элемент JSON: либо строка, либо объект с текстом и временем в миллисекундах.
+type copyqItem struct {
+	Text string `json:"text"`
+	Time int64  `json:"time,omitempty"`
+}
+
+func (it *copyqItem) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &it.Text)
+	}
+	type plain copyqItem
+	return json.Unmarshal(data, (*plain)(it))
+}
+
+// parseCopyQ читает массив JSON от старых элементов к новым. Такой массив выгружает
+// сама CopyQ (команда в README):
+//
+//	copyq eval "var r=[];for(var i=size()-1;i>=0;--i){var t=str(read('text/plain',i));if(t)r.push({text:t})}print(JSON.stringify(r))"
+//
+// Элементам без времени назначается время импорта с сохранением порядка.
+func parseCopyQ(data []byte) (*Result, error) {
+	var items []copyqItem
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &items); err != nil {
+		return nil, fmt.Errorf("некорректный JSON CopyQ: %w", err)
+	}
+	res := &Result{Format: FormatCopyQ}
+	base := time.Now().Add(-time.Duration(len(items)) * time.Millisecond)
+	for i, item := range items {
+		if item.Text == "" {
+			res.Skipped++
+			continue
+		}
+		ts := base.Add(time.Duration(i) * time.Millisecond)
+		if item.Time > 0 {
+			ts = time.UnixMilli(item.Time)
+		}
+		res.Clips = append(res.Clips, Clip{SourceID: strconv.Itoa(i), Time: ts, Text: item.Text})
+	}
+	return res, nil
+}
+
+// isCopyQBinary узнаёт .cpq по строке "CopyQ" в заголовке (Qt пишет её в UTF-16).
+func isCopyQBinary(data []byte) bool {
+	head := data[:min(len(data), 64)]
+	return bytes.Contains(head, []byte("CopyQ")) ||
+		bytes.Contains(head, []byte("C\x00o\x00p\x00y\x00Q")) ||
+		bytes.Contains(head, []byte("\x00C\x00o\x00p\x00y\x00Q"))
+}
@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+)
+
+// Модификаторы в старшем байте Main.lShortCut (HOTKEYF_*). Ditto хранит Win во флаге EXT.
+const (
+	dittoShift = 0x01
+	dittoCtrl  = 0x02
+	dittoAlt   = 0x04
+	dittoWin   = 0x08
+)
+
+// dittoData — форматы одного клипа из таблицы Data. Неизвестные форматы не сохраняются,
+// но сама запись появляется: по ней видно, что у клипа есть содержимое помимо mText.
+type dittoData struct {
+	unicodeText string
+	ansiText    string
+	png         []byte
+	dib         []byte
+}
+
+// parseDitto читает таблицы Main (клипы) и Data (содержимое в форматах буфера обмена).
+// Группы пропускаются; клипы с глобальным сочетанием клавиш дополнительно становятся сниппетами.
+func parseDitto(data []byte) (*Result, error) {
+	db, err := openSQLite(data)
+	if err != nil {
+		return nil, err
+	}
+	mainTbl, err := db.table("Main")
+	if err != nil {
+		return nil, fmt.Errorf("не похоже на базу Ditto: %w", err)
+	}
+	dataTbl, err := db.table("Data")
+	if err != nil {
+		return nil, fmt.Errorf("не похоже на базу Ditto: %w", err)
+	}
+
+	formats, err := readDittoData(db, dataTbl)
+	if err != nil {
+		return nil, err
+	}
+
+	col := func(name string) int { return mainTbl.column(name) }
+	colDate, colText, colGroup := col("lDate"), col("mText"), col("bIsGroup")
+	colShortcut, colGlobal, colName := col("lShortCut"), col("globalShortCut"), col("QuickPasteText")
+
+	res := &Result{Format: FormatDitto}
+	err = db.scan(mainTbl, func(row sqliteRow) error {
+		if intValue(row, colGroup) != 0 {
+			return nil
+		}
+		clip := Clip{
+			SourceID: strconv.FormatInt(row.rowid, 10),
+			Time:     time.Unix(intValue(row, colDate), 0),
+		}
+		d, hasData := formats[row.rowid]
+		switch {
+		case d.unicodeText != "":
+			clip.Text = d.unicodeText
+		case d.ansiText != "":
+			clip.Text = d.ansiText
+		case len(d.png) > 0:
+			clip.ImagePNG = d.png
+		case len(d.dib) > 0:
+			if png, err := imaging.DIBToPNG(d.dib); err == nil {
+				clip.ImagePNG = png
+			}
+		}
+		if clip.Text == "" && clip.ImagePNG == nil {
+			// У старых версий Ditto текст есть только в mText.
+			if text := stringValue(row, colText); text != "" && !hasData {
+				clip.Text = text
+			}
+		}
+		if clip.Text == "" && clip.ImagePNG == nil {
+			res.Skipped++
+			return nil
+		}
+		res.Clips = append(res.Clips, clip)
+
+		shortcut := intValue(row, colShortcut)
+		if shortcut == 0 || clip.Text == "" || (colGlobal >= 0 && intValue(row, colGlobal) == 0) {
+			return nil
+		}
+		hotkey, ok := dittoHotkey(shortcut)
+		if !ok {
+			return nil
+		}
+		name := stringValue(row, colName)
+		if name == "" {
+			name = "Ditto " + hotkey
+		}
+		res.Snippets = append(res.Snippets, Snippet{Name: name, Text: clip.Text, Hotkey: hotkey})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(res.Clips, func(i, j int) bool { return res.Clips[i].Time.Before(res.Clips[j].Time) })
+	return res, nil
+}
+
+func readDittoData(db *sqliteDB, t *sqliteTable) (map[int64]dittoData, error) {
+	colParent, colFormat, colData := t.column("lParentID"), t.column("strClipBoardFormat"), t.column("ooData")
+	if colParent < 0 || colFormat < 0 || colData < 0 {
+		return nil, fmt.Errorf("не похоже на базу Ditto: в таблице Data нет нужных столбцов")
+	}
+	formats := make(map[int64]dittoData)
+	err := db.scan(t, func(row sqliteRow) error {
+		parent := intValue(row, colParent)
+		blob := bytesValue(row, colData)
+		d := formats[parent]
+		switch stringValue(row, colFormat) {
+		case "CF_UNICODETEXT":
+			d.unicodeText = strings.TrimRight(decodeUTF16(blob, binary.LittleEndian), "\x00")
+		case "CF_TEXT":
+			// CF_TEXT в кодировке ANSI; берём его, только если это корректный UTF-8.
+			if text := strings.TrimRight(string(blob), "\x00"); utf8.ValidString(text) {
+				d.ansiText = text
+			}
+		case "PNG":
+			d.png = blob
+		case "CF_DIB":
+			d.dib = blob
+		}
+		formats[parent] = d
+		return nil
+	})
+	return formats, err
+}
+
+// dittoHotkey переводит значение lShortCut (младший байт — код клавиши, старший — HOTKEYF_*)
+// в строку сочетания ClipQueue. Поддерживаются буквы, цифры и F1–F12.
+func dittoHotkey(shortcut int64) (string, bool) {
+	vk := shortcut & 0xff
+	mods := (shortcut >> 8) & 0xff
+	var key string
+	switch {
+	case vk >= '0' && vk <= '9', vk >= 'A' && vk <= 'Z':
+		key = string(rune(vk))
+	case vk >= 0x70 && vk <= 0x7B:
+		key = "F" + strconv.FormatInt(vk-0x70+1, 10)
+	default:
+		return "", false
+	}
+	var parts []string
+	if mods&dittoCtrl != 0 {
+		parts = append(parts, "Ctrl")
+	}
+	if mods&dittoAlt != 0 {
+		parts = append(parts, "Alt")
+	}
+	if mods&dittoShift != 0 {
+		parts = append(parts, "Shift")
+	}
+	if mods&dittoWin != 0 {
+		parts = append(parts, "Win")
+	}
+	return strings.Join(append(parts, key), "+"), true
+}
+
+func intValue(row sqliteRow, col int) int64 {
+	if col < 0 || col >= len(row.values) {
+		return 0
+	}
+	switch v := row.values[col].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func stringValue(row sqliteRow, col int) string {
+	if col < 0 || col >= len(row.values) {
+		return ""
+	}
+	switch v := row.values[col].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func bytesValue(row sqliteRow, col int) []byte {
+	if col < 0 || col >= len(row.values) {
+		return nil
+	}
+	switch v := row.values[col].(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
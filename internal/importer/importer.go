@@ -0,0 +1,119 @@
+// Package importer читает историю и сниппеты других менеджеров буфера обмена
+// (Ditto, CopyQ) и файлы экспорта самого ClipQueue, чтобы перенести их в историю.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/archive"
+	"github.com/serty2005/clipqueue/internal/filebundle"
+)
+
+// Format — формат импортируемых данных.
+type Format string
+
+const (
+	FormatClipQueue Format = "clipqueue" // Экспорт истории ClipQueue (JSON, CSV или ZIP)
+	FormatDitto     Format = "ditto"     // База Ditto.db (SQLite)
+	FormatCopyQ     Format = "copyq"     // JSON, выгруженный из CopyQ командой copyq eval
+)
+
+// Clip — элемент истории из другой программы. Заполнено одно из полей Text, Files или ImagePNG.
+type Clip struct {
+	SourceID string // ID в исходной программе: по нему повторный импорт не создаёт дублей
+	Time     time.Time
+	Text     string
+	Files    []string
+	ImagePNG []byte
+	// Embedded — содержимое файлов, встроенное при экспорте; при импорте файлы
+	// восстанавливаются во временный каталог вместо путей Files.
+	Embedded []filebundle.File
+}
+
+// Snippet — элемент с глобальным сочетанием клавиш, который становится макросом.
+type Snippet struct {
+	Name   string
+	Text   string
+	Hotkey string // В формате конфигурации, например "Ctrl+Shift+1"
+}
+
+// Result — всё, что удалось прочитать. Clips упорядочены от старых к новым.
+type Result struct {
+	Format   Format
+	Clips    []Clip
+	Snippets []Snippet
+	Skipped  int // Элементы без поддерживаемого содержимого (группы, списки файлов Ditto, неизвестные форматы)
+}
+
+// ErrUnknownFormat возвращается, когда формат данных не распознан.
+var ErrUnknownFormat = errors.New("формат не распознан: ожидается экспорт ClipQueue, база Ditto (.db) или JSON из CopyQ")
+
+// Detect определяет формат по содержимому.
+func Detect(data []byte) (Format, error) {
+	if _, ok := archive.Detect(data); ok {
+		return FormatClipQueue, nil
+	}
+	if isSQLite(data) {
+		return FormatDitto, nil
+	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n\xef\xbb\xbf")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return FormatCopyQ, nil
+	}
+	if isCopyQBinary(data) {
+		return "", errCopyQBinary
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse читает данные в формате format; пустой format определяется через Detect.
+// limits ограничивает распаковку ZIP-экспорта ClipQueue.
+func Parse(data []byte, format Format, limits archive.Limits) (*Result, error) {
+	if format == "" {
+		detected, err := Detect(data)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+	switch format {
+	case FormatDitto:
+		return parseDitto(data)
+	case FormatCopyQ:
+		return parseCopyQ(data)
+	case FormatClipQueue:
+		return parseClipQueue(data, limits)
+	}
+	return nil, fmt.Errorf("неизвестный формат %q", format)
+}
+
+// parseClipQueue читает файл экспорта ClipQueue. ID элементов сохраняются как есть,
+// поэтому импорт на том же компьютере не дублирует уже существующие элементы.
+func parseClipQueue(data []byte, limits archive.Limits) (*Result, error) {
+	items, err := archive.Read(data, limits)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Format: FormatClipQueue}
+	for _, item := range items {
+		clip := Clip{SourceID: item.ID, Time: item.Timestamp}
+		switch item.Type {
+		case archive.TypeText:
+			clip.Text = item.Text
+		case archive.TypeFiles:
+			clip.Files = item.Files
+			clip.Embedded = item.Embedded
+		case archive.TypeImage:
+			clip.ImagePNG = item.ImagePNG
+		}
+		if item.ID == "" || (clip.Text == "" && len(clip.Files) == 0 && len(clip.ImagePNG) == 0) {
+			res.Skipped++
+			continue
+		}
+		res.Clips = append(res.Clips, clip)
+	}
+	return res, nil
+}
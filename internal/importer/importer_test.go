@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/archive"
+)
+
+// testdata/ditto.db собран sqlite3 по схеме Ditto со страницами 1 КиБ, поэтому
+// в нём есть внутренние страницы b-tree и страницы переполнения.
+func TestParseDitto(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "ditto.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format, err := Detect(data); err != nil || format != FormatDitto {
+		t.Fatalf("Detect = %q, %v", format, err)
+	}
+	res, err := Parse(data, "", archive.Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 150 коротких клипов, длинный текст, изображение, два клипа с сочетаниями и
+	// старый клип только с mText; группа и клип с файлами пропущены.
+	if len(res.Clips) != 155 || res.Skipped != 1 {
+		t.Fatalf("клипов %d, пропущено %d", len(res.Clips), res.Skipped)
+	}
+	if first := res.Clips[0]; first.Text != "старый" {
+		t.Fatalf("первый клип %q, ожидался самый старый", first.Text)
+	}
+	if res.Clips[1].Text != "клип 0" || res.Clips[150].Text != "клип 149" {
+		t.Fatalf("порядок клипов нарушен: %q, %q", res.Clips[1].Text, res.Clips[150].Text)
+	}
+	if long := res.Clips[151].Text; len(long) < 10000 || !strings.HasPrefix(long, "длинный текст ") {
+		t.Fatalf("длинный текст прочитан неверно: %d байт", len(long))
+	}
+	img, err := png.Decode(bytes.NewReader(res.Clips[152].ImagePNG))
+	if err != nil || img.Bounds().Dx() != 3 || img.Bounds().Dy() != 2 {
+		t.Fatalf("изображение: %v", err)
+	}
+	if res.Clips[152].Time.Unix() != 1700001001 {
+		t.Fatalf("время клипа %v", res.Clips[152].Time)
+	}
+
+	if len(res.Snippets) != 1 {
+		t.Fatalf("сниппетов %d, ожидался 1 с глобальным сочетанием", len(res.Snippets))
+	}
+	if s := res.Snippets[0]; s.Name != "Подпись" || s.Text != "подпись" || s.Hotkey != "Ctrl+Shift+1" {
+		t.Fatalf("сниппет %+v", s)
+	}
+}
+
+func TestParseCopyQ(t *testing.T) {
+	data := []byte(`["первый", {"text": "второй", "time": 1700000000000}, "", {"text": "третий"}]`)
+	res, err := Parse(data, "", archive.Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Format != FormatCopyQ || len(res.Clips) != 3 || res.Skipped != 1 {
+		t.Fatalf("результат %+v", res)
+	}
+	if res.Clips[1].Text != "второй" || res.Clips[1].Time.UnixMilli() != 1700000000000 {
+		t.Fatalf("второй клип %+v", res.Clips[1])
+	}
+	if !res.Clips[0].Time.Before(res.Clips[2].Time) {
+		t.Fatal("клипы без времени должны сохранять порядок")
+	}
+}
+
+func TestDetectRejectsUnknown(t *testing.T) {
+	if _, err := Detect([]byte("просто текст")); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Detect = %v", err)
+	}
+	if _, err := Detect([]byte("\x00\x00\x00\x10\x00C\x00o\x00p\x00y\x00Q")); !errors.Is(err, errCopyQBinary) {
+		t.Fatalf("Detect(.cpq) = %v", err)
+	}
+}
+
+func TestDittoHotkey(t *testing.T) {
+	cases := map[int64]string{
+		0x0231: "Ctrl+1",
+		0x0C70: "Alt+Win+F1",
+		0x0341: "Ctrl+Shift+A",
+	}
+	for value, want := range cases {
+		if got, ok := dittoHotkey(value); !ok || got != want {
+			t.Errorf("dittoHotkey(%#x) = %q, %v; ожидалось %q", value, got, ok, want)
+		}
+	}
+	if _, ok := dittoHotkey(0x0220); ok {
+		t.Error("пробел не поддерживается сочетаниями ClipQueue")
+	}
+}
+
+// Цепочка внутренних страниц, каждая ячейка которых ссылается на следующую:
+// без учёта посещённых страниц обход растёт как 10^19 и не завершается.
+func TestSQLiteRejectsRepeatedPages(t *testing.T) {
+	const pageSize, pages, cells = 512, 20, 10
+	data := make([]byte, pages*pageSize)
+	copy(data, sqliteMagic)
+	data[16], data[17] = pageSize>>8, pageSize&0xff
+	data[59] = 1 // UTF-8
+
+	for n := 1; n < pages; n++ {
+		page := data[(n-1)*pageSize : n*pageSize]
+		hdr := 0
+		if n == 1 {
+			hdr = 100
+		}
+		page[hdr] = pageTableInterior
+		page[hdr+4] = cells
+		binary.BigEndian.PutUint32(page[hdr+8:], uint32(n+1))
+		for i := 0; i < cells; i++ {
+			off := 300 + 5*i
+			binary.BigEndian.PutUint16(page[hdr+12+2*i:], uint16(off))
+			binary.BigEndian.PutUint32(page[off:], uint32(n+1))
+			page[off+4] = byte(i)
+		}
+	}
+	data[(pages-1)*pageSize] = pageTableLeaf
+
+	db, err := openSQLite(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.table("Main"); !errors.Is(err, errCorrupt) {
+		t.Fatalf("ожидалась errCorrupt, получено %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// Минимальный читатель файлов SQLite 3: только обход таблиц (b-tree с rowid)
+// и разбор записей. Индексы, WAL и запись не поддерживаются — для импорта
+// базы закрытой программы этого достаточно, а драйвер SQLite не нужен.
+
+const sqliteMagic = "SQLite format 3\x00"
+
+// Типы страниц b-tree таблиц.
+const (
+	pageTableInterior = 0x05
+	pageTableLeaf     = 0x0D
+)
+
+// maxTreeDepth ограничивает глубину рекурсии; от зацикленных и повторных ссылок
+// в повреждённом файле защищает учёт посещённых страниц в scan.
+const maxTreeDepth = 32
+
+var errCorrupt = errors.New("повреждённый файл SQLite")
+
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+	encoding int // 1 — UTF-8, 2 — UTF-16LE, 3 — UTF-16BE
+}
+
+// sqliteTable — таблица из sqlite_master.
+type sqliteTable struct {
+	name     string
+	rootPage int
+	columns  []string
+	rowidCol int // Столбец INTEGER PRIMARY KEY (хранится как rowid) или -1
+}
+
+// sqliteRow — строка таблицы. Значения: nil, int64, float64, string или []byte.
+type sqliteRow struct {
+	rowid  int64
+	values []any
+}
+
+func isSQLite(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sqliteMagic))
+}
+
+func openSQLite(data []byte) (*sqliteDB, error) {
+	if len(data) < 100 || !isSQLite(data) {
+		return nil, errors.New("не файл SQLite 3")
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("%w: размер страницы %d", errCorrupt, pageSize)
+	}
+	db := &sqliteDB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+		encoding: int(binary.BigEndian.Uint32(data[56:60])),
+	}
+	if db.encoding == 0 {
+		db.encoding = 1
+	}
+	if db.encoding > 3 {
+		return nil, fmt.Errorf("%w: кодировка %d", errCorrupt, db.encoding)
+	}
+	return db, nil
+}
+
+func (db *sqliteDB) page(n int) ([]byte, error) {
+	start := (n - 1) * db.pageSize
+	if n < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("%w: нет страницы %d", errCorrupt, n)
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// table ищет таблицу в sqlite_master и разбирает имена столбцов из CREATE TABLE.
+func (db *sqliteDB) table(name string) (*sqliteTable, error) {
+	master := &sqliteTable{name: "sqlite_master", rootPage: 1, rowidCol: -1}
+	var found *sqliteTable
+	err := db.scan(master, func(row sqliteRow) error {
+		if len(row.values) < 5 || row.values[0] != "table" {
+			return nil
+		}
+		tblName, _ := row.values[1].(string)
+		if !strings.EqualFold(tblName, name) {
+			return nil
+		}
+		root, _ := row.values[3].(int64)
+		sql, _ := row.values[4].(string)
+		columns, rowidCol := parseColumns(sql)
+		found = &sqliteTable{name: tblName, rootPage: int(root), columns: columns, rowidCol: rowidCol}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("в базе нет таблицы %s", name)
+	}
+	return found, nil
+}
+
+// column возвращает индекс столбца по имени без учёта регистра или -1.
+func (t *sqliteTable) column(name string) int {
+	for i, c := range t.columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// scan обходит все строки таблицы в порядке rowid. Каждая страница дерева и
+// переполнения читается не больше одного раза: страница, на которую ссылаются
+// дважды (сама на себя или из нескольких ячеек), означает повреждённый файл,
+// а её повторный обход рос бы экспоненциально.
+func (db *sqliteDB) scan(t *sqliteTable, fn func(row sqliteRow) error) error {
+	return db.walk(t, t.rootPage, 0, make(map[int]bool), fn)
+}
+
+// visit отмечает страницу n прочитанной или возвращает errCorrupt, если она уже встречалась.
+func visit(visited map[int]bool, n int) error {
+	if visited[n] {
+		return fmt.Errorf("%w: повторная ссылка на страницу %d", errCorrupt, n)
+	}
+	visited[n] = true
+	return nil
+}
+
+func (db *sqliteDB) walk(t *sqliteTable, pageNo, depth int, visited map[int]bool, fn func(row sqliteRow) error) error {
+	if depth > maxTreeDepth {
+		return fmt.Errorf("%w: слишком глубокое дерево", errCorrupt)
+	}
+	if err := visit(visited, pageNo); err != nil {
+		return err
+	}
+	page, err := db.page(pageNo)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if pageNo == 1 {
+		hdr = 100 // На первой странице b-tree идёт после заголовка файла
+	}
+	if hdr+8 > len(page) {
+		return errCorrupt
+	}
+	kind := page[hdr]
+	cells := int(binary.BigEndian.Uint16(page[hdr+3:]))
+	ptrs := hdr + 8
+	if kind == pageTableInterior {
+		ptrs = hdr + 12
+	}
+	if ptrs+2*cells > len(page) {
+		return errCorrupt
+	}
+
+	for i := 0; i < cells; i++ {
+		off := int(binary.BigEndian.Uint16(page[ptrs+2*i:]))
+		if off >= len(page) {
+			return errCorrupt
+		}
+		switch kind {
+		case pageTableInterior:
+			if off+4 > len(page) {
+				return errCorrupt
+			}
+			child := int(binary.BigEndian.Uint32(page[off:]))
+			if err := db.walk(t, child, depth+1, visited, fn); err != nil {
+				return err
+			}
+		case pageTableLeaf:
+			row, err := db.leafCell(t, page, off, visited)
+			if err != nil {
+				return err
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: страница %d типа %#x в таблице %s", errCorrupt, pageNo, kind, t.name)
+		}
+	}
+	if kind == pageTableInterior {
+		right := int(binary.BigEndian.Uint32(page[hdr+8:]))
+		return db.walk(t, right, depth+1, visited, fn)
+	}
+	return nil
+}
+
+// leafCell читает ячейку листа таблицы вместе со страницами переполнения;
+// страницы цепочки отмечаются в visited, как и страницы дерева.
+func (db *sqliteDB) leafCell(t *sqliteTable, page []byte, off int, visited map[int]bool) (sqliteRow, error) {
+	size, n := readVarint(page[off:])
+	if n == 0 {
+		return sqliteRow{}, errCorrupt
+	}
+	off += n
+	rowid, n := readVarint(page[off:])
+	if n == 0 {
+		return sqliteRow{}, errCorrupt
+	}
+	off += n
+	if size < 0 || size > int64(len(db.data)) {
+		return sqliteRow{}, errCorrupt
+	}
+
+	total := int(size)
+	local := db.localPayload(total)
+	if off+local > len(page) {
+		return sqliteRow{}, errCorrupt
+	}
+	payload := page[off : off+local]
+	if local < total {
+		if off+local+4 > len(page) {
+			return sqliteRow{}, errCorrupt
+		}
+		buf := make([]byte, 0, total)
+		buf = append(buf, payload...)
+		next := int(binary.BigEndian.Uint32(page[off+local:]))
+		for len(buf) < total {
+			if err := visit(visited, next); err != nil {
+				return sqliteRow{}, err
+			}
+			ovf, err := db.page(next)
+			if err != nil {
+				return sqliteRow{}, err
+			}
+			chunk := ovf[4:db.usable]
+			if rest := total - len(buf); len(chunk) > rest {
+				chunk = chunk[:rest]
+			}
+			buf = append(buf, chunk...)
+			next = int(binary.BigEndian.Uint32(ovf))
+		}
+		payload = buf
+	}
+
+	values, err := db.record(payload)
+	if err != nil {
+		return sqliteRow{}, err
+	}
+	if t.rowidCol >= 0 && t.rowidCol < len(values) && values[t.rowidCol] == nil {
+		values[t.rowidCol] = rowid
+	}
+	return sqliteRow{rowid: rowid, values: values}, nil
+}
+
+// localPayload — сколько байт записи хранится на самой странице листа таблицы.
+func (db *sqliteDB) localPayload(total int) int {
+	u := db.usable
+	maxLocal := u - 35
+	if total <= maxLocal {
+		return total
+	}
+	minLocal := (u-12)*32/255 - 23
+	k := minLocal + (total-minLocal)%(u-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// record разбирает запись: заголовок с типами столбцов и их значения.
+func (db *sqliteDB) record(payload []byte) ([]any, error) {
+	hdrSize, n := readVarint(payload)
+	if n == 0 || hdrSize < int64(n) || hdrSize > int64(len(payload)) {
+		return nil, errCorrupt
+	}
+	var types []int64
+	for pos := n; pos < int(hdrSize); {
+		st, m := readVarint(payload[pos:int(hdrSize)])
+		if m == 0 {
+			return nil, errCorrupt
+		}
+		types = append(types, st)
+		pos += m
+	}
+
+	body := payload[hdrSize:]
+	values := make([]any, len(types))
+	for i, st := range types {
+		size := serialSize(st)
+		if size < 0 || size > len(body) {
+			return nil, errCorrupt
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case st == 0:
+			values[i] = nil
+		case st >= 1 && st <= 6:
+			values[i] = readInt(v)
+		case st == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(v))
+		case st == 8:
+			values[i] = int64(0)
+		case st == 9:
+			values[i] = int64(1)
+		case st >= 12 && st%2 == 0:
+			values[i] = v
+		case st >= 13:
+			values[i] = db.text(v)
+		default:
+			return nil, fmt.Errorf("%w: тип столбца %d", errCorrupt, st)
+		}
+	}
+	return values, nil
+}
+
+func (db *sqliteDB) text(v []byte) string {
+	if db.encoding == 1 {
+		return string(v)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if db.encoding == 3 {
+		order = binary.BigEndian
+	}
+	return decodeUTF16(v, order)
+}
+
+func decodeUTF16(v []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(v)/2)
+	for i := range units {
+		units[i] = order.Uint16(v[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func serialSize(st int64) int {
+	switch {
+	case st >= 0 && st <= 4:
+		return [...]int{0, 1, 2, 3, 4}[st]
+	case st == 5:
+		return 6
+	case st == 6, st == 7:
+		return 8
+	case st == 8, st == 9:
+		return 0
+	case st >= 12:
+		return int((st - 12) / 2)
+	}
+	return -1
+}
+
+// readInt читает знаковое целое big-endian длиной 1–8 байт.
+func readInt(v []byte) int64 {
+	var x int64
+	if len(v) > 0 && v[0]&0x80 != 0 {
+		x = -1
+	}
+	for _, b := range v {
+		x = x<<8 | int64(b)
+	}
+	return x
+}
+
+// readVarint читает varint SQLite (до 9 байт, big-endian). n == 0 — данных не хватило.
+func readVarint(b []byte) (v int64, n int) {
+	var x uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return int64(x<<8 | uint64(b[i])), 9
+		}
+		x = x<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return int64(x), i + 1
+		}
+	}
+	return 0, 0
+}
+
+// parseColumns достаёт имена столбцов из CREATE TABLE и номер столбца,
+// объявленного как INTEGER PRIMARY KEY.
+func parseColumns(sql string) ([]string, int) {
+	open := strings.IndexByte(sql, '(')
+	end := strings.LastIndexByte(sql, ')')
+	if open < 0 || end <= open {
+		return nil, -1
+	}
+	var defs []string
+	depth, start := 0, open+1
+	for i := open + 1; i < end; i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, sql[start:i])
+				start = i + 1
+			}
+		}
+	}
+	defs = append(defs, sql[start:end])
+
+	var columns []string
+	rowidCol := -1
+	for _, def := range defs {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+			continue
+		}
+		upper := strings.ToUpper(strings.Join(fields[1:], " "))
+		if strings.HasPrefix(upper, "INTEGER PRIMARY KEY") {
+			rowidCol = len(columns)
+		}
+		columns = append(columns, strings.Trim(fields[0], "\"'`[]"))
+	}
+	return columns, rowidCol
+}
@@ -0,0 +1,53 @@
+// Package instance хранит сведения о запущенном экземпляре ClipQueue,
+// по которым внешние клиенты (CLI, скрипты) находят его API.
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AddrFileName — файл в каталоге данных с адресом UI-сервера запущенного экземпляра.
+const AddrFileName = "server.addr"
+
+// ErrNotRunning возвращается, если файл адреса отсутствует.
+var ErrNotRunning = errors.New("ClipQueue не запущен")
+
+// AddrFile возвращает путь к файлу адреса в каталоге данных.
+func AddrFile(dataDir string) string {
+	return filepath.Join(dataDir, AddrFileName)
+}
+
+// WriteAddr сохраняет базовый URL API, например http://127.0.0.1:54321.
+func WriteAddr(dataDir, url string) error {
+	return os.WriteFile(AddrFile(dataDir), []byte(url+"\n"), 0644)
+}
+
+// ReadAddr читает базовый URL API запущенного экземпляра.
+func ReadAddr(dataDir string) (string, error) {
+	data, err := os.ReadFile(AddrFile(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotRunning
+	}
+	if err != nil {
+		return "", err
+	}
+	url := strings.TrimSpace(string(data))
+	if url == "" {
+		return "", fmt.Errorf("пустой файл адреса %s", AddrFile(dataDir))
+	}
+	return url, nil
+}
+
+// RemoveAddr удаляет файл адреса при остановке экземпляра.
+func RemoveAddr(dataDir string) error {
+	err := os.Remove(AddrFile(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package instance
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddrRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := ReadAddr(dir); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("без файла ожидалась ErrNotRunning, получено %v", err)
+	}
+	if err := WriteAddr(dir, "http://127.0.0.1:5000"); err != nil {
+		t.Fatalf("WriteAddr: %v", err)
+	}
+	if got, err := ReadAddr(dir); err != nil || got != "http://127.0.0.1:5000" {
+		t.Fatalf("ReadAddr = %q, %v", got, err)
+	}
+	if err := RemoveAddr(dir); err != nil {
+		t.Fatalf("RemoveAddr: %v", err)
+	}
+	if err := RemoveAddr(dir); err != nil {
+		t.Fatalf("повторное удаление не должно быть ошибкой: %v", err)
+	}
+}
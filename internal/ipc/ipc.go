@@ -0,0 +1,167 @@
+// Package ipc реализует локальный протокол управления ClipQueue поверх потокового
+// соединения (именованного канала): по одной JSON-команде на строку и по одному
+// JSON-ответу на строку.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/serty2005/clipqueue/internal/crash"
+)
+
+// PipeName возвращает имя именованного канала управления для сеанса Windows
+// sessionID. Канал свой у каждого сеанса, как и блокировка единственного
+// экземпляра: при быстром переключении пользователей и в RDP каждый экземпляр
+// слушает свой канал, а клиент подключается к экземпляру своего сеанса.
+func PipeName(sessionID uint32) string {
+	return fmt.Sprintf(`\\.\pipe\clipqueue-%d`, sessionID)
+}
+
+// maxRequestBytes ограничивает длину одной строки запроса.
+const maxRequestBytes = 16 << 20
+
+// Команды протокола.
+const (
+	CmdState     = "state"
+	CmdToggle    = "toggle"
+	CmdPasteNext = "paste-next"
+	CmdPush      = "push"
+	CmdCopy      = "copy"
+	// CmdShowSettings открывает окно настроек; его шлёт повторно запущенный экземпляр.
+	CmdShowSettings = "show-settings"
+)
+
+// Request — одна команда клиента.
+type Request struct {
+	Cmd  string `json:"cmd"`
+	Text string `json:"text,omitempty"`
+}
+
+// QueueState описывает состояние очереди в ответе.
+type QueueState struct {
+	Enabled bool   `json:"enabled"`
+	Count   int    `json:"count"`
+	Order   string `json:"order"`
+}
+
+// Response — ответ на команду. При ошибке OK == false и заполнено Error.
+type Response struct {
+	OK    bool        `json:"ok"`
+	Error string      `json:"error,omitempty"`
+	ID    string      `json:"id,omitempty"`
+	Queue *QueueState `json:"queue,omitempty"`
+}
+
+// Backend выполняет команды протокола. Его реализует контроллер приложения.
+type Backend interface {
+	ToggleQueue()
+	PasteNext()
+	PushText(text string) (string, error)
+	CopyText(text string) error
+	GetQueueState() (enabled bool, count int, order string)
+	ShowSettings() error
+}
+
+// Listener принимает входящие соединения, например экземпляры именованного канала.
+type Listener interface {
+	Accept() (io.ReadWriteCloser, error)
+}
+
+// ServeListener обслуживает соединения до ошибки Accept (обычно — закрытия слушателя).
+func ServeListener(l Listener, b Backend) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		crash.Go("ipc.Serve", func() {
+			defer conn.Close()
+			Serve(conn, b)
+		})
+	}
+}
+
+// Serve читает команды из rw до конца потока и отвечает на каждую.
+func Serve(rw io.ReadWriter, b Backend) error {
+	scanner := bufio.NewScanner(rw)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestBytes)
+	enc := json.NewEncoder(rw)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req Request
+		resp := Response{}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp.Error = fmt.Sprintf("некорректный JSON: %v", err)
+		} else {
+			resp = Handle(req, b)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Handle выполняет одну команду.
+func Handle(req Request, b Backend) Response {
+	var resp Response
+	switch req.Cmd {
+	case CmdState:
+	case CmdToggle:
+		b.ToggleQueue()
+	case CmdPasteNext:
+		b.PasteNext()
+	case CmdPush:
+		id, err := b.PushText(req.Text)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		resp.ID = id
+	case CmdCopy:
+		if err := b.CopyText(req.Text); err != nil {
+			return Response{Error: err.Error()}
+		}
+	case CmdShowSettings:
+		if err := b.ShowSettings(); err != nil {
+			return Response{Error: err.Error()}
+		}
+	default:
+		return Response{Error: fmt.Sprintf("неизвестная команда %q", req.Cmd)}
+	}
+
+	enabled, count, order := b.GetQueueState()
+	resp.OK = true
+	resp.Queue = &QueueState{Enabled: enabled, Count: count, Order: order}
+	return resp
+}
+
+// Call отправляет одну команду и читает ответ на неё.
+func Call(rw io.ReadWriter, req Request) (Response, error) {
+	if err := json.NewEncoder(rw).Encode(req); err != nil {
+		return Response{}, err
+	}
+	line, err := bufio.NewReader(rw).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return Response{}, fmt.Errorf("некорректный ответ: %v", err)
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,126 @@
+package ipc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct {
+	enabled  bool
+	queue    []string
+	settings int
+}
+
+func (f *fakeBackend) ShowSettings() error { f.settings++; return nil }
+
+func (f *fakeBackend) ToggleQueue() { f.enabled = !f.enabled }
+func (f *fakeBackend) PasteNext() {
+	if len(f.queue) > 0 {
+		f.queue = f.queue[1:]
+	}
+}
+func (f *fakeBackend) PushText(text string) (string, error) {
+	if text == "" {
+		return "", errors.New("пустой текст")
+	}
+	f.queue = append(f.queue, text)
+	return "id-" + text, nil
+}
+func (f *fakeBackend) CopyText(text string) error { return nil }
+func (f *fakeBackend) GetQueueState() (bool, int, string) {
+	return f.enabled, len(f.queue), "FIFO"
+}
+
+type pipeStub struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (p *pipeStub) Read(b []byte) (int, error) { return p.Reader.Read(b) }
+
+func serveLines(t *testing.T, b Backend, lines ...string) []Response {
+	t.Helper()
+	conn := &pipeStub{Reader: strings.NewReader(strings.Join(lines, "\n") + "\n")}
+	if err := Serve(conn, b); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var out []Response
+	sc := bufio.NewScanner(&conn.Buffer)
+	for sc.Scan() {
+		var r Response
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("некорректный ответ %q: %v", sc.Text(), err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestServeHandlesCommandSequence(t *testing.T) {
+	b := &fakeBackend{}
+	resps := serveLines(t, b,
+		`{"cmd":"toggle"}`,
+		`{"cmd":"push","text":"a"}`,
+		`{"cmd":"push","text":"b"}`,
+		`{"cmd":"paste-next"}`,
+		`{"cmd":"state"}`,
+	)
+
+	if len(resps) != 5 {
+		t.Fatalf("ожидалось 5 ответов, получено %d", len(resps))
+	}
+	if !resps[0].OK || !resps[0].Queue.Enabled {
+		t.Fatalf("toggle должен включить очередь: %+v", resps[0])
+	}
+	if resps[1].ID != "id-a" {
+		t.Fatalf("push должен вернуть ID, получено %+v", resps[1])
+	}
+	if got := resps[4].Queue.Count; got != 1 {
+		t.Fatalf("после двух push и одной вставки в очереди должен остаться 1 элемент, осталось %d", got)
+	}
+}
+
+func TestServeReportsErrors(t *testing.T) {
+	resps := serveLines(t, &fakeBackend{},
+		`not json`,
+		`{"cmd":"bogus"}`,
+		`{"cmd":"push"}`,
+	)
+
+	for i, r := range resps {
+		if r.OK || r.Error == "" {
+			t.Fatalf("ответ %d должен быть ошибкой: %+v", i, r)
+		}
+	}
+}
+
+func TestPipeNameIncludesSession(t *testing.T) {
+	if got := PipeName(3); got != `\\.\pipe\clipqueue-3` {
+		t.Fatalf("PipeName(3) = %q", got)
+	}
+	if PipeName(1) == PipeName(2) {
+		t.Fatal("у разных сеансов должны быть разные каналы")
+	}
+}
+
+func TestCallReadsResponse(t *testing.T) {
+	b := &fakeBackend{}
+	conn := &pipeStub{Reader: strings.NewReader(`{"ok":true,"queue":{"enabled":false,"count":0,"order":"FIFO"}}` + "\n")}
+
+	resp, err := Call(conn, Request{Cmd: CmdShowSettings})
+	if err != nil || !resp.OK {
+		t.Fatalf("Call: %+v, %v", resp, err)
+	}
+	if got := conn.Buffer.String(); !strings.Contains(got, `"cmd":"show-settings"`) {
+		t.Fatalf("запрос не отправлен: %q", got)
+	}
+
+	if r := Handle(Request{Cmd: CmdShowSettings}, b); !r.OK || b.settings != 1 {
+		t.Fatalf("show-settings должен открыть настройки: %+v", r)
+	}
+}
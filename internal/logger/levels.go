@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Уровни логирования в порядке возрастания важности.
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[string]int{
+	"debug":   LevelDebug,
+	"info":    LevelInfo,
+	"warn":    LevelWarn,
+	"warning": LevelWarn,
+	"error":   LevelError,
+}
+
+var (
+	levelsMu     sync.RWMutex
+	defaultLevel = LevelDebug
+	moduleLevels = map[string]int{}
+)
+
+// ParseLevel переводит имя уровня (debug, info, warn, error) в константу Level*.
+func ParseLevel(name string) (int, error) {
+	level, ok := levelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("неизвестный уровень логирования %q, допустимы debug, info, warn, error", name)
+	}
+	return level, nil
+}
+
+// SetLevels задаёт общий уровень и переопределения для модулей.
+// Ключ модуля совпадает с модулем записи целиком или с его префиксом до точки:
+// "server" действует на "server.server" и "server.native_bridge", а "windows.clipboard" — только на один файл.
+// Пустой level означает debug.
+func SetLevels(level string, modules map[string]string) error {
+	def := LevelDebug
+	if level != "" {
+		var err error
+		if def, err = ParseLevel(level); err != nil {
+			return err
+		}
+	}
+	parsed := make(map[string]int, len(modules))
+	for module, name := range modules {
+		l, err := ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("модуль %s: %w", module, err)
+		}
+		parsed[strings.ToLower(strings.TrimSpace(module))] = l
+	}
+
+	levelsMu.Lock()
+	defaultLevel = def
+	moduleLevels = parsed
+	levelsMu.Unlock()
+	return nil
+}
+
+// enabled сообщает, нужно ли писать запись уровня level из модуля module.
+// Побеждает самое длинное совпадающее переопределение.
+func enabled(level int, module string) bool {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+
+	threshold := defaultLevel
+	if len(moduleLevels) > 0 {
+		key := strings.ToLower(module)
+		for {
+			if l, ok := moduleLevels[key]; ok {
+				threshold = l
+				break
+			}
+			dot := strings.LastIndexByte(key, '.')
+			if dot < 0 {
+				break
+			}
+			key = key[:dot]
+		}
+	}
+	return level >= threshold
+}
@@ -1,22 +1,75 @@
 package logger
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/serty2005/clipqueue/internal/config"
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Fields — структурированные поля записи:
+//
+//	logger.WithFields(logger.Fields{"id": id, "process": name}).Info("Элемент вставлен")
+type Fields map[string]interface{}
+
+// FieldEntry пишет записи с заранее заданными полями.
+type FieldEntry struct {
+	fields Fields
+}
+
+// WithFields возвращает запись с полями, которые попадут в JSON-поле fields
+// (в текстовом формате — пары key=value после сообщения).
+func WithFields(fields Fields) FieldEntry {
+	return FieldEntry{fields: fields}
+}
+
+func (e FieldEntry) Info(format string, v ...interface{}) {
+	output(LevelInfo, "INFO", e.fields, format, v)
+}
+
+func (e FieldEntry) Error(format string, v ...interface{}) {
+	output(LevelError, "ERROR", e.fields, format, v)
+}
+
+func (e FieldEntry) Debug(format string, v ...interface{}) {
+	output(LevelDebug, "DEBUG", e.fields, format, v)
+}
+
+func (e FieldEntry) Warn(format string, v ...interface{}) {
+	output(LevelWarn, "WARN", e.fields, format, v)
+}
+
 var (
 	fileLogger    *log.Logger
 	consoleLogger *log.Logger
-	logFile       *os.File
+	logFile       *rotatingFile
 	initOnce      sync.Once
+	format        = FormatText
 )
 
+// entry — запись лога в формате JSON.
+type entry struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Module    string `json:"module"`
+	Message   string `json:"message"`
+	Fields    Fields `json:"fields,omitempty"`
+}
+
 func Init(cfg *config.Config) error {
 	var err error
 
@@ -26,6 +79,18 @@ func Init(cfg *config.Config) error {
 			return
 		}
 
+		if err = SetLevels(cfg.Logging.Level, cfg.Logging.Modules); err != nil {
+			return
+		}
+		if cfg.Logging.Format == FormatJSON {
+			format = FormatJSON
+		}
+		flags := log.LstdFlags
+		if format == FormatJSON {
+			// Время пишется полем timestamp, префикс log.Logger сломал бы JSON.
+			flags = 0
+		}
+
 		if cfg.App.Logs {
 			logDir := filepath.Join(config.ResolvePath(cfg.App.DataDir), "logs")
 			if err = os.MkdirAll(logDir, 0755); err != nil {
@@ -33,18 +98,24 @@ func Init(cfg *config.Config) error {
 			}
 
 			logPath := filepath.Join(logDir, "app.log")
-			logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			logFile, err = openRotatingFile(
+				logPath,
+				int64(cfg.Logging.MaxSizeMB)*1024*1024,
+				cfg.Logging.MaxFiles,
+				time.Duration(cfg.Logging.MaxAgeDays)*24*time.Hour,
+				cfg.Logging.Compress,
+			)
 			if err != nil {
 				return
 			}
 
-			fileLogger = log.New(logFile, "", log.LstdFlags)
+			fileLogger = log.New(logFile, "", flags)
 		}
 
 		if cfg.App.Silent {
-			consoleLogger = log.New(io.Discard, "", log.LstdFlags)
+			consoleLogger = log.New(io.Discard, "", flags)
 		} else {
-			consoleLogger = log.New(os.Stdout, "", log.LstdFlags)
+			consoleLogger = log.New(os.Stdout, "", flags)
 		}
 	})
 
@@ -58,37 +129,92 @@ func Close() {
 }
 
 func Info(format string, v ...interface{}) {
-	if consoleLogger != nil {
-		consoleLogger.Printf("INFO: "+format, v...)
-	}
-	if fileLogger != nil {
-		fileLogger.Printf("INFO: "+format, v...)
-	}
+	output(LevelInfo, "INFO", nil, format, v)
 }
 
 func Error(format string, v ...interface{}) {
+	output(LevelError, "ERROR", nil, format, v)
+}
+
+func Debug(format string, v ...interface{}) {
+	output(LevelDebug, "DEBUG", nil, format, v)
+}
+
+func Warn(format string, v ...interface{}) {
+	output(LevelWarn, "WARN", nil, format, v)
+}
+
+func output(severity int, level string, fields Fields, msgFormat string, v []interface{}) {
+	if consoleLogger == nil && fileLogger == nil {
+		return
+	}
+
+	// output вызывается из Info/Debug/Warn/Error, поэтому модуль берём двумя кадрами выше.
+	module := callerModule(3)
+	if !enabled(severity, module) {
+		return
+	}
+	now := time.Now()
+	line := formatLine(level, module, fmt.Sprintf(msgFormat, v...), fields, now)
+	remember(line, now)
+
 	if consoleLogger != nil {
-		consoleLogger.Printf("ERROR: "+format, v...)
+		consoleLogger.Print(line)
 	}
 	if fileLogger != nil {
-		fileLogger.Printf("ERROR: "+format, v...)
+		fileLogger.Print(line)
 	}
 }
 
-func Debug(format string, v ...interface{}) {
-	if consoleLogger != nil {
-		consoleLogger.Printf("DEBUG: "+format, v...)
+func formatLine(level, module, message string, fields Fields, ts time.Time) string {
+	if format == FormatJSON {
+		data, err := json.Marshal(entry{
+			Timestamp: ts.Format(time.RFC3339Nano),
+			Level:     strings.ToLower(level),
+			Module:    module,
+			Message:   message,
+			Fields:    fields,
+		})
+		if err == nil {
+			return string(data)
+		}
+		// Поля с несериализуемыми значениями не должны терять саму запись.
+		data, _ = json.Marshal(entry{
+			Timestamp: ts.Format(time.RFC3339Nano),
+			Level:     strings.ToLower(level),
+			Module:    module,
+			Message:   message,
+			Fields:    Fields{"fieldsError": err.Error()},
+		})
+		return string(data)
 	}
-	if fileLogger != nil {
-		fileLogger.Printf("DEBUG: "+format, v...)
+
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteString(": ")
+	b.WriteString(message)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
 	}
+	return b.String()
 }
 
-func Warn(format string, v ...interface{}) {
-	if consoleLogger != nil {
-		consoleLogger.Printf("WARN: "+format, v...)
+// callerModule возвращает модуль вызывающего кода в виде "<пакет>.<файл>",
+// например "windows.clipboard" для platform/windows/clipboard.go.
+func callerModule(skip int) string {
+	_, file, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
 	}
-	if fileLogger != nil {
-		fileLogger.Printf("WARN: "+format, v...)
+	pkg := filepath.Base(filepath.Dir(file))
+	name := strings.TrimSuffix(filepath.Base(file), ".go")
+	if pkg == "." || pkg == "/" {
+		return name
 	}
+	return pkg + "." + name
 }
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatLineJSON(t *testing.T) {
+	format = FormatJSON
+	defer func() { format = FormatText }()
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	line := formatLine("WARN", "windows.clipboard", "буфер занят", Fields{"attempt": 3}, ts)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("строка не является JSON: %v (%s)", err, line)
+	}
+	if got["timestamp"] != "2026-01-02T03:04:05Z" || got["level"] != "warn" ||
+		got["module"] != "windows.clipboard" || got["message"] != "буфер занят" {
+		t.Fatalf("неожиданная запись: %v", got)
+	}
+	if fields, ok := got["fields"].(map[string]interface{}); !ok || fields["attempt"] != float64(3) {
+		t.Fatalf("неожиданные поля: %v", got["fields"])
+	}
+}
+
+func TestFormatLineTextKeepsLegacyLayout(t *testing.T) {
+	line := formatLine("INFO", "app.controller", "Queue cleared", nil, time.Now())
+	if line != "INFO: Queue cleared" {
+		t.Fatalf("неожиданная строка: %q", line)
+	}
+
+	line = formatLine("INFO", "app.controller", "вставка", Fields{"b": 2, "a": 1}, time.Now())
+	if line != "INFO: вставка a=1 b=2" {
+		t.Fatalf("поля должны идти после сообщения в порядке ключей: %q", line)
+	}
+}
+
+func TestCallerModule(t *testing.T) {
+	if got := callerModule(1); got != "logger.logger_test" {
+		t.Fatalf("неожиданный модуль: %q", got)
+	}
+}
+
+func TestModuleLevelOverrides(t *testing.T) {
+	if err := SetLevels("info", map[string]string{"windows.clipboard": "debug", "server": "warn"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLevels("", nil)
+
+	cases := []struct {
+		level  int
+		module string
+		want   bool
+	}{
+		{LevelDebug, "app.controller", false},
+		{LevelInfo, "app.controller", true},
+		{LevelDebug, "windows.clipboard", true},
+		{LevelDebug, "windows.clipboard_watcher", false},
+		{LevelInfo, "server.server", false},
+		{LevelWarn, "server.native_bridge", true},
+	}
+	for _, c := range cases {
+		if got := enabled(c.level, c.module); got != c.want {
+			t.Errorf("enabled(%d, %q) = %v, ожидалось %v", c.level, c.module, got, c.want)
+		}
+	}
+}
+
+func TestSetLevelsRejectsUnknownLevel(t *testing.T) {
+	if err := SetLevels("verbose", nil); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного уровня")
+	}
+	if err := SetLevels("info", map[string]string{"server": "loud"}); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного уровня модуля")
+	}
+}
+
+func TestRecentKeepsLastLines(t *testing.T) {
+	ts := time.Now()
+	for i := 0; i < recentLimit+5; i++ {
+		remember(fmt.Sprintf("line %d", i), ts)
+	}
+
+	got := Recent(3)
+	if len(got) != 3 {
+		t.Fatalf("ожидалось 3 строки, получено %d", len(got))
+	}
+	for i, want := range []string{"line 202", "line 203", "line 204"} {
+		if !strings.HasSuffix(got[i], want) {
+			t.Fatalf("строка %d = %q, ожидалось окончание %q", i, got[i], want)
+		}
+	}
+	if all := Recent(0); len(all) != recentLimit {
+		t.Fatalf("Recent(0) должен вернуть весь буфер, получено %d", len(all))
+	}
+}
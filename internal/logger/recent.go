@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// recentLimit — сколько последних записей хранится в памяти для отчётов о сбоях.
+const recentLimit = 200
+
+// recent — кольцевой буфер последних записей лога независимо от того,
+// включена ли запись в файл.
+var recent = struct {
+	mu    sync.Mutex
+	lines [recentLimit]string
+	next  int
+	count int
+}{}
+
+func remember(line string, ts time.Time) {
+	if format != FormatJSON {
+		// В JSON время уже есть в поле timestamp.
+		line = ts.Format("2006/01/02 15:04:05.000 ") + line
+	}
+	recent.mu.Lock()
+	recent.lines[recent.next] = line
+	recent.next = (recent.next + 1) % recentLimit
+	if recent.count < recentLimit {
+		recent.count++
+	}
+	recent.mu.Unlock()
+}
+
+// Recent возвращает до n последних записей лога от старых к новым.
+func Recent(n int) []string {
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+
+	if n <= 0 || n > recent.count {
+		n = recent.count
+	}
+	out := make([]string, 0, n)
+	start := (recent.next - n + recentLimit) % recentLimit
+	for i := 0; i < n; i++ {
+		out = append(out, recent.lines[(start+i)%recentLimit])
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatingFile пишет лог в файл и ротирует его при превышении maxSize:
+// текущий файл переименовывается в <name>-<время>.log, при необходимости сжимается в .gz,
+// а архивы сверх maxFiles или старше maxAge удаляются.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	maxAge   time.Duration
+	compress bool
+	file     *os.File
+	size     int64
+	wg       sync.WaitGroup // Фоновое сжатие и очистка архивов
+	now      func() time.Time
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int, maxAge time.Duration, compress bool) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		maxAge:   maxAge,
+		compress: compress,
+		now:      time.Now,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	// Убираем архивы, устаревшие за время, пока приложение не работало.
+	rf.cleanup()
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotateLocked(); err != nil {
+			// Продолжаем писать в текущий файл: потеря ротации лучше потери логов.
+			fmt.Fprintf(os.Stderr, "ротация лога не удалась: %v\n", err)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotateLocked() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	archive := rf.archiveName(rf.now())
+	renameErr := os.Rename(rf.path, archive)
+	if err := rf.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	rf.wg.Add(1)
+	go func() {
+		defer rf.wg.Done()
+		if rf.compress {
+			if err := gzipFile(archive); err != nil {
+				fmt.Fprintf(os.Stderr, "сжатие лога %s не удалось: %v\n", archive, err)
+			}
+		}
+		rf.cleanup()
+	}()
+	return nil
+}
+
+// archiveName возвращает уникальное имя архива вида app-20060102-150405.000.log.
+func (rf *rotatingFile) archiveName(t time.Time) string {
+	ext := filepath.Ext(rf.path)
+	base := strings.TrimSuffix(rf.path, ext)
+	name := fmt.Sprintf("%s-%s%s", base, t.Format("20060102-150405.000"), ext)
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%s-%d%s", base, t.Format("20060102-150405.000"), i, ext)
+	}
+	return name
+}
+
+// archives возвращает архивы текущего лога от новых к старым.
+func (rf *rotatingFile) archives() []string {
+	ext := filepath.Ext(rf.path)
+	pattern := strings.TrimSuffix(rf.path, ext) + "-*" + ext
+	plain, _ := filepath.Glob(pattern)
+	gz, _ := filepath.Glob(pattern + ".gz")
+	all := append(plain, gz...)
+	// Имена содержат метку времени, поэтому лексикографический порядок совпадает с хронологическим.
+	sort.Sort(sort.Reverse(sort.StringSlice(all)))
+	return all
+}
+
+func (rf *rotatingFile) cleanup() {
+	now := rf.now()
+	for i, name := range rf.archives() {
+		expired := false
+		if rf.maxAge > 0 {
+			if info, err := os.Stat(name); err == nil && now.Sub(info.ModTime()) > rf.maxAge {
+				expired = true
+			}
+		}
+		if (rf.maxFiles > 0 && i >= rf.maxFiles) || expired {
+			os.Remove(name)
+		}
+	}
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.mu.Unlock()
+	rf.wg.Wait()
+	return err
+}
+
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(name)
+	zw.ModTime = info.ModTime()
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	// Сохраняем время архива, чтобы maxAge отсчитывался от момента ротации.
+	os.Chtimes(name+".gz", info.ModTime(), info.ModTime())
+	src.Close()
+	return os.Remove(name)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileRotatesAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rf, err := openRotatingFile(path, 100, 5, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	if len(archives) != 2 {
+		t.Fatalf("ожидалось 2 сжатых архива, получено %v", archives)
+	}
+	if plain, _ := filepath.Glob(filepath.Join(dir, "app-*.log")); len(plain) != 0 {
+		t.Fatalf("несжатые архивы должны удаляться после gzip: %v", plain)
+	}
+
+	f, err := os.Open(archives[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != line {
+		t.Fatalf("неожиданное содержимое архива: %q", data)
+	}
+
+	current, _ := os.ReadFile(path)
+	if string(current) != line {
+		t.Fatalf("текущий файл должен содержать только последнюю запись, получено %q", current)
+	}
+}
+
+func TestRotatingFileKeepsMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rf, err := openRotatingFile(path, 10, 2, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		if _, err := rf.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rf.Close()
+
+	if archives := rf.archives(); len(archives) != 2 {
+		t.Fatalf("ожидалось 2 архива, получено %v", archives)
+	}
+}
+
+func TestRotatingFileRemovesExpiredArchives(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	old := filepath.Join(dir, "app-20200101-000000.000.log.gz")
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	rf, err := openRotatingFile(path, 0, 0, 24*time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	if fileExists(old) {
+		t.Fatal("архив старше maxAge должен удаляться при открытии")
+	}
+}
@@ -0,0 +1,286 @@
+// Package mqtt — минимальный клиент MQTT 3.1.1 для публикации событий ClipQueue
+// и подписки на команды: QoS 0 на отправку, приём сообщений QoS 0 и 1, TCP или TLS.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/crash"
+)
+
+const (
+	dialTimeout      = 10 * time.Second
+	defaultKeepAlive = 60 * time.Second
+	ackTimeout       = 10 * time.Second
+)
+
+// Коды отказа CONNACK.
+var connackErrors = map[byte]string{
+	1: "неподдерживаемая версия протокола",
+	2: "идентификатор клиента отклонён",
+	3: "сервер недоступен",
+	4: "неверное имя пользователя или пароль",
+	5: "нет прав на подключение",
+}
+
+// ErrClosed возвращается операциями после закрытия соединения.
+var ErrClosed = errors.New("соединение MQTT закрыто")
+
+// Options — параметры подключения.
+type Options struct {
+	// Broker — адрес брокера: tcp://host:1883 или tls://host:8883 (также mqtt:// и mqtts://).
+	Broker    string
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	// OnMessage получает сообщения из подписок; вызывается в горутине чтения.
+	OnMessage func(topic string, payload []byte)
+}
+
+// ParseBroker проверяет адрес брокера и возвращает host:port и признак TLS.
+func ParseBroker(broker string) (addr string, useTLS bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, err
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("неизвестная схема %q, допустимы tcp:// и tls://", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", false, errors.New("не указан хост брокера")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// Client — подключение к брокеру.
+type Client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	onMessage func(topic string, payload []byte)
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint16
+	pending map[uint16]chan byte
+	err     error
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Connect подключается к брокеру и ждёт подтверждения CONNACK.
+func Connect(opts Options) (*Client, error) {
+	addr, useTLS, err := ParseBroker(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive := opts.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultKeepAlive
+	}
+	c := &Client{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		onMessage: opts.OnMessage,
+		pending:   make(map[uint16]chan byte),
+		done:      make(chan struct{}),
+	}
+	if c.onMessage == nil {
+		c.onMessage = func(string, []byte) {}
+	}
+
+	conn.SetDeadline(time.Now().Add(ackTimeout))
+	if err := writePacket(conn, packetConnect, 0, connectBody(opts, uint16(keepAlive/time.Second))); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ack, err := readPacket(c.reader)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("нет ответа на CONNECT: %w", err)
+	}
+	if ack.kind != packetConnack || len(ack.body) < 2 {
+		conn.Close()
+		return nil, errors.New("брокер ответил не CONNACK")
+	}
+	if code := ack.body[1]; code != 0 {
+		conn.Close()
+		if msg, ok := connackErrors[code]; ok {
+			return nil, fmt.Errorf("брокер отклонил подключение: %s", msg)
+		}
+		return nil, fmt.Errorf("брокер отклонил подключение: код %d", code)
+	}
+	conn.SetDeadline(time.Time{})
+
+	crash.Go("mqtt.read", c.readLoop)
+	crash.Go("mqtt.ping", func() { c.pingLoop(keepAlive) })
+	return c, nil
+}
+
+// Done закрывается при потере или закрытии соединения.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err возвращает причину разрыва соединения.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Publish отправляет сообщение с QoS 0.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var flags byte
+	if retain {
+		flags = 0x01
+	}
+	return c.write(packetPublish, flags, publishBody(topic, payload))
+}
+
+// Subscribe подписывается на фильтр темы с QoS 0 и ждёт SUBACK.
+func (c *Client) Subscribe(filter string) error {
+	ack := make(chan byte, 1)
+	c.mu.Lock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	c.pending[id] = ack
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	body = appendString(body, filter)
+	body = append(body, 0) // QoS 0
+	if err := c.write(packetSubscribe, 0x02, body); err != nil {
+		return err
+	}
+	select {
+	case code := <-ack:
+		if code == 0x80 {
+			return fmt.Errorf("брокер отклонил подписку на %q", filter)
+		}
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-time.After(ackTimeout):
+		return fmt.Errorf("нет ответа на подписку %q", filter)
+	}
+}
+
+// Close отправляет DISCONNECT и закрывает соединение.
+func (c *Client) Close() error {
+	c.write(packetDisconnect, 0, nil)
+	c.fail(ErrClosed)
+	return nil
+}
+
+func (c *Client) write(kind, flags byte, body []byte) error {
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(ackTimeout))
+	if err := writePacket(c.conn, kind, flags, body); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) fail(err error) {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *Client) readLoop() {
+	for {
+		p, err := readPacket(c.reader)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		switch p.kind {
+		case packetPublish:
+			topic, payload, id, err := parsePublish(p)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			if id != 0 {
+				c.write(packetPuback, 0, binary.BigEndian.AppendUint16(nil, id))
+			}
+			c.onMessage(topic, payload)
+		case packetSuback:
+			if len(p.body) < 3 {
+				continue
+			}
+			id := binary.BigEndian.Uint16(p.body)
+			c.mu.Lock()
+			ack := c.pending[id]
+			c.mu.Unlock()
+			if ack != nil {
+				ack <- p.body[2]
+			}
+		case packetPingresp:
+		}
+	}
+}
+
+// pingLoop отправляет PINGREQ, чтобы брокер не разорвал простаивающее соединение.
+func (c *Client) pingLoop(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(packetPingreq, 0, nil); err != nil {
+				return
+			}
+		}
+	}
+}
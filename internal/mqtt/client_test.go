@@ -0,0 +1,117 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeBroker принимает одно подключение, подтверждает CONNECT и SUBSCRIBE,
+// отправляет сообщение QoS 1 в подписанную тему и пересылает PUBLISH клиента в published.
+func fakeBroker(t *testing.T, published chan<- packet) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			p, err := readPacket(r)
+			if err != nil {
+				return
+			}
+			switch p.kind {
+			case packetConnect:
+				writePacket(conn, packetConnack, 0, []byte{0, 0})
+			case packetSubscribe:
+				id := binary.BigEndian.Uint16(p.body)
+				filter, _, _ := readString(p.body[2:])
+				writePacket(conn, packetSuback, 0, append(binary.BigEndian.AppendUint16(nil, id), 0))
+				body := appendString(nil, filter)
+				body = binary.BigEndian.AppendUint16(body, 7)
+				writePacket(conn, packetPublish, 0x02, append(body, "hello"...))
+			case packetPublish, packetPuback:
+				published <- p
+			}
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestClientPublishAndSubscribe(t *testing.T) {
+	published := make(chan packet, 4)
+	messages := make(chan string, 1)
+	c, err := Connect(Options{
+		Broker:    fakeBroker(t, published),
+		ClientID:  "test",
+		OnMessage: func(topic string, payload []byte) { messages <- topic + "=" + string(payload) },
+	})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Subscribe("clipqueue/push"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	select {
+	case msg := <-messages:
+		if msg != "clipqueue/push=hello" {
+			t.Fatalf("получено %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("сообщение из подписки не получено")
+	}
+
+	if err := c.Publish("clipqueue/paste", []byte(`{"x":1}`), false); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	for {
+		select {
+		case p := <-published:
+			if p.kind == packetPuback {
+				if binary.BigEndian.Uint16(p.body) != 7 {
+					t.Fatalf("PUBACK с идентификатором %d", binary.BigEndian.Uint16(p.body))
+				}
+				continue
+			}
+			topic, payload, _, err := parsePublish(p)
+			if err != nil || topic != "clipqueue/paste" || string(payload) != `{"x":1}` {
+				t.Fatalf("опубликовано %q %q, %v", topic, payload, err)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("PUBLISH не дошёл до брокера")
+		}
+	}
+}
+
+func TestParseBroker(t *testing.T) {
+	cases := map[string]struct {
+		addr string
+		tls  bool
+	}{
+		"tcp://broker.local":   {"broker.local:1883", false},
+		"mqtts://broker.local": {"broker.local:8883", true},
+		"tls://10.0.0.5:9000":  {"10.0.0.5:9000", true},
+		"mqtt://[::1]:1884":    {"[::1]:1884", false},
+	}
+	for in, want := range cases {
+		addr, useTLS, err := ParseBroker(in)
+		if err != nil || addr != want.addr || useTLS != want.tls {
+			t.Errorf("ParseBroker(%q) = %q, %v, %v", in, addr, useTLS, err)
+		}
+	}
+	if _, _, err := ParseBroker("http://broker.local"); err == nil {
+		t.Error("схема http принята")
+	}
+}
@@ -0,0 +1,145 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Типы пакетов MQTT 3.1.1.
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetSubscribe  = 8
+	packetSuback     = 9
+	packetPingreq    = 12
+	packetPingresp   = 13
+	packetDisconnect = 14
+)
+
+// maxPacketBytes ограничивает входящий пакет: больше ClipQueue не принимает.
+const maxPacketBytes = 16 << 20
+
+// packet — разобранный пакет: тип, флаги фиксированного заголовка и тело.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendRemainingLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func writePacket(w io.Writer, kind, flags byte, body []byte) error {
+	buf := make([]byte, 0, len(body)+5)
+	buf = append(buf, kind<<4|flags)
+	buf = appendRemainingLength(buf, len(body))
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	var p packet
+	header, err := r.ReadByte()
+	if err != nil {
+		return p, err
+	}
+	p.kind, p.flags = header>>4, header&0x0f
+
+	n, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return p, errors.New("неверная длина пакета MQTT")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return p, err
+		}
+		n += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if n > maxPacketBytes {
+		return p, fmt.Errorf("пакет MQTT %d байт больше допустимых %d", n, maxPacketBytes)
+	}
+	p.body = make([]byte, n)
+	_, err = io.ReadFull(r, p.body)
+	return p, err
+}
+
+// readString читает строку с 2-байтовой длиной и возвращает остаток.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("обрезанная строка MQTT")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("обрезанная строка MQTT")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+func connectBody(opts Options, keepAlive uint16) []byte {
+	var flags byte = 0x02 // Чистый сеанс: подписки восстанавливаются при каждом подключении
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+	b := appendString(nil, "MQTT")
+	b = append(b, 4, flags) // Уровень протокола 4 — MQTT 3.1.1
+	b = binary.BigEndian.AppendUint16(b, keepAlive)
+	b = appendString(b, opts.ClientID)
+	if opts.Username != "" {
+		b = appendString(b, opts.Username)
+		if opts.Password != "" {
+			b = appendString(b, opts.Password)
+		}
+	}
+	return b
+}
+
+// publishBody собирает PUBLISH с QoS 0: без идентификатора пакета.
+func publishBody(topic string, payload []byte) []byte {
+	b := appendString(make([]byte, 0, len(topic)+2+len(payload)), topic)
+	return append(b, payload...)
+}
+
+// parsePublish разбирает входящий PUBLISH. Для QoS 1 возвращается идентификатор для PUBACK.
+func parsePublish(p packet) (topic string, payload []byte, id uint16, err error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	if qos := (p.flags >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, 0, errors.New("PUBLISH без идентификатора пакета")
+		}
+		id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	return topic, rest, id, nil
+}
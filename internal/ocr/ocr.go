@@ -0,0 +1,213 @@
+// Package ocr распознаёт текст на изображениях: встроенным движком Windows
+// (Windows.Media.Ocr через WinRT из PowerShell) или программой tesseract.
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/shell"
+)
+
+// Engine — движок распознавания.
+type Engine string
+
+const (
+	Auto      Engine = "auto" // Windows OCR, при ошибке — tesseract, если он установлен
+	Windows   Engine = "windows"
+	Tesseract Engine = "tesseract"
+)
+
+// DefaultTimeout ограничивает распознавание одного изображения.
+const DefaultTimeout = 30 * time.Second
+
+// fileEnv передаёт путь к изображению скрипту PowerShell, чтобы не экранировать его в команде.
+const fileEnv = "CLIPQUEUE_OCR_FILE"
+
+// langEnv передаёт скрипту язык распознавания.
+const langEnv = "CLIPQUEUE_OCR_LANG"
+
+var (
+	// ErrNoText возвращается, если на изображении не найден текст.
+	ErrNoText = errors.New("текст на изображении не распознан")
+	// ErrUnavailable возвращается, если выбранный движок недоступен на этом компьютере.
+	ErrUnavailable = errors.New("движок OCR недоступен")
+)
+
+// Options — параметры распознавания.
+type Options struct {
+	Engine        Engine
+	Language      string // Язык Windows OCR в формате BCP-47 (ru-RU); пустой — языки профиля пользователя
+	TesseractPath string // Пустой — tesseract из PATH или из стандартного каталога установки
+	TesseractLang string // Языки tesseract (-l), например rus+eng; пустой — по умолчанию tesseract
+	Timeout       time.Duration
+}
+
+// ParseEngine проверяет название движка; пустое значение означает auto.
+func ParseEngine(s string) (Engine, error) {
+	switch e := Engine(strings.ToLower(s)); e {
+	case "":
+		return Auto, nil
+	case Auto, Windows, Tesseract:
+		return e, nil
+	}
+	return "", fmt.Errorf("неизвестный движок OCR %q: допустимы auto, windows и tesseract", s)
+}
+
+// Recognize распознаёт текст на изображении PNG и возвращает его построчно
+// с переводами строк \r\n. Возвращает движок, который дал результат.
+func Recognize(ctx context.Context, png []byte, opts Options) (string, Engine, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	dir, err := os.MkdirTemp("", "clipqueue-ocr-")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(file, png, 0600); err != nil {
+		return "", "", err
+	}
+
+	engine, err := ParseEngine(string(opts.Engine))
+	if err != nil {
+		return "", "", err
+	}
+	switch engine {
+	case Windows:
+		text, err := recognizeWindows(ctx, file, opts)
+		return text, Windows, err
+	case Tesseract:
+		text, err := recognizeTesseract(ctx, file, opts)
+		return text, Tesseract, err
+	}
+
+	if runtime.GOOS == "windows" {
+		text, winErr := recognizeWindows(ctx, file, opts)
+		if winErr == nil || errors.Is(winErr, ErrNoText) {
+			return text, Windows, winErr
+		}
+		if _, err := tesseractPath(opts.TesseractPath); err != nil {
+			return "", Windows, winErr
+		}
+	}
+	text, err := recognizeTesseract(ctx, file, opts)
+	return text, Tesseract, err
+}
+
+// windowsScript читает изображение через WinRT и печатает строки результата.
+// Нужен Windows PowerShell 5.1: pwsh не загружает типы WinRT таким способом.
+const windowsScript = `$ErrorActionPreference = 'Stop'
+Add-Type -AssemblyName System.Runtime.WindowsRuntime
+$asTask = ([System.WindowsRuntimeSystemExtensions].GetMethods() | Where-Object { $_.Name -eq 'AsTask' -and $_.GetParameters().Count -eq 1 -and $_.GetParameters()[0].ParameterType.Name -eq 'IAsyncOperation` + "`" + `1' })[0]
+function Await($op, [Type]$type) { $task = $asTask.MakeGenericMethod($type).Invoke($null, @($op)); $task.Wait(-1) | Out-Null; $task.Result }
+$null = [Windows.Storage.StorageFile, Windows.Storage, ContentType = WindowsRuntime]
+$null = [Windows.Graphics.Imaging.BitmapDecoder, Windows.Graphics, ContentType = WindowsRuntime]
+$null = [Windows.Media.Ocr.OcrEngine, Windows.Foundation, ContentType = WindowsRuntime]
+$null = [Windows.Globalization.Language, Windows.Globalization, ContentType = WindowsRuntime]
+$file = Await ([Windows.Storage.StorageFile]::GetFileFromPathAsync($env:` + fileEnv + `)) ([Windows.Storage.StorageFile])
+$stream = Await ($file.OpenAsync([Windows.Storage.FileAccessMode]::Read)) ([Windows.Storage.Streams.IRandomAccessStream])
+$decoder = Await ([Windows.Graphics.Imaging.BitmapDecoder]::CreateAsync($stream)) ([Windows.Graphics.Imaging.BitmapDecoder])
+$bitmap = Await ($decoder.GetSoftwareBitmapAsync()) ([Windows.Graphics.Imaging.SoftwareBitmap])
+if ($env:` + langEnv + `) { $engine = [Windows.Media.Ocr.OcrEngine]::TryCreateFromLanguage([Windows.Globalization.Language]::new($env:` + langEnv + `)) } else { $engine = [Windows.Media.Ocr.OcrEngine]::TryCreateFromUserProfileLanguages() }
+if ($null -eq $engine) { [Console]::Error.WriteLine('no-engine'); exit 3 }
+$result = Await ($engine.RecognizeAsync($bitmap)) ([Windows.Media.Ocr.OcrResult])
+$stream.Dispose()
+foreach ($line in $result.Lines) { [Console]::Out.WriteLine($line.Text) }`
+
+func recognizeWindows(ctx context.Context, file string, opts Options) (string, error) {
+	if runtime.GOOS != "windows" {
+		return "", fmt.Errorf("%w: Windows OCR есть только в Windows", ErrUnavailable)
+	}
+	env := append(shell.SandboxEnv(), fileEnv+"="+file, langEnv+"="+opts.Language)
+	res, err := shell.Run(ctx, windowsScript, shell.Options{Dialect: shell.PowerShell, Timeout: opts.Timeout, Env: env})
+	if err != nil {
+		return "", err
+	}
+	if err := resultError("Windows OCR", res, opts.Timeout); err != nil {
+		if strings.Contains(res.Stderr, "no-engine") {
+			return "", fmt.Errorf("%w: в Windows не установлен язык OCR %q", ErrUnavailable, opts.Language)
+		}
+		return "", err
+	}
+	return normalize(res.Stdout)
+}
+
+func recognizeTesseract(ctx context.Context, file string, opts Options) (string, error) {
+	path, err := tesseractPath(opts.TesseractPath)
+	if err != nil {
+		return "", err
+	}
+	command := `"` + path + `" "` + file + `" stdout`
+	if opts.TesseractLang != "" {
+		command += ` -l "` + opts.TesseractLang + `"`
+	}
+	res, err := shell.Run(ctx, command, shell.Options{Dialect: shell.Cmd, Timeout: opts.Timeout})
+	if err != nil {
+		return "", err
+	}
+	if err := resultError("tesseract", res, opts.Timeout); err != nil {
+		return "", err
+	}
+	return normalize(res.Stdout)
+}
+
+// tesseractPath находит исполняемый файл tesseract.
+func tesseractPath(configured string) (string, error) {
+	if configured != "" {
+		if _, err := os.Stat(configured); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return configured, nil
+	}
+	if path, err := exec.LookPath("tesseract"); err == nil {
+		return path, nil
+	}
+	if runtime.GOOS == "windows" {
+		for _, dir := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)")} {
+			path := filepath.Join(dir, "Tesseract-OCR", "tesseract.exe")
+			if dir != "" {
+				if _, err := os.Stat(path); err == nil {
+					return path, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: tesseract не найден в PATH, укажите ocr.tesseract_path", ErrUnavailable)
+}
+
+func resultError(engine string, res shell.Result, timeout time.Duration) error {
+	if res.TimedOut {
+		return fmt.Errorf("%s не завершился за %s", engine, timeout)
+	}
+	if res.ExitCode != 0 {
+		stderr := strings.TrimSpace(res.Stderr)
+		if len(stderr) > 512 {
+			stderr = strings.ToValidUTF8(stderr[:512], "") + "…"
+		}
+		return fmt.Errorf("%s завершился с кодом %d: %s", engine, res.ExitCode, stderr)
+	}
+	return nil
+}
+
+// normalize убирает пустые строки по краям и приводит переводы строк к \r\n,
+// как у текста, скопированного в Windows.
+func normalize(out string) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\f")
+	}
+	text := strings.Trim(strings.Join(lines, "\r\n"), "\r\n")
+	if strings.TrimSpace(text) == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
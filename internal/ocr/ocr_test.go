@@ -0,0 +1,76 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeTesseract создаёт сценарий, который печатает out вместо распознанного текста,
+// а аргументы командной строки записывает в args.txt рядом с собой.
+func fakeTesseract(t *testing.T, out string) (path, argsFile string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("сценарий-заглушка tesseract написан для sh")
+	}
+	dir := t.TempDir()
+	path = filepath.Join(dir, "tesseract")
+	argsFile = filepath.Join(dir, "args.txt")
+	script := "#!/bin/sh\necho \"$2 $3 $4\" > '" + argsFile + "'\nprintf '" + out + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, argsFile
+}
+
+func TestRecognizeTesseract(t *testing.T) {
+	path, argsFile := fakeTesseract(t, "  Hello  \\n\\nworld\\n\\f\\n")
+	text, engine, err := Recognize(context.Background(), []byte("png"), Options{Engine: Auto, TesseractPath: path, TesseractLang: "rus+eng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine != Tesseract {
+		t.Fatalf("движок %q, ожидался tesseract", engine)
+	}
+	if want := "  Hello\r\n\r\nworld"; text != want {
+		t.Fatalf("текст %q, ожидалось %q", text, want)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if want := "stdout -l rus+eng\n"; string(args) != want {
+		t.Fatalf("аргументы %q, ожидалось %q", args, want)
+	}
+}
+
+func TestRecognizeNoText(t *testing.T) {
+	path, _ := fakeTesseract(t, " \\n\\f")
+	if _, _, err := Recognize(context.Background(), []byte("png"), Options{Engine: Tesseract, TesseractPath: path}); !errors.Is(err, ErrNoText) {
+		t.Fatalf("ошибка %v, ожидалась ErrNoText", err)
+	}
+}
+
+func TestRecognizeUnavailable(t *testing.T) {
+	_, _, err := Recognize(context.Background(), []byte("png"), Options{Engine: Tesseract, TesseractPath: filepath.Join(t.TempDir(), "missing")})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("ошибка %v, ожидалась ErrUnavailable", err)
+	}
+	if runtime.GOOS != "windows" {
+		if _, _, err := Recognize(context.Background(), []byte("png"), Options{Engine: Windows}); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("ошибка %v, ожидалась ErrUnavailable", err)
+		}
+	}
+}
+
+func TestParseEngine(t *testing.T) {
+	if e, err := ParseEngine(""); err != nil || e != Auto {
+		t.Fatalf("ParseEngine(\"\") = %q, %v", e, err)
+	}
+	if e, err := ParseEngine("Tesseract"); err != nil || e != Tesseract {
+		t.Fatalf("ParseEngine(Tesseract) = %q, %v", e, err)
+	}
+	if _, err := ParseEngine("abbyy"); err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+}
@@ -0,0 +1,107 @@
+// Package pagetitle получает заголовок (<title>) веб-страницы по ссылке, чтобы
+// история показывала «GitHub - clipQueue» вместо голой ссылки.
+package pagetitle
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+
+	"github.com/serty2005/clipqueue/internal/version"
+)
+
+// maxHeadBytes ограничивает прочитанное начало страницы: <title> стоит в <head>,
+// а тело страницы бывает многомегабайтным.
+const maxHeadBytes = 256 << 10
+
+// maxTitleRunes — длиннее заголовок обрезается: это имя элемента, а не описание.
+const maxTitleRunes = 200
+
+var (
+	singleURL    = regexp.MustCompile(`^https?://\S+$`)
+	titleTag     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaCharset  = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([\w-]+)`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// SingleURL возвращает ссылку, если весь текст (без пробелов по краям) —
+// одна ссылка http или https, иначе пустую строку.
+func SingleURL(text string) string {
+	text = strings.TrimSpace(text)
+	if !singleURL.MatchString(text) {
+		return ""
+	}
+	if u, err := url.Parse(text); err != nil || u.Host == "" {
+		return ""
+	}
+	return text
+}
+
+// Fetch загружает начало страницы link и возвращает её заголовок без лишних
+// пробелов и HTML-сущностей. Ответ не HTML или страница без <title> — ошибка.
+func Fetch(ctx context.Context, client *http.Client, link string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "ClipQueue/"+version.Version)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("ответ %s", resp.Status)
+	}
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("страница не HTML: %s", mediaType)
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, maxHeadBytes))
+	if err != nil {
+		return "", err
+	}
+	return parseTitle(head, params["charset"])
+}
+
+// parseTitle извлекает заголовок из начала страницы. Кодировка берётся из
+// заголовка Content-Type, иначе из <meta charset>; по умолчанию — UTF-8.
+func parseTitle(head []byte, charset string) (string, error) {
+	m := titleTag.FindSubmatch(head)
+	if m == nil {
+		return "", fmt.Errorf("на странице нет <title>")
+	}
+	raw := m[1]
+	if charset == "" {
+		if cm := metaCharset.FindSubmatch(head); cm != nil {
+			charset = string(cm[1])
+		}
+	}
+	if charset != "" && !strings.EqualFold(charset, "utf-8") {
+		if enc, err := htmlindex.Get(charset); err == nil {
+			if decoded, err := enc.NewDecoder().Bytes(raw); err == nil {
+				raw = decoded
+			}
+		}
+	}
+	title := strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(string(raw)), " "))
+	if title == "" {
+		return "", fmt.Errorf("заголовок страницы пуст")
+	}
+	if runes := []rune(title); len(runes) > maxTitleRunes {
+		title = string(runes[:maxTitleRunes-1]) + "…"
+	}
+	return title, nil
+}
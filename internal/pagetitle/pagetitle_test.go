@@ -0,0 +1,64 @@
+package pagetitle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSingleURL(t *testing.T) {
+	cases := []struct {
+		text, want string
+	}{
+		{"  https://github.com/serty2005/clipQueue\n", "https://github.com/serty2005/clipQueue"},
+		{"http://example.com/a?b=1", "http://example.com/a?b=1"},
+		{"см. https://example.com", ""},
+		{"https://example.com https://example.org", ""},
+		{"ftp://example.com", ""},
+		{"https://", ""},
+	}
+	for _, tc := range cases {
+		if got := SingleURL(tc.text); got != tc.want {
+			t.Errorf("SingleURL(%q) = %q, ожидалось %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/repo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><head><TITLE>\n  GitHub -\tclipQueue &amp; co </TITLE></head></html>"))
+	})
+	mux.HandleFunc("/cp1251", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<meta charset=\"windows-1251\"><title>\xcf\xf0\xe8\xe2\xe5\xf2</title>"))
+	})
+	mux.HandleFunc("/file.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("PK"))
+	})
+	mux.HandleFunc("/untitled", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>нет заголовка</body></html>"))
+	})
+
+	fetch := func(path string) (string, error) {
+		return Fetch(context.Background(), srv.Client(), srv.URL+path, time.Second)
+	}
+	if title, err := fetch("/repo"); err != nil || title != "GitHub - clipQueue & co" {
+		t.Fatalf("заголовок %q, ошибка %v", title, err)
+	}
+	if title, err := fetch("/cp1251"); err != nil || title != "Привет" {
+		t.Fatalf("заголовок в windows-1251: %q, ошибка %v", title, err)
+	}
+	for _, path := range []string{"/file.zip", "/untitled", "/missing"} {
+		if title, err := fetch(path); err == nil {
+			t.Errorf("%s: ожидалась ошибка, получен заголовок %q", path, title)
+		}
+	}
+}
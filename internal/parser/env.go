@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// envRef находит ссылки на переменные окружения: %NAME% (cmd), $env:NAME и ${env:NAME} (PowerShell).
+var envRef = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%|\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}|\$env:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// EnvVars возвращает имена переменных окружения, упомянутых в s, без повторов
+// (регистр имён не различается, как в Windows) в порядке первого появления.
+func EnvVars(s string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range envRef.FindAllStringSubmatch(s, -1) {
+		name := refName(m)
+		key := strings.ToUpper(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// ExpandEnv подставляет значения переменных окружения в s. Переменные, для
+// которых lookup ничего не нашёл, остаются в исходном виде, как это делает cmd.
+func ExpandEnv(s string, lookup func(string) (string, bool)) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := lookup(refName(envRef.FindStringSubmatch(ref))); ok {
+			return value
+		}
+		return ref
+	})
+}
+
+func refName(m []string) string {
+	for _, name := range m[1:] {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// stepEnvVars собирает переменные из команды, аргументов и целей перенаправлений шага.
+func stepEnvVars(step CommandStep) []string {
+	parts := append([]string{step.Command}, step.Args...)
+	for _, r := range step.Redirects {
+		parts = append(parts, r.Target)
+	}
+	return EnvVars(strings.Join(parts, " "))
+}
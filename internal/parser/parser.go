@@ -1,23 +1,97 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 )
 
+// Dialect — синтаксис разбираемой строки. Значения совпадают с shell.Dialect.
+type Dialect string
+
+const (
+	Cmd        Dialect = "cmd"
+	PowerShell Dialect = "powershell"
+)
+
+// ParseDialect проверяет название диалекта; пустое значение означает cmd.
+func ParseDialect(s string) (Dialect, error) {
+	switch d := Dialect(strings.ToLower(s)); d {
+	case "":
+		return Cmd, nil
+	case Cmd, PowerShell:
+		return d, nil
+	}
+	return "", fmt.Errorf("неизвестный диалект %q: допустимы cmd и powershell", s)
+}
+
 // Token представляет минимальную единицу разбора
 type Token string
 
 // CommandStep представляет шаг пайплайна с командой, аргументами и оператором
 type CommandStep struct {
-	Command  string
-	Args     []string
-	Operator string
+	Command      string
+	CommandQuote string // Кавычка вокруг команды в исходной строке: ", ' или пусто
+	Args         []string
+	ArgQuotes    []string // Кавычки аргументов по индексам Args; пусто — аргумент был без кавычек
+	Operator     string
+	Redirects    []Redirect // Перенаправления ввода-вывода в порядке появления
+	EnvVars      []string   // Имена переменных окружения из %VAR% и $env:VAR без повторов
+}
+
+// Redirect — перенаправление потока: Op — оператор вида >, >>, <, 2>, 2>>
+// или дублирование дескриптора (2>&1, 1>&2), у которого нет Target.
+type Redirect struct {
+	Op          string
+	Target      string
+	TargetQuote string // Кавычка вокруг Target в исходной строке
+}
+
+// IsDup сообщает, что перенаправление направляет поток в другой дескриптор, а не в файл.
+func (r Redirect) IsDup() bool {
+	return strings.Contains(r.Op, ">&") || strings.Contains(r.Op, "<&")
+}
+
+// String собирает перенаправление обратно: 2>&1 или > out.txt.
+func (r Redirect) String() string {
+	return r.format(Cmd)
+}
+
+func (r Redirect) format(dialect Dialect) string {
+	if r.IsDup() || r.Target == "" {
+		return r.Op
+	}
+	return r.Op + " " + quoteWord(r.Target, r.TargetQuote, dialect)
+}
+
+// ArgQuote возвращает кавычку i-го аргумента или пустую строку.
+func (s CommandStep) ArgQuote(i int) string {
+	if i < len(s.ArgQuotes) {
+		return s.ArgQuotes[i]
+	}
+	return ""
+}
+
+// quoteWord возвращает слово в исходных кавычках quote. Слово без сведений о
+// кавычках заключается в двойные кавычки, если иначе оно разобралось бы
+// по-другому: пустое, с пробелами, операторами или кавычками внутри.
+func quoteWord(word, quote string, dialect Dialect) string {
+	if quote == "" {
+		if tokens := tokenize(word, dialect); len(tokens) == 1 && tokens[0] == (token{text: word}) {
+			return word
+		}
+		quote = `"`
+		if strings.Contains(word, `"`) {
+			quote = "'"
+		}
+	}
+	return quote + word + quote
 }
 
 // Pipeline представляет полный пайплайн с шагами и исходной строкой
 type Pipeline struct {
 	Steps    []CommandStep
 	Original string
+	Dialect  Dialect // Определяет, где при сборке нужны кавычки; пустой — cmd
 }
 
 // String собирает пайплайн обратно в строку
@@ -27,11 +101,17 @@ func (p *Pipeline) String() string {
 	}
 	var parts []string
 	for i, step := range p.Steps {
-		cmd := step.Command
-		if len(step.Args) > 0 {
-			cmd += " " + strings.Join(step.Args, " ")
+		var words []string
+		if step.Command != "" || step.CommandQuote != "" {
+			words = append(words, quoteWord(step.Command, step.CommandQuote, p.Dialect))
+		}
+		for j, arg := range step.Args {
+			words = append(words, quoteWord(arg, step.ArgQuote(j), p.Dialect))
 		}
-		parts = append(parts, cmd)
+		for _, r := range step.Redirects {
+			words = append(words, r.format(p.Dialect))
+		}
+		parts = append(parts, strings.Join(words, " "))
 		if step.Operator != "" && i < len(p.Steps)-1 {
 			parts = append(parts, step.Operator)
 		}
@@ -39,91 +119,203 @@ func (p *Pipeline) String() string {
 	return strings.Join(parts, " ")
 }
 
-// tokenize разбивает входную строку на токены с учётом кавычек
-func tokenize(input string) []string {
-	var tokens []string
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOperator
+	tokenRedirect
+)
+
+type token struct {
+	text  string
+	kind  tokenKind
+	quote string // Первая кавычка внутри слова: ", ' или пусто
+}
+
+// tokenize разбивает входную строку на токены с учётом кавычек и правил диалекта
+func tokenize(input string, dialect Dialect) []token {
+	ps := dialect == PowerShell
+	var tokens []token
 	var current strings.Builder
 	inQuotes := false
 	quoteChar := byte(0)
+	wordQuote := ""
+	flush := func() {
+		// Пустое слово сохраняется, только если было в кавычках: "" — это аргумент
+		if current.Len() > 0 || wordQuote != "" {
+			tok := token{text: current.String(), quote: wordQuote}
+			if ps && wordQuote == "" && psOperators[strings.ToLower(tok.text)] {
+				tok.text, tok.kind = strings.ToLower(tok.text), tokenOperator
+			}
+			tokens = append(tokens, tok)
+			current.Reset()
+			wordQuote = ""
+		}
+	}
 	i := 0
 	for i < len(input) {
 		ch := input[i]
 		switch {
+		case ps && ch == '`' && quoteChar != '\'' && i+1 < len(input):
+			// Экранирование PowerShell остаётся в тексте как есть: так его видно
+			// в шаге, и сборка воспроизводит исходную строку
+			current.WriteString(input[i : i+2])
+			i++
+		case ps && inQuotes && ch == quoteChar && i+1 < len(input) && input[i+1] == quoteChar:
+			// Удвоенная кавычка внутри строки PowerShell — сама кавычка
+			current.WriteString(input[i : i+2])
+			i++
+		case ps && inQuotes && quoteChar == '"' && ch == '$' && i+1 < len(input) && input[i+1] == '(':
+			end := scanGroup(input, i+1)
+			current.WriteString(input[i:end])
+			i = end - 1
+		case ps && !inQuotes && (ch == '(' || ch == '{'):
+			// Скобки, $(...), @(...), @{...} и блоки скрипта — одно слово целиком
+			end := scanGroup(input, i)
+			current.WriteString(input[i:end])
+			i = end - 1
 		case !inQuotes && (ch == '"' || ch == '\''):
 			inQuotes = true
 			quoteChar = ch
+			if wordQuote == "" {
+				wordQuote = string(ch)
+			}
 		case inQuotes && ch == quoteChar:
 			inQuotes = false
 			quoteChar = 0
 			// Не добавляем кавычку
 		case !inQuotes && (ch == ' ' || ch == '\t'):
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
+			flush()
+		case !inQuotes && (ch == '>' || ch == '<'):
+			// Одиночная цифра прямо перед оператором — номер дескриптора (2>, 1>&2),
+			// в PowerShell ещё * — все потоки (*>)
+			op := ""
+			if s := current.String(); len(s) == 1 && (s[0] >= '0' && s[0] <= '9' || ps && s[0] == '*') && wordQuote == "" {
+				op = s
 				current.Reset()
 			}
-		case !inQuotes && (ch == '|' || ch == '&' || ch == ';' || ch == '>'):
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
+			flush()
+			op += string(ch)
+			if ch == '>' && i+1 < len(input) && input[i+1] == '>' {
+				op += ">"
+				i++
 			}
+			if i+2 < len(input) && input[i+1] == '&' && input[i+2] >= '0' && input[i+2] <= '9' {
+				op += input[i+1 : i+3]
+				i += 2
+			}
+			tokens = append(tokens, token{text: op, kind: tokenRedirect})
+		case !inQuotes && ps && ch == '&' && (i+1 >= len(input) || input[i+1] != '&'):
+			// Одиночный & в PowerShell — оператор вызова, а не разделитель команд
+			current.WriteByte(ch)
+		case !inQuotes && (ch == '|' || ch == '&' || ch == ';'):
+			flush()
 			// Проверяем на && или ||
 			if ch == '&' && i+1 < len(input) && input[i+1] == '&' {
-				tokens = append(tokens, "&&")
+				tokens = append(tokens, token{text: "&&", kind: tokenOperator})
 				i++
 			} else if ch == '|' && i+1 < len(input) && input[i+1] == '|' {
-				tokens = append(tokens, "||")
+				tokens = append(tokens, token{text: "||", kind: tokenOperator})
 				i++
 			} else {
-				tokens = append(tokens, string(ch))
+				tokens = append(tokens, token{text: string(ch), kind: tokenOperator})
 			}
 		default:
 			current.WriteByte(ch)
 		}
 		i++
 	}
-	if current.Len() > 0 {
-		tokens = append(tokens, current.String())
-	}
+	flush()
 	return tokens
 }
 
+// psOperators — логические операторы PowerShell, которые на верхнем уровне
+// строки соединяют шаги так же, как && и ||.
+var psOperators = map[string]bool{"-and": true, "-or": true, "-xor": true}
+
+// scanGroup возвращает позицию сразу за скобкой, закрывающей ( или { в input[start].
+// Вложенные скобки, строки в кавычках и экранирование ` учитываются; у
+// незакрытой группы концом считается конец строки.
+func scanGroup(input string, start int) int {
+	depth := 0
+	quote := byte(0)
+	for i := start; i < len(input); i++ {
+		ch := input[i]
+		switch {
+		case ch == '`' && quote != '\'':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '{':
+			depth++
+		case ch == ')' || ch == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(input)
+}
+
 // parseSteps парсит токены в CommandStep
-func parseSteps(tokens []string) []CommandStep {
+func parseSteps(tokens []token) []CommandStep {
 	var steps []CommandStep
 	i := 0
 	for i < len(tokens) {
-		if isOperator(tokens[i]) {
+		if tokens[i].kind == tokenOperator {
 			// Оператор без команды перед ним? Пропустить или ошибка
 			i++
 			continue
 		}
 		step := CommandStep{}
-		// Первый токен - команда
-		step.Command = tokens[i]
-		i++
-		// Собираем args до оператора
-		for i < len(tokens) && !isOperator(tokens[i]) {
-			step.Args = append(step.Args, tokens[i])
+		// Первое слово - команда, остальные - аргументы; перенаправления
+		// могут стоять в любом месте шага, в том числе перед командой
+		for i < len(tokens) && tokens[i].kind != tokenOperator {
+			tok := tokens[i]
 			i++
+			switch {
+			case tok.kind == tokenRedirect:
+				r := Redirect{Op: tok.text}
+				if !r.IsDup() && i < len(tokens) && tokens[i].kind == tokenWord {
+					r.Target, r.TargetQuote = tokens[i].text, tokens[i].quote
+					i++
+				}
+				step.Redirects = append(step.Redirects, r)
+			case step.Command == "" && step.CommandQuote == "":
+				step.Command, step.CommandQuote = tok.text, tok.quote
+			default:
+				step.Args = append(step.Args, tok.text)
+				step.ArgQuotes = append(step.ArgQuotes, tok.quote)
+			}
 		}
 		// Если есть оператор, устанавливаем его
-		if i < len(tokens) && isOperator(tokens[i]) {
-			step.Operator = tokens[i]
+		if i < len(tokens) {
+			step.Operator = tokens[i].text
 			i++
 		}
+		if strings.Join(step.ArgQuotes, "") == "" {
+			step.ArgQuotes = nil
+		}
+		step.EnvVars = stepEnvVars(step)
 		steps = append(steps, step)
 	}
 	return steps
 }
 
-// isOperator проверяет, является ли токен оператором
-func isOperator(token string) bool {
-	return token == "|" || token == "&&" || token == "||" || token == ";" || token == ">"
-}
-
-// Parse разбирает входную строку на Pipeline
-func Parse(input string) (*Pipeline, error) {
-	tokens := tokenize(input)
+// Parse разбирает входную строку на Pipeline по правилам диалекта; пустой
+// диалект означает cmd.
+func Parse(input string, dialect Dialect) (*Pipeline, error) {
+	dialect, err := ParseDialect(string(dialect))
+	if err != nil {
+		return nil, err
+	}
+	tokens := tokenize(input, dialect)
 	steps := parseSteps(tokens)
-	return &Pipeline{Steps: steps, Original: input}, nil
+	return &Pipeline{Steps: steps, Original: input, Dialect: dialect}, nil
 }
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRedirects(t *testing.T) {
+	p, err := Parse(`dir C:\temp 2>&1 >> log.txt | findstr /i "my file" 2> nul`, Cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Steps) != 2 {
+		t.Fatalf("шагов %d, ожидалось 2", len(p.Steps))
+	}
+	first := p.Steps[0]
+	if first.Command != "dir" || !reflect.DeepEqual(first.Args, []string{`C:\temp`}) || first.Operator != "|" {
+		t.Fatalf("первый шаг: %+v", first)
+	}
+	if want := []Redirect{{Op: "2>&1"}, {Op: ">>", Target: "log.txt"}}; !reflect.DeepEqual(first.Redirects, want) {
+		t.Fatalf("перенаправления: %+v, ожидалось %+v", first.Redirects, want)
+	}
+	second := p.Steps[1]
+	if !reflect.DeepEqual(second.Args, []string{"/i", "my file"}) {
+		t.Fatalf("аргументы: %q", second.Args)
+	}
+	if want := []Redirect{{Op: "2>", Target: "nul"}}; !reflect.DeepEqual(second.Redirects, want) {
+		t.Fatalf("перенаправления: %+v", second.Redirects)
+	}
+}
+
+func TestParseRedirectBeforeCommand(t *testing.T) {
+	p, _ := Parse(`< in.txt sort`, Cmd)
+	step := p.Steps[0]
+	if step.Command != "sort" || len(step.Args) != 0 || !reflect.DeepEqual(step.Redirects, []Redirect{{Op: "<", Target: "in.txt"}}) {
+		t.Fatalf("шаг: %+v", step)
+	}
+}
+
+func TestParseDigitArgumentIsNotDescriptor(t *testing.T) {
+	// Цифра в составе слова или в кавычках — аргумент, а не номер дескриптора.
+	p, _ := Parse(`echo 12> out.txt "2>x"`, Cmd)
+	step := p.Steps[0]
+	if !reflect.DeepEqual(step.Args, []string{"12", "2>x"}) {
+		t.Fatalf("аргументы: %q", step.Args)
+	}
+	if !reflect.DeepEqual(step.Redirects, []Redirect{{Op: ">", Target: "out.txt"}}) {
+		t.Fatalf("перенаправления: %+v", step.Redirects)
+	}
+}
+
+func TestParseSingleAmpersandSeparatesCommands(t *testing.T) {
+	p, _ := Parse(`cd %TEMP% & dir`, Cmd)
+	if len(p.Steps) != 2 || p.Steps[0].Operator != "&" || p.Steps[1].Command != "dir" {
+		t.Fatalf("шаги: %+v", p.Steps)
+	}
+}
+
+func TestParseEnvVars(t *testing.T) {
+	p, _ := Parse(`copy %USERPROFILE%\a.txt %Temp%\b.txt > %temp%\log.txt; echo $env:PATH ${env:ProgramFiles} $notenv`, Cmd)
+	if want := []string{"USERPROFILE", "Temp"}; !reflect.DeepEqual(p.Steps[0].EnvVars, want) {
+		t.Fatalf("переменные: %q, ожидалось %q", p.Steps[0].EnvVars, want)
+	}
+	if want := []string{"PATH", "ProgramFiles"}; !reflect.DeepEqual(p.Steps[1].EnvVars, want) {
+		t.Fatalf("переменные: %q, ожидалось %q", p.Steps[1].EnvVars, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, cmd := range []string{
+		`dir /b > files.txt 2>&1`,
+		`type a.txt >> b.txt && echo done || echo fail`,
+		`cmd1 2> err.log | sort < input.txt`,
+		`cd %TEMP% & dir`,
+		`echo "hello world"`,
+		`"C:\Program Files\Git\bin\git.exe" log '--format=%h %s' > "my log.txt"`,
+		`findstr "" 'a b' x`,
+	} {
+		p, _ := Parse(cmd, Cmd)
+		if got := p.String(); got != cmd {
+			t.Errorf("String() = %q, ожидалось %q", got, cmd)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"HOME": `C:\Users\me`, "PATH": `C:\bin`}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	got := ExpandEnv(`%HOME%\x $env:PATH ${env:HOME} %MISSING% 100%`, lookup)
+	want := `C:\Users\me\x C:\bin C:\Users\me %MISSING% 100%`
+	if got != want {
+		t.Fatalf("ExpandEnv = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestParseQuotes(t *testing.T) {
+	p, _ := Parse(`"my app.exe" --name="a b" plain ""`, Cmd)
+	step := p.Steps[0]
+	if step.Command != "my app.exe" || step.CommandQuote != `"` {
+		t.Fatalf("команда: %q %q", step.Command, step.CommandQuote)
+	}
+	if want := []string{"--name=a b", "plain", ""}; !reflect.DeepEqual(step.Args, want) {
+		t.Fatalf("аргументы: %q", step.Args)
+	}
+	if want := []string{`"`, "", `"`}; !reflect.DeepEqual(step.ArgQuotes, want) {
+		t.Fatalf("кавычки: %q", step.ArgQuotes)
+	}
+	// Частично закавыченное слово собирается в кавычках целиком: для программы это тот же аргумент.
+	if got, want := p.String(), `"my app.exe" "--name=a b" plain ""`; got != want {
+		t.Fatalf("String() = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestStringQuotesWithoutMetadata(t *testing.T) {
+	// Шаги, собранные вручную, не знают исходных кавычек: нужные добавляются сами.
+	p := Pipeline{Steps: []CommandStep{{
+		Command:   "echo",
+		Args:      []string{"hello world", "a|b", `say "hi"`, ""},
+		Redirects: []Redirect{{Op: ">", Target: "out file.txt"}},
+	}}}
+	if got, want := p.String(), `echo "hello world" "a|b" 'say "hi"' "" > "out file.txt"`; got != want {
+		t.Fatalf("String() = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestParsePowerShell(t *testing.T) {
+	p, err := Parse("Get-ChildItem $env:TEMP -Filter `*.log | Where-Object { $_.Length -gt 1kb -and $_.Name -ne 'a b' } | ForEach-Object { \"$($_.Name): ok\" } *> out.txt", PowerShell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Steps) != 3 {
+		t.Fatalf("шагов %d, ожидалось 3: %+v", len(p.Steps), p.Steps)
+	}
+	if want := []string{"$env:TEMP", "-Filter", "`*.log"}; !reflect.DeepEqual(p.Steps[0].Args, want) {
+		t.Fatalf("аргументы: %q", p.Steps[0].Args)
+	}
+	if want := []string{"TEMP"}; !reflect.DeepEqual(p.Steps[0].EnvVars, want) {
+		t.Fatalf("переменные: %q", p.Steps[0].EnvVars)
+	}
+	// Блок скрипта — один аргумент: -and и | внутри него не разделяют шаги.
+	if want := []string{"{ $_.Length -gt 1kb -and $_.Name -ne 'a b' }"}; !reflect.DeepEqual(p.Steps[1].Args, want) {
+		t.Fatalf("аргументы: %q", p.Steps[1].Args)
+	}
+	if want := []Redirect{{Op: "*>", Target: "out.txt"}}; !reflect.DeepEqual(p.Steps[2].Redirects, want) {
+		t.Fatalf("перенаправления: %+v", p.Steps[2].Redirects)
+	}
+	if got := p.String(); got != p.Original {
+		t.Fatalf("String() = %q, ожидалось %q", got, p.Original)
+	}
+}
+
+func TestParsePowerShellOperators(t *testing.T) {
+	p, _ := Parse(`(Test-Path C:\a) -AND (Test-Path "C:\b c"); & "C:\my tool.exe" /q && echo "say ""hi"" `+"`"+`"x`+"`"+`"" $(Get-Date -Format "HH:mm")`, PowerShell)
+	var ops []string
+	for _, step := range p.Steps {
+		ops = append(ops, step.Operator)
+	}
+	if want := []string{"-and", ";", "&&", ""}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf("операторы: %q", ops)
+	}
+	if p.Steps[0].Command != `(Test-Path C:\a)` {
+		t.Fatalf("команда: %q", p.Steps[0].Command)
+	}
+	call := p.Steps[2]
+	if call.Command != "&" || !reflect.DeepEqual(call.Args, []string{`C:\my tool.exe`, "/q"}) {
+		t.Fatalf("оператор вызова: %+v", call)
+	}
+	echo := p.Steps[3]
+	if want := []string{"say \"\"hi\"\" `\"x`\"", `$(Get-Date -Format "HH:mm")`}; !reflect.DeepEqual(echo.Args, want) {
+		t.Fatalf("аргументы: %q", echo.Args)
+	}
+	if got, want := p.String(), `(Test-Path C:\a) -and (Test-Path "C:\b c") ; & "C:\my tool.exe" /q && echo "say ""hi"" `+"`"+`"x`+"`"+`"" $(Get-Date -Format "HH:mm")`; got != want {
+		t.Fatalf("String() = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestParseCmdKeepsAmpersandSeparator(t *testing.T) {
+	// В cmd те же символы значат другое: & разделяет команды, скобки и ` — обычный текст.
+	p, _ := Parse("echo `a & echo (b c)", Cmd)
+	if len(p.Steps) != 2 || !reflect.DeepEqual(p.Steps[0].Args, []string{"`a"}) || !reflect.DeepEqual(p.Steps[1].Args, []string{"(b", "c)"}) {
+		t.Fatalf("шаги: %+v", p.Steps)
+	}
+}
+
+func TestParseUnknownDialect(t *testing.T) {
+	if _, err := Parse("dir", "bash"); err == nil {
+		t.Fatal("ожидалась ошибка неизвестного диалекта")
+	}
+}
@@ -0,0 +1,99 @@
+package peersync
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// passphraseIterations — число итераций PBKDF2 для ключа из пароля. Ключ
+	// выводится один раз при создании узла, поэтому задержка незаметна.
+	passphraseIterations = 210000
+
+	labelChannel = "clipqueue-sync-channel"
+)
+
+// passphraseSalt фиксирован: узлы должны получить одинаковый ключ из пароля
+// без обмена данными. Случайность каждого сеанса дают nonce и ключи X25519.
+var passphraseSalt = []byte("clipqueue-sync-passphrase-v2")
+
+// derivePassphraseKey выводит 32-байтовый ключ из пароля синхронизации.
+func derivePassphraseKey(passphrase string) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, passphraseSalt, passphraseIterations, 32)
+}
+
+// channel шифрует кадры сеанса AES-256-GCM. Для каждого направления свой ключ,
+// поэтому номер кадра можно использовать как nonce без риска повтора.
+type channel struct {
+	send cipher.AEAD
+	recv cipher.AEAD
+}
+
+// newChannel выводит ключи сеанса из общего секрета X25519 (эфемерные ключи
+// обеих сторон), ключа пароля и nonce рукопожатия. Без пароля перехватчик не
+// пройдёт рукопожатие, а без эфемерного закрытого ключа не расшифрует записанный трафик.
+func newChannel(priv *ecdh.PrivateKey, peerPub, passKey, nonceA, nonceB []byte, self, peer string) (*channel, error) {
+	pub, err := ecdh.X25519().NewPublicKey(peerPub)
+	if err != nil {
+		return nil, fmt.Errorf("неверный открытый ключ узла: %w", err)
+	}
+	shared, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(nonceA, nonceB) > 0 {
+		nonceA, nonceB = nonceB, nonceA
+	}
+	salt := append(append(append([]byte(nil), passKey...), nonceA...), nonceB...)
+
+	send, err := channelAEAD(shared, salt, self)
+	if err != nil {
+		return nil, err
+	}
+	recv, err := channelAEAD(shared, salt, peer)
+	if err != nil {
+		return nil, err
+	}
+	return &channel{send: send, recv: recv}, nil
+}
+
+// channelAEAD выводит ключ направления от узла sender.
+func channelAEAD(shared, salt []byte, sender string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, shared, salt, labelChannel+"|"+sender, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func frameNonce(seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
+
+func frameAAD(seq uint64) []byte {
+	aad := make([]byte, len(frameItem)+8)
+	copy(aad, frameItem)
+	binary.BigEndian.PutUint64(aad[len(frameItem):], seq)
+	return aad
+}
+
+func (c *channel) seal(seq uint64, plaintext []byte) []byte {
+	return c.send.Seal(nil, frameNonce(seq), plaintext, frameAAD(seq))
+}
+
+func (c *channel) open(seq uint64, ciphertext []byte) ([]byte, error) {
+	return c.recv.Open(nil, frameNonce(seq), ciphertext, frameAAD(seq))
+}
@@ -0,0 +1,47 @@
+package peersync
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"testing"
+)
+
+func TestChannelRoundTripAndTamper(t *testing.T) {
+	passKey, err := derivePassphraseKey(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privA, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	privB, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	nonceA, nonceB := []byte("nonce-a"), []byte("nonce-b")
+
+	a, err := newChannel(privA, privB.PublicKey().Bytes(), passKey, nonceA, nonceB, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newChannel(privB, privA.PublicKey().Bytes(), passKey, nonceB, nonceA, "b", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("пароль от почты")
+	sealed := a.seal(1, secret)
+	if bytes.Contains(sealed, secret) {
+		t.Fatal("шифртекст содержит открытый текст")
+	}
+	got, err := b.open(1, sealed)
+	if err != nil || !bytes.Equal(got, secret) {
+		t.Fatalf("open = %q, %v", got, err)
+	}
+	if _, err := b.open(2, sealed); err == nil {
+		t.Fatal("кадр с чужим номером расшифрован")
+	}
+	if _, err := a.open(1, sealed); err == nil {
+		t.Fatal("собственный кадр, отражённый обратно, расшифрован")
+	}
+	sealed[0] ^= 1
+	if _, err := b.open(1, sealed); err == nil {
+		t.Fatal("изменённый кадр расшифрован")
+	}
+}
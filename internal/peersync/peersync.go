@@ -0,0 +1,430 @@
+// Package peersync синхронизирует элементы буфера между экземплярами ClipQueue
+// в локальной сети. Узлы соединяются по TCP, проверяют друг друга ключом из общего
+// пароля (HMAC с challenge-response), договариваются о ключах сеанса по X25519 и
+// пересылают скопированные элементы (текст, изображения и файлы) зашифрованными AES-GCM.
+package peersync
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/filebundle"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const (
+	// DefaultPort — TCP-порт синхронизации по умолчанию.
+	DefaultPort = 47321
+	// MinKeyLength — минимальная длина общего пароля.
+	MinKeyLength = 16
+
+	handshakeTimeout = 10 * time.Second
+	writeTimeout     = 30 * time.Second
+	dialTimeout      = 5 * time.Second
+	minRedial        = 2 * time.Second
+	maxRedial        = time.Minute
+	maxSeen          = 1024
+)
+
+// Виды элементов.
+const (
+	KindText  = "text"
+	KindImage = "image"
+	KindFiles = "files"
+)
+
+// Item — элемент буфера, который пересылается между узлами.
+type Item struct {
+	ID        string    `json:"id"`
+	Origin    string    `json:"origin"`
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`
+	Text      string    `json:"text,omitempty"`
+	ImagePNG  []byte    `json:"imagePng,omitempty"`
+	// Files — содержимое файлов элемента Files: пути другого компьютера бессмысленны.
+	Files []filebundle.File `json:"files,omitempty"`
+}
+
+// Config — параметры узла.
+type Config struct {
+	// Listen — адрес для входящих подключений, например ":47321". Пустой — только исходящие.
+	Listen string
+	// Peers — адреса host:port других узлов, к которым узел подключается сам.
+	Peers []string
+	// Key — общий пароль, одинаковый на всех узлах; из него выводится ключ PBKDF2.
+	Key string
+}
+
+// ErrClosed возвращается при повторном запуске остановленного узла.
+var ErrClosed = errors.New("узел синхронизации остановлен")
+
+// Node — узел синхронизации: принимает подключения, подключается к известным
+// узлам и рассылает им элементы.
+type Node struct {
+	cfg    Config
+	key    []byte
+	id     string
+	onItem func(Item)
+
+	mu        sync.Mutex
+	closed    bool
+	listener  net.Listener
+	sessions  map[*session]struct{}
+	seen      map[string]struct{}
+	seenOrder []string
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New создаёт узел. onItem вызывается для каждого нового элемента, полученного от других узлов.
+func New(cfg Config, onItem func(Item)) (*Node, error) {
+	if len(cfg.Key) < MinKeyLength {
+		return nil, fmt.Errorf("ключ синхронизации короче %d символов", MinKeyLength)
+	}
+	key, err := derivePassphraseKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	if onItem == nil {
+		onItem = func(Item) {}
+	}
+	return &Node{
+		cfg:      cfg,
+		key:      key,
+		id:       hex.EncodeToString(id),
+		onItem:   onItem,
+		sessions: make(map[*session]struct{}),
+		seen:     make(map[string]struct{}),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// ID возвращает случайный идентификатор узла, выбранный при создании.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Start открывает порт для входящих подключений и запускает подключение к узлам из Peers.
+func (n *Node) Start() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	if n.cfg.Listen != "" {
+		ln, err := net.Listen("tcp", n.cfg.Listen)
+		if err != nil {
+			return fmt.Errorf("не удалось открыть порт синхронизации %s: %w", n.cfg.Listen, err)
+		}
+		n.listener = ln
+		logger.Info("Синхронизация: ожидание подключений на %s", ln.Addr())
+		n.wg.Add(1)
+		crash.Go("peersync.accept", func() {
+			defer n.wg.Done()
+			n.acceptLoop(ln)
+		})
+	}
+	for _, addr := range n.cfg.Peers {
+		addr := addr
+		n.wg.Add(1)
+		crash.Go("peersync.dial", func() {
+			defer n.wg.Done()
+			n.dialLoop(addr)
+		})
+	}
+	return nil
+}
+
+// Addr возвращает адрес, на котором узел принимает подключения, или nil.
+func (n *Node) Addr() net.Addr {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.listener == nil {
+		return nil
+	}
+	return n.listener.Addr()
+}
+
+// Close останавливает узел и закрывает все соединения.
+func (n *Node) Close() error {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil
+	}
+	n.closed = true
+	close(n.stop)
+	if n.listener != nil {
+		n.listener.Close()
+	}
+	for s := range n.sessions {
+		s.conn.Close()
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+	return nil
+}
+
+// Connected возвращает число установленных соединений с другими узлами.
+func (n *Node) Connected() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.sessions)
+}
+
+// Broadcast рассылает элемент всем подключённым узлам. Пустой Origin заменяется
+// на ID узла. Ошибка отправки закрывает соединение; оно восстановится само.
+func (n *Node) Broadcast(item Item) {
+	if item.Origin == "" {
+		item.Origin = n.id
+	}
+	n.markSeen(item)
+	plaintext, err := json.Marshal(item)
+	if err != nil {
+		logger.Warn("Синхронизация: не удалось сериализовать элемент %s: %v", item.ID, err)
+		return
+	}
+
+	n.mu.Lock()
+	sessions := make([]*session, 0, len(n.sessions))
+	for s := range n.sessions {
+		sessions = append(sessions, s)
+	}
+	n.mu.Unlock()
+
+	for _, s := range sessions {
+		if err := s.send(plaintext); err != nil {
+			logger.Warn("Синхронизация: не удалось отправить элемент узлу %s: %v", s.conn.RemoteAddr(), err)
+			s.conn.Close()
+		}
+	}
+	if len(sessions) > 0 {
+		logger.Debug("Синхронизация: элемент %s (%s) отправлен узлам: %d", item.ID, item.Kind, len(sessions))
+	}
+}
+
+// markSeen запоминает элемент и сообщает, встречался ли он раньше. Два узла,
+// подключённые друг к другу встречно, получают элемент дважды — второй копии не будет.
+// ID выводится из содержимого, поэтому в ключ входит и время: то же содержимое,
+// скопированное позже, снова считается новым.
+func (n *Node) markSeen(item Item) (fresh bool) {
+	key := item.Origin + "/" + item.ID + "/" + strconv.FormatInt(item.Timestamp.UnixNano(), 10)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.seen[key]; ok {
+		return false
+	}
+	n.seen[key] = struct{}{}
+	n.seenOrder = append(n.seenOrder, key)
+	if len(n.seenOrder) > maxSeen {
+		delete(n.seen, n.seenOrder[0])
+		n.seenOrder = n.seenOrder[1:]
+	}
+	return true
+}
+
+func (n *Node) acceptLoop(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-n.stop:
+			default:
+				logger.Warn("Синхронизация: приём подключений остановлен: %v", err)
+			}
+			return
+		}
+		n.wg.Add(1)
+		crash.Go("peersync.serve", func() {
+			defer n.wg.Done()
+			n.serve(conn)
+		})
+	}
+}
+
+// dialLoop поддерживает соединение с узлом addr, переподключаясь с нарастающей паузой.
+func (n *Node) dialLoop(addr string) {
+	delay := minRedial
+	for {
+		conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+		if err == nil {
+			started := time.Now()
+			n.serve(conn)
+			if time.Since(started) > maxRedial {
+				delay = minRedial
+			}
+		} else {
+			logger.Debug("Синхронизация: узел %s недоступен: %v", addr, err)
+		}
+		select {
+		case <-n.stop:
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRedial {
+			delay = maxRedial
+		}
+	}
+}
+
+// serve проводит рукопожатие и читает кадры, пока соединение не закроется.
+func (n *Node) serve(conn net.Conn) {
+	defer conn.Close()
+	s, err := n.handshake(conn)
+	if err != nil {
+		logger.Warn("Синхронизация: узел %s отклонён: %v", conn.RemoteAddr(), err)
+		return
+	}
+	if !n.addSession(s) {
+		return
+	}
+	defer n.removeSession(s)
+	logger.Info("Синхронизация: подключён узел %s (%s)", s.peer, conn.RemoteAddr())
+
+	err = s.readLoop(func(item Item) {
+		if !n.markSeen(item) {
+			return
+		}
+		logger.Info("Синхронизация: получен элемент %s (%s) от %s", item.ID, item.Kind, s.peer)
+		n.onItem(item)
+	})
+	select {
+	case <-n.stop:
+	default:
+		logger.Info("Синхронизация: узел %s отключён: %v", s.peer, err)
+	}
+}
+
+func (n *Node) addSession(s *session) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return false
+	}
+	n.sessions[s] = struct{}{}
+	return true
+}
+
+func (n *Node) removeSession(s *session) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.sessions, s)
+}
+
+// handshake обменивается nonce, эфемерными ключами X25519 и доказательствами знания
+// пароля. Обе стороны выполняют одинаковые шаги, поэтому роль (принимающий или
+// подключающийся) не важна.
+func (n *Node) handshake(conn net.Conn) (*session, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	pub := priv.PublicKey().Bytes()
+	if err := writeFrame(conn, frame{Type: frameHello, Version: protocolVersion, Node: n.id, Nonce: nonce, Pub: pub}); err != nil {
+		return nil, err
+	}
+	hello, err := readFrame(conn, maxHandshakeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if hello.Type != frameHello || len(hello.Nonce) != len(nonce) || hello.Node == "" {
+		return nil, errors.New("ожидалось приветствие ClipQueue")
+	}
+	if hello.Version != protocolVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия протокола %d", hello.Version)
+	}
+	if hello.Node == n.id {
+		return nil, errors.New("подключение к самому себе")
+	}
+
+	proof := authMAC(n.key, hello.Nonce, nonce, hello.Pub, pub, n.id)
+	if err := writeFrame(conn, frame{Type: frameAuth, MAC: proof}); err != nil {
+		return nil, err
+	}
+	auth, err := readFrame(conn, maxHandshakeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if auth.Type != frameAuth || !hmac.Equal(auth.MAC, authMAC(n.key, nonce, hello.Nonce, pub, hello.Pub, hello.Node)) {
+		return nil, errors.New("неверный ключ синхронизации")
+	}
+	ch, err := newChannel(priv, hello.Pub, n.key, nonce, hello.Nonce, n.id, hello.Node)
+	if err != nil {
+		return nil, err
+	}
+	return &session{conn: conn, peer: hello.Node, ch: ch}, nil
+}
+
+// session — проверенное соединение с другим узлом.
+type session struct {
+	conn net.Conn
+	peer string
+	ch   *channel
+
+	writeMu sync.Mutex
+	sendSeq uint64
+}
+
+func (s *session) send(plaintext []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.sendSeq++
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return writeFrame(s.conn, frame{
+		Type: frameItem,
+		Seq:  s.sendSeq,
+		Data: s.ch.seal(s.sendSeq, plaintext),
+	})
+}
+
+// readLoop расшифровывает кадры, проверяет их порядок и передаёт элементы в handle.
+func (s *session) readLoop(handle func(Item)) error {
+	var lastSeq uint64
+	for {
+		f, err := readFrame(s.conn, maxFrameBytes)
+		if err != nil {
+			return err
+		}
+		if f.Type != frameItem {
+			continue
+		}
+		if f.Seq <= lastSeq {
+			return errors.New("кадр вне очереди")
+		}
+		plaintext, err := s.ch.open(f.Seq, f.Data)
+		if err != nil {
+			return errors.New("кадр не расшифрован: данные повреждены или подменены")
+		}
+		lastSeq = f.Seq
+		var item Item
+		if err := json.Unmarshal(plaintext, &item); err != nil {
+			return fmt.Errorf("повреждённый элемент: %w", err)
+		}
+		if item.ID == "" || (item.Kind != KindText && item.Kind != KindImage && item.Kind != KindFiles) {
+			logger.Debug("Синхронизация: пропущен элемент неизвестного вида %q", item.Kind)
+			continue
+		}
+		handle(item)
+	}
+}
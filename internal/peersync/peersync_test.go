@@ -0,0 +1,79 @@
+package peersync
+
+import (
+	"testing"
+	"time"
+)
+
+const testKey = "0123456789abcdef-test"
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("не дождались: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func startPair(t *testing.T, serverKey, clientKey string, onItem func(Item)) (*Node, *Node) {
+	t.Helper()
+	server, err := New(Config{Listen: "127.0.0.1:0", Key: serverKey}, onItem)
+	if err != nil {
+		t.Fatalf("New server: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	client, err := New(Config{Peers: []string{server.Addr().String()}, Key: clientKey}, nil)
+	if err != nil {
+		t.Fatalf("New client: %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestBroadcastDeliversItemOnce(t *testing.T) {
+	received := make(chan Item, 4)
+	server, client := startPair(t, testKey, testKey, func(item Item) { received <- item })
+	waitFor(t, "подключение узлов", func() bool { return server.Connected() == 1 && client.Connected() == 1 })
+
+	item := Item{ID: "1", Timestamp: time.Now(), Kind: KindImage, ImagePNG: []byte{1, 2, 3}}
+	client.Broadcast(item)
+	client.Broadcast(item)
+
+	select {
+	case got := <-received:
+		if got.ID != "1" || got.Origin != client.ID() || string(got.ImagePNG) != "\x01\x02\x03" {
+			t.Fatalf("получен элемент %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("элемент не доставлен")
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("повторная доставка элемента %+v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWrongKeyRejected(t *testing.T) {
+	server, client := startPair(t, testKey, "fedcba9876543210-other", nil)
+	time.Sleep(200 * time.Millisecond)
+	if server.Connected() != 0 || client.Connected() != 0 {
+		t.Fatalf("узлы с разными ключами соединились: %d/%d", server.Connected(), client.Connected())
+	}
+}
+
+func TestNewRejectsShortKey(t *testing.T) {
+	if _, err := New(Config{Key: "short"}, nil); err == nil {
+		t.Fatal("короткий ключ принят")
+	}
+}
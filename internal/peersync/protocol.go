@@ -0,0 +1,92 @@
+package peersync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	// protocolVersion 2: элементы шифруются AES-GCM ключами сеанса X25519.
+	protocolVersion = 2
+
+	// maxHandshakeBytes ограничивает кадры до проверки ключа, чтобы посторонний
+	// клиент не мог заставить выделить большой буфер.
+	maxHandshakeBytes = 4 << 10
+	// maxFrameBytes ограничивает кадр с элементом (зашифрованный PNG плюс JSON-обвязка).
+	maxFrameBytes = 64 << 20
+
+	frameHello = "hello"
+	frameAuth  = "auth"
+	frameItem  = "item"
+
+	labelAuth = "clipqueue-sync-auth"
+)
+
+// frame — кадр протокола. Поля заполняются в зависимости от типа.
+type frame struct {
+	Type    string `json:"type"`
+	Version int    `json:"version,omitempty"`
+	Node    string `json:"node,omitempty"`
+	Nonce   []byte `json:"nonce,omitempty"`
+	Pub     []byte `json:"pub,omitempty"` // Эфемерный открытый ключ X25519
+	MAC     []byte `json:"mac,omitempty"`
+	Seq     uint64 `json:"seq,omitempty"`
+	Data    []byte `json:"data,omitempty"` // Элемент, зашифрованный ключом сеанса
+}
+
+// writeFrame пишет кадр: 4 байта длины (big-endian) и JSON.
+func writeFrame(w io.Writer, f frame) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	_, err = w.Write(buf)
+	return err
+}
+
+// readFrame читает кадр не длиннее limit байт.
+func readFrame(r io.Reader, limit int) (frame, error) {
+	var f frame
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return f, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if int64(n) > int64(limit) {
+		return f, fmt.Errorf("кадр %d байт больше допустимых %d", n, limit)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("повреждённый кадр: %w", err)
+	}
+	return f, nil
+}
+
+func mac(key []byte, parts ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, p := range parts {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(p)))
+		h.Write(size[:])
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// authMAC доказывает знание ключа пароля: отправитель sender подписывает
+// nonce получателя вместе со своим и оба эфемерных ключа X25519. Порядок полей
+// и имя отправителя в подписи не дают вернуть собеседнику его же ответ, а
+// подписанные открытые ключи — подменить их посредником.
+func authMAC(key []byte, receiverNonce, senderNonce, receiverPub, senderPub []byte, sender string) []byte {
+	return mac(key, []byte(labelAuth), receiverNonce, senderNonce, receiverPub, senderPub, []byte(sender))
+}
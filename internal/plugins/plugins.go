@@ -0,0 +1,318 @@
+// Package plugins загружает пользовательские скрипты Lua из каталога plugins и
+// вызывает их хуки: transform и on_capture для новых элементов, on_before_paste
+// перед вставкой из очереди, а также действия макросов режима script.
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// DirName — каталог скриптов внутри App.DataDir.
+const DirName = "plugins"
+
+// DefaultTimeout ограничивает один вызов скрипта, если в конфигурации не задано иное.
+const DefaultTimeout = time.Second
+
+// Имена глобальных функций-хуков в скрипте.
+const (
+	hookTransform   = "transform"
+	hookCapture     = "on_capture"
+	hookBeforePaste = "on_before_paste"
+)
+
+// ErrUnknownAction возвращается, если ни один плагин не зарегистрировал действие.
+var ErrUnknownAction = errors.New("действие не зарегистрировано ни одним плагином")
+
+// Item — элемент истории в том виде, в котором его видят скрипты.
+type Item struct {
+	ID        string
+	Type      string // Text, Image или Files
+	Text      string
+	Files     []string
+	Preview   string
+	Timestamp time.Time
+}
+
+// Target — окно, в которое выполняется вставка.
+type Target struct {
+	Title   string
+	Process string
+}
+
+// Host — действия приложения, доступные скриптам через таблицу clipqueue.
+type Host interface {
+	PushText(text string) (string, error)
+	CopyText(text string) error
+	Notify(title, text string)
+}
+
+// Manager держит загруженные плагины. Методы безопасны для вызова из разных горутин:
+// каждый скрипт выполняется в своём состоянии Lua под своим мьютексом.
+type Manager struct {
+	host    Host
+	timeout time.Duration
+	plugins []*plugin
+}
+
+type plugin struct {
+	name    string
+	mu      sync.Mutex
+	state   *lua.LState
+	actions map[string]*lua.LFunction
+}
+
+// Load загружает все *.lua из dir в алфавитном порядке. Скрипт с ошибкой
+// пропускается и попадает в журнал, остальные продолжают работать.
+// Отсутствие каталога — не ошибка: менеджер просто пуст.
+func Load(dir string, host Host, timeout time.Duration) (*Manager, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	m := &Manager{host: host, timeout: timeout}
+	files, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		p, err := m.load(file)
+		if err != nil {
+			logger.Error("Плагин %s не загружен: %v", filepath.Base(file), err)
+			continue
+		}
+		m.plugins = append(m.plugins, p)
+		logger.Info("Плагин %s загружен (действий: %d)", p.name, len(p.actions))
+	}
+	return m, nil
+}
+
+func (m *Manager) load(file string) (*plugin, error) {
+	p := &plugin{name: strings.TrimSuffix(filepath.Base(file), ".lua"), actions: make(map[string]*lua.LFunction)}
+	p.state = newState()
+	p.state.SetGlobal("clipqueue", m.api(p))
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	p.state.SetContext(ctx)
+	defer p.state.RemoveContext()
+	if err := p.state.DoFile(file); err != nil {
+		p.state.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// newState создаёт состояние Lua только с безопасными библиотеками: без io,
+// загрузки файлов и запуска процессов; из os остаются функции времени.
+func newState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.OsLibName, lua.OpenOs},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	if osTbl, ok := L.GetGlobal(lua.OsLibName).(*lua.LTable); ok {
+		for _, name := range []string{"execute", "exit", "remove", "rename", "setenv", "setlocale", "tmpname"} {
+			osTbl.RawSetString(name, lua.LNil)
+		}
+	}
+	return L
+}
+
+// api строит таблицу clipqueue с функциями для скрипта p.
+func (m *Manager) api(p *plugin) *lua.LTable {
+	L := p.state
+	return L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"action": func(L *lua.LState) int {
+			p.actions[L.CheckString(1)] = L.CheckFunction(2)
+			return 0
+		},
+		"log": func(L *lua.LState) int {
+			logger.Info("Плагин %s: %s", p.name, L.CheckString(1))
+			return 0
+		},
+		"notify": func(L *lua.LState) int {
+			m.host.Notify(L.CheckString(1), L.OptString(2, ""))
+			return 0
+		},
+		"push": func(L *lua.LState) int {
+			id, err := m.host.PushText(L.CheckString(1))
+			if err != nil {
+				L.RaiseError("%v", err)
+			}
+			L.Push(lua.LString(id))
+			return 1
+		},
+		"copy": func(L *lua.LState) int {
+			if err := m.host.CopyText(L.CheckString(1)); err != nil {
+				L.RaiseError("%v", err)
+			}
+			return 0
+		},
+	})
+}
+
+// Close освобождает состояния Lua.
+func (m *Manager) Close() {
+	for _, p := range m.plugins {
+		p.mu.Lock()
+		p.state.Close()
+		p.mu.Unlock()
+	}
+	m.plugins = nil
+}
+
+// Names возвращает имена загруженных плагинов.
+func (m *Manager) Names() []string {
+	names := make([]string, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// Capture пропускает новый элемент через плагины по порядку: transform(text, item)
+// может вернуть новый текст текстового элемента, on_capture(item) — false, чтобы
+// элемент не попал в историю и очередь.
+func (m *Manager) Capture(item Item) (Item, bool) {
+	for _, p := range m.plugins {
+		if item.Type == "Text" {
+			ret, err := m.call(p, hookTransform, func(L *lua.LState) []lua.LValue {
+				return []lua.LValue{lua.LString(item.Text), itemTable(L, item)}
+			})
+			if err != nil {
+				logger.Warn("Плагин %s: %s: %v", p.name, hookTransform, err)
+			} else if s, ok := ret.(lua.LString); ok {
+				item.Text = string(s)
+			}
+		}
+		ret, err := m.call(p, hookCapture, func(L *lua.LState) []lua.LValue {
+			return []lua.LValue{itemTable(L, item)}
+		})
+		if err != nil {
+			logger.Warn("Плагин %s: %s: %v", p.name, hookCapture, err)
+			continue
+		}
+		if ret == lua.LFalse {
+			logger.Info("Плагин %s отбросил элемент %s", p.name, item.ID)
+			return item, false
+		}
+	}
+	return item, true
+}
+
+// BeforePaste вызывает on_before_paste(item, target) перед вставкой. Строка
+// в ответе заменяет вставляемый текст, false отменяет вставку.
+func (m *Manager) BeforePaste(item Item, target Target) (Item, bool) {
+	for _, p := range m.plugins {
+		ret, err := m.call(p, hookBeforePaste, func(L *lua.LState) []lua.LValue {
+			t := L.NewTable()
+			t.RawSetString("title", lua.LString(target.Title))
+			t.RawSetString("process", lua.LString(target.Process))
+			return []lua.LValue{itemTable(L, item), t}
+		})
+		if err != nil {
+			logger.Warn("Плагин %s: %s: %v", p.name, hookBeforePaste, err)
+			continue
+		}
+		switch v := ret.(type) {
+		case lua.LBool:
+			if !bool(v) {
+				logger.Info("Плагин %s отменил вставку элемента %s", p.name, item.ID)
+				return item, false
+			}
+		case lua.LString:
+			item.Type, item.Text, item.Files = "Text", string(v), nil
+		}
+	}
+	return item, true
+}
+
+// RunAction выполняет действие, зарегистрированное через clipqueue.action(name, fn).
+// Функция получает text макроса; ошибка скрипта возвращается вызывающему.
+func (m *Manager) RunAction(name, text string) error {
+	for _, p := range m.plugins {
+		p.mu.Lock()
+		fn, ok := p.actions[name]
+		p.mu.Unlock()
+		if !ok {
+			continue
+		}
+		_, err := m.callFunc(p, fn, func(L *lua.LState) []lua.LValue {
+			return []lua.LValue{lua.LString(text)}
+		})
+		if err != nil {
+			return fmt.Errorf("плагин %s, действие %s: %w", p.name, name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownAction, name)
+}
+
+// call вызывает глобальную функцию hook, если скрипт её объявил. Без функции
+// возвращается LNil без ошибки.
+func (m *Manager) call(p *plugin, hook string, args func(L *lua.LState) []lua.LValue) (lua.LValue, error) {
+	p.mu.Lock()
+	fn, ok := p.state.GetGlobal(hook).(*lua.LFunction)
+	p.mu.Unlock()
+	if !ok {
+		return lua.LNil, nil
+	}
+	return m.callFunc(p, fn, args)
+}
+
+func (m *Manager) callFunc(p *plugin, fn *lua.LFunction, args func(L *lua.LState) []lua.LValue) (lua.LValue, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	L := p.state
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args(L)...); err != nil {
+		return lua.LNil, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	return ret, nil
+}
+
+func itemTable(L *lua.LState, item Item) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("id", lua.LString(item.ID))
+	t.RawSetString("type", lua.LString(item.Type))
+	t.RawSetString("text", lua.LString(item.Text))
+	t.RawSetString("preview", lua.LString(item.Preview))
+	t.RawSetString("timestamp", lua.LNumber(item.Timestamp.Unix()))
+	files := L.NewTable()
+	for _, f := range item.Files {
+		files.Append(lua.LString(f))
+	}
+	t.RawSetString("files", files)
+	return t
+}
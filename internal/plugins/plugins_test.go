@@ -0,0 +1,102 @@
+package plugins
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeHost struct {
+	pushed []string
+}
+
+func (h *fakeHost) PushText(text string) (string, error) {
+	h.pushed = append(h.pushed, text)
+	return "id-" + text, nil
+}
+func (h *fakeHost) CopyText(string) error { return nil }
+func (h *fakeHost) Notify(string, string) {}
+
+func loadScripts(t *testing.T, host Host, scripts map[string]string) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := Load(dir, host, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestCaptureHooks(t *testing.T) {
+	m := loadScripts(t, &fakeHost{}, map[string]string{
+		"a_trim.lua": `function transform(text) return (text:gsub("^%s+", ""):gsub("%s+$", "")) end`,
+		"b_filter.lua": `function on_capture(item)
+			if item.text:find("password") then return false end
+		end`,
+		"c_broken.lua": `this is not lua`,
+	})
+	if names := m.Names(); strings.Join(names, ",") != "a_trim,b_filter" {
+		t.Fatalf("загружены %v, сломанный скрипт должен пропускаться", names)
+	}
+
+	item, keep := m.Capture(Item{ID: "1", Type: "Text", Text: "  hello  "})
+	if !keep || item.Text != "hello" {
+		t.Fatalf("Capture = %+v, %v", item, keep)
+	}
+	if _, keep := m.Capture(Item{ID: "2", Type: "Text", Text: "my password"}); keep {
+		t.Fatal("on_capture вернул false, элемент должен отбрасываться")
+	}
+	if item, keep := m.Capture(Item{ID: "3", Type: "Image"}); !keep || item.Text != "" {
+		t.Fatal("transform не применяется к изображениям")
+	}
+}
+
+func TestBeforePaste(t *testing.T) {
+	m := loadScripts(t, &fakeHost{}, map[string]string{
+		"paste.lua": `function on_before_paste(item, target)
+			if target.process == "cmd.exe" then return false end
+			if target.process == "notepad.exe" then return item.text:upper() end
+		end`,
+	})
+	if _, ok := m.BeforePaste(Item{Type: "Text", Text: "x"}, Target{Process: "cmd.exe"}); ok {
+		t.Fatal("вставка в cmd.exe должна отменяться")
+	}
+	item, ok := m.BeforePaste(Item{Type: "Text", Text: "abc"}, Target{Process: "notepad.exe"})
+	if !ok || item.Text != "ABC" {
+		t.Fatalf("BeforePaste = %+v, %v", item, ok)
+	}
+}
+
+func TestActionsAndSandbox(t *testing.T) {
+	host := &fakeHost{}
+	m := loadScripts(t, host, map[string]string{
+		"act.lua": `clipqueue.action("twice", function(text) clipqueue.push(text .. text) end)
+			clipqueue.action("spin", function() while true do end end)
+			clipqueue.action("sandbox", function()
+				assert(io == nil and os.execute == nil and dofile == nil, "небезопасные функции доступны")
+				assert(os.time() > 0)
+			end)`,
+	})
+	if err := m.RunAction("twice", "ab"); err != nil || len(host.pushed) != 1 || host.pushed[0] != "abab" {
+		t.Fatalf("twice: %v, %v", err, host.pushed)
+	}
+	if err := m.RunAction("sandbox", ""); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := m.RunAction("spin", ""); err == nil || time.Since(start) > 2*time.Second {
+		t.Fatalf("бесконечный цикл должен прерываться по таймауту: %v", err)
+	}
+	if err := m.RunAction("missing", ""); !errors.Is(err, ErrUnknownAction) {
+		t.Fatalf("неизвестное действие: %v", err)
+	}
+}
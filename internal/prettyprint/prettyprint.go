@@ -0,0 +1,185 @@
+// Package prettyprint распознаёт в тексте JSON или XML и переформатирует его
+// с отступами.
+package prettyprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format — распознанный формат текста.
+type Format string
+
+const (
+	JSON Format = "json"
+	XML  Format = "xml"
+)
+
+// indent — отступ одного уровня вложенности.
+const indent = "  "
+
+var (
+	// ErrUnknownFormat возвращается, если текст не похож ни на JSON, ни на XML.
+	ErrUnknownFormat = errors.New("текст не похож на JSON или XML")
+	// ErrInvalid возвращается, если текст похож на JSON или XML, но не разбирается.
+	ErrInvalid = errors.New("текст не разбирается")
+)
+
+// Detect определяет формат по первому значащему символу: { или [ — JSON, < — XML.
+func Detect(text string) Format {
+	switch trimmed := strings.TrimSpace(text); {
+	case strings.HasPrefix(trimmed, "{"), strings.HasPrefix(trimmed, "["):
+		return JSON
+	case strings.HasPrefix(trimmed, "<"):
+		return XML
+	}
+	return ""
+}
+
+// Pretty переформатирует JSON или XML с отступом в два пробела. Порядок ключей
+// JSON и запись чисел сохраняются.
+func Pretty(text string) (string, Format, error) {
+	format := Detect(text)
+	var (
+		out string
+		err error
+	)
+	switch format {
+	case JSON:
+		out, err = prettyJSON(text)
+	case XML:
+		out, err = prettyXML(text)
+	default:
+		return "", "", ErrUnknownFormat
+	}
+	if err != nil {
+		return "", format, fmt.Errorf("%w как %s: %v", ErrInvalid, strings.ToUpper(string(format)), err)
+	}
+	return out, format, nil
+}
+
+func prettyJSON(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(text)), "", indent); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;")
+)
+
+// prettyXML расставляет элементы XML по строкам с отступами. Токены читаются
+// без разрешения пространств имён, поэтому префиксы остаются как в исходном
+// тексте. Пробелы между элементами отбрасываются, текст элемента без дочерних
+// элементов остаётся на строке открывающего тега.
+func prettyXML(text string) (string, error) {
+	dec := xml.NewDecoder(strings.NewReader(strings.TrimSpace(text)))
+	var (
+		buf     strings.Builder
+		open    []string          // Имена открытых элементов: RawToken не проверяет парность тегов
+		pending *xml.StartElement // Открытый тег ещё не записан: может оказаться пустым
+		inline  bool              // После открывающего тега записан текст
+	)
+	newline := func() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(indent, len(open)))
+	}
+	flush := func(selfClose bool) {
+		if pending == nil {
+			return
+		}
+		newline()
+		buf.WriteString("<" + qualified(pending.Name))
+		for _, attr := range pending.Attr {
+			buf.WriteString(" " + qualified(attr.Name) + `="` + attrEscaper.Replace(attr.Value) + `"`)
+		}
+		if selfClose {
+			buf.WriteString("/>")
+		} else {
+			buf.WriteString(">")
+			open = append(open, qualified(pending.Name))
+		}
+		pending = nil
+	}
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			flush(false)
+			start := t.Copy()
+			pending, inline = &start, false
+		case xml.EndElement:
+			name := qualified(t.Name)
+			if pending != nil && qualified(pending.Name) == name {
+				flush(true)
+				continue
+			}
+			flush(false)
+			if len(open) == 0 || open[len(open)-1] != name {
+				return "", fmt.Errorf("лишний закрывающий тег </%s>", name)
+			}
+			open = open[:len(open)-1]
+			if !inline {
+				newline()
+			}
+			buf.WriteString("</" + name + ">")
+			inline = false
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			wasPending := pending != nil
+			flush(false)
+			if !wasPending && !inline {
+				newline()
+			}
+			buf.WriteString(textEscaper.Replace(string(t)))
+			inline = true
+		case xml.Comment:
+			flush(false)
+			newline()
+			buf.WriteString("<!--" + string(t) + "-->")
+			inline = false
+		case xml.ProcInst:
+			flush(false)
+			newline()
+			buf.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+			inline = false
+		case xml.Directive:
+			flush(false)
+			newline()
+			buf.WriteString("<!" + string(t) + ">")
+			inline = false
+		}
+	}
+	if pending != nil {
+		open = append(open, qualified(pending.Name))
+	}
+	if len(open) > 0 {
+		return "", fmt.Errorf("не закрыт элемент <%s>", open[len(open)-1])
+	}
+	return buf.String(), nil
+}
+
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
@@ -0,0 +1,45 @@
+package prettyprint
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	got, format, err := Pretty(` {"b":1,"a":[1.50,true,null],"c":{}} `)
+	want := "{\n  \"b\": 1,\n  \"a\": [\n    1.50,\n    true,\n    null\n  ],\n  \"c\": {}\n}"
+	if err != nil || format != JSON || got != want {
+		t.Fatalf("Pretty: %q (%s), ошибка %v; ожидалось %q", got, format, err, want)
+	}
+	if _, _, err := Pretty(`{"a":}`); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("для битого JSON ожидалась ErrInvalid, получено %v", err)
+	}
+}
+
+func TestPrettyXML(t *testing.T) {
+	in := `<?xml version="1.0"?><soap:Envelope xmlns:soap="urn:x"><!-- c --><soap:Body><item id="1" name="a &amp; b"/><name>Tom &lt;3</name><empty></empty></soap:Body></soap:Envelope>`
+	want := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="urn:x">
+  <!-- c -->
+  <soap:Body>
+    <item id="1" name="a &amp; b"/>
+    <name>Tom &lt;3</name>
+    <empty/>
+  </soap:Body>
+</soap:Envelope>`
+	got, format, err := Pretty(in)
+	if err != nil || format != XML || got != want {
+		t.Fatalf("Pretty:\n%s\n(%s), ошибка %v; ожидалось:\n%s", got, format, err, want)
+	}
+	for _, bad := range []string{"<a><b></a>", "<a></b>", "<a>", "<3 не xml"} {
+		if _, _, err := Pretty(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Pretty(%q): ожидалась ErrInvalid, получено %v", bad, err)
+		}
+	}
+}
+
+func TestPrettyUnknownFormat(t *testing.T) {
+	if _, _, err := Pretty("просто текст"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("ожидалась ErrUnknownFormat, получено %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package sensitive распознаёт в скопированном тексте вероятные секреты —
+// номера банковских карт, JWT и закрытые ключи — и строит для них
+// маскированный предпросмотр.
+package sensitive
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind — вид найденного секрета. Пустое значение — секрет не найден.
+type Kind string
+
+const (
+	Card       Kind = "card"        // Номер банковской карты, прошедший проверку Луна
+	JWT        Kind = "jwt"         // JSON Web Token
+	PrivateKey Kind = "private_key" // Закрытый ключ в формате PEM, OpenSSH или PGP
+)
+
+var (
+	privateKeyHeader = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`)
+	// Заголовок и данные JWT — JSON в base64url, поэтому оба начинаются с eyJ ("{\"").
+	jwtToken = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`)
+	// Номер карты: 13–19 цифр, группы могут разделяться пробелом или дефисом.
+	cardCandidate = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Detect возвращает вид первого найденного в тексте секрета. Закрытый ключ
+// проверяется первым: внутри него могут встречаться длинные цифровые фрагменты.
+func Detect(text string) Kind {
+	if privateKeyHeader.MatchString(text) {
+		return PrivateKey
+	}
+	if jwtToken.MatchString(text) {
+		return JWT
+	}
+	for _, candidate := range cardCandidate.FindAllString(text, -1) {
+		if isCardNumber(stripSeparators(candidate)) {
+			return Card
+		}
+	}
+	return ""
+}
+
+// MaskPreview возвращает предпросмотр, в котором не видно сам секрет.
+// У карты оставляются последние четыре цифры, как на чеках.
+func MaskPreview(kind Kind, text string) string {
+	switch kind {
+	case Card:
+		for _, candidate := range cardCandidate.FindAllString(text, -1) {
+			if digits := stripSeparators(candidate); isCardNumber(digits) {
+				return "Карта •••• " + digits[len(digits)-4:]
+			}
+		}
+		return "Карта ••••"
+	case JWT:
+		return "JWT ••••••"
+	case PrivateKey:
+		return "Закрытый ключ ••••••"
+	}
+	return "••••••"
+}
+
+func stripSeparators(s string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(s)
+}
+
+// isCardNumber проверяет контрольную сумму Луна. Номера из одной повторяющейся
+// цифры (0000…) проходят проверку, но картами не бывают.
+func isCardNumber(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 || strings.Count(digits, digits[:1]) == len(digits) {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,162 @@
+// Package shell выполняет командные строки через cmd.exe или PowerShell с
+// таймаутом, ограничением вывода и урезанным окружением. Используется
+// преобразованиями буфера и выполнением пайплайнов раздела Lab.
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Dialect — командная оболочка, которой передаётся строка.
+type Dialect string
+
+const (
+	Cmd        Dialect = "cmd"
+	PowerShell Dialect = "powershell"
+)
+
+// DefaultTimeout ограничивает выполнение, если в Options не задано иное.
+const DefaultTimeout = 10 * time.Second
+
+// DefaultMaxOutput ограничивает stdout и stderr по отдельности.
+const DefaultMaxOutput = 1 << 20
+
+// ParseDialect проверяет название оболочки; пустое значение означает cmd.
+func ParseDialect(s string) (Dialect, error) {
+	switch d := Dialect(strings.ToLower(s)); d {
+	case "":
+		return Cmd, nil
+	case Cmd, PowerShell:
+		return d, nil
+	}
+	return "", fmt.Errorf("неизвестная оболочка %q: допустимы cmd и powershell", s)
+}
+
+// Options — параметры запуска.
+type Options struct {
+	Dialect   Dialect
+	Input     string        // Передаётся на stdin
+	Timeout   time.Duration // 0 — DefaultTimeout
+	Dir       string        // Рабочий каталог; пустой — текущий каталог процесса
+	Env       []string      // nil — окружение процесса целиком, см. SandboxEnv
+	MaxOutput int           // 0 — DefaultMaxOutput
+}
+
+// Result — итог выполнения. Ненулевой код выхода и таймаут не считаются ошибкой Run.
+type Result struct {
+	Stdout    string        `json:"stdout"`
+	Stderr    string        `json:"stderr"`
+	ExitCode  int           `json:"exitCode"`
+	TimedOut  bool          `json:"timedOut"`
+	Truncated bool          `json:"truncated"` // Вывод обрезан по MaxOutput
+	Duration  time.Duration `json:"durationNs"`
+}
+
+// sandboxVars — переменные окружения, без которых не работают типичные утилиты.
+// Остальные (токены, пароли, переменные других программ) в песочницу не попадают.
+var sandboxVars = []string{
+	"PATH", "PATHEXT", "SystemRoot", "SystemDrive", "windir", "ComSpec",
+	"TEMP", "TMP", "TMPDIR", "HOME", "USERPROFILE", "HOMEDRIVE", "HOMEPATH",
+	"APPDATA", "LOCALAPPDATA", "ProgramData", "ProgramFiles", "ProgramFiles(x86)",
+	"ProgramW6432", "CommonProgramFiles", "CommonProgramFiles(x86)", "PSModulePath",
+	"NUMBER_OF_PROCESSORS", "PROCESSOR_ARCHITECTURE", "OS", "USERNAME", "COMPUTERNAME", "LANG",
+}
+
+// SandboxEnv возвращает окружение процесса, в котором оставлены только системные
+// переменные из sandboxVars. Регистр имён не учитывается, как в Windows.
+func SandboxEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			continue
+		}
+		for _, allowed := range sandboxVars {
+			if strings.EqualFold(name, allowed) {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
+
+// Run выполняет command. Ошибка возвращается, только если процесс не удалось
+// запустить; по таймауту процесс завершается вместе с дочерними, а в Result
+// выставляется TimedOut.
+func Run(ctx context.Context, command string, opts Options) (Result, error) {
+	if strings.TrimSpace(command) == "" {
+		return Result{}, errors.New("команда не задана")
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = Cmd
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxOutput <= 0 {
+		opts.MaxOutput = DefaultMaxOutput
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	cmd, err := shellCommand(ctx, opts.Dialect, command)
+	if err != nil {
+		return Result{}, err
+	}
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	cmd.Stdin = strings.NewReader(opts.Input)
+	stdout := &limitedBuffer{max: opts.MaxOutput}
+	stderr := &limitedBuffer{max: opts.MaxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Без WaitDelay дочерние процессы оболочки могут держать stdout и после таймаута.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	res := Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  cmd.ProcessState.ExitCode(),
+		TimedOut:  ctx.Err() == context.DeadlineExceeded,
+		Truncated: stdout.truncated || stderr.truncated,
+		Duration:  time.Since(start),
+	}
+	if err != nil && !res.TimedOut {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+			return res, fmt.Errorf("не удалось запустить %q: %w", command, err)
+		}
+	}
+	return res, nil
+}
+
+// limitedBuffer сохраняет не больше max байт, остальное отбрасывает, не прерывая процесс.
+// bytes.Buffer не встраивается: его ReadFrom позволил бы io.Copy обойти Write.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string { return b.buf.String() }
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// shellCommand запускает строку через /bin/sh или pwsh; нужен для тестов и сборки вне Windows.
+func shellCommand(ctx context.Context, dialect Dialect, command string) (*exec.Cmd, error) {
+	switch dialect {
+	case Cmd:
+		return exec.CommandContext(ctx, "/bin/sh", "-c", command), nil
+	case PowerShell:
+		return exec.CommandContext(ctx, "pwsh", "-NoProfile", "-NonInteractive", "-Command", command), nil
+	}
+	return nil, fmt.Errorf("неизвестная оболочка %q", dialect)
+}
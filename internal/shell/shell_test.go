@@ -0,0 +1,70 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// helperCommand запускает этот же тестовый бинарник в роли внешней программы,
+// поэтому тест не зависит от утилит ОС.
+func helperCommand(t *testing.T, mode string) string {
+	t.Helper()
+	t.Setenv("CLIPQUEUE_SHELL_HELPER", mode)
+	return fmt.Sprintf(`"%s" -test.run=^TestHelperProcess$`, os.Args[0])
+}
+
+func TestHelperProcess(t *testing.T) {
+	switch os.Getenv("CLIPQUEUE_SHELL_HELPER") {
+	case "":
+		return
+	case "big":
+		fmt.Print(strings.Repeat("x", 100))
+		fmt.Fprint(os.Stderr, "warn")
+		os.Exit(2)
+	case "env":
+		fmt.Print(os.Getenv("CLIPQUEUE_SECRET"))
+	}
+	os.Exit(0)
+}
+
+func TestRunResult(t *testing.T) {
+	res, err := Run(context.Background(), helperCommand(t, "big"), Options{MaxOutput: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ExitCode != 2 || res.Stdout != "xxxxxxxxxx" || !res.Truncated || res.Stderr != "warn" || res.TimedOut {
+		t.Fatalf("результат %+v", res)
+	}
+}
+
+func TestSandboxEnv(t *testing.T) {
+	t.Setenv("CLIPQUEUE_SECRET", "token")
+	command := helperCommand(t, "env")
+	env := SandboxEnv()
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "CLIPQUEUE_SECRET=") {
+			t.Fatal("посторонняя переменная попала в песочницу")
+		}
+	}
+	// Переменная режима вспомогательного процесса нужна ему самому.
+	env = append(env, "CLIPQUEUE_SHELL_HELPER=env")
+	res, err := Run(context.Background(), command, Options{Env: env})
+	if err != nil || res.ExitCode != 0 || res.Stdout != "" {
+		t.Fatalf("результат %+v, %v", res, err)
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	if d, err := ParseDialect(""); err != nil || d != Cmd {
+		t.Fatalf("ParseDialect(\"\") = %q, %v", d, err)
+	}
+	if d, err := ParseDialect("PowerShell"); err != nil || d != PowerShell {
+		t.Fatalf("ParseDialect(PowerShell) = %q, %v", d, err)
+	}
+	if _, err := ParseDialect("bash"); err == nil {
+		t.Fatal("неизвестная оболочка должна отклоняться")
+	}
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+const createNoWindow = 0x08000000
+
+// shellCommand строит запуск без окна консоли. Для cmd строка передаётся через
+// CmdLine как есть (CmdLine заменяет всю командную строку, включая имя программы),
+// поэтому работают кавычки, конвейеры и .cmd-обёртки (prettier, npx). Обе оболочки
+// переключаются на вывод в UTF-8. При отмене завершается всё дерево процессов.
+func shellCommand(ctx context.Context, dialect Dialect, command string) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	switch dialect {
+	case Cmd:
+		shell := os.Getenv("ComSpec")
+		if shell == "" {
+			shell = filepath.Join(os.Getenv("SystemRoot"), "System32", "cmd.exe")
+		}
+		cmd = exec.CommandContext(ctx, shell)
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			CmdLine: `"` + shell + `" /D /S /C "chcp 65001>nul & ` + command + `"`,
+		}
+	case PowerShell:
+		cmd = exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+			"[Console]::OutputEncoding=[Text.Encoding]::UTF8; "+command)
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	default:
+		return nil, fmt.Errorf("неизвестная оболочка %q", dialect)
+	}
+	cmd.SysProcAttr.HideWindow = true
+	cmd.SysProcAttr.CreationFlags = createNoWindow
+	cmd.Cancel = func() error {
+		// taskkill /T завершает и дочерние процессы оболочки; Kill — запасной вариант.
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		kill.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	return cmd, nil
+}
@@ -0,0 +1,50 @@
+package snippets
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrMissingField возвращается, если при вставке не заполнено поле сниппета.
+var ErrMissingField = errors.New("не заполнены поля сниппета")
+
+// fieldPattern находит поле для заполнения: {{field:Имя}}.
+var fieldPattern = regexp.MustCompile(`\{\{\s*field:\s*([^{}]*?)\s*\}\}`)
+
+// ParseFields возвращает имена полей {{field:Имя}} в порядке первого появления
+// в тексте; повторное поле с тем же именем заполняется одним значением.
+func ParseFields(body string) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, m := range fieldPattern.FindAllStringSubmatch(body, -1) {
+		if name := m[1]; name != "" && !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// Fill подставляет значения полей в текст сниппета. Пустое значение допустимо,
+// но каждое поле должно присутствовать в values, иначе возвращается ErrMissingField
+// с именами незаполненных полей.
+func Fill(body string, values map[string]string) (string, error) {
+	var missing []string
+	for _, name := range ParseFields(body) {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingField, strings.Join(missing, ", "))
+	}
+	return fieldPattern.ReplaceAllStringFunc(body, func(m string) string {
+		name := fieldPattern.FindStringSubmatch(m)[1]
+		if name == "" {
+			return m
+		}
+		return values[name]
+	}), nil
+}
@@ -0,0 +1,106 @@
+package snippets
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Веса полей в нечётком поиске: совпадение в заголовке важнее, чем в тексте.
+const (
+	weightTitle  = 3
+	weightFolder = 2
+	weightBody   = 1
+)
+
+// SearchResult — сниппет, найденный нечётким поиском, и его релевантность.
+type SearchResult struct {
+	Snippet
+	Score int `json:"score"`
+}
+
+// Search ищет сниппеты нечётким совпадением: каждое слово запроса должно найтись
+// как подпоследовательность букв в заголовке, папке, теге или одном из слов текста
+// («пдп» находит «Подпись»). Результаты упорядочены по убыванию релевантности;
+// limit <= 0 — без ограничения. Пустой запрос возвращает всю библиотеку.
+func (s *Store) Search(query string, limit int) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+	var results []SearchResult
+	for _, snippet := range s.List() {
+		score, ok := matchSnippet(snippet, terms)
+		if ok {
+			results = append(results, SearchResult{Snippet: snippet, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchSnippet складывает лучшие оценки слов запроса; сниппет подходит, только
+// если нашлось каждое слово.
+func matchSnippet(snippet Snippet, terms []string) (int, bool) {
+	total := 0
+	for _, term := range terms {
+		best := fuzzyScore(term, snippet.Title) * weightTitle
+		best = max(best, fuzzyScore(term, snippet.Category)*weightFolder)
+		for _, tag := range snippet.Tags {
+			best = max(best, fuzzyScore(term, tag)*weightFolder)
+		}
+		// Текст сравнивается по словам: подпоследовательность по всему длинному
+		// тексту нашлась бы почти для любого запроса.
+		for _, word := range strings.FieldsFunc(snippet.Body, isSeparator) {
+			best = max(best, fuzzyScore(term, word)*weightBody)
+		}
+		if best == 0 {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
+
+// fuzzyScore оценивает, насколько хорошо pattern (в нижнем регистре) встречается
+// в text как подпоследовательность; 0 — не встречается. Бонусы получают подряд
+// идущие буквы и буквы в начале слова, пропуски между буквами штрафуются.
+// Перебираются все начальные позиции, из них берётся лучшая.
+func fuzzyScore(pattern, text string) int {
+	p := []rune(pattern)
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 || len(p) > len(t) {
+		return 0
+	}
+	best := 0
+	for start := range t {
+		if t[start] != p[0] {
+			continue
+		}
+		score, prev, pi := 0, -1, 0
+		for ti := start; ti < len(t) && pi < len(p); ti++ {
+			if t[ti] != p[pi] {
+				continue
+			}
+			score += 2
+			switch {
+			case prev == ti-1:
+				score += 3
+			case prev >= 0:
+				score -= min(ti-prev-1, 3)
+			}
+			if ti == 0 || isSeparator(t[ti-1]) {
+				score += 2
+			}
+			prev, pi = ti, pi+1
+		}
+		if pi == len(p) {
+			best = max(best, max(score, 1))
+		}
+	}
+	return best
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
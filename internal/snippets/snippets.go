@@ -0,0 +1,268 @@
+// Package snippets хранит библиотеку часто используемых текстов: в отличие от
+// макросов, сниппету не нужен хоткей — он вставляется по ID из UI или API.
+// Сниппеты раскладываются по вложенным папкам и помечаются тегами, а нечёткий
+// поиск по заголовкам и текстам позволяет быстро выбрать нужный.
+package snippets
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName — файл библиотеки внутри app.data_dir.
+const FileName = "snippets.json"
+
+var (
+	// ErrNotFound возвращается, если сниппета с таким ID нет.
+	ErrNotFound = errors.New("сниппет не найден")
+	// ErrInvalid возвращается для сниппета без заголовка или текста.
+	ErrInvalid = errors.New("у сниппета должны быть заголовок и текст")
+)
+
+// Snippet — именованный текст в библиотеке.
+type Snippet struct {
+	ID       string    `json:"id"`
+	Category string    `json:"category,omitempty"` // Путь папки через «/», например «Работа/Почта»; пустой — корень
+	Tags     []string  `json:"tags,omitempty"`
+	Title    string    `json:"title"`
+	Body     string    `json:"body"`
+	Fields   []string  `json:"fields,omitempty"` // Поля {{field:Имя}} из текста, заполняются перед вставкой
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Store — библиотека сниппетов в JSON-файле. Каждое изменение сразу сохраняется.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	snippets []Snippet
+}
+
+// Open читает библиотеку из файла path; отсутствующий файл — пустая библиотека.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("не удалось прочитать библиотеку сниппетов: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.snippets); err != nil {
+		return s, fmt.Errorf("повреждён файл сниппетов %s: %w", path, err)
+	}
+	for i := range s.snippets {
+		s.snippets[i].Fields = ParseFields(s.snippets[i].Body)
+	}
+	return s, nil
+}
+
+// List возвращает сниппеты, упорядоченные по категории и заголовку.
+func (s *Store) List() []Snippet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := append([]Snippet(nil), s.snippets...)
+	sort.SliceStable(list, func(i, j int) bool {
+		ci, cj := strings.ToLower(list[i].Category), strings.ToLower(list[j].Category)
+		if ci != cj {
+			return ci < cj
+		}
+		return strings.ToLower(list[i].Title) < strings.ToLower(list[j].Title)
+	})
+	return list
+}
+
+// Get возвращает сниппет по ID.
+func (s *Store) Get(id string) (Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.indexLocked(id); i >= 0 {
+		return s.snippets[i], nil
+	}
+	return Snippet{}, fmt.Errorf("%w: id %s", ErrNotFound, id)
+}
+
+// Create добавляет сниппет с новым ID и возвращает его.
+func (s *Store) Create(snippet Snippet) (Snippet, error) {
+	snippet, err := normalize(snippet)
+	if err != nil {
+		return Snippet{}, err
+	}
+	snippet.ID = newID()
+	snippet.Created = time.Now()
+	snippet.Updated = snippet.Created
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snippets = append(s.snippets, snippet)
+	if err := s.saveLocked(); err != nil {
+		s.snippets = s.snippets[:len(s.snippets)-1]
+		return Snippet{}, err
+	}
+	return snippet, nil
+}
+
+// Update заменяет категорию, заголовок и текст сниппета id.
+func (s *Store) Update(id string, snippet Snippet) (Snippet, error) {
+	snippet, err := normalize(snippet)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexLocked(id)
+	if i < 0 {
+		return Snippet{}, fmt.Errorf("%w: id %s", ErrNotFound, id)
+	}
+	previous := s.snippets[i]
+	snippet.ID, snippet.Created, snippet.Updated = id, previous.Created, time.Now()
+	s.snippets[i] = snippet
+	if err := s.saveLocked(); err != nil {
+		s.snippets[i] = previous
+		return Snippet{}, err
+	}
+	return snippet, nil
+}
+
+// Delete удаляет сниппет id.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexLocked(id)
+	if i < 0 {
+		return fmt.Errorf("%w: id %s", ErrNotFound, id)
+	}
+	previous := s.snippets
+	s.snippets = append(s.snippets[:i:i], s.snippets[i+1:]...)
+	if err := s.saveLocked(); err != nil {
+		s.snippets = previous
+		return err
+	}
+	return nil
+}
+
+func (s *Store) indexLocked(id string) int {
+	for i, snippet := range s.snippets {
+		if snippet.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveLocked записывает библиотеку во временный файл и переименовывает его,
+// чтобы сбой посреди записи не испортил файл. Предполагает, что мьютекс захвачен.
+func (s *Store) saveLocked() error {
+	data, err := json.MarshalIndent(s.snippets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать сниппеты: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("не удалось создать каталог сниппетов: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("не удалось сохранить сниппеты: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("не удалось сохранить сниппеты: %w", err)
+	}
+	return nil
+}
+
+// normalize приводит путь папки к виду «a/b», убирает пустые и повторные теги,
+// обрезает пробелы у заголовка и находит поля в тексте; текст сохраняется как есть.
+func normalize(snippet Snippet) (Snippet, error) {
+	snippet.Category = CleanFolder(snippet.Category)
+	snippet.Tags = cleanTags(snippet.Tags)
+	snippet.Title = strings.TrimSpace(snippet.Title)
+	if snippet.Title == "" || snippet.Body == "" {
+		return Snippet{}, ErrInvalid
+	}
+	snippet.Fields = ParseFields(snippet.Body)
+	return snippet, nil
+}
+
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// CleanFolder приводит путь папки к виду «a/b»: обрезает пробелы у частей,
+// пропускает пустые части и принимает «\» как разделитель.
+func CleanFolder(folder string) string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(folder, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// InFolder сообщает, что сниппет лежит в папке folder или в одной из её вложенных.
+// Пустая папка — корень, в ней лежат все сниппеты.
+func (s Snippet) InFolder(folder string) bool {
+	folder = strings.ToLower(CleanFolder(folder))
+	category := strings.ToLower(s.Category)
+	return folder == "" || category == folder || strings.HasPrefix(category, folder+"/")
+}
+
+// HasTag сообщает, что у сниппета есть тег (без учёта регистра).
+func (s Snippet) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(t, strings.TrimSpace(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Folders возвращает все папки библиотеки вместе с родительскими: для сниппета
+// в «Работа/Почта» это «Работа» и «Работа/Почта».
+func (s *Store) Folders() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool)
+	var folders []string
+	for _, snippet := range s.snippets {
+		parts := strings.Split(snippet.Category, "/")
+		for i := range parts {
+			folder := strings.Join(parts[:i+1], "/")
+			if folder == "" || seen[strings.ToLower(folder)] {
+				continue
+			}
+			seen[strings.ToLower(folder)] = true
+			folders = append(folders, folder)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool { return strings.ToLower(folders[i]) < strings.ToLower(folders[j]) })
+	return folders
+}
+
+func cleanTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		out = append(out, tag)
+	}
+	return out
+}
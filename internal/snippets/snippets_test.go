@@ -0,0 +1,138 @@
+package snippets
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreCRUDPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	sig, err := store.Create(Snippet{Category: " Почта ", Title: "Подпись", Body: "С уважением,\nИван"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if sig.ID == "" || sig.Category != "Почта" || sig.Created.IsZero() {
+		t.Fatalf("неожиданный сниппет: %+v", sig)
+	}
+	addr, _ := store.Create(Snippet{Title: "Адрес", Body: "Москва"})
+	if _, err := store.Create(Snippet{Title: "Пустой"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("ожидалась ErrInvalid, получено %v", err)
+	}
+
+	updated, err := store.Update(sig.ID, Snippet{Category: "Почта", Title: "Подпись", Body: "Спасибо"})
+	if err != nil || updated.Body != "Спасибо" || !updated.Created.Equal(sig.Created) {
+		t.Fatalf("Update: %+v, %v", updated, err)
+	}
+	if err := store.Delete(addr.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(addr.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ожидалась ErrNotFound, получено %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	list := reopened.List()
+	if len(list) != 1 || list[0].ID != sig.ID || list[0].Body != "Спасибо" {
+		t.Fatalf("библиотека не сохранилась: %+v", list)
+	}
+}
+
+func TestListOrdersByCategoryAndTitle(t *testing.T) {
+	store, _ := Open(filepath.Join(t.TempDir(), FileName))
+	store.Create(Snippet{Category: "b", Title: "x", Body: "1"})
+	store.Create(Snippet{Category: "a", Title: "Я", Body: "2"})
+	store.Create(Snippet{Category: "a", Title: "а", Body: "3"})
+	list := store.List()
+	if list[0].Body != "3" || list[1].Body != "2" || list[2].Body != "1" {
+		t.Fatalf("неожиданный порядок: %+v", list)
+	}
+}
+
+func TestFoldersAndTags(t *testing.T) {
+	store, _ := Open(filepath.Join(t.TempDir(), FileName))
+	mail, err := store.Create(Snippet{Category: " Работа \\ Почта/ ", Tags: []string{"ответ", " Ответ", ""}, Title: "Подпись", Body: "1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if mail.Category != "Работа/Почта" || len(mail.Tags) != 1 {
+		t.Fatalf("папка или теги не нормализованы: %+v", mail)
+	}
+	store.Create(Snippet{Category: "Личное", Title: "Адрес", Body: "2"})
+
+	folders := store.Folders()
+	if len(folders) != 3 || folders[0] != "Личное" || folders[1] != "Работа" || folders[2] != "Работа/Почта" {
+		t.Fatalf("неожиданные папки: %v", folders)
+	}
+	if !mail.InFolder("работа") || !mail.InFolder("Работа/Почта") || mail.InFolder("Работ") || !mail.InFolder("") {
+		t.Fatal("InFolder должен учитывать вложенные папки целиком")
+	}
+	if !mail.HasTag("ОТВЕТ") || mail.HasTag("адрес") {
+		t.Fatal("HasTag должен сравнивать теги без учёта регистра")
+	}
+}
+
+func TestSearchFuzzy(t *testing.T) {
+	store, _ := Open(filepath.Join(t.TempDir(), FileName))
+	store.Create(Snippet{Title: "Подпись для писем", Body: "С уважением, Иван"})
+	store.Create(Snippet{Title: "Адрес офиса", Body: "Москва, Тверская 1"})
+	store.Create(Snippet{Category: "Шаблоны", Title: "Отказ", Body: "К сожалению, подписать договор не можем"})
+
+	results := store.Search("пдп", 0)
+	if len(results) != 2 || results[0].Title != "Подпись для писем" {
+		t.Fatalf("заголовок должен быть выше совпадения в тексте: %+v", results)
+	}
+	if results := store.Search("мск твр", 0); len(results) != 1 || results[0].Title != "Адрес офиса" {
+		t.Fatalf("должны учитываться все слова запроса: %+v", results)
+	}
+	if results := store.Search("шаб", 0); len(results) != 1 || results[0].Title != "Отказ" {
+		t.Fatalf("поиск должен учитывать папку: %+v", results)
+	}
+	if results := store.Search("xyz", 0); len(results) != 0 {
+		t.Fatalf("ничего не должно найтись: %+v", results)
+	}
+	if results := store.Search("", 1); len(results) != 1 {
+		t.Fatalf("limit не соблюдён: %+v", results)
+	}
+}
+
+func TestFuzzyScorePrefersContiguous(t *testing.T) {
+	if fuzzyScore("под", "подпись") <= fuzzyScore("под", "пароль доступа") {
+		t.Fatal("подряд идущие буквы должны оцениваться выше")
+	}
+	if fuzzyScore("abc", "ab") != 0 || fuzzyScore("ac", "abc") == 0 {
+		t.Fatal("неверная проверка подпоследовательности")
+	}
+}
+
+func TestFieldsFill(t *testing.T) {
+	body := "Здравствуйте, {{field:Имя}}!\nЗаказ {{ field: Номер }} для {{field:Имя}} готов. {{field:}}"
+	fields := ParseFields(body)
+	if len(fields) != 2 || fields[0] != "Имя" || fields[1] != "Номер" {
+		t.Fatalf("неожиданные поля: %v", fields)
+	}
+	if _, err := Fill(body, map[string]string{"Имя": "Анна"}); !errors.Is(err, ErrMissingField) {
+		t.Fatalf("ожидалась ErrMissingField, получено %v", err)
+	}
+	got, err := Fill(body, map[string]string{"Имя": "Анна", "Номер": ""})
+	if err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+	if want := "Здравствуйте, Анна!\nЗаказ  для Анна готов. {{field:}}"; got != want {
+		t.Fatalf("Fill = %q, ожидалось %q", got, want)
+	}
+
+	store, _ := Open(filepath.Join(t.TempDir(), FileName))
+	snippet, _ := store.Create(Snippet{Title: "Ответ", Body: body})
+	if len(snippet.Fields) != 2 {
+		t.Fatalf("поля не найдены при сохранении: %+v", snippet)
+	}
+}
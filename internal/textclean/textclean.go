@@ -0,0 +1,148 @@
+// Package textclean приводит текст к виду, безопасному для вставки
+// в терминал и код: нормализует Unicode, убирает невидимые управляющие символы,
+// приводит переводы строк и чистит ссылки от параметров отслеживания.
+package textclean
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Form — форма нормализации Unicode. Пустое значение — не нормализовать.
+type Form string
+
+const (
+	NFC  Form = "nfc"  // Каноническая композиция: «е» + U+0308 → «ё»
+	NFKC Form = "nfkc" // Совместимая композиция: ещё и лигатуры, полноширинные символы, «²» → «2»
+)
+
+// Valid сообщает, поддерживается ли форма.
+func (f Form) Valid() bool {
+	return f == "" || f == NFC || f == NFKC
+}
+
+// isInvisible сообщает, относится ли r к символам нулевой ширины или управлению
+// направлением текста: в редакторе их не видно, а в терминале и коде они ломают
+// команды и идентификаторы.
+func isInvisible(r rune) bool {
+	switch {
+	case r >= 0x200B && r <= 0x200F: // Пробел, (не)соединитель нулевой ширины, LRM, RLM
+		return true
+	case r >= 0x202A && r <= 0x202E: // Встраивание и переопределение направления
+		return true
+	case r >= 0x2060 && r <= 0x2064: // Word joiner и невидимые операторы
+		return true
+	case r >= 0x2066 && r <= 0x2069: // Изоляция направления
+		return true
+	}
+	return r == 0xFEFF || r == 0x061C || r == 0x00AD // BOM, арабская метка направления, мягкий перенос
+}
+
+// StripInvisible удаляет из текста символы нулевой ширины и управления направлением.
+func StripInvisible(text string) string {
+	if strings.IndexFunc(text, isInvisible) < 0 {
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// Normalize приводит текст к форме form.
+func Normalize(text string, form Form) string {
+	switch form {
+	case NFC:
+		return norm.NFC.String(text)
+	case NFKC:
+		return norm.NFKC.String(text)
+	}
+	return text
+}
+
+// LineEnding — перевод строки, к которому приводится текст при вставке.
+type LineEnding string
+
+const (
+	KeepLineEndings LineEnding = "keep" // Оставить как есть
+	LF              LineEnding = "lf"   // Unix: терминалы WSL и SSH, большинство редакторов кода
+	CRLF            LineEnding = "crlf" // Windows: Блокнот, старые элементы управления
+)
+
+// Valid сообщает, поддерживается ли перевод строки. Пустое значение равно keep.
+func (e LineEnding) Valid() bool {
+	return e == "" || e == KeepLineEndings || e == LF || e == CRLF
+}
+
+// ConvertLineEndings приводит все переводы строки текста (CRLF, LF и одиночный CR)
+// к ending.
+func ConvertLineEndings(text string, ending LineEnding) string {
+	if ending != LF && ending != CRLF || !strings.ContainsAny(text, "\r\n") {
+		return text
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if ending == CRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return text
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// urlTrailingPunct — знаки препинания, которые после ссылки в тексте
+// («см. https://x.ru/?a=1.») обычно к ней не относятся.
+const urlTrailingPunct = ".,;:!?)]}'\""
+
+// CleanURLs удаляет из ссылок в тексте параметры запроса, имя которых совпадает
+// с одним из шаблонов params (синтаксис path.Match без учёта регистра: utm_*, fbclid).
+// Порядок остальных параметров и их кодирование сохраняются.
+func CleanURLs(text string, params []string) string {
+	if len(params) == 0 || !strings.Contains(text, "://") {
+		return text
+	}
+	return urlPattern.ReplaceAllStringFunc(text, func(link string) string {
+		trimmed := strings.TrimRight(link, urlTrailingPunct)
+		return cleanURL(trimmed, params) + link[len(trimmed):]
+	})
+}
+
+func cleanURL(link string, params []string) string {
+	base, fragment, hasFragment := strings.Cut(link, "#")
+	base, query, hasQuery := strings.Cut(base, "?")
+	if !hasQuery {
+		return link
+	}
+	kept := make([]string, 0, strings.Count(query, "&")+1)
+	for _, pair := range strings.Split(query, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if pair != "" && !matchesAny(strings.ToLower(name), params) {
+			kept = append(kept, pair)
+		}
+	}
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
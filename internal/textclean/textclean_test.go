@@ -0,0 +1,81 @@
+package textclean
+
+import "testing"
+
+func TestStripInvisible(t *testing.T) {
+	cases := []struct {
+		text, want string
+	}{
+		{"git\u200b push", "git push"},
+		{"\ufeffpackage main", "package main"},
+		{"admin\u202e\u2066txt.exe\u2069", "admintxt.exe"},
+		{"пере\u00adнос", "перенос"},
+		{"обычный текст", "обычный текст"},
+	}
+	for _, tc := range cases {
+		if got := StripInvisible(tc.text); got != tc.want {
+			t.Errorf("StripInvisible(%q) = %q, ожидалось %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		text string
+		form Form
+		want string
+	}{
+		{"е\u0308ж", NFC, "ёж"},
+		{"\ufb01le x²", NFC, "\ufb01le x²"},
+		{"\ufb01le x²", NFKC, "file x2"},
+		{"ＡＢＣ", NFKC, "ABC"},
+		{"е\u0308ж", "", "е\u0308ж"},
+	}
+	for _, tc := range cases {
+		if got := Normalize(tc.text, tc.form); got != tc.want {
+			t.Errorf("Normalize(%q, %q) = %q, ожидалось %q", tc.text, tc.form, got, tc.want)
+		}
+	}
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	cases := []struct {
+		text   string
+		ending LineEnding
+		want   string
+	}{
+		{"a\r\nb\nc\rd", LF, "a\nb\nc\nd"},
+		{"a\r\nb\nc", CRLF, "a\r\nb\r\nc"},
+		{"a\r\nb\n", KeepLineEndings, "a\r\nb\n"},
+		{"a\r\nb", "", "a\r\nb"},
+		{"одна строка", LF, "одна строка"},
+	}
+	for _, tc := range cases {
+		if got := ConvertLineEndings(tc.text, tc.ending); got != tc.want {
+			t.Errorf("ConvertLineEndings(%q, %q) = %q, ожидалось %q", tc.text, tc.ending, got, tc.want)
+		}
+	}
+}
+
+func TestCleanURLs(t *testing.T) {
+	params := []string{"utm_*", "fbclid", "GCLID"}
+	cases := []struct {
+		text, want string
+	}{
+		{"https://example.com/a?utm_source=x&id=5&utm_medium=y", "https://example.com/a?id=5"},
+		{"https://example.com/?fbclid=abc", "https://example.com/"},
+		{"https://example.com/?UTM_Source=x&gclid=1#top", "https://example.com/#top"},
+		{"см. https://example.com/p?q=%D0%B0+b&utm_term=z.", "см. https://example.com/p?q=%D0%B0+b."},
+		{"(https://example.com/?a=1&fbclid=2)", "(https://example.com/?a=1)"},
+		{"https://example.com/?utm=1&futm_x=2", "https://example.com/?utm=1&futm_x=2"},
+		{"без ссылок utm_source=x", "без ссылок utm_source=x"},
+	}
+	for _, tc := range cases {
+		if got := CleanURLs(tc.text, params); got != tc.want {
+			t.Errorf("CleanURLs(%q) = %q, ожидалось %q", tc.text, got, tc.want)
+		}
+	}
+	if got := CleanURLs(cases[0].text, nil); got != cases[0].text {
+		t.Errorf("без шаблонов ссылка изменена: %q", got)
+	}
+}
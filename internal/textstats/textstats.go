@@ -0,0 +1,97 @@
+// Package textstats считает символы, слова и строки текста для подписей
+// в истории и подробной статистики выбранного элемента.
+package textstats
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// WordsPerMinute — скорость чтения для оценки ReadingSeconds.
+const WordsPerMinute = 200
+
+// Counts — краткая статистика, которая показывается в списке истории.
+type Counts struct {
+	Chars int // Символы Unicode (руны), включая пробелы и переводы строк
+	Words int // Последовательности символов между пробельными
+	Lines int // Строки; завершающий перевод строки не начинает новую строку
+}
+
+// Stats — подробная статистика текста.
+type Stats struct {
+	Counts
+	CharsNoSpaces  int     // Символы без пробельных
+	Bytes          int     // Размер в UTF-8
+	UTF16Units     int     // Размер в кодовых единицах UTF-16, как его видит Windows
+	BlankLines     int     // Пустые строки и строки только из пробельных символов
+	Paragraphs     int     // Блоки непустых строк, разделённые пустыми
+	LongestLine    int     // Длина самой длинной строки в символах
+	AvgWordLength  float64 // Средняя длина слова в символах
+	ReadingSeconds int     // Оценка времени чтения при WordsPerMinute
+}
+
+// Count считает символы, слова и строки за один проход.
+func Count(text string) Counts {
+	var c Counts
+	if text == "" {
+		return c
+	}
+	inWord := false
+	for _, r := range text {
+		c.Chars++
+		if r == '\n' {
+			c.Lines++
+		}
+		space := unicode.IsSpace(r)
+		if !space && !inWord {
+			c.Words++
+		}
+		inWord = !space
+	}
+	if !strings.HasSuffix(text, "\n") {
+		c.Lines++
+	}
+	return c
+}
+
+// Compute считает подробную статистику текста.
+func Compute(text string) Stats {
+	s := Stats{Counts: Count(text), Bytes: len(text)}
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			s.CharsNoSpaces++
+		}
+		if n := utf16.RuneLen(r); n > 0 {
+			s.UTF16Units += n
+		} else {
+			s.UTF16Units++ // Некорректная последовательность UTF-8 читается как U+FFFD
+		}
+	}
+	if s.Words > 0 {
+		s.AvgWordLength = float64(s.CharsNoSpaces) / float64(s.Words)
+		s.ReadingSeconds = (s.Words*60 + WordsPerMinute - 1) / WordsPerMinute
+	}
+
+	if text == "" {
+		return s
+	}
+	inParagraph := false
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if n := utf8.RuneCountInString(line); n > s.LongestLine {
+			s.LongestLine = n
+		}
+		if strings.TrimSpace(line) == "" {
+			s.BlankLines++
+			inParagraph = false
+			continue
+		}
+		if !inParagraph {
+			s.Paragraphs++
+			inParagraph = true
+		}
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package textstats
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		text string
+		want Counts
+	}{
+		{"", Counts{}},
+		{"one", Counts{Chars: 3, Words: 1, Lines: 1}},
+		{"Привет, мир!\r\n", Counts{Chars: 14, Words: 2, Lines: 1}},
+		{"a  b\n\n c\n", Counts{Chars: 9, Words: 3, Lines: 3}},
+		{"  \t", Counts{Chars: 3, Words: 0, Lines: 1}},
+	}
+	for _, tc := range cases {
+		if got := Count(tc.text); got != tc.want {
+			t.Errorf("Count(%q) = %+v, ожидалось %+v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	s := Compute("Первый абзац\r\nвторая строка\r\n\r\n  \r\nВторой 😀\r\n")
+	want := Stats{
+		Counts:         Counts{Chars: 45, Words: 6, Lines: 5},
+		CharsNoSpaces:  30,
+		Bytes:          77,
+		UTF16Units:     46,
+		BlankLines:     2,
+		Paragraphs:     2,
+		LongestLine:    13,
+		AvgWordLength:  5,
+		ReadingSeconds: 2,
+	}
+	if s != want {
+		t.Fatalf("Compute = %+v\nожидалось  %+v", s, want)
+	}
+}
@@ -0,0 +1,59 @@
+// Package transform пропускает текст буфера обмена через внешнюю команду:
+// текст подаётся на stdin, результатом становится stdout.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/shell"
+)
+
+// DefaultTimeout ограничивает выполнение команды, если в правиле не задано иное.
+const DefaultTimeout = 5 * time.Second
+
+// maxStderr — сколько байт stderr попадает в текст ошибки.
+const maxStderr = 512
+
+// Run запускает command через cmd.exe, передаёт input на stdin и возвращает stdout.
+// Если input не заканчивается переводом строки, завершающий перевод строки вывода
+// отбрасывается: так утилиты вроде jq не добавляют лишнюю строку к вставляемому
+// тексту. Ненулевой код выхода и превышение timeout возвращаются ошибкой.
+func Run(ctx context.Context, command, input string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	res, err := shell.Run(ctx, command, shell.Options{Dialect: shell.Cmd, Input: input, Timeout: timeout})
+	if err != nil {
+		return "", err
+	}
+	if res.TimedOut {
+		return "", fmt.Errorf("команда %q не завершилась за %s", command, timeout)
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("команда %q завершилась с кодом %d: %s", command, res.ExitCode, stderrSummary(res.Stderr))
+	}
+	if res.Truncated {
+		return "", fmt.Errorf("вывод команды %q больше %d байт", command, shell.DefaultMaxOutput)
+	}
+
+	out := res.Stdout
+	if !strings.HasSuffix(input, "\n") {
+		out = strings.TrimSuffix(out, "\n")
+		out = strings.TrimSuffix(out, "\r")
+	}
+	return out, nil
+}
+
+func stderrSummary(stderr string) string {
+	s := strings.TrimSpace(stderr)
+	if s == "" {
+		return "stderr пуст"
+	}
+	if len(s) > maxStderr {
+		s = strings.ToValidUTF8(s[:maxStderr], "") + "…"
+	}
+	return s
+}
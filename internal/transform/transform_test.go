@@ -0,0 +1,69 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helperCommand строит команду, которая запускает этот же тестовый бинарник
+// в роли внешней программы, поэтому тест не зависит от утилит ОС.
+func helperCommand(t *testing.T, mode string) string {
+	t.Helper()
+	t.Setenv("CLIPQUEUE_TRANSFORM_HELPER", mode)
+	return fmt.Sprintf(`"%s" -test.run=^TestHelperProcess$`, os.Args[0])
+}
+
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("CLIPQUEUE_TRANSFORM_HELPER")
+	if mode == "" {
+		return
+	}
+	input, _ := io.ReadAll(os.Stdin)
+	switch mode {
+	case "upper":
+		fmt.Println(strings.ToUpper(string(input)))
+	case "fail":
+		fmt.Fprint(os.Stderr, "bad input")
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func TestRun(t *testing.T) {
+	out, err := Run(context.Background(), helperCommand(t, "upper"), "hello", 0)
+	if err != nil || out != "HELLO" {
+		t.Fatalf("Run = %q, %v", out, err)
+	}
+	// Текст с переводом строки в конце сохраняет его в выводе.
+	out, err = Run(context.Background(), helperCommand(t, "upper"), "a\n", 0)
+	if err != nil || out != "A\n\n" {
+		t.Fatalf("Run = %q, %v", out, err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := Run(context.Background(), helperCommand(t, "fail"), "x", 0)
+	if err == nil || !strings.Contains(err.Error(), "кодом 3") || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("ошибка команды: %v", err)
+	}
+
+	start := time.Now()
+	_, err = Run(context.Background(), helperCommand(t, "hang"), "x", 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "не завершилась") {
+		t.Fatalf("таймаут: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("таймаут сработал через %s", time.Since(start))
+	}
+
+	if _, err := Run(context.Background(), "  ", "x", 0); err == nil {
+		t.Fatal("пустая команда должна отклоняться")
+	}
+}
@@ -49,19 +49,63 @@
             request,
             getConfig() { return window.cqNativeGetConfig(); },
             saveConfig(cfg) { return window.cqNativeSaveConfig(cfg); },
+            validateConfig(cfg) { return postJSON('/api/config/validate', cfg); },
             captureHotkey() { return window.cqNativeCaptureHotkey(); },
             getHistory() { return window.cqNativeGetHistory(); },
+            queryHistory(params) { return request('/api/history?' + new URLSearchParams(params || {})); },
             getQueueState() { return window.cqNativeGetQueueState(); },
             toggleQueue() { return window.cqNativeToggleQueue(); },
             toggleQueueOrder() { return window.cqNativeToggleQueueOrder(); },
             copyHistoryItem(id) { return window.cqNativeCopyHistoryItem(id); },
             clearQueue() { return window.cqNativeClearQueue(); },
             removeQueueItem(index) { return window.cqNativeRemoveQueueItem(index); },
-            parseLab(command) { return window.cqNativeParseLab(command); },
-            buildLab(steps) { return window.cqNativeBuildLab(steps); },
+            parseLab(command, shell) { return window.cqNativeParseLab(command, shell || ''); },
+            buildLab(steps, shell) { return window.cqNativeBuildLab(steps, shell || ''); },
+            runLab(req) { return postJSON('/api/lab/run', req); },
             startSequenceRecording() { return window.cqNativeStartSequenceRecording(); },
             stopSequenceRecording() { return window.cqNativeStopSequenceRecording(); },
-            getSequenceStatus(last) { return window.cqNativeGetSequenceStatus(typeof last === 'number' ? last : 30); }
+            getSequenceStatus(last) { return window.cqNativeGetSequenceStatus(typeof last === 'number' ? last : 30); },
+            getPasteTargets() { return request('/api/paste/targets'); },
+            applyPasteTarget(process) { return postJSON('/api/paste/targets/apply', { process }); },
+            getPasteHistory(limit) {
+                const qs = typeof limit === 'number' ? ('?limit=' + encodeURIComponent(limit)) : '';
+                return request('/api/paste/history' + qs);
+            },
+            pushText(text) { return postJSON('/api/queue', { type: 'text', text }); },
+            getItem(id) { return request('/api/item/' + encodeURIComponent(id)); },
+            itemDownloadURL(id) { return '/api/item/' + encodeURIComponent(id) + '/download'; },
+            ocrItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/ocr', { method: 'POST' }); },
+            formatItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/format', { method: 'POST' }); },
+            qrItem(id, toQueue) { return request('/api/item/' + encodeURIComponent(id) + '/qr?to=' + (toQueue ? 'queue' : 'clipboard'), { method: 'POST' }); },
+            itemStats(id) { return request('/api/item/' + encodeURIComponent(id) + '/stats'); },
+            itemFiles(id) { return request('/api/item/' + encodeURIComponent(id) + '/files'); },
+            promoteItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/promote', { method: 'POST' }); },
+            demoteItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/demote', { method: 'POST' }); },
+            listWindows() { return request('/api/windows'); },
+            pasteNextTo(hwnd) { return postJSON('/api/queue/paste-to', { hwnd }); },
+            getHistoryApps(since) { return request('/api/history/apps' + (since ? '?since=' + encodeURIComponent(since) : '')); },
+            getStats() { return request('/api/stats'); },
+            getAudit(params) { return request('/api/audit?' + new URLSearchParams(params || {})); },
+            getPause() { return request('/api/pause'); },
+            pause(minutes) { return postJSON('/api/pause', minutes === undefined ? {} : { minutes }); },
+            resume() { return postJSON('/api/resume', {}); },
+            getDiagnostics() { return request('/api/diagnostics'); },
+            mergeQueueItems(ids, separator) { return postJSON('/api/queue/merge', { ids, separator }); },
+            splitItem(id, by, pattern, keepEmpty) { return postJSON('/api/item/' + encodeURIComponent(id) + '/split', { by, pattern, keepEmpty }); },
+            getMacros() { return request('/api/macros'); },
+            createMacro(macro) { return postJSON('/api/macros', macro); },
+            updateMacro(signature, macro) { return request('/api/macros/' + encodeURIComponent(signature), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(macro) }); },
+            exportMacros(format) { return request('/api/macros/export?format=' + encodeURIComponent(format || 'yaml')); },
+            importMacros(data, conflict, contentType) { return request('/api/macros/import?conflict=' + encodeURIComponent(conflict || 'skip'), { method: 'POST', headers: { 'Content-Type': contentType || 'application/yaml' }, body: data }); },
+            deleteMacro(signature) { return request('/api/macros/' + encodeURIComponent(signature), { method: 'DELETE' }); },
+            getSnippets(folder, tag) { return request('/api/snippets?' + new URLSearchParams({ folder: folder || '', tag: tag || '' })); },
+            getSnippetFolders() { return request('/api/snippets/folders'); },
+            searchSnippets(q, limit) { return request('/api/snippets/search?' + new URLSearchParams({ q: q || '', limit: String(limit || 20) })); },
+            createSnippet(snippet) { return postJSON('/api/snippets', snippet); },
+            updateSnippet(id, snippet) { return request('/api/snippets/' + encodeURIComponent(id), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(snippet) }); },
+            deleteSnippet(id) { return request('/api/snippets/' + encodeURIComponent(id), { method: 'DELETE' }); },
+            pasteSnippet(id, hwnd, fields) { return postJSON('/api/snippets/' + encodeURIComponent(id) + '/paste', { hwnd: hwnd || 0, fields: fields || {} }); },
+            editItem(id, text) { return request('/api/item/' + encodeURIComponent(id), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ text }) }); }
         };
     }
 
@@ -70,22 +114,66 @@
             request,
             getConfig() { return request('/api/config'); },
             saveConfig(cfg) { return postJSON('/api/config', cfg); },
+            validateConfig(cfg) { return postJSON('/api/config/validate', cfg); },
             captureHotkey() { return request('/api/hotkeys/capture', { method: 'POST' }); },
             getHistory() { return request('/api/history'); },
+            queryHistory(params) { return request('/api/history?' + new URLSearchParams(params || {})); },
             getQueueState() { return request('/api/queue/state'); },
             toggleQueue() { return request('/api/queue/toggle', { method: 'POST' }); },
             toggleQueueOrder() { return request('/api/queue/order/toggle', { method: 'POST' }); },
             copyHistoryItem(id) { return request('/api/copy?id=' + encodeURIComponent(id), { method: 'POST' }); },
             clearQueue() { return request('/api/queue/clear', { method: 'POST' }); },
             removeQueueItem(index) { return request('/api/history?index=' + encodeURIComponent(index), { method: 'DELETE' }); },
-            parseLab(command) { return postJSON('/api/lab/parse', { command }); },
-            buildLab(steps) { return postJSON('/api/lab/build', { steps }); },
+            parseLab(command, shell) { return postJSON('/api/lab/parse', { command, shell }); },
+            buildLab(steps, shell) { return postJSON('/api/lab/build', { steps, shell }); },
+            runLab(req) { return postJSON('/api/lab/run', req); },
             startSequenceRecording() { return request('/api/sequence/start', { method: 'POST' }); },
             stopSequenceRecording() { return request('/api/sequence/stop', { method: 'POST' }); },
             getSequenceStatus(last) {
                 const qs = typeof last === 'number' ? ('?last=' + encodeURIComponent(last)) : '';
                 return request('/api/sequence/status' + qs);
-            }
+            },
+            getPasteTargets() { return request('/api/paste/targets'); },
+            applyPasteTarget(process) { return postJSON('/api/paste/targets/apply', { process }); },
+            getPasteHistory(limit) {
+                const qs = typeof limit === 'number' ? ('?limit=' + encodeURIComponent(limit)) : '';
+                return request('/api/paste/history' + qs);
+            },
+            pushText(text) { return postJSON('/api/queue', { type: 'text', text }); },
+            getItem(id) { return request('/api/item/' + encodeURIComponent(id)); },
+            itemDownloadURL(id) { return '/api/item/' + encodeURIComponent(id) + '/download'; },
+            ocrItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/ocr', { method: 'POST' }); },
+            formatItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/format', { method: 'POST' }); },
+            qrItem(id, toQueue) { return request('/api/item/' + encodeURIComponent(id) + '/qr?to=' + (toQueue ? 'queue' : 'clipboard'), { method: 'POST' }); },
+            itemStats(id) { return request('/api/item/' + encodeURIComponent(id) + '/stats'); },
+            itemFiles(id) { return request('/api/item/' + encodeURIComponent(id) + '/files'); },
+            promoteItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/promote', { method: 'POST' }); },
+            demoteItem(id) { return request('/api/item/' + encodeURIComponent(id) + '/demote', { method: 'POST' }); },
+            listWindows() { return request('/api/windows'); },
+            pasteNextTo(hwnd) { return postJSON('/api/queue/paste-to', { hwnd }); },
+            getHistoryApps(since) { return request('/api/history/apps' + (since ? '?since=' + encodeURIComponent(since) : '')); },
+            getStats() { return request('/api/stats'); },
+            getAudit(params) { return request('/api/audit?' + new URLSearchParams(params || {})); },
+            getPause() { return request('/api/pause'); },
+            pause(minutes) { return postJSON('/api/pause', minutes === undefined ? {} : { minutes }); },
+            resume() { return postJSON('/api/resume', {}); },
+            getDiagnostics() { return request('/api/diagnostics'); },
+            mergeQueueItems(ids, separator) { return postJSON('/api/queue/merge', { ids, separator }); },
+            splitItem(id, by, pattern, keepEmpty) { return postJSON('/api/item/' + encodeURIComponent(id) + '/split', { by, pattern, keepEmpty }); },
+            getMacros() { return request('/api/macros'); },
+            createMacro(macro) { return postJSON('/api/macros', macro); },
+            updateMacro(signature, macro) { return request('/api/macros/' + encodeURIComponent(signature), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(macro) }); },
+            exportMacros(format) { return request('/api/macros/export?format=' + encodeURIComponent(format || 'yaml')); },
+            importMacros(data, conflict, contentType) { return request('/api/macros/import?conflict=' + encodeURIComponent(conflict || 'skip'), { method: 'POST', headers: { 'Content-Type': contentType || 'application/yaml' }, body: data }); },
+            deleteMacro(signature) { return request('/api/macros/' + encodeURIComponent(signature), { method: 'DELETE' }); },
+            getSnippets(folder, tag) { return request('/api/snippets?' + new URLSearchParams({ folder: folder || '', tag: tag || '' })); },
+            getSnippetFolders() { return request('/api/snippets/folders'); },
+            searchSnippets(q, limit) { return request('/api/snippets/search?' + new URLSearchParams({ q: q || '', limit: String(limit || 20) })); },
+            createSnippet(snippet) { return postJSON('/api/snippets', snippet); },
+            updateSnippet(id, snippet) { return request('/api/snippets/' + encodeURIComponent(id), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(snippet) }); },
+            deleteSnippet(id) { return request('/api/snippets/' + encodeURIComponent(id), { method: 'DELETE' }); },
+            pasteSnippet(id, hwnd, fields) { return postJSON('/api/snippets/' + encodeURIComponent(id) + '/paste', { hwnd: hwnd || 0, fields: fields || {} }); },
+            editItem(id, text) { return request('/api/item/' + encodeURIComponent(id), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ text }) }); }
         };
     }
 
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// defaultAuditLimit — сколько записей журнала вставок отдаётся без параметра limit.
+const defaultAuditLimit = 100
+
+// handleAudit отдаёт журнал вставок из очереди и макросов, начиная с самой свежей.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now()
+	q := app.AuditQuery{ItemID: query.Get("item"), App: query.Get("app"), Limit: defaultAuditLimit}
+	var err error
+	if q.Since, err = parseSince(query.Get("since"), now); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_since", err)})
+		return
+	}
+	if q.Until, err = parseSince(query.Get("until"), now); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_until", err)})
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		if q.Limit, err = strconv.Atoi(value); err != nil || q.Limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_limit")})
+			return
+		}
+	}
+
+	records, err := s.controller.GetAudit(q)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
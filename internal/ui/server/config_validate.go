@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// ConfigValidation — ответ POST /api/config/validate. Valid ложно, если среди
+// Issues есть хотя бы одна ошибка; предупреждения сохранению не мешают.
+type ConfigValidation struct {
+	Valid  bool                `json:"valid"`
+	Issues []config.FieldIssue `json:"issues"`
+}
+
+// handleConfigValidate проверяет присланный конфиг, ничего не сохраняя, и возвращает
+// все ошибки и предупреждения по полям. Тело — тот же JSON, что принимает POST /api/config.
+func (s *Server) handleConfigValidate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	var cfg config.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+
+	issues := config.Validate(&cfg)
+	// Хоткеи разбирает платформа, поэтому config их не проверяет — как и при сохранении.
+	if host, ok := s.host.(macroHost); ok {
+		for i, macro := range cfg.Macros {
+			if host.ParseHotkeyToSignature(macro.Hotkey) == nil && host.ParseHotkeyToSignature(macro.Signature) == nil {
+				issues = append(issues, config.FieldIssue{
+					Field:    fmt.Sprintf("macros[%d].hotkey", i),
+					Message:  i18n.T("api.invalid_macro_hotkey", macro.Hotkey, macro.Signature),
+					Severity: config.SeverityError,
+				})
+			}
+		}
+	}
+
+	result := ConfigValidation{Valid: true, Issues: []config.FieldIssue{}}
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			result.Valid = false
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+	json.NewEncoder(w).Encode(result)
+}
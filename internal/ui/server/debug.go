@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+var processStart = time.Now()
+
+// RuntimeStatsResponse — снимок состояния рантайма Go для /api/debug/runtime.
+type RuntimeStatsResponse struct {
+	GoVersion     string    `json:"goVersion"`
+	Uptime        string    `json:"uptime"`
+	NumCPU        int       `json:"numCPU"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	Goroutines    int       `json:"goroutines"`
+	HeapAlloc     uint64    `json:"heapAlloc"`
+	HeapInuse     uint64    `json:"heapInuse"`
+	HeapSys       uint64    `json:"heapSys"`
+	HeapObjects   uint64    `json:"heapObjects"`
+	TotalAlloc    uint64    `json:"totalAlloc"`
+	Sys           uint64    `json:"sys"`
+	NumGC         uint32    `json:"numGC"`
+	PauseTotalNs  uint64    `json:"pauseTotalNs"`
+	LastPauseNs   uint64    `json:"lastPauseNs"`
+	LastGC        time.Time `json:"lastGC,omitempty"`
+	GCCPUFraction float64   `json:"gcCPUFraction"`
+}
+
+// registerDebugRoutes подключает pprof и /api/debug/runtime. Обработчики отвечают 404,
+// пока debug.enable_pprof выключен, поэтому переключение не требует перезапуска.
+func (s *Server) registerDebugRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", s.debugOnly(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", s.debugOnly(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", s.debugOnly(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", s.debugOnly(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", s.debugOnly(pprof.Trace))
+	mux.HandleFunc("/api/debug/runtime", s.debugOnly(s.handleDebugRuntime))
+}
+
+func (s *Server) debugOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.Get().Debug.EnablePprof {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleDebugRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collectRuntimeStats())
+}
+
+func collectRuntimeStats() RuntimeStatsResponse {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	stats := RuntimeStatsResponse{
+		GoVersion:     runtime.Version(),
+		Uptime:        time.Since(processStart).Round(time.Second).String(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAlloc:     ms.HeapAlloc,
+		HeapInuse:     ms.HeapInuse,
+		HeapSys:       ms.HeapSys,
+		HeapObjects:   ms.HeapObjects,
+		TotalAlloc:    ms.TotalAlloc,
+		Sys:           ms.Sys,
+		NumGC:         ms.NumGC,
+		PauseTotalNs:  ms.PauseTotalNs,
+		GCCPUFraction: ms.GCCPUFraction,
+	}
+	if ms.NumGC > 0 {
+		stats.LastPauseNs = ms.PauseNs[(ms.NumGC+255)%256]
+		stats.LastGC = time.Unix(0, int64(ms.LastGC))
+	}
+	return stats
+}
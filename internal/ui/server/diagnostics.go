@@ -0,0 +1,30 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// DiagnosticsResponse — сведения для поиска причин сбоев копирования и вставки.
+type DiagnosticsResponse struct {
+	Clipboard     windows.ClipboardContention `json:"clipboard"`
+	RemoteSession bool                        `json:"remoteSession"` // ClipQueue запущен в сеансе удалённого рабочего стола
+}
+
+// handleDiagnostics возвращает, какие приложения блокировали буфер обмена, и
+// кто держит его открытым прямо сейчас.
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DiagnosticsResponse{
+		Clipboard:     windows.ClipboardContentionStats(),
+		RemoteSession: windows.IsRemoteSession(),
+	})
+}
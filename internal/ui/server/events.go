@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// eventBuffer — сколько событий ждут медленного подписчика /api/events, прежде чем отбрасываться.
+const eventBuffer = 64
+
+// eventKeepAlive — период комментария-пинга, чтобы прокси не закрывали молчащее соединение.
+const eventKeepAlive = 30 * time.Second
+
+// StreamEvent — данные события потока GET /api/events. Имя события (state,
+// capture, enqueue, paste) передаётся в поле event: SSE и повторяется в Event.
+type StreamEvent struct {
+	Event string             `json:"event"`
+	Queue QueueStateResponse `json:"queue"`
+	Item  *HistoryItemDTO    `json:"item,omitempty"` // Элемент без содержимого; только для capture, enqueue и paste
+}
+
+// PublishEvent рассылает событие подписчикам /api/events. Состояние очереди
+// берётся у контроллера. Медленный подписчик теряет события, но не задерживает остальных.
+func (s *Server) PublishEvent(event string, content *windows.ClipboardContent) {
+	ev := StreamEvent{Event: event, Queue: s.queueState()}
+	if content != nil {
+		ev.Item = &HistoryItemDTO{
+			ID:          content.ID,
+			Type:        content.Type.String(),
+			Preview:     content.Preview,
+			Timestamp:   content.Timestamp,
+			Sensitive:   content.Sensitive,
+			SourceApp:   content.SourceApp,
+			SourceTitle: content.SourceTitle,
+			Title:       content.Title,
+			QueueIndex:  -1,
+		}
+	}
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	for ch := range s.events {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (s *Server) queueState() QueueStateResponse {
+	enabled, count, order := s.controller.GetQueueState()
+	return QueueStateResponse{Enabled: enabled, Count: count, Order: order}
+}
+
+// closeEvents завершает все потоки /api/events, иначе Shutdown ждал бы их до таймаута.
+func (s *Server) closeEvents() {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	for ch := range s.events {
+		close(ch)
+		delete(s.events, ch)
+	}
+}
+
+// handleEvents отдаёт поток Server-Sent Events: сначала текущее состояние очереди,
+// затем события контроллера до отключения клиента.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.internal_error")})
+		return
+	}
+
+	ch := make(chan StreamEvent, eventBuffer)
+	s.eventsMu.Lock()
+	s.events[ch] = struct{}{}
+	s.eventsMu.Unlock()
+	defer func() {
+		s.eventsMu.Lock()
+		delete(s.events, ch)
+		s.eventsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	send := func(ev StreamEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := send(StreamEvent{Event: "state", Queue: s.queueState()}); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(eventKeepAlive)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// parseSince разбирает параметр since: today — с начала суток, длительность
+// (24h, 30m) — столько назад, дата 2006-01-02 или время RFC 3339. Пустое
+// значение — без ограничения.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return time.Time{}, nil
+	case strings.EqualFold(value, "today"):
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New(value)
+}
+
+// historySorts — допустимые значения параметра sort; первое — порядок по умолчанию.
+var historySorts = []string{"newest", "oldest", "type", "app", "queue"}
+
+// historyFilter — фильтры и сортировка GET /api/history.
+type historyFilter struct {
+	App   string    // Процесс-источник без учёта регистра
+	Types []string  // Text, Image, Files; пусто — любые
+	Since time.Time // Не раньше
+	Until time.Time // Раньше; нулевое — без ограничения
+	Sort  string
+}
+
+// parseHistoryFilter разбирает параметры app, type, since, until, pinned и sort.
+// Ошибка уже переведена и годится для ответа клиенту.
+func parseHistoryFilter(query url.Values, now time.Time) (historyFilter, error) {
+	f := historyFilter{App: strings.TrimSpace(query.Get("app")), Sort: historySorts[0]}
+	var err error
+	if f.Since, err = parseSince(query.Get("since"), now); err != nil {
+		return f, errors.New(i18n.T("api.invalid_since", err))
+	}
+	if f.Until, err = parseSince(query.Get("until"), now); err != nil {
+		return f, errors.New(i18n.T("api.invalid_until", err))
+	}
+	for _, t := range strings.Split(query.Get("type"), ",") {
+		switch t = strings.ToLower(strings.TrimSpace(t)); t {
+		case "":
+		case "text", "image", "files":
+			f.Types = append(f.Types, t)
+		default:
+			return f, errors.New(i18n.T("api.invalid_type", t))
+		}
+	}
+	// Закрепления элементов в истории нет: pinned=false совпадает со всей историей,
+	// а pinned=true честнее отклонить, чем молча вернуть пустой список.
+	if pinned := strings.TrimSpace(query.Get("pinned")); pinned != "" {
+		if b, err := strconv.ParseBool(pinned); err != nil || b {
+			return f, errors.New(i18n.T("api.pinned_unsupported"))
+		}
+	}
+	if value := strings.ToLower(strings.TrimSpace(query.Get("sort"))); value != "" {
+		if !slices.Contains(historySorts, value) {
+			return f, errors.New(i18n.T("api.invalid_sort", value, strings.Join(historySorts, ", ")))
+		}
+		f.Sort = value
+	}
+	return f, nil
+}
+
+// apply фильтрует и сортирует список, который buildHistoryDTOs отдаёт от новых к старым.
+func (f historyFilter) apply(items []HistoryItemDTO) []HistoryItemDTO {
+	filtered := items[:0]
+	for _, item := range items {
+		if f.App != "" && !strings.EqualFold(item.SourceApp, f.App) {
+			continue
+		}
+		if len(f.Types) > 0 && !slices.Contains(f.Types, strings.ToLower(item.Type)) {
+			continue
+		}
+		if item.Timestamp.Before(f.Since) || (!f.Until.IsZero() && !item.Timestamp.Before(f.Until)) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	switch f.Sort {
+	case "oldest":
+		slices.Reverse(filtered)
+	case "type":
+		sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].Type < filtered[j].Type })
+	case "app":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return strings.ToLower(filtered[i].SourceApp) < strings.ToLower(filtered[j].SourceApp)
+		})
+	case "queue":
+		// Сначала элементы очереди в порядке вставки, за ними остальные от новых к старым.
+		sort.SliceStable(filtered, func(i, j int) bool {
+			a, b := filtered[i], filtered[j]
+			if a.IsQueued != b.IsQueued {
+				return a.IsQueued
+			}
+			return a.IsQueued && (a.IsNext && !b.IsNext || a.IsNext == b.IsNext && a.QueueIndex < b.QueueIndex)
+		})
+	}
+	return filtered
+}
+
+// historyPage — страница истории из параметров offset и limit; Limit 0 — до конца.
+type historyPage struct {
+	Offset int
+	Limit  int
+}
+
+// parseHistoryPage разбирает offset и limit. Без них возвращается вся история.
+func parseHistoryPage(query url.Values) (historyPage, error) {
+	var page historyPage
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"offset", &page.Offset}, {"limit", &page.Limit}} {
+		value := strings.TrimSpace(query.Get(param.name))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return historyPage{}, errors.New(param.name + "=" + value)
+		}
+		*param.dst = n
+	}
+	return page, nil
+}
+
+// apply вырезает страницу из уже отфильтрованного списка.
+func (p historyPage) apply(items []HistoryItemDTO) []HistoryItemDTO {
+	if p.Offset >= len(items) {
+		return []HistoryItemDTO{}
+	}
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(items) {
+		items = items[:p.Limit]
+	}
+	return items
+}
+
+// handleHistoryApps возвращает статистику истории по приложениям-источникам.
+func (s *Server) handleHistoryApps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	since, err := parseSince(r.URL.Query().Get("since"), time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_since", err)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.controller.GetSourceAppStats(since))
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHistoryFilterAndPage(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	items := []HistoryItemDTO{
+		{ID: "4", Type: "Text", SourceApp: "code.exe", Timestamp: now.Add(-1 * time.Hour), QueueIndex: -1},
+		{ID: "3", Type: "Image", SourceApp: "chrome.exe", Timestamp: now.Add(-2 * time.Hour), IsQueued: true, QueueIndex: 1},
+		{ID: "2", Type: "Text", SourceApp: "Chrome.exe", Timestamp: now.Add(-3 * time.Hour), IsQueued: true, QueueIndex: 0, IsNext: true},
+		{ID: "1", Type: "Files", Timestamp: now.Add(-48 * time.Hour), QueueIndex: -1},
+	}
+	ids := func(list []HistoryItemDTO) string {
+		var out string
+		for _, item := range list {
+			out += item.ID
+		}
+		return out
+	}
+
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"", "4321"},
+		{"type=text", "42"},
+		{"type=image,files", "31"},
+		{"app=chrome.exe&sort=oldest", "23"},
+		{"since=24h&until=90m", "32"},
+		{"sort=queue", "2341"},
+		{"pinned=false&sort=type", "1342"},
+		{"offset=1&limit=2", "32"},
+		{"offset=10", ""},
+	}
+	for _, tc := range cases {
+		query, _ := url.ParseQuery(tc.query)
+		filter, err := parseHistoryFilter(query, now)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.query, err)
+		}
+		page, err := parseHistoryPage(query)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.query, err)
+		}
+		list := append([]HistoryItemDTO(nil), items...)
+		if got := ids(page.apply(filter.apply(list))); got != tc.want {
+			t.Errorf("%s: получено %q, ожидалось %q", tc.query, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"type=video", "sort=size", "pinned=true", "until=вчера"} {
+		query, _ := url.ParseQuery(bad)
+		if _, err := parseHistoryFilter(query, now); err == nil {
+			t.Errorf("%s: ожидалась ошибка", bad)
+		}
+	}
+	for _, bad := range []string{"limit=-1", "offset=x"} {
+		query, _ := url.ParseQuery(bad)
+		if _, err := parseHistoryPage(query); err == nil {
+			t.Errorf("%s: ожидалась ошибка", bad)
+		}
+	}
+}
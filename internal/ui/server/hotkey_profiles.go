@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// HotkeyProfilesResponse — наборы хоткеев и активный набор; пустое имя — основной набор hotkeys.
+type HotkeyProfilesResponse struct {
+	Active   string                 `json:"active"`
+	Profiles []config.HotkeyProfile `json:"profiles"`
+}
+
+// HotkeyProfileRequest — тело POST /api/hotkeys/profiles.
+type HotkeyProfileRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) hotkeyProfiles() HotkeyProfilesResponse {
+	cfg := s.config.Get()
+	resp := HotkeyProfilesResponse{Profiles: cfg.HotkeyProfiles.Profiles}
+	if p := cfg.ActiveHotkeyProfile(); p != nil {
+		resp.Active = p.Name
+	}
+	return resp
+}
+
+// handleHotkeyProfiles возвращает наборы хоткеев (GET) или переключает активный (POST).
+func (s *Server) handleHotkeyProfiles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
+		var req HotkeyProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+			return
+		}
+		if err := s.config.Get().SetHotkeyProfile(req.Name); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.hotkey_profile_not_found", req.Name)})
+			return
+		}
+		if s.SwitchHotkeyProfile == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.internal_error")})
+			return
+		}
+		if err := s.SwitchHotkeyProfile(req.Name); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.hotkeyProfiles())
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/archive"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/filebundle"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/importer"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// maxImportBytes ограничивает размер импортируемого файла: база Ditto читается в память целиком.
+const maxImportBytes = 512 << 20
+
+// maxImportUnpackedBytes ограничивает всё, что распаковывается из ZIP при импорте;
+// одно изображение или встроенный файл ограничены clipboard.max_item_bytes.
+const maxImportUnpackedBytes = 1 << 30
+
+// ImportResponse — итог POST /api/import и POST /api/history/import.
+type ImportResponse struct {
+	Format   string `json:"format"`
+	Imported int    `json:"imported"` // Добавлено в историю с учётом её лимитов
+	Skipped  int    `json:"skipped"`  // Не перенесено: группы, пустые элементы, неизвестные форматы
+	Macros   int    `json:"macros"`   // Новые макросы из сниппетов с сочетаниями клавиш
+}
+
+// handleHistoryExport выгружает историю файлом: ?format=json (по умолчанию), csv или zip.
+// Изображения, ещё не дочитанные из буфера, не выгружаются; в CSV изображений нет совсем.
+// При files.embed в JSON и ZIP встраивается содержимое файлов из элементов Files.
+func (s *Server) handleHistoryExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	format, err := archive.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	files := s.config.Get().Files
+	embed := files.Embed && format != archive.FormatCSV
+	history := s.controller.GetHistory()
+	items := make([]archive.Item, 0, len(history))
+	for _, content := range history {
+		if content.Type == windows.Empty || content.NeedsImageCapture() {
+			continue
+		}
+		item := archive.Item{
+			ID:        content.ID,
+			Timestamp: content.Timestamp,
+			Type:      content.Type.String(),
+			Text:      content.Text,
+			Files:     content.Files,
+			ImagePNG:  content.ImagePNG,
+			ImageType: content.ImageType,
+		}
+		if embed && content.Type == windows.Files {
+			if item.Embedded, err = filebundle.Collect(content.Files, files.MaxEmbedBytes); err != nil {
+				logger.Warn("Экспорт: файлы элемента %s выгружены только путями: %v", content.ID, err)
+				item.Embedded = nil
+			}
+		}
+		items = append(items, item)
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "clipqueue-history-" + time.Now().Format("20060102-150405") + format.Ext(),
+	}))
+	written, err := archive.Write(w, format, items)
+	if err != nil {
+		// Заголовки уже отправлены, поэтому ошибку остаётся только записать в журнал.
+		logger.Error("Экспорт истории прерван: %v", err)
+		return
+	}
+	logger.Info("История выгружена в %s: %d элементов", format, written)
+}
+
+// handleImport переносит историю из файла экспорта ClipQueue, Ditto (Ditto.db) или CopyQ (JSON).
+// Файл передаётся телом запроса или multipart-полем file; формат определяется
+// по содержимому, параметр ?format=clipqueue|ditto|copyq задаёт его явно.
+// Импорт добавляет макросы и записывает файлы на диск, поэтому тело простого
+// типа (multipart/form-data, text/plain) принимается только с заголовком X-ClipQueue-Request.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	if !requireNonSimple(w, r) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	data, err := readImportBody(r)
+	if err == nil {
+		var res *importer.Result
+		limits := archive.Limits{EntryBytes: s.config.Get().Clipboard.MaxItemBytes, TotalBytes: maxImportUnpackedBytes}
+		res, err = importer.Parse(data, importer.Format(r.URL.Query().Get("format")), limits)
+		if err == nil {
+			s.applyImport(w, res)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func readImportBody(r *http.Request) ([]byte, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New(i18n.T("api.import_file_required", err))
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, errors.New(i18n.T("api.import_read_failed", err))
+		}
+		return data, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.New(i18n.T("api.import_read_failed", err))
+	}
+	return data, nil
+}
+
+// applyImport добавляет клипы в историю, а сниппеты — в макросы.
+func (s *Server) applyImport(w http.ResponseWriter, res *importer.Result) {
+	items := make([]windows.ClipboardContent, 0, len(res.Clips))
+	skipped := res.Skipped
+	for _, clip := range res.Clips {
+		item, err := importedContent(clip)
+		if err != nil {
+			logger.Warn("Импорт: элемент %s пропущен: %v", clip.SourceID, err)
+			skipped++
+			continue
+		}
+		item.ID = "import-" + string(res.Format) + "-" + clip.SourceID
+		if res.Format == importer.FormatClipQueue {
+			item.ID = clip.SourceID
+		}
+		item.Timestamp = clip.Time
+		items = append(items, item)
+	}
+
+	imported, err := s.controller.ImportHistory(items)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	macros, err := s.importMacros(res.Snippets)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Импорт %s: в историю %d, пропущено %d, макросов %d", res.Format, imported, skipped, macros)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ImportResponse{
+		Format:   string(res.Format),
+		Imported: imported,
+		Skipped:  skipped,
+		Macros:   macros,
+	})
+}
+
+func importedContent(clip importer.Clip) (windows.ClipboardContent, error) {
+	if clip.Text != "" {
+		return windows.NewTextContent(clip.Text), nil
+	}
+	if len(clip.Embedded) > 0 {
+		paths, err := filebundle.Materialize(filebundle.DefaultRoot(), clip.Embedded)
+		if err != nil {
+			return windows.ClipboardContent{}, err
+		}
+		return windows.NewFilesContent(paths), nil
+	}
+	if len(clip.Files) > 0 {
+		return windows.NewFilesContent(clip.Files), nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(clip.ImagePNG))
+	if err != nil {
+		return windows.ClipboardContent{}, err
+	}
+	return windows.NewImageContent(img)
+}
+
+// importMacros добавляет сниппеты как макросы режима paste. Сочетания, уже занятые
+// макросами, пропускаются. Возвращает число добавленных макросов.
+func (s *Server) importMacros(snippets []importer.Snippet) (int, error) {
+	if len(snippets) == 0 {
+		return 0, nil
+	}
+	added := 0
+	err := s.config.Mutate(func(cfg *config.Config) {
+		used := make(map[string]bool, len(cfg.Macros))
+		for _, macro := range cfg.Macros {
+			used[strings.ToUpper(macro.Hotkey)] = true
+		}
+		for _, snippet := range snippets {
+			if used[strings.ToUpper(snippet.Hotkey)] {
+				continue
+			}
+			sig, err := config.HotkeySignature(snippet.Hotkey)
+			if err != nil {
+				logger.Warn("Импорт: сниппет %q пропущен: %v", snippet.Name, err)
+				continue
+			}
+			used[strings.ToUpper(snippet.Hotkey)] = true
+			cfg.Macros = append(cfg.Macros, config.Macro{
+				Name:      snippet.Name,
+				Hotkey:    snippet.Hotkey,
+				Signature: sig,
+				Enabled:   true,
+				Text:      snippet.Text,
+				Mode:      "paste",
+			})
+			added++
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if added > 0 && s.OnConfigUpdate != nil {
+		s.OnConfigUpdate()
+	}
+	return added, nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportRejectsSimpleRequests(t *testing.T) {
+	s := &Server{}
+	cases := []struct {
+		name        string
+		contentType string
+	}{
+		{"text/plain", "text/plain;charset=UTF-8"},
+		{"multipart", "multipart/form-data; boundary=x"},
+		{"форма", "application/x-www-form-urlencoded"},
+		{"без типа", ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/api/import", strings.NewReader(`[{"text":"x"}]`))
+		if c.contentType != "" {
+			r.Header.Set("Content-Type", c.contentType)
+		}
+		w := httptest.NewRecorder()
+		s.handleImport(w, r)
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: код %d, ожидался 415", c.name, w.Code)
+		}
+	}
+}
+
+func TestRequireNonSimple(t *testing.T) {
+	cases := []struct {
+		contentType string
+		header      bool
+		want        bool
+	}{
+		{"application/json", false, true},
+		{"application/octet-stream", false, true},
+		{"multipart/form-data; boundary=x", true, true},
+		{"text/plain", true, true},
+		{"multipart/form-data; boundary=x", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/api/import", nil)
+		if c.contentType != "" {
+			r.Header.Set("Content-Type", c.contentType)
+		}
+		if c.header {
+			r.Header.Set(requestHeader, "1")
+		}
+		if got := requireNonSimple(httptest.NewRecorder(), r); got != c.want {
+			t.Errorf("Content-Type %q, заголовок %v: %v, ожидалось %v", c.contentType, c.header, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/fileinfo"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/ocr"
+	"github.com/serty2005/clipqueue/internal/prettyprint"
+	"github.com/serty2005/clipqueue/internal/snippets"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// ItemContentDTO содержит полное содержимое элемента истории или очереди.
+// ImagePNG кодируется в JSON как base64; ImageType — его MIME-тип.
+type ItemContentDTO struct {
+	ID                string    `json:"id"`
+	Type              string    `json:"type"`
+	Preview           string    `json:"preview"`
+	Timestamp         time.Time `json:"timestamp"`
+	SizeBytes         int       `json:"sizeBytes"`
+	Text              string    `json:"text,omitempty"`
+	Files             []string  `json:"files,omitempty"`
+	ImagePNG          []byte    `json:"imagePng,omitempty"`
+	ImageType         string    `json:"imageType,omitempty"`
+	NeedsImageCapture bool      `json:"needsImageCapture"`
+	Sensitive         string    `json:"sensitive,omitempty"`
+	Oversized         bool      `json:"oversized,omitempty"`
+	SourceApp         string    `json:"sourceApp,omitempty"`
+	SourceTitle       string    `json:"sourceTitle,omitempty"`
+	Title             string    `json:"title,omitempty"`
+	Revision          int       `json:"revision,omitempty"`
+	RevisionOf        string    `json:"revisionOf,omitempty"`
+}
+
+// ItemEditRequest — тело PUT /api/item/{id}.
+type ItemEditRequest struct {
+	Text string `json:"text"`
+}
+
+// maxQueuePushBytes ограничивает размер тела POST /api/queue.
+const maxQueuePushBytes = 64 << 20
+
+// QueuePushRequest — JSON-тело POST /api/queue для текстовых элементов.
+type QueuePushRequest struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// QueuePushResponse возвращает ID добавленного элемента и новое состояние очереди.
+type QueuePushResponse struct {
+	ID    string             `json:"id"`
+	Queue QueueStateResponse `json:"queue"`
+}
+
+// writeItemError отвечает JSON-ошибкой, выбирая код по виду ошибки контроллера.
+func writeItemError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, app.ErrItemNotFound), errors.Is(err, app.ErrNoThumbnail), errors.Is(err, app.ErrNotQueued),
+		errors.Is(err, windows.ErrWindowNotFound), errors.Is(err, snippets.ErrNotFound), errors.Is(err, app.ErrTargetNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, app.ErrQueueDisabled), errors.Is(err, app.ErrHistoryDisabled), errors.Is(err, app.ErrNothingToPaste):
+		status = http.StatusConflict
+	case errors.Is(err, app.ErrNotImage), errors.Is(err, app.ErrNotText), errors.Is(err, app.ErrNotFiles), errors.Is(err, imaging.ErrQRTooLong),
+		errors.Is(err, app.ErrMergeTooFew), errors.Is(err, app.ErrSplit), errors.Is(err, snippets.ErrInvalid),
+		errors.Is(err, snippets.ErrMissingField):
+		status = http.StatusBadRequest
+	case errors.Is(err, ocr.ErrNoText), errors.Is(err, prettyprint.ErrUnknownFormat), errors.Is(err, prettyprint.ErrInvalid):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, ocr.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleItemThumbnail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	data, contentType, err := s.controller.GetItemThumbnail(r.PathValue("id"))
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	// Миниатюра элемента не меняется, пока элемент существует.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write(data)
+}
+
+// handleItem отдаёт полное содержимое элемента. С параметром ?format=binary
+// изображение возвращается как image/png без обёртки JSON.
+func (s *Server) handleItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		s.handleItemEdit(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	item, err := s.controller.GetItem(r.PathValue("id"))
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "binary" {
+		if len(item.ImagePNG) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.binary_images_only")})
+			return
+		}
+		w.Header().Set("Content-Type", imageContentType(item))
+		w.Write(item.ImagePNG)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(itemContentDTO(item))
+}
+
+// handleItemEdit сохраняет новый текст элемента как его правку и возвращает её.
+func (s *Server) handleItemEdit(w http.ResponseWriter, r *http.Request) {
+	var req ItemEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+	if req.Text == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.empty_text")})
+		return
+	}
+	item, err := s.controller.EditItemText(r.PathValue("id"), req.Text)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(itemContentDTO(item))
+}
+
+func itemContentDTO(item windows.ClipboardContent) ItemContentDTO {
+	return ItemContentDTO{
+		ID:                item.ID,
+		Type:              item.Type.String(),
+		Preview:           item.Preview,
+		Timestamp:         item.Timestamp,
+		SizeBytes:         item.SizeBytes,
+		Text:              item.Text,
+		Files:             item.Files,
+		ImagePNG:          item.ImagePNG,
+		ImageType:         imageContentType(item),
+		NeedsImageCapture: item.NeedsImageCapture(),
+		Sensitive:         item.Sensitive,
+		Oversized:         item.Oversized,
+		SourceApp:         item.SourceApp,
+		SourceTitle:       item.SourceTitle,
+		Title:             item.Title,
+		Revision:          item.Revision,
+		RevisionOf:        item.RevisionOf,
+	}
+}
+
+// OCRResponse — результат POST /api/item/{id}/ocr: новый текстовый элемент.
+type OCRResponse struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// handleItemOCR распознаёт текст изображения и добавляет его новым текстовым элементом.
+func (s *Server) handleItemOCR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	item, err := s.controller.OCRItem(r.PathValue("id"))
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OCRResponse{ID: item.ID, Text: item.Text})
+}
+
+// FormatResponse — результат POST /api/item/{id}/format: новый элемент с
+// отформатированным JSON или XML.
+type FormatResponse struct {
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	Format string `json:"format"` // json или xml
+}
+
+// handleItemFormat переформатирует JSON или XML элемента с отступами и добавляет
+// результат новым текстовым элементом.
+func (s *Server) handleItemFormat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	item, err := s.controller.FormatItem(r.PathValue("id"))
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FormatResponse{ID: item.ID, Text: item.Text, Format: string(prettyprint.Detect(item.Text))})
+}
+
+// QRResponse — результат POST /api/item/{id}/qr. ID заполнен, только если
+// QR-код добавлен в очередь; из буфера обмена элемент попадёт в историю сам.
+type QRResponse struct {
+	ID       string `json:"id,omitempty"`
+	ImagePNG []byte `json:"imagePng"`
+}
+
+// handleItemQR кодирует текст элемента в QR-код: ?to=clipboard (по умолчанию)
+// записывает изображение в буфер обмена, ?to=queue — добавляет в очередь.
+func (s *Server) handleItemQR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if to != "" && to != "clipboard" && to != "queue" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.qr_invalid_target", to)})
+		return
+	}
+
+	item, err := s.controller.QRItem(r.PathValue("id"), to == "queue")
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	resp := QRResponse{ImagePNG: item.ImagePNG}
+	if to == "queue" {
+		resp.ID = item.ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// TextStatsDTO — подробная статистика текста элемента, ответ GET /api/item/{id}/stats.
+type TextStatsDTO struct {
+	ID             string  `json:"id"`
+	Chars          int     `json:"chars"`
+	CharsNoSpaces  int     `json:"charsNoSpaces"`
+	Words          int     `json:"words"`
+	Lines          int     `json:"lines"`
+	BlankLines     int     `json:"blankLines"`
+	Paragraphs     int     `json:"paragraphs"`
+	LongestLine    int     `json:"longestLine"`
+	AvgWordLength  float64 `json:"avgWordLength"`
+	Bytes          int     `json:"bytes"`
+	UTF16Units     int     `json:"utf16Units"`
+	ReadingSeconds int     `json:"readingSeconds"`
+}
+
+func (s *Server) handleItemStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	id := r.PathValue("id")
+	st, err := s.controller.GetItemTextStats(id)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TextStatsDTO{
+		ID:             id,
+		Chars:          st.Chars,
+		CharsNoSpaces:  st.CharsNoSpaces,
+		Words:          st.Words,
+		Lines:          st.Lines,
+		BlankLines:     st.BlankLines,
+		Paragraphs:     st.Paragraphs,
+		LongestLine:    st.LongestLine,
+		AvgWordLength:  math.Round(st.AvgWordLength*10) / 10,
+		Bytes:          st.Bytes,
+		UTF16Units:     st.UTF16Units,
+		ReadingSeconds: st.ReadingSeconds,
+	})
+}
+
+// handleItemPromote делает элемент очереди следующим для вставки.
+func (s *Server) handleItemPromote(w http.ResponseWriter, r *http.Request) {
+	s.handleItemMove(w, r, s.controller.PromoteItem)
+}
+
+// handleItemDemote переносит элемент очереди в её хвост.
+func (s *Server) handleItemDemote(w http.ResponseWriter, r *http.Request) {
+	s.handleItemMove(w, r, s.controller.DemoteItem)
+}
+
+func (s *Server) handleItemMove(w http.ResponseWriter, r *http.Request, move func(id string) error) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	if err := move(r.PathValue("id")); err != nil {
+		writeItemError(w, err)
+		return
+	}
+	enabled, count, order := s.controller.GetQueueState()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueueStateResponse{Enabled: enabled, Count: count, Order: order})
+}
+
+// FileTreeDTO — дерево путей элемента Files с настоящими размерами, ответ GET /api/item/{id}/files.
+type FileTreeDTO struct {
+	ID string `json:"id"`
+	fileinfo.Totals
+	Entries []fileinfo.Entry `json:"entries"`
+}
+
+// handleItemFiles обходит пути элемента Files: размеры, каталоги и два уровня вложенности.
+func (s *Server) handleItemFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	id := r.PathValue("id")
+	entries, totals, err := s.controller.GetItemFileTree(id)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FileTreeDTO{ID: id, Totals: totals, Entries: entries})
+}
+
+// handleItemDownload отдаёт элемент файлом: изображение — как PNG, текст и список файлов — как .txt.
+func (s *Server) handleItemDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	item, err := s.controller.GetItem(r.PathValue("id"))
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	var (
+		data        []byte
+		contentType string
+		ext         string
+	)
+	switch item.Type {
+	case windows.Image:
+		if len(item.ImagePNG) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.image_not_captured")})
+			return
+		}
+		data, contentType, ext = item.ImagePNG, imageContentType(item), ".png"
+		if contentType == "image/jpeg" {
+			ext = ".jpg"
+		}
+	case windows.Files:
+		data, contentType, ext = []byte(strings.Join(item.Files, "\r\n")), "text/plain; charset=utf-8", ".txt"
+	default:
+		data, contentType, ext = []byte(item.Text), "text/plain; charset=utf-8", ".txt"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": downloadFileName(item, ext),
+	}))
+	w.Write(data)
+}
+
+// downloadFileName строит имя файла по времени копирования элемента.
+func downloadFileName(item windows.ClipboardContent, ext string) string {
+	ts := item.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return "clipqueue-" + ts.Format("20060102-150405") + ext
+}
+
+// handleQueuePush добавляет элемент в очередь: текст — JSON {type:"text", text:"..."},
+// изображение — multipart/form-data с файлом в поле image (PNG, JPEG или GIF)
+// и заголовком X-ClipQueue-Request.
+func (s *Server) handleQueuePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	multipart := false
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		multipart = true
+		if !requireNonSimple(w, r) {
+			return
+		}
+	} else if !requireJSON(w, r) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxQueuePushBytes)
+	var (
+		item windows.ClipboardContent
+		err  error
+	)
+	if multipart {
+		item, err = readImageUpload(r)
+	} else {
+		item, err = readTextPush(r)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if item, err = s.controller.PushItem(item); err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	enabled, count, order := s.controller.GetQueueState()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueuePushResponse{
+		ID:    item.ID,
+		Queue: QueueStateResponse{Enabled: enabled, Count: count, Order: order},
+	})
+}
+
+func readTextPush(r *http.Request) (windows.ClipboardContent, error) {
+	var req QueuePushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return windows.ClipboardContent{}, errors.New(i18n.T("api.invalid_json", err))
+	}
+	if req.Type != "" && req.Type != "text" {
+		return windows.ClipboardContent{}, errors.New(i18n.T("api.unsupported_type", req.Type))
+	}
+	if req.Text == "" {
+		return windows.ClipboardContent{}, errors.New(i18n.T("api.empty_text"))
+	}
+	return windows.NewTextContent(req.Text), nil
+}
+
+func readImageUpload(r *http.Request) (windows.ClipboardContent, error) {
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return windows.ClipboardContent{}, errors.New(i18n.T("api.image_field_required", err))
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return windows.ClipboardContent{}, errors.New(i18n.T("api.image_decode_failed", err))
+	}
+	return windows.NewImageContent(img)
+}
+
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// handleCopyText обрабатывает POST /api/copy с JSON {text:"..."}: текст записывается в буфер обмена.
+func (s *Server) handleCopyText(w http.ResponseWriter, r *http.Request) {
+	var req QueuePushRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxQueuePushBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+	if err := s.controller.CopyText(req.Text); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "text copied to clipboard"})
+}
+
+// imageContentType возвращает MIME-тип данных изображения: история может хранить
+// уменьшенную JPEG-копию вместо PNG.
+func imageContentType(item windows.ClipboardContent) string {
+	if item.ImageType != "" {
+		return item.ImageType
+	}
+	return "image/png"
+}
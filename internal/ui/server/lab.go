@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/parser"
+	"github.com/serty2005/clipqueue/internal/shell"
+)
+
+// labDirName — рабочий каталог команд Lab внутри app.data_dir.
+const labDirName = "lab"
+
+// maxLabRunBytes ограничивает тело запроса /api/lab/run вместе с input.
+const maxLabRunBytes = 8 << 20
+
+// LabRunRequest — тело POST /api/lab/run. Command задаёт строку целиком;
+// если она пуста, команда собирается из Steps.
+type LabRunRequest struct {
+	Command string           `json:"command"`
+	Steps   []CommandStepDTO `json:"steps"`
+	Shell   string           `json:"shell"` // Пустое — lab.shell из конфигурации
+	Input   string           `json:"input"` // Передаётся на stdin
+	Push    bool             `json:"push"`  // Добавить stdout в очередь при коде выхода 0
+}
+
+// LabRunResponse — результат выполнения.
+type LabRunResponse struct {
+	Command string `json:"command"`
+	Shell   string `json:"shell"`
+	shell.Result
+	PushedID  string `json:"pushedId,omitempty"`
+	PushError string `json:"pushError,omitempty"` // Вывод получен, но в очередь не добавлен
+}
+
+// requireJSON отклоняет POST без Content-Type: application/json. Такой запрос
+// с чужой страницы браузер не отправит без CORS preflight, поэтому сайт не
+// сможет незаметно вызвать выполнение команд или изменить конфигурацию.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnsupportedMediaType)
+	json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.json_required")})
+	return false
+}
+
+// requestHeader подтверждает, что форму или файл отправил клиент ClipQueue.
+// Нестандартный заголовок браузер со сторонней страницы не добавит без CORS
+// preflight, а multipart/form-data и text/plain он отправляет без него.
+const requestHeader = "X-ClipQueue-Request"
+
+// simpleContentTypes — типы тела, с которыми браузер отправляет запрос на чужой
+// сайт без preflight; пустой тип — тело без Content-Type.
+var simpleContentTypes = map[string]bool{
+	"":                                  true,
+	"application/x-www-form-urlencoded": true,
+	"multipart/form-data":               true,
+	"text/plain":                        true,
+}
+
+// requireNonSimple отклоняет POST, который сторонняя страница может отправить
+// без preflight: тело простого типа принимается только с заголовком requestHeader.
+func requireNonSimple(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !simpleContentTypes[mediaType] || r.Header.Get(requestHeader) != "" {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnsupportedMediaType)
+	json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.request_header_required", requestHeader)})
+	return false
+}
+
+// handleLabRun выполняет пайплайн Lab через cmd.exe или PowerShell и возвращает
+// stdout, stderr и код выхода. Команда запускается в <data_dir>\lab с урезанным
+// окружением и таймаутом lab.timeout_ms; выполнение доступно только при
+// features.enable_lab и lab.allow_exec.
+func (s *Server) handleLabRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	cfg := s.config.Get()
+	if !cfg.Features.EnableLab || !cfg.Lab.AllowExec {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.lab_exec_disabled")})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxLabRunBytes)
+	var req LabRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_body")})
+		return
+	}
+	command := req.Command
+	dialect, err := s.labDialect(req.Shell)
+	if err == nil && command == "" {
+		pipeline := parser.Pipeline{Steps: labSteps(req.Steps), Dialect: parser.Dialect(dialect)}
+		command = pipeline.String()
+	}
+	if err == nil {
+		var resp LabRunResponse
+		resp, err = s.runLab(r.Context(), cfg, command, dialect, req)
+		if err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func (s *Server) runLab(ctx context.Context, cfg *config.Config, command string, dialect shell.Dialect, req LabRunRequest) (LabRunResponse, error) {
+	dir := filepath.Join(config.ResolvePath(cfg.App.DataDir), labDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return LabRunResponse{}, err
+	}
+	res, err := shell.Run(ctx, command, shell.Options{
+		Dialect: dialect,
+		Input:   req.Input,
+		Timeout: time.Duration(cfg.Lab.TimeoutMs) * time.Millisecond,
+		Dir:     dir,
+		Env:     shell.SandboxEnv(),
+	})
+	if err != nil {
+		return LabRunResponse{}, err
+	}
+	logger.Info("Lab: выполнено через %s за %s, код %d (таймаут=%v)", dialect, res.Duration.Round(time.Millisecond), res.ExitCode, res.TimedOut)
+
+	resp := LabRunResponse{Command: command, Shell: string(dialect), Result: res}
+	if req.Push && res.ExitCode == 0 && !res.TimedOut && res.Stdout != "" {
+		if id, err := s.controller.PushText(res.Stdout); err != nil {
+			resp.PushError = err.Error()
+		} else {
+			resp.PushedID = id
+		}
+	}
+	return resp, nil
+}
+
+// labDialect проверяет оболочку из запроса; пустое значение означает lab.shell.
+func (s *Server) labDialect(name string) (shell.Dialect, error) {
+	if name == "" {
+		name = s.config.Get().Lab.Shell
+	}
+	return shell.ParseDialect(name)
+}
+
+// parseLab разбирает команду по синтаксису выбранной оболочки.
+func (s *Server) parseLab(command, dialectName string) (*parser.Pipeline, error) {
+	dialect, err := s.labDialect(dialectName)
+	if err != nil {
+		return nil, err
+	}
+	return parser.Parse(command, parser.Dialect(dialect))
+}
+
+// buildLab собирает команду из шагов, расставляя кавычки по правилам оболочки.
+func (s *Server) buildLab(steps []CommandStepDTO, dialectName string) (string, error) {
+	dialect, err := s.labDialect(dialectName)
+	if err != nil {
+		return "", err
+	}
+	pipeline := parser.Pipeline{Steps: labSteps(steps), Dialect: parser.Dialect(dialect)}
+	return pipeline.String(), nil
+}
+
+func labSteps(steps []CommandStepDTO) []parser.CommandStep {
+	converted := make([]parser.CommandStep, len(steps))
+	for i, step := range steps {
+		converted[i] = parser.CommandStep{
+			Command:      step.Command,
+			CommandQuote: step.CommandQuote,
+			Args:         step.Args,
+			ArgQuotes:    step.ArgQuotes,
+			Operator:     step.Operator,
+		}
+		for _, r := range step.Redirects {
+			converted[i].Redirects = append(converted[i].Redirects, parser.Redirect{Op: r.Op, Target: r.Target, TargetQuote: r.TargetQuote})
+		}
+	}
+	return converted
+}
+
+// pipelineDTO переводит разобранный пайплайн в ответ API.
+func pipelineDTO(pipeline *parser.Pipeline) PipelineDTO {
+	dto := PipelineDTO{
+		Original: pipeline.Original,
+		Shell:    string(pipeline.Dialect),
+		Steps:    make([]CommandStepDTO, len(pipeline.Steps)),
+	}
+	for i, step := range pipeline.Steps {
+		dto.Steps[i] = CommandStepDTO{
+			Command:      step.Command,
+			CommandQuote: step.CommandQuote,
+			Args:         step.Args,
+			ArgQuotes:    step.ArgQuotes,
+			Operator:     step.Operator,
+			EnvVars:      step.EnvVars,
+		}
+		for _, r := range step.Redirects {
+			dto.Steps[i].Redirects = append(dto.Steps[i].Redirects, RedirectDTO{Op: r.Op, Target: r.Target, TargetQuote: r.TargetQuote})
+		}
+	}
+	return dto
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// localHostHandler пропускает к локальному серверу только запросы с Host
+// 127.0.0.1:<порт> или localhost:<порт>. Без этой проверки страница в браузере
+// через перепривязку DNS (DNS rebinding) обращается к 127.0.0.1 как к своему
+// источнику, и ни CORS, ни requireJSON её не останавливают.
+func (s *Server) localHostHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !localHost(r.Host, s.httpServer.Addr) {
+			logger.Warn("Запрос %s %s с чужим Host %q отклонён", r.Method, r.URL.Path, r.Host)
+			writeRemoteError(w, http.StatusForbidden, i18n.T("api.host_forbidden", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// localHost сообщает, что host — адрес локального слушателя addr под именем
+// 127.0.0.1 или localhost.
+func localHost(host, addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	name, gotPort, err := net.SplitHostPort(host)
+	if err != nil || gotPort != port {
+		return false
+	}
+	return name == "127.0.0.1" || strings.EqualFold(name, "localhost")
+}
+
+// keepFileOnlySettings возвращает в newCfg настройки, которые меняются только
+// правкой config.yml: разрешение команд Lab и раздел transforms с командами.
+// Через API их не включить, даже если запрос дошёл до сервера.
+func keepFileOnlySettings(newCfg, cur *config.Config) {
+	if newCfg.Lab.AllowExec != cur.Lab.AllowExec {
+		logger.Warn("lab.allow_exec меняется только в config.yml; значение из запроса не применено")
+		newCfg.Lab.AllowExec = cur.Lab.AllowExec
+	}
+	if !slices.Equal(newCfg.Transforms, cur.Transforms) {
+		logger.Warn("Раздел transforms меняется только в config.yml; значение из запроса не применено")
+	}
+	newCfg.Transforms = slices.Clone(cur.Transforms)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+)
+
+func TestLocalHostHandler(t *testing.T) {
+	s := &Server{httpServer: &http.Server{Addr: "127.0.0.1:8765"}}
+	h := s.localHostHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		host string
+		want int
+	}{
+		{"127.0.0.1:8765", http.StatusOK},
+		{"localhost:8765", http.StatusOK},
+		{"LocalHost:8765", http.StatusOK},
+		{"evil.test:8765", http.StatusForbidden},
+		{"127.0.0.1:9999", http.StatusForbidden},
+		{"127.0.0.1", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/api/config", nil)
+		r.Host = c.host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.want {
+			t.Errorf("Host %q: ожидался код %d, получено %d", c.host, c.want, w.Code)
+		}
+	}
+}
+
+func TestKeepFileOnlySettings(t *testing.T) {
+	cur := &config.Config{Transforms: []config.Transform{{Name: "upper", Command: "tr a-z A-Z"}}}
+	newCfg := &config.Config{Transforms: []config.Transform{{Name: "evil", Command: "calc.exe", Auto: true}}}
+	newCfg.Lab.AllowExec = true
+
+	keepFileOnlySettings(newCfg, cur)
+
+	if newCfg.Lab.AllowExec {
+		t.Fatal("lab.allow_exec не должен включаться через API")
+	}
+	if len(newCfg.Transforms) != 1 || newCfg.Transforms[0].Command != "tr a-z A-Z" {
+		t.Fatalf("transforms должны остаться из config.yml: %+v", newCfg.Transforms)
+	}
+}
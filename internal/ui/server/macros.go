@@ -0,0 +1,246 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// macroHost — часть хоста, которая нужна для изменения отдельных макросов.
+type macroHost interface {
+	ParseHotkeyToSignature(hotkeyStr string) *windows.InputSignature
+	ReplaceMacroHotkey(prev, next *config.Macro)
+}
+
+// handleMacros возвращает макросы (GET) или добавляет макрос (POST).
+func (s *Server) handleMacros(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.config.Get().Macros)
+	case http.MethodPost:
+		s.createMacro(w, r, "")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+	}
+}
+
+// handleMacro работает с одним макросом; {id} — его сигнатура. POST добавляет
+// макрос с этой сигнатурой, PUT заменяет, DELETE удаляет. Хоткей перерегистрируется
+// только у изменённого макроса, остальные настройки не сохраняются заново.
+func (s *Server) handleMacro(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		macro, ok := s.config.Get().Macro(id)
+		if !ok {
+			writeMacroError(w, config.ErrMacroNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(macro)
+	case http.MethodPost:
+		s.createMacro(w, r, id)
+	case http.MethodPut:
+		host, macro, ok := s.decodeMacro(w, r, id)
+		if !ok {
+			return
+		}
+		prev, err := s.config.UpdateMacro(id, macro)
+		if err != nil {
+			writeMacroError(w, err)
+			return
+		}
+		host.ReplaceMacroHotkey(&prev, &macro)
+		logger.Info("Макрос %q изменён", macro.Name)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(macro)
+	case http.MethodDelete:
+		host, ok := s.host.(macroHost)
+		if !ok {
+			writeMacroError(w, errors.New(i18n.T("api.hotkey_validation_unsupported")))
+			return
+		}
+		prev, err := s.config.DeleteMacro(id)
+		if err != nil {
+			writeMacroError(w, err)
+			return
+		}
+		host.ReplaceMacroHotkey(&prev, nil)
+		logger.Info("Макрос %q удалён", prev.Name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+	}
+}
+
+func (s *Server) createMacro(w http.ResponseWriter, r *http.Request, signature string) {
+	host, macro, ok := s.decodeMacro(w, r, signature)
+	if !ok {
+		return
+	}
+	if err := s.config.AddMacro(macro); err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	host.ReplaceMacroHotkey(nil, &macro)
+	logger.Info("Макрос %q добавлен", macro.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(macro)
+}
+
+// decodeMacro читает макрос из тела запроса. Без сигнатуры в теле берётся
+// signature из пути, а без неё — хоткей, как делает UI; режим по умолчанию — type.
+func (s *Server) decodeMacro(w http.ResponseWriter, r *http.Request, signature string) (macroHost, config.Macro, bool) {
+	host, ok := s.host.(macroHost)
+	if !ok {
+		writeMacroError(w, errors.New(i18n.T("api.hotkey_validation_unsupported")))
+		return nil, config.Macro{}, false
+	}
+	if !requireJSON(w, r) {
+		return nil, config.Macro{}, false
+	}
+	macro := config.Macro{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&macro); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return nil, config.Macro{}, false
+	}
+	if macro.Signature == "" {
+		macro.Signature = signature
+	}
+	macro.SetDefaults()
+	if host.ParseHotkeyToSignature(macro.Hotkey) == nil && host.ParseHotkeyToSignature(macro.Signature) == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_macro_hotkey", macro.Hotkey, macro.Signature)})
+		return nil, config.Macro{}, false
+	}
+	return host, macro, true
+}
+
+// writeMacroError отвечает JSON-ошибкой с кодом по виду ошибки конфига.
+func writeMacroError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, config.ErrMacroNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, config.ErrMacroExists):
+		status = http.StatusConflict
+	case errors.Is(err, config.ErrInvalidMacro):
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// maxMacroPackBytes ограничивает размер импортируемого набора макросов.
+const maxMacroPackBytes = 4 << 20
+
+// macroPackTypes — типы тела POST /api/macros/import. Простых типов форм среди них
+// нет, поэтому сторонняя страница не может отправить набор из браузера.
+var macroPackTypes = map[string]bool{
+	"application/json":   true,
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+}
+
+// handleMacrosExport выгружает макросы набором: ?format=yaml (по умолчанию) или json.
+func (s *Server) handleMacrosExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	pack := config.MacroPack{Macros: s.config.Get().Macros}
+	var data []byte
+	var err error
+	ext := ".yaml"
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "yaml", "yml":
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		data, err = config.MarshalMacroPack(pack.Macros)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		ext = ".json"
+		data, err = json.MarshalIndent(pack, "", "  ")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_macro_format", format)})
+		return
+	}
+	if err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "clipqueue-macros-" + time.Now().Format("20060102-150405") + ext,
+	}))
+	w.Write(data)
+}
+
+// handleMacrosImport добавляет макросы из набора в YAML или JSON. Макросы
+// сопоставляются с существующими по имени; ?conflict=skip (по умолчанию),
+// overwrite или rename задаёт, что делать при совпадении. Хоткеи
+// перерегистрируются только у добавленных и заменённых макросов.
+func (s *Server) handleMacrosImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	host, ok := s.host.(macroHost)
+	if !ok {
+		writeMacroError(w, errors.New(i18n.T("api.hotkey_validation_unsupported")))
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); !macroPackTypes[mediaType] {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.macro_pack_type_required")})
+		return
+	}
+
+	conflict, err := config.ParseMacroConflict(r.URL.Query().Get("conflict"))
+	var macros []config.Macro
+	if err == nil {
+		var data []byte
+		data, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxMacroPackBytes))
+		if err == nil {
+			macros, err = config.ParseMacroPack(data)
+		}
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	res, err := s.config.ImportMacros(macros, conflict)
+	if err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	for _, change := range res.Changes {
+		host.ReplaceMacroHotkey(change.Prev, change.Next)
+	}
+	logger.Info("Импорт макросов: добавлено %d, заменено %d, пропущено %d", len(res.Added), len(res.Updated), len(res.Skipped))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
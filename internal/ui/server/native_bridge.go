@@ -1,12 +1,13 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
 	"github.com/serty2005/clipqueue/internal/logger"
-	"github.com/serty2005/clipqueue/internal/parser"
 	"github.com/serty2005/clipqueue/platform/windows"
 )
 
@@ -18,22 +19,16 @@ type UISnapshotResponse struct {
 func (s *Server) buildHistoryDTOs() []HistoryItemDTO {
 	history := s.controller.GetHistory()
 	queue := s.controller.GetQueue()
-	order := s.controller.GetOrderStrategy()
 	currentClipboardID := s.controller.GetCurrentClipboardID()
+	pastedTo := s.controller.GetItemPasteTargets()
+	textCounts := s.controller.GetItemTextCounts()
 
 	queueMap := make(map[string]int, len(queue))
 	for i, item := range queue {
 		queueMap[item.ID] = i
 	}
 
-	var nextID string
-	if len(queue) > 0 {
-		if order == "LIFO" {
-			nextID = queue[len(queue)-1].ID
-		} else {
-			nextID = queue[0].ID
-		}
-	}
+	nextID := s.controller.NextQueueItemID()
 
 	items := make([]HistoryItemDTO, 0, len(history))
 	for i := len(history) - 1; i >= 0; i-- {
@@ -44,6 +39,15 @@ func (s *Server) buildHistoryDTOs() []HistoryItemDTO {
 			Preview:           item.Preview,
 			Timestamp:         item.Timestamp,
 			NeedsImageCapture: item.NeedsImageCapture(),
+			HasThumbnail:      len(item.Thumbnail) > 0,
+			Sensitive:         item.Sensitive,
+			Oversized:         item.Oversized,
+			FilesBytes:        item.FilesBytes,
+			FileDirs:          item.FileDirs,
+			SourceApp:         item.SourceApp,
+			SourceTitle:       item.SourceTitle,
+			Title:             item.Title,
+			Revision:          item.Revision,
 		}
 		if idx, exists := queueMap[item.ID]; exists {
 			dto.IsQueued = true
@@ -54,6 +58,10 @@ func (s *Server) buildHistoryDTOs() []HistoryItemDTO {
 		}
 		dto.IsNext = dto.IsQueued && item.ID == nextID
 		dto.IsCurrentClipboard = item.ID == currentClipboardID
+		dto.PastedTo = pastedTo[item.ID]
+		if counts, ok := textCounts[item.ID]; ok {
+			dto.TextStats = &TextCountsDTO{Chars: counts.Chars, Words: counts.Words, Lines: counts.Lines}
+		}
 		items = append(items, dto)
 	}
 
@@ -99,18 +107,19 @@ func (s *Server) NativeGetConfig() *config.Config {
 }
 
 func (s *Server) NativeSaveConfig(newCfg config.Config) (map[string]string, error) {
+	keepFileOnlySettings(&newCfg, s.config.Get())
 	host, ok := s.host.(*windows.Host)
 	if !ok {
-		return nil, fmt.Errorf("Hotkey validation not supported on this platform")
+		return nil, errors.New(i18n.T("api.hotkey_validation_unsupported"))
 	}
 	for i, macro := range newCfg.Macros {
 		if host.ParseHotkeyToSignature(macro.Hotkey) == nil && host.ParseHotkeyToSignature(macro.Signature) == nil {
-			return nil, fmt.Errorf("Invalid macro %d: neither Hotkey '%s' nor Signature '%s' is valid", i, macro.Hotkey, macro.Signature)
+			return nil, errors.New(i18n.T("api.invalid_macro", i, macro.Hotkey, macro.Signature))
 		}
 	}
 
 	if err := s.config.Update(&newCfg); err != nil {
-		return nil, fmt.Errorf("Failed to update config: %w", err)
+		return nil, fmt.Errorf("%s: %w", i18n.T("api.config_update_failed"), err)
 	}
 
 	logger.Info("Config updated successfully (native bridge)")
@@ -131,7 +140,7 @@ func (s *Server) NativeCaptureHotkey() (map[string]string, error) {
 		CaptureHotkeyWithDisplay(timeout time.Duration) (string, string, error)
 	})
 	if !ok {
-		return nil, fmt.Errorf("Hotkey capture not supported on this platform")
+		return nil, errors.New(i18n.T("api.capture_unsupported"))
 	}
 	signature, display, err := host.CaptureHotkeyWithDisplay(5 * time.Second)
 	if err != nil {
@@ -156,36 +165,20 @@ func (s *Server) NativeRemoveQueueItem(index int) (map[string]string, error) {
 	return map[string]string{"message": "item removed"}, nil
 }
 
-func (s *Server) NativeParseLab(command string) (PipelineDTO, error) {
-	pipeline, err := parser.Parse(command)
+func (s *Server) NativeParseLab(command, dialect string) (PipelineDTO, error) {
+	pipeline, err := s.parseLab(command, dialect)
 	if err != nil {
-		return PipelineDTO{}, fmt.Errorf("Parse error: %v", err)
-	}
-	dto := PipelineDTO{
-		Original: pipeline.Original,
-		Steps:    make([]CommandStepDTO, len(pipeline.Steps)),
+		return PipelineDTO{}, errors.New(i18n.T("api.parse_error", err))
 	}
-	for i, step := range pipeline.Steps {
-		dto.Steps[i] = CommandStepDTO{
-			Command:  step.Command,
-			Args:     step.Args,
-			Operator: step.Operator,
-		}
-	}
-	return dto, nil
+	return pipelineDTO(pipeline), nil
 }
 
-func (s *Server) NativeBuildLab(steps []CommandStepDTO) (BuildResponse, error) {
-	converted := make([]parser.CommandStep, len(steps))
-	for i, step := range steps {
-		converted[i] = parser.CommandStep{
-			Command:  step.Command,
-			Args:     step.Args,
-			Operator: step.Operator,
-		}
+func (s *Server) NativeBuildLab(steps []CommandStepDTO, dialect string) (BuildResponse, error) {
+	command, err := s.buildLab(steps, dialect)
+	if err != nil {
+		return BuildResponse{}, err
 	}
-	pipeline := parser.Pipeline{Steps: converted}
-	return BuildResponse{Command: pipeline.String()}, nil
+	return BuildResponse{Command: command}, nil
 }
 
 func (s *Server) NativeStartSequenceRecording() (map[string]string, error) {
@@ -193,7 +186,7 @@ func (s *Server) NativeStartSequenceRecording() (map[string]string, error) {
 		StartSequenceRecording() error
 	})
 	if !ok {
-		return nil, fmt.Errorf("Sequence recording not supported on this platform")
+		return nil, errors.New(i18n.T("api.sequence_unsupported"))
 	}
 	if err := host.StartSequenceRecording(); err != nil {
 		return nil, err
@@ -206,7 +199,7 @@ func (s *Server) NativeStopSequenceRecording() (SequenceStopResponse, error) {
 		StopSequenceRecording() (*windows.RecordedSequence, string, error)
 	})
 	if !ok {
-		return SequenceStopResponse{}, fmt.Errorf("Sequence recording not supported on this platform")
+		return SequenceStopResponse{}, errors.New(i18n.T("api.sequence_unsupported"))
 	}
 	seq, encoded, err := host.StopSequenceRecording()
 	if err != nil {
@@ -227,7 +220,7 @@ func (s *Server) NativeGetSequenceStatus(last int) (windows.SequenceRecordingSta
 		GetSequenceRecordingStatus(lastN int) (windows.SequenceRecordingStatus, error)
 	})
 	if !ok {
-		return windows.SequenceRecordingStatus{}, fmt.Errorf("Sequence status not supported on this platform")
+		return windows.SequenceRecordingStatus{}, errors.New(i18n.T("api.sequence_status_unsupported"))
 	}
 	return host.GetSequenceRecordingStatus(last)
 }
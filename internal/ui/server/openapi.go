@@ -0,0 +1,383 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/snippets"
+	"github.com/serty2005/clipqueue/internal/version"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// ErrorResponse — тело ответа с ошибкой у всех эндпоинтов API.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// MessageResponse — ответ эндпоинтов, которым нечего вернуть, кроме подтверждения.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// CapturedHotkeyResponse — результат POST /api/hotkeys/capture.
+type CapturedHotkeyResponse struct {
+	Signature string `json:"signature"`
+	Display   string `json:"display"`
+}
+
+// apiParam — параметр строки запроса.
+type apiParam struct {
+	Name        string
+	Description string
+	Integer     bool
+	Enum        []string
+}
+
+// apiOperation описывает один метод эндпоинта для спецификации OpenAPI. Схемы
+// тел строятся по типам Request и Response, поэтому спецификация меняется вместе с DTO.
+type apiOperation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Query       []apiParam
+	Request     any    // Образец JSON-тела запроса; nil — без JSON-тела
+	RequestRaw  string // Content-Type тела, которое передаётся как есть (файл импорта)
+	Multipart   string // Поле файла, если тело можно передать как multipart/form-data
+	Response    any    // Образец JSON-ответа
+	ResponseRaw string // Content-Type ответа не в JSON (файл, изображение, текст); вместе с Response — формат записей потока
+	Status      int    // Код успешного ответа; 0 — 200
+}
+
+var (
+	sinceParam = apiParam{Name: "since", Description: "today, длительность (24h) назад, дата 2006-01-02 или время RFC 3339"}
+	limitParam = apiParam{Name: "limit", Description: "Наибольшее число записей", Integer: true}
+)
+
+// apiOperations — все эндпоинты /api. Новый маршрут в NewServer нужно описать здесь же.
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/api/openapi.json", Summary: "Эта спецификация", ResponseRaw: "application/json"},
+
+	{Method: "GET", Path: "/api/config", Summary: "Текущая конфигурация", Response: config.Config{}},
+	{Method: "POST", Path: "/api/config", Summary: "Сохранить конфигурацию целиком", Request: config.Config{}, ResponseRaw: "text/plain"},
+	{Method: "POST", Path: "/api/config/validate", Summary: "Проверить конфигурацию без сохранения", Request: config.Config{}, Response: ConfigValidation{}},
+	{Method: "GET", Path: "/api/hotkeys/profiles", Summary: "Наборы хоткеев и активный набор", Response: HotkeyProfilesResponse{}},
+	{Method: "POST", Path: "/api/hotkeys/profiles", Summary: "Сделать активным набор хоткеев; пустое имя — основной", Request: HotkeyProfileRequest{}, Response: HotkeyProfilesResponse{}},
+	{Method: "POST", Path: "/api/hotkeys/capture", Summary: "Дождаться нажатия сочетания клавиш (до 5 секунд)", Response: CapturedHotkeyResponse{}},
+
+	{Method: "GET", Path: "/api/history", Summary: "История буфера обмена; общее число элементов после фильтров — в заголовке X-Total-Count", Query: []apiParam{
+		{Name: "app", Description: "Только элементы из этого процесса"}, sinceParam,
+		{Name: "until", Description: "Только скопированные раньше: today, длительность, дата или RFC 3339"},
+		{Name: "type", Description: "Типы через запятую: text, image, files"},
+		{Name: "pinned", Description: "Закрепления нет: допустимо только false"},
+		{Name: "sort", Enum: historySorts},
+		{Name: "offset", Description: "Сколько элементов пропустить", Integer: true},
+		{Name: "limit", Description: "Сколько элементов вернуть; без параметра — все", Integer: true}}, Response: []HistoryItemDTO{}},
+	{Method: "DELETE", Path: "/api/history", Summary: "Удалить элемент очереди по индексу", Query: []apiParam{
+		{Name: "index", Description: "Индекс в очереди", Integer: true}}, Response: MessageResponse{}},
+	{Method: "GET", Path: "/api/history/export", Summary: "Выгрузить историю файлом", Query: []apiParam{
+		{Name: "format", Enum: []string{"json", "csv", "zip"}}}, ResponseRaw: "application/octet-stream"},
+	{Method: "GET", Path: "/api/history/apps", Summary: "Статистика истории по приложениям-источникам", Query: []apiParam{sinceParam}, Response: []app.SourceAppStats{}},
+	{Method: "POST", Path: "/api/history/import", Summary: "Импорт истории из ClipQueue, Ditto или CopyQ", Query: []apiParam{
+		{Name: "format", Enum: []string{"clipqueue", "ditto", "copyq"}}}, RequestRaw: "application/octet-stream", Multipart: "file", Response: ImportResponse{}},
+	{Method: "POST", Path: "/api/import", Summary: "То же, что POST /api/history/import", Query: []apiParam{
+		{Name: "format", Enum: []string{"clipqueue", "ditto", "copyq"}}}, RequestRaw: "application/octet-stream", Multipart: "file", Response: ImportResponse{}},
+
+	{Method: "POST", Path: "/api/queue", Summary: "Добавить текст (JSON) или изображение (multipart, поле image, заголовок X-ClipQueue-Request) в очередь", Request: QueuePushRequest{}, Multipart: "image", Response: QueuePushResponse{}},
+	{Method: "GET", Path: "/api/queue/state", Summary: "Состояние очереди", Response: QueueStateResponse{}},
+	{Method: "GET", Path: "/api/events", Summary: "Поток Server-Sent Events: state, capture, enqueue, paste", Response: StreamEvent{}, ResponseRaw: "text/event-stream"},
+	{Method: "POST", Path: "/api/queue/toggle", Summary: "Включить или выключить запись в очередь", Response: QueueStateResponse{}},
+	{Method: "POST", Path: "/api/queue/order/toggle", Summary: "Переключить порядок FIFO/LIFO", Response: QueueStateResponse{}},
+	{Method: "POST", Path: "/api/queue/paste-next", Summary: "Вставить следующий элемент в активное окно", Response: QueueStateResponse{}},
+	{Method: "POST", Path: "/api/queue/paste-to", Summary: "Вставить следующий элемент в выбранное окно", Request: PasteToRequest{}, Response: QueueStateResponse{}},
+	{Method: "POST", Path: "/api/queue/merge", Summary: "Склеить текстовые элементы очереди", Request: QueueMergeRequest{}, Response: QueuePushResponse{}},
+	{Method: "POST", Path: "/api/queue/clear", Summary: "Очистить очередь", Response: MessageResponse{}},
+	{Method: "GET", Path: "/api/windows", Summary: "Окна, в которые можно вставить", Response: []windows.WindowInfo{}},
+	{Method: "POST", Path: "/api/copy", Summary: "Записать в буфер элемент (?id=) или текст (JSON {text})", Query: []apiParam{
+		{Name: "id", Description: "ID элемента истории"}}, Request: QueuePushRequest{}, Response: MessageResponse{}},
+
+	{Method: "GET", Path: "/api/item/{id}", Summary: "Полное содержимое элемента", Query: []apiParam{
+		{Name: "format", Description: "binary — изображение без обёртки JSON", Enum: []string{"binary"}}}, Response: ItemContentDTO{}},
+	{Method: "PUT", Path: "/api/item/{id}", Summary: "Сохранить правку текста элемента", Request: ItemEditRequest{}, Response: ItemContentDTO{}},
+	{Method: "GET", Path: "/api/item/{id}/thumbnail", Summary: "Миниатюра изображения", ResponseRaw: "image/png"},
+	{Method: "GET", Path: "/api/item/{id}/download", Summary: "Элемент файлом", ResponseRaw: "application/octet-stream"},
+	{Method: "POST", Path: "/api/item/{id}/ocr", Summary: "Распознать текст изображения", Response: OCRResponse{}},
+	{Method: "POST", Path: "/api/item/{id}/format", Summary: "Отформатировать JSON или XML новым элементом", Response: FormatResponse{}},
+	{Method: "POST", Path: "/api/item/{id}/qr", Summary: "QR-код из текста элемента", Query: []apiParam{
+		{Name: "to", Enum: []string{"clipboard", "queue"}}}, Response: QRResponse{}},
+	{Method: "GET", Path: "/api/item/{id}/stats", Summary: "Статистика текста", Response: TextStatsDTO{}},
+	{Method: "GET", Path: "/api/item/{id}/files", Summary: "Дерево файлов элемента Files", Response: FileTreeDTO{}},
+	{Method: "POST", Path: "/api/item/{id}/promote", Summary: "Сделать элемент очереди следующим", Response: QueueStateResponse{}},
+	{Method: "POST", Path: "/api/item/{id}/demote", Summary: "Перенести элемент в хвост очереди", Response: QueueStateResponse{}},
+	{Method: "POST", Path: "/api/item/{id}/split", Summary: "Разделить текст на элементы очереди", Request: ItemSplitRequest{}, Response: ItemSplitResponse{}},
+
+	{Method: "GET", Path: "/api/macros", Summary: "Все макросы", Response: []config.Macro{}},
+	{Method: "POST", Path: "/api/macros", Summary: "Добавить макрос", Request: config.Macro{}, Response: config.Macro{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/macros/export", Summary: "Выгрузить макросы набором", Query: []apiParam{
+		{Name: "format", Enum: []string{"yaml", "json"}}}, ResponseRaw: "application/yaml"},
+	{Method: "POST", Path: "/api/macros/import", Summary: "Загрузить набор макросов (YAML или JSON)", Query: []apiParam{
+		{Name: "conflict", Enum: []string{"skip", "overwrite", "rename"}}}, RequestRaw: "application/yaml", Response: config.MacroImportResult{}},
+	{Method: "GET", Path: "/api/macros/{id}", Summary: "Макрос по сигнатуре", Response: config.Macro{}},
+	{Method: "POST", Path: "/api/macros/{id}", Summary: "Добавить макрос с этой сигнатурой", Request: config.Macro{}, Response: config.Macro{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/macros/{id}", Summary: "Заменить макрос", Request: config.Macro{}, Response: config.Macro{}},
+	{Method: "DELETE", Path: "/api/macros/{id}", Summary: "Удалить макрос", Status: http.StatusNoContent},
+
+	{Method: "GET", Path: "/api/snippets", Summary: "Сниппеты папки и тега", Query: []apiParam{
+		{Name: "folder", Description: "Папка вместе с вложенными"}, {Name: "tag"}}, Response: []snippets.Snippet{}},
+	{Method: "POST", Path: "/api/snippets", Summary: "Добавить сниппет", Request: SnippetRequest{}, Response: snippets.Snippet{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/snippets/folders", Summary: "Папки библиотеки", Response: []string{}},
+	{Method: "GET", Path: "/api/snippets/search", Summary: "Нечёткий поиск сниппетов", Query: []apiParam{
+		{Name: "q"}, limitParam}, Response: []snippets.SearchResult{}},
+	{Method: "GET", Path: "/api/snippets/{id}", Summary: "Сниппет по ID", Response: snippets.Snippet{}},
+	{Method: "PUT", Path: "/api/snippets/{id}", Summary: "Изменить сниппет", Request: SnippetRequest{}, Response: snippets.Snippet{}},
+	{Method: "DELETE", Path: "/api/snippets/{id}", Summary: "Удалить сниппет", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/api/snippets/{id}/paste", Summary: "Вставить сниппет", Request: SnippetPasteRequest{}, Status: http.StatusNoContent},
+
+	{Method: "GET", Path: "/api/paste/targets", Summary: "Приложения-получатели и выученные способы вставки", Response: []app.TargetSuggestion{}},
+	{Method: "POST", Path: "/api/paste/targets/apply", Summary: "Записать выученные настройки приложения в clipboard.paste_methods", Request: PasteTargetApplyRequest{}, Response: config.PasteMethodRule{}},
+	{Method: "GET", Path: "/api/paste/history", Summary: "Последние вставки", Query: []apiParam{limitParam}, Response: []app.PasteRecord{}},
+	{Method: "GET", Path: "/api/audit", Summary: "Журнал вставок из очереди и макросов, новые первыми", Query: []apiParam{
+		sinceParam,
+		{Name: "until", Description: "Только вставки раньше: today, длительность, дата или RFC 3339"},
+		{Name: "item", Description: "ID элемента истории"},
+		{Name: "app", Description: "Процесс окна-получателя"},
+		{Name: "limit", Description: "Наибольшее число записей; по умолчанию 100, 0 — все", Integer: true}}, Response: []app.PasteRecord{}},
+	{Method: "GET", Path: "/api/stats", Summary: "Сводка по истории, очереди и вставкам", Response: app.Stats{}},
+	{Method: "GET", Path: "/api/pause", Summary: "Состояние паузы ClipQueue", Response: PauseStateResponse{}},
+	{Method: "POST", Path: "/api/pause", Summary: "Приостановить ClipQueue: снять хуки ввода и слежение за буфером обмена", Request: PauseRequest{}, Response: PauseStateResponse{}},
+	{Method: "POST", Path: "/api/resume", Summary: "Снять паузу", Response: PauseStateResponse{}},
+	{Method: "GET", Path: "/api/diagnostics", Summary: "Диагностика: какие приложения блокировали буфер обмена", Response: DiagnosticsResponse{}},
+
+	{Method: "POST", Path: "/api/sequence/start", Summary: "Начать запись последовательности клавиш", Response: MessageResponse{}},
+	{Method: "POST", Path: "/api/sequence/stop", Summary: "Закончить запись", Response: SequenceStopResponse{}},
+	{Method: "GET", Path: "/api/sequence/status", Summary: "Ход записи", Query: []apiParam{
+		{Name: "last", Description: "Сколько последних событий вернуть", Integer: true}}, Response: windows.SequenceRecordingStatus{}},
+
+	{Method: "POST", Path: "/api/lab/parse", Summary: "Разобрать команду на шаги", Request: ParseRequest{}, Response: PipelineDTO{}},
+	{Method: "POST", Path: "/api/lab/build", Summary: "Собрать команду из шагов", Request: BuildRequest{}, Response: BuildResponse{}},
+	{Method: "POST", Path: "/api/lab/run", Summary: "Выполнить команду (при lab.allow_exec)", Request: LabRunRequest{}, Response: LabRunResponse{}},
+
+	{Method: "GET", Path: "/api/debug/runtime", Summary: "Состояние рантайма Go (при debug.enable_pprof)", Response: RuntimeStatsResponse{}},
+}
+
+// openAPISpec строится один раз: маршруты и DTO не меняются во время работы.
+var openAPISpec = sync.OnceValue(buildOpenAPI)
+
+// handleOpenAPI отдаёт спецификацию OpenAPI 3.0 для всех эндпоинтов /api.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	w.Write(openAPISpec())
+}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+func buildOpenAPI() []byte {
+	schemas := newSchemaRegistry()
+	errorSchema := schemas.of(reflect.TypeOf(ErrorResponse{}))
+	paths := make(map[string]map[string]any)
+	for _, op := range apiOperations {
+		operation := map[string]any{"summary": op.Summary}
+
+		var params []any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		for _, p := range op.Query {
+			schema := map[string]any{"type": "string"}
+			if p.Integer {
+				schema["type"] = "integer"
+			}
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+			param := map[string]any{"name": p.Name, "in": "query", "schema": schema}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+
+		body := make(map[string]any)
+		if op.Request != nil {
+			body["application/json"] = map[string]any{"schema": schemas.of(reflect.TypeOf(op.Request))}
+		}
+		if op.RequestRaw != "" {
+			body[op.RequestRaw] = map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}
+		}
+		if op.Multipart != "" {
+			body["multipart/form-data"] = map[string]any{"schema": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{op.Multipart: map[string]any{"type": "string", "format": "binary"}},
+			}}
+		}
+		if len(body) > 0 {
+			operation["requestBody"] = map[string]any{"content": body}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		switch {
+		case op.Response != nil:
+			contentType := "application/json"
+			if op.ResponseRaw != "" {
+				contentType = op.ResponseRaw
+			}
+			success["content"] = map[string]any{contentType: map[string]any{"schema": schemas.of(reflect.TypeOf(op.Response))}}
+		case op.ResponseRaw != "":
+			success["content"] = map[string]any{op.ResponseRaw: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}
+		}
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"default": map[string]any{
+				"description": "Ошибка",
+				"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+			},
+		}
+
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]any)
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "ClipQueue HTTP API",
+			"version":     version.Version,
+			"description": "Локальный API ClipQueue. Сервер слушает только 127.0.0.1; запросы, меняющие конфигурацию или запускающие команды, принимают только application/json.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{"remoteToken": map[string]any{
+				"type":        "http",
+				"scheme":      "bearer",
+				"description": "remote.token; нужен только на адресе remote.listen, локальный 127.0.0.1 токен не проверяет",
+			}},
+		},
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		// Спецификация собирается из статических данных: ошибка здесь — ошибка в коде.
+		panic(err)
+	}
+	return data
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaRegistry строит JSON-схемы по типам Go так же, как их кодирует encoding/json.
+// Именованные структуры попадают в components/schemas и подставляются ссылкой.
+type schemaRegistry struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: make(map[string]any), names: make(map[reflect.Type]string)}
+}
+
+func (g *schemaRegistry) of(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.componentName(t)
+			g.names[t] = name
+			g.components[name] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// object описывает поля структуры; встроенные структуры без имени в теге
+// раскрываются, как это делает encoding/json.
+func (g *schemaRegistry) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range g.object(ft)["properties"].(map[string]any) {
+				props[k] = v
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.of(f.Type)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
+
+// componentName — имя типа; совпадающие имена из разных пакетов получают префикс пакета.
+func (g *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.components[name]; !taken {
+		return name
+	}
+	pkg := []rune(path.Base(t.PkgPath()))
+	pkg[0] = unicode.ToUpper(pkg[0])
+	return string(pkg) + name
+}
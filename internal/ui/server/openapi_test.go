@@ -0,0 +1,31 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"testing"
+)
+
+// TestOpenAPICoversRoutes сверяет спецификацию с маршрутами, которые регистрирует сервер.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec(), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	route := regexp.MustCompile(`mux\.HandleFunc\("(/api/[^"]+)"`)
+	for _, file := range []string{"server.go", "debug.go"} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range route.FindAllStringSubmatch(string(src), -1) {
+			if len(spec.Paths[m[1]]) == 0 {
+				t.Errorf("маршрут %s не описан в apiOperations", m[1])
+			}
+		}
+	}
+}
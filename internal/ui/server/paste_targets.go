@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// PasteTargetApplyRequest — тело POST /api/paste/targets/apply.
+type PasteTargetApplyRequest struct {
+	Process string `json:"process"` // Имя процесса из GET /api/paste/targets
+}
+
+// handlePasteTargetApply записывает выученные настройки приложения в правило
+// clipboard.paste_methods: прежнее правило того же процесса заменяется.
+// Преобразование, которого уже нет в transforms, не записывается.
+func (s *Server) handlePasteTargetApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+
+	var req PasteTargetApplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+	rule, err := s.controller.TargetRule(req.Process)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	err = s.config.Mutate(func(cfg *config.Config) {
+		if !slices.ContainsFunc(cfg.Transforms, func(t config.Transform) bool { return t.Name == rule.Transform }) {
+			rule.Transform = ""
+		}
+		cfg.SetPasteMethodRule(rule)
+	})
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.config_update_failed") + ": " + err.Error()})
+		return
+	}
+	logger.Info("Применены выученные настройки вставки для %s: способ %s, задержка %d мс, преобразование %q",
+		rule.Process, rule.Method, rule.RestoreDelayMs, rule.Transform)
+	if s.OnConfigUpdate != nil {
+		s.OnConfigUpdate()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// PasteToRequest — тело POST /api/queue/paste-to.
+type PasteToRequest struct {
+	HWND uintptr `json:"hwnd"` // Дескриптор окна из GET /api/windows
+}
+
+// handleWindows возвращает окна, в которые можно вставить элемент очереди.
+func (s *Server) handleWindows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(windows.ListWindows())
+}
+
+// handleQueuePasteTo активирует выбранное окно и вставляет в него следующий элемент очереди.
+func (s *Server) handleQueuePasteTo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	var req PasteToRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+	if req.HWND == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.hwnd_required")})
+		return
+	}
+	if err := s.controller.PasteNextTo(req.HWND); err != nil {
+		writeItemError(w, err)
+		return
+	}
+	enabled, count, order := s.controller.GetQueueState()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueueStateResponse{Enabled: enabled, Count: count, Order: order})
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// pauseHost — часть хоста, которая ставит ClipQueue на паузу.
+type pauseHost interface {
+	SetPaused(paused bool, resumeAfter time.Duration) error
+	PauseState() (paused bool, until time.Time)
+	FullscreenApp() string
+}
+
+// PauseStateResponse — состояние паузы; until есть, только если пауза снимется сама.
+// fullscreen — процесс полноэкранного окна, из-за которого ClipQueue приостановлен
+// независимо от паузы.
+type PauseStateResponse struct {
+	Paused     bool       `json:"paused"`
+	Until      *time.Time `json:"until,omitempty"`
+	Fullscreen string     `json:"fullscreen,omitempty"`
+}
+
+// PauseRequest — тело POST /api/pause. Без minutes действует app.pause_minutes;
+// 0 — пауза до POST /api/resume.
+type PauseRequest struct {
+	Minutes *int `json:"minutes,omitempty"`
+}
+
+func pauseState(host pauseHost) PauseStateResponse {
+	paused, until := host.PauseState()
+	resp := PauseStateResponse{Paused: paused, Fullscreen: host.FullscreenApp()}
+	if paused && !until.IsZero() {
+		resp.Until = &until
+	}
+	return resp
+}
+
+// handlePause возвращает состояние паузы (GET) или ставит ClipQueue на паузу (POST):
+// хуки клавиатуры и мыши снимаются, буфер обмена не отслеживается.
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	host, ok := s.host.(pauseHost)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.pause_unsupported")})
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req PauseRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+				return
+			}
+		}
+		minutes := s.config.Get().App.PauseMinutes
+		if req.Minutes != nil {
+			minutes = *req.Minutes
+		}
+		if minutes < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_pause_minutes")})
+			return
+		}
+		if err := host.SetPaused(true, time.Duration(minutes)*time.Minute); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pauseState(host))
+}
+
+// handleResume снимает паузу и отменяет таймер автоматического снятия.
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+	host, ok := s.host.(pauseHost)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.pause_unsupported")})
+		return
+	}
+	if err := host.SetPaused(false, 0); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pauseState(host))
+}
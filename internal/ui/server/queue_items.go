@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// QueueMergeRequest — тело POST /api/queue/merge.
+type QueueMergeRequest struct {
+	IDs       []string `json:"ids"`       // Элементы очереди в порядке склейки
+	Separator string   `json:"separator"` // Разделитель между текстами; пустой — без разделителя
+}
+
+// handleQueueMerge склеивает текстовые элементы очереди в один.
+func (s *Server) handleQueueMerge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	var req QueueMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+	merged, err := s.controller.MergeQueueItems(req.IDs, req.Separator)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	enabled, count, order := s.controller.GetQueueState()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueuePushResponse{ID: merged.ID, Queue: QueueStateResponse{Enabled: enabled, Count: count, Order: order}})
+}
+
+// ItemSplitRequest — тело POST /api/item/{id}/split.
+type ItemSplitRequest struct {
+	By        string `json:"by"`        // lines (по умолчанию), tab, cells или regex
+	Pattern   string `json:"pattern"`   // Регулярное выражение для regex
+	KeepEmpty bool   `json:"keepEmpty"` // Оставлять пустые части (пустые ячейки таблицы)
+}
+
+// ItemSplitResponse — ID новых элементов в порядке текста и состояние очереди.
+type ItemSplitResponse struct {
+	IDs   []string           `json:"ids"`
+	Queue QueueStateResponse `json:"queue"`
+}
+
+// handleItemSplit делит текстовый элемент на элементы очереди.
+func (s *Server) handleItemSplit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	var req ItemSplitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return
+	}
+	parts, err := s.controller.SplitItem(r.PathValue("id"), req.By, req.Pattern, req.KeepEmpty)
+	if err != nil {
+		writeItemError(w, err)
+		return
+	}
+	resp := ItemSplitResponse{IDs: make([]string, 0, len(parts))}
+	for _, part := range parts {
+		resp.IDs = append(resp.IDs, part.ID)
+	}
+	resp.Queue.Enabled, resp.Queue.Count, resp.Queue.Order = s.controller.GetQueueState()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
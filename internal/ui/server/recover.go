@@ -0,0 +1,33 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/i18n"
+)
+
+// recoverHandler пишет отчёт о панике в обработчике и отвечает 500 вместо
+// оборванного соединения. http.ErrAbortHandler пропускается: им обработчик
+// намеренно прерывает ответ.
+func recoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+			crash.Report("http "+r.Method+" "+r.URL.Path, rec, debug.Stack())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.internal_error")})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// remoteCookie хранит токен в браузере другого устройства после входа по ссылке ?token=.
+// SameSite=Strict не даёт чужим сайтам отправлять запросы с этим cookie.
+const remoteCookie = "cq_token"
+
+// ApplyRemote запускает, останавливает или перезапускает слушатель удалённого
+// доступа по настройкам remote. Локальный адрес 127.0.0.1 работает независимо от него.
+func (s *Server) ApplyRemote(cfg *config.Config) {
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	if s.remote != nil && reflect.DeepEqual(s.remoteApplied.Remote, cfg.Remote) {
+		return
+	}
+	s.stopRemoteLocked()
+	s.remoteApplied.Remote = cfg.Remote
+	if !cfg.Remote.Enabled {
+		return
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.Remote.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.ResolvePath(cfg.Remote.TLSCert), config.ResolvePath(cfg.Remote.TLSKey))
+		if err != nil {
+			logger.Error("Удалённый доступ не запущен: сертификат TLS: %v", err)
+			return
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+	ln, err := net.Listen("tcp", cfg.Remote.Listen)
+	if err != nil {
+		logger.Error("Удалённый доступ не запущен: %v", err)
+		return
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+		scheme = "https"
+	} else {
+		logger.Warn("Удалённый доступ без TLS: токен и данные передаются по сети открытым текстом")
+	}
+	srv := &http.Server{
+		Handler:           recoverHandler(s.remoteHandler(s.remoteRoutes())),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.remote = srv
+	logger.Info("Удалённый доступ: API и интерфейс слушают %s://%s", scheme, ln.Addr())
+	crash.Go("remote.Serve", func() {
+		if err := srv.Serve(ln); err != http.ErrServerClosed {
+			logger.Error("Удалённый доступ остановлен с ошибкой: %v", err)
+		}
+	})
+}
+
+func (s *Server) stopRemoteLocked() {
+	if s.remote == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.remote.Shutdown(ctx); err != nil {
+		logger.Warn("Удалённый доступ: %v", err)
+	}
+	s.remote = nil
+}
+
+// remoteHandler пропускает к API только запросы с токеном и с разрешённых страниц.
+// Запрос без Origin (curl, скрипт) проверяется только по токену; из браузера —
+// ещё и по Origin: собственный интерфейс и remote.allowed_origins получают заголовки CORS,
+// остальные сайты — 403.
+func (s *Server) remoteHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote := s.config.Get().Remote
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			if !originAllowed(origin, remote.AllowedOrigins) {
+				logger.Warn("Удалённый доступ: запрос %s %s со страницы %s отклонён", r.Method, r.URL.Path, origin)
+				writeRemoteError(w, http.StatusForbidden, i18n.T("api.origin_forbidden", origin))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+			if r.Method == http.MethodOptions {
+				// Предварительный запрос CORS приходит без токена.
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		// Вход по ссылке: токен переносится в cookie и убирается из адреса,
+		// чтобы не остаться в истории браузера.
+		if token := r.URL.Query().Get("token"); token != "" && r.Method == http.MethodGet && tokenMatches(token, remote.Token) {
+			http.SetCookie(w, &http.Cookie{Name: remoteCookie, Value: token, Path: "/", HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteStrictMode})
+			query := r.URL.Query()
+			query.Del("token")
+			target := *r.URL
+			target.RawQuery = query.Encode()
+			http.Redirect(w, r, target.RequestURI(), http.StatusSeeOther)
+			return
+		}
+
+		if !remoteAuthorized(r, remote.Token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ClipQueue"`)
+			writeRemoteError(w, http.StatusUnauthorized, i18n.T("api.unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteRoutes — маршруты, доступные через remote.listen. Выполнение команд Lab,
+// отладка, запись настроек (в том числе импорт макросов и переключение профилей
+// хоткеев), захват хоткеев и диагностика сюда не входят: остальные запросы
+// получают 404 или 405. GET /api/config отдаёт настройки без секретов.
+func (s *Server) remoteRoutes() http.Handler {
+	mux := http.NewServeMux()
+	for _, pattern := range []string{
+		"GET /{$}",
+		"GET /app-api.js",
+		"GET /api/openapi.json",
+		"GET /api/events",
+		"GET /api/hotkeys/profiles",
+		"/api/history",
+		"GET /api/history/export",
+		"GET /api/history/apps",
+		"/api/queue",
+		"/api/queue/state",
+		"/api/queue/toggle",
+		"/api/queue/order/toggle",
+		"/api/queue/paste-next",
+		"/api/queue/paste-to",
+		"/api/queue/merge",
+		"/api/queue/clear",
+		"/api/windows",
+		"/api/copy",
+		"/api/item/{id}",
+		"/api/item/{id}/thumbnail",
+		"/api/item/{id}/download",
+		"/api/item/{id}/ocr",
+		"/api/item/{id}/format",
+		"/api/item/{id}/qr",
+		"/api/item/{id}/stats",
+		"/api/item/{id}/files",
+		"/api/item/{id}/promote",
+		"/api/item/{id}/split",
+		"/api/item/{id}/demote",
+		"GET /api/macros",
+		"GET /api/macros/export",
+		"GET /api/macros/{id}",
+		"/api/snippets",
+		"/api/snippets/folders",
+		"/api/snippets/search",
+		"/api/snippets/{id}",
+		"/api/snippets/{id}/paste",
+		"GET /api/paste/targets",
+		"GET /api/paste/history",
+		"GET /api/stats",
+		"GET /api/audit",
+		"/api/pause",
+		"/api/resume",
+		"/api/sequence/start",
+		"/api/sequence/stop",
+		"/api/sequence/status",
+	} {
+		mux.Handle(pattern, s.mux)
+	}
+	mux.HandleFunc("GET /api/config", s.handleRemoteConfig)
+	return mux
+}
+
+// handleRemoteConfig отдаёт настройки другому устройству без токенов, ключей и паролей.
+func (s *Server) handleRemoteConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redactSecrets(s.config.Get()))
+}
+
+// redactSecrets очищает в копии настроек поля с токенами, ключами и паролями.
+func redactSecrets(cfg *config.Config) *config.Config {
+	cfg.Remote.Token = ""
+	cfg.GRPC.Token = ""
+	cfg.Sync.Key = ""
+	cfg.MQTT.Password = ""
+	cfg.Webhooks.Secret = ""
+	return cfg
+}
+
+// remoteAuthorized проверяет токен из заголовка authorization или из cookie входа.
+func remoteAuthorized(r *http.Request, token string) bool {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return tokenMatches(strings.TrimPrefix(auth, "Bearer "), token)
+	}
+	if cookie, err := r.Cookie(remoteCookie); err == nil {
+		return tokenMatches(cookie.Value, token)
+	}
+	return false
+}
+
+// tokenMatches сравнивает за постоянное время; пустой токен в конфиге не пускает никого.
+func tokenMatches(got, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// sameOrigin сообщает, что запрос пришёл со страницы самого сервера.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+func originAllowed(origin string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeRemoteError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
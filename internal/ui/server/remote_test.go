@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/serty2005/clipqueue/internal/config"
+)
+
+func TestRemoteHandler(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Remote.Token = "0123456789abcdef"
+	cfg.Remote.AllowedOrigins = []string{"http://nas.local:8080"}
+	s := &Server{config: config.NewSafeConfig(cfg)}
+	h := s.remoteHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		origin string
+		auth   string
+		want   int
+	}{
+		{"без токена", "GET", "/api/queue/state", "", "", http.StatusUnauthorized},
+		{"неверный токен", "GET", "/api/queue/state", "", "Bearer wrong", http.StatusUnauthorized},
+		{"токен в заголовке", "GET", "/api/queue/state", "", "Bearer 0123456789abcdef", http.StatusOK},
+		{"свой интерфейс", "POST", "/api/queue/clear", "http://example.com", "Bearer 0123456789abcdef", http.StatusOK},
+		{"чужой сайт", "POST", "/api/queue/clear", "http://evil.test", "Bearer 0123456789abcdef", http.StatusForbidden},
+		{"разрешённый сайт", "POST", "/api/queue/clear", "http://nas.local:8080", "Bearer 0123456789abcdef", http.StatusOK},
+		{"preflight", "OPTIONS", "/api/queue/clear", "http://nas.local:8080", "", http.StatusNoContent},
+		{"вход по ссылке", "GET", "/?token=0123456789abcdef", "", "", http.StatusSeeOther},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "http://example.com"+c.target, nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if c.auth != "" {
+			r.Header.Set("Authorization", c.auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.want {
+			t.Errorf("%s: ожидался код %d, получено %d", c.name, c.want, w.Code)
+		}
+	}
+}
+
+func TestRemoteRoutes(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Remote.Token = "0123456789abcdef"
+	cfg.Sync.Key = "общий пароль"
+	cfg.MQTT.Password = "mqtt"
+	s := &Server{
+		config: config.NewSafeConfig(cfg),
+		mux:    http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+	h := s.remoteRoutes()
+
+	cases := []struct {
+		method, target string
+		want           int
+	}{
+		{"GET", "/", http.StatusOK},
+		{"GET", "/api/queue/state", http.StatusOK},
+		{"POST", "/api/queue/clear", http.StatusOK},
+		{"GET", "/api/item/abc/download", http.StatusOK},
+		{"GET", "/api/config", http.StatusOK},
+		{"POST", "/api/config", http.StatusMethodNotAllowed},
+		{"POST", "/api/lab/run", http.StatusNotFound},
+		{"GET", "/debug/pprof/", http.StatusNotFound},
+		{"POST", "/api/hotkeys/capture", http.StatusNotFound},
+		{"POST", "/api/macros/import", http.StatusMethodNotAllowed},
+		{"POST", "/api/paste/targets/apply", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(c.method, c.target, nil))
+		if w.Code != c.want {
+			t.Errorf("%s %s: ожидался код %d, получено %d", c.method, c.target, c.want, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api/config", nil))
+	for _, secret := range []string{cfg.Remote.Token, cfg.Sync.Key, cfg.MQTT.Password} {
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("GET /api/config через remote.listen выдаёт секрет %q", secret)
+		}
+	}
+}
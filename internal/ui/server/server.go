@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/serty2005/clipqueue/internal/app"
 	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/i18n"
 	"github.com/serty2005/clipqueue/internal/logger"
-	"github.com/serty2005/clipqueue/internal/parser"
 	"github.com/serty2005/clipqueue/platform/windows"
 )
 
@@ -21,38 +23,70 @@ var embedFS embed.FS
 
 // HistoryItemDTO represents a history item for API responses
 type HistoryItemDTO struct {
-	ID                 string    `json:"id"`
-	Type               string    `json:"type"`
-	Preview            string    `json:"preview"`
-	Timestamp          time.Time `json:"timestamp"`
-	IsQueued           bool      `json:"isQueued"`
-	QueueIndex         int       `json:"queueIndex"`
-	IsNext             bool      `json:"isNext"`
-	IsCurrentClipboard bool      `json:"isCurrentClipboard"`
-	NeedsImageCapture  bool      `json:"needsImageCapture"`
+	ID                 string         `json:"id"`
+	Type               string         `json:"type"`
+	Preview            string         `json:"preview"`
+	Timestamp          time.Time      `json:"timestamp"`
+	IsQueued           bool           `json:"isQueued"`
+	QueueIndex         int            `json:"queueIndex"`
+	IsNext             bool           `json:"isNext"`
+	IsCurrentClipboard bool           `json:"isCurrentClipboard"`
+	NeedsImageCapture  bool           `json:"needsImageCapture"`
+	HasThumbnail       bool           `json:"hasThumbnail"`
+	PastedTo           []string       `json:"pastedTo,omitempty"`
+	TextStats          *TextCountsDTO `json:"textStats,omitempty"`
+	Sensitive          string         `json:"sensitive,omitempty"`
+	Oversized          bool           `json:"oversized,omitempty"`
+	FilesBytes         int64          `json:"filesBytes,omitempty"` // Размер файлов элемента Files без содержимого каталогов
+	FileDirs           int            `json:"fileDirs,omitempty"`
+	SourceApp          string         `json:"sourceApp,omitempty"`   // Процесс, из которого скопирован элемент
+	SourceTitle        string         `json:"sourceTitle,omitempty"` // Заголовок его окна
+	Title              string         `json:"title,omitempty"`       // Отображаемое имя, например заголовок страницы ссылки
+	Revision           int            `json:"revision,omitempty"`    // Номер правки текста, 0 — исходный элемент
+}
+
+// TextCountsDTO — краткая статистика текстового элемента для списка истории.
+type TextCountsDTO struct {
+	Chars int `json:"chars"`
+	Words int `json:"words"`
+	Lines int `json:"lines"`
 }
 
 // CommandStepDTO represents a single step in a command pipeline for API
 type CommandStepDTO struct {
-	Command  string   `json:"command"`
-	Args     []string `json:"args"`
-	Operator string   `json:"operator"`
+	Command      string        `json:"command"`
+	CommandQuote string        `json:"commandQuote,omitempty"`
+	Args         []string      `json:"args"`
+	ArgQuotes    []string      `json:"argQuotes,omitempty"`
+	Operator     string        `json:"operator"`
+	Redirects    []RedirectDTO `json:"redirects,omitempty"`
+	EnvVars      []string      `json:"envVars,omitempty"`
+}
+
+// RedirectDTO represents a redirection such as "> out.txt" or "2>&1"
+type RedirectDTO struct {
+	Op          string `json:"op"`
+	Target      string `json:"target,omitempty"`
+	TargetQuote string `json:"targetQuote,omitempty"`
 }
 
 // PipelineDTO represents the parsed command structure for API
 type PipelineDTO struct {
 	Steps    []CommandStepDTO `json:"steps"`
 	Original string           `json:"original"`
+	Shell    string           `json:"shell"`
 }
 
 // ParseRequest is the request body for parsing a command
 type ParseRequest struct {
 	Command string `json:"command"`
+	Shell   string `json:"shell"` // cmd or powershell; empty means lab.shell
 }
 
 // BuildRequest is the request body for building a command from steps
 type BuildRequest struct {
 	Steps []CommandStepDTO `json:"steps"`
+	Shell string           `json:"shell"`
 }
 
 // BuildResponse is the response body containing the built command
@@ -78,6 +112,16 @@ type Server struct {
 	host           interface{} // Pointer to platform-specific host implementation
 	controller     *app.Controller
 	OnConfigUpdate func() // Callback for config changes
+	// SwitchHotkeyProfile делает активным набор хоткеев и перерегистрирует их; задаётся в main.
+	SwitchHotkeyProfile func(name string) error
+
+	mux           http.Handler
+	remoteMu      sync.Mutex
+	remote        *http.Server  // Слушатель remote.listen; nil — удалённый доступ выключен
+	remoteApplied config.Config // Настройки, с которыми запущен remote
+
+	eventsMu sync.Mutex
+	events   map[chan StreamEvent]struct{} // Подписчики GET /api/events
 }
 
 func NewServer(cfg *config.SafeConfig, host interface{}, controller *app.Controller) *Server {
@@ -85,25 +129,68 @@ func NewServer(cfg *config.SafeConfig, host interface{}, controller *app.Control
 
 	s := &Server{
 		httpServer: &http.Server{
-			Addr:    "127.0.0.1:0", // Используем случайный свободный порт
-			Handler: mux,
+			Addr: "127.0.0.1:0", // Используем случайный свободный порт
 		},
 		config:     cfg,
 		host:       host,
 		controller: controller,
+		mux:        mux,
+		events:     make(map[chan StreamEvent]struct{}),
 	}
+	s.httpServer.Handler = recoverHandler(s.localHostHandler(mux))
 
 	// Настраиваем маршруты
 	mux.HandleFunc("/", s.handleIndex)
 	mux.HandleFunc("/app-api.js", s.handleAppAPIJS)
+	mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("/api/events", s.handleEvents)
 	mux.HandleFunc("/api/config", s.handleConfig)
+	mux.HandleFunc("/api/config/validate", s.handleConfigValidate)
 	mux.HandleFunc("/api/hotkeys/capture", s.handleCaptureHotkey)
+	mux.HandleFunc("/api/hotkeys/profiles", s.handleHotkeyProfiles)
 	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/history/export", s.handleHistoryExport)
+	mux.HandleFunc("/api/history/apps", s.handleHistoryApps)
+	mux.HandleFunc("/api/history/import", s.handleImport)
+	mux.HandleFunc("/api/queue", s.handleQueuePush)
 	mux.HandleFunc("/api/queue/state", s.handleQueueState)
 	mux.HandleFunc("/api/queue/toggle", s.handleQueueToggle)
 	mux.HandleFunc("/api/queue/order/toggle", s.handleQueueOrderToggle)
+	mux.HandleFunc("/api/queue/paste-next", s.handleQueuePasteNext)
+	mux.HandleFunc("/api/queue/paste-to", s.handleQueuePasteTo)
+	mux.HandleFunc("/api/queue/merge", s.handleQueueMerge)
+	mux.HandleFunc("/api/windows", s.handleWindows)
 	mux.HandleFunc("/api/queue/clear", s.handleQueueClear)
 	mux.HandleFunc("/api/copy", s.handleCopy)
+	mux.HandleFunc("/api/import", s.handleImport)
+	mux.HandleFunc("/api/item/{id}", s.handleItem)
+	mux.HandleFunc("/api/item/{id}/thumbnail", s.handleItemThumbnail)
+	mux.HandleFunc("/api/item/{id}/download", s.handleItemDownload)
+	mux.HandleFunc("/api/item/{id}/ocr", s.handleItemOCR)
+	mux.HandleFunc("/api/item/{id}/format", s.handleItemFormat)
+	mux.HandleFunc("/api/item/{id}/qr", s.handleItemQR)
+	mux.HandleFunc("/api/item/{id}/stats", s.handleItemStats)
+	mux.HandleFunc("/api/item/{id}/files", s.handleItemFiles)
+	mux.HandleFunc("/api/item/{id}/promote", s.handleItemPromote)
+	mux.HandleFunc("/api/item/{id}/split", s.handleItemSplit)
+	mux.HandleFunc("/api/item/{id}/demote", s.handleItemDemote)
+	mux.HandleFunc("/api/macros", s.handleMacros)
+	mux.HandleFunc("/api/macros/export", s.handleMacrosExport)
+	mux.HandleFunc("/api/macros/import", s.handleMacrosImport)
+	mux.HandleFunc("/api/macros/{id}", s.handleMacro)
+	mux.HandleFunc("/api/snippets", s.handleSnippets)
+	mux.HandleFunc("/api/snippets/folders", s.handleSnippetFolders)
+	mux.HandleFunc("/api/snippets/search", s.handleSnippetSearch)
+	mux.HandleFunc("/api/snippets/{id}", s.handleSnippet)
+	mux.HandleFunc("/api/snippets/{id}/paste", s.handleSnippetPaste)
+	mux.HandleFunc("/api/paste/targets", s.handlePasteTargets)
+	mux.HandleFunc("/api/paste/targets/apply", s.handlePasteTargetApply)
+	mux.HandleFunc("/api/paste/history", s.handlePasteHistory)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/audit", s.handleAudit)
+	mux.HandleFunc("/api/pause", s.handlePause)
+	mux.HandleFunc("/api/resume", s.handleResume)
+	mux.HandleFunc("/api/diagnostics", s.handleDiagnostics)
 	mux.HandleFunc("/api/sequence/start", s.handleSequenceStart)
 	mux.HandleFunc("/api/sequence/stop", s.handleSequenceStop)
 	mux.HandleFunc("/api/sequence/status", s.handleSequenceStatus)
@@ -111,6 +198,9 @@ func NewServer(cfg *config.SafeConfig, host interface{}, controller *app.Control
 	// Lab API routes
 	mux.HandleFunc("/api/lab/parse", s.handleLabParse)
 	mux.HandleFunc("/api/lab/build", s.handleLabBuild)
+	mux.HandleFunc("/api/lab/run", s.handleLabRun)
+
+	s.registerDebugRoutes(mux)
 
 	return s
 }
@@ -124,6 +214,9 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(cfg)
 		return
 	case http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
 		// Update config
 		var newCfg config.Config
 		if err := json.NewDecoder(r.Body).Decode(&newCfg); err != nil {
@@ -133,6 +226,8 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		keepFileOnlySettings(&newCfg, s.config.Get())
+
 		// Validate macros
 		host, ok := s.host.(*windows.Host)
 		if !ok {
@@ -201,13 +296,17 @@ func (s *Server) Start() error {
 
 func (s *Server) Stop(ctx context.Context) error {
 	logger.Info("stopping server...")
+	s.remoteMu.Lock()
+	s.stopRemoteLocked()
+	s.remoteMu.Unlock()
+	s.closeEvents()
 	return s.httpServer.Shutdown(ctx)
 }
 
 func (s *Server) handleCaptureHotkey(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -217,7 +316,7 @@ func (s *Server) handleCaptureHotkey(w http.ResponseWriter, r *http.Request) {
 	})
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Hotkey capture not supported on this platform"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.capture_unsupported")})
 		return
 	}
 
@@ -231,70 +330,43 @@ func (s *Server) handleCaptureHotkey(w http.ResponseWriter, r *http.Request) {
 
 	// Return captured hotkey
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"signature": signature, "display": display})
+	json.NewEncoder(w).Encode(CapturedHotkeyResponse{Signature: signature, Display: display})
 }
 
 func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		// Get history items
-		history := s.controller.GetHistory()
-		queue := s.controller.GetQueue()
-		order := s.controller.GetOrderStrategy()
-		currentClipboardID := s.controller.GetCurrentClipboardID()
-		var items []HistoryItemDTO
-
-		// Create map for quick lookup in queue
-		queueMap := make(map[string]int) // id -> index
-		for i, item := range queue {
-			queueMap[item.ID] = i
-		}
-
-		// Determine next for paste
-		var nextID string
-		if len(queue) > 0 {
-			if order == "LIFO" {
-				nextID = queue[len(queue)-1].ID
-			} else {
-				nextID = queue[0].ID
-			}
+		query := r.URL.Query()
+		filter, err := parseHistoryFilter(query, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
 		}
-
-		for i := len(history) - 1; i >= 0; i-- {
-			item := history[i]
-			dto := HistoryItemDTO{
-				ID:                item.ID,
-				Type:              item.Type.String(),
-				Preview:           item.Preview,
-				Timestamp:         item.Timestamp,
-				NeedsImageCapture: item.NeedsImageCapture(),
-			}
-			if idx, exists := queueMap[item.ID]; exists {
-				dto.IsQueued = true
-				dto.QueueIndex = idx
-			} else {
-				dto.IsQueued = false
-				dto.QueueIndex = -1
-			}
-			dto.IsNext = dto.IsQueued && item.ID == nextID
-			dto.IsCurrentClipboard = item.ID == currentClipboardID
-			items = append(items, dto)
+		page, err := parseHistoryPage(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_page", err)})
+			return
 		}
+		items := filter.apply(s.buildHistoryDTOs())
+		// Общее число элементов после фильтров — чтобы клиент знал, сколько страниц осталось.
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(items)
+		json.NewEncoder(w).Encode(page.apply(items))
 		return
 	case http.MethodDelete:
 		// Delete item by index from queue
 		indexStr := r.URL.Query().Get("index")
 		if indexStr == "" {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "index parameter required"})
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.index_required")})
 			return
 		}
 		var index int
 		if _, err := fmt.Sscanf(indexStr, "%d", &index); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "invalid index"})
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_index")})
 			return
 		}
 		if err := s.controller.RemoveItem(index); err != nil {
@@ -307,7 +379,7 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 }
@@ -315,7 +387,7 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleQueueClear(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -327,7 +399,7 @@ func (s *Server) handleQueueClear(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleQueueState(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -343,7 +415,7 @@ func (s *Server) handleQueueState(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleQueueToggle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -357,10 +429,27 @@ func (s *Server) handleQueueToggle(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleQueuePasteNext(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	s.controller.PasteNext()
+	enabled, count, order := s.controller.GetQueueState()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueueStateResponse{
+		Enabled: enabled,
+		Count:   count,
+		Order:   order,
+	})
+}
+
 func (s *Server) handleQueueOrderToggle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -377,14 +466,18 @@ func (s *Server) handleQueueOrderToggle(w http.ResponseWriter, r *http.Request)
 func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
 	idStr := r.URL.Query().Get("id")
+	if idStr == "" && isJSONRequest(r) {
+		s.handleCopyText(w, r)
+		return
+	}
 	if idStr == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "id parameter required"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.id_required")})
 		return
 	}
 
@@ -398,10 +491,53 @@ func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "item copied to clipboard"})
 }
 
+func (s *Server) handlePasteTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.controller.GetTargetSuggestions())
+}
+
+func (s *Server) handlePasteHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_limit")})
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.controller.GetPasteRecords(limit))
+}
+
+// handleStats возвращает сводку по истории, очереди и вставкам.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.controller.GetStats())
+}
+
 func (s *Server) handleSequenceStart(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -410,7 +546,7 @@ func (s *Server) handleSequenceStart(w http.ResponseWriter, r *http.Request) {
 	})
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Sequence recording not supported on this platform"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.sequence_unsupported")})
 		return
 	}
 
@@ -427,7 +563,7 @@ func (s *Server) handleSequenceStart(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSequenceStop(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -436,7 +572,7 @@ func (s *Server) handleSequenceStop(w http.ResponseWriter, r *http.Request) {
 	})
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Sequence recording not supported on this platform"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.sequence_unsupported")})
 		return
 	}
 
@@ -459,7 +595,7 @@ func (s *Server) handleSequenceStop(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSequenceStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
@@ -467,7 +603,7 @@ func (s *Server) handleSequenceStatus(w http.ResponseWriter, r *http.Request) {
 	if lastStr := r.URL.Query().Get("last"); lastStr != "" {
 		if _, err := fmt.Sscanf(lastStr, "%d", &last); err != nil || last <= 0 {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "invalid last parameter"})
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_last")})
 			return
 		}
 	}
@@ -477,7 +613,7 @@ func (s *Server) handleSequenceStatus(w http.ResponseWriter, r *http.Request) {
 	})
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Sequence status not supported on this platform"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.sequence_status_unsupported")})
 		return
 	}
 
@@ -495,69 +631,49 @@ func (s *Server) handleSequenceStatus(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleLabParse(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
 	var req ParseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_body")})
 		return
 	}
 
-	pipeline, err := parser.Parse(req.Command)
+	pipeline, err := s.parseLab(req.Command, req.Shell)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Parse error: %v", err)})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.parse_error", err)})
 		return
 	}
 
-	// Convert to DTO
-	dto := PipelineDTO{
-		Original: pipeline.Original,
-		Steps:    make([]CommandStepDTO, len(pipeline.Steps)),
-	}
-
-	for i, step := range pipeline.Steps {
-		dto.Steps[i] = CommandStepDTO{
-			Command:  step.Command,
-			Args:     step.Args,
-			Operator: step.Operator,
-		}
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dto)
+	json.NewEncoder(w).Encode(pipelineDTO(pipeline))
 }
 
 func (s *Server) handleLabBuild(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
 		return
 	}
 
 	var req BuildRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_body")})
 		return
 	}
 
-	// Convert from DTO
-	steps := make([]parser.CommandStep, len(req.Steps))
-	for i, step := range req.Steps {
-		steps[i] = parser.CommandStep{
-			Command:  step.Command,
-			Args:     step.Args,
-			Operator: step.Operator,
-		}
+	builtCommand, err := s.buildLab(req.Steps, req.Shell)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
 	}
 
-	pipeline := parser.Pipeline{Steps: steps}
-	builtCommand := pipeline.String()
-
 	resp := BuildResponse{
 		Command: builtCommand,
 	}
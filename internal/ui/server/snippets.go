@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/snippets"
+)
+
+// SnippetRequest — тело POST /api/snippets и PUT /api/snippets/{id}.
+type SnippetRequest struct {
+	Category string   `json:"category"` // Путь папки через «/»
+	Tags     []string `json:"tags"`
+	Title    string   `json:"title"`
+	Body     string   `json:"body"`
+}
+
+// SnippetPasteRequest — тело POST /api/snippets/{id}/paste. Без hwnd сниппет
+// вставляется в активное окно.
+type SnippetPasteRequest struct {
+	HWND   uintptr           `json:"hwnd"`
+	Fields map[string]string `json:"fields"` // Значения полей {{field:Имя}} по имени
+}
+
+// handleSnippets возвращает библиотеку сниппетов (GET) или добавляет сниппет (POST).
+func (s *Server) handleSnippets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		list := s.controller.ListSnippets(query.Get("folder"), query.Get("tag"))
+		if list == nil {
+			list = []snippets.Snippet{}
+		}
+		json.NewEncoder(w).Encode(list)
+	case http.MethodPost:
+		req, ok := decodeSnippetRequest(w, r)
+		if !ok {
+			return
+		}
+		snippet, err := s.controller.CreateSnippet(req)
+		if err != nil {
+			writeItemError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(snippet)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+	}
+}
+
+// handleSnippetFolders возвращает папки библиотеки вместе с родительскими.
+func (s *Server) handleSnippetFolders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	folders := s.controller.SnippetFolders()
+	if folders == nil {
+		folders = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folders)
+}
+
+// handleSnippetSearch ищет сниппеты нечётким совпадением для быстрого выбора.
+func (s *Server) handleSnippetSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_limit")})
+			return
+		}
+	}
+
+	results := s.controller.SearchSnippets(r.URL.Query().Get("q"), limit)
+	if results == nil {
+		results = []snippets.SearchResult{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// handleSnippet читает (GET), изменяет (PUT) или удаляет (DELETE) сниппет.
+func (s *Server) handleSnippet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		snippet, err := s.controller.GetSnippet(id)
+		if err != nil {
+			writeItemError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snippet)
+	case http.MethodPut:
+		req, ok := decodeSnippetRequest(w, r)
+		if !ok {
+			return
+		}
+		snippet, err := s.controller.UpdateSnippet(id, req)
+		if err != nil {
+			writeItemError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snippet)
+	case http.MethodDelete:
+		if err := s.controller.DeleteSnippet(id); err != nil {
+			writeItemError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+	}
+}
+
+// handleSnippetPaste вставляет сниппет в активное или выбранное окно.
+func (s *Server) handleSnippetPaste(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.method_not_allowed")})
+		return
+	}
+
+	var req SnippetPasteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+			return
+		}
+	}
+	if err := s.controller.PasteSnippet(r.PathValue("id"), req.HWND, req.Fields); err != nil {
+		writeItemError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeSnippetRequest(w http.ResponseWriter, r *http.Request) (snippets.Snippet, bool) {
+	var req SnippetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": i18n.T("api.invalid_json", err)})
+		return snippets.Snippet{}, false
+	}
+	return snippets.Snippet{Category: req.Category, Tags: req.Tags, Title: req.Title, Body: req.Body}, true
+}
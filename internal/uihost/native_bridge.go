@@ -12,8 +12,8 @@ type NativeBridge struct {
 	ClearQueue         func() (interface{}, error)
 	CopyHistoryItem    func(id string) (interface{}, error)
 	RemoveQueueItem    func(index int) (interface{}, error)
-	ParseLab           func(command string) (interface{}, error)
-	BuildLab           func(steps []map[string]interface{}) (interface{}, error)
+	ParseLab           func(command, shell string) (interface{}, error)
+	BuildLab           func(steps []map[string]interface{}, shell string) (interface{}, error)
 	StartSequence      func() (interface{}, error)
 	StopSequence       func() (interface{}, error)
 	GetSequenceStatus  func(last int) (interface{}, error)
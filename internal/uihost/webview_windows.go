@@ -186,6 +186,9 @@ func (h *WebViewUIHost) runUIThread() {
 	}
 
 	h.applyFramelessStyle(hwnd)
+	if err := windows.ApplyAppIcon(hwnd); err != nil {
+		logger.Warn("Не удалось установить иконку окна UI: %v", err)
+	}
 	h.applyStoredBounds(hwnd, initialState)
 	h.bindNativeBridge(wv)
 	h.installWindowSubclasses(hwnd)
@@ -522,17 +525,17 @@ func (h *WebViewUIHost) bindNativeBridge(wv webview2.WebView) {
 		}
 		return bridge.RemoveQueueItem(index)
 	})
-	mustBind("cqNativeParseLab", func(command string) (interface{}, error) {
+	mustBind("cqNativeParseLab", func(command, shell string) (interface{}, error) {
 		if bridge.ParseLab == nil {
 			return nil, fmt.Errorf("native parse lab bridge not configured")
 		}
-		return bridge.ParseLab(command)
+		return bridge.ParseLab(command, shell)
 	})
-	mustBind("cqNativeBuildLab", func(steps []map[string]interface{}) (interface{}, error) {
+	mustBind("cqNativeBuildLab", func(steps []map[string]interface{}, shell string) (interface{}, error) {
 		if bridge.BuildLab == nil {
 			return nil, fmt.Errorf("native build lab bridge not configured")
 		}
-		return bridge.BuildLab(steps)
+		return bridge.BuildLab(steps, shell)
 	})
 	mustBind("cqNativeStartSequenceRecording", func() (interface{}, error) {
 		if bridge.StartSequence == nil {
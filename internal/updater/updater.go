@@ -0,0 +1,432 @@
+// Package updater проверяет релизы ClipQueue на GitHub и подготавливает
+// новый исполняемый файл к замене при следующем запуске.
+//
+// Файл принимается, только если подпись Ed25519 из SignatureName сходится
+// с ключом SigningKey, вшитым в сборку: подменённый репозиторий или ответ
+// сервера не может установить чужой исполняемый файл.
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepo — репозиторий, релизы которого проверяются по умолчанию.
+	DefaultRepo = "serty2005/clipQueue"
+	// AssetName — имя исполняемого файла в релизе.
+	AssetName = "clipqueue.exe"
+	// SignatureName — имя файла подписи в релизе: подпись Ed25519 от SHA-256
+	// исполняемого файла в base64.
+	SignatureName = AssetName + ".sig"
+
+	defaultAPIBase   = "https://api.github.com"
+	maxAssetSize     = 256 << 20
+	maxSignatureSize = 1 << 10
+)
+
+// SigningKey — открытый ключ Ed25519 (base64), которым подписываются релизы.
+// Задаётся при сборке релиза вместе с версией:
+//
+//	go build -ldflags "-X github.com/serty2005/clipqueue/internal/updater.SigningKey=<ключ>"
+//
+// Сборка без ключа находит новые версии, но не загружает и не устанавливает их.
+var SigningKey = ""
+
+var (
+	// ErrNoAsset возвращается, если в релизе нет исполняемого файла.
+	ErrNoAsset = errors.New("в релизе нет " + AssetName)
+	// ErrNoSignature возвращается, если в релизе нет файла подписи.
+	ErrNoSignature = errors.New("в релизе нет " + SignatureName)
+	// ErrNoSigningKey возвращается, если сборка не содержит ключа проверки подписи.
+	ErrNoSigningKey = errors.New("в сборке нет ключа проверки обновлений")
+	// ErrBadSignature возвращается, если подпись не сходится с файлом.
+	ErrBadSignature = errors.New("подпись обновления не сходится")
+)
+
+// repoPattern — имя репозитория GitHub вида owner/name.
+var repoPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?/[A-Za-z0-9._-]+$`)
+
+// ValidRepo сообщает, похоже ли repo на имя репозитория GitHub owner/name.
+func ValidRepo(repo string) bool {
+	return repoPattern.MatchString(repo) && !strings.HasSuffix(repo, "/..") && !strings.HasSuffix(repo, "/.")
+}
+
+// signingKey разбирает SigningKey.
+func signingKey() (ed25519.PublicKey, error) {
+	if SigningKey == "" {
+		return nil, ErrNoSigningKey
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(SigningKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("неверный ключ проверки обновлений")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// CanVerify сообщает, содержит ли сборка ключ проверки обновлений: без него
+// обновление можно только предложить, но не загрузить.
+func CanVerify() bool {
+	_, err := signingKey()
+	return err == nil
+}
+
+// verify сверяет подпись sig (base64) с SHA-256 файла digest.
+func verify(digest, sig []byte) error {
+	key, err := signingKey()
+	if err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+	if err != nil || !ed25519.Verify(key, digest, raw) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Asset — файл, прикреплённый к релизу.
+type Asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+	Size        int64  `json:"size"`
+}
+
+// Release — описание релиза из GitHub API.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Name    string  `json:"name"`
+	HTMLURL string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Version возвращает номер версии релиза без префикса "v".
+func (r Release) Version() string {
+	return strings.TrimPrefix(strings.TrimSpace(r.TagName), "v")
+}
+
+// Asset ищет файл релиза по имени без учёта регистра.
+func (r Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if strings.EqualFold(a.Name, name) {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Checker запрашивает последний релиз репозитория.
+type Checker struct {
+	Repo    string
+	APIBase string
+	Client  *http.Client
+}
+
+func (c *Checker) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// Latest возвращает последний опубликованный релиз.
+func (c *Checker) Latest(ctx context.Context) (Release, error) {
+	repo := c.Repo
+	if repo == "" {
+		repo = DefaultRepo
+	}
+	if !ValidRepo(repo) {
+		return Release{}, fmt.Errorf("неверное имя репозитория %q", repo)
+	}
+	base := c.APIBase
+	if base == "" {
+		base = defaultAPIBase
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+"/repos/"+repo+"/releases/latest", nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return Release{}, fmt.Errorf("запрос релизов: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("запрос релизов: HTTP %d", resp.StatusCode)
+	}
+
+	var rel Release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&rel); err != nil {
+		return Release{}, fmt.Errorf("разбор ответа GitHub: %w", err)
+	}
+	return rel, nil
+}
+
+// CompareVersions сравнивает версии вида 1.2.3 (префикс "v" допускается) покомпонентно.
+// Нечисловые компоненты считаются нулём.
+func CompareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsNewer сообщает, новее ли релиз текущей версии.
+func IsNewer(rel Release, current string) bool {
+	return rel.Version() != "" && CompareVersions(rel.Version(), current) > 0
+}
+
+// Download скачивает исполняемый файл релиза во временный файл рядом с dest
+// и переименовывает его в dest, только если загрузка завершилась полностью
+// и подпись из SignatureName сходится с ключом SigningKey. Подпись сохраняется
+// рядом как dest.sig, чтобы ApplyStaged проверил файл ещё раз перед заменой.
+func (c *Checker) Download(ctx context.Context, rel Release, dest string) error {
+	asset, ok := rel.Asset(AssetName)
+	if !ok {
+		return ErrNoAsset
+	}
+	sigAsset, ok := rel.Asset(SignatureName)
+	if !ok {
+		return ErrNoSignature
+	}
+	if _, err := signingKey(); err != nil {
+		return err
+	}
+	sig, err := c.fetch(ctx, sigAsset, maxSignatureSize)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.get(ctx, asset)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp := dest + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	sum := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, sum), io.LimitReader(resp.Body, maxAssetSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+	case n > maxAssetSize:
+		err = fmt.Errorf("файл %s больше %d байт", asset.Name, maxAssetSize)
+	case asset.Size > 0 && n != asset.Size:
+		err = fmt.Errorf("файл %s загружен не полностью: %d из %d байт", asset.Name, n, asset.Size)
+	default:
+		if err = verify(sum.Sum(nil), sig); err != nil {
+			err = fmt.Errorf("файл %s: %w", asset.Name, err)
+		}
+	}
+	if err == nil {
+		err = os.WriteFile(signaturePath(dest), sig, 0644)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// get запрашивает файл релиза.
+func (c *Checker) get(ctx context.Context, asset Asset) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.DownloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Загрузка бинарника дольше запроса API, поэтому общий таймаут клиента не используется.
+	client := *c.client()
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("загрузка %s: %w", asset.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("загрузка %s: HTTP %d", asset.Name, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// fetch читает небольшой файл релиза в память.
+func (c *Checker) fetch(ctx context.Context, asset Asset, limit int64) ([]byte, error) {
+	resp, err := c.get(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("загрузка %s: %w", asset.Name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("файл %s больше %d байт", asset.Name, limit)
+	}
+	return data, nil
+}
+
+// signaturePath возвращает путь сохранённой подписи файла.
+func signaturePath(path string) string {
+	return path + ".sig"
+}
+
+// verifyFile сверяет файл path с подписью, сохранённой рядом с ним.
+func verifyFile(path string) error {
+	sig, err := os.ReadFile(signaturePath(path))
+	if err != nil {
+		return ErrBadSignature
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return err
+	}
+	return verify(sum.Sum(nil), sig)
+}
+
+// StagedPath возвращает путь, куда складывается загруженное обновление.
+func StagedPath(dataDir string) string {
+	return filepath.Join(dataDir, "update", AssetName)
+}
+
+func stagedVersionPath(dataDir string) string {
+	return filepath.Join(dataDir, "update", "version")
+}
+
+// Stage скачивает исполняемый файл релиза в каталог обновления и запоминает его версию.
+func (c *Checker) Stage(ctx context.Context, rel Release, dataDir string) error {
+	if err := c.Download(ctx, rel, StagedPath(dataDir)); err != nil {
+		return err
+	}
+	return os.WriteFile(stagedVersionPath(dataDir), []byte(rel.Version()), 0644)
+}
+
+// StagedVersion возвращает версию подготовленного обновления или пустую строку.
+func StagedVersion(dataDir string) string {
+	if _, err := os.Stat(StagedPath(dataDir)); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(stagedVersionPath(dataDir))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// DiscardStaged удаляет подготовленное обновление.
+func DiscardStaged(dataDir string) {
+	os.Remove(StagedPath(dataDir))
+	os.Remove(signaturePath(StagedPath(dataDir)))
+	os.Remove(stagedVersionPath(dataDir))
+}
+
+// oldSuffix — расширение прежнего исполняемого файла после замены.
+const oldSuffix = ".old"
+
+// ApplyStaged подменяет exePath подготовленным файлом, если его версия новее current
+// и подпись файла сходится с ключом SigningKey; файл без верной подписи удаляется.
+// Windows разрешает переименовать запущенный .exe, поэтому вызывать можно при
+// завершении работы: новая версия стартует при следующем запуске. Возвращает
+// установленную версию или пустую строку, если устанавливать нечего.
+func ApplyStaged(exePath, dataDir, current string) (string, error) {
+	staged := StagedVersion(dataDir)
+	if staged == "" {
+		return "", nil
+	}
+	if CompareVersions(staged, current) <= 0 {
+		// Уже установлена эта или более новая версия.
+		DiscardStaged(dataDir)
+		return "", nil
+	}
+	if err := verifyFile(StagedPath(dataDir)); err != nil {
+		DiscardStaged(dataDir)
+		return "", fmt.Errorf("обновление %s отклонено: %w", staged, err)
+	}
+
+	old := exePath + oldSuffix
+	os.Remove(old)
+	if err := os.Rename(exePath, old); err != nil {
+		return "", fmt.Errorf("не удалось переименовать %s: %w", exePath, err)
+	}
+	if err := moveFile(StagedPath(dataDir), exePath); err != nil {
+		// Возвращаем прежний файл, чтобы не остаться без исполняемого.
+		os.Rename(old, exePath)
+		return "", fmt.Errorf("не удалось установить обновление: %w", err)
+	}
+	os.Remove(signaturePath(StagedPath(dataDir)))
+	os.Remove(stagedVersionPath(dataDir))
+	return staged, nil
+}
+
+// CleanupOld удаляет исполняемый файл, оставшийся от предыдущей замены.
+func CleanupOld(exePath string) {
+	os.Remove(exePath + oldSuffix)
+}
+
+// moveFile переносит файл, в том числе между томами (data_dir может лежать не на диске с .exe).
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,228 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useSigningKey подставляет на время теста новый ключ проверки и возвращает
+// функцию подписи содержимого.
+func useSigningKey(t *testing.T) func(data []byte) string {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := SigningKey
+	SigningKey = base64.StdEncoding.EncodeToString(pub)
+	t.Cleanup(func() { SigningKey = prev })
+	return func(data []byte) string {
+		digest := sha256.Sum256(data)
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, digest[:]))
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.4", "1.2.3", 1},
+		{"1.10", "1.9.9", 1},
+		{"1.2", "1.2.0", 0},
+		{"0.9", "1.0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, ожидалось %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValidRepo(t *testing.T) {
+	for repo, want := range map[string]bool{
+		"serty2005/clipQueue": true,
+		"owner/repo.name":     true,
+		"owner":               false,
+		"owner/repo/extra":    false,
+		"../repo":             false,
+		"owner/..":            false,
+		"owner/repo?x=1":      false,
+		"":                    false,
+	} {
+		if got := ValidRepo(repo); got != want {
+			t.Errorf("ValidRepo(%q) = %v, ожидалось %v", repo, got, want)
+		}
+	}
+}
+
+// releaseServer отдаёт релиз v2.0.0 с исполняемым файлом payload и подписью sig.
+func releaseServer(t *testing.T, payload []byte, sig string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/repos/owner/repo/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Release{
+			TagName: "v2.0.0",
+			Assets: []Asset{
+				{Name: "ClipQueue.exe", DownloadURL: srv.URL + "/asset", Size: int64(len(payload))},
+				{Name: SignatureName, DownloadURL: srv.URL + "/sig", Size: int64(len(sig))},
+			},
+		})
+	})
+	mux.HandleFunc("/asset", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	})
+	mux.HandleFunc("/sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sig))
+	})
+	return srv
+}
+
+func TestLatestAndDownload(t *testing.T) {
+	sign := useSigningKey(t)
+	payload := []byte("new binary")
+	srv := releaseServer(t, payload, sign(payload))
+
+	c := &Checker{Repo: "owner/repo", APIBase: srv.URL}
+	rel, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if !IsNewer(rel, "1.5.0") || IsNewer(rel, "2.0.0") {
+		t.Fatalf("неверное сравнение версии релиза %q", rel.Version())
+	}
+
+	dataDir := t.TempDir()
+	if err := c.Stage(context.Background(), rel, dataDir); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	if data, _ := os.ReadFile(StagedPath(dataDir)); string(data) != string(payload) {
+		t.Fatalf("неожиданное содержимое загруженного файла: %q", data)
+	}
+	if v := StagedVersion(dataDir); v != "2.0.0" {
+		t.Fatalf("StagedVersion = %q, ожидалось 2.0.0", v)
+	}
+}
+
+func TestDownloadRejectsBadSignature(t *testing.T) {
+	sign := useSigningKey(t)
+	payload := []byte("new binary")
+	c := &Checker{Repo: "owner/repo", APIBase: releaseServer(t, payload, sign([]byte("other binary"))).URL}
+	rel, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+
+	dataDir := t.TempDir()
+	if err := c.Stage(context.Background(), rel, dataDir); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Stage = %v, ожидалась ErrBadSignature", err)
+	}
+	if _, err := os.Stat(StagedPath(dataDir)); !os.IsNotExist(err) {
+		t.Fatal("файл с неверной подписью не должен оставаться в каталоге обновления")
+	}
+
+	SigningKey = ""
+	if err := c.Stage(context.Background(), rel, dataDir); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("Stage без ключа = %v, ожидалась ErrNoSigningKey", err)
+	}
+
+	rel.Assets = rel.Assets[:1]
+	if err := c.Stage(context.Background(), rel, dataDir); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("Stage без подписи = %v, ожидалась ErrNoSignature", err)
+	}
+}
+
+func TestLatestRejectsBadRepo(t *testing.T) {
+	c := &Checker{Repo: "owner/repo/../../evil", APIBase: "http://127.0.0.1:1"}
+	if _, err := c.Latest(context.Background()); err == nil {
+		t.Fatal("неверное имя репозитория должно отклоняться")
+	}
+}
+
+// stageFile кладёт в каталог обновления файл content, подписанный sig.
+func stageFile(t *testing.T, dataDir, version, content, sig string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(StagedPath(dataDir)), 0755)
+	os.WriteFile(StagedPath(dataDir), []byte(content), 0755)
+	os.WriteFile(signaturePath(StagedPath(dataDir)), []byte(sig), 0644)
+	os.WriteFile(stagedVersionPath(dataDir), []byte(version), 0644)
+}
+
+func TestApplyStaged(t *testing.T) {
+	sign := useSigningKey(t)
+	dir := t.TempDir()
+	exe := filepath.Join(dir, AssetName)
+	dataDir := filepath.Join(dir, "data")
+	os.WriteFile(exe, []byte("old"), 0755)
+
+	if v, err := ApplyStaged(exe, dataDir, "1.0.0"); err != nil || v != "" {
+		t.Fatalf("без подготовленного файла ничего не должно меняться: v=%q err=%v", v, err)
+	}
+
+	stageFile(t, dataDir, "1.1.0", "new", sign([]byte("new")))
+	v, err := ApplyStaged(exe, dataDir, "1.0.0")
+	if err != nil || v != "1.1.0" {
+		t.Fatalf("ApplyStaged: v=%q err=%v", v, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new" {
+		t.Fatalf("исполняемый файл не заменён: %q", data)
+	}
+	if StagedVersion(dataDir) != "" {
+		t.Fatal("подготовленное обновление должно быть израсходовано")
+	}
+
+	CleanupOld(exe)
+	if _, err := os.Stat(exe + oldSuffix); !os.IsNotExist(err) {
+		t.Fatal("прежний файл должен быть удалён")
+	}
+}
+
+func TestApplyStagedSkipsOlderVersion(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, AssetName)
+	dataDir := filepath.Join(dir, "data")
+	os.WriteFile(exe, []byte("current"), 0755)
+	stageFile(t, dataDir, "1.0.0", "stale", "")
+
+	if v, err := ApplyStaged(exe, dataDir, "1.0.0"); err != nil || v != "" {
+		t.Fatalf("та же версия не должна устанавливаться: v=%q err=%v", v, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "current" {
+		t.Fatalf("исполняемый файл не должен меняться: %q", data)
+	}
+	if _, err := os.Stat(StagedPath(dataDir)); !os.IsNotExist(err) {
+		t.Fatal("устаревшее обновление должно быть удалено")
+	}
+}
+
+func TestApplyStagedRejectsTamperedFile(t *testing.T) {
+	sign := useSigningKey(t)
+	dir := t.TempDir()
+	exe := filepath.Join(dir, AssetName)
+	dataDir := filepath.Join(dir, "data")
+	os.WriteFile(exe, []byte("current"), 0755)
+	stageFile(t, dataDir, "2.0.0", "tampered", sign([]byte("new")))
+
+	if v, err := ApplyStaged(exe, dataDir, "1.0.0"); !errors.Is(err, ErrBadSignature) || v != "" {
+		t.Fatalf("подменённый файл не должен устанавливаться: v=%q err=%v", v, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "current" {
+		t.Fatalf("исполняемый файл не должен меняться: %q", data)
+	}
+	if StagedVersion(dataDir) != "" {
+		t.Fatal("файл с неверной подписью должен быть удалён")
+	}
+}
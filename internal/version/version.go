@@ -0,0 +1,14 @@
+// Package version хранит версию сборки ClipQueue.
+package version
+
+// Version задаётся при сборке релиза:
+//
+//	go build -ldflags "-X github.com/serty2005/clipqueue/internal/version.Version=1.2.3"
+//
+// Локальные сборки остаются "dev" и не проверяют обновления.
+var Version = "dev"
+
+// IsRelease сообщает, собран ли бинарник с номером версии.
+func IsRelease() bool {
+	return Version != "" && Version != "dev"
+}
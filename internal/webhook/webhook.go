@@ -0,0 +1,215 @@
+// Package webhook отправляет события ClipQueue (захват, добавление в очередь,
+// вставка) POST-запросами с JSON на адреса пользователя, например в n8n или Home Assistant.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/version"
+)
+
+// События, на которые можно подписаться.
+const (
+	EventCapture = "capture" // Новый элемент в буфере обмена
+	EventEnqueue = "enqueue" // Элемент добавлен в очередь
+	EventPaste   = "paste"   // Элемент вставлен из очереди
+)
+
+// Events — все поддерживаемые события.
+var Events = []string{EventCapture, EventEnqueue, EventPaste}
+
+const (
+	// SignatureHeader содержит "sha256=<hex>" — HMAC-SHA256 тела запроса по секрету.
+	SignatureHeader = "X-ClipQueue-Signature"
+	// EventHeader дублирует поле event для маршрутизации без разбора тела.
+	EventHeader = "X-ClipQueue-Event"
+
+	queueSize      = 256
+	requestTimeout = 10 * time.Second
+	retryDelay     = 2 * time.Second
+)
+
+// Payload — тело запроса.
+type Payload struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	ItemID    string    `json:"itemId"`
+	Type      string    `json:"type"`
+	Preview   string    `json:"preview"`
+	Text      string    `json:"text,omitempty"`
+	SizeBytes int       `json:"sizeBytes"`
+	Target    string    `json:"target,omitempty"` // Процесс окна, в которое выполнена вставка
+}
+
+// Settings — текущие настройки вебхуков; читаются перед каждым событием.
+type Settings struct {
+	URLs        []string
+	Events      []string // Пустой список — все события
+	IncludeText bool     // Передавать полный текст, а не только предпросмотр
+	Secret      string   // Ключ подписи; пустой — без подписи
+}
+
+func (s Settings) wants(event string) bool {
+	if len(s.URLs) == 0 {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Supported сообщает, известно ли событие.
+func Supported(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign возвращает значение заголовка подписи для тела body.
+func Sign(secret string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+type delivery struct {
+	payload  Payload
+	settings Settings
+}
+
+// Dispatcher отправляет события в фоне, чтобы медленный адрес не задерживал
+// вставку и захват буфера. При переполнении очереди события отбрасываются.
+type Dispatcher struct {
+	settings func() Settings
+	client   *http.Client
+
+	queue chan delivery
+	stop  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+// New создаёт диспетчер; settings вызывается для каждого события.
+func New(settings func() Settings) *Dispatcher {
+	return &Dispatcher{
+		settings: settings,
+		client:   &http.Client{Timeout: requestTimeout},
+		queue:    make(chan delivery, queueSize),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start запускает отправку событий.
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	crash.Go("webhook.run", func() {
+		defer d.wg.Done()
+		d.run()
+	})
+}
+
+// Close останавливает отправку. Неотправленные события теряются.
+func (d *Dispatcher) Close() {
+	d.once.Do(func() { close(d.stop) })
+	d.wg.Wait()
+}
+
+// Emit ставит событие в очередь отправки, если на него есть подписка.
+func (d *Dispatcher) Emit(p Payload) {
+	s := d.settings()
+	if !s.wants(p.Event) {
+		return
+	}
+	if !s.IncludeText {
+		p.Text = ""
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	select {
+	case d.queue <- delivery{payload: p, settings: s}:
+	default:
+		logger.Warn("Вебхук: очередь отправки переполнена, событие %s для %s отброшено", p.Event, p.ItemID)
+	}
+}
+
+func (d *Dispatcher) run() {
+	for {
+		select {
+		case <-d.stop:
+			return
+		case del := <-d.queue:
+			body, err := json.Marshal(del.payload)
+			if err != nil {
+				logger.Warn("Вебхук: не удалось сериализовать событие: %v", err)
+				continue
+			}
+			for _, url := range del.settings.URLs {
+				d.deliver(url, del.payload.Event, body, del.settings.Secret)
+			}
+		}
+	}
+}
+
+// deliver отправляет событие; при ошибке или ответе не 2xx делает одну повторную попытку.
+func (d *Dispatcher) deliver(url, event string, body []byte, secret string) {
+	err := d.post(url, event, body, secret)
+	if err == nil {
+		return
+	}
+	select {
+	case <-d.stop:
+		return
+	case <-time.After(retryDelay):
+	}
+	if err = d.post(url, event, body, secret); err != nil {
+		logger.Warn("Вебхук %s: событие %s не доставлено: %v", url, event, err)
+	}
+}
+
+func (d *Dispatcher) post(url, event string, body []byte, secret string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ClipQueue/"+version.Version)
+	req.Header.Set(EventHeader, event)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ответ %s", resp.Status)
+	}
+	logger.Debug("Вебхук %s: событие %s доставлено", url, event)
+	return nil
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type received struct {
+	payload   Payload
+	event     string
+	signature string
+	body      []byte
+}
+
+func TestDispatcherDeliversSignedFilteredEvents(t *testing.T) {
+	got := make(chan received, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("тело не JSON: %v", err)
+		}
+		got <- received{payload: p, event: r.Header.Get(EventHeader), signature: r.Header.Get(SignatureHeader), body: body}
+	}))
+	defer srv.Close()
+
+	d := New(func() Settings {
+		return Settings{URLs: []string{srv.URL}, Events: []string{EventPaste}, Secret: "s3cret"}
+	})
+	d.Start()
+	defer d.Close()
+
+	d.Emit(Payload{Event: EventCapture, ItemID: "skip"})
+	d.Emit(Payload{Event: EventPaste, ItemID: "1", Type: "Text", Preview: "hel…", Text: "hello world"})
+
+	select {
+	case r := <-got:
+		if r.payload.ItemID != "1" || r.event != EventPaste {
+			t.Fatalf("доставлено не то событие: %+v", r.payload)
+		}
+		if r.payload.Text != "" {
+			t.Fatalf("полный текст отправлен без include_text: %q", r.payload.Text)
+		}
+		if r.signature != Sign("s3cret", r.body) {
+			t.Fatalf("подпись %q не совпадает", r.signature)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("событие не доставлено")
+	}
+	select {
+	case r := <-got:
+		t.Fatalf("доставлено событие без подписки: %+v", r.payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSettingsWithoutURLsWantNothing(t *testing.T) {
+	if (Settings{}).wants(EventCapture) {
+		t.Fatal("событие без адресов принято к отправке")
+	}
+	if !(Settings{URLs: []string{"http://x"}}).wants(EventEnqueue) {
+		t.Fatal("пустой список событий должен означать все события")
+	}
+}
@@ -4,21 +4,50 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/serty2005/clipqueue/internal/agent"
 	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/cli"
 	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/instance"
+	"github.com/serty2005/clipqueue/internal/ipc"
 	"github.com/serty2005/clipqueue/internal/logger"
 	"github.com/serty2005/clipqueue/internal/ui/server"
 	"github.com/serty2005/clipqueue/internal/uihost"
+	"github.com/serty2005/clipqueue/internal/updater"
+	"github.com/serty2005/clipqueue/internal/version"
 	"github.com/serty2005/clipqueue/platform/windows"
 )
 
 func main() {
+	// Портативный режим выбирается до любого обращения к путям конфигурации.
+	config.SetPortable(windows.HasArg(config.PortableArg))
+
+	// Подкоманды CLI обращаются к уже запущенному экземпляру и не поднимают приложение.
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
+	// Агент и его установка не поднимают приложение и не занимают мьютекс единственного экземпляра.
+	switch {
+	case windows.HasArg(windows.AgentArg):
+		os.Exit(runAgent())
+	case windows.HasArg(windows.InstallAgentArg):
+		os.Exit(installAgent(true))
+	case windows.HasArg(windows.UninstallAgentArg):
+		os.Exit(installAgent(false))
+	}
+
 	_, statErr := os.Stat(config.ConfigPath())
 	firstRun := os.IsNotExist(statErr)
 
@@ -29,8 +58,29 @@ func main() {
 		return
 	}
 
+	// --silent передают запись автозапуска и агент: приложение стартует в трее без окна UI
+	startSilent := windows.HasArg(windows.SilentArg)
+
+	// Второй экземпляр дублировал бы хуки и иконку трея: передаём команду первому и выходим.
+	// Фоновый запуск (автозапуск, агент) при уже работающем экземпляре просто завершается.
+	instanceLock, alreadyRunning, err := windows.AcquireSingleInstance(windows.SingleInstanceMutexName)
+	// Перезапуск от имени администратора ждёт, пока прежний экземпляр освободит мьютекс.
+	for wait := 0; err == nil && alreadyRunning && windows.HasArg(windows.ElevatedRestartArg) && wait < 50; wait++ {
+		time.Sleep(200 * time.Millisecond)
+		instanceLock, alreadyRunning, err = windows.AcquireSingleInstance(windows.SingleInstanceMutexName)
+	}
+	if err != nil {
+		fmt.Printf("Failed to check running instance: %v\n", err)
+	} else if alreadyRunning {
+		if !startSilent {
+			activateRunningInstance(config.ResolvePath(cfg.App.DataDir))
+		}
+		return
+	}
+	defer instanceLock.Release()
+
 	// Hide console if silent mode is enabled
-	if cfg.App.Silent {
+	if cfg.App.Silent || startSilent {
 		windows.HideConsole()
 	}
 
@@ -44,19 +94,39 @@ func main() {
 	}
 	defer logger.Close()
 
-	logger.Info("ClipQueue starting...")
+	logger.Info("ClipQueue %s starting...", version.Version)
+
 	logger.Info("Config loaded successfully")
 
 	for key, macro := range cfg.Macros {
 		logger.Info("Loaded macro: %s -> Text len: %d, Mode: %s", key, len(macro.Text), macro.Mode)
 	}
 
+	applyLanguage(cfg.App.Language)
+	windows.SetReadLimits(cfg.Clipboard.MaxItemBytes, cfg.Clipboard.MaxImagePixels)
+	applyOpenRetry(cfg)
+
 	// Wrap config for thread-safe access
 	safeCfg := config.NewSafeConfig(cfg)
 
+	// Отчёты о паниках в обработчиках пишутся в <data_dir>\crash
+	crash.Init(config.ResolvePath(cfg.App.DataDir), func() string {
+		return configSummary(safeCfg.Get())
+	})
+	defer crash.RecoverExit("main")
+
 	// Create controller for managing clipboard queue
 	controller := app.NewController(safeCfg.Get())
 
+	// Очередь и история переживают перезапуск, если включено app.persist_state
+	if cfg.App.PersistState {
+		if err := controller.LoadState(); err != nil {
+			logger.Warn("Не удалось восстановить состояние: %v", err)
+		}
+	} else if err := controller.RemoveState(); err != nil {
+		logger.Warn("%v", err)
+	}
+
 	// Create Windows host
 	host, err := windows.NewHost(safeCfg, controller)
 	if err != nil {
@@ -71,6 +141,14 @@ func main() {
 		return
 	}
 	uiURL := uiServer.GetURL()
+	dataDir := config.ResolvePath(cfg.App.DataDir)
+	if exePath, err := os.Executable(); err == nil {
+		updater.CleanupOld(exePath)
+	}
+	if err := instance.WriteAddr(dataDir, uiURL); err != nil {
+		logger.Warn("Не удалось сохранить адрес API для CLI: %v", err)
+	}
+	defer instance.RemoveAddr(dataDir)
 	if firstRun {
 		parsedURL, err := url.Parse(uiURL)
 		if err == nil {
@@ -146,10 +224,10 @@ func main() {
 			RemoveQueueItem: func(index int) (interface{}, error) {
 				return uiServer.NativeRemoveQueueItem(index)
 			},
-			ParseLab: func(command string) (interface{}, error) {
-				return uiServer.NativeParseLab(command)
+			ParseLab: func(command, shell string) (interface{}, error) {
+				return uiServer.NativeParseLab(command, shell)
 			},
-			BuildLab: func(stepsRaw []map[string]interface{}) (interface{}, error) {
+			BuildLab: func(stepsRaw []map[string]interface{}, shell string) (interface{}, error) {
 				raw, err := json.Marshal(stepsRaw)
 				if err != nil {
 					return nil, err
@@ -158,7 +236,7 @@ func main() {
 				if err := json.Unmarshal(raw, &steps); err != nil {
 					return nil, err
 				}
-				return uiServer.NativeBuildLab(steps)
+				return uiServer.NativeBuildLab(steps, shell)
 			},
 			StartSequence: func() (interface{}, error) {
 				return uiServer.NativeStartSequenceRecording()
@@ -172,8 +250,50 @@ func main() {
 		})
 	}
 
+	peerSync := newSyncService(controller)
+	webhooks := newWebhookDispatcher(safeCfg)
+	mqttPublisher := newMQTTService(controller)
+	grpcAPI := newGRPCService(controller)
+	scripts := newPluginService(controller, func(title, text string) {
+		if !safeCfg.Get().Notifications.Enabled {
+			return
+		}
+		if err := host.ShowTrayNotification(title, text, false); err != nil {
+			logger.Warn("Не удалось показать уведомление в трее: %v", err)
+		}
+	})
+	controller.SetEventCallback(func(ev app.Event) {
+		webhooks.Emit(webhookPayload(ev))
+		mqttPublisher.publish(ev)
+		grpcAPI.publish(ev.Kind, &ev.Item)
+		uiServer.PublishEvent(ev.Kind, &ev.Item)
+	})
+
 	// Set config update callback to reload hotkeys
 	uiServer.OnConfigUpdate = func() {
+		logCfg := safeCfg.Get().Logging
+		if err := logger.SetLevels(logCfg.Level, logCfg.Modules); err != nil {
+			logger.Warn("Не удалось применить уровни логирования: %v", err)
+		}
+		controller.SetHistoryLimits(safeCfg.Get())
+		controller.SetQueueAutoDisable(safeCfg.Get())
+		controller.SetAutoClear(safeCfg.Get())
+		controller.SetTypingOptions(safeCfg.Get())
+		controller.SetRemoteTiming(safeCfg.Get())
+		controller.SetPasteMethods(safeCfg.Get())
+		controller.SetTransforms(safeCfg.Get())
+		controller.SetOCR(safeCfg.Get())
+		controller.SetCaptureFilters(safeCfg.Get())
+		controller.SetAudit(safeCfg.Get())
+		applyAutostart(safeCfg.Get().App.Autostart)
+		applyLanguage(safeCfg.Get().App.Language)
+		windows.SetReadLimits(safeCfg.Get().Clipboard.MaxItemBytes, safeCfg.Get().Clipboard.MaxImagePixels)
+		applyOpenRetry(safeCfg.Get())
+		peerSync.apply(safeCfg.Get())
+		mqttPublisher.apply(safeCfg.Get())
+		grpcAPI.apply(safeCfg.Get())
+		uiServer.ApplyRemote(safeCfg.Get())
+		scripts.apply(safeCfg.Get())
 		logger.Info("Config updated, reloading hotkeys...")
 		if err := host.ReloadConfig(); err != nil {
 			logger.Error("Failed to reload config: %v", err)
@@ -181,16 +301,46 @@ func main() {
 	}
 
 	// Set controller state change callback to update tray tooltip
-	controller.SetStateCallback(func(enabled bool, count int, mode string) {
-		var tooltip string
+	trayTooltip := func(enabled bool, count int, mode string) string {
+		if paused, _ := host.PauseState(); paused {
+			return i18n.T("tray.paused_tooltip")
+		}
 		if enabled {
-			tooltip = fmt.Sprintf("ClipQueue: ON [%s] (%d)", mode, count)
-		} else {
-			tooltip = "ClipQueue: OFF"
+			return fmt.Sprintf("ClipQueue: ON [%s] (%d)", mode, count)
 		}
-		if err := host.UpdateTrayTooltip(tooltip); err != nil {
+		return "ClipQueue: OFF"
+	}
+	controller.SetStateCallback(func(enabled bool, count int, mode string) {
+		if err := host.UpdateTrayTooltip(trayTooltip(enabled, count, mode)); err != nil {
 			logger.Error("Failed to update tray tooltip: %v", err)
 		}
+		if err := host.UpdateTrayState(enabled, count); err != nil {
+			logger.Warn("Не удалось обновить иконку трея: %v", err)
+		}
+		grpcAPI.publish("state", nil)
+		uiServer.PublishEvent("state", nil)
+	})
+	// Буфер, который не удаётся открыть за все попытки, иначе виден только в логе.
+	windows.OnClipboardOpenFailure(func(err error) {
+		if !safeCfg.Get().Notifications.Enabled {
+			return
+		}
+		if err := host.ShowTrayNotification(i18n.T("tray.clipboard_busy"), err.Error(), true); err != nil {
+			logger.Warn("Не удалось показать уведомление в трее: %v", err)
+		}
+	})
+	controller.SetNotifyCallback(func(title, text string, failure bool) {
+		if !safeCfg.Get().Notifications.Enabled {
+			return
+		}
+		if err := host.ShowTrayNotification(title, text, failure); err != nil {
+			logger.Warn("Не удалось показать уведомление в трее: %v", err)
+		}
+	})
+	updates := newUpdateService(safeCfg, func(title, text string, failure bool) {
+		if err := host.ShowTrayNotification(title, text, failure); err != nil {
+			logger.Warn("Не удалось показать уведомление в трее: %v", err)
+		}
 	})
 	controller.SetUIRefreshCallback(func() {
 		if nativeUI, ok := uiHost.(uihost.NativeBridgeCapable); ok {
@@ -206,26 +356,96 @@ func main() {
 	// Setup event handlers
 	host.OnHotkeyToggleQueue(func() {
 		logger.Debug("ToggleQueue hotkey pressed")
-		go controller.ToggleQueue()
+		crash.Go("controller.ToggleQueue", controller.ToggleQueue)
 	})
 
 	host.OnHotkeyToggleUI(func() {
 		logger.Debug("ToggleUI hotkey pressed")
-		go func() {
+		crash.Go("uiHost.Toggle", func() {
 			if err := uiHost.Toggle(); err != nil {
 				logger.Error("Failed to toggle UI host: %v", err)
 			}
-		}()
+		})
 	})
 
 	host.OnHotkeyToggleQueueOrder(func() {
 		logger.Debug("ToggleQueueOrder hotkey pressed")
-		go controller.ToggleOrder()
+		crash.Go("controller.ToggleOrder", controller.ToggleOrder)
 	})
 
 	host.OnHotkeyPasteNext(func() {
 		logger.Debug("PasteNext hotkey pressed")
-		go controller.PasteNext()
+		crash.Go("controller.PasteNext", controller.PasteNext)
+	})
+
+	// switchHotkeyProfile делает активным набор хоткеев name (пустое — основной),
+	// сохраняет выбор в конфиг и перерегистрирует хоткеи.
+	switchHotkeyProfile := func(name string) error {
+		next := safeCfg.Get()
+		if err := next.SetHotkeyProfile(name); err != nil {
+			return err
+		}
+		if err := safeCfg.Mutate(func(cfg *config.Config) { cfg.HotkeyProfiles.Active = next.HotkeyProfiles.Active }); err != nil {
+			return err
+		}
+		label := next.HotkeyProfiles.Active
+		if label == "" {
+			label = i18n.T("tray.hotkey_profile_default")
+		}
+		logger.Info("Набор хоткеев: %s", label)
+		if err := host.ReloadConfig(); err != nil {
+			return err
+		}
+		if safeCfg.Get().Notifications.Enabled {
+			if err := host.ShowTrayNotification("ClipQueue", i18n.T("tray.hotkey_profiles", label), false); err != nil {
+				logger.Warn("Не удалось показать уведомление в трее: %v", err)
+			}
+		}
+		return nil
+	}
+	uiServer.SwitchHotkeyProfile = switchHotkeyProfile
+	host.OnHotkeyCycleProfile(func() {
+		logger.Debug("CycleHotkeyProfile hotkey pressed")
+		crash.Go("switchHotkeyProfile", func() {
+			if err := switchHotkeyProfile(safeCfg.Get().NextHotkeyProfile()); err != nil {
+				logger.Error("Не удалось сменить набор хоткеев: %v", err)
+			}
+		})
+	})
+	host.OnTrayHotkeyProfileSelect(func(name string) {
+		if err := switchHotkeyProfile(name); err != nil {
+			logger.Error("Не удалось сменить набор хоткеев: %v", err)
+		}
+	})
+
+	// togglePause ставит ClipQueue на паузу на app.pause_minutes или снимает её.
+	togglePause := func() {
+		paused, _ := host.PauseState()
+		minutes := safeCfg.Get().App.PauseMinutes
+		if err := host.SetPaused(!paused, time.Duration(minutes)*time.Minute); err != nil {
+			logger.Error("Не удалось переключить паузу: %v", err)
+		}
+	}
+	host.OnHotkeyPause(func() {
+		logger.Debug("Pause hotkey pressed")
+		togglePause()
+	})
+	host.OnPauseChange(func(paused bool) {
+		if err := host.UpdateTrayTooltip(trayTooltip(controller.GetQueueState())); err != nil {
+			logger.Error("Failed to update tray tooltip: %v", err)
+		}
+		if !safeCfg.Get().Notifications.Enabled {
+			return
+		}
+		text := i18n.T("tray.resumed")
+		if _, until := host.PauseState(); paused && !until.IsZero() {
+			text = i18n.T("tray.paused_until", until.Format("15:04"))
+		} else if paused {
+			text = i18n.T("tray.paused")
+		}
+		if err := host.ShowTrayNotification("ClipQueue", text, false); err != nil {
+			logger.Warn("Не удалось показать уведомление в трее: %v", err)
+		}
 	})
 
 	// Setup clipboard update coalescing worker
@@ -246,8 +466,11 @@ func main() {
 					}
 				}
 
-				// Process clipboard update
-				controller.OnClipboardUpdate()
+				// Process clipboard update; паника не должна останавливать обработчик событий
+				func() {
+					defer crash.Recover("controller.OnClipboardUpdate")
+					controller.OnClipboardUpdate()
+				}()
 			}
 		}()
 
@@ -266,6 +489,24 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Вставка в окно администратора невозможна без прав: при включённом app.auto_elevate
+	// перезапускаемся через UAC.
+	controller.SetElevatedTargetCallback(func(target windows.WindowInfo) {
+		if !safeCfg.Get().App.AutoElevate || windows.IsCurrentProcessElevated() {
+			return
+		}
+		logger.Info("Перезапуск от имени администратора для вставки в %s", target.ProcessName)
+		if err := windows.RelaunchElevated(); err != nil {
+			logger.Warn("%v", err)
+			return
+		}
+		// Очередь переживает перезапуск через state.json, если сохранение состояния включено.
+		select {
+		case sigChan <- syscall.SIGTERM:
+		default:
+		}
+	})
+
 	// Setup tray command handler
 	host.OnTrayCommand(func(id uint32) {
 		switch id {
@@ -273,18 +514,32 @@ func main() {
 			logger.Info("Tray info command selected")
 		case windows.ID_TRAY_TOGGLE_QUEUE:
 			logger.Debug("Tray toggle queue command selected")
-			go controller.ToggleQueue()
+			crash.Go("controller.ToggleQueue", controller.ToggleQueue)
 		case windows.ID_TRAY_SWITCH_ORDER:
 			logger.Debug("Tray switch order command selected")
-			go controller.ToggleOrder()
+			crash.Go("controller.ToggleOrder", controller.ToggleOrder)
 		case windows.ID_TRAY_CLEAR:
 			logger.Debug("Tray clear queue command selected")
-			go controller.ClearQueue()
+			crash.Go("controller.ClearQueue", controller.ClearQueue)
 		case windows.ID_TRAY_TOGGLE_UI:
 			logger.Debug("Tray toggle UI command selected")
 			if err := uiHost.Toggle(); err != nil {
 				logger.Error("Failed to show UI host: %v", err)
 			}
+		case windows.ID_TRAY_AUTOSTART:
+			enabled := !safeCfg.Get().App.Autostart
+			logger.Debug("Tray autostart command selected: %v", enabled)
+			if err := safeCfg.Mutate(func(cfg *config.Config) { cfg.App.Autostart = enabled }); err != nil {
+				logger.Error("Не удалось сохранить настройку автозапуска: %v", err)
+				break
+			}
+			applyAutostart(enabled)
+		case windows.ID_TRAY_PAUSE:
+			logger.Debug("Tray pause command selected")
+			togglePause()
+		case windows.ID_TRAY_UPDATES:
+			logger.Debug("Tray check updates command selected")
+			crash.Go("updates.check", func() { updates.check(true) })
 		case windows.ID_TRAY_EXIT:
 			logger.Info("Tray exit command selected")
 			// Send SIGTERM to trigger graceful shutdown
@@ -292,6 +547,34 @@ func main() {
 		}
 	})
 
+	host.SetTrayOrderProvider(controller.GetOrderStrategy)
+	host.SetTrayHistoryProvider(func() []windows.TrayHistoryItem {
+		history := controller.GetHistory()
+		currentID := controller.GetCurrentClipboardID()
+		items := make([]windows.TrayHistoryItem, 0, windows.TrayHistoryLimit)
+		for i := len(history) - 1; i >= 0 && len(items) < windows.TrayHistoryLimit; i-- {
+			items = append(items, windows.TrayHistoryItem{
+				ID:      history[i].ID,
+				Label:   history[i].Preview,
+				Current: history[i].ID == currentID,
+			})
+		}
+		return items
+	})
+	// Windows завершает процесс вскоре после WM_ENDSESSION, не дожидаясь SIGTERM,
+	// поэтому снимок пишется прямо из обработчика. Конфиг сохраняется при каждом изменении.
+	host.OnEndSession(func() {
+		flushState(controller, safeCfg)
+	})
+	host.OnTrayHistorySelect(func(id string) {
+		logger.Debug("Tray history item selected: %s", id)
+		crash.Go("controller.CopyItem", func() {
+			if err := controller.CopyItem(id); err != nil {
+				logger.Error("Не удалось скопировать элемент из трея: %v", err)
+			}
+		})
+	})
+
 	// Start host (this will run the message loop in a goroutine)
 	if err := host.Start(); err != nil {
 		logger.Error("Failed to start Windows host: %v", err)
@@ -299,13 +582,64 @@ func main() {
 	}
 	logger.Info("Host started")
 
-	if firstRun || cfg.UI.Visible {
+	// Запись автозапуска синхронизируется с конфигом, в том числе после переноса .exe
+	applyAutostart(cfg.App.Autostart)
+
+	// Initial tray icon reflects the queue state before the first state change
+	queueEnabled, queueCount, _ := controller.GetQueueState()
+	if err := host.UpdateTrayState(queueEnabled, queueCount); err != nil {
+		logger.Warn("Не удалось обновить иконку трея: %v", err)
+	}
+
+	if !startSilent && (firstRun || cfg.UI.Visible) {
 		if err := uiHost.Show(); err != nil {
 			logger.Warn("Failed to show UI host on startup: %v", err)
 		}
 	}
 
+	// Локальное управление через именованный канал не зависит от HTTP-сервера
+	if cfg.IPC.NamedPipe {
+		pipeName := controlPipeName()
+		pipe, err := windows.ListenPipe(pipeName)
+		if err != nil {
+			logger.Warn("Не удалось открыть именованный канал %s: %v", pipeName, err)
+		} else {
+			logger.Info("Именованный канал управления открыт: %s", pipeName)
+			go ipc.ServeListener(pipe, ipcBackend{
+				Controller: controller,
+				showSettings: func() error {
+					if err := uiHost.Navigate(settingsURL(uiServer.GetURL())); err != nil {
+						return err
+					}
+					if err := uiHost.Show(); err != nil {
+						return err
+					}
+					return uiHost.Focus()
+				},
+			})
+			defer pipe.Close()
+		}
+	}
+
+	// Фоновая очистка истории по history.ttl и лимитам размера
+	stopSweeper := make(chan struct{})
+	crash.Go("controller.RunHistorySweeper", func() { controller.RunHistorySweeper(stopSweeper) })
+	crash.Go("updates.run", func() { updates.run(stopSweeper) })
+	peerSync.apply(safeCfg.Get())
+	webhooks.Start()
+	mqttPublisher.apply(safeCfg.Get())
+	grpcAPI.apply(safeCfg.Get())
+	uiServer.ApplyRemote(safeCfg.Get())
+	scripts.apply(safeCfg.Get())
+
 	<-sigChan
+	close(stopSweeper)
+	peerSync.stop()
+	webhooks.Close()
+	mqttPublisher.close()
+	grpcAPI.stop()
+	scripts.close()
+	flushState(controller, safeCfg)
 
 	if err := uiHost.Close(); err != nil {
 		logger.Warn("Failed to close UI host: %v", err)
@@ -328,5 +662,187 @@ func main() {
 		logger.Error("Failed to stop UI server: %v", err)
 	}
 
+	// Загруженное обновление подменяет .exe после остановки всех компонентов
+	applyStagedUpdate(dataDir)
+
 	logger.Info("ClipQueue stopped")
 }
+
+// configSummary описывает конфигурацию для отчёта о сбое. Тексты макросов и
+// хоткеи не попадают в отчёт: там могут быть пароли и личные данные.
+func configSummary(cfg *config.Config) string {
+	return fmt.Sprintf(
+		"portable=%v data_dir=%q silent=%v logs=%v persist_state=%v\n"+
+			"features: queue=%v clipboard=%v macros=%v lab=%v\n"+
+			"history: max_items=%d max_total_bytes=%d ttl=%q\n"+
+			"clipboard: watch_debounce_ms=%d paste_delay_ms=%d restore_delay_ms=%d\n"+
+			"queue.default_order=%s macros=%d ipc.named_pipe=%v updates.check=%v sync=%v peers=%d",
+		config.IsPortable(), cfg.App.DataDir, cfg.App.Silent, cfg.App.Logs, cfg.App.PersistState,
+		cfg.Features.EnableQueue, cfg.Features.EnableClipboard, cfg.Features.EnableMacros, cfg.Features.EnableLab,
+		cfg.History.MaxItems, cfg.History.MaxTotalBytes, cfg.History.TTL,
+		cfg.Clipboard.WatchDebounceMs, cfg.Clipboard.PasteDelayMs, cfg.Clipboard.RestoreDelayMs,
+		cfg.Queue.DefaultOrder, len(cfg.Macros), cfg.IPC.NamedPipe, cfg.Updates.Check, cfg.Sync.Enabled, len(cfg.Sync.Peers),
+	)
+}
+
+// flushState дописывает отложенные профили приложений-получателей и сохраняет
+// снимок очереди и истории, если это разрешено конфигом.
+func flushState(controller *app.Controller, safeCfg *config.SafeConfig) {
+	controller.FlushPasteTargets()
+	if !safeCfg.Get().App.PersistState {
+		return
+	}
+	if err := controller.SaveState(); err != nil {
+		logger.Error("Не удалось сохранить состояние: %v", err)
+	}
+}
+
+// runCLI выполняет подкоманду консольного клиента и возвращает код выхода.
+func runCLI(args []string) int {
+	windows.AttachParentConsole()
+
+	dataDir := config.ResolvePath("")
+	if cfg, err := config.Load(); err == nil {
+		dataDir = config.ResolvePath(cfg.App.DataDir)
+	}
+	return cli.Run(args, cli.Options{DataDir: dataDir})
+}
+
+// runAgent перезапускает основной процесс после аварийного завершения, пока тот
+// не выйдет штатно. Журнал агента пишется в <data_dir>\logs\agent.log.
+func runAgent() int {
+	windows.HideConsole()
+
+	dataDir := config.ResolvePath("")
+	if cfg, err := config.Load(); err == nil {
+		dataDir = config.ResolvePath(cfg.App.DataDir)
+	}
+	out := io.Writer(os.Stdout)
+	logDir := filepath.Join(dataDir, "logs")
+	if err := os.MkdirAll(logDir, 0755); err == nil {
+		if f, err := os.OpenFile(filepath.Join(logDir, "agent.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err == nil {
+			defer f.Close()
+			out = f
+		}
+	}
+	agentLog := log.New(out, "", log.LstdFlags)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	agentLog.Printf("Агент ClipQueue запущен")
+	if err := agent.Supervise(ctx, windows.RunAgentChild, agent.DefaultPolicy, agentLog.Printf); err != nil {
+		agentLog.Printf("Агент остановлен: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// installAgent регистрирует или удаляет задачу планировщика агента. Запись автозапуска
+// в реестре при установке отключается, чтобы приложение не стартовало дважды.
+func installAgent(install bool) int {
+	windows.AttachParentConsole()
+
+	if !install {
+		if err := windows.RemoveAgentTask(); err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось удалить задачу агента: %v\n", err)
+			return 1
+		}
+		fmt.Println("Задача агента ClipQueue удалена")
+		return 0
+	}
+
+	if err := windows.InstallAgentTask(); err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось создать задачу агента: %v\n", err)
+		return 1
+	}
+	if cfg, err := config.Load(); err == nil && cfg.App.Autostart {
+		if err := config.NewSafeConfig(cfg).Mutate(func(cfg *config.Config) { cfg.App.Autostart = false }); err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось отключить app.autostart: %v\n", err)
+		}
+	}
+	if err := windows.SetAutostart(false); err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось удалить запись автозапуска: %v\n", err)
+	}
+	fmt.Println("Задача агента ClipQueue создана: агент запустится при следующем входе в систему")
+	return 0
+}
+
+// ipcBackend дополняет контроллер командами, которые касаются окна UI.
+type ipcBackend struct {
+	*app.Controller
+	showSettings func() error
+}
+
+func (b ipcBackend) ShowSettings() error {
+	return b.showSettings()
+}
+
+// settingsURL возвращает адрес UI с открытым экраном настроек.
+func settingsURL(base string) string {
+	parsedURL, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	query := parsedURL.Query()
+	query.Set("screen", "set")
+	parsedURL.RawQuery = query.Encode()
+	return parsedURL.String()
+}
+
+// controlPipeName возвращает имя канала управления для сеанса текущего процесса.
+func controlPipeName() string {
+	session, err := windows.CurrentSessionID()
+	if err != nil {
+		logger.Warn("Не удалось определить сеанс Windows: %v", err)
+	}
+	return ipc.PipeName(session)
+}
+
+// activateRunningInstance просит уже запущенный экземпляр открыть настройки:
+// сначала через именованный канал, а если он выключен — открывая UI по server.addr в браузере.
+func activateRunningInstance(dataDir string) {
+	conn, err := windows.DialPipe(controlPipeName(), 2*time.Second)
+	if err == nil {
+		defer conn.Close()
+		if _, err = ipc.Call(conn, ipc.Request{Cmd: ipc.CmdShowSettings}); err == nil {
+			return
+		}
+	}
+	fmt.Printf("ClipQueue уже запущен; не удалось передать команду через канал: %v\n", err)
+
+	addr, addrErr := instance.ReadAddr(dataDir)
+	if addrErr != nil {
+		fmt.Printf("Адрес запущенного экземпляра неизвестен: %v\n", addrErr)
+		return
+	}
+	if err := windows.OpenBrowser(settingsURL(addr)); err != nil {
+		fmt.Printf("Не удалось открыть настройки в браузере: %v\n", err)
+	}
+}
+
+// applyAutostart приводит запись автозапуска в реестре к значению из конфига.
+func applyAutostart(enabled bool) {
+	if err := windows.SetAutostart(enabled); err != nil {
+		logger.Warn("Не удалось обновить автозапуск: %v", err)
+	}
+}
+
+// applyOpenRetry применяет clipboard.open_retry.
+func applyOpenRetry(cfg *config.Config) {
+	retry := cfg.Clipboard.OpenRetry
+	windows.SetOpenRetryPolicy(windows.OpenRetryPolicy{
+		Attempts:     retry.Attempts,
+		InitialDelay: time.Duration(retry.InitialDelayMs) * time.Millisecond,
+		MaxDelay:     time.Duration(retry.MaxDelayMs) * time.Millisecond,
+		Jitter:       retry.Jitter,
+	})
+}
+
+// applyLanguage выбирает язык меню трея и ошибок API; auto берёт язык интерфейса Windows.
+func applyLanguage(lang string) {
+	if lang == "" || lang == i18n.Auto {
+		lang = windows.UserLanguage()
+	}
+	logger.Info("Язык интерфейса: %s", i18n.SetLanguage(lang))
+}
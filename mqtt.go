@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/mqtt"
+)
+
+const (
+	mqttQueueSize   = 256
+	mqttMinRedial   = 2 * time.Second
+	mqttMaxRedial   = 5 * time.Minute
+	mqttStableAfter = 5 * time.Minute
+)
+
+// mqttService публикует события контроллера в брокер MQTT и добавляет в очередь
+// текст из темы push. При изменении раздела mqtt подключение перезапускается.
+type mqttService struct {
+	controller *app.Controller
+	events     chan app.Event
+
+	mu      sync.Mutex
+	applied config.Config // Применённый раздел MQTT; остальные поля не используются
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newMQTTService(controller *app.Controller) *mqttService {
+	return &mqttService{controller: controller, events: make(chan app.Event, mqttQueueSize)}
+}
+
+// apply запускает, останавливает или перезапускает подключение по настройкам cfg.
+func (m *mqttService) apply(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil && reflect.DeepEqual(m.applied.MQTT, cfg.MQTT) {
+		return
+	}
+	m.stopLocked()
+	m.applied.MQTT = cfg.MQTT
+	if !cfg.MQTT.Enabled {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	m.stop, m.done = stop, done
+	settings := m.applied
+	crash.Go("mqtt.run", func() {
+		defer close(done)
+		m.run(&settings, stop)
+	})
+}
+
+// close отключается от брокера при выходе из приложения.
+func (m *mqttService) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopLocked()
+}
+
+func (m *mqttService) stopLocked() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+	m.stop, m.done = nil, nil
+}
+
+// publish ставит событие в очередь публикации. Без подключения события копятся
+// до mqttQueueSize, затем отбрасываются.
+func (m *mqttService) publish(ev app.Event) {
+	m.mu.Lock()
+	enabled := m.stop != nil
+	m.mu.Unlock()
+	if !enabled {
+		return
+	}
+	select {
+	case m.events <- ev:
+	default:
+		logger.Debug("MQTT: очередь публикации переполнена, событие %s отброшено", ev.Kind)
+	}
+}
+
+func (m *mqttService) run(cfg *config.Config, stop <-chan struct{}) {
+	delay := mqttMinRedial
+	for {
+		started := time.Now()
+		if err := m.session(cfg, stop); err != nil {
+			logger.Warn("MQTT: соединение с %s: %v", cfg.MQTT.Broker, err)
+		}
+		if time.Since(started) > mqttStableAfter {
+			delay = mqttMinRedial
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > mqttMaxRedial {
+			delay = mqttMaxRedial
+		}
+	}
+}
+
+// session подключается к брокеру и публикует события до разрыва или остановки.
+func (m *mqttService) session(cfg *config.Config, stop <-chan struct{}) error {
+	client, err := mqtt.Connect(mqtt.Options{
+		Broker:    cfg.MQTT.Broker,
+		ClientID:  cfg.MQTT.ClientID,
+		Username:  cfg.MQTT.Username,
+		Password:  cfg.MQTT.Password,
+		OnMessage: m.onPush,
+	})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	logger.Info("MQTT: подключено к %s", cfg.MQTT.Broker)
+
+	if cfg.MQTT.PushTopic != "" {
+		if err := client.Subscribe(cfg.MQTT.PushTopic); err != nil {
+			return err
+		}
+	}
+	prefix := strings.TrimSuffix(cfg.MQTT.TopicPrefix, "/")
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-client.Done():
+			return client.Err()
+		case ev := <-m.events:
+			p := webhookPayload(ev)
+			if !cfg.MQTT.IncludeText {
+				p.Text = ""
+			}
+			body, err := json.Marshal(p)
+			if err != nil {
+				continue
+			}
+			if err := client.Publish(prefix+"/"+ev.Kind, body, cfg.MQTT.Retain); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// onPush добавляет в очередь текст из темы push: сообщение целиком или поле
+// text, если сообщение — JSON-объект.
+func (m *mqttService) onPush(topic string, payload []byte) {
+	text := string(payload)
+	var msg struct {
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(payload, &msg) == nil && msg.Text != "" {
+		text = msg.Text
+	}
+	id, err := m.controller.PushText(text)
+	if err != nil {
+		logger.Warn("MQTT: текст из %s не добавлен в очередь: %v", topic, err)
+		return
+	}
+	logger.Info("MQTT: текст из %s добавлен в очередь (id=%s)", topic, id)
+}
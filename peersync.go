@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"reflect"
+	"sync"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
+	"github.com/serty2005/clipqueue/internal/filebundle"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/peersync"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// syncService связывает контроллер с узлом синхронизации и перезапускает узел
+// при изменении раздела sync в настройках.
+type syncService struct {
+	controller *app.Controller
+
+	mu      sync.Mutex
+	node    *peersync.Node
+	applied config.Config // Применённые разделы Sync и Files; остальные поля не используются
+}
+
+func newSyncService(controller *app.Controller) *syncService {
+	return &syncService{controller: controller}
+}
+
+// apply запускает, останавливает или перезапускает узел по настройкам cfg.
+func (s *syncService) apply(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.node != nil && reflect.DeepEqual(s.applied.Sync, cfg.Sync) && s.applied.Files == cfg.Files {
+		return
+	}
+	s.stopLocked()
+	s.applied.Sync = cfg.Sync
+	s.applied.Files = cfg.Files
+	if !cfg.Sync.Enabled {
+		return
+	}
+
+	node, err := peersync.New(peersync.Config{
+		Listen: cfg.Sync.Listen,
+		Peers:  cfg.Sync.Peers,
+		Key:    cfg.Sync.Key,
+	}, s.receive)
+	if err == nil {
+		err = node.Start()
+	}
+	if err != nil {
+		logger.Error("Синхронизация не запущена: %v", err)
+		return
+	}
+	s.node = node
+	files := cfg.Files
+	s.controller.SetCaptureCallback(func(content windows.ClipboardContent) {
+		// Файлы читаются с диска, поэтому элемент собирается вне потока захвата.
+		crash.Go("peersync.Broadcast", func() {
+			item, ok := toSyncItem(content, files.Embed, files.MaxEmbedBytes)
+			if !ok {
+				return
+			}
+			node.Broadcast(item)
+		})
+	}, cfg.Sync.Images)
+}
+
+// stop останавливает узел при выходе из приложения.
+func (s *syncService) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopLocked()
+}
+
+func (s *syncService) stopLocked() {
+	if s.node == nil {
+		return
+	}
+	s.controller.SetCaptureCallback(nil, false)
+	s.node.Close()
+	s.node = nil
+}
+
+func (s *syncService) receive(item peersync.Item) {
+	content, err := fromSyncItem(item)
+	if err != nil {
+		logger.Warn("Синхронизация: элемент %s пропущен: %v", item.ID, err)
+		return
+	}
+	s.controller.AddRemoteItem(content)
+}
+
+// toSyncItem преобразует локальный элемент для отправки. Списки файлов
+// пересылаются только с содержимым (files.embed): пути другого компьютера бессмысленны.
+func toSyncItem(content windows.ClipboardContent, embedFiles bool, maxEmbedBytes int64) (peersync.Item, bool) {
+	item := peersync.Item{ID: content.ID, Timestamp: content.Timestamp}
+	switch content.Type {
+	case windows.Text:
+		item.Kind = peersync.KindText
+		item.Text = content.Text
+	case windows.Image:
+		if len(content.ImagePNG) == 0 {
+			return item, false
+		}
+		item.Kind = peersync.KindImage
+		item.ImagePNG = content.ImagePNG
+	case windows.Files:
+		if !embedFiles {
+			return item, false
+		}
+		files, err := filebundle.Collect(content.Files, maxEmbedBytes)
+		if err != nil {
+			logger.Warn("Синхронизация: файлы элемента %s не отправлены: %v", content.ID, err)
+			return item, false
+		}
+		if len(files) == 0 {
+			return item, false
+		}
+		item.Kind = peersync.KindFiles
+		item.Files = files
+	default:
+		return item, false
+	}
+	return item, true
+}
+
+// fromSyncItem создаёт локальный элемент из полученного. ID сохраняется, чтобы
+// повторная доставка того же элемента не дублировала его в истории.
+func fromSyncItem(item peersync.Item) (windows.ClipboardContent, error) {
+	var content windows.ClipboardContent
+	switch item.Kind {
+	case peersync.KindText:
+		content = windows.NewTextContent(item.Text)
+	case peersync.KindImage:
+		img, _, err := image.Decode(bytes.NewReader(item.ImagePNG))
+		if err != nil {
+			return content, fmt.Errorf("повреждённое изображение: %w", err)
+		}
+		if content, err = windows.NewImageContent(img); err != nil {
+			return content, err
+		}
+	case peersync.KindFiles:
+		paths, err := filebundle.Materialize(filebundle.DefaultRoot(), item.Files)
+		if err != nil {
+			return content, fmt.Errorf("не удалось сохранить файлы: %w", err)
+		}
+		content = windows.NewFilesContent(paths)
+	default:
+		return content, fmt.Errorf("неизвестный вид %q", item.Kind)
+	}
+	content.ID = item.Origin + "-" + item.ID
+	if !item.Timestamp.IsZero() {
+		content.Timestamp = item.Timestamp
+	}
+	return content, nil
+}
@@ -0,0 +1,84 @@
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/serty2005/clipqueue/internal/config"
+)
+
+const (
+	// AgentArg запускает ClipQueue в режиме агента, который перезапускает основной процесс после сбоя.
+	AgentArg = "--agent"
+	// InstallAgentArg и UninstallAgentArg регистрируют и удаляют задачу планировщика агента.
+	InstallAgentArg   = "--install-agent"
+	UninstallAgentArg = "--uninstall-agent"
+
+	agentTaskName = "ClipQueue"
+
+	CREATE_NO_WINDOW = 0x08000000
+)
+
+// InstallAgentTask создаёт задачу планировщика, которая при входе текущего пользователя
+// запускает агента ClipQueue с обычными (не повышенными) правами.
+func InstallAgentTask() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("не удалось определить путь к исполняемому файлу: %w", err)
+	}
+	user := os.Getenv("USERNAME")
+	if domain := os.Getenv("USERDOMAIN"); domain != "" && user != "" {
+		user = domain + `\` + user
+	}
+
+	args := []string{
+		"/Create", "/F",
+		"/TN", agentTaskName,
+		"/SC", "ONLOGON",
+		"/RL", "LIMITED",
+		"/IT",
+		"/TR", strings.Join(append([]string{fmt.Sprintf("%q", exePath), AgentArg}, config.LaunchArgs()...), " "),
+	}
+	if user != "" {
+		args = append(args, "/RU", user)
+	}
+	return runSchtasks(args...)
+}
+
+// RemoveAgentTask удаляет задачу планировщика агента.
+func RemoveAgentTask() error {
+	return runSchtasks("/Delete", "/F", "/TN", agentTaskName)
+}
+
+func runSchtasks(args ...string) error {
+	cmd := exec.Command("schtasks", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RunAgentChild запускает основной процесс ClipQueue в фоне (с SilentArg),
+// дожидается его завершения и возвращает код выхода.
+func RunAgentChild() (int, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось определить путь к исполняемому файлу: %w", err)
+	}
+	cmd := exec.Command(exePath, append([]string{SilentArg}, config.LaunchArgs()...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,103 @@
+package windows
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+)
+
+//go:embed assets/clipqueue.ico
+var appIconICO []byte
+
+const (
+	SM_CXICON    = 11
+	WM_SETICON   = 0x0080
+	ICON_SMALL   = 0
+	ICON_BIG     = 1
+	pngSignature = "\x89PNG\r\n\x1a\n"
+)
+
+var procSendMessageW = user32.NewProc("SendMessageW")
+
+// LoadAppIcon создаёт HICON встроенной иконки приложения для размера size (в пикселях).
+// Вызывающий владеет иконкой и отвечает за DestroyIcon.
+func LoadAppIcon(size int) (uintptr, error) {
+	entries, err := imaging.ParseICO(appIconICO)
+	if err != nil {
+		return 0, err
+	}
+	entry, ok := imaging.PickIcon(entries, size)
+	if !ok {
+		return 0, fmt.Errorf("встроенная иконка не содержит изображений")
+	}
+
+	// CreateIconFromResourceEx принимает как DIB, так и PNG-ресурс и масштабирует его до cx×cy.
+	hIcon, _, err := procCreateIconFromResourceEx.Call(
+		uintptr(unsafe.Pointer(&entry.Data[0])),
+		uintptr(len(entry.Data)),
+		1, // fIcon
+		iconResourceVersion,
+		uintptr(size),
+		uintptr(size),
+		0, // LR_DEFAULTCOLOR
+	)
+	if hIcon == 0 {
+		return 0, fmt.Errorf("CreateIconFromResourceEx: %w", err)
+	}
+	return hIcon, nil
+}
+
+// ApplyAppIcon устанавливает встроенную иконку окну (заголовок и панель задач).
+func ApplyAppIcon(hwnd uintptr) error {
+	small, err := LoadAppIcon(trayIconSize())
+	if err != nil {
+		return err
+	}
+	big, err := LoadAppIcon(systemMetric(SM_CXICON, 32))
+	if err != nil {
+		procDestroyIcon.Call(small)
+		return err
+	}
+	procSendMessageW.Call(hwnd, WM_SETICON, ICON_SMALL, small)
+	procSendMessageW.Call(hwnd, WM_SETICON, ICON_BIG, big)
+	return nil
+}
+
+// appIconImage возвращает изображение встроенной иконки размером size×size.
+func appIconImage(size int) (*image.NRGBA, error) {
+	entries, err := imaging.ParseICO(appIconICO)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := imaging.PickIcon(entries, size)
+	if !ok {
+		return nil, fmt.Errorf("встроенная иконка не содержит изображений")
+	}
+	if !bytes.HasPrefix(entry.Data, []byte(pngSignature)) {
+		return nil, fmt.Errorf("изображение иконки %dx%d не в формате PNG", entry.Width, entry.Height)
+	}
+	src, err := png.Decode(bytes.NewReader(entry.Data))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось декодировать иконку: %w", err)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	if src.Bounds().Dx() == size && src.Bounds().Dy() == size {
+		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+		return dst, nil
+	}
+	// Ближайший сосед достаточен для редких размеров, отсутствующих в .ico.
+	sb := src.Bounds()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dst.Set(x, y, src.At(sb.Min.X+x*sb.Dx()/size, sb.Min.Y+y*sb.Dy()/size))
+		}
+	}
+	return dst, nil
+}
@@ -2,11 +2,9 @@ package windows
 
 import (
 	"bytes"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
-	"image/color"
-	"image/draw"
 	"image/png"
 	"strings"
 	"sync/atomic"
@@ -14,6 +12,8 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/serty2005/clipqueue/internal/fileinfo"
+	"github.com/serty2005/clipqueue/internal/imaging"
 	"github.com/serty2005/clipqueue/internal/logger"
 )
 
@@ -54,14 +54,176 @@ type ClipboardContent struct {
 	SizeBytes int
 	Preview   string
 	SourceSeq uint32
+	// Thumbnail — уменьшенная копия изображения для UI, ThumbnailType — её MIME-тип.
+	Thumbnail     []byte
+	ThumbnailType string
+	// ImageType — MIME-тип ImagePNG, если история перекодировала изображение
+	// (см. history.image_format); пустое значение — PNG. Compacted — изображение
+	// уже уменьшено и повторно не обрабатывается.
+	ImageType string
+	Compacted bool
+	// FilesBytes — суммарный размер файлов элемента Files на диске в момент захвата
+	// (без содержимого каталогов), FileDirs — сколько из путей каталоги.
+	FilesBytes int64
+	FileDirs   int
+	// EMF — байты векторного рисунка (CF_ENHMETAFILE), сохранённые рядом с растром
+	// ImagePNG; при записи в буфер метафайл возвращается вместе с изображением.
+	EMF []byte
+	// Oversized — содержимое превысило лимит SetReadLimits и не сохранено:
+	// элемент хранит только тип, размер и предпросмотр, вставить его нельзя.
+	Oversized bool
+	// Hash — SHA-256 полезной нагрузки в hex для поиска дубликатов (history.dedup);
+	// пусто, пока изображение не дочитано из буфера.
+	Hash string
+	// Sensitive — вид найденного секрета (card, jwt, private_key); у такого элемента
+	// Preview маскирован. Пустое значение — обычный элемент.
+	Sensitive string
+	// SourceApp и SourceTitle — процесс и заголовок окна, из которого скопирован элемент;
+	// пусто, если элемент создан не копированием или источник не определён.
+	SourceApp   string
+	SourceTitle string
+	// Title — отображаемое имя элемента вместо предпросмотра, например заголовок
+	// страницы для ссылки (clipboard.fetch_titles); пусто — показывается Preview.
+	Title string
+	// Revision — номер правки текста (0 — исходный элемент), RevisionOf — ID исходного
+	// элемента, из которого получены все правки.
+	Revision   int
+	RevisionOf string
 }
 
 func (c ClipboardContent) NeedsImageCapture() bool {
 	return c.Type == Image && len(c.ImagePNG) == 0 && c.SourceSeq != 0
 }
 
-// readClipboardDIBBytes reads raw DIB data from clipboard without conversion
+// NewTextContent создаёт текстовый элемент, не связанный с буфером обмена.
+func NewTextContent(text string) ClipboardContent {
+	return ClipboardContent{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp: time.Now(),
+		Type:      Text,
+		Text:      text,
+		SizeBytes: len([]byte(text)),
+		Preview:   formatTextPreview(text),
+	}
+}
+
+// NewFilesContent создаёт элемент со списком файлов, не связанный с буфером обмена.
+func NewFilesContent(files []string) ClipboardContent {
+	content := ClipboardContent{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp: time.Now(),
+		Type:      Files,
+		Files:     files,
+		SizeBytes: calculateFilesSize(files),
+		Preview:   formatFilesPreview(files),
+	}
+	content.setFileTotals()
+	return content
+}
+
+// setFileTotals запоминает настоящий размер файлов и число каталогов. Каталоги
+// здесь не обходятся: полный размер с вложенными файлами считает fileinfo.Tree по запросу.
+func (c *ClipboardContent) setFileTotals() {
+	totals := fileinfo.Stat(c.Files)
+	c.FilesBytes = totals.Bytes
+	c.FileDirs = totals.Dirs
+}
+
+// NewImageContent создаёт элемент-изображение с PNG и миниатюрой, не связанный с буфером обмена.
+func NewImageContent(img image.Image) (ClipboardContent, error) {
+	imgData, err := imaging.EncodePNG(img)
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+	content := ClipboardContent{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp: time.Now(),
+		Type:      Image,
+		ImagePNG:  imgData,
+		SizeBytes: len(imgData),
+		Preview:   formatImagePreview(imgData),
+	}
+	if thumb, thumbType, err := imaging.EncodeThumbnail(img, imaging.ThumbnailSize); err == nil {
+		content.Thumbnail = thumb
+		content.ThumbnailType = thumbType
+	}
+	return content, nil
+}
+
+// ErrOversized возвращается при попытке записать в буфер заглушку элемента,
+// превысившего лимит размера: его данные не сохранялись.
+var ErrOversized = errors.New("элемент превышает лимит размера, его содержимое не сохранено")
+
+// Лимиты чтения буфера; 0 — без ограничения.
+var (
+	maxItemBytes   atomic.Int64
+	maxImagePixels atomic.Int64
+)
+
+// SetReadLimits задаёт лимиты на размер читаемого элемента (для изображения —
+// размер DIB) и на число пикселей изображения. Элемент сверх лимита не читается
+// в память целиком, а возвращается заглушкой с Oversized.
+func SetReadLimits(itemBytes, imagePixels int64) {
+	maxItemBytes.Store(itemBytes)
+	maxImagePixels.Store(imagePixels)
+}
+
+// oversizedError сообщает, что элемент буфера превышает лимит; preview описывает
+// элемент для заглушки.
+type oversizedError struct {
+	preview string
+	size    int
+}
+
+func (e *oversizedError) Error() string { return e.preview }
+
+func formatBytesMB(n uintptr) string {
+	return fmt.Sprintf("%.1f МБ", float64(n)/(1<<20))
+}
+
+// checkItemBytes проверяет размер данных формата буфера по clipboard.max_item_bytes.
+func checkItemBytes(kind string, size uintptr) error {
+	if limit := maxItemBytes.Load(); limit > 0 && int64(size) > limit {
+		return &oversizedError{
+			preview: fmt.Sprintf("%s %s не сохранён: больше clipboard.max_item_bytes", kind, formatBytesMB(size)),
+			size:    int(size),
+		}
+	}
+	return nil
+}
+
+// checkImageLimits проверяет заголовок DIB по лимитам до копирования пикселей.
+func checkImageLimits(ptr, size uintptr) error {
+	if err := checkItemBytes("Изображение", size); err != nil {
+		return err
+	}
+	limit := maxImagePixels.Load()
+	if limit <= 0 || size < imaging.BitmapInfoHeaderSize {
+		return nil
+	}
+	hdr, err := imaging.ParseBitmapInfoHeader(unsafe.Slice((*byte)(unsafe.Pointer(ptr)), imaging.BitmapInfoHeaderSize))
+	if err != nil {
+		return nil // Повреждённый заголовок отклонит DecodeDIB
+	}
+	width, height := int64(hdr.Width), int64(hdr.Height)
+	if height < 0 {
+		height = -height
+	}
+	if width*height > limit {
+		return &oversizedError{
+			preview: fmt.Sprintf("Изображение %dx%d не сохранено: больше clipboard.max_image_pixels", width, height),
+			size:    int(size),
+		}
+	}
+	return nil
+}
+
+// readClipboardDIBBytes reads raw DIB data from clipboard without conversion.
+// CF_BITMAP переводится в DIB через GDI.
 func readClipboardDIBBytes(format uint32) ([]byte, error) {
+	if format == CF_BITMAP {
+		return readClipboardBitmapDIB()
+	}
 	handle, _, err := procGetClipboardData.Call(uintptr(format))
 	if handle == 0 {
 		return nil, err
@@ -79,6 +241,9 @@ func readClipboardDIBBytes(format uint32) ([]byte, error) {
 	if size == 0 || size > maxSize {
 		return nil, fmt.Errorf("DIB data size %d exceeds limit %d", size, maxSize)
 	}
+	if err := checkImageLimits(ptr, size); err != nil {
+		return nil, err
+	}
 
 	// Read DIB data
 	dibData := make([]byte, size)
@@ -88,6 +253,15 @@ func readClipboardDIBBytes(format uint32) ([]byte, error) {
 	return dibData, nil
 }
 
+// oversizedContent превращает элемент в заглушку: данные не сохраняются, остаются
+// тип, исходный размер и объяснение в предпросмотре.
+func oversizedContent(content ClipboardContent, err *oversizedError) ClipboardContent {
+	content.Oversized = true
+	content.SizeBytes = err.size
+	content.Preview = err.preview
+	return content
+}
+
 type readClipboardOptions struct {
 	allowSlowImages bool
 }
@@ -110,6 +284,11 @@ func readClipboard(options readClipboardOptions) (ClipboardContent, error) {
 	var content ClipboardContent
 	content.ID = fmt.Sprintf("%d", time.Now().UnixNano())
 	content.Timestamp = time.Now()
+	if pending, ok := pendingClipboardContent(); ok && options.allowSlowImages {
+		// Собственное изображение с отложенной отрисовкой: данные уже есть в памяти.
+		pending.ID, pending.Timestamp = content.ID, content.Timestamp
+		return pending, nil
+	}
 	startTime := time.Now()
 	defer func() {
 		logger.Debug("Total Read() duration: %v", time.Since(startTime))
@@ -141,9 +320,11 @@ func readClipboard(options readClipboardOptions) (ClipboardContent, error) {
 			logger.Error("Не удалось прочитать CF_HDROP: %v", err)
 			return content, err
 		}
+		closeClipboardTracked()
 		content.Files = files
 		content.SizeBytes = calculateFilesSize(files)
 		content.Preview = formatFilesPreview(files)
+		content.setFileTotals()
 		return content, nil
 	}
 
@@ -154,35 +335,90 @@ func readClipboard(options readClipboardOptions) (ClipboardContent, error) {
 			return content, nil
 		}
 
-		dibData, err := readClipboardDIBBytes(imageFormat)
-		if err != nil {
-			logger.Error("Не удалось прочитать %s: %v", clipboardFormatName(imageFormat), err)
-			return content, err
+		// Векторный рисунок сохраняется вместе с растром, чтобы вставка вернула EMF.
+		// Если растра в буфере нет, миниатюра и PNG строятся из самого метафайла.
+		var (
+			emfData []byte
+			img     *image.RGBA
+			err     error
+		)
+		vectorOnly := imageFormat == CF_ENHMETAFILE
+		if hasClipboardFormat(CF_ENHMETAFILE) {
+			emfData, img, err = readClipboardEMF(vectorOnly)
+			var oversized *oversizedError
+			if errors.As(err, &oversized) && vectorOnly {
+				logger.Warn("Метафайл в буфере превышает лимит: %v", err)
+				return oversizedContent(content, oversized), nil
+			}
+			if err != nil {
+				if vectorOnly {
+					logger.Error("Не удалось прочитать CF_ENHMETAFILE: %v", err)
+					return content, err
+				}
+				logger.Warn("Метафайл не сохранён, остаётся только растр: %v", err)
+				emfData = nil
+			}
 		}
 
-		closeClipboardTracked()
+		if !vectorOnly {
+			dibData, err := readClipboardDIBBytes(imageFormat)
+			var oversized *oversizedError
+			if errors.As(err, &oversized) {
+				logger.Warn("Изображение в буфере превышает лимит: %v", err)
+				return oversizedContent(content, oversized), nil
+			}
+			if err != nil {
+				logger.Error("Не удалось прочитать %s: %v", clipboardFormatName(imageFormat), err)
+				return content, err
+			}
 
-		imgData, err := dibToPNG(dibData)
-		if err != nil {
-			if err == ErrUnsupportedDIB {
-				err = fmt.Errorf("неподдерживаемый формат изображения в буфере (%s): %w", clipboardFormatName(imageFormat), err)
-				logger.Warn("%v", err)
+			closeClipboardTracked()
+
+			img, err = imaging.DecodeDIB(dibData)
+			if err != nil {
+				if errors.Is(err, ErrUnsupportedDIB) {
+					err = fmt.Errorf("неподдерживаемый формат изображения в буфере (%s): %w", clipboardFormatName(imageFormat), err)
+					logger.Warn("%v", err)
+					return content, err
+				}
+				logger.Error("Не удалось конвертировать %s в PNG: %v", clipboardFormatName(imageFormat), err)
 				return content, err
 			}
+		}
+		closeClipboardTracked()
+
+		imgData, err := imaging.EncodePNG(img)
+		if err != nil {
 			logger.Error("Не удалось конвертировать %s в PNG: %v", clipboardFormatName(imageFormat), err)
 			return content, err
 		}
 
+		// Миниатюра строится сразу из декодированного изображения, чтобы UI не декодировал PNG повторно.
+		if thumb, thumbType, err := imaging.EncodeThumbnail(img, imaging.ThumbnailSize); err != nil {
+			logger.Warn("Не удалось построить миниатюру изображения: %v", err)
+		} else {
+			content.Thumbnail = thumb
+			content.ThumbnailType = thumbType
+		}
+
 		content.ImagePNG = imgData
-		content.SizeBytes = len(imgData)
+		content.EMF = emfData
+		content.SizeBytes = len(imgData) + len(emfData)
 		content.Preview = formatImagePreview(imgData)
+		if vectorOnly {
+			content.Preview = strings.Replace(content.Preview, "PNG", "EMF", 1)
+		}
 		return content, nil
 	}
 
 	if hasClipboardFormat(CF_UNICODETEXT) {
 		content.Type = Text
 		text, err := readUnicodeText()
-
+		var oversized *oversizedError
+		if errors.As(err, &oversized) {
+			logger.Warn("Текст в буфере превышает лимит: %v", err)
+			return oversizedContent(content, oversized), nil
+		}
 		if err != nil {
 			logger.Error("Не удалось прочитать CF_UNICODETEXT: %v", err)
 			return content, err
@@ -200,6 +436,9 @@ func readClipboard(options readClipboardOptions) (ClipboardContent, error) {
 // Write writes the given ClipboardContent to the clipboard
 func Write(content ClipboardContent) error {
 	startTime := time.Now()
+	if content.Oversized {
+		return fmt.Errorf("%w: %s", ErrOversized, content.Preview)
+	}
 
 	// Special case: clearing clipboard
 	if content.Type == Empty {
@@ -227,7 +466,6 @@ func Write(content ClipboardContent) error {
 	var (
 		textHandle  uintptr
 		filesHandle uintptr
-		imageHandle uintptr
 		err         error
 	)
 
@@ -312,38 +550,12 @@ func Write(content ClipboardContent) error {
 		procGlobalUnlock.Call(filesHandle)
 
 	case Image:
-		// Decode PNG to image
-		var img image.Image
-		img, err = png.Decode(bytes.NewReader(content.ImagePNG))
-		if err != nil {
-			logger.Error("Failed to decode PNG image: %v", err)
-			return err
-		}
-		// Convert image to DIB
-		var dibData []byte
-		dibData, err = imageToDIB(img)
-		if err != nil {
-			logger.Error("Failed to convert image to DIB: %v", err)
+		// Данные форматов собираются по запросу (см. clipboard_render.go);
+		// здесь проверяется только, что изображение удастся декодировать.
+		if _, _, err = image.DecodeConfig(bytes.NewReader(content.ImagePNG)); err != nil {
+			logger.Error("Failed to decode image: %v", err)
 			return err
 		}
-		// Allocate memory
-		imageHandle, _, err = procGlobalAlloc.Call(GMEM_MOVEABLE|GMEM_DDESHARE, uintptr(len(dibData)))
-		if imageHandle == 0 {
-			logger.Error("Failed to allocate memory for DIB: %v", err)
-			return err
-		}
-		// Lock memory and copy data
-		var ptrImage uintptr
-		ptrImage, _, err = procGlobalLock.Call(imageHandle)
-		if ptrImage == 0 {
-			procGlobalFree.Call(imageHandle)
-			logger.Error("Failed to lock memory for DIB: %v", err)
-			return err
-		}
-		// Safe copy without giant-slice
-		dst := unsafe.Slice((*byte)(unsafe.Pointer(ptrImage)), len(dibData))
-		copy(dst, dibData)
-		procGlobalUnlock.Call(imageHandle)
 	}
 
 	// Check if we have a valid handle for the content type
@@ -354,7 +566,7 @@ func Write(content ClipboardContent) error {
 	case Files:
 		validHandle = filesHandle != 0
 	case Image:
-		validHandle = imageHandle != 0
+		validHandle = true
 	}
 
 	if !validHandle {
@@ -365,9 +577,6 @@ func Write(content ClipboardContent) error {
 		if filesHandle != 0 {
 			procGlobalFree.Call(filesHandle)
 		}
-		if imageHandle != 0 {
-			procGlobalFree.Call(imageHandle)
-		}
 		return fmt.Errorf("failed to prepare clipboard content: no valid handle created")
 	}
 
@@ -382,9 +591,6 @@ func Write(content ClipboardContent) error {
 		if filesHandle != 0 {
 			procGlobalFree.Call(filesHandle)
 		}
-		if imageHandle != 0 {
-			procGlobalFree.Call(imageHandle)
-		}
 		return err
 	}
 	defer closeClipboard()
@@ -400,9 +606,6 @@ func Write(content ClipboardContent) error {
 		if filesHandle != 0 {
 			procGlobalFree.Call(filesHandle)
 		}
-		if imageHandle != 0 {
-			procGlobalFree.Call(imageHandle)
-		}
 		return err
 	}
 
@@ -419,10 +622,15 @@ func Write(content ClipboardContent) error {
 			return err
 		}
 	case Image:
-		if err := setClipboardData(CF_DIB, imageHandle); err != nil {
-			logger.Error("Не удалось записать %s: %v", clipboardFormatName(CF_DIB), err)
+		if err := setDelayedClipboardData(content, imageClipboardFormats()); err != nil {
+			logger.Error("Не удалось объявить форматы изображения: %v", err)
 			return err
 		}
+		if len(content.EMF) > 0 {
+			if err := setClipboardEMF(content.EMF); err != nil {
+				logger.Warn("Метафайл не записан, вставится только растр: %v", err)
+			}
+		}
 	}
 
 	// Update last write sequence number
@@ -436,24 +644,6 @@ func Write(content ClipboardContent) error {
 	return nil
 }
 
-// openClipboardWithRetry opens the clipboard with retry logic and exponential backoff
-func openClipboardWithRetry() error {
-	const maxRetries = 5
-	const initialDelay = 50 * time.Millisecond
-	var lastErr error
-
-	for i := 0; i < maxRetries; i++ {
-		if err := openClipboard(); err == nil {
-			return nil
-		} else {
-			lastErr = err
-		}
-		time.Sleep(initialDelay * (1 << uint(i)))
-	}
-
-	return lastErr
-}
-
 func pickClipboardImageFormat() uint32 {
 	if hasClipboardFormat(CF_DIB) {
 		return CF_DIB
@@ -461,6 +651,12 @@ func pickClipboardImageFormat() uint32 {
 	if hasClipboardFormat(CF_DIBV5) {
 		return CF_DIBV5
 	}
+	if hasClipboardFormat(CF_BITMAP) {
+		return CF_BITMAP
+	}
+	if hasClipboardFormat(CF_ENHMETAFILE) {
+		return CF_ENHMETAFILE
+	}
 	return 0
 }
 
@@ -474,7 +670,14 @@ func clipboardFormatName(format uint32) string {
 		return "CF_DIB"
 	case CF_DIBV5:
 		return "CF_DIBV5"
+	case CF_BITMAP:
+		return "CF_BITMAP"
+	case CF_ENHMETAFILE:
+		return "CF_ENHMETAFILE"
 	default:
+		if format != 0 && format == cfPNG() {
+			return "PNG"
+		}
 		return fmt.Sprintf("format=%d", format)
 	}
 }
@@ -507,10 +710,11 @@ func hasClipboardFormat(format uint32) bool {
 	return ret != 0
 }
 
+// calculateFilesSize возвращает размер данных CF_HDROP — столько элемент занимает
+// в памяти и в лимитах истории. Размер самих файлов хранится в FilesBytes.
 func calculateFilesSize(files []string) int {
 	size := 0
 	for _, file := range files {
-		// Note: This is a simplified calculation. For accurate size, we should stat each file.
 		size += len(file) * 2 // UTF-16 encoding
 	}
 	size += 2 // Double null terminator
@@ -637,6 +841,9 @@ func readUnicodeText() (string, error) {
 	if size == 0 || size > 100*1024*1024 { // Limit to 100MB
 		return "", err
 	}
+	if err := checkItemBytes("Текст", size); err != nil {
+		return "", err
+	}
 
 	// Read UTF-16 string from pointer
 	utf16Slice := unsafe.Slice((*uint16)(unsafe.Pointer(ptr)), size/2)
@@ -688,228 +895,8 @@ func readHDrop() ([]string, error) {
 	return files, nil
 }
 
-// imageToDIB converts an image to DIB format (BITMAPINFOHEADER 40, 32bpp BGRA)
-func imageToDIB(img image.Image) ([]byte, error) {
-	// Convert image to RGBA
-	rgba, ok := img.(*image.RGBA)
-	if !ok {
-		rgba = image.NewRGBA(img.Bounds())
-		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
-	}
-
-	bounds := rgba.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// BITMAPINFOHEADER for 32bpp BGRA
-	var bmi BITMAPINFOHEADER
-	bmi.biSize = 40
-	bmi.biWidth = int32(width)
-	bmi.biHeight = int32(height) // Bottom-up image (standard for DIB)
-	bmi.biPlanes = 1
-	bmi.biBitCount = 32
-	bmi.biCompression = BI_RGB
-	rowSize := ((width*4 + 3) / 4) * 4
-	bmi.biSizeImage = uint32(rowSize * height)
-	bmi.biXPelsPerMeter = 2835 // 72 DPI
-	bmi.biYPelsPerMeter = 2835
-	bmi.biClrUsed = 0
-	bmi.biClrImportant = 0
-
-	// Calculate buffer size
-	bufferSize := int(bmi.biSize) + int(bmi.biSizeImage)
-
-	// Create buffer
-	buffer := make([]byte, bufferSize)
-
-	// Write BITMAPINFOHEADER
-	binary.LittleEndian.PutUint32(buffer[0:4], bmi.biSize)
-	binary.LittleEndian.PutUint32(buffer[4:8], uint32(bmi.biWidth))
-	binary.LittleEndian.PutUint32(buffer[8:12], uint32(bmi.biHeight))
-	binary.LittleEndian.PutUint16(buffer[12:14], uint16(bmi.biPlanes))
-	binary.LittleEndian.PutUint16(buffer[14:16], uint16(bmi.biBitCount))
-	binary.LittleEndian.PutUint32(buffer[16:20], bmi.biCompression)
-	binary.LittleEndian.PutUint32(buffer[20:24], bmi.biSizeImage)
-	binary.LittleEndian.PutUint32(buffer[24:28], uint32(bmi.biXPelsPerMeter))
-	binary.LittleEndian.PutUint32(buffer[28:32], uint32(bmi.biYPelsPerMeter))
-	binary.LittleEndian.PutUint32(buffer[32:36], bmi.biClrUsed)
-	binary.LittleEndian.PutUint32(buffer[36:40], bmi.biClrImportant)
-
-	// Write pixel data (BGRA format)
-	pixelOffset := int(bmi.biSize)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			// For bottom-up DIB, first row in buffer is bottom row of image
-			bufY := height - 1 - y
-			r, g, b, a := rgba.At(x, y).RGBA()
-			index := pixelOffset + bufY*rowSize + x*4
-			buffer[index] = byte(b >> 8)
-			buffer[index+1] = byte(g >> 8)
-			buffer[index+2] = byte(r >> 8)
-			buffer[index+3] = byte(a >> 8)
-		}
-	}
-
-	return buffer, nil
-}
-
 // ErrUnsupportedDIB is returned when DIB format is not supported
-var ErrUnsupportedDIB = fmt.Errorf("unsupported DIB format")
-
-// dibToPNG converts DIB data to PNG format
-func dibToPNG(dibData []byte) ([]byte, error) {
-	// Check if DIB data has BITMAPINFOHEADER
-	if len(dibData) < 40 { // BITMAPINFOHEADER size is 40 bytes
-		logger.Warn("DIB data too short for BITMAPINFOHEADER")
-		return nil, ErrUnsupportedDIB
-	}
-
-	// Read BITMAPINFOHEADER
-	var bmi BITMAPINFOHEADER
-	bmi.biSize = binary.LittleEndian.Uint32(dibData[0:4])
-	bmi.biWidth = int32(binary.LittleEndian.Uint32(dibData[4:8]))
-	bmi.biHeight = int32(binary.LittleEndian.Uint32(dibData[8:12]))
-	bmi.biPlanes = int16(binary.LittleEndian.Uint16(dibData[12:14]))
-	bmi.biBitCount = int16(binary.LittleEndian.Uint16(dibData[14:16]))
-	bmi.biCompression = binary.LittleEndian.Uint32(dibData[16:20])
-	bmi.biSizeImage = binary.LittleEndian.Uint32(dibData[20:24])
-	bmi.biXPelsPerMeter = int32(binary.LittleEndian.Uint32(dibData[24:28]))
-	bmi.biYPelsPerMeter = int32(binary.LittleEndian.Uint32(dibData[28:32]))
-	bmi.biClrUsed = binary.LittleEndian.Uint32(dibData[32:36])
-	bmi.biClrImportant = binary.LittleEndian.Uint32(dibData[36:40])
-
-	// Validate DIB dimensions and size
-	if bmi.biWidth <= 0 {
-		logger.Warn("Invalid DIB width: %d", bmi.biWidth)
-		return nil, ErrUnsupportedDIB
-	}
-
-	height := bmi.biHeight
-	if height == 0 {
-		logger.Warn("Invalid DIB height: %d", height)
-		return nil, ErrUnsupportedDIB
-	}
-
-	if height < 0 {
-		height = -height // Convert to absolute value for top-down DIB
-	}
-
-	if int(bmi.biSize) > len(dibData) {
-		logger.Warn("DIB header size %d exceeds buffer size %d", bmi.biSize, len(dibData))
-		return nil, ErrUnsupportedDIB
-	}
-
-	// Currently support 24bpp BGR and 32bpp BGRA (BI_RGB or BI_BITFIELDS with standard masks)
-	if (bmi.biBitCount != 24 && bmi.biBitCount != 32) ||
-		(bmi.biBitCount == 24 && bmi.biCompression != BI_RGB) ||
-		(bmi.biBitCount == 32 && bmi.biCompression != BI_RGB && bmi.biCompression != BI_BITFIELDS) {
-		logger.Warn("Only 24bpp BGR (BI_RGB) and 32bpp BGRA (BI_RGB or BI_BITFIELDS) DIBs are supported currently (got %dbpp, compression: %d)",
-			bmi.biBitCount, bmi.biCompression)
-		return nil, ErrUnsupportedDIB
-	}
-
-	// Calculate pixel data offset
-	var pixelOffset = int(bmi.biSize)
-	if bmi.biClrUsed > 0 || (bmi.biBitCount <= 8 && bmi.biClrUsed == 0) {
-		colorsCount := 1 << bmi.biBitCount
-		if bmi.biClrUsed > 0 && bmi.biClrUsed < uint32(colorsCount) {
-			colorsCount = int(bmi.biClrUsed)
-		}
-		pixelOffset += colorsCount * 4 // Each color in RGBQUAD is 4 bytes
-	}
-
-	// For BI_BITFIELDS with 32bpp, we need to skip color masks (3 DWORDs = 12 bytes)
-	if bmi.biCompression == BI_BITFIELDS {
-		pixelOffset += 12 // 3 masks (R, G, B) each 4 bytes
-	}
-
-	// Calculate row stride
-	bpp := int(bmi.biBitCount) / 8
-	rowSize := ((int(bmi.biWidth)*bpp + 3) / 4) * 4
-
-	// Determine if image is top-down or bottom-up
-	isTopDown := bmi.biHeight < 0
-	h := bmi.biHeight
-	if isTopDown {
-		h = -h
-	}
-
-	// Check if we have enough data for pixels
-	expectedSize := pixelOffset + int(h)*rowSize
-	if len(dibData) < expectedSize {
-		logger.Warn("DIB data too short for pixel data. Expected: %d, Got: %d", expectedSize, len(dibData))
-		return nil, ErrUnsupportedDIB
-	}
-
-	// Create RGBA image
-	img := image.NewRGBA(image.Rect(0, 0, int(bmi.biWidth), int(height)))
-
-	// Get pixel data
-	pixelData := dibData[pixelOffset:]
-
-	// Copy pixels from DIB to RGBA, taking into account stride
-	for y := 0; y < int(height); y++ {
-		// Calculate row start in DIB pixel data
-		var rowStart int
-		if isTopDown {
-			rowStart = y * rowSize
-		} else {
-			rowStart = (int(height) - 1 - y) * rowSize
-		}
-
-		// Copy row pixels
-		for x := 0; x < int(bmi.biWidth); x++ {
-			index := rowStart + x*bpp
-
-			var r, g, b, a byte
-			switch bmi.biBitCount {
-			case 32:
-				// DIB pixels are stored as BGRA (for both BI_RGB and BI_BITFIELDS with standard masks)
-				b = pixelData[index]
-				g = pixelData[index+1]
-				r = pixelData[index+2]
-				a = pixelData[index+3]
-			case 24:
-				// DIB pixels are stored as BGR
-				b = pixelData[index]
-				g = pixelData[index+1]
-				r = pixelData[index+2]
-				a = 255 // Opaque
-			}
-
-			// RGBA pixels are stored as RGBA
-			img.SetRGBA(x, y, color.RGBA{r, g, b, a})
-		}
-	}
-
-	// Encode to PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		logger.Error("Failed to encode PNG: %v", err)
-		return []byte{}, err
-	}
-
-	return buf.Bytes(), nil
-}
-
-// BITMAPINFOHEADER structure
-type BITMAPINFOHEADER struct {
-	biSize          uint32
-	biWidth         int32
-	biHeight        int32
-	biPlanes        int16
-	biBitCount      int16
-	biCompression   uint32
-	biSizeImage     uint32
-	biXPelsPerMeter int32
-	biYPelsPerMeter int32
-	biClrUsed       uint32
-	biClrImportant  uint32
-}
-
-// BI_RGB and BI_BITFIELDS constants
-const BI_RGB = 0
-const BI_BITFIELDS = 3
+var ErrUnsupportedDIB = imaging.ErrUnsupportedDIB
 
 // Global memory allocation constants
 const (
@@ -0,0 +1,76 @@
+//go:build windows
+
+package windows
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+)
+
+// CF_BITMAP — дескриптор HBITMAP. Некоторые программы кладут в буфер только его;
+// обычно Windows синтезирует из него CF_DIB, но не всегда.
+const CF_BITMAP = 2
+
+// DIB_RGB_COLORS — таблица цветов GetDIBits содержит RGB-значения.
+const DIB_RGB_COLORS = 0
+
+var procGetObjectW = gdi32.NewProc("GetObjectW")
+
+// BITMAP описывает HBITMAP (структура BITMAP из wingdi.h).
+type BITMAP struct {
+	Type       int32
+	Width      int32
+	Height     int32
+	WidthBytes int32
+	Planes     uint16
+	BitsPixel  uint16
+	Bits       uintptr
+}
+
+// readClipboardBitmapDIB переводит CF_BITMAP в DIB того же вида, что CF_DIB:
+// BITMAPINFOHEADER и 24-битные строки снизу вверх. 24 бита выбраны потому,
+// что альфа-канал HBITMAP обычно нулевой и 32-битный DIB вышел бы прозрачным.
+// Буфер должен быть открыт.
+func readClipboardBitmapDIB() ([]byte, error) {
+	hbm, _, err := procGetClipboardData.Call(CF_BITMAP)
+	if hbm == 0 {
+		return nil, err
+	}
+	var bm BITMAP
+	if ret, _, err := procGetObjectW.Call(hbm, unsafe.Sizeof(bm), uintptr(unsafe.Pointer(&bm))); ret == 0 {
+		return nil, fmt.Errorf("GetObject(CF_BITMAP): %v", err)
+	}
+	if bm.Width <= 0 || bm.Height <= 0 {
+		return nil, fmt.Errorf("CF_BITMAP: некорректный размер %dx%d", bm.Width, bm.Height)
+	}
+
+	header := imaging.BitmapInfoHeader{
+		Size:        imaging.BitmapInfoHeaderSize,
+		Width:       bm.Width,
+		Height:      bm.Height,
+		Planes:      1,
+		BitCount:    24,
+		Compression: imaging.BI_RGB,
+	}
+	pixelBytes := imaging.RowStride(int(bm.Width), 24) * int(bm.Height)
+	size := uintptr(imaging.BitmapInfoHeaderSize + pixelBytes)
+	if err := checkImageLimits(uintptr(unsafe.Pointer(&header)), size); err != nil {
+		return nil, err
+	}
+
+	dib := make([]byte, size)
+	dc, _, _ := procGetDC.Call(0)
+	if dc == 0 {
+		return nil, fmt.Errorf("GetDC вернул 0")
+	}
+	defer procReleaseDC.Call(0, dc)
+	lines, _, err := procGetDIBits.Call(dc, hbm, 0, uintptr(bm.Height),
+		uintptr(unsafe.Pointer(&dib[imaging.BitmapInfoHeaderSize])), uintptr(unsafe.Pointer(&header)), DIB_RGB_COLORS)
+	if lines == 0 {
+		return nil, fmt.Errorf("GetDIBits(CF_BITMAP): %v", err)
+	}
+	copy(dib, unsafe.Slice((*byte)(unsafe.Pointer(&header)), imaging.BitmapInfoHeaderSize))
+	return dib, nil
+}
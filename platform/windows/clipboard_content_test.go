@@ -1,6 +1,14 @@
 package windows
 
-import "testing"
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"testing"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+)
 
 func TestClipboardContentNeedsImageCapture(t *testing.T) {
 	item := ClipboardContent{
@@ -24,3 +32,67 @@ func TestClipboardContentWithPayloadDoesNotNeedImageCapture(t *testing.T) {
 		t.Fatal("изображение с локальным payload не должно ожидать захвата")
 	}
 }
+
+func TestNewImageContentBuildsPNGAndThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	item, err := NewImageContent(img)
+	if err != nil {
+		t.Fatalf("NewImageContent: %v", err)
+	}
+	if item.Type != Image || len(item.ImagePNG) == 0 || item.SizeBytes != len(item.ImagePNG) {
+		t.Fatalf("неполный элемент-изображение: %+v", item)
+	}
+	if item.Preview != "400x200 PNG" {
+		t.Fatalf("неожиданный предпросмотр %q", item.Preview)
+	}
+	if len(item.Thumbnail) == 0 || item.ThumbnailType == "" {
+		t.Fatal("ожидалась миниатюра")
+	}
+	if item.NeedsImageCapture() {
+		t.Fatal("загруженное изображение не должно ожидать захвата из буфера")
+	}
+}
+
+func TestCheckImageLimits(t *testing.T) {
+	defer SetReadLimits(0, 0)
+	dib := make([]byte, imaging.BitmapInfoHeaderSize)
+	binary.LittleEndian.PutUint32(dib[0:], imaging.BitmapInfoHeaderSize)
+	binary.LittleEndian.PutUint32(dib[4:], 4000)
+	binary.LittleEndian.PutUint32(dib[8:], uint32(0xFFFFFFFF-3000+1)) // -3000: строки сверху вниз
+	ptr := uintptr(unsafe.Pointer(&dib[0]))
+	size := uintptr(4000 * 3000 * 4)
+
+	SetReadLimits(0, 0)
+	if err := checkImageLimits(ptr, size); err != nil {
+		t.Fatalf("без лимитов ошибки быть не должно: %v", err)
+	}
+	SetReadLimits(0, 10_000_000)
+	var oversized *oversizedError
+	if err := checkImageLimits(ptr, size); !errors.As(err, &oversized) || oversized.size != int(size) {
+		t.Fatalf("ожидалось превышение лимита пикселей, получено %v", err)
+	}
+	SetReadLimits(10<<20, 0)
+	if err := checkImageLimits(ptr, size); !errors.As(err, &oversized) {
+		t.Fatalf("ожидалось превышение лимита размера, получено %v", err)
+	}
+	if err := Write(oversizedContent(ClipboardContent{Type: Image}, oversized)); !errors.Is(err, ErrOversized) {
+		t.Fatalf("заглушку нельзя записывать в буфер, получено %v", err)
+	}
+}
+
+func TestEMFRasterSize(t *testing.T) {
+	// 10x5 см при 96 DPI — 377x188 пикселей.
+	w, h := emfRasterSize(ENHMETAHEADER{Frame: RECT{Right: 10000, Bottom: 5000}})
+	if w != 377 || h != 188 {
+		t.Fatalf("размер = %dx%d, ожидалось 377x188", w, h)
+	}
+	// Пустая рамка: берутся границы в единицах устройства.
+	if w, h := emfRasterSize(ENHMETAHEADER{Bounds: RECT{Right: 99, Bottom: 49}}); w != 100 || h != 50 {
+		t.Fatalf("размер по границам = %dx%d, ожидалось 100x50", w, h)
+	}
+	// Огромный рисунок уменьшается с сохранением пропорций.
+	if w, h := emfRasterSize(ENHMETAHEADER{Frame: RECT{Right: 1_000_000, Bottom: 500_000}}); w != emfRasterMaxSize || h != emfRasterMaxSize/2 {
+		t.Fatalf("размер = %dx%d, ожидалось %dx%d", w, h, emfRasterMaxSize, emfRasterMaxSize/2)
+	}
+}
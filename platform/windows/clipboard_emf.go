@@ -0,0 +1,168 @@
+//go:build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+)
+
+// CF_ENHMETAFILE — векторный рисунок (HENHMETAFILE). Office и Visio при копировании
+// фигур и диаграмм часто кладут в буфер только его.
+const CF_ENHMETAFILE = 14
+
+// emfRasterMaxSize ограничивает большую сторону растровой копии метафайла, пикселей.
+const emfRasterMaxSize = 4096
+
+// whiteness — растровая операция PatBlt, заливающая область белым.
+const whiteness = 0x00FF0062
+
+var (
+	procGetEnhMetaFileBits   = gdi32.NewProc("GetEnhMetaFileBits")
+	procSetEnhMetaFileBits   = gdi32.NewProc("SetEnhMetaFileBits")
+	procGetEnhMetaFileHeader = gdi32.NewProc("GetEnhMetaFileHeader")
+	procPlayEnhMetaFile      = gdi32.NewProc("PlayEnhMetaFile")
+	procDeleteEnhMetaFile    = gdi32.NewProc("DeleteEnhMetaFile")
+	procPatBlt               = gdi32.NewProc("PatBlt")
+)
+
+// ENHMETAHEADER — начало заголовка метафайла до полей размеров устройства.
+type ENHMETAHEADER struct {
+	Type           uint32
+	Size           uint32
+	Bounds         RECT // Границы рисунка в единицах устройства
+	Frame          RECT // Рамка рисунка в сотых долях миллиметра
+	Signature      uint32
+	Version        uint32
+	Bytes          uint32
+	Records        uint32
+	Handles        uint16
+	Reserved       uint16
+	NDescription   uint32
+	OffDescription uint32
+	NPalEntries    uint32
+	Device         [2]int32
+	Millimeters    [2]int32
+}
+
+// readClipboardEMF копирует байты CF_ENHMETAFILE и, если нужно, растеризует
+// рисунок на белом фоне для миниатюры и вставки в программы без поддержки EMF.
+// Буфер должен быть открыт.
+func readClipboardEMF(rasterize bool) ([]byte, *image.RGBA, error) {
+	hemf, _, err := procGetClipboardData.Call(CF_ENHMETAFILE)
+	if hemf == 0 {
+		return nil, nil, err
+	}
+	size, _, err := procGetEnhMetaFileBits.Call(hemf, 0, 0)
+	if size == 0 {
+		return nil, nil, fmt.Errorf("GetEnhMetaFileBits: %v", err)
+	}
+	if err := checkItemBytes("Метафайл", size); err != nil {
+		return nil, nil, err
+	}
+	data := make([]byte, size)
+	if ret, _, err := procGetEnhMetaFileBits.Call(hemf, size, uintptr(unsafe.Pointer(&data[0]))); ret == 0 {
+		return nil, nil, fmt.Errorf("GetEnhMetaFileBits: %v", err)
+	}
+	if !rasterize {
+		return data, nil, nil
+	}
+	img, err := rasterizeEMF(hemf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, img, nil
+}
+
+// emfRasterSize переводит рамку метафайла (сотые доли миллиметра) в пиксели
+// при 96 DPI и уменьшает результат до emfRasterMaxSize. Если рамка пуста,
+// используются границы в единицах устройства.
+func emfRasterSize(hdr ENHMETAHEADER) (int, int) {
+	w := int(hdr.Frame.Right-hdr.Frame.Left) * 96 / 2540
+	h := int(hdr.Frame.Bottom-hdr.Frame.Top) * 96 / 2540
+	if w <= 0 || h <= 0 {
+		w = int(hdr.Bounds.Right-hdr.Bounds.Left) + 1
+		h = int(hdr.Bounds.Bottom-hdr.Bounds.Top) + 1
+	}
+	if w > emfRasterMaxSize || h > emfRasterMaxSize {
+		if w >= h {
+			w, h = emfRasterMaxSize, max(1, h*emfRasterMaxSize/w)
+		} else {
+			w, h = max(1, w*emfRasterMaxSize/h), emfRasterMaxSize
+		}
+	}
+	return w, h
+}
+
+func rasterizeEMF(hemf uintptr) (*image.RGBA, error) {
+	var hdr ENHMETAHEADER
+	if ret, _, err := procGetEnhMetaFileHeader.Call(hemf, unsafe.Sizeof(hdr), uintptr(unsafe.Pointer(&hdr))); ret == 0 {
+		return nil, fmt.Errorf("GetEnhMetaFileHeader: %v", err)
+	}
+	w, h := emfRasterSize(hdr)
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("метафайл без размеров")
+	}
+	header := imaging.BitmapInfoHeader{
+		Size:     imaging.BitmapInfoHeaderSize,
+		Width:    int32(w),
+		Height:   -int32(h), // Отрицательная высота — строки сверху вниз
+		Planes:   1,
+		BitCount: 32,
+	}
+	if err := checkImageLimits(uintptr(unsafe.Pointer(&header)), uintptr(imaging.BitmapInfoHeaderSize+w*h*4)); err != nil {
+		return nil, err
+	}
+
+	screenDC, _, _ := procGetDC.Call(0)
+	if screenDC == 0 {
+		return nil, errors.New("GetDC экрана не удался")
+	}
+	defer procReleaseDC.Call(0, screenDC)
+	memDC, _, _ := procCreateCompatibleDC.Call(screenDC)
+	if memDC == 0 {
+		return nil, errors.New("CreateCompatibleDC не удался")
+	}
+	defer procDeleteDC.Call(memDC)
+	bitmap, _, _ := procCreateCompatibleBM.Call(screenDC, uintptr(w), uintptr(h))
+	if bitmap == 0 {
+		return nil, fmt.Errorf("CreateCompatibleBitmap %dx%d не удался", w, h)
+	}
+	defer procDeleteObject.Call(bitmap)
+	old, _, _ := procSelectObject.Call(memDC, bitmap)
+
+	procPatBlt.Call(memDC, 0, 0, uintptr(w), uintptr(h), whiteness)
+	rect := RECT{Right: int32(w), Bottom: int32(h)}
+	ok, _, err := procPlayEnhMetaFile.Call(memDC, hemf, uintptr(unsafe.Pointer(&rect)))
+	procSelectObject.Call(memDC, old)
+	if ok == 0 {
+		return nil, fmt.Errorf("PlayEnhMetaFile: %v", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if lines, _, err := procGetDIBits.Call(memDC, bitmap, 0, uintptr(h), uintptr(unsafe.Pointer(&img.Pix[0])), uintptr(unsafe.Pointer(&header)), DIB_RGB_COLORS); lines == 0 {
+		return nil, fmt.Errorf("GetDIBits: %v", err)
+	}
+	// GetDIBits отдаёт BGRX: меняем каналы местами, фон непрозрачный.
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+2], img.Pix[i+3] = img.Pix[i+2], img.Pix[i], 0xFF
+	}
+	return img, nil
+}
+
+// setClipboardEMF записывает метафайл в открытый и очищенный буфер.
+func setClipboardEMF(data []byte) error {
+	hemf, _, err := procSetEnhMetaFileBits.Call(uintptr(len(data)), uintptr(unsafe.Pointer(&data[0])))
+	if hemf == 0 {
+		return fmt.Errorf("SetEnhMetaFileBits: %v", err)
+	}
+	if ret, _, err := procSetClipboardData.Call(CF_ENHMETAFILE, hemf); ret == 0 {
+		procDeleteEnhMetaFile.Call(hemf)
+		return fmt.Errorf("SetClipboardData(CF_ENHMETAFILE): %v", err)
+	}
+	return nil
+}
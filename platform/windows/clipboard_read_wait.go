@@ -0,0 +1,101 @@
+package windows
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	clipboardReadPoll = time.Millisecond
+	// clipboardReadSettle — сколько буфер должен оставаться закрытым после чтения:
+	// приложения нередко открывают его повторно за другим форматом.
+	clipboardReadSettle = 30 * time.Millisecond
+	// ClipboardReadMaxWait ограничивает ожидание приложения, которое начало читать
+	// буфер, но всё ещё держит его открытым.
+	ClipboardReadMaxWait = 5 * time.Second
+)
+
+var procGetOpenClipboardWindow = user32.NewProc("GetOpenClipboardWindow")
+
+// clipboardRenders считает запросы отложенных форматов (WM_RENDERFORMAT) и помнит
+// процесс, который запросил последний из них.
+var clipboardRenders struct {
+	sync.Mutex
+	count uint64
+	pid   uint32
+}
+
+// clipboardHolderPID возвращает процесс, который сейчас держит буфер открытым;
+// 0 — буфер закрыт или открыт без окна.
+func clipboardHolderPID() uint32 {
+	hwnd, _, _ := procGetOpenClipboardWindow.Call()
+	if hwnd == 0 {
+		return 0
+	}
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	return pid
+}
+
+// noteClipboardRender запоминает процесс, запросивший отложенный формат.
+// Вызывается из WM_RENDERFORMAT, пока запросивший держит буфер открытым.
+func noteClipboardRender() {
+	pid := clipboardHolderPID()
+	clipboardRenders.Lock()
+	clipboardRenders.count++
+	clipboardRenders.pid = pid
+	clipboardRenders.Unlock()
+}
+
+func clipboardRenderState() (uint64, uint32) {
+	clipboardRenders.Lock()
+	defer clipboardRenders.Unlock()
+	return clipboardRenders.count, clipboardRenders.pid
+}
+
+// WaitClipboardRead ждёт, пока процесс pid прочитает содержимое буфера с номером
+// последовательности seq: откроет буфер (или запросит отложенный формат) и закроет его.
+// Чтение другими процессами, например журналом буфера Windows, не учитывается.
+// Если за timeout чтение не началось, возвращается false; начатое чтение ждётся
+// до ClipboardReadMaxWait. false также означает, что буфер уже заменён.
+// Приложение, которое открывает буфер без окна, не заметить — для него всегда
+// выжидается timeout.
+func WaitClipboardRead(seq, pid uint32, timeout time.Duration) bool {
+	if pid == 0 {
+		time.Sleep(timeout)
+		return false
+	}
+	start := time.Now()
+	renders, _ := clipboardRenderState()
+	var reading bool
+	var closedAt time.Time
+	for {
+		if GetClipboardSequenceNumber() != seq {
+			return false
+		}
+		holder := clipboardHolderPID()
+		if count, renderPID := clipboardRenderState(); count != renders {
+			renders = count
+			if renderPID == pid {
+				reading, closedAt = true, time.Time{}
+			}
+		}
+		now := time.Now()
+		if holder == pid {
+			reading, closedAt = true, time.Time{}
+		} else if reading {
+			if closedAt.IsZero() {
+				closedAt = now
+			}
+			if now.Sub(closedAt) >= clipboardReadSettle {
+				return true
+			}
+		}
+		elapsed := now.Sub(start)
+		if (!reading && elapsed >= timeout) || elapsed >= ClipboardReadMaxWait {
+			return false
+		}
+		time.Sleep(clipboardReadPoll)
+	}
+}
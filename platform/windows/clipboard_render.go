@@ -0,0 +1,196 @@
+package windows
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// Изображения записываются в буфер с отложенной отрисовкой: Write объявляет
+// форматы CF_DIB и PNG без данных, а DIB собирается только когда получатель
+// запросит его (WM_RENDERFORMAT). Вставка и восстановление буфера не тратят
+// время на перекодирование, которое может никому не понадобиться.
+
+const (
+	WM_RENDERFORMAT     = 0x0305
+	WM_RENDERALLFORMATS = 0x0306
+	WM_DESTROYCLIPBOARD = 0x0307
+)
+
+var (
+	procGetClipboardOwner        = user32.NewProc("GetClipboardOwner")
+	procRegisterClipboardFormatW = user32.NewProc("RegisterClipboardFormatW")
+)
+
+// cfPNG — зарегистрированный формат "PNG", который понимают браузеры и графические
+// редакторы; в отличие от CF_DIB он сохраняет прозрачность.
+var cfPNG = sync.OnceValue(func() uint32 {
+	name, _ := syscall.UTF16PtrFromString("PNG")
+	ret, _, err := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(name)))
+	if ret == 0 {
+		logger.Warn("Не удалось зарегистрировать формат буфера PNG: %v", err)
+	}
+	return uint32(ret)
+})
+
+// delayedClipboard — содержимое, записанное с отложенной отрисовкой, и форматы,
+// которые ещё не отрисованы. Сведения актуальны, пока номер последовательности
+// буфера равен seq.
+var delayedClipboard struct {
+	sync.Mutex
+	active  bool
+	content ClipboardContent
+	pending []uint32
+	seq     uint32
+}
+
+// imageClipboardFormats перечисляет форматы, которые Write объявляет для изображения.
+func imageClipboardFormats() []uint32 {
+	if png := cfPNG(); png != 0 {
+		return []uint32{CF_DIB, png}
+	}
+	return []uint32{CF_DIB}
+}
+
+// setDelayedClipboardData объявляет форматы без данных. Буфер должен быть открыт
+// и очищен этим процессом. При отложенной отрисовке SetClipboardData возвращает
+// NULL и при успехе, поэтому ошибкой считается только ненулевой код ошибки.
+func setDelayedClipboardData(content ClipboardContent, formats []uint32) error {
+	for _, format := range formats {
+		ret, _, sysErr := procSetClipboardData.Call(uintptr(format), 0)
+		if ret == 0 && !isZeroSyscallError(sysErr) {
+			return fmt.Errorf("SetClipboardData(%s, NULL): %w", clipboardFormatName(format), sysErr)
+		}
+	}
+	delayedClipboard.Lock()
+	delayedClipboard.active = true
+	delayedClipboard.content = content
+	delayedClipboard.pending = append([]uint32(nil), formats...)
+	delayedClipboard.seq = GetClipboardSequenceNumber()
+	delayedClipboard.Unlock()
+	return nil
+}
+
+// pendingClipboardContent возвращает содержимое с отложенной отрисовкой, если
+// буфер с тех пор не менялся. Так чтение собственного изображения перед вставкой
+// не заставляет собирать DIB только ради того, чтобы потом восстановить его.
+func pendingClipboardContent() (ClipboardContent, bool) {
+	delayedClipboard.Lock()
+	defer delayedClipboard.Unlock()
+	if !delayedClipboard.active || delayedClipboard.seq != GetClipboardSequenceNumber() {
+		return ClipboardContent{}, false
+	}
+	return delayedClipboard.content, true
+}
+
+func clearDelayedClipboard() {
+	delayedClipboard.Lock()
+	delayedClipboard.active = false
+	delayedClipboard.content = ClipboardContent{}
+	delayedClipboard.pending = nil
+	delayedClipboard.Unlock()
+}
+
+// takeDelayedFormat возвращает содержимое, если формат ещё ждёт отрисовки,
+// и снимает его с ожидания: система запрашивает каждый формат один раз.
+func takeDelayedFormat(format uint32) (ClipboardContent, bool) {
+	delayedClipboard.Lock()
+	defer delayedClipboard.Unlock()
+	for i, f := range delayedClipboard.pending {
+		if f == format {
+			delayedClipboard.pending = append(delayedClipboard.pending[:i:i], delayedClipboard.pending[i+1:]...)
+			return delayedClipboard.content, true
+		}
+	}
+	return ClipboardContent{}, false
+}
+
+// renderClipboardFormat кодирует изображение в запрошенный формат.
+func renderClipboardFormat(content ClipboardContent, format uint32) ([]byte, error) {
+	if format == cfPNG() && (content.ImageType == "" || content.ImageType == "image/png") {
+		return content.ImagePNG, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(content.ImagePNG))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось декодировать изображение: %w", err)
+	}
+	if format == CF_DIB {
+		return imaging.EncodeDIB(img), nil
+	}
+	return imaging.EncodePNG(img)
+}
+
+// globalAllocBytes копирует данные в перемещаемый блок памяти для SetClipboardData.
+func globalAllocBytes(data []byte) (uintptr, error) {
+	handle, _, err := procGlobalAlloc.Call(GMEM_MOVEABLE|GMEM_DDESHARE, uintptr(len(data)))
+	if handle == 0 {
+		return 0, fmt.Errorf("GlobalAlloc: %w", err)
+	}
+	ptr, _, err := procGlobalLock.Call(handle)
+	if ptr == 0 {
+		procGlobalFree.Call(handle)
+		return 0, fmt.Errorf("GlobalLock: %w", err)
+	}
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(ptr)), len(data)), data)
+	procGlobalUnlock.Call(handle)
+	return handle, nil
+}
+
+// renderDelayedFormat отрисовывает один формат. Буфер уже открыт процессом,
+// который запросил данные, поэтому здесь он не открывается.
+func renderDelayedFormat(format uint32) {
+	noteClipboardRender()
+	content, ok := takeDelayedFormat(format)
+	if !ok {
+		logger.Warn("WM_RENDERFORMAT: формат %s не ожидает отрисовки", clipboardFormatName(format))
+		return
+	}
+	start := time.Now()
+	data, err := renderClipboardFormat(content, format)
+	if err != nil {
+		logger.Error("Не удалось подготовить %s по запросу: %v", clipboardFormatName(format), err)
+		return
+	}
+	handle, err := globalAllocBytes(data)
+	if err != nil {
+		logger.Error("Не удалось выделить память для %s: %v", clipboardFormatName(format), err)
+		return
+	}
+	if err := setClipboardData(format, handle); err != nil {
+		logger.Error("Не удалось передать %s по запросу: %v", clipboardFormatName(format), err)
+		return
+	}
+	logger.Debug("Формат %s отрисован по запросу за %v (%d байт)", clipboardFormatName(format), time.Since(start), len(data))
+}
+
+// renderAllDelayedFormats отрисовывает все ожидающие форматы перед закрытием
+// окна-владельца, чтобы изображение осталось в буфере после выхода программы.
+func renderAllDelayedFormats(hwnd uintptr) {
+	delayedClipboard.Lock()
+	formats := append([]uint32(nil), delayedClipboard.pending...)
+	delayedClipboard.Unlock()
+	if len(formats) == 0 {
+		return
+	}
+	if err := openClipboard(); err != nil {
+		logger.Warn("Не удалось открыть буфер для отрисовки отложенных форматов: %v", err)
+		return
+	}
+	defer closeClipboard()
+	// Пока буфер был закрыт, его мог занять другой процесс.
+	if owner, _, _ := procGetClipboardOwner.Call(); owner != hwnd {
+		clearDelayedClipboard()
+		return
+	}
+	for _, format := range formats {
+		renderDelayedFormat(format)
+	}
+	clearDelayedClipboard()
+}
@@ -0,0 +1,194 @@
+package windows
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const (
+	// clipboardOpenReportInterval — не чаще этого сообщать о том, что буфер не открывается.
+	clipboardOpenReportInterval = time.Minute
+	// clipboardLockLimit — сколько приложений, блокировавших буфер, помнит диагностика.
+	clipboardLockLimit = 20
+)
+
+// OpenRetryPolicy задаёт повторные попытки открыть буфер обмена, пока его держит
+// другое приложение: пауза растёт вдвое от InitialDelay до MaxDelay и случайно
+// отклоняется на долю Jitter, чтобы не совпадать по времени с чужими повторами.
+type OpenRetryPolicy struct {
+	Attempts     int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Jitter       float64 // 0.2 — пауза случайно меняется в пределах ±20 %
+}
+
+var openRetry atomic.Pointer[OpenRetryPolicy]
+
+var openFailures struct {
+	sync.Mutex
+	handler  func(err error)
+	reported time.Time
+}
+
+// SetOpenRetryPolicy задаёт политику повторного открытия буфера обмена.
+func SetOpenRetryPolicy(p OpenRetryPolicy) {
+	openRetry.Store(&p)
+}
+
+// OnClipboardOpenFailure задаёт обработчик случаев, когда буфер не удалось открыть
+// за все попытки. Обработчик вызывается в отдельной горутине и не чаще раза в минуту.
+func OnClipboardOpenFailure(handler func(err error)) {
+	openFailures.Lock()
+	openFailures.handler = handler
+	openFailures.Unlock()
+}
+
+// ClipboardBusyError — буфер обмена не открылся за все попытки.
+type ClipboardBusyError struct {
+	Attempts int
+	Holder   string // Процесс, который держит буфер открытым, если его удалось определить
+	Err      error
+}
+
+func (e *ClipboardBusyError) Error() string {
+	if e.Holder != "" {
+		return fmt.Sprintf("буфер обмена заблокирован приложением %s (попыток: %d)", e.Holder, e.Attempts)
+	}
+	return fmt.Sprintf("буфер обмена не открылся за %d попыток: %v", e.Attempts, e.Err)
+}
+
+// ClipboardLock — приложение, из-за которого буфер обмена не открывался.
+type ClipboardLock struct {
+	Process   string    `json:"process"` // Пусто — буфер был открыт без окна, владельца не определить
+	Title     string    `json:"title,omitempty"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"lastError"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// ClipboardContention — статистика открытия буфера обмена с запуска ClipQueue.
+type ClipboardContention struct {
+	Retried  int             `json:"retried"`          // Открытия, удавшиеся не с первой попытки
+	Failures int             `json:"failures"`         // Случаи, когда буфер не открылся за все попытки
+	Holder   string          `json:"holder,omitempty"` // Процесс, который держит буфер открытым сейчас
+	Locks    []ClipboardLock `json:"locks"`            // Блокировавшие приложения, последние первыми
+}
+
+var contention struct {
+	sync.Mutex
+	retried  int
+	failures int
+	locks    []ClipboardLock
+}
+
+// ClipboardContentionStats возвращает статистику открытия буфера обмена.
+func ClipboardContentionStats() ClipboardContention {
+	contention.Lock()
+	stats := ClipboardContention{
+		Retried:  contention.retried,
+		Failures: contention.failures,
+		Locks:    append([]ClipboardLock{}, contention.locks...),
+	}
+	contention.Unlock()
+	stats.Holder = clipboardHolder().ProcessName
+	return stats
+}
+
+// clipboardHolder возвращает окно, которое сейчас держит буфер открытым; нулевое —
+// буфер закрыт или открыт без окна.
+func clipboardHolder() WindowInfo {
+	hwnd, _, _ := procGetOpenClipboardWindow.Call()
+	if hwnd == 0 {
+		return WindowInfo{}
+	}
+	return GetWindowInfo(hwnd)
+}
+
+// noteClipboardLock запоминает приложение, из-за которого буфер не открылся.
+func noteClipboardLock(holder WindowInfo, err error) {
+	contention.Lock()
+	defer contention.Unlock()
+	contention.failures++
+	lock := ClipboardLock{Process: holder.ProcessName, Title: holder.Title, Failures: 1, LastError: err.Error(), LastSeen: time.Now()}
+	for i, known := range contention.locks {
+		if known.Process == holder.ProcessName {
+			lock.Failures += known.Failures
+			contention.locks = append(contention.locks[:i], contention.locks[i+1:]...)
+			break
+		}
+	}
+	contention.locks = append([]ClipboardLock{lock}, contention.locks...)
+	if len(contention.locks) > clipboardLockLimit {
+		contention.locks = contention.locks[:clipboardLockLimit]
+	}
+}
+
+func (e *ClipboardBusyError) Unwrap() error { return e.Err }
+
+// openRetryDelay возвращает паузу после неудачной попытки attempt (с нуля);
+// random — случайное число из [0, 1).
+func openRetryDelay(p OpenRetryPolicy, attempt int, random float64) time.Duration {
+	d := p.InitialDelay
+	for i := 0; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*random-1)))
+	}
+	return d
+}
+
+// openClipboardWithRetry opens the clipboard with retry logic and exponential backoff
+func openClipboardWithRetry() error {
+	p := OpenRetryPolicy{Attempts: 5, InitialDelay: 50 * time.Millisecond, MaxDelay: 800 * time.Millisecond}
+	if configured := openRetry.Load(); configured != nil {
+		p = *configured
+	}
+	attempts := max(p.Attempts, 1)
+	var lastErr error
+
+	for i := 0; i < attempts; i++ {
+		if err := openClipboard(); err == nil {
+			if i > 0 {
+				contention.Lock()
+				contention.retried++
+				contention.Unlock()
+			}
+			return nil
+		} else {
+			lastErr = err
+		}
+		if i < attempts-1 {
+			time.Sleep(openRetryDelay(p, i, rand.Float64()))
+		}
+	}
+
+	holder := clipboardHolder()
+	err := &ClipboardBusyError{Attempts: attempts, Holder: holder.ProcessName, Err: lastErr}
+	noteClipboardLock(holder, lastErr)
+	reportOpenFailure(err, holder)
+	return err
+}
+
+func reportOpenFailure(err error, holder WindowInfo) {
+	if holder.ProcessName != "" {
+		logger.Warn("%v, окно %q", err, holder.Title)
+	} else {
+		logger.Warn("%v", err)
+	}
+	openFailures.Lock()
+	defer openFailures.Unlock()
+	if openFailures.handler == nil || time.Since(openFailures.reported) < clipboardOpenReportInterval {
+		return
+	}
+	openFailures.reported = time.Now()
+	go openFailures.handler(err)
+}
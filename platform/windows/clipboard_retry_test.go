@@ -0,0 +1,28 @@
+package windows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenRetryDelay(t *testing.T) {
+	p := OpenRetryPolicy{Attempts: 6, InitialDelay: 50 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	want := []time.Duration{50, 100, 200, 300, 300}
+	for attempt, w := range want {
+		if got := openRetryDelay(p, attempt, 0.5); got != w*time.Millisecond {
+			t.Errorf("попытка %d: ожидалось %v, получено %v", attempt, w*time.Millisecond, got)
+		}
+	}
+
+	p.Jitter = 0.2
+	if got := openRetryDelay(p, 0, 0); got != 40*time.Millisecond {
+		t.Errorf("нижняя граница разброса: ожидалось 40ms, получено %v", got)
+	}
+	if got := openRetryDelay(p, 0, 1); got != 60*time.Millisecond {
+		t.Errorf("верхняя граница разброса: ожидалось 60ms, получено %v", got)
+	}
+
+	if got := openRetryDelay(OpenRetryPolicy{InitialDelay: time.Millisecond}, 10, 0.5); got != 1024*time.Millisecond {
+		t.Errorf("без предела пауза растёт вдвое: ожидалось 1.024s, получено %v", got)
+	}
+}
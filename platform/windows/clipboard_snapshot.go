@@ -0,0 +1,176 @@
+//go:build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+// Форматы, данные которых — дескрипторы GDI или окна, а не память GlobalAlloc.
+// CF_BITMAP система синтезирует из CF_DIB, поэтому его копия не нужна;
+// CF_ENHMETAFILE сохраняется отдельно через байты метафайла.
+const (
+	cfMetafilePict    = 3
+	cfPalette         = 9
+	cfOwnerDisplay    = 0x0080
+	cfDspBitmap       = 0x0082
+	cfDspMetafilePict = 0x0083
+	cfDspEnhMetafile  = 0x008E
+	cfGDIObjFirst     = 0x0300
+	cfGDIObjLast      = 0x03FF
+)
+
+// snapshotMaxFormats ограничивает число форматов в снимке: некоторые приложения
+// объявляют десятки своих форматов.
+const snapshotMaxFormats = 64
+
+var procEnumClipboardFormats = user32.NewProc("EnumClipboardFormats")
+
+// SnapshotFormat — сырые байты одного формата буфера обмена.
+type SnapshotFormat struct {
+	Format uint32
+	Data   []byte
+}
+
+// Snapshot — содержимое буфера обмена во всех форматах, которые можно скопировать
+// как память: текст в кодировках, HTML, RTF, форматы приложений. В отличие от
+// Read, который выбирает один формат, восстанавливает буфер таким же, каким он был.
+type Snapshot struct {
+	Formats []SnapshotFormat
+	Size    int // Суммарный размер данных
+}
+
+// Empty сообщает, что в снимке нет ни одного формата.
+func (s *Snapshot) Empty() bool {
+	return s == nil || len(s.Formats) == 0
+}
+
+// TakeSnapshot копирует все форматы буфера обмена. Снимок больше
+// clipboard.max_item_bytes не делается — возвращается ErrOversized.
+func TakeSnapshot() (*Snapshot, error) {
+	startTime := time.Now()
+	if err := openClipboardWithRetry(); err != nil {
+		return nil, fmt.Errorf("не удалось открыть буфер обмена для снимка: %w", err)
+	}
+	defer closeClipboard()
+
+	snapshot := &Snapshot{}
+	var format uintptr
+	for len(snapshot.Formats) < snapshotMaxFormats {
+		format, _, _ = procEnumClipboardFormats.Call(format)
+		if format == 0 {
+			break
+		}
+		data, err := readSnapshotFormat(uint32(format))
+		var oversized *oversizedError
+		if errors.As(err, &oversized) {
+			return nil, fmt.Errorf("%w: %v", ErrOversized, err)
+		}
+		if err != nil {
+			logger.Debug("Снимок буфера: формат %s пропущен: %v", clipboardFormatName(uint32(format)), err)
+			continue
+		}
+		if data == nil {
+			continue
+		}
+		snapshot.Size += len(data)
+		if err := checkItemBytes("Снимок буфера", uintptr(snapshot.Size)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOversized, err)
+		}
+		snapshot.Formats = append(snapshot.Formats, SnapshotFormat{Format: uint32(format), Data: data})
+	}
+	logger.Debug("Снимок буфера: форматов %d, %d байт за %v", len(snapshot.Formats), snapshot.Size, time.Since(startTime))
+	return snapshot, nil
+}
+
+// readSnapshotFormat читает данные формата из открытого буфера. nil без ошибки —
+// формат не хранится как память и в снимок не входит.
+func readSnapshotFormat(format uint32) ([]byte, error) {
+	switch {
+	case format == CF_ENHMETAFILE:
+		data, _, err := readClipboardEMF(false)
+		return data, err
+	case format == CF_BITMAP, format == cfMetafilePict, format == cfPalette,
+		format == cfOwnerDisplay, format == cfDspBitmap, format == cfDspMetafilePict, format == cfDspEnhMetafile,
+		format >= cfGDIObjFirst && format <= cfGDIObjLast:
+		return nil, nil
+	}
+
+	handle, _, err := procGetClipboardData.Call(uintptr(format))
+	if handle == 0 {
+		return nil, err
+	}
+	size, _, _ := procGlobalSize.Call(handle)
+	if size == 0 {
+		return nil, nil
+	}
+	if err := checkItemBytes("Формат буфера", size); err != nil {
+		return nil, err
+	}
+	ptr, _, err := procGlobalLock.Call(handle)
+	if ptr == 0 {
+		return nil, err
+	}
+	defer procGlobalUnlock.Call(handle)
+	data := make([]byte, size)
+	copy(data, unsafe.Slice((*byte)(unsafe.Pointer(ptr)), size))
+	return data, nil
+}
+
+// RestoreSnapshot заменяет содержимое буфера обмена снимком. Пустой снимок очищает буфер.
+func RestoreSnapshot(snapshot *Snapshot) error {
+	if snapshot.Empty() {
+		return Write(ClipboardContent{Type: Empty})
+	}
+	if clipboardOpenOwner() == 0 {
+		return fmt.Errorf("окно-владелец буфера обмена не зарегистрировано")
+	}
+	if err := openClipboardWithRetry(); err != nil {
+		return fmt.Errorf("не удалось открыть буфер обмена для восстановления: %w", err)
+	}
+	defer closeClipboard()
+	if err := emptyClipboard(); err != nil {
+		return fmt.Errorf("не удалось очистить буфер обмена: %w", err)
+	}
+
+	restored := 0
+	for _, f := range snapshot.Formats {
+		if err := writeSnapshotFormat(f); err != nil {
+			logger.Warn("Снимок буфера: формат %s не восстановлен: %v", clipboardFormatName(f.Format), err)
+			continue
+		}
+		restored++
+	}
+	lastWriteSeq.Store(GetClipboardSequenceNumber())
+	if restored == 0 {
+		return fmt.Errorf("не восстановлен ни один из %d форматов", len(snapshot.Formats))
+	}
+	return nil
+}
+
+// writeSnapshotFormat записывает один формат в открытый и очищенный буфер.
+func writeSnapshotFormat(f SnapshotFormat) error {
+	if len(f.Data) == 0 {
+		return nil
+	}
+	if f.Format == CF_ENHMETAFILE {
+		return setClipboardEMF(f.Data)
+	}
+	handle, _, err := procGlobalAlloc.Call(GMEM_MOVEABLE|GMEM_DDESHARE, uintptr(len(f.Data)))
+	if handle == 0 {
+		return fmt.Errorf("GlobalAlloc: %v", err)
+	}
+	ptr, _, err := procGlobalLock.Call(handle)
+	if ptr == 0 {
+		procGlobalFree.Call(handle)
+		return fmt.Errorf("GlobalLock: %v", err)
+	}
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(ptr)), len(f.Data)), f.Data)
+	procGlobalUnlock.Call(handle)
+	return setClipboardData(f.Format, handle)
+}
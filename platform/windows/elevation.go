@@ -0,0 +1,112 @@
+package windows
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"golang.org/x/sys/windows"
+)
+
+// ElevatedRestartArg передаётся процессу, перезапущенному с правами администратора:
+// он ждёт, пока прежний экземпляр освободит мьютекс единственного экземпляра.
+const ElevatedRestartArg = "--elevated-restart"
+
+// integrityLevel возвращает уровень целостности (RID метки обязательного контроля) токена.
+func integrityLevel(token windows.Token) (uint32, error) {
+	n := uint32(64)
+	for {
+		buf := make([]byte, n)
+		err := windows.GetTokenInformation(token, windows.TokenIntegrityLevel, &buf[0], n, &n)
+		if err == windows.ERROR_INSUFFICIENT_BUFFER {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		label := (*windows.Tokenmandatorylabel)(unsafe.Pointer(&buf[0]))
+		sid := label.Label.Sid
+		count := sid.SubAuthorityCount()
+		if count == 0 {
+			return 0, fmt.Errorf("пустая метка целостности")
+		}
+		return sid.SubAuthority(uint32(count - 1)), nil
+	}
+}
+
+// processIntegrityLevel возвращает уровень целостности процесса по его PID.
+func processIntegrityLevel(pid uint32) (uint32, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return 0, fmt.Errorf("OpenProcess %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(h)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(h, windows.TOKEN_QUERY, &token); err != nil {
+		return 0, fmt.Errorf("OpenProcessToken %d: %w", pid, err)
+	}
+	defer token.Close()
+	return integrityLevel(token)
+}
+
+// IsCurrentProcessElevated сообщает, запущен ли ClipQueue с правами администратора.
+func IsCurrentProcessElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// IsInputBlocked сообщает, отбросит ли Windows (UIPI) ввод SendInput в окно target:
+// так происходит, когда процесс окна работает с более высоким уровнем целостности,
+// например запущен от имени администратора, а ClipQueue — нет. Ошибка возвращается,
+// если уровень целостности определить не удалось; тогда вставка выполняется как обычно.
+func IsInputBlocked(target WindowInfo) (bool, error) {
+	if target.ProcessID == 0 {
+		return false, nil
+	}
+	own, err := integrityLevel(windows.GetCurrentProcessToken())
+	if err != nil {
+		return false, fmt.Errorf("уровень целостности ClipQueue: %w", err)
+	}
+	other, err := processIntegrityLevel(target.ProcessID)
+	if err != nil {
+		return false, err
+	}
+	return other > own, nil
+}
+
+// RelaunchElevated запускает новый экземпляр ClipQueue с правами администратора через
+// запрос UAC. Вызывающий должен завершить текущий процесс после успешного вызова.
+func RelaunchElevated() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("не удалось определить путь к исполняемому файлу: %w", err)
+	}
+	// Прежний экземпляр уже работал в трее, поэтому новый стартует без окна UI.
+	args := []string{ElevatedRestartArg, SilentArg}
+	for _, a := range os.Args[1:] {
+		if a != ElevatedRestartArg && a != SilentArg && a != config.PortableArg {
+			args = append(args, a)
+		}
+	}
+	args = append(args, config.LaunchArgs()...)
+	for i, a := range args {
+		args[i] = syscall.EscapeArg(a)
+	}
+
+	verb, _ := syscall.UTF16PtrFromString("runas")
+	file, err := syscall.UTF16PtrFromString(exePath)
+	if err != nil {
+		return err
+	}
+	params, err := syscall.UTF16PtrFromString(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if err := windows.ShellExecute(0, verb, file, params, nil, windows.SW_SHOWNORMAL); err != nil {
+		return fmt.Errorf("не удалось перезапустить ClipQueue от имени администратора: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package windows
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const (
+	WM_TIMER = 0x0113
+
+	fullscreenTimerID = 1
+	fullscreenPollMs  = 1000
+
+	monitorDefaultToNearest = 2
+
+	// Состояния SHQueryUserNotificationState, при которых уведомления подавляются.
+	qunsRunningD3DFullScreen = 3
+	qunsPresentationMode     = 4
+)
+
+var (
+	procSetTimer                     = user32.NewProc("SetTimer")
+	procKillTimer                    = user32.NewProc("KillTimer")
+	procGetShellWindow               = user32.NewProc("GetShellWindow")
+	procMonitorFromWindow            = user32.NewProc("MonitorFromWindow")
+	procGetMonitorInfoW              = user32.NewProc("GetMonitorInfoW")
+	procSHQueryUserNotificationState = shell32.NewProc("SHQueryUserNotificationState")
+)
+
+// monitorInfo повторяет структуру MONITORINFO из WinAPI.
+type monitorInfo struct {
+	Size    uint32
+	Monitor RECT
+	Work    RECT
+	Flags   uint32
+}
+
+// FullscreenApp возвращает процесс полноэкранного окна, из-за которого ClipQueue
+// сейчас приостановлен, или пустую строку.
+func (h *Host) FullscreenApp() string {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	return h.fullscreenApp
+}
+
+// configureFullscreenWatch включает или выключает опрос активного окна по
+// fullscreen.suspend. Событий о переходе окна в полноэкранный режим Windows не
+// присылает, поэтому окно проверяется по таймеру раз в секунду.
+func (h *Host) configureFullscreenWatch() {
+	if h.cfg.Get().Fullscreen.Suspend {
+		procSetTimer.Call(h.hwnd, fullscreenTimerID, fullscreenPollMs, 0)
+		h.checkFullscreen()
+		return
+	}
+	procKillTimer.Call(h.hwnd, fullscreenTimerID)
+	h.setFullscreenApp("")
+}
+
+// checkFullscreen приостанавливает ClipQueue, если активное окно полноэкранное
+// и его процесс подходит под fullscreen.allow и fullscreen.deny.
+func (h *Host) checkFullscreen() {
+	app := ""
+	if hwnd, _, _ := procGetForegroundWindow.Call(); isFullscreenWindow(hwnd) {
+		info := GetWindowInfo(hwnd)
+		if info.ProcessID != uint32(os.Getpid()) && h.cfg.Get().FullscreenSuspends(info.ProcessName) {
+			app = info.ProcessName
+		}
+	}
+	h.setFullscreenApp(app)
+}
+
+func (h *Host) setFullscreenApp(app string) {
+	h.pauseMu.Lock()
+	prev := h.fullscreenApp
+	h.fullscreenApp = app
+	h.pauseMu.Unlock()
+	if prev == app {
+		return
+	}
+	if app != "" {
+		logger.Info("Активно полноэкранное окно %s, хоткеи и захват буфера обмена приостанавливаются", app)
+	} else {
+		logger.Info("Полноэкранное окно %s больше не активно", prev)
+	}
+	h.applySuspend()
+}
+
+// isFullscreenWindow сообщает, что окно занимает весь свой монитор или работает
+// в монопольном режиме Direct3D либо в режиме презентации. Рабочий стол не считается.
+func isFullscreenWindow(hwnd uintptr) bool {
+	if hwnd == 0 {
+		return false
+	}
+	if shell, _, _ := procGetShellWindow.Call(); hwnd == shell {
+		return false
+	}
+	switch windowClassName(hwnd) {
+	case "Progman", "WorkerW":
+		return false
+	}
+
+	var state uint32
+	if hr, _, _ := procSHQueryUserNotificationState.Call(uintptr(unsafe.Pointer(&state))); hr == 0 &&
+		(state == qunsRunningD3DFullScreen || state == qunsPresentationMode) {
+		return true
+	}
+
+	var r RECT
+	if ok, _, _ := procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&r))); ok == 0 {
+		return false
+	}
+	monitor, _, _ := procMonitorFromWindow.Call(hwnd, monitorDefaultToNearest)
+	mi := monitorInfo{Size: uint32(unsafe.Sizeof(monitorInfo{}))}
+	if ok, _, _ := procGetMonitorInfoW.Call(monitor, uintptr(unsafe.Pointer(&mi))); ok == 0 {
+		return false
+	}
+	return r.Left <= mi.Monitor.Left && r.Top <= mi.Monitor.Top &&
+		r.Right >= mi.Monitor.Right && r.Bottom >= mi.Monitor.Bottom
+}
@@ -1,9 +1,12 @@
 package windows
 
 import (
+	"runtime/debug"
 	"sync/atomic"
 	"syscall"
 	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/crash"
 )
 
 var (
@@ -63,7 +66,8 @@ var (
 
 // SetHook sets the low-level keyboard hook with a callback for captured hotkeys
 func SetHook(onCapture func(string)) (uintptr, error) {
-	hookCallback = func(nCode int, wParam uintptr, lParam uintptr) uintptr {
+	hookCallback = func(nCode int, wParam uintptr, lParam uintptr) (ret uintptr) {
+		defer recoverHook("hook.capture", nCode, wParam, lParam, &ret)
 		if nCode >= 0 && (wParam == WM_KEYDOWN || wParam == WM_SYSKEYDOWN) {
 			kbdStruct := (*KBDLLHOOKSTRUCT)(unsafe.Pointer(lParam))
 
@@ -126,6 +130,15 @@ func Unhook(handle uintptr) error {
 }
 
 // CallNextHook calls the next hook in the chain
+// recoverHook перехватывает панику в low-level хуке: без этого она завершила бы
+// процесс прямо из системного обратного вызова. Событие передаётся дальше по цепочке.
+func recoverHook(where string, nCode int, wParam, lParam uintptr, ret *uintptr) {
+	if r := recover(); r != nil {
+		crash.Report(where, r, debug.Stack())
+		*ret = CallNextHook(nCode, wParam, lParam)
+	}
+}
+
 func CallNextHook(nCode int, wParam, lParam uintptr) uintptr {
 	ret, _, _ := procCallNextHookEx.Call(0, uintptr(nCode), wParam, lParam)
 	return ret
@@ -4,12 +4,15 @@ import (
 	"encoding/binary"
 	"fmt"
 	"runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
 
 	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/crash"
 	"github.com/serty2005/clipqueue/internal/logger"
 )
 
@@ -72,9 +75,27 @@ type Host struct {
 	onToggleQueue      func()
 	onToggleQueueOrder func()
 	onPasteNext        func()
+	onCycleHotkey      func()
+	onPauseHotkey      func()
+	onPauseChange      func(paused bool)
 	onClipboardUpdate  func()
-	onTrayCommand      func(id uint32) // Callback for system tray menu commands
+	onTrayCommand      func(id uint32)   // Callback for system tray menu commands
+	onTrayHistory      func(id string)   // Callback for history items picked from the tray submenu
+	onTrayProfile      func(name string) // Callback for hotkey profiles picked from the tray submenu
+	onEndSession       func()            // Callback for flushing state before Windows logs off or shuts down
+	trayHistory        func() []TrayHistoryItem
+	trayOrder          func() string
 	inputListener      *InputListener
+	hooksPaused        bool        // Хуки сняты на время блокировки сеанса
+	paused             bool        // Пауза применена в потоке окна и передана onPauseChange
+	suspended          bool        // Хуки и слушатель буфера обмена сняты паузой или полноэкранным окном
+	fullscreenApp      string      // Процесс полноэкранного окна, из-за которого ClipQueue приостановлен
+	pauseMu            sync.Mutex  // Защищает pauseWanted, pauseUntil, resumeTimer и fullscreenApp
+	pauseWanted        bool        // Запрошенное состояние паузы; применяется по WM_APPLY_PAUSE
+	pauseUntil         time.Time   // Когда пауза снимется сама; нулевое — только вручную
+	resumeTimer        *time.Timer // Таймер автоматического снятия паузы
+	osHotkeys          *Hotkeys    // Хоткеи RegisterHotKey, работающие без хуков (пауза)
+	sessionNotify      bool        // Окно подписано на WM_WTSSESSION_CHANGE
 	clipboardWatcher   *ClipboardWatcher
 	tray               *Tray         // System tray icon
 	done               chan struct{} // Channel to signal that host has stopped
@@ -90,8 +111,14 @@ func NewHost(cfg *config.SafeConfig, controller MacroExecutor) (*Host, error) {
 		onToggleQueue:      func() {},
 		onToggleQueueOrder: func() {},
 		onPasteNext:        func() {},
+		onCycleHotkey:      func() {},
+		onPauseHotkey:      func() {},
+		onPauseChange:      func(paused bool) {},
 		onClipboardUpdate:  func() {},
 		onTrayCommand:      func(id uint32) {}, // Empty default callback
+		onTrayHistory:      func(id string) {},
+		onTrayProfile:      func(name string) {},
+		onEndSession:       func() {},
 		done:               make(chan struct{}),
 		captureChan:        make(chan string, 1), // Buffered to avoid blocking
 	}
@@ -128,6 +155,11 @@ func (h *Host) OnHotkeyPasteNext(callback func()) {
 	h.onPasteNext = callback
 }
 
+// OnHotkeyCycleProfile задаёт обработчик хоткея hotkey_profiles.cycle.
+func (h *Host) OnHotkeyCycleProfile(callback func()) {
+	h.onCycleHotkey = callback
+}
+
 func (h *Host) OnClipboardUpdate(callback func()) {
 	h.onClipboardUpdate = callback
 }
@@ -137,14 +169,54 @@ func (h *Host) OnTrayCommand(callback func(id uint32)) {
 	h.onTrayCommand = callback
 }
 
+// OnTrayHistorySelect задаёт обработчик выбора элемента из подменю недавней истории.
+func (h *Host) OnTrayHistorySelect(callback func(id string)) {
+	h.onTrayHistory = callback
+}
+
+// OnTrayHotkeyProfileSelect задаёт обработчик выбора набора хоткеев в меню трея;
+// пустое имя — основной набор.
+func (h *Host) OnTrayHotkeyProfileSelect(callback func(name string)) {
+	h.onTrayProfile = callback
+}
+
+// OnEndSession задаёт обработчик завершения сеанса Windows. Он вызывается синхронно
+// из WM_ENDSESSION: после возврата из обработчика система может завершить процесс.
+func (h *Host) OnEndSession(callback func()) {
+	h.onEndSession = callback
+}
+
+// SetTrayHistoryProvider задаёт источник элементов подменю недавней истории.
+// Должен вызываться до Start.
+func (h *Host) SetTrayHistoryProvider(provider func() []TrayHistoryItem) {
+	h.trayHistory = provider
+}
+
+// SetTrayOrderProvider задаёт источник порядка очереди для пункта меню трея,
+// который его переключает. Должен вызываться до Start.
+func (h *Host) SetTrayOrderProvider(provider func() string) {
+	h.trayOrder = provider
+}
+
 // registerConfiguredHotkeys регистрирует хоткеи из конфига
 func (h *Host) registerConfiguredHotkeys() {
 	cfg := h.cfg.Get()
 	matcher := h.inputListener.GetMatcher()
 
+	// Активный набор подменяет системные хоткеи. Пустое поле набора значит «не
+	// регистрировать», поэтому запасные Alt+C и Alt+V действуют только в основном наборе.
+	hotkeys := cfg.Hotkeys
+	fallback := true
+	if p := cfg.ActiveHotkeyProfile(); p != nil {
+		hotkeys.ToggleQueue, hotkeys.PasteNext = p.ToggleQueue, p.PasteNext
+		hotkeys.ToggleQueueOrder, hotkeys.ToggleUI = p.ToggleQueueOrder, p.ToggleUI
+		fallback = false
+		logger.Info("Активный набор хоткеев: %s", p.Name)
+	}
+
 	// ToggleUI
-	if cfg.Hotkeys.ToggleUI != "" {
-		hotkeyStr := cfg.Hotkeys.ToggleUI
+	if hotkeys.ToggleUI != "" {
+		hotkeyStr := hotkeys.ToggleUI
 		sig := h.parseHotkeyToSignature(hotkeyStr)
 		if sig != nil {
 			matcher.Register(*sig, "toggle_ui", func() {
@@ -152,15 +224,15 @@ func (h *Host) registerConfiguredHotkeys() {
 			})
 			logger.Info("Успешная регистрация хоткея ToggleUI: %s", hotkeyStr)
 		} else {
-			logger.Error("Не удалось зарегистрировать хоткей ToggleUI: %s", cfg.Hotkeys.ToggleUI)
+			logger.Error("Не удалось зарегистрировать хоткей ToggleUI: %s", hotkeys.ToggleUI)
 		}
 	}
 
 	// ToggleQueue
-	if cfg.Features.EnableQueue {
-		hotkeyStr := cfg.Hotkeys.ToggleQueue
+	if cfg.Features.EnableQueue && (fallback || hotkeys.ToggleQueue != "") {
+		hotkeyStr := hotkeys.ToggleQueue
 		sig := h.parseHotkeyToSignature(hotkeyStr)
-		if sig == nil {
+		if sig == nil && fallback {
 			hotkeyStr = "Alt+C"
 			sig = h.parseHotkeyToSignature(hotkeyStr)
 		}
@@ -170,15 +242,15 @@ func (h *Host) registerConfiguredHotkeys() {
 			})
 			logger.Info("Успешная регистрация хоткея ToggleQueue: %s", hotkeyStr)
 		} else {
-			logger.Error("Не удалось зарегистрировать хоткей ToggleQueue: %s", cfg.Hotkeys.ToggleQueue)
+			logger.Error("Не удалось зарегистрировать хоткей ToggleQueue: %s", hotkeys.ToggleQueue)
 		}
 	}
 
 	// PasteNext
-	if cfg.Features.EnableQueue {
-		hotkeyStr := cfg.Hotkeys.PasteNext
+	if cfg.Features.EnableQueue && (fallback || hotkeys.PasteNext != "") {
+		hotkeyStr := hotkeys.PasteNext
 		sig := h.parseHotkeyToSignature(hotkeyStr)
-		if sig == nil {
+		if sig == nil && fallback {
 			hotkeyStr = "Alt+V"
 			sig = h.parseHotkeyToSignature(hotkeyStr)
 		}
@@ -188,13 +260,13 @@ func (h *Host) registerConfiguredHotkeys() {
 			})
 			logger.Info("Успешная регистрация хоткея PasteNext: %s", hotkeyStr)
 		} else {
-			logger.Error("Не удалось зарегистрировать хоткей PasteNext: %s", cfg.Hotkeys.PasteNext)
+			logger.Error("Не удалось зарегистрировать хоткей PasteNext: %s", hotkeys.PasteNext)
 		}
 	}
 
 	// ToggleQueueOrder
-	if cfg.Features.EnableQueue && cfg.Hotkeys.ToggleQueueOrder != "" {
-		hotkeyStr := cfg.Hotkeys.ToggleQueueOrder
+	if cfg.Features.EnableQueue && hotkeys.ToggleQueueOrder != "" {
+		hotkeyStr := hotkeys.ToggleQueueOrder
 		sig := h.parseHotkeyToSignature(hotkeyStr)
 		if sig != nil {
 			matcher.Register(*sig, "toggle_queue_order", func() {
@@ -202,34 +274,76 @@ func (h *Host) registerConfiguredHotkeys() {
 			})
 			logger.Info("Успешная регистрация хоткея ToggleQueueOrder: %s", hotkeyStr)
 		} else {
-			logger.Error("Не удалось зарегистрировать хоткей ToggleQueueOrder: %s", cfg.Hotkeys.ToggleQueueOrder)
+			logger.Error("Не удалось зарегистрировать хоткей ToggleQueueOrder: %s", hotkeys.ToggleQueueOrder)
+		}
+	}
+
+	// Смена набора хоткеев действует в любом наборе, иначе из него не выйти.
+	if cycle := cfg.HotkeyProfiles.Cycle; cycle != "" && len(cfg.HotkeyProfiles.Profiles) > 0 {
+		if sig := h.parseHotkeyToSignature(cycle); sig != nil {
+			matcher.Register(*sig, "cycle_hotkey_profile", func() {
+				h.onCycleHotkey()
+			})
+			logger.Info("Успешная регистрация хоткея смены набора: %s", cycle)
+		} else {
+			logger.Error("Не удалось зарегистрировать хоткей смены набора: %s", cycle)
 		}
 	}
 
 	// Макросы
-	if cfg.Features.EnableMacros {
+	if macrosEnabled(cfg) {
 		for _, macro := range cfg.Macros {
-			if !macro.Enabled {
-				logger.Info("Макрос отключён, регистрация пропущена: %s", macro.Name)
-				continue
-			}
-			m := macro
-			hotkeyStr := macro.Signature
-			sig := h.parseHotkeyToSignature(hotkeyStr)
-			if macro.Signature == "" || sig == nil {
-				hotkeyStr = macro.Hotkey
-				sig = h.parseHotkeyToSignature(hotkeyStr)
-			}
-			if sig != nil {
-				matcher.Register(*sig, "macro:"+hotkeyStr, h.buildMacroCallback(m))
-				logger.Info("Успешная регистрация макроса %s: %s", macro.Name, hotkeyStr)
-			} else {
-				logger.Error("Не удалось зарегистрировать макрос %s: Signature='%s', Hotkey='%s'", macro.Name, macro.Signature, macro.Hotkey)
-			}
+			h.registerMacroHotkey(macro)
 		}
 	}
 }
 
+// macroHotkey возвращает сигнатуру макроса и ID, под которым она регистрируется:
+// сначала Signature, а если её нет или она не разбирается — Hotkey.
+func (h *Host) macroHotkey(macro config.Macro) (string, *InputSignature) {
+	hotkeyStr := macro.Signature
+	sig := h.parseHotkeyToSignature(hotkeyStr)
+	if macro.Signature == "" || sig == nil {
+		hotkeyStr = macro.Hotkey
+		sig = h.parseHotkeyToSignature(hotkeyStr)
+	}
+	return "macro:" + hotkeyStr, sig
+}
+
+func (h *Host) registerMacroHotkey(macro config.Macro) {
+	if !macro.Enabled {
+		logger.Info("Макрос отключён, регистрация пропущена: %s", macro.Name)
+		return
+	}
+	id, sig := h.macroHotkey(macro)
+	if sig == nil {
+		logger.Error("Не удалось зарегистрировать макрос %s: Signature='%s', Hotkey='%s'", macro.Name, macro.Signature, macro.Hotkey)
+		return
+	}
+	h.inputListener.GetMatcher().Register(*sig, id, h.buildMacroCallback(macro))
+	logger.Info("Успешная регистрация макроса %s: %s", macro.Name, strings.TrimPrefix(id, "macro:"))
+}
+
+// ReplaceMacroHotkey снимает хоткей макроса prev и регистрирует хоткей next, не
+// перерегистрируя остальные хоткеи. nil вместо prev — макрос добавлен, вместо
+// next — удалён.
+func (h *Host) ReplaceMacroHotkey(prev, next *config.Macro) {
+	if prev != nil {
+		id, _ := h.macroHotkey(*prev)
+		h.inputListener.GetMatcher().Unregister(id)
+	}
+	if next != nil && macrosEnabled(h.cfg.Get()) {
+		h.registerMacroHotkey(*next)
+	}
+}
+
+// macrosEnabled сообщает, регистрируются ли хоткеи макросов: функция включена
+// и активный набор хоткеев их не снимает.
+func macrosEnabled(cfg *config.Config) bool {
+	p := cfg.ActiveHotkeyProfile()
+	return cfg.Features.EnableMacros && (p == nil || !p.DisableMacros)
+}
+
 func (h *Host) buildMacroCallback(macro config.Macro) func() {
 	return func() {
 		if err := h.controller.ExecuteMacro(macro); err != nil {
@@ -310,6 +424,25 @@ func (h *Host) UpdateTrayTooltip(text string) error {
 	return nil
 }
 
+// UpdateTrayState redraws the tray icon to reflect queue state and item count
+func (h *Host) UpdateTrayState(enabled bool, count int) error {
+	if h.tray != nil {
+		return h.tray.SetState(enabled, count)
+	}
+	return nil
+}
+
+// ShowTrayNotification shows a balloon notification from the system tray icon
+func (h *Host) ShowTrayNotification(title, text string, failure bool) error {
+	if h.tray == nil {
+		return nil
+	}
+	if failure {
+		return h.tray.NotifyWithIcon(title, text, NIIF_WARNING)
+	}
+	return h.tray.Notify(title, text)
+}
+
 // RegisterMacro registers a macro hotkey that sends text when pressed
 func (h *Host) RegisterMacro(hotkey string, macro config.Macro) error {
 	if sig := h.parseHotkeyToSignature(hotkey); sig != nil {
@@ -336,6 +469,16 @@ func (h *Host) Start() error {
 		}
 		h.className = className
 
+		// Иконки класса окна; при ошибке окно остаётся без иконки, как и раньше
+		classIcon, err := LoadAppIcon(systemMetric(SM_CXICON, 32))
+		if err != nil {
+			logger.Warn("Не удалось загрузить иконку приложения: %v", err)
+		}
+		classIconSm, err := LoadAppIcon(trayIconSize())
+		if err != nil {
+			logger.Warn("Не удалось загрузить маленькую иконку приложения: %v", err)
+		}
+
 		wc := WNDCLASSEX{
 			Size:       uint32(unsafe.Sizeof(WNDCLASSEX{})),
 			Style:      0,
@@ -343,12 +486,12 @@ func (h *Host) Start() error {
 			ClsExtra:   0,
 			WndExtra:   0,
 			Instance:   0,
-			Icon:       0,
+			Icon:       classIcon,
 			Cursor:     0,
 			Background: 0,
 			MenuName:   nil,
 			ClassName:  h.className,
-			IconSm:     0,
+			IconSm:     classIconSm,
 		}
 
 		atom, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wc)))
@@ -382,10 +525,14 @@ func (h *Host) Start() error {
 			return
 		}
 
+		h.registerSessionNotifications()
+
 		cfg := h.cfg.Get()
 
 		// Register configured hotkeys
 		h.registerConfiguredHotkeys()
+		h.registerPauseHotkey()
+		h.configureFullscreenWatch()
 
 		// Add clipboard format listener
 		if cfg.Features.EnableClipboard {
@@ -398,6 +545,22 @@ func (h *Host) Start() error {
 		// Initialize system tray if not in silent mode
 		if !h.cfg.Get().App.Silent {
 			h.tray = NewTray(h.hwnd)
+			h.tray.SetHistoryProvider(h.trayHistory)
+			if h.trayOrder != nil {
+				h.tray.SetOrderState(h.trayOrder)
+			}
+			h.tray.SetAutostartState(func() bool { return h.cfg.Get().App.Autostart })
+			h.tray.SetPausedState(func() bool {
+				paused, _ := h.PauseState()
+				return paused
+			})
+			h.tray.SetHotkeyProfileProvider(func() ([]string, string) {
+				cfg := h.cfg.Get()
+				if p := cfg.ActiveHotkeyProfile(); p != nil {
+					return cfg.HotkeyProfileNames(), p.Name
+				}
+				return cfg.HotkeyProfileNames(), ""
+			})
 			if err := h.tray.Setup(""); err != nil {
 				logger.Error("Failed to initialize system tray: %v", err)
 			}
@@ -410,7 +573,14 @@ func (h *Host) Start() error {
 		h.messageLoop()
 
 		// Cleanup after message loop exits
+		h.pauseMu.Lock()
+		if h.resumeTimer != nil {
+			h.resumeTimer.Stop()
+		}
+		h.pauseMu.Unlock()
+		h.osHotkeys.Unregister()
 		h.clipboardWatcher.Stop()
+		h.unregisterSessionNotifications()
 		h.inputListener.Stop()
 		if h.tray != nil {
 			h.tray.Remove()
@@ -497,12 +667,22 @@ func (h *Host) messageLoop() {
 	close(h.done) // Signal that host has stopped
 }
 
-func (h *Host) windowProc(hwnd uintptr, msg uint32, wParam, lParam uintptr) uintptr {
+func (h *Host) windowProc(hwnd uintptr, msg uint32, wParam, lParam uintptr) (result uintptr) {
+	// Паника в обработчике сообщения не должна завершать процесс из оконной процедуры
+	defer func() {
+		if r := recover(); r != nil {
+			crash.Report(fmt.Sprintf("windowProc msg=0x%X", msg), r, debug.Stack())
+			result = 0
+		}
+	}()
+
 	const (
-		WM_CLOSE     = 0x0010
-		WM_DESTROY   = 0x0002
-		WM_RBUTTONUP = 0x0205
-		WM_LBUTTONUP = 0x0202
+		WM_CLOSE           = 0x0010
+		WM_DESTROY         = 0x0002
+		WM_QUERYENDSESSION = 0x0011
+		WM_ENDSESSION      = 0x0016
+		WM_RBUTTONUP       = 0x0205
+		WM_LBUTTONUP       = 0x0202
 	)
 
 	switch msg {
@@ -512,7 +692,11 @@ func (h *Host) windowProc(hwnd uintptr, msg uint32, wParam, lParam uintptr) uint
 			if h.tray != nil {
 				selectedID := h.tray.ShowMenu()
 				logger.Info("Menu item selected: %d", selectedID)
-				if selectedID > 0 {
+				if name, ok := h.tray.HotkeyProfileName(selectedID); ok {
+					h.onTrayProfile(name)
+				} else if itemID, ok := h.tray.HistoryItemID(selectedID); ok {
+					h.onTrayHistory(itemID)
+				} else if selectedID > 0 {
 					h.onTrayCommand(selectedID)
 				}
 			}
@@ -524,17 +708,75 @@ func (h *Host) windowProc(hwnd uintptr, msg uint32, wParam, lParam uintptr) uint
 		h.onClipboardUpdate()
 		return 0
 
+	case WM_RENDERFORMAT:
+		renderDelayedFormat(uint32(wParam))
+		return 0
+
+	case WM_RENDERALLFORMATS:
+		renderAllDelayedFormats(hwnd)
+		return 0
+
+	case WM_DESTROYCLIPBOARD:
+		clearDelayedClipboard()
+		return 0
+
 	case WM_RELOAD_CONFIG:
 		logger.Info("WM_RELOAD_CONFIG received, reloading hotkeys...")
 		// Unregister all existing signatures
 		h.inputListener.GetMatcher().UnregisterAll()
 		// Re-register configured hotkeys
 		h.registerConfiguredHotkeys()
+		h.registerPauseHotkey()
+		h.configureFullscreenWatch()
 		logger.Info("Hotkeys reloaded successfully")
 		return 0
 
+	case WM_TIMER:
+		if wParam == fullscreenTimerID {
+			h.checkFullscreen()
+		}
+		return 0
+
+	case WM_HOTKEY:
+		if h.osHotkeys != nil {
+			if callback, ok := h.osHotkeys.GetCallback(uint32(wParam)); ok {
+				callback()
+			}
+		}
+		return 0
+
+	case WM_APPLY_PAUSE:
+		h.applySuspend()
+		return 0
+
+	case WM_WTSSESSION_CHANGE:
+		h.handleSessionChange(wParam)
+		return 0
+
+	case WM_SETTINGCHANGE:
+		if h.tray != nil && isThemeChange(lParam) {
+			if err := h.tray.RefreshTheme(); err != nil {
+				logger.Warn("Не удалось обновить иконку трея после смены темы: %v", err)
+			}
+		}
+		return 0
+
+	case WM_QUERYENDSESSION:
+		// Не блокируем выход из системы; состояние сохраняется в WM_ENDSESSION.
+		logger.Info("WM_QUERYENDSESSION received")
+		return 1
+
+	case WM_ENDSESSION:
+		if wParam != 0 {
+			logger.Info("WM_ENDSESSION received, сохраняем состояние")
+			h.onEndSession()
+		}
+		return 0
+
 	case WM_CLOSE:
 		logger.Info("WM_CLOSE received, posting WM_QUIT")
+		// Окно не уничтожается, поэтому WM_RENDERALLFORMATS не придёт: отрисовываем сами.
+		renderAllDelayedFormats(hwnd)
 		procPostQuitMessage := user32.NewProc("PostQuitMessage")
 		procPostQuitMessage.Call(0)
 		return 0
@@ -1,6 +1,7 @@
 package windows
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/serty2005/clipqueue/internal/config"
@@ -108,15 +109,13 @@ func (h *Hotkeys) parseHotkey(hotkeyString string) (uint32, uint32, error) {
 				vk = code
 				foundKey = true
 			} else {
-				logger.Error("Unknown key: %s", part)
-				return 0, 0, nil
+				return 0, 0, fmt.Errorf("unknown key: %s", part)
 			}
 		}
 	}
 
 	if !foundKey {
-		logger.Error("No valid key found in hotkey: %s", hotkeyString)
-		return 0, 0, nil
+		return 0, 0, fmt.Errorf("no valid key found in hotkey: %s", hotkeyString)
 	}
 
 	return modifiers, vk, nil
@@ -26,7 +26,10 @@ const (
 	KEYEVENTF_SCANCODE    = 0x0008
 
 	// MapVirtualKey constants
-	MAPVK_VK_TO_VSC = 0
+	MAPVK_VK_TO_VSC    = 0
+	MAPVK_VK_TO_VSC_EX = 4 // Для расширенных клавиш возвращает скан-код с префиксом 0xE0
+
+	VK_RETURN = 0x0D
 )
 
 // GetAsyncKeyState checks if a key is currently pressed
@@ -62,6 +65,7 @@ var (
 	procGetKeyboardLayout        = user32.NewProc("GetKeyboardLayout")
 	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procGetGUIThreadInfo         = user32.NewProc("GetGUIThreadInfo")
 )
 
 func describeVkKeyScanModifiers(mods byte) string {
@@ -84,18 +88,60 @@ func describeVkKeyScanModifiers(mods byte) string {
 	return names[:len(names)-1]
 }
 
+// getForegroundKeyboardContext возвращает активное окно, поток, который получает
+// ввод с клавиатуры, и его раскладку. Язык ввода выбирается для каждого потока,
+// поэтому раскладка ClipQueue тут не подходит. Поток берётся у окна с фокусом:
+// у UWP-приложений и встроенных элементов оно живёт не в потоке рамки окна.
+// Если раскладку узнать не удалось, возвращается раскладка текущего потока.
 func getForegroundKeyboardContext() (hwnd uintptr, threadID uint32, hkl uintptr) {
 	hwnd, _, _ = procGetForegroundWindow.Call()
-	if hwnd == 0 {
-		return 0, 0, 0
+	if hwnd != 0 {
+		tid, _, _ := procGetWindowThreadProcessId.Call(hwnd, 0)
+		threadID = uint32(tid)
+		if focusThread := focusedWindowThread(threadID); focusThread != 0 {
+			threadID = focusThread
+		}
+		hkl, _, _ = procGetKeyboardLayout.Call(uintptr(threadID))
+	}
+	if hkl == 0 {
+		hkl, _, _ = procGetKeyboardLayout.Call(0)
 	}
+	return
+}
 
-	tid, _, _ := procGetWindowThreadProcessId.Call(hwnd, 0)
-	threadID = uint32(tid)
+// guiThreadInfo — GUITHREADINFO.
+type guiThreadInfo struct {
+	cbSize        uint32
+	flags         uint32
+	hwndActive    uintptr
+	hwndFocus     uintptr
+	hwndCapture   uintptr
+	hwndMenuOwner uintptr
+	hwndMoveSize  uintptr
+	hwndCaret     uintptr
+	rcCaret       RECT
+}
 
-	layout, _, _ := procGetKeyboardLayout.Call(uintptr(threadID))
-	hkl = layout
-	return
+// focusedWindow возвращает окно, которое держит фокус ввода в потоке threadID;
+// 0 — фокуса нет или его не удалось узнать.
+func focusedWindow(threadID uint32) uintptr {
+	info := guiThreadInfo{}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	if ok, _, _ := procGetGUIThreadInfo.Call(uintptr(threadID), uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0
+	}
+	return info.hwndFocus
+}
+
+// focusedWindowThread возвращает поток окна, которое держит фокус ввода в потоке
+// threadID; 0 — фокуса нет или его не удалось узнать.
+func focusedWindowThread(threadID uint32) uint32 {
+	focus := focusedWindow(threadID)
+	if focus == 0 {
+		return 0
+	}
+	tid, _, _ := procGetWindowThreadProcessId.Call(focus, 0)
+	return uint32(tid)
 }
 
 func appendUnicodeRuneInputs(inputs *[]INPUT, r rune) {
@@ -132,6 +178,34 @@ func appendVirtualKeyInput(inputs *[]INPUT, vk uint16, keyUp bool) {
 	})
 }
 
+// appendScanCodeInput добавляет нажатие или отпускание клавиши vk скан-кодом
+// раскладки hkl: у одной и той же виртуальной клавиши на AZERTY и QWERTY разные скан-коды.
+// Игры и программы, читающие сырой ввод, видят только скан-коды: виртуальный код
+// без скан-кода (как у appendVirtualKeyInput) они пропускают, и, например, Shift
+// не срабатывает. Если у клавиши нет скан-кода, отправляется виртуальный код.
+func appendScanCodeInput(inputs *[]INPUT, vk uint16, keyUp bool, hkl uintptr) {
+	sc := mapVirtualKey(vk, MAPVK_VK_TO_VSC_EX, hkl)
+	if sc == 0 {
+		appendVirtualKeyInput(inputs, vk, keyUp)
+		return
+	}
+	flags := uint32(KEYEVENTF_SCANCODE)
+	if sc>>8 == 0xE0 {
+		flags |= KEYEVENTF_EXTENDEDKEY
+	}
+	if keyUp {
+		flags |= KEYEVENTF_KEYUP
+	}
+	*inputs = append(*inputs, INPUT{
+		Type: INPUT_KEYBOARD,
+		Ki: KEYBDINPUT{
+			Wvk:     vk,
+			WScan:   uint16(sc & 0xFF),
+			DwFlags: flags,
+		},
+	})
+}
+
 // ReleaseHotkeyState releases modifier and main keys from a hotkey display string
 // (e.g. "Ctrl+Alt+1") before replaying synthetic input sequences.
 func ReleaseHotkeyState(hotkey string) error {
@@ -209,8 +283,41 @@ func sendInput(inputs []INPUT) uint32 {
 	return uint32(ret)
 }
 
+// TypingOptions задаёт темп ввода: события SendInput отправляются порциями по
+// ChunkSize с паузой ChunkDelay между ними. RDP и Citrix теряют символы, если
+// передавать текст одним вызовом.
+type TypingOptions struct {
+	ChunkSize  int
+	ChunkDelay time.Duration
+}
+
+// DefaultTypingOptions — темп по умолчанию: 50 событий (25 символов) за 20 мс.
+var DefaultTypingOptions = TypingOptions{ChunkSize: 50, ChunkDelay: 20 * time.Millisecond}
+
+// sendInputsPaced отправляет события порциями. Неположительный размер порции
+// заменяется значением по умолчанию.
+func sendInputsPaced(inputs []INPUT, opts TypingOptions) error {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultTypingOptions.ChunkSize
+	}
+	for i := 0; i < len(inputs); i += chunkSize {
+		end := min(i+chunkSize, len(inputs))
+		chunk := inputs[i:end]
+		result := sendInput(chunk)
+		if result != uint32(len(chunk)) {
+			logger.Error("SendInput failed: only %d out of %d inputs sent", result, len(chunk))
+			return syscall.GetLastError()
+		}
+
+		// Add delay to "humanize" input for RDP sessions
+		time.Sleep(opts.ChunkDelay)
+	}
+	return nil
+}
+
 // TypeString sends text to the active window using Unicode injection for all characters
-func TypeString(text string) error {
+func TypeString(text string, opts TypingOptions) error {
 	var inputs []INPUT
 
 	// Release any stuck modifier keys before sending text
@@ -242,23 +349,8 @@ func TypeString(text string) error {
 		appendUnicodeRuneInputs(&inputs, r)
 	}
 
-	// Send inputs in chunks with delays for RDP sessions
-	const chunkSize = 50
-	for i := 0; i < len(inputs); i += chunkSize {
-		end := i + chunkSize
-		if end > len(inputs) {
-			end = len(inputs)
-		}
-
-		chunk := inputs[i:end]
-		result := sendInput(chunk)
-		if result != uint32(len(chunk)) {
-			logger.Error("SendInput failed: only %d out of %d inputs sent", result, len(chunk))
-			return syscall.GetLastError()
-		}
-
-		// Add delay to "humanize" input for RDP sessions
-		time.Sleep(20 * time.Millisecond)
+	if err := sendInputsPaced(inputs, opts); err != nil {
+		return err
 	}
 
 	logger.Debug("TypeString completed successfully: %s", text)
@@ -266,12 +358,13 @@ func TypeString(text string) error {
 }
 
 // TypeStringHardware sends text to the active window using hardware key events (scan codes)
-func TypeStringHardware(text string) error {
+func TypeStringHardware(text string, opts TypingOptions) error {
 	var inputs []INPUT
 	hwnd, threadID, hkl := getForegroundKeyboardContext()
 	logger.Debug("TypeStringHardware start: textLen=%d, fgHwnd=0x%X, fgThreadID=%d, fgHKL=0x%X",
 		len([]rune(text)), hwnd, threadID, hkl)
 	mappedCount := 0
+	composedCount := 0
 	fallbackUnicodeCount := 0
 
 	// Release any stuck modifier keys before sending text
@@ -299,98 +392,50 @@ func TypeStringHardware(text string) error {
 		}
 	}
 
-	for idx, r := range text {
-		// Get virtual key code and shift state for the character
-		var vkAndShift uintptr
-		if hkl != 0 {
-			vkAndShift, _, _ = procVkKeyScanExW.Call(uintptr(r), hkl)
-		} else {
-			vkAndShift, _, _ = procVkKeyScanW.Call(uintptr(r))
+	runes := []rune(text)
+	for idx, r := range runes {
+		// Перевод строки набирается одним Enter: VkKeyScan сопоставляет '\n' с Ctrl+Enter,
+		// а пара "\r\n" дала бы два перевода.
+		if r == '\r' && idx+1 < len(runes) && runes[idx+1] == '\n' {
+			continue
 		}
-		vkScanShort := int16(uint16(vkAndShift))
-		unmappable := vkScanShort == -1
-		vkScanRaw := uint16(vkScanShort)
-		vk := uint16(vkScanRaw & 0x00FF)
-		mods := byte((vkScanRaw >> 8) & 0x00FF)
-		shift := (mods & 0x01) != 0
-
-		// Get scan code for the virtual key
-		sc, _, _ := procMapVirtualKeyW.Call(uintptr(vk), MAPVK_VK_TO_VSC)
-		scanCode := uint16(sc)
-
-		logger.Debug("TypeStringHardware map[%d]: rune=%q U+%04X vkScan=0x%04X signed=%d unmappable=%v vk=0x%02X mods=0x%02X(%s) scan=0x%02X",
-			idx, r, r, vkScanRaw, vkScanShort, unmappable, vk, mods, describeVkKeyScanModifiers(mods), scanCode)
-
-		if unmappable || vk == 0 || scanCode == 0 || (mods&^byte(0x07)) != 0 {
-			fallbackUnicodeCount++
-			logger.Debug("TypeStringHardware fallback[%d]: rune=%q reason=unmappable_or_unsupported", idx, r)
-			appendUnicodeRuneInputs(&inputs, r)
+		if r == '\n' || r == '\r' {
+			appendScanCodeInput(&inputs, VK_RETURN, false, hkl)
+			appendScanCodeInput(&inputs, VK_RETURN, true, hkl)
+			mappedCount++
 			continue
 		}
 
-		if mods&0x02 != 0 {
-			appendVirtualKeyInput(&inputs, VK_CONTROL, false)
-		}
-		if mods&0x04 != 0 {
-			appendVirtualKeyInput(&inputs, VK_MENU, false)
+		if k, ok := lookupKeyStroke(r, hkl); ok {
+			logger.Debug("TypeStringHardware map[%d]: rune=%q U+%04X vk=0x%02X mods=0x%02X(%s)",
+				idx, r, r, k.vk, k.mods, describeVkKeyScanModifiers(k.mods))
+			appendKeyStroke(&inputs, k, hkl)
+			if isDeadKey(k, hkl) {
+				// Мёртвая клавиша сама ничего не печатает: пробел после неё выводит её знак.
+				appendKeyStroke(&inputs, keyStroke{vk: vkSpace}, hkl)
+			}
+			mappedCount++
+			continue
 		}
-
-		if shift {
-			appendVirtualKeyInput(&inputs, VK_SHIFT, false)
+		if dead, base, ok := lookupDeadKeyStrokes(r, hkl); ok {
+			logger.Debug("TypeStringHardware compose[%d]: rune=%q dead vk=0x%02X mods=0x%02X, base vk=0x%02X",
+				idx, r, dead.vk, dead.mods, base.vk)
+			appendKeyStroke(&inputs, dead, hkl)
+			appendKeyStroke(&inputs, base, hkl)
+			composedCount++
+			continue
 		}
 
-		// Key down event
-		inputs = append(inputs, INPUT{
-			Type: INPUT_KEYBOARD,
-			Ki: KEYBDINPUT{
-				Wvk:     vk,
-				WScan:   scanCode,
-				DwFlags: KEYEVENTF_SCANCODE,
-			},
-		})
-
-		// Key up event
-		inputs = append(inputs, INPUT{
-			Type: INPUT_KEYBOARD,
-			Ki: KEYBDINPUT{
-				Wvk:     vk,
-				WScan:   scanCode,
-				DwFlags: KEYEVENTF_SCANCODE | KEYEVENTF_KEYUP,
-			},
-		})
-
-		if shift {
-			appendVirtualKeyInput(&inputs, VK_SHIFT, true)
-		}
-		if mods&0x04 != 0 {
-			appendVirtualKeyInput(&inputs, VK_MENU, true)
-		}
-		if mods&0x02 != 0 {
-			appendVirtualKeyInput(&inputs, VK_CONTROL, true)
-		}
-		mappedCount++
+		fallbackUnicodeCount++
+		logger.Debug("TypeStringHardware fallback[%d]: rune=%q reason=unmappable_or_unsupported", idx, r)
+		appendUnicodeRuneInputs(&inputs, r)
 	}
 
-	// Send inputs in chunks with delays for RDP sessions
-	const chunkSize = 50
-	for i := 0; i < len(inputs); i += chunkSize {
-		end := i + chunkSize
-		if end > len(inputs) {
-			end = len(inputs)
-		}
-
-		chunk := inputs[i:end]
-		result := sendInput(chunk)
-		if result != uint32(len(chunk)) {
-			logger.Error("SendInput failed: only %d out of %d inputs sent", result, len(chunk))
-			return syscall.GetLastError()
-		}
-
-		// Add delay to "humanize" input for RDP sessions
-		time.Sleep(20 * time.Millisecond)
+	if err := sendInputsPaced(inputs, opts); err != nil {
+		return err
 	}
 
-	logger.Debug("TypeStringHardware summary: mapped=%d fallbackUnicode=%d", mappedCount, fallbackUnicodeCount)
+	logger.Debug("TypeStringHardware summary: mapped=%d composed=%d fallbackUnicode=%d", mappedCount, composedCount, fallbackUnicodeCount)
 	logger.Debug("TypeStringHardware completed successfully: %s", text)
 	return nil
 }
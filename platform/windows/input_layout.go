@@ -0,0 +1,160 @@
+package windows
+
+import (
+	"unsafe"
+)
+
+// Модификаторы в старшем байте результата VkKeyScanEx.
+const (
+	vkScanShift = 0x01
+	vkScanCtrl  = 0x02
+	vkScanAlt   = 0x04
+	// vkScanAltGr — Ctrl+Alt: так VkKeyScanEx описывает символы, которые на европейских
+	// раскладках набираются правым Alt (AltGr), например «€» или «@» на немецкой.
+	vkScanAltGr = vkScanCtrl | vkScanAlt
+
+	vkSpace = 0x20
+
+	// toUnicodeNoStateChange — флаг ToUnicodeEx (Windows 10 1607+): проверка клавиши
+	// не оставляет мёртвую клавишу в состоянии клавиатуры потока.
+	toUnicodeNoStateChange = 0x4
+)
+
+var (
+	procToUnicodeEx      = user32.NewProc("ToUnicodeEx")
+	procMapVirtualKeyExW = user32.NewProc("MapVirtualKeyExW")
+)
+
+// mapVirtualKey переводит код клавиши по раскладке hkl, а не по раскладке ClipQueue.
+func mapVirtualKey(vk uint16, mapType uint32, hkl uintptr) uintptr {
+	ret, _, _ := procMapVirtualKeyExW.Call(uintptr(vk), uintptr(mapType), hkl)
+	return ret
+}
+
+// keyStroke — клавиша и модификаторы, которыми набирается символ на раскладке.
+type keyStroke struct {
+	vk   uint16
+	mods byte
+}
+
+// lookupKeyStroke находит клавишу для символа на раскладке hkl.
+func lookupKeyStroke(r rune, hkl uintptr) (keyStroke, bool) {
+	if r > 0xFFFF {
+		return keyStroke{}, false
+	}
+	res, _, _ := procVkKeyScanExW.Call(uintptr(r), hkl)
+	if int16(uint16(res)) == -1 {
+		return keyStroke{}, false
+	}
+	k := keyStroke{vk: uint16(res & 0xFF), mods: byte(res >> 8)}
+	// Старшие биты — модификаторы Hankaku и специфичные для раскладки: их не набрать.
+	if k.vk == 0 || k.mods&^byte(vkScanShift|vkScanAltGr) != 0 {
+		return keyStroke{}, false
+	}
+	if mapVirtualKey(k.vk, MAPVK_VK_TO_VSC, hkl) == 0 {
+		return keyStroke{}, false
+	}
+	return k, true
+}
+
+// appendKeyStroke добавляет нажатие клавиши с модификаторами скан-кодами.
+// Ctrl+Alt набирается как AltGr: левый Ctrl и правый Alt. Некоторые программы
+// отличают AltGr от левых Ctrl+Alt и на второй комбинации срабатывают как на хоткее.
+func appendKeyStroke(inputs *[]INPUT, k keyStroke, hkl uintptr) {
+	var mods []uint16
+	switch {
+	case k.mods&vkScanAltGr == vkScanAltGr:
+		mods = append(mods, VK_LCONTROL, VK_RMENU)
+	case k.mods&vkScanCtrl != 0:
+		mods = append(mods, VK_CONTROL)
+	case k.mods&vkScanAlt != 0:
+		mods = append(mods, VK_MENU)
+	}
+	if k.mods&vkScanShift != 0 {
+		mods = append(mods, VK_SHIFT)
+	}
+
+	for _, vk := range mods {
+		appendScanCodeInput(inputs, vk, false, hkl)
+	}
+	appendScanCodeInput(inputs, k.vk, false, hkl)
+	appendScanCodeInput(inputs, k.vk, true, hkl)
+	for i := len(mods) - 1; i >= 0; i-- {
+		appendScanCodeInput(inputs, mods[i], true, hkl)
+	}
+}
+
+// isDeadKey сообщает, что клавиша с модификаторами на раскладке hkl — мёртвая:
+// она ничего не печатает, а меняет следующий символ (например «^» на французской).
+func isDeadKey(k keyStroke, hkl uintptr) bool {
+	var state [256]byte
+	const down = 0x80
+	if k.mods&vkScanShift != 0 {
+		state[VK_SHIFT] = down
+	}
+	if k.mods&vkScanCtrl != 0 {
+		state[VK_CONTROL] = down
+		state[VK_LCONTROL] = down
+	}
+	if k.mods&vkScanAlt != 0 {
+		state[VK_MENU] = down
+		state[VK_RMENU] = down
+	}
+	sc := mapVirtualKey(k.vk, MAPVK_VK_TO_VSC, hkl)
+	var buf [8]uint16
+	ret, _, _ := procToUnicodeEx.Call(uintptr(k.vk), sc, uintptr(unsafe.Pointer(&state[0])),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), toUnicodeNoStateChange, hkl)
+	return int32(ret) < 0
+}
+
+// deadKeyAccents — символы с диакритикой, которых нет на раскладке отдельной клавишей,
+// но которые набираются мёртвой клавишей и буквой. dead — знаки, под которыми мёртвая
+// клавиша встречается на раскладках (на US-International острое ударение стоит на «'»).
+var deadKeyAccents = []struct {
+	dead     []rune
+	base     string
+	composed string
+}{
+	{[]rune{'`'}, "aeiouAEIOU", "àèìòùÀÈÌÒÙ"},
+	{[]rune{'´', '\''}, "aeiouycnszAEIOUYCNSZ", "áéíóúýćńśźÁÉÍÓÚÝĆŃŚŹ"},
+	{[]rune{'^'}, "aeiouAEIOU", "âêîôûÂÊÎÔÛ"},
+	{[]rune{'~'}, "anoANO", "ãñõÃÑÕ"},
+	{[]rune{'¨', '"'}, "aeiouyAEIOUY", "äëïöüÿÄËÏÖÜŸ"},
+	{[]rune{'¸'}, "cC", "çÇ"},
+	{[]rune{'ˇ'}, "cenrszCENRSZ", "čěňřšžČĚŇŘŠŽ"},
+	{[]rune{'˚', '°'}, "auAU", "åůÅŮ"},
+}
+
+type deadKeyComposition struct {
+	dead []rune
+	base rune
+}
+
+var deadKeyCompositions = func() map[rune]deadKeyComposition {
+	m := make(map[rune]deadKeyComposition)
+	for _, accent := range deadKeyAccents {
+		base, composed := []rune(accent.base), []rune(accent.composed)
+		for i, r := range composed {
+			m[r] = deadKeyComposition{dead: accent.dead, base: base[i]}
+		}
+	}
+	return m
+}()
+
+// lookupDeadKeyStrokes находит для символа с диакритикой пару «мёртвая клавиша, буква»
+// на раскладке hkl.
+func lookupDeadKeyStrokes(r rune, hkl uintptr) (dead, base keyStroke, ok bool) {
+	composition, found := deadKeyCompositions[r]
+	if !found {
+		return keyStroke{}, keyStroke{}, false
+	}
+	if base, ok = lookupKeyStroke(composition.base, hkl); !ok {
+		return keyStroke{}, keyStroke{}, false
+	}
+	for _, accent := range composition.dead {
+		if dead, ok = lookupKeyStroke(accent, hkl); ok && isDeadKey(dead, hkl) {
+			return dead, base, true
+		}
+	}
+	return keyStroke{}, keyStroke{}, false
+}
@@ -0,0 +1,14 @@
+package windows
+
+import "testing"
+
+func TestDeadKeyAccentsAligned(t *testing.T) {
+	for _, accent := range deadKeyAccents {
+		if len([]rune(accent.base)) != len([]rune(accent.composed)) {
+			t.Fatalf("для %q буквы и составные символы не совпадают по длине", accent.dead)
+		}
+	}
+	if c := deadKeyCompositions['é']; c.base != 'e' || c.dead[0] != '´' {
+		t.Fatalf("é = %+v", c)
+	}
+}
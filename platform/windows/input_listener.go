@@ -9,6 +9,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/serty2005/clipqueue/internal/crash"
 	"github.com/serty2005/clipqueue/internal/logger"
 )
 
@@ -255,7 +256,8 @@ func (l *InputListener) getCurrentModifiers() uint8 {
 
 // setKeyboardHook устанавливает низкоуровневый клавиатурный хук
 func (l *InputListener) setKeyboardHook() (uintptr, error) {
-	callback := func(nCode int, wParam uintptr, lParam uintptr) uintptr {
+	callback := func(nCode int, wParam uintptr, lParam uintptr) (ret uintptr) {
+		defer recoverHook("hook.keyboard", nCode, wParam, lParam, &ret)
 		if nCode >= 0 && (wParam == WM_KEYDOWN || wParam == WM_SYSKEYDOWN || wParam == WM_KEYUP || wParam == WM_SYSKEYUP) {
 			kb := (*KBDLLHOOKSTRUCT)(unsafe.Pointer(lParam))
 			l.recordKeyboardEvent(kb, wParam)
@@ -291,7 +293,7 @@ func (l *InputListener) setKeyboardHook() (uintptr, error) {
 			// Режим сопоставления
 			if callback := l.matcher.Match(&sig); callback != nil {
 				logger.Debug("Matched keyboard: %s", sig.DisplayHint)
-				go callback()
+				crash.Go("hotkey "+sig.DisplayHint, callback)
 				return 1 // Блокируем
 			}
 		}
@@ -315,7 +317,8 @@ func (l *InputListener) setKeyboardHook() (uintptr, error) {
 
 // setMouseHook устанавливает низкоуровневый мышиный хук
 func (l *InputListener) setMouseHook() (uintptr, error) {
-	callback := func(nCode int, wParam uintptr, lParam uintptr) uintptr {
+	callback := func(nCode int, wParam uintptr, lParam uintptr) (ret uintptr) {
+		defer recoverHook("hook.mouse", nCode, wParam, lParam, &ret)
 		if nCode >= 0 {
 			mouse := (*MSLLHOOKSTRUCT)(unsafe.Pointer(lParam))
 
@@ -440,7 +443,7 @@ func (l *InputListener) setMouseHook() (uintptr, error) {
 				}
 				if callback := l.matcher.Match(&sig); callback != nil {
 					logger.Debug("Matched mouse: %s", sig.DisplayHint)
-					go callback()
+					crash.Go("hotkey "+sig.DisplayHint, callback)
 					return 1
 				}
 			}
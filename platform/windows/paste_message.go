@@ -0,0 +1,62 @@
+package windows
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const (
+	wmCommand        = 0x0111
+	wmPaste          = 0x0302
+	smtoAbortIfHung  = 0x0002
+	pasteMessageWait = 1000 // мс
+
+	// consolePasteCommand — пункт «Изменить → Вставить» системного меню консоли conhost.
+	consolePasteCommand = 0xFFF1
+	consoleWindowClass  = "ConsoleWindowClass"
+)
+
+var (
+	procSendMessageTimeoutW = user32.NewProc("SendMessageTimeoutW")
+	procGetClassNameW       = user32.NewProc("GetClassNameW")
+)
+
+// windowClassName возвращает имя класса окна.
+func windowClassName(hwnd uintptr) string {
+	var buf [256]uint16
+	n, _, _ := procGetClassNameW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf[:n])
+}
+
+// SendPasteMessage вставляет буфер обмена сообщением, а не нажатием Ctrl+V:
+// WM_PASTE отправляется элементу с фокусом в активном окне (поля ввода Win32
+// понимают его, даже когда перехватывают или игнорируют клавиши). Окно консоли
+// conhost получает команду «Вставить» своего меню. Ответ ждётся не дольше секунды,
+// чтобы зависшее окно не остановило вставку.
+func SendPasteMessage() error {
+	fg, _, _ := procGetForegroundWindow.Call()
+	if fg == 0 {
+		return fmt.Errorf("нет активного окна")
+	}
+	tid, _, _ := procGetWindowThreadProcessId.Call(fg, 0)
+	target := focusedWindow(uint32(tid))
+	if target == 0 {
+		target = fg
+	}
+
+	msg, wParam := uintptr(wmPaste), uintptr(0)
+	if windowClassName(fg) == consoleWindowClass {
+		target, msg, wParam = fg, wmCommand, consolePasteCommand
+	}
+	var result uintptr
+	ret, _, err := procSendMessageTimeoutW.Call(target, msg, wParam, 0,
+		smtoAbortIfHung, pasteMessageWait, uintptr(unsafe.Pointer(&result)))
+	if ret == 0 {
+		return fmt.Errorf("окно %s не обработало сообщение вставки: %v", windowClassName(target), err)
+	}
+	logger.Debug("SendPasteMessage: сообщение 0x%04X отправлено окну %s", msg, windowClassName(target))
+	return nil
+}
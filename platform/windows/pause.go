@@ -0,0 +1,118 @@
+package windows
+
+import (
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const WM_APPLY_PAUSE = 0x0400 + 5 // WM_USER + 5
+
+// OnPauseChange задаёт обработчик смены паузы. Вызывается в потоке окна после того,
+// как хуки и слушатель буфера обмена сняты или восстановлены. Приостановка из-за
+// полноэкранного окна паузой не считается и обработчик не вызывает.
+func (h *Host) OnPauseChange(callback func(paused bool)) {
+	h.onPauseChange = callback
+}
+
+// OnHotkeyPause задаёт обработчик хоткея hotkeys.pause.
+func (h *Host) OnHotkeyPause(callback func()) {
+	h.onPauseHotkey = callback
+}
+
+// SetPaused приостанавливает ClipQueue или снимает паузу: снимает хуки клавиатуры
+// и мыши и перестаёт следить за буфером обмена, не завершая процесс. При
+// resumeAfter > 0 пауза снимается сама через это время.
+func (h *Host) SetPaused(paused bool, resumeAfter time.Duration) error {
+	h.pauseMu.Lock()
+	if h.resumeTimer != nil {
+		h.resumeTimer.Stop()
+		h.resumeTimer = nil
+	}
+	h.pauseWanted = paused
+	h.pauseUntil = time.Time{}
+	if paused && resumeAfter > 0 {
+		h.pauseUntil = time.Now().Add(resumeAfter)
+		h.resumeTimer = time.AfterFunc(resumeAfter, func() {
+			logger.Info("Время паузы истекло, ClipQueue возобновляет работу")
+			if err := h.SetPaused(false, 0); err != nil {
+				logger.Error("Не удалось снять паузу: %v", err)
+			}
+		})
+	}
+	h.pauseMu.Unlock()
+
+	// Хуки ставятся и снимаются только в потоке окна, поэтому само применение
+	// откладывается до WM_APPLY_PAUSE.
+	procPostMessage := user32.NewProc("PostMessageW")
+	ret, _, err := procPostMessage.Call(h.hwnd, uintptr(WM_APPLY_PAUSE), 0, 0)
+	if ret == 0 {
+		logger.Error("PostMessage failed for WM_APPLY_PAUSE: %v", err)
+		return err
+	}
+	return nil
+}
+
+// PauseState сообщает, запрошена ли пауза и когда она снимется сама
+// (нулевое время — только вручную).
+func (h *Host) PauseState() (paused bool, until time.Time) {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	return h.pauseWanted, h.pauseUntil
+}
+
+// applySuspend приводит хуки и слушатель буфера обмена к запрошенному состоянию:
+// они сняты, пока действует пауза или активно полноэкранное окно из fullscreen.
+// Вызывается только в потоке окна.
+func (h *Host) applySuspend() {
+	h.pauseMu.Lock()
+	wanted, fullscreen := h.pauseWanted, h.fullscreenApp
+	h.pauseMu.Unlock()
+
+	if suspend := wanted || fullscreen != ""; suspend != h.suspended {
+		clipboard := h.cfg.Get().Features.EnableClipboard
+		if suspend {
+			logger.Info("ClipQueue приостановлен: хуки ввода и слежение за буфером обмена сняты")
+			// На заблокированном сеансе хуки уже сняты handleSessionChange.
+			if !h.hooksPaused {
+				h.inputListener.Stop()
+			}
+			if clipboard {
+				h.clipboardWatcher.Stop()
+			}
+		} else {
+			logger.Info("ClipQueue возобновляет работу, хуки ввода и слежение за буфером обмена восстанавливаются")
+			if !h.hooksPaused {
+				if err := h.inputListener.Start(); err != nil {
+					logger.Error("Не удалось восстановить хуки ввода: %v", err)
+				}
+			}
+			if clipboard {
+				if err := h.clipboardWatcher.Start(); err != nil {
+					logger.Error("Не удалось восстановить слежение за буфером обмена: %v", err)
+				}
+			}
+		}
+		h.suspended = suspend
+	}
+	if wanted != h.paused {
+		h.paused = wanted
+		h.onPauseChange(wanted)
+	}
+}
+
+// registerPauseHotkey регистрирует hotkeys.pause через RegisterHotKey: такой хоткей
+// приходит окну как WM_HOTKEY и работает, пока хуки ввода сняты паузой.
+func (h *Host) registerPauseHotkey() {
+	if h.osHotkeys == nil {
+		h.osHotkeys, _ = NewHotkeys(h)
+	}
+	h.osHotkeys.Unregister()
+	pause := h.cfg.Get().Hotkeys.Pause
+	if pause == "" {
+		return
+	}
+	if _, err := h.osHotkeys.ParseAndRegister(pause, func() { h.onPauseHotkey() }); err != nil {
+		logger.Error("Не удалось зарегистрировать хоткей паузы %s: %v", pause, err)
+	}
+}
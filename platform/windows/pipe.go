@@ -0,0 +1,208 @@
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	PIPE_ACCESS_DUPLEX            = 0x00000003
+	FILE_FLAG_FIRST_PIPE_INSTANCE = 0x00080000
+	PIPE_TYPE_BYTE                = 0x00000000
+	PIPE_READMODE_BYTE            = 0x00000000
+	PIPE_WAIT                     = 0x00000000
+	PIPE_REJECT_REMOTE_CLIENTS    = 0x00000008
+	PIPE_UNLIMITED_INSTANCES      = 255
+
+	ERROR_PIPE_BUSY      = 231
+	ERROR_PIPE_CONNECTED = 535
+
+	pipeBufferSize = 64 * 1024
+)
+
+var (
+	procCreateNamedPipeW     = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe     = kernel32.NewProc("ConnectNamedPipe")
+	procDisconnectNamedPipe  = kernel32.NewProc("DisconnectNamedPipe")
+	procWaitNamedPipeW       = kernel32.NewProc("WaitNamedPipeW")
+	procProcessIdToSessionId = kernel32.NewProc("ProcessIdToSessionId")
+)
+
+// CurrentSessionID возвращает номер сеанса Windows текущего процесса: у каждого
+// пользователя при быстром переключении и у каждого подключения RDP он свой.
+func CurrentSessionID() (uint32, error) {
+	var session uint32
+	ret, _, err := procProcessIdToSessionId.Call(uintptr(syscall.Getpid()), uintptr(unsafe.Pointer(&session)))
+	if ret == 0 {
+		return 0, fmt.Errorf("ProcessIdToSessionId: %w", err)
+	}
+	return session, nil
+}
+
+// ErrPipeClosed возвращается из Accept после закрытия слушателя.
+var ErrPipeClosed = errors.New("именованный канал закрыт")
+
+// PipeListener принимает подключения к именованному каналу. Каждое подключение
+// обслуживается отдельным экземпляром канала, поэтому клиенты не мешают друг другу.
+type PipeListener struct {
+	name    string
+	mu      sync.Mutex
+	closed  bool
+	pending syscall.Handle
+}
+
+// ListenPipe создаёт первый экземпляр канала с FILE_FLAG_FIRST_PIPE_INSTANCE.
+// Ошибка означает, что канал с таким именем уже создал другой процесс (в том
+// числе чужой, чтобы перехватывать команды) или имя недоступно текущему пользователю.
+func ListenPipe(name string) (*PipeListener, error) {
+	h, err := createPipeInstance(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &PipeListener{name: name, pending: h}, nil
+}
+
+func createPipeInstance(name string, first bool) (syscall.Handle, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	openMode := uintptr(PIPE_ACCESS_DUPLEX)
+	if first {
+		openMode |= FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	h, _, callErr := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		openMode,
+		PIPE_TYPE_BYTE|PIPE_READMODE_BYTE|PIPE_WAIT|PIPE_REJECT_REMOTE_CLIENTS,
+		PIPE_UNLIMITED_INSTANCES,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		0, // Дескриптор безопасности по умолчанию: запись только для владельца и администраторов
+	)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, fmt.Errorf("CreateNamedPipe %s: %w", name, callErr)
+	}
+	return syscall.Handle(h), nil
+}
+
+// Accept ждёт подключения клиента и возвращает соединение с ним.
+func (l *PipeListener) Accept() (io.ReadWriteCloser, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, ErrPipeClosed
+	}
+	h := l.pending
+	l.pending = syscall.InvalidHandle
+	l.mu.Unlock()
+
+	if h == syscall.InvalidHandle {
+		var err error
+		if h, err = createPipeInstance(l.name, false); err != nil {
+			return nil, err
+		}
+	}
+
+	ret, _, err := procConnectNamedPipe.Call(uintptr(h), 0)
+	if ret == 0 && err != syscall.Errno(ERROR_PIPE_CONNECTED) {
+		syscall.CloseHandle(h)
+		return nil, fmt.Errorf("ConnectNamedPipe: %w", err)
+	}
+
+	l.mu.Lock()
+	closed := l.closed
+	l.mu.Unlock()
+	if closed {
+		// Подключение от Close, которое будит ожидающий ConnectNamedPipe.
+		syscall.CloseHandle(h)
+		return nil, ErrPipeClosed
+	}
+	return &pipeConn{h: h}, nil
+}
+
+// Close останавливает приём подключений. Уже открытые соединения не закрываются.
+func (l *PipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	pending := l.pending
+	l.pending = syscall.InvalidHandle
+	l.mu.Unlock()
+
+	if pending != syscall.InvalidHandle {
+		syscall.CloseHandle(pending)
+	}
+
+	// ConnectNamedPipe блокирует поток, поэтому будим Accept собственным подключением.
+	namePtr, err := syscall.UTF16PtrFromString(l.name)
+	if err != nil {
+		return err
+	}
+	h, err := syscall.CreateFile(namePtr, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err == nil {
+		syscall.CloseHandle(h)
+	}
+	return nil
+}
+
+// DialPipe подключается к именованному каналу, ожидая свободный экземпляр не дольше timeout.
+func DialPipe(name string, timeout time.Duration) (io.ReadWriteCloser, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		h, err := syscall.CreateFile(namePtr, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+		if err == nil {
+			return &pipeConn{h: h, client: true}, nil
+		}
+		remaining := time.Until(deadline)
+		if err != syscall.Errno(ERROR_PIPE_BUSY) || remaining <= 0 {
+			return nil, fmt.Errorf("подключение к %s: %w", name, err)
+		}
+		// Все экземпляры заняты — ждём освобождения одного из них.
+		procWaitNamedPipeW.Call(uintptr(unsafe.Pointer(namePtr)), uintptr(remaining.Milliseconds()))
+	}
+}
+
+type pipeConn struct {
+	h      syscall.Handle
+	client bool
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	var n uint32
+	err := syscall.ReadFile(c.h, p, &n, nil)
+	if err == syscall.ERROR_BROKEN_PIPE {
+		return int(n), io.EOF
+	}
+	if err == nil && n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return int(n), err
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	var n uint32
+	err := syscall.WriteFile(c.h, p, &n, nil)
+	return int(n), err
+}
+
+func (c *pipeConn) Close() error {
+	if !c.client {
+		syscall.FlushFileBuffers(c.h)
+		procDisconnectNamedPipe.Call(uintptr(c.h))
+	}
+	return syscall.CloseHandle(c.h)
+}
@@ -0,0 +1,379 @@
+//go:build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+var (
+	gdi32                  = syscall.NewLazyDLL("gdi32.dll")
+	procCreateCompatibleDC = gdi32.NewProc("CreateCompatibleDC")
+	procCreateCompatibleBM = gdi32.NewProc("CreateCompatibleBitmap")
+	procSelectObject       = gdi32.NewProc("SelectObject")
+	procBitBlt             = gdi32.NewProc("BitBlt")
+	procGetDIBits          = gdi32.NewProc("GetDIBits")
+	procDeleteObject       = gdi32.NewProc("DeleteObject")
+	procDeleteDC           = gdi32.NewProc("DeleteDC")
+	procGetPixel           = gdi32.NewProc("GetPixel")
+
+	dwmapi                    = syscall.NewLazyDLL("dwmapi.dll")
+	procDwmGetWindowAttribute = dwmapi.NewProc("DwmGetWindowAttribute")
+
+	procGetDC                      = user32.NewProc("GetDC")
+	procReleaseDC                  = user32.NewProc("ReleaseDC")
+	procGetWindowRect              = user32.NewProc("GetWindowRect")
+	procGetCursorPosScreen         = user32.NewProc("GetCursorPos")
+	procSetCapture                 = user32.NewProc("SetCapture")
+	procReleaseCapture             = user32.NewProc("ReleaseCapture")
+	procLoadCursorW                = user32.NewProc("LoadCursorW")
+	procSetForegroundWindowOverlay = user32.NewProc("SetForegroundWindow")
+	procSetLayeredWindowAttributes = user32.NewProc("SetLayeredWindowAttributes")
+	procDrawFocusRect              = user32.NewProc("DrawFocusRect")
+	procPostQuitMessageOverlay     = user32.NewProc("PostQuitMessage")
+)
+
+// ScreenshotMode — что снимать.
+type ScreenshotMode string
+
+const (
+	ScreenshotFull   ScreenshotMode = "full"   // Все мониторы целиком
+	ScreenshotWindow ScreenshotMode = "window" // Активное окно
+	ScreenshotRegion ScreenshotMode = "region" // Область, выделенная мышью
+)
+
+// ErrScreenshotCancelled возвращается, если выделение области отменено Esc или правой кнопкой.
+var ErrScreenshotCancelled = errors.New("снимок экрана отменён")
+
+const (
+	smXVirtualScreen  = 76
+	smYVirtualScreen  = 77
+	smCXVirtualScreen = 78
+	smCYVirtualScreen = 79
+
+	srcCopy    = 0x00CC0020
+	captureBlt = 0x40000000
+
+	dwmwaExtendedFrameBounds = 9
+
+	clrInvalid = 0xFFFFFFFF
+)
+
+// RECT повторяет структуру RECT из WinAPI.
+type RECT struct {
+	Left, Top, Right, Bottom int32
+}
+
+// Screenshot снимает экран в режиме mode. Координаты физические: процесс
+// работает с DPI awareness, поэтому масштабирование Windows не размывает снимок.
+func Screenshot(mode ScreenshotMode) (image.Image, error) {
+	switch mode {
+	case ScreenshotFull, "":
+		return captureRect(virtualScreen())
+	case ScreenshotWindow:
+		hwnd, _, _ := procGetForegroundWindow.Call()
+		if hwnd == 0 {
+			return nil, errors.New("нет активного окна")
+		}
+		return captureRect(windowBounds(hwnd))
+	case ScreenshotRegion:
+		return captureRegion()
+	}
+	return nil, fmt.Errorf("неизвестный режим снимка %q: допустимы full, window и region", mode)
+}
+
+// PixelAtCursor возвращает цвет пикселя экрана под курсором мыши и координаты курсора.
+func PixelAtCursor() (color.RGBA, image.Point, error) {
+	var pt POINT
+	if ok, _, err := procGetCursorPosScreen.Call(uintptr(unsafe.Pointer(&pt))); ok == 0 {
+		return color.RGBA{}, image.Point{}, fmt.Errorf("GetCursorPos: %v", err)
+	}
+	at := image.Pt(int(pt.X), int(pt.Y))
+	screenDC, _, _ := procGetDC.Call(0)
+	if screenDC == 0 {
+		return color.RGBA{}, at, errors.New("GetDC экрана не удался")
+	}
+	defer procReleaseDC.Call(0, screenDC)
+	ref, _, _ := procGetPixel.Call(screenDC, uintptr(pt.X), uintptr(pt.Y))
+	if uint32(ref) == clrInvalid {
+		return color.RGBA{}, at, fmt.Errorf("GetPixel(%d, %d) не удался", pt.X, pt.Y)
+	}
+	// COLORREF хранит каналы как 0x00BBGGRR.
+	return color.RGBA{R: uint8(ref), G: uint8(ref >> 8), B: uint8(ref >> 16), A: 0xFF}, at, nil
+}
+
+func virtualScreen() image.Rectangle {
+	metric := func(index uintptr) int {
+		v, _, _ := procGetSystemMetrics.Call(index)
+		return int(int32(v))
+	}
+	x, y := metric(smXVirtualScreen), metric(smYVirtualScreen)
+	return image.Rect(x, y, x+metric(smCXVirtualScreen), y+metric(smCYVirtualScreen))
+}
+
+// windowBounds возвращает видимую рамку окна: у GetWindowRect в Windows 10+
+// в размер входят невидимые поля для изменения размера, DWM отдаёт точную рамку.
+func windowBounds(hwnd uintptr) image.Rectangle {
+	var r RECT
+	if hr, _, _ := procDwmGetWindowAttribute.Call(hwnd, dwmwaExtendedFrameBounds, uintptr(unsafe.Pointer(&r)), unsafe.Sizeof(r)); hr != 0 {
+		procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&r)))
+	}
+	return image.Rect(int(r.Left), int(r.Top), int(r.Right), int(r.Bottom)).Intersect(virtualScreen())
+}
+
+// captureRect копирует прямоугольник экрана через BitBlt в 32-битный DIB.
+func captureRect(rect image.Rectangle) (image.Image, error) {
+	w, h := rect.Dx(), rect.Dy()
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("пустая область снимка %v", rect)
+	}
+
+	screenDC, _, _ := procGetDC.Call(0)
+	if screenDC == 0 {
+		return nil, errors.New("GetDC экрана не удался")
+	}
+	defer procReleaseDC.Call(0, screenDC)
+	memDC, _, _ := procCreateCompatibleDC.Call(screenDC)
+	if memDC == 0 {
+		return nil, errors.New("CreateCompatibleDC не удался")
+	}
+	defer procDeleteDC.Call(memDC)
+	bitmap, _, _ := procCreateCompatibleBM.Call(screenDC, uintptr(w), uintptr(h))
+	if bitmap == 0 {
+		return nil, fmt.Errorf("CreateCompatibleBitmap %dx%d не удался", w, h)
+	}
+	defer procDeleteObject.Call(bitmap)
+	old, _, _ := procSelectObject.Call(memDC, bitmap)
+	defer procSelectObject.Call(memDC, old)
+
+	// CAPTUREBLT добавляет в снимок многослойные окна (всплывающие подсказки, меню).
+	if ok, _, err := procBitBlt.Call(memDC, 0, 0, uintptr(w), uintptr(h), screenDC, uintptr(rect.Min.X), uintptr(rect.Min.Y), srcCopy|captureBlt); ok == 0 {
+		return nil, fmt.Errorf("BitBlt: %v", err)
+	}
+
+	header := imaging.BitmapInfoHeader{
+		Size:     imaging.BitmapInfoHeaderSize,
+		Width:    int32(w),
+		Height:   -int32(h), // Отрицательная высота — строки сверху вниз
+		Planes:   1,
+		BitCount: 32,
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if lines, _, err := procGetDIBits.Call(memDC, bitmap, 0, uintptr(h), uintptr(unsafe.Pointer(&img.Pix[0])), uintptr(unsafe.Pointer(&header)), 0); lines == 0 {
+		return nil, fmt.Errorf("GetDIBits: %v", err)
+	}
+	// GetDIBits отдаёт BGRX: меняем каналы местами, альфа у экрана всегда непрозрачная.
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+2], img.Pix[i+3] = img.Pix[i+2], img.Pix[i], 0xFF
+	}
+	return img, nil
+}
+
+// captureRegion снимает все мониторы, показывает поверх них затемняющее окно и
+// вырезает из снимка прямоугольник, выделенный мышью. Снимок делается до показа
+// окна, поэтому в него не попадают ни затемнение, ни рамка выделения.
+func captureRegion() (image.Image, error) {
+	screen := virtualScreen()
+	full, err := captureRect(screen)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := selectRegion(screen)
+	if err != nil {
+		return nil, err
+	}
+	sub := sel.Sub(screen.Min).Intersect(full.Bounds())
+	if sub.Dx() < 2 || sub.Dy() < 2 {
+		return nil, ErrScreenshotCancelled
+	}
+	return full.(*image.RGBA).SubImage(sub), nil
+}
+
+const (
+	wmDestroy     = 0x0002
+	wmKeyDown     = 0x0100
+	wmMouseMove   = 0x0200
+	wmLButtonDown = 0x0201
+	wmLButtonUp   = 0x0202
+	wmRButtonUp   = 0x0205
+
+	vkEscape = 0x1B
+
+	wsPopup         = 0x80000000
+	wsVisible       = 0x10000000
+	wsExTopmost     = 0x00000008
+	wsExToolWindow  = 0x00000080
+	wsExLayered     = 0x00080000
+	lwaAlpha        = 0x2
+	idcCross        = 32515
+	blackBrush      = 4
+	overlayAlpha    = 96
+	overlayClassStr = "ClipQueueScreenshotOverlay"
+)
+
+var procGetStockObject = gdi32.NewProc("GetStockObject")
+
+// regionSelection — состояние одного выделения. Окно выделения одно на процесс,
+// поэтому состояние глобальное и защищено тем, что выделения не идут параллельно.
+type regionSelection struct {
+	origin   image.Point // Левый верхний угол виртуального экрана
+	dragging bool
+	start    image.Point
+	current  image.Point
+	drawn    bool // На экране нарисована рамка current (рисуется XOR, стирается повторным рисованием)
+	result   image.Rectangle
+	done     bool
+}
+
+var (
+	overlayOnce  sync.Once
+	overlayClass *uint16
+	overlayErr   error
+	overlayMu    sync.Mutex // Одно выделение за раз
+	overlayState *regionSelection
+)
+
+func registerOverlayClass() {
+	overlayClass, overlayErr = syscall.UTF16PtrFromString(overlayClassStr)
+	if overlayErr != nil {
+		return
+	}
+	cursor, _, _ := procLoadCursorW.Call(0, idcCross)
+	brush, _, _ := procGetStockObject.Call(blackBrush)
+	wc := WNDCLASSEX{
+		Size:       uint32(unsafe.Sizeof(WNDCLASSEX{})),
+		WndProc:    syscall.NewCallback(overlayProc),
+		Cursor:     cursor,
+		Background: brush,
+		ClassName:  overlayClass,
+	}
+	if atom, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wc))); atom == 0 {
+		overlayErr = fmt.Errorf("RegisterClassEx: %v", err)
+	}
+}
+
+// selectRegion показывает окно выделения поверх screen и ждёт, пока пользователь
+// выделит прямоугольник левой кнопкой мыши. Esc или правая кнопка отменяют выбор.
+func selectRegion(screen image.Rectangle) (image.Rectangle, error) {
+	overlayMu.Lock()
+	defer overlayMu.Unlock()
+
+	// Окно и его цикл сообщений живут в одном потоке ОС.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	ApplyCurrentThreadHighDPIAwareness()
+
+	overlayOnce.Do(registerOverlayClass)
+	if overlayErr != nil {
+		return image.Rectangle{}, overlayErr
+	}
+
+	overlayState = &regionSelection{origin: screen.Min}
+	defer func() { overlayState = nil }()
+
+	hwnd, _, err := procCreateWindowEx.Call(
+		wsExTopmost|wsExToolWindow|wsExLayered,
+		uintptr(unsafe.Pointer(overlayClass)),
+		0,
+		wsPopup|wsVisible,
+		uintptr(screen.Min.X), uintptr(screen.Min.Y), uintptr(screen.Dx()), uintptr(screen.Dy()),
+		0, 0, 0, 0,
+	)
+	if hwnd == 0 {
+		return image.Rectangle{}, fmt.Errorf("CreateWindowEx: %v", err)
+	}
+	procSetLayeredWindowAttributes.Call(hwnd, 0, overlayAlpha, lwaAlpha)
+	procSetForegroundWindowOverlay.Call(hwnd)
+	logger.Debug("Окно выделения области открыто (%v)", screen)
+
+	var msg MSG
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if ret == 0 || int32(ret) == -1 {
+			break
+		}
+		procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+	}
+
+	if !overlayState.done {
+		return image.Rectangle{}, ErrScreenshotCancelled
+	}
+	return overlayState.result, nil
+}
+
+func overlayProc(hwnd uintptr, msg uint32, wParam, lParam uintptr) uintptr {
+	s := overlayState
+	if s == nil {
+		ret, _, _ := procDefWindowProc.Call(hwnd, uintptr(msg), wParam, lParam)
+		return ret
+	}
+	// Координаты клиентской области: окно начинается в левом верхнем углу виртуального экрана.
+	point := func() image.Point {
+		return image.Pt(int(int16(lParam&0xFFFF)), int(int16(lParam>>16&0xFFFF))).Add(s.origin)
+	}
+	switch msg {
+	case wmLButtonDown:
+		s.dragging, s.start, s.current = true, point(), point()
+		procSetCapture.Call(hwnd)
+		return 0
+	case wmMouseMove:
+		if s.dragging {
+			drawSelection(hwnd, s) // Стираем прежнюю рамку
+			s.current = point()
+			drawSelection(hwnd, s)
+		}
+		return 0
+	case wmLButtonUp:
+		if s.dragging {
+			s.current = point()
+			s.result = image.Rectangle{Min: s.start, Max: s.current}.Canon()
+			s.done = true
+			procReleaseCapture.Call()
+			procDestroyWindow.Call(hwnd)
+		}
+		return 0
+	case wmRButtonUp:
+		procReleaseCapture.Call()
+		procDestroyWindow.Call(hwnd)
+		return 0
+	case wmKeyDown:
+		if wParam == vkEscape {
+			procReleaseCapture.Call()
+			procDestroyWindow.Call(hwnd)
+		}
+		return 0
+	case wmDestroy:
+		procPostQuitMessageOverlay.Call(0)
+		return 0
+	}
+	ret, _, _ := procDefWindowProc.Call(hwnd, uintptr(msg), wParam, lParam)
+	return ret
+}
+
+// drawSelection рисует или стирает рамку выделения: DrawFocusRect рисует XOR,
+// поэтому повторный вызов с тем же прямоугольником возвращает фон.
+func drawSelection(hwnd uintptr, s *regionSelection) {
+	if !s.drawn && s.current == s.start {
+		s.drawn = true
+		return
+	}
+	r := image.Rectangle{Min: s.start, Max: s.current}.Canon().Sub(s.origin)
+	rect := RECT{Left: int32(r.Min.X), Top: int32(r.Min.Y), Right: int32(r.Max.X), Bottom: int32(r.Max.Y)}
+	dc, _, _ := procGetDC.Call(hwnd)
+	if dc == 0 {
+		return
+	}
+	procDrawFocusRect.Call(dc, uintptr(unsafe.Pointer(&rect)))
+	procReleaseDC.Call(hwnd, dc)
+}
@@ -0,0 +1,84 @@
+package windows
+
+import (
+	"syscall"
+
+	"github.com/serty2005/clipqueue/internal/logger"
+)
+
+const (
+	WM_WTSSESSION_CHANGE = 0x02B1
+
+	WTS_SESSION_LOCK   = 0x7
+	WTS_SESSION_UNLOCK = 0x8
+
+	NOTIFY_FOR_THIS_SESSION = 0
+
+	smRemoteSession = 0x1000
+)
+
+var (
+	wtsapi32                             = syscall.NewLazyDLL("wtsapi32.dll")
+	procWTSRegisterSessionNotification   = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSUnRegisterSessionNotification = wtsapi32.NewProc("WTSUnRegisterSessionNotification")
+)
+
+// IsRemoteSession сообщает, что ClipQueue запущен в сеансе удалённого рабочего стола.
+func IsRemoteSession() bool {
+	v, _, _ := procGetSystemMetrics.Call(smRemoteSession)
+	return v != 0
+}
+
+// registerSessionNotifications подписывает окно хоста на WM_WTSSESSION_CHANGE.
+func (h *Host) registerSessionNotifications() {
+	if err := procWTSRegisterSessionNotification.Find(); err != nil {
+		logger.Warn("Уведомления о блокировке сеанса недоступны: %v", err)
+		return
+	}
+	ret, _, err := procWTSRegisterSessionNotification.Call(h.hwnd, NOTIFY_FOR_THIS_SESSION)
+	if ret == 0 {
+		logger.Warn("Не удалось подписаться на события сеанса: %v", err)
+		return
+	}
+	h.sessionNotify = true
+}
+
+func (h *Host) unregisterSessionNotifications() {
+	if h.sessionNotify {
+		procWTSUnRegisterSessionNotification.Call(h.hwnd)
+		h.sessionNotify = false
+	}
+}
+
+// handleSessionChange снимает хуки ввода на время блокировки сеанса: на экране
+// блокировки ввод пароля не должен проходить через хуки и запускать макросы.
+func (h *Host) handleSessionChange(event uintptr) {
+	switch event {
+	case WTS_SESSION_LOCK:
+		if !h.cfg.Get().App.PauseHooksOnLock || h.hooksPaused {
+			return
+		}
+		logger.Info("Сеанс заблокирован, хуки ввода приостановлены")
+		if !h.suspended {
+			h.inputListener.Stop()
+		}
+		h.hooksPaused = true
+
+	case WTS_SESSION_UNLOCK:
+		if !h.hooksPaused {
+			return
+		}
+		if h.suspended {
+			// Хуки вернёт снятие паузы или выход из полноэкранного режима.
+			logger.Info("Сеанс разблокирован, ClipQueue остаётся приостановленным")
+			h.hooksPaused = false
+			return
+		}
+		logger.Info("Сеанс разблокирован, хуки ввода восстанавливаются")
+		if err := h.inputListener.Start(); err != nil {
+			logger.Error("Не удалось восстановить хуки ввода: %v", err)
+			return
+		}
+		h.hooksPaused = false
+	}
+}
@@ -0,0 +1,50 @@
+package windows
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// SingleInstanceMutexName — именованный мьютекс, по которому определяется уже
+// запущенный экземпляр в текущей сессии пользователя.
+const SingleInstanceMutexName = `Local\ClipQueue.SingleInstance`
+
+var procCreateMutexW = kernel32.NewProc("CreateMutexW")
+
+// InstanceLock удерживает мьютекс единственного экземпляра до Release.
+type InstanceLock struct {
+	handle uintptr
+}
+
+// AcquireSingleInstance создаёт именованный мьютекс. alreadyRunning == true означает,
+// что мьютекс уже существует: другой экземпляр запущен, и lock в этом случае nil.
+func AcquireSingleInstance(name string) (lock *InstanceLock, alreadyRunning bool, err error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, false, err
+	}
+	h, _, callErr := procCreateMutexW.Call(0, 0, uintptr(unsafe.Pointer(namePtr)))
+	if h == 0 {
+		// Мьютекс, созданный экземпляром с правами администратора, недоступен обычному
+		// процессу: ERROR_ACCESS_DENIED тоже означает уже запущенный экземпляр.
+		if callErr == syscall.ERROR_ACCESS_DENIED {
+			return nil, true, nil
+		}
+		return nil, false, fmt.Errorf("CreateMutex %s: %w", name, callErr)
+	}
+	if callErr == syscall.ERROR_ALREADY_EXISTS {
+		procCloseHandle.Call(h)
+		return nil, true, nil
+	}
+	return &InstanceLock{handle: h}, false, nil
+}
+
+// Release освобождает мьютекс, позволяя запустить новый экземпляр.
+func (l *InstanceLock) Release() {
+	if l == nil || l.handle == 0 {
+		return
+	}
+	procCloseHandle.Call(l.handle)
+	l.handle = 0
+}
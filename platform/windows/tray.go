@@ -1,8 +1,12 @@
 package windows
 
 import (
+	"strings"
+	"sync"
 	"unsafe"
 
+	"github.com/serty2005/clipqueue/internal/i18n"
+	"github.com/serty2005/clipqueue/internal/logger"
 	"golang.org/x/sys/windows"
 )
 
@@ -21,6 +25,12 @@ const (
 	NIF_MESSAGE = 0x00000001
 	NIF_ICON    = 0x00000002
 	NIF_TIP     = 0x00000004
+	NIF_INFO    = 0x00000010
+
+	// Значки всплывающих уведомлений (NOTIFYICONDATA.dwInfoFlags)
+	NIIF_INFO    = 0x00000001
+	NIIF_WARNING = 0x00000002
+	NIIF_ERROR   = 0x00000003
 
 	// Флаги для TrackPopupMenu
 	TPM_RETURNCMD = 0x0100
@@ -33,6 +43,17 @@ const (
 	ID_TRAY_SETTINGS     = 106
 	ID_TRAY_TOGGLE_UI    = ID_TRAY_SETTINGS
 	ID_TRAY_EXIT         = 105
+	ID_TRAY_AUTOSTART    = 107
+	ID_TRAY_UPDATES      = 108
+	ID_TRAY_PAUSE        = 109
+
+	// Пункты подменю недавней истории занимают диапазон [ID_TRAY_HISTORY_BASE, ID_TRAY_HISTORY_BASE+TrayHistoryLimit)
+	ID_TRAY_HISTORY_BASE = 200
+	TrayHistoryLimit     = 10
+
+	// Пункты подменю наборов хоткеев занимают диапазон [ID_TRAY_PROFILE_BASE, ID_TRAY_PROFILE_BASE+TrayProfileLimit)
+	ID_TRAY_PROFILE_BASE = 300
+	TrayProfileLimit     = 20
 
 	// Размеры для NOTIFYICONDATA (для Windows Vista и выше)
 	NOTIFYICONDATA_V2_SIZE = 968 // Размер структуры для Windows Vista+ (x64)
@@ -61,15 +82,35 @@ type NOTIFYICONDATA struct {
 
 // Tray структура для управления системным треем
 type Tray struct {
-	hwnd   uintptr
-	hIcon  uintptr
-	hidden bool
+	mu              sync.Mutex // Защищает hIcon: состояние обновляется из горутин контроллера
+	hwnd            uintptr
+	hIcon           uintptr
+	hidden          bool
+	historyProvider func() []TrayHistoryItem
+	menuHistory     []TrayHistoryItem         // Элементы истории, показанные в последнем меню
+	autostartState  func() bool               // Текущее состояние автозапуска для отметки в меню
+	pausedState     func() bool               // ClipQueue на паузе: пункт паузы отмечается
+	orderState      func() string             // Текущий порядок очереди для пункта переключения
+	profileProvider func() ([]string, string) // Имена наборов хоткеев и активный набор
+	menuProfiles    []string                  // Наборы хоткеев, показанные в последнем меню
+	darkTaskbar     bool                      // Тема панели задач, под которую нарисована иконка
+	stateKnown      bool                      // SetState уже вызывался: иконку можно перерисовать при смене темы
+	stateEnabled    bool
+	stateCount      int
+}
+
+// TrayHistoryItem описывает элемент истории в подменю трея
+type TrayHistoryItem struct {
+	ID      string
+	Label   string
+	Current bool // Элемент сейчас находится в буфере обмена
 }
 
 // NewTray создаёт новый экземпляр Tray
 func NewTray(hwnd uintptr) *Tray {
 	return &Tray{
-		hwnd: hwnd,
+		hwnd:        hwnd,
+		darkTaskbar: IsTaskbarDark(),
 	}
 }
 
@@ -79,15 +120,19 @@ func (t *Tray) Setup(iconPath string) error {
 	var err error
 
 	if iconPath == "" {
-		// Загружаем системную иконку по умолчанию (IDI_APPLICATION)
-		user32 := windows.NewLazySystemDLL("user32.dll")
-		procLoadIcon := user32.NewProc("LoadIconW")
-		hIcon, _, err = procLoadIcon.Call(
-			0,
-			uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("#32512"))), // IDI_APPLICATION
-		)
-		if hIcon == 0 {
-			return err
+		// Встроенная иконка приложения; системная IDI_APPLICATION — только запасной вариант
+		hIcon, err = LoadAppIcon(trayIconSize())
+		if err != nil {
+			logger.Warn("Не удалось загрузить встроенную иконку: %v", err)
+			user32 := windows.NewLazySystemDLL("user32.dll")
+			procLoadIcon := user32.NewProc("LoadIconW")
+			hIcon, _, err = procLoadIcon.Call(
+				0,
+				uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("#32512"))), // IDI_APPLICATION
+			)
+			if hIcon == 0 {
+				return err
+			}
 		}
 	} else {
 		// Загружаем иконку из файла
@@ -176,14 +221,9 @@ func (t *Tray) SetIcon(iconPath string) error {
 	var err error
 
 	if iconPath == "" {
-		// Загружаем системную иконку по умолчанию
-		user32 := windows.NewLazySystemDLL("user32.dll")
-		procLoadIcon := user32.NewProc("LoadIconW")
-		hIcon, _, err = procLoadIcon.Call(
-			0,
-			uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("#32512"))),
-		)
-		if hIcon == 0 {
+		// Загружаем встроенную иконку приложения
+		hIcon, err = LoadAppIcon(trayIconSize())
+		if err != nil {
 			return err
 		}
 	} else {
@@ -203,14 +243,51 @@ func (t *Tray) SetIcon(iconPath string) error {
 		}
 	}
 
-	if t.hIcon != 0 {
-		// Уничтожаем старую иконку
-		user32 := windows.NewLazySystemDLL("user32.dll")
-		procDestroyIcon := user32.NewProc("DestroyIcon")
-		procDestroyIcon.Call(t.hIcon)
+	return t.replaceIcon(hIcon)
+}
+
+// SetState перерисовывает иконку трея по состоянию очереди: цвет показывает ON/OFF,
+// бейдж — количество элементов в очереди.
+func (t *Tray) SetState(enabled bool, count int) error {
+	t.mu.Lock()
+	t.stateKnown, t.stateEnabled, t.stateCount = true, enabled, count
+	dark := t.darkTaskbar
+	t.mu.Unlock()
+
+	size := trayIconSize()
+	base, err := appIconImage(size)
+	if err != nil {
+		logger.Debug("Иконка приложения недоступна для трея, используется упрощённая: %v", err)
+		base = nil
+	}
+	hIcon, err := createIconFromImage(renderTrayStateIcon(base, size, enabled, count, trayThemeFor(dark)))
+	if err != nil {
+		return err
 	}
+	return t.replaceIcon(hIcon)
+}
 
-	t.hIcon = hIcon
+// RefreshTheme перечитывает тему панели задач и перерисовывает иконку, если тема сменилась.
+func (t *Tray) RefreshTheme() error {
+	dark := IsTaskbarDark()
+
+	t.mu.Lock()
+	changed := dark != t.darkTaskbar
+	t.darkTaskbar = dark
+	known, enabled, count := t.stateKnown, t.stateEnabled, t.stateCount
+	t.mu.Unlock()
+
+	if !changed || !known {
+		return nil
+	}
+	logger.Info("Тема панели задач изменилась (тёмная=%v), иконка трея перерисовывается", dark)
+	return t.SetState(enabled, count)
+}
+
+// replaceIcon устанавливает новую иконку и уничтожает предыдущую
+func (t *Tray) replaceIcon(hIcon uintptr) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	var nid NOTIFYICONDATA
 	nid.CbSize = NOTIFYICONDATA_V2_SIZE
@@ -226,73 +303,201 @@ func (t *Tray) SetIcon(iconPath string) error {
 		uintptr(unsafe.Pointer(&nid)),
 	)
 	if result == 0 {
+		procDestroyIcon.Call(hIcon)
 		return err
 	}
 
+	if t.hIcon != 0 {
+		// Уничтожаем старую иконку только после того, как оболочка переключилась на новую
+		procDestroyIcon.Call(t.hIcon)
+	}
+	t.hIcon = hIcon
+
 	return nil
 }
 
-// ShowMenu показывает контекстное меню и возвращает ID выбранного пункта
-func (t *Tray) ShowMenu() uint32 {
-	return t.showSimpleMenu()
+// Notify показывает всплывающее уведомление (balloon/toast) с информационным значком
+func (t *Tray) Notify(title, text string) error {
+	return t.NotifyWithIcon(title, text, NIIF_INFO)
+}
+
+// NotifyWithIcon показывает всплывающее уведомление с заданным значком NIIF_*
+func (t *Tray) NotifyWithIcon(title, text string, infoFlags uint32) error {
+	var nid NOTIFYICONDATA
+	nid.CbSize = NOTIFYICONDATA_V2_SIZE
+	nid.HWnd = t.hwnd
+	nid.UID = 1
+	nid.UFlags = NIF_INFO
+	nid.DwInfoFlags = infoFlags
+
+	// Оставляем место под завершающий ноль; NUL внутри строки приводит к панике StringToUTF16
+	copy(nid.SzInfoTitle[:len(nid.SzInfoTitle)-1], windows.StringToUTF16(strings.ReplaceAll(title, "\x00", "")))
+	copy(nid.SzInfo[:len(nid.SzInfo)-1], windows.StringToUTF16(strings.ReplaceAll(text, "\x00", "")))
+
+	shell32 := windows.NewLazySystemDLL("shell32.dll")
+	procShellNotifyIcon := shell32.NewProc("Shell_NotifyIconW")
+	result, _, err := procShellNotifyIcon.Call(
+		uintptr(NIM_MODIFY),
+		uintptr(unsafe.Pointer(&nid)),
+	)
+	if result == 0 {
+		return err
+	}
 
+	return nil
+}
+
+// ShowMenu показывает контекстное меню и возвращает ID выбранного пункта.
+// Если задан источник истории, в меню добавляется подменю последних элементов;
+// выбранный из него элемент можно получить через HistoryItemID.
+func (t *Tray) ShowMenu() uint32 {
 	user32 := windows.NewLazySystemDLL("user32.dll")
 
-	// Создаём контекстное меню
 	procCreatePopupMenu := user32.NewProc("CreatePopupMenu")
 	hMenu, _, _ := procCreatePopupMenu.Call()
 	if hMenu == 0 {
 		return 0
 	}
 	defer func() {
+		// DestroyMenu рекурсивно уничтожает и присоединённые подменю
 		procDestroyMenu := user32.NewProc("DestroyMenu")
 		procDestroyMenu.Call(hMenu)
 	}()
 
-	// Добавляем пункты меню
 	const MF_STRING = 0x00000000
 	const MF_ENABLED = 0x00000000
 	const MF_GRAYED = 0x00000001
-
+	const MF_CHECKED = 0x00000008
+	const MF_POPUP = 0x00000010
+	const MF_SEPARATOR = 0x00000800
 	procAppendMenu := user32.NewProc("AppendMenuW")
+
+	t.menuHistory = nil
+	if t.historyProvider != nil {
+		t.menuHistory = t.historyProvider()
+		if len(t.menuHistory) > TrayHistoryLimit {
+			t.menuHistory = t.menuHistory[:TrayHistoryLimit]
+		}
+
+		hSubMenu, _, _ := procCreatePopupMenu.Call()
+		if hSubMenu != 0 {
+			if len(t.menuHistory) == 0 {
+				_, _, _ = procAppendMenu.Call(
+					hSubMenu,
+					uintptr(MF_STRING|MF_GRAYED),
+					0,
+					uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.history_empty")))),
+				)
+			}
+			for i, item := range t.menuHistory {
+				flags := uintptr(MF_STRING | MF_ENABLED)
+				if item.Current {
+					flags |= MF_CHECKED
+				}
+				_, _, _ = procAppendMenu.Call(
+					hSubMenu,
+					flags,
+					uintptr(ID_TRAY_HISTORY_BASE+i),
+					uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(trayMenuLabel(item.Label)))),
+				)
+			}
+			_, _, _ = procAppendMenu.Call(
+				hMenu,
+				uintptr(MF_STRING|MF_POPUP),
+				hSubMenu,
+				uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.recent")))),
+			)
+			_, _, _ = procAppendMenu.Call(hMenu, uintptr(MF_SEPARATOR), 0, 0)
+		}
+	}
+
 	_, _, _ = procAppendMenu.Call(
 		hMenu,
 		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_INFO),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Информация"))),
-	)
-	_, _, _ = procAppendMenu.Call(
-		hMenu,
-		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_TOGGLE_QUEUE),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Включить/Выключить очередь"))),
-	)
-	_, _, _ = procAppendMenu.Call(
-		hMenu,
-		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_SWITCH_ORDER),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Переключить порядок"))),
-	)
-	_, _, _ = procAppendMenu.Call(
-		hMenu,
-		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_CLEAR),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Очистить очередь"))),
+		uintptr(ID_TRAY_TOGGLE_UI),
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.toggle_ui")))),
 	)
+	if t.orderState != nil {
+		_, _, _ = procAppendMenu.Call(
+			hMenu,
+			uintptr(MF_STRING|MF_ENABLED),
+			uintptr(ID_TRAY_SWITCH_ORDER),
+			uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.order", t.orderState())))),
+		)
+	}
+	t.menuProfiles = nil
+	if t.profileProvider != nil {
+		names, active := t.profileProvider()
+		if len(names) > TrayProfileLimit {
+			names = names[:TrayProfileLimit]
+		}
+		if len(names) > 1 {
+			if hSubMenu, _, _ := procCreatePopupMenu.Call(); hSubMenu != 0 {
+				t.menuProfiles = names
+				activeLabel := i18n.T("tray.hotkey_profile_default")
+				for i, name := range names {
+					label := name
+					if name == "" {
+						label = i18n.T("tray.hotkey_profile_default")
+					}
+					flags := uintptr(MF_STRING | MF_ENABLED)
+					if name == active {
+						flags |= MF_CHECKED
+						activeLabel = label
+					}
+					_, _, _ = procAppendMenu.Call(
+						hSubMenu,
+						flags,
+						uintptr(ID_TRAY_PROFILE_BASE+i),
+						uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(trayMenuLabel(label)))),
+					)
+				}
+				_, _, _ = procAppendMenu.Call(
+					hMenu,
+					uintptr(MF_STRING|MF_POPUP),
+					hSubMenu,
+					uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.hotkey_profiles", activeLabel)))),
+				)
+			}
+		}
+	}
+	if t.pausedState != nil {
+		flags := uintptr(MF_STRING | MF_ENABLED)
+		if t.pausedState() {
+			flags |= MF_CHECKED
+		}
+		_, _, _ = procAppendMenu.Call(
+			hMenu,
+			flags,
+			uintptr(ID_TRAY_PAUSE),
+			uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.pause")))),
+		)
+	}
+	if t.autostartState != nil {
+		flags := uintptr(MF_STRING | MF_ENABLED)
+		if t.autostartState() {
+			flags |= MF_CHECKED
+		}
+		_, _, _ = procAppendMenu.Call(
+			hMenu,
+			flags,
+			uintptr(ID_TRAY_AUTOSTART),
+			uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.autostart")))),
+		)
+	}
 	_, _, _ = procAppendMenu.Call(
 		hMenu,
 		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_SETTINGS),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Настройки"))),
+		uintptr(ID_TRAY_UPDATES),
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.check_updates")))),
 	)
 	_, _, _ = procAppendMenu.Call(
 		hMenu,
 		uintptr(MF_STRING|MF_ENABLED),
 		uintptr(ID_TRAY_EXIT),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Выход"))),
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(i18n.T("tray.exit")))),
 	)
 
-	// Получаем позицию курсора
 	var point struct {
 		X int32
 		Y int32
@@ -307,7 +512,6 @@ func (t *Tray) ShowMenu() uint32 {
 	procSetForegroundWindow := user32.NewProc("SetForegroundWindow")
 	procSetForegroundWindow.Call(t.hwnd)
 
-	// Показываем контекстное меню
 	const TPM_LEFTALIGN = 0x0000
 	const TPM_TOPALIGN = 0x0000
 	procTrackPopupMenu := user32.NewProc("TrackPopupMenu")
@@ -324,62 +528,68 @@ func (t *Tray) ShowMenu() uint32 {
 	return uint32(selectedID)
 }
 
-func (t *Tray) showSimpleMenu() uint32 {
-	user32 := windows.NewLazySystemDLL("user32.dll")
+// SetOrderState задаёт источник порядка очереди для пункта меню его переключения.
+func (t *Tray) SetOrderState(state func() string) {
+	t.orderState = state
+}
 
-	procCreatePopupMenu := user32.NewProc("CreatePopupMenu")
-	hMenu, _, _ := procCreatePopupMenu.Call()
-	if hMenu == 0 {
-		return 0
-	}
-	defer func() {
-		procDestroyMenu := user32.NewProc("DestroyMenu")
-		procDestroyMenu.Call(hMenu)
-	}()
+// SetAutostartState задаёт источник состояния пункта меню автозапуска.
+func (t *Tray) SetAutostartState(state func() bool) {
+	t.autostartState = state
+}
 
-	const MF_STRING = 0x00000000
-	const MF_ENABLED = 0x00000000
-	procAppendMenu := user32.NewProc("AppendMenuW")
-	_, _, _ = procAppendMenu.Call(
-		hMenu,
-		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_TOGGLE_UI),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Открыть/спрятать UI"))),
-	)
-	_, _, _ = procAppendMenu.Call(
-		hMenu,
-		uintptr(MF_STRING|MF_ENABLED),
-		uintptr(ID_TRAY_EXIT),
-		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("Выход"))),
-	)
+// SetPausedState задаёт источник отметки пункта меню паузы.
+func (t *Tray) SetPausedState(state func() bool) {
+	t.pausedState = state
+}
 
-	var point struct {
-		X int32
-		Y int32
+// SetHistoryProvider задаёт источник элементов для подменю недавней истории.
+func (t *Tray) SetHistoryProvider(provider func() []TrayHistoryItem) {
+	t.historyProvider = provider
+}
+
+// SetHotkeyProfileProvider задаёт источник наборов хоткеев для подменю; подменю
+// показывается, только если кроме основного есть другие наборы.
+func (t *Tray) SetHotkeyProfileProvider(provider func() (names []string, active string)) {
+	t.profileProvider = provider
+}
+
+// HotkeyProfileName возвращает имя набора хоткеев, соответствующее пункту последнего
+// показанного меню; пустое имя — основной набор.
+func (t *Tray) HotkeyProfileName(cmd uint32) (string, bool) {
+	if cmd < ID_TRAY_PROFILE_BASE {
+		return "", false
 	}
-	procGetCursorPos := user32.NewProc("GetCursorPos")
-	ret, _, _ := procGetCursorPos.Call(uintptr(unsafe.Pointer(&point)))
-	if ret == 0 {
-		return 0
+	idx := int(cmd - ID_TRAY_PROFILE_BASE)
+	if idx >= len(t.menuProfiles) {
+		return "", false
 	}
+	return t.menuProfiles[idx], true
+}
 
-	procSetForegroundWindow := user32.NewProc("SetForegroundWindow")
-	procSetForegroundWindow.Call(t.hwnd)
-
-	const TPM_LEFTALIGN = 0x0000
-	const TPM_TOPALIGN = 0x0000
-	procTrackPopupMenu := user32.NewProc("TrackPopupMenu")
-	selectedID, _, _ := procTrackPopupMenu.Call(
-		hMenu,
-		uintptr(TPM_RETURNCMD|TPM_LEFTALIGN|TPM_TOPALIGN),
-		uintptr(point.X),
-		uintptr(point.Y),
-		0,
-		t.hwnd,
-		0,
-	)
+// HistoryItemID возвращает ID элемента истории, соответствующий пункту последнего показанного меню.
+func (t *Tray) HistoryItemID(cmd uint32) (string, bool) {
+	if cmd < ID_TRAY_HISTORY_BASE {
+		return "", false
+	}
+	idx := int(cmd - ID_TRAY_HISTORY_BASE)
+	if idx >= len(t.menuHistory) {
+		return "", false
+	}
+	return t.menuHistory[idx].ID, true
+}
 
-	return uint32(selectedID)
+// trayMenuLabel укорачивает предпросмотр до одной строки и экранирует '&' для WinAPI меню.
+func trayMenuLabel(preview string) string {
+	const maxRunes = 48
+	label := strings.Join(strings.Fields(preview), " ")
+	if label == "" {
+		label = i18n.T("tray.no_preview")
+	}
+	if runes := []rune(label); len(runes) > maxRunes {
+		label = string(runes[:maxRunes-1]) + "…"
+	}
+	return strings.ReplaceAll(label, "&", "&&")
 }
 
 // Remove удаляет иконку из системного трея и очищает ресурсы
@@ -399,12 +609,12 @@ func (t *Tray) Remove() error {
 		return err
 	}
 
+	t.mu.Lock()
 	if t.hIcon != 0 {
-		user32 := windows.NewLazySystemDLL("user32.dll")
-		procDestroyIcon := user32.NewProc("DestroyIcon")
 		procDestroyIcon.Call(t.hIcon)
 		t.hIcon = 0
 	}
+	t.mu.Unlock()
 
 	return nil
 }
@@ -0,0 +1,136 @@
+package windows
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"unsafe"
+
+	"github.com/serty2005/clipqueue/internal/imaging"
+)
+
+const (
+	SM_CXSMICON = 49
+
+	iconResourceVersion = 0x00030000
+)
+
+var (
+	procGetSystemMetrics         = user32.NewProc("GetSystemMetrics")
+	procCreateIconFromResourceEx = user32.NewProc("CreateIconFromResourceEx")
+	procDestroyIcon              = user32.NewProc("DestroyIcon")
+	trayIconOnColor              = color.NRGBA{0x2E, 0xA0, 0x43, 0xFF}
+	trayIconOffColor             = color.NRGBA{0x8A, 0x8F, 0x98, 0xFF}
+	trayIconClipColor            = color.NRGBA{0x3B, 0x3F, 0x46, 0xFF}
+	trayIconPaperColor           = color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	trayIconBadgeColor           = color.NRGBA{0xD9, 0x30, 0x25, 0xFF}
+	trayIconBadgeTextColor       = color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	trayIconDigitFont            = [10][5]uint8{
+		{0b111, 0b101, 0b101, 0b101, 0b111}, // 0
+		{0b010, 0b110, 0b010, 0b010, 0b111}, // 1
+		{0b111, 0b001, 0b111, 0b100, 0b111}, // 2
+		{0b111, 0b001, 0b111, 0b001, 0b111}, // 3
+		{0b101, 0b101, 0b111, 0b001, 0b001}, // 4
+		{0b111, 0b100, 0b111, 0b001, 0b111}, // 5
+		{0b111, 0b100, 0b111, 0b101, 0b111}, // 6
+		{0b111, 0b001, 0b010, 0b010, 0b010}, // 7
+		{0b111, 0b101, 0b111, 0b101, 0b111}, // 8
+		{0b111, 0b101, 0b111, 0b001, 0b111}, // 9
+	}
+)
+
+// trayIconSize возвращает размер маленькой иконки с учётом DPI.
+func trayIconSize() int {
+	return systemMetric(SM_CXSMICON, 16)
+}
+
+func systemMetric(index uintptr, fallback int) int {
+	value, _, _ := procGetSystemMetrics.Call(index)
+	if value == 0 {
+		return fallback
+	}
+	return int(value)
+}
+
+// createIconFromImage создаёт HICON из изображения. Вызывающий отвечает за DestroyIcon.
+func createIconFromImage(img image.Image) (uintptr, error) {
+	res := imaging.EncodeIconResource(img)
+	b := img.Bounds()
+	hIcon, _, err := procCreateIconFromResourceEx.Call(
+		uintptr(unsafe.Pointer(&res[0])),
+		uintptr(len(res)),
+		1, // fIcon
+		iconResourceVersion,
+		uintptr(b.Dx()),
+		uintptr(b.Dy()),
+		0, // LR_DEFAULTCOLOR
+	)
+	if hIcon == 0 {
+		return 0, fmt.Errorf("CreateIconFromResourceEx: %w", err)
+	}
+	return hIcon, nil
+}
+
+// renderTrayStateIcon рисует иконку трея поверх base: индикатор состояния очереди
+// в левом нижнем углу и красный бейдж с числом элементов в правом нижнем.
+// Если base == nil, вместо иконки приложения рисуется упрощённый планшет.
+// Цвета обводки и выключенного индикатора берутся из темы панели задач.
+func renderTrayStateIcon(base *image.NRGBA, size int, enabled bool, count int, theme trayTheme) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	at := func(f float64) int { return int(f*float64(size) + 0.5) }
+
+	if base != nil {
+		copy(img.Pix, base.Pix)
+	} else {
+		fillRect(img, at(0.14), at(0.12), at(0.86), at(0.98), theme.clip)
+		fillRect(img, at(0.26), at(0.26), at(0.74), at(0.86), theme.paper)
+	}
+
+	state := theme.off
+	if enabled {
+		state = trayIconOnColor
+	}
+	dot := max(4, at(0.4))
+	fillRect(img, 0, size-dot, dot, size, theme.outline)
+	fillRect(img, 1, size-dot+1, dot-1, size-1, state)
+
+	if count <= 0 {
+		return img
+	}
+	if count > 99 {
+		count = 99
+	}
+	text := strconv.Itoa(count)
+
+	scale := max(1, size/16)
+	glyphW, glyphH := 3*scale, 5*scale
+	badgeW := len(text)*(glyphW+scale) + scale
+	badgeH := glyphH + 2*scale
+	x0, y0 := size-badgeW, size-badgeH
+	fillRect(img, x0, y0, size, size, trayIconBadgeColor)
+
+	for i, ch := range text {
+		glyph := trayIconDigitFont[ch-'0']
+		gx := x0 + scale + i*(glyphW+scale)
+		gy := y0 + scale
+		for row := 0; row < 5; row++ {
+			for col := 0; col < 3; col++ {
+				if glyph[row]&(1<<(2-col)) == 0 {
+					continue
+				}
+				fillRect(img, gx+col*scale, gy+row*scale, gx+(col+1)*scale, gy+(row+1)*scale, trayIconBadgeTextColor)
+			}
+		}
+	}
+	return img
+}
+
+func fillRect(img *image.NRGBA, x0, y0, x1, y1 int, c color.NRGBA) {
+	r := image.Rect(x0, y0, x1, y1).Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package windows
+
+import "testing"
+
+func TestRenderTrayStateIconReflectsState(t *testing.T) {
+	on := renderTrayStateIcon(nil, 16, true, 0, trayThemeDark)
+	off := renderTrayStateIcon(nil, 16, false, 0, trayThemeDark)
+
+	// Индикатор состояния в левом нижнем углу окрашен в цвет ON/OFF.
+	if got := on.NRGBAAt(2, 13); got != trayIconOnColor {
+		t.Fatalf("ожидался цвет ON, получено %v", got)
+	}
+	if got := off.NRGBAAt(2, 13); got != trayIconOffColor {
+		t.Fatalf("ожидался цвет OFF, получено %v", got)
+	}
+	if got := on.NRGBAAt(15, 15); got == trayIconBadgeColor {
+		t.Fatal("без элементов бейдж не рисуется")
+	}
+}
+
+func TestRenderTrayStateIconDrawsCountBadge(t *testing.T) {
+	img := renderTrayStateIcon(nil, 32, true, 123, trayThemeDark)
+
+	if got := img.NRGBAAt(31, 31); got != trayIconBadgeColor {
+		t.Fatalf("ожидался бейдж в правом нижнем углу, получено %v", got)
+	}
+	// Число ограничено двумя цифрами, поэтому бейдж занимает не больше 18 px из 32.
+	if got := img.NRGBAAt(12, 31); got == trayIconBadgeColor {
+		t.Fatal("бейдж слишком широкий")
+	}
+}
+
+func TestRenderTrayStateIconFollowsTaskbarTheme(t *testing.T) {
+	dark := renderTrayStateIcon(nil, 16, false, 0, trayThemeDark)
+	light := renderTrayStateIcon(nil, 16, false, 0, trayThemeLight)
+
+	// Обводка индикатора (левый нижний угол) должна контрастировать с панелью задач.
+	if got := dark.NRGBAAt(0, 15); got != trayThemeDark.outline {
+		t.Fatalf("тёмная тема: ожидалась обводка %v, получено %v", trayThemeDark.outline, got)
+	}
+	if got := light.NRGBAAt(0, 15); got != trayThemeLight.outline {
+		t.Fatalf("светлая тема: ожидалась обводка %v, получено %v", trayThemeLight.outline, got)
+	}
+	if got := light.NRGBAAt(2, 13); got != trayThemeLight.off {
+		t.Fatalf("светлая тема: ожидался цвет OFF %v, получено %v", trayThemeLight.off, got)
+	}
+}
+
+func TestAppIconImageMatchesRequestedSize(t *testing.T) {
+	for _, size := range []int{16, 20, 24, 32, 48, 30} {
+		img, err := appIconImage(size)
+		if err != nil {
+			t.Fatalf("размер %d: %v", size, err)
+		}
+		if img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+			t.Fatalf("размер %d: получено %v", size, img.Bounds())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package windows
+
+import (
+	"image/color"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	WM_SETTINGCHANGE = 0x001A
+
+	personalizeKey = `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`
+	// shouldSystemUseDarkModeOrdinal — недокументированная функция uxtheme.dll (Windows 10 1903+).
+	shouldSystemUseDarkModeOrdinal = 138
+)
+
+// trayTheme — цвета элементов иконки трея, зависящие от темы панели задач.
+type trayTheme struct {
+	outline color.NRGBA // Обводка индикатора состояния, отделяющая его от фона панели
+	off     color.NRGBA // Индикатор выключенной очереди
+	clip    color.NRGBA // Планшет упрощённой иконки
+	paper   color.NRGBA // Лист упрощённой иконки
+}
+
+var (
+	// На тёмной панели задач светлая обводка отделяет индикатор от фона.
+	trayThemeDark = trayTheme{
+		outline: color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		off:     trayIconOffColor,
+		clip:    trayIconClipColor,
+		paper:   trayIconPaperColor,
+	}
+	// На светлой панели белая обводка и светло-серый индикатор теряются, поэтому цвета темнее.
+	trayThemeLight = trayTheme{
+		outline: color.NRGBA{0x20, 0x22, 0x26, 0xFF},
+		off:     color.NRGBA{0x5F, 0x63, 0x6B, 0xFF},
+		clip:    color.NRGBA{0x20, 0x22, 0x26, 0xFF},
+		paper:   color.NRGBA{0xF4, 0xF5, 0xF7, 0xFF},
+	}
+)
+
+func trayThemeFor(dark bool) trayTheme {
+	if dark {
+		return trayThemeDark
+	}
+	return trayThemeLight
+}
+
+// IsTaskbarDark сообщает, использует ли панель задач тёмную тему. Основной источник —
+// значение SystemUsesLightTheme в реестре (тема панели задач и меню «Пуск»);
+// если его нет, опрашивается ShouldSystemUseDarkMode из uxtheme.dll. Windows до 10
+// не поддерживает светлую панель, поэтому по умолчанию тема тёмная.
+func IsTaskbarDark() bool {
+	if key, err := registry.OpenKey(registry.CURRENT_USER, personalizeKey, registry.QUERY_VALUE); err == nil {
+		defer key.Close()
+		if light, _, err := key.GetIntegerValue("SystemUsesLightTheme"); err == nil {
+			return light == 0
+		}
+	}
+	if dark, ok := shouldSystemUseDarkMode(); ok {
+		return dark
+	}
+	return true
+}
+
+func shouldSystemUseDarkMode() (dark bool, ok bool) {
+	uxtheme, err := windows.LoadLibraryEx("uxtheme.dll", 0, windows.LOAD_LIBRARY_SEARCH_SYSTEM32)
+	if err != nil {
+		return false, false
+	}
+	defer windows.FreeLibrary(uxtheme)
+	proc, err := windows.GetProcAddressByOrdinal(uxtheme, shouldSystemUseDarkModeOrdinal)
+	if err != nil {
+		return false, false
+	}
+	ret, _, _ := syscall.SyscallN(proc)
+	return ret&0xff != 0, true
+}
+
+// isThemeChange проверяет, что WM_SETTINGCHANGE сообщает о смене цветовой схемы.
+func isThemeChange(lParam uintptr) bool {
+	if lParam == 0 {
+		return false
+	}
+	return windows.UTF16PtrToString((*uint16)(unsafe.Pointer(lParam))) == "ImmersiveColorSet"
+}
@@ -1,13 +1,31 @@
 package windows
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	autostartRunKey    = `Software\Microsoft\Windows\CurrentVersion\Run`
+	autostartValueName = "ClipQueue"
+
+	// SilentArg — аргумент запуска в фоне без окна UI; его получает запись автозапуска.
+	SilentArg = "--silent"
 )
 
 var (
-	procGetConsoleWindow = kernel32.NewProc("GetConsoleWindow")
-	procShowWindow       = user32.NewProc("ShowWindow")
+	procGetConsoleWindow         = kernel32.NewProc("GetConsoleWindow")
+	procAttachConsole            = kernel32.NewProc("AttachConsole")
+	procGetUserDefaultUILanguage = kernel32.NewProc("GetUserDefaultUILanguage")
+	procShowWindow               = user32.NewProc("ShowWindow")
 
 	SW_HIDE = 0
 )
@@ -20,6 +38,25 @@ func HideConsole() {
 	}
 }
 
+// AttachParentConsole подключает вывод к консоли родительского процесса.
+// Сборка с -H windowsgui запускается без консоли, и без этого вывод CLI теряется.
+// Если stdout уже доступен (консольная сборка или перенаправление), ничего не делает.
+func AttachParentConsole() {
+	if os.Stdout != nil {
+		if fd := os.Stdout.Fd(); fd != 0 && fd != uintptr(syscall.InvalidHandle) {
+			return
+		}
+	}
+	const attachParentProcess = ^uintptr(0) // ATTACH_PARENT_PROCESS = (DWORD)-1
+	if ret, _, _ := procAttachConsole.Call(attachParentProcess); ret == 0 {
+		return
+	}
+	if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = f
+		os.Stderr = f
+	}
+}
+
 // OpenBrowser открывает указанный URL в браузере по умолчанию
 func OpenBrowser(url string) error {
 	if runtime.GOOS != "windows" {
@@ -30,3 +67,78 @@ func OpenBrowser(url string) error {
 	cmd := exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
 	return cmd.Start()
 }
+
+// autostartCommand возвращает командную строку автозапуска текущего исполняемого файла.
+func autostartCommand() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("не удалось определить путь к исполняемому файлу: %w", err)
+	}
+	return strings.Join(append([]string{fmt.Sprintf("%q", exePath), SilentArg}, config.LaunchArgs()...), " "), nil
+}
+
+// SetAutostart добавляет или удаляет запись ClipQueue в HKCU\...\Run.
+// Запись указывает на текущий исполняемый файл, поэтому после переноса .exe
+// повторный вызов с enabled=true обновляет путь.
+func SetAutostart(enabled bool) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, autostartRunKey, registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть ключ автозапуска: %w", err)
+	}
+	defer key.Close()
+
+	if !enabled {
+		if err := key.DeleteValue(autostartValueName); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return fmt.Errorf("не удалось удалить запись автозапуска: %w", err)
+		}
+		return nil
+	}
+
+	command, err := autostartCommand()
+	if err != nil {
+		return err
+	}
+	if current, _, err := key.GetStringValue(autostartValueName); err == nil && current == command {
+		return nil
+	}
+	if err := key.SetStringValue(autostartValueName, command); err != nil {
+		return fmt.Errorf("не удалось записать автозапуск: %w", err)
+	}
+	return nil
+}
+
+// IsAutostartEnabled сообщает, есть ли запись автозапуска ClipQueue.
+func IsAutostartEnabled() bool {
+	key, err := registry.OpenKey(registry.CURRENT_USER, autostartRunKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+	_, _, err = key.GetStringValue(autostartValueName)
+	return err == nil
+}
+
+// HasArg сообщает, передан ли аргумент командной строки.
+func HasArg(arg string) bool {
+	for _, a := range os.Args[1:] {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// UserLanguage возвращает код языка интерфейса Windows текущего пользователя
+// ("ru", "en" и т.п.) или пустую строку, если язык не распознан.
+func UserLanguage() string {
+	langID, _, _ := procGetUserDefaultUILanguage.Call()
+	// Младшие 10 бит LANGID — основной язык (PRIMARYLANGID).
+	switch langID & 0x3ff {
+	case 0x19:
+		return "ru"
+	case 0x09:
+		return "en"
+	default:
+		return ""
+	}
+}
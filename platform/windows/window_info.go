@@ -0,0 +1,94 @@
+package windows
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const processQueryLimitedInformation = 0x1000
+
+var (
+	procGetWindowTextW             = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW       = user32.NewProc("GetWindowTextLengthW")
+	procOpenProcess                = kernel32.NewProc("OpenProcess")
+	procCloseHandle                = kernel32.NewProc("CloseHandle")
+	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+// WindowInfo описывает окно верхнего уровня и процесс, которому оно принадлежит.
+type WindowInfo struct {
+	HWND        uintptr `json:"hwnd"`
+	ProcessID   uint32  `json:"processId"`
+	ProcessName string  `json:"processName"`
+	Title       string  `json:"title"`
+}
+
+// GetForegroundWindowInfo возвращает сведения об активном окне.
+func GetForegroundWindowInfo() WindowInfo {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	return GetWindowInfo(hwnd)
+}
+
+// GetWindowInfo возвращает заголовок окна и имя исполняемого файла его процесса.
+func GetWindowInfo(hwnd uintptr) WindowInfo {
+	info := WindowInfo{HWND: hwnd}
+	if hwnd == 0 {
+		return info
+	}
+
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	info.ProcessID = pid
+	if path := processImagePath(pid); path != "" {
+		info.ProcessName = filepath.Base(path)
+	}
+	info.Title = windowText(hwnd)
+	return info
+}
+
+func windowText(hwnd uintptr) string {
+	n, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if n == 0 {
+		return ""
+	}
+	buf := make([]uint16, n+1)
+	procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf)
+}
+
+func processImagePath(pid uint32) string {
+	if pid == 0 {
+		return ""
+	}
+	handle, _, _ := procOpenProcess.Call(processQueryLimitedInformation, 0, uintptr(pid))
+	if handle == 0 {
+		return ""
+	}
+	defer procCloseHandle.Call(handle)
+
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	ret, _, _ := procQueryFullProcessImageNameW.Call(handle, 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:size])
+}
+
+// GetClipboardSourceInfo возвращает приложение, которое записало текущее содержимое
+// буфера обмена. Владелец буфера (GetClipboardOwner) — чаще всего скрытое служебное
+// окно без заголовка, поэтому заголовок берётся у активного окна того же процесса.
+// Если владельца нет (данные записаны без окна), источником считается активное окно.
+func GetClipboardSourceInfo() WindowInfo {
+	foreground := GetForegroundWindowInfo()
+	owner, _, _ := procGetClipboardOwner.Call()
+	if owner == 0 {
+		return foreground
+	}
+	info := GetWindowInfo(owner)
+	if info.ProcessID == foreground.ProcessID {
+		info.HWND, info.Title = foreground.HWND, foreground.Title
+	}
+	return info
+}
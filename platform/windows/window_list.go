@@ -0,0 +1,119 @@
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	gwOwner         = 4
+	gwlExStyle      = ^uintptr(19) // GWL_EXSTYLE == -20
+	wsExNoActivate  = 0x08000000
+	swRestore       = 9
+	activateTimeout = 500 * time.Millisecond
+)
+
+// ErrWindowNotFound — окна с таким дескриптором уже нет.
+var ErrWindowNotFound = errors.New("окно не найдено")
+
+var (
+	procEnumWindows          = user32.NewProc("EnumWindows")
+	procIsWindow             = user32.NewProc("IsWindow")
+	procIsWindowVisible      = user32.NewProc("IsWindowVisible")
+	procIsIconic             = user32.NewProc("IsIconic")
+	procGetWindow            = user32.NewProc("GetWindow")
+	procGetWindowLongPtrW    = user32.NewProc("GetWindowLongPtrW")
+	procBringWindowToTop     = user32.NewProc("BringWindowToTop")
+	procSetForegroundWindowW = user32.NewProc("SetForegroundWindow")
+	procAttachThreadInput    = user32.NewProc("AttachThreadInput")
+	procGetCurrentThreadId   = kernel32.NewProc("GetCurrentThreadId")
+)
+
+// enumWindowsCallback создаётся один раз: число обратных вызовов syscall ограничено.
+var enumWindowsCallback = sync.OnceValue(func() uintptr {
+	return syscall.NewCallback(func(hwnd, lParam uintptr) uintptr {
+		list := (*[]uintptr)(unsafe.Pointer(lParam))
+		*list = append(*list, hwnd)
+		return 1
+	})
+})
+
+// ListWindows возвращает окна верхнего уровня, которые видны в переключателе Alt+Tab,
+// в порядке Z (сверху вниз). Окна самого приложения не входят.
+func ListWindows() []WindowInfo {
+	var handles []uintptr
+	procEnumWindows.Call(enumWindowsCallback(), uintptr(unsafe.Pointer(&handles)))
+
+	self := uint32(os.Getpid())
+	windows := make([]WindowInfo, 0, len(handles))
+	for _, hwnd := range handles {
+		if !isTaskWindow(hwnd) {
+			continue
+		}
+		info := GetWindowInfo(hwnd)
+		if info.ProcessID == self || info.Title == "" {
+			continue
+		}
+		windows = append(windows, info)
+	}
+	return windows
+}
+
+// isTaskWindow повторяет правило панели задач: видимое окно без владельца,
+// не служебное и способное стать активным.
+func isTaskWindow(hwnd uintptr) bool {
+	if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+		return false
+	}
+	if owner, _, _ := procGetWindow.Call(hwnd, gwOwner); owner != 0 {
+		return false
+	}
+	exStyle, _, _ := procGetWindowLongPtrW.Call(hwnd, gwlExStyle)
+	return exStyle&(wsExToolWindow|wsExNoActivate) == 0
+}
+
+// ActivateWindow разворачивает окно, если оно свёрнуто, и делает его активным.
+// Windows разрешает SetForegroundWindow только процессу, получившему последний ввод,
+// поэтому поток на время вызова подключается к очереди ввода текущего активного окна.
+func ActivateWindow(hwnd uintptr) error {
+	if ok, _, _ := procIsWindow.Call(hwnd); ok == 0 {
+		return ErrWindowNotFound
+	}
+	if iconic, _, _ := procIsIconic.Call(hwnd); iconic != 0 {
+		procShowWindow.Call(hwnd, swRestore)
+	}
+	fg, _, _ := procGetForegroundWindow.Call()
+	if fg == hwnd {
+		return nil
+	}
+
+	// Подключение к очереди ввода действует для потока ОС, а не горутины.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	fgThread, _, _ := procGetWindowThreadProcessId.Call(fg, 0)
+	current, _, _ := procGetCurrentThreadId.Call()
+	if fgThread != 0 && fgThread != current {
+		if attached, _, _ := procAttachThreadInput.Call(current, fgThread, 1); attached != 0 {
+			defer procAttachThreadInput.Call(current, fgThread, 0)
+		}
+	}
+	procBringWindowToTop.Call(hwnd)
+	procSetForegroundWindowW.Call(hwnd)
+
+	deadline := time.Now().Add(activateTimeout)
+	for {
+		if fg, _, _ := procGetForegroundWindow.Call(); fg == hwnd {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("окно %s не стало активным", GetWindowInfo(hwnd).ProcessName)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/plugins"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// pluginService загружает скрипты из <app.data_dir>/plugins и передаёт их контроллеру.
+// При изменении раздела plugins или каталога данных скрипты загружаются заново.
+type pluginService struct {
+	controller *app.Controller
+	notify     func(title, text string)
+
+	mu      sync.Mutex
+	applied config.Config // Применённые раздел plugins и app.data_dir; остальные поля не используются
+	manager *plugins.Manager
+}
+
+func newPluginService(controller *app.Controller, notify func(title, text string)) *pluginService {
+	return &pluginService{controller: controller, notify: notify}
+}
+
+// apply включает, выключает или перезагружает плагины по настройкам cfg.
+func (s *pluginService) apply(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.manager != nil && s.applied.Plugins == cfg.Plugins && s.applied.App.DataDir == cfg.App.DataDir {
+		return
+	}
+	s.closeLocked()
+	s.applied.Plugins = cfg.Plugins
+	s.applied.App.DataDir = cfg.App.DataDir
+	if !cfg.Plugins.Enabled {
+		return
+	}
+	dir := filepath.Join(config.ResolvePath(cfg.App.DataDir), plugins.DirName)
+	manager, err := plugins.Load(dir, pluginHost{s}, time.Duration(cfg.Plugins.TimeoutMs)*time.Millisecond)
+	if err != nil {
+		logger.Error("Не удалось загрузить плагины из %s: %v", dir, err)
+		return
+	}
+	s.manager = manager
+	s.controller.SetPlugins(pluginAdapter{manager})
+	logger.Info("Плагины: загружено %d из %s", len(manager.Names()), dir)
+}
+
+// close выгружает плагины при выходе из приложения.
+func (s *pluginService) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *pluginService) closeLocked() {
+	if s.manager == nil {
+		return
+	}
+	s.controller.SetPlugins(nil)
+	s.manager.Close()
+	s.manager = nil
+}
+
+// pluginHost — функции приложения, которые скрипты вызывают через таблицу clipqueue.
+type pluginHost struct{ s *pluginService }
+
+func (h pluginHost) PushText(text string) (string, error) { return h.s.controller.PushText(text) }
+func (h pluginHost) CopyText(text string) error           { return h.s.controller.CopyText(text) }
+func (h pluginHost) Notify(title, text string)            { h.s.notify(title, text) }
+
+// pluginAdapter переводит элементы контроллера в представление плагинов и обратно.
+type pluginAdapter struct{ m *plugins.Manager }
+
+func (a pluginAdapter) Capture(content windows.ClipboardContent) (windows.ClipboardContent, bool) {
+	item, keep := a.m.Capture(pluginItem(content))
+	return fromPluginItem(content, item), keep
+}
+
+func (a pluginAdapter) BeforePaste(content windows.ClipboardContent, target windows.WindowInfo) (windows.ClipboardContent, bool) {
+	item, ok := a.m.BeforePaste(pluginItem(content), plugins.Target{Title: target.Title, Process: target.ProcessName})
+	if !ok {
+		return content, false
+	}
+	return fromPluginItem(content, item), true
+}
+
+func (a pluginAdapter) RunAction(name, text string) error { return a.m.RunAction(name, text) }
+
+func pluginItem(content windows.ClipboardContent) plugins.Item {
+	return plugins.Item{
+		ID:        content.ID,
+		Type:      content.Type.String(),
+		Text:      content.Text,
+		Files:     content.Files,
+		Preview:   content.Preview,
+		Timestamp: content.Timestamp,
+	}
+}
+
+// fromPluginItem применяет изменения скрипта: новый текст превращает элемент в
+// текстовый с тем же ID, временем и приложением-источником.
+func fromPluginItem(content windows.ClipboardContent, item plugins.Item) windows.ClipboardContent {
+	if item.Type == content.Type.String() && item.Text == content.Text {
+		return content
+	}
+	changed := windows.NewTextContent(item.Text)
+	changed.ID = content.ID
+	changed.Timestamp = content.Timestamp
+	changed.SourceApp, changed.SourceTitle = content.SourceApp, content.SourceTitle
+	return changed
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/plugins"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+func TestFromPluginItemKeepsSource(t *testing.T) {
+	content := windows.NewTextContent("исходный текст")
+	content.ID = "42"
+	content.Timestamp = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	content.SourceApp, content.SourceTitle = "Code.exe", "main.go - Visual Studio Code"
+
+	changed := fromPluginItem(content, plugins.Item{Type: "Text", Text: "ИСХОДНЫЙ ТЕКСТ"})
+
+	if changed.Text != "ИСХОДНЫЙ ТЕКСТ" || changed.ID != "42" || !changed.Timestamp.Equal(content.Timestamp) {
+		t.Fatalf("неожиданный элемент после скрипта: %+v", changed)
+	}
+	if changed.SourceApp != "Code.exe" || changed.SourceTitle != content.SourceTitle {
+		t.Fatalf("приложение-источник потеряно: %q, %q", changed.SourceApp, changed.SourceTitle)
+	}
+	if same := fromPluginItem(content, pluginItem(content)); same.Text != content.Text || same.SourceApp != "Code.exe" {
+		t.Fatalf("без изменений элемент должен остаться прежним: %+v", same)
+	}
+}
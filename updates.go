@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/logger"
+	"github.com/serty2005/clipqueue/internal/updater"
+	"github.com/serty2005/clipqueue/internal/version"
+)
+
+// updateFirstCheckDelay откладывает первую проверку, чтобы не нагружать старт приложения.
+const updateFirstCheckDelay = time.Minute
+
+// updateService периодически проверяет релизы на GitHub и сообщает о новой версии
+// уведомлением трея. При updates.auto_download файл скачивается заранее и
+// подменяет текущий .exe при выходе из приложения.
+type updateService struct {
+	cfg      *config.SafeConfig
+	notify   func(title, text string, failure bool)
+	mu       sync.Mutex
+	notified string // Версия, о которой уже сообщили
+}
+
+func newUpdateService(cfg *config.SafeConfig, notify func(title, text string, failure bool)) *updateService {
+	return &updateService{cfg: cfg, notify: notify}
+}
+
+// run выполняет фоновые проверки, пока не закрыт stop. Параметры читаются
+// из конфига перед каждой проверкой, поэтому включение в UI действует без перезапуска.
+func (u *updateService) run(stop <-chan struct{}) {
+	timer := time.NewTimer(updateFirstCheckDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		if u.cfg.Get().Updates.Check {
+			u.check(false)
+		}
+		hours := u.cfg.Get().Updates.IntervalHours
+		if hours <= 0 {
+			hours = 24
+		}
+		timer.Reset(time.Duration(hours) * time.Hour)
+	}
+}
+
+// check запрашивает последний релиз. При ручной проверке пользователь получает
+// уведомление и тогда, когда обновлений нет или проверка не удалась.
+func (u *updateService) check(manual bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !version.IsRelease() {
+		logger.Info("Проверка обновлений пропущена: сборка без номера версии (%s)", version.Version)
+		if manual {
+			u.show(manual, "Обновления", "Проверка недоступна для сборки без номера версии", false)
+		}
+		return
+	}
+
+	cfg := u.cfg.Get()
+	checker := &updater.Checker{Repo: cfg.Updates.Repo}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	rel, err := checker.Latest(ctx)
+	if err != nil {
+		logger.Warn("Не удалось проверить обновления: %v", err)
+		if manual {
+			u.show(manual, "Обновления", "Не удалось проверить обновления", true)
+		}
+		return
+	}
+	if !updater.IsNewer(rel, version.Version) {
+		logger.Info("Обновлений нет: текущая версия %s, последняя %s", version.Version, rel.Version())
+		if manual {
+			u.show(manual, "Обновления", fmt.Sprintf("Установлена последняя версия %s", version.Version), false)
+		}
+		return
+	}
+
+	latest := rel.Version()
+	logger.Info("Доступна новая версия %s (текущая %s)", latest, version.Version)
+	if cfg.Updates.AutoDownload && !updater.CanVerify() {
+		logger.Warn("Обновление %s не загружается: в сборке нет ключа проверки подписи", latest)
+	}
+	if !cfg.Updates.AutoDownload || !updater.CanVerify() {
+		if manual || u.notified != latest {
+			u.show(manual, "Доступно обновление", fmt.Sprintf("ClipQueue %s: %s", latest, rel.HTMLURL), false)
+			u.notified = latest
+		}
+		return
+	}
+
+	dataDir := config.ResolvePath(cfg.App.DataDir)
+	if updater.StagedVersion(dataDir) != latest {
+		if err := checker.Stage(ctx, rel, dataDir); err != nil {
+			logger.Error("Не удалось загрузить обновление %s: %v", latest, err)
+			u.show(manual, "Обновления", fmt.Sprintf("Не удалось загрузить версию %s", latest), true)
+			return
+		}
+		logger.Info("Обновление %s загружено в %s", latest, updater.StagedPath(dataDir))
+	}
+	if manual || u.notified != latest {
+		u.show(manual, "Обновление загружено", fmt.Sprintf("ClipQueue %s будет установлен при следующем запуске", latest), false)
+		u.notified = latest
+	}
+}
+
+// show показывает уведомление; фоновые проверки учитывают notifications.enabled,
+// ручная проверка из трея отвечает всегда.
+func (u *updateService) show(manual bool, title, text string, failure bool) {
+	if manual || u.cfg.Get().Notifications.Enabled {
+		u.notify(title, text, failure)
+	}
+}
+
+// applyStagedUpdate подменяет исполняемый файл загруженным обновлением при выходе.
+func applyStagedUpdate(dataDir string) {
+	if !version.IsRelease() {
+		return
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		logger.Warn("Не удалось определить путь к исполняемому файлу: %v", err)
+		return
+	}
+	installed, err := updater.ApplyStaged(exePath, dataDir, version.Version)
+	if err != nil {
+		logger.Error("Не удалось установить обновление: %v", err)
+		return
+	}
+	if installed != "" {
+		logger.Info("Установлено обновление %s, оно начнёт работать после перезапуска", installed)
+	}
+}
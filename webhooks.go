@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/serty2005/clipqueue/internal/app"
+	"github.com/serty2005/clipqueue/internal/config"
+	"github.com/serty2005/clipqueue/internal/webhook"
+	"github.com/serty2005/clipqueue/platform/windows"
+)
+
+// newWebhookDispatcher создаёт диспетчер вебхуков, который читает раздел webhooks
+// из текущего конфига перед каждым событием: изменения в настройках действуют сразу.
+func newWebhookDispatcher(safeCfg *config.SafeConfig) *webhook.Dispatcher {
+	return webhook.New(func() webhook.Settings {
+		cfg := safeCfg.Get().Webhooks
+		return webhook.Settings{
+			URLs:        cfg.URLs,
+			Events:      cfg.Events,
+			IncludeText: cfg.IncludeText,
+			Secret:      cfg.Secret,
+		}
+	})
+}
+
+// webhookPayload описывает событие контроллера для вебхука. Содержимое изображений
+// и списки файлов не передаются — только предпросмотр.
+func webhookPayload(ev app.Event) webhook.Payload {
+	p := webhook.Payload{
+		Event:     ev.Kind,
+		ItemID:    ev.Item.ID,
+		Type:      ev.Item.Type.String(),
+		Preview:   ev.Item.Preview,
+		SizeBytes: ev.Item.SizeBytes,
+		Target:    ev.Target,
+	}
+	// Текст секрета не уходит наружу даже при include_text.
+	if ev.Item.Type == windows.Text && ev.Item.Sensitive == "" {
+		p.Text = ev.Item.Text
+	}
+	return p
+}